@@ -6,13 +6,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/cassiomorais/payments/internal/bootstrap"
 	"github.com/cassiomorais/payments/internal/controller"
-	"github.com/cassiomorais/payments/internal/providers"
+	infraRedis "github.com/cassiomorais/payments/internal/infrastructure/redis"
+	"github.com/cassiomorais/payments/internal/middleware"
 	"github.com/cassiomorais/payments/internal/repository/postgres"
 	"github.com/cassiomorais/payments/internal/service"
+	"github.com/google/uuid"
 )
 
 func main() {
@@ -27,16 +30,103 @@ func main() {
 
 	// --- Repositories ---
 	accountRepo := postgres.NewAccountRepository(app.Pool)
-	paymentRepo := postgres.NewPaymentRepository(app.Pool)
+	paymentRepo := postgres.NewPaymentRepository(app.Pool, postgres.WithIdempotencyTTL(app.Config.Worker.IdempotencyTTL))
 	outboxRepo := postgres.NewOutboxRepository(app.Pool)
 	idempotencyRepo := postgres.NewIdempotencyRepository(app.Pool)
+	disputeRepo := postgres.NewDisputeRepository(app.Pool)
+	manualRefundRepo := postgres.NewManualRefundRepository(app.Pool)
+	auditRepo := postgres.NewAuditRepository(app.Pool)
 	txManager := postgres.NewTxManager(app.Pool)
 
 	// --- Services ---
-	providerFactory := providers.NewFactory()
-	accountService := service.NewAccountService(accountRepo)
-	paymentService := service.NewPaymentService(paymentRepo, accountRepo, outboxRepo, txManager, providerFactory)
-	authzService := service.NewAuthzService(accountRepo)
+	providerFactory, err := app.NewProviderFactory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid provider config: %v\n", err)
+		os.Exit(1)
+	}
+	providerFactory.RequireManualRefunds(app.Config.Payment.ManualRefunds.Providers...)
+	accountService := service.NewAccountService(accountRepo,
+		service.WithStatementSigningKey([]byte(app.Config.Payment.StatementSigningKey)),
+		service.WithAccountTransactions(txManager))
+	paymentOpts := []service.PaymentServiceOption{
+		service.WithCancelNotifier(infraRedis.NewCancelPublisher(app.Redis)),
+		service.WithCancelLock(infraRedis.NewLocker(app.Redis), app.Config.Payment.LockTTL),
+		service.WithBreakerResetNotifier(infraRedis.NewBreakerResetPublisher(app.Redis)),
+		service.WithDeadLetters(infraRedis.NewDeadLetters(app.Redis)),
+		service.WithMetrics(app.Metrics),
+		service.WithDefaultCurrency(app.Config.Payment.DefaultCurrency),
+		service.WithRefundWindow(app.Config.Payment.RefundWindow, app.Config.Payment.RefundOverrideScope),
+		service.WithDisputes(disputeRepo, service.DisputePolicy(app.Config.Payment.Disputes.Policy)),
+		service.WithExternalSourceRequired(app.Config.Payment.RequireExternalSource),
+		service.WithManualRefunds(manualRefundRepo, service.ManualRefundMode(app.Config.Payment.ManualRefunds.Mode)),
+		service.WithMaxRetries(app.Config.Payment.MaxRetries),
+		service.WithProviderTimeout(app.Config.Payment.ProcessingTimeout),
+		service.WithMaxBatchSize(app.Config.Payment.MaxBatchSize),
+	}
+	if feeCfg := app.Config.Payment.TransferFee; feeCfg.FeeAccountID != "" {
+		paymentOpts = append(paymentOpts, service.WithTransferFee(service.TransferFeePolicy{
+			AccountID:   uuid.MustParse(feeCfg.FeeAccountID), // validated by config.Load
+			FlatCents:   feeCfg.FlatCents,
+			BasisPoints: feeCfg.BasisPoints,
+		}))
+	}
+	if id := app.Config.Payment.PlatformFeeAccountID; id != "" {
+		paymentOpts = append(paymentOpts, service.WithPlatformFeeAccount(uuid.MustParse(id))) // validated by config.Load
+	}
+	if pairs := app.Config.Payment.FX.AllowedPairs; len(pairs) > 0 {
+		fxPairs := make([]service.CurrencyPair, 0, len(pairs))
+		for _, s := range pairs {
+			pair, err := service.ParseCurrencyPair(s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid FX config: %v\n", err)
+				os.Exit(1)
+			}
+			fxPairs = append(fxPairs, pair)
+		}
+		// No exchange rate provider is wired yet; quotes report ErrExchangeRateUnavailable.
+		paymentOpts = append(paymentOpts, service.WithFX(service.NewFXPolicy(fxPairs, nil)))
+	}
+	paymentService := service.NewPaymentService(paymentRepo, accountRepo, outboxRepo, txManager, providerFactory, paymentOpts...)
+	stepUpCfg := app.Config.Auth.StepUp
+	overrides := make(map[string]service.Policy, len(app.Config.Auth.Policies))
+	for op, p := range app.Config.Auth.Policies {
+		overrides[op] = service.Policy{Check: service.Check(p.Check), Scopes: p.Scopes, Roles: p.Roles}
+	}
+	policies, err := service.DefaultPolicies(app.Config.Auth.AdminScope, app.Config.Auth.SupportScope).WithOverrides(overrides)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid authorization policy config: %v\n", err)
+		os.Exit(1)
+	}
+	authzService := service.NewAuthzService(accountRepo, service.WithStepUpPolicy(service.StepUpPolicy{
+		AmountThreshold: stepUpCfg.AmountThreshold,
+		CountThreshold:  stepUpCfg.CountThreshold,
+		CountWindow:     stepUpCfg.CountWindow,
+		RequiredScope:   stepUpCfg.RequiredScope,
+	}, paymentRepo), service.WithPolicies(policies, paymentRepo),
+		service.WithRoleScopes(map[string]string{
+			service.RoleAdmin:   app.Config.Auth.AdminScope,
+			service.RoleSupport: app.Config.Auth.SupportScope,
+		}))
+
+	clients := make(map[string]service.Client, len(app.Config.Auth.Clients))
+	for id, c := range app.Config.Auth.Clients {
+		clients[id] = service.Client{SecretHash: strings.ToLower(c.SecretSHA256), UserID: c.UserID, Scopes: c.Scopes, Roles: c.Roles}
+	}
+	tokenService := service.NewTokenService(postgres.NewRefreshTokenRepository(app.Pool), []byte(app.Config.Auth.JWTSecret),
+		service.WithClients(clients),
+		service.WithTokenTTLs(app.Config.Auth.JWTExpiry, app.Config.Auth.RefreshTokenExpiry))
+
+	auditLogger := service.NewAuditLogger(auditRepo,
+		service.WithAuditBuffer(app.Config.Server.AuditBufferSize),
+		service.WithAuditMetrics(app.Metrics))
+
+	maskingRules := controller.DefaultMaskingRules()
+	if cfg := app.Config.Auth.ResponseMasking; len(cfg) > 0 {
+		if maskingRules, err = controller.ParseMaskingRules(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid response masking config: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	// --- Build router ---
 	router := controller.NewRouter(controller.RouterDeps{
@@ -50,6 +140,18 @@ func main() {
 		CORSConfig:      app.Config.Server.CORS,
 		JWTSecret:       app.Config.Auth.JWTSecret,
 		AuthzService:    authzService,
+		AdminScope:      app.Config.Auth.AdminScope,
+		SupportScope:    app.Config.Auth.SupportScope,
+		MaskingRules:    maskingRules,
+
+		DisputeWebhookSecret: app.Config.Payment.Disputes.WebhookSecret,
+		StrictQueryParams:    app.Config.Server.StrictQueryParams,
+		ResponseEnvelope:     middleware.EnvelopeMode(app.Config.Server.ResponseEnvelope),
+		MaxBodyBytes:         app.Config.Server.MaxBodyBytes,
+		MaxBatchBodyBytes:    app.Config.Server.MaxBatchBodyBytes,
+		IdempotencyTTL:       app.Config.Worker.IdempotencyTTL,
+		TokenService:         tokenService,
+		AuditLogger:          auditLogger,
 	})
 
 	// --- HTTP server ---
@@ -82,5 +184,10 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		app.Logger.Error().Err(err).Msg("Server forced to shutdown")
 	}
+	// Requests have finished; write the audit entries they queued before the
+	// database pool closes.
+	if err := auditLogger.Close(shutdownCtx); err != nil {
+		app.Logger.Error().Err(err).Msg("Audit log not fully flushed")
+	}
 	app.Logger.Info().Msg("Server exited")
 }
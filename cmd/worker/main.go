@@ -2,18 +2,27 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/cassiomorais/payments/internal/bootstrap"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/outbox"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
 	infraRedis "github.com/cassiomorais/payments/internal/infrastructure/redis"
-	"github.com/cassiomorais/payments/internal/providers"
 	"github.com/cassiomorais/payments/internal/repository/postgres"
 	"github.com/cassiomorais/payments/internal/service"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 	"golang.org/x/sync/errgroup"
 )
@@ -30,15 +39,27 @@ func main() {
 	defer app.Close()
 
 	// --- Repositories ---
-	paymentRepo := postgres.NewPaymentRepository(app.Pool)
+	paymentRepo := postgres.NewPaymentRepository(app.Pool, postgres.WithIdempotencyTTL(app.Config.Worker.IdempotencyTTL))
 	accountRepo := postgres.NewAccountRepository(app.Pool)
 	outboxRepo := postgres.NewOutboxRepository(app.Pool)
 	txManager := postgres.NewTxManager(app.Pool)
-	providerFactory := providers.NewFactory()
+	providerFactory, err := app.NewProviderFactory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid provider config: %v\n", err)
+		os.Exit(1)
+	}
 	streamProducer := infraRedis.NewStreamProducer(app.Redis)
+	idempotencyRepo := postgres.NewIdempotencyRepository(app.Pool)
+	processingStore := postgres.NewProcessingStore(idempotencyRepo, app.Config.Worker.IdempotencyTTL)
 
 	// --- Services ---
-	paymentService := service.NewPaymentService(paymentRepo, accountRepo, outboxRepo, txManager, providerFactory)
+	paymentService := service.NewPaymentService(paymentRepo, accountRepo, outboxRepo, txManager, providerFactory,
+		service.WithProcessingStore(processingStore),
+		service.WithRetryBackoff(app.Config.Payment.RetryDelay, app.Config.Payment.MaxRetryDelay),
+		service.WithProviderTimeout(app.Config.Payment.ProcessingTimeout),
+		service.WithReconciliation(max(app.Config.Payment.ReconcileMinAge, app.Config.Payment.ProcessingTimeout),
+			infraRedis.NewLockInspector(app.Redis)))
+	reconciliation := service.NewReconciliationUseCase(paymentService, app.Metrics)
 
 	// --- Payment stream consumer ---
 	workerCfg := app.Config.Worker
@@ -50,8 +71,24 @@ func main() {
 		workerCfg.BatchSize,
 		workerCfg.BlockDuration,
 	)
-	if err := consumer.CreateGroup(ctx); err != nil {
-		app.Logger.Error().Err(err).Msg("Failed to create consumer group (may already exist)")
+
+	// The DLQ is only appended to and read by range, so it needs no group.
+	streams := []infraRedis.StreamSpec{consumer.Spec(), {Stream: infraRedis.DLQStream}}
+	var webhookConsumer *infraRedis.StreamConsumer
+	if app.Config.Webhook.URL != "" {
+		webhookConsumer = infraRedis.NewStreamConsumer(
+			app.Redis,
+			infraRedis.WebhookStream,
+			workerCfg.ConsumerGroup,
+			app.Config.InstanceID,
+			workerCfg.BatchSize,
+			workerCfg.BlockDuration,
+		)
+		streams = append(streams, webhookConsumer.Spec())
+	}
+	if err := infraRedis.EnsureStreams(ctx, app.Redis, streams...); err != nil {
+		app.Logger.Error().Err(err).Msg("Failed to set up streams")
+		os.Exit(1)
 	}
 
 	app.Logger.Info().
@@ -65,18 +102,107 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	g, gCtx := errgroup.WithContext(ctx)
+	cancels := infraRedis.NewCancelRegistry()
+
+	active := observability.NewInFlight(app.Metrics.ActivePayments)
+	defer active.Reset()
+	handlePayment := paymentMessageHandler(app.Logger, consumer, paymentService, streamProducer, cancels, active, app)
+
+	// 1. Payment processor (reads from Redis Streams). On shutdown it stops
+	// reading and finishes the current batch within worker.shutdown_timeout.
+	g.Go(func() error {
+		return runStreamProcessor(gCtx, app.Logger, consumer, handlePayment, workerCfg.ShutdownTimeout)
+	})
+
+	// 2. Pending reclaimer (takes over messages stuck with another consumer).
+	g.Go(func() error {
+		return runPendingReclaimer(gCtx, app.Logger, consumer, handlePayment, workerCfg.ReclaimInterval, workerCfg.ReclaimMinIdle,
+			workerCfg.ShutdownTimeout)
+	})
+
+	// 3. Cancel listener (aborts in-flight payments on request).
+	g.Go(func() error {
+		return cancels.Listen(gCtx, app.Redis)
+	})
+
+	// 4. Breaker reset listener (applies resets requested through the API).
+	g.Go(func() error {
+		return infraRedis.ListenBreakerResets(gCtx, app.Redis, func(provider string) {
+			if _, err := providerFactory.ResetBreaker(payment.Provider(provider)); err != nil {
+				app.Logger.Warn().Err(err).Msg("Ignoring breaker reset")
+				return
+			}
+			app.Logger.Info().Str("provider", provider).Msg("Circuit breaker reset")
+		})
+	})
+
+	// 5. Outbox processor (polls outbox table and publishes to Redis Streams).
+	g.Go(func() error {
+		return runOutboxProcessor(gCtx, app.Logger, outboxRepo, streamProducer, workerCfg.OutboxPollInterval, outboxRetry{
+			backoff:    service.RetryBackoff{Base: workerCfg.OutboxRetryDelay, Max: max(workerCfg.OutboxRetryDelay, workerCfg.OutboxMaxRetryDelay)},
+			deadLetter: workerCfg.OutboxDeadLetter,
+		})
+	})
+
+	// 6. Consumer group monitor (recreates a missing group, reports lag).
+	var groupReady atomic.Bool
+	g.Go(func() error {
+		return runGroupMonitor(gCtx, app.Logger, consumer, app, &groupReady)
+	})
+
+	// 7. DLQ monitor (alerts when the dead-letter queue grows too deep or too fast).
+	var dlqDegraded atomic.Bool
+	alerter := observability.NewLogAlerter(app.Logger)
+	g.Go(func() error {
+		return runDLQMonitor(gCtx, app.Logger, infraRedis.NewDLQReader(app.Redis), alerter, app, &dlqDegraded)
+	})
+
+	// 8. Outbox cleanup (deletes old published and failed entries).
+	g.Go(func() error {
+		return runOutboxCleanup(gCtx, app.Logger, outboxRepo, app)
+	})
+
+	// 9. Webhook delivery (posts the webhook stream to the configured endpoint).
+	if whCfg := app.Config.Webhook; webhookConsumer != nil {
+		delivery := service.NewWebhookDeliveryService(service.WebhookDeliveryPolicy{
+			URL:        whCfg.URL,
+			Secret:     whCfg.Secret,
+			MaxRetries: whCfg.MaxRetries,
+			RetryDelay: whCfg.RetryDelay,
+		}, &http.Client{Timeout: whCfg.Timeout})
+		whLogger := app.Logger.With().Str("stream", infraRedis.WebhookStream).Logger()
+		handleWebhook := webhookMessageHandler(whLogger, webhookConsumer, delivery, streamProducer, app.Metrics)
+		g.Go(func() error {
+			return runStreamProcessor(gCtx, whLogger, webhookConsumer, handleWebhook, workerCfg.ShutdownTimeout)
+		})
+		// Redelivers webhooks left pending by a shutdown or a failed DLQ write.
+		g.Go(func() error {
+			return runPendingReclaimer(gCtx, whLogger, webhookConsumer, handleWebhook, workerCfg.ReclaimInterval, workerCfg.ReclaimMinIdle,
+				workerCfg.ShutdownTimeout)
+		})
+	}
 
-	// 1. Payment processor (reads from Redis Streams).
+	// 10. Scheduled payments (queues future-dated payments once due).
 	g.Go(func() error {
-		return runPaymentProcessor(gCtx, app.Logger, consumer, paymentService, app)
+		return runScheduledPaymentProcessor(gCtx, app.Logger, paymentService, workerCfg.SchedulePollInterval)
 	})
 
-	// 2. Outbox processor (polls outbox table and publishes to Redis Streams).
+	// 11. Reconciliation (settles payments stuck in processing with their provider).
 	g.Go(func() error {
-		return runOutboxProcessor(gCtx, app.Logger, txManager, outboxRepo, streamProducer, workerCfg.OutboxPollInterval)
+		return runReconciliation(gCtx, app.Logger, reconciliation, workerCfg.ReconcileInterval, workerCfg.ReconcileBatchSize)
 	})
 
-	// 3. Wait for shutdown signal.
+	// 12. Idempotency cleanup (purges keys older than the idempotency TTL).
+	g.Go(func() error {
+		return runIdempotencyCleanup(gCtx, app.Logger, idempotencyRepo, workerCfg.IdempotencyTTL, workerCfg.IdempotencyCleanupInterval)
+	})
+
+	// 13. Health server (probes and metrics).
+	g.Go(func() error {
+		return runHealthServer(gCtx, app.Logger, workerCfg.HealthPort, &groupReady, &dlqDegraded)
+	})
+
+	// 14. Wait for shutdown signal.
 	g.Go(func() error {
 		select {
 		case <-gCtx.Done():
@@ -94,13 +220,19 @@ func main() {
 	app.Logger.Info().Msg("Worker exited")
 }
 
-func runPaymentProcessor(
+// runStreamProcessor reads a stream's messages until ctx is done. Messages
+// already read are handled with a drain context that outlives ctx by up to
+// drainTimeout, so a shutdown lets the current batch finish and ack; any
+// left when the drain ends stay pending for another worker to reclaim.
+func runStreamProcessor(
 	ctx context.Context,
 	logger zerolog.Logger,
 	consumer *infraRedis.StreamConsumer,
-	paymentService *service.PaymentService,
-	app *bootstrap.App,
+	handle func(context.Context, redis.XMessage),
+	drainTimeout time.Duration,
 ) error {
+	work, stop := drainContext(ctx, drainTimeout)
+	defer stop()
 	for {
 		select {
 		case <-ctx.Done():
@@ -110,51 +242,478 @@ func runPaymentProcessor(
 
 		streams, err := consumer.Read(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
 			logger.Error().Err(err).Msg("Failed to read from stream")
 			time.Sleep(1 * time.Second)
 			continue
 		}
 
-		for _, stream := range streams {
-			for _, msg := range stream.Messages {
-				paymentIDStr, _ := msg.Values["payment_id"].(string)
-				paymentID, err := uuid.Parse(paymentIDStr)
+		if !handleBatch(work, logger, streamMessages(streams), handle) {
+			return nil
+		}
+	}
+}
+
+// drainContext returns a context that is done timeout after ctx is: long
+// enough for in-flight messages to finish on shutdown, and no longer. A
+// non-positive timeout ends it with ctx.
+func drainContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	work, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(timeout, cancel)
+	})
+	return work, func() {
+		stop()
+		cancel()
+	}
+}
+
+// handleBatch handles msgs in order until work is done, and reports whether
+// it handled them all. The rest are left pending.
+func handleBatch(work context.Context, logger zerolog.Logger, msgs []redis.XMessage, handle func(context.Context, redis.XMessage)) bool {
+	for i, msg := range msgs {
+		if work.Err() != nil {
+			logger.Warn().Int("pending", len(msgs)-i).Msg("Shutdown drain timed out, leaving messages pending for reclaim")
+			return false
+		}
+		handle(work, msg)
+	}
+	return true
+}
+
+func streamMessages(streams []redis.XStream) []redis.XMessage {
+	var msgs []redis.XMessage
+	for _, stream := range streams {
+		msgs = append(msgs, stream.Messages...)
+	}
+	return msgs
+}
+
+// paymentMessageHandler returns the handler for payment stream messages,
+// shared by the processor and the pending-message reclaimer. A message whose
+// payment lock is held elsewhere, or whose payment failed with retries left,
+// is left pending for a later reclaim. Once retries are exhausted the message
+// is moved to the DLQ with the failure reason.
+func paymentMessageHandler(
+	logger zerolog.Logger,
+	consumer *infraRedis.StreamConsumer,
+	paymentService *service.PaymentService,
+	producer *infraRedis.StreamProducer,
+	cancels *infraRedis.CancelRegistry,
+	active *observability.InFlight,
+	app *bootstrap.App,
+) func(context.Context, redis.XMessage) {
+	return func(ctx context.Context, msg redis.XMessage) {
+		paymentIDStr, _ := msg.Values["payment_id"].(string)
+		paymentID, err := uuid.Parse(paymentIDStr)
+		if err != nil {
+			logger.Error().Str("raw", paymentIDStr).Msg("Invalid payment ID in stream message")
+			consumer.Ack(ctx, msg.ID)
+			return
+		}
+
+		lock := infraRedis.NewDistributedLock(app.Redis, service.PaymentLockKey(paymentID), app.Config.Payment.LockTTL)
+		token, acquired, err := lock.Acquire(ctx)
+		if err != nil || !acquired {
+			logger.Warn().Str("payment_id", paymentID.String()).Msg("Could not acquire lock, skipping")
+			return
+		}
+
+		// Release with a context of its own: on shutdown ctx may be done, and
+		// the lock must not be left to expire.
+		defer func() {
+			releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+			defer cancel()
+			lock.Release(releaseCtx, token)
+		}()
+
+		logger.Info().Str("payment_id", paymentID.String()).Int64("lock_token", token).Msg("Processing payment")
+
+		// Renew the lock while processing so a reclaimer cannot take over the
+		// payment from a consumer that is slow but still alive.
+		stopKeepAlive := lock.KeepAlive(ctx)
+		done := active.Start()
+		procCtx, untrack := cancels.Track(ctx, paymentID.String())
+		err = paymentService.ProcessPayment(procCtx, paymentID)
+		untrack()
+		done()
+		if kaErr := stopKeepAlive(); kaErr != nil {
+			logger.Warn().Err(kaErr).Str("payment_id", paymentID.String()).Msg("Lost payment lock during processing")
+		}
+		if ctx.Err() != nil {
+			// Cut off by shutdown: leave the message pending for reclaim.
+			logger.Warn().Str("payment_id", paymentID.String()).Msg("Payment processing interrupted by shutdown")
+			return
+		}
+
+		switch {
+		case err == nil:
+			app.Metrics.WorkerMessagesProcessed.WithLabelValues(infraRedis.PaymentStream, "success").Inc()
+		case errors.Is(err, domainErrors.ErrMaxRetriesExceeded):
+			logger.Error().Err(err).Str("payment_id", paymentID.String()).Msg("Payment retries exhausted, moving to DLQ")
+			app.Metrics.PaymentErrors.WithLabelValues("external_payment", "processing_error").Inc()
+			if err := producer.PublishToDLQ(ctx, paymentID.String(), err.Error(), msg.Values); err != nil {
+				logger.Error().Err(err).Str("payment_id", paymentID.String()).Msg("Failed to move payment to DLQ")
+				return
+			}
+			app.Metrics.WorkerMessagesProcessed.WithLabelValues(infraRedis.PaymentStream, "dlq").Inc()
+		case errors.Is(err, domainErrors.ErrRetryNotDue):
+			// Backing off; the reclaimer picks the message up again later.
+			logger.Debug().Str("payment_id", paymentID.String()).Msg("Payment retry not due yet, skipping")
+			return
+		case errors.Is(err, domainErrors.ErrOptimisticLockFailed):
+			// Another writer changed the payment first, such as a second
+			// consumer after a reclaim. Leave the message pending so the
+			// reclaimer re-reads the payment instead of acting on a stale copy.
+			logger.Warn().Str("payment_id", paymentID.String()).Msg("Payment changed concurrently, will retry")
+			return
+		case errors.Is(err, domainErrors.ErrPaymentFailed):
+			// Leave the message pending; the reclaimer retries it once idle.
+			logger.Error().Err(err).Str("payment_id", paymentID.String()).Msg("Failed to process payment, will retry")
+			app.Metrics.PaymentErrors.WithLabelValues("external_payment", "processing_error").Inc()
+			return
+		default:
+			logger.Error().Err(err).Str("payment_id", paymentID.String()).Msg("Failed to process payment")
+			app.Metrics.PaymentErrors.WithLabelValues("external_payment", "processing_error").Inc()
+		}
+
+		consumer.Ack(ctx, msg.ID)
+	}
+}
+
+// pendingClaimer claims a consumer group's stale pending messages;
+// *infraRedis.StreamConsumer implements it.
+type pendingClaimer interface {
+	AutoClaim(ctx context.Context, minIdle time.Duration, start string) ([]redis.XMessage, string, error)
+}
+
+// runPendingReclaimer periodically takes over stream messages left pending
+// longer than minIdle, by a crashed consumer, a skipped lock or a failed
+// dead-letter write, and handles them. Claims use XAUTOCLAIM so workers reclaiming at the same time split
+// the pending list instead of contending for the same IDs.
+func runPendingReclaimer(
+	ctx context.Context,
+	logger zerolog.Logger,
+	consumer pendingClaimer,
+	handle func(context.Context, redis.XMessage),
+	interval, minIdle, drainTimeout time.Duration,
+) error {
+	if interval <= 0 {
+		return nil
+	}
+	work, stop := drainContext(ctx, drainTimeout)
+	defer stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		cursor := "0-0"
+		for ctx.Err() == nil {
+			messages, next, err := consumer.AutoClaim(ctx, minIdle, cursor)
+			if err != nil {
+				logger.Error().Err(err).Msg("Failed to reclaim pending messages")
+				break
+			}
+			if len(messages) > 0 {
+				logger.Info().Int("count", len(messages)).Msg("Reclaimed pending messages")
+			}
+			if !handleBatch(work, logger, messages, handle) {
+				return nil
+			}
+			if next == "0-0" {
+				break
+			}
+			cursor = next
+		}
+	}
+}
+
+// messageAcker acks stream messages; *infraRedis.StreamConsumer implements it.
+type messageAcker interface {
+	Ack(ctx context.Context, messageID string) error
+}
+
+// webhookDeliverer posts a webhook payload; *service.WebhookDeliveryService
+// implements it.
+type webhookDeliverer interface {
+	Deliver(ctx context.Context, webhookID string, payload []byte) error
+}
+
+// webhookDeadLetterer moves undeliverable webhooks to the DLQ;
+// *infraRedis.StreamProducer implements it.
+type webhookDeadLetterer interface {
+	PublishWebhookToDLQ(ctx context.Context, webhookID string, reason string, payload string) error
+}
+
+// webhookMessageHandler returns the handler for webhook stream messages,
+// shared by the processor and the pending-message reclaimer. A message is
+// acked once delivered, or once moved to the DLQ after delivery retries run
+// out. One cut short by shutdown, or whose DLQ write fails, stays pending
+// until the reclaimer delivers it again.
+func webhookMessageHandler(
+	logger zerolog.Logger,
+	consumer messageAcker,
+	delivery webhookDeliverer,
+	dlq webhookDeadLetterer,
+	metrics *observability.Metrics,
+) func(context.Context, redis.XMessage) {
+	return func(ctx context.Context, msg redis.XMessage) {
+		webhookID, _ := msg.Values["webhook_id"].(string)
+		payload, _ := msg.Values["payload"].(string)
+
+		err := delivery.Deliver(ctx, webhookID, []byte(payload))
+		if err == nil {
+			metrics.WorkerMessagesProcessed.WithLabelValues(infraRedis.WebhookStream, "success").Inc()
+			consumer.Ack(ctx, msg.ID)
+			return
+		}
+		if ctx.Err() != nil {
+			logger.Warn().Str("webhook_id", webhookID).Msg("Webhook delivery interrupted by shutdown")
+			return
+		}
+
+		logger.Error().Err(err).Str("webhook_id", webhookID).Msg("Webhook delivery failed, moving to DLQ")
+		if err := dlq.PublishWebhookToDLQ(ctx, webhookID, err.Error(), payload); err != nil {
+			logger.Error().Err(err).Str("webhook_id", webhookID).Msg("Failed to move webhook to DLQ")
+			return
+		}
+		metrics.WorkerMessagesProcessed.WithLabelValues(infraRedis.WebhookStream, "dlq").Inc()
+		consumer.Ack(ctx, msg.ID)
+	}
+}
+
+// runGroupMonitor periodically verifies the payment consumer group exists,
+// recreating it if it was deleted, and publishes its lag and pending counts.
+// The worker reports not-ready while the group is missing or lagging beyond
+// worker.max_group_lag.
+func runGroupMonitor(
+	ctx context.Context,
+	logger zerolog.Logger,
+	consumer *infraRedis.StreamConsumer,
+	app *bootstrap.App,
+	ready *atomic.Bool,
+) error {
+	workerCfg := app.Config.Worker
+	interval := workerCfg.GroupCheckInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	stream, group := infraRedis.PaymentStream, workerCfg.ConsumerGroup
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ready.Store(checkConsumerGroup(ctx, logger, consumer, app, stream, group))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func checkConsumerGroup(
+	ctx context.Context,
+	logger zerolog.Logger,
+	consumer *infraRedis.StreamConsumer,
+	app *bootstrap.App,
+	stream, group string,
+) bool {
+	recreated, err := consumer.EnsureGroup(ctx)
+	if err != nil {
+		logger.Error().Err(err).Str("stream", stream).Str("group", group).Msg("Consumer group check failed")
+		return false
+	}
+	if recreated {
+		logger.Warn().Str("stream", stream).Str("group", group).Msg("Consumer group was missing and has been recreated")
+		app.Metrics.ConsumerGroupRecreated.WithLabelValues(stream, group).Inc()
+	}
+
+	info, err := consumer.GroupInfo(ctx)
+	if err != nil {
+		logger.Error().Err(err).Str("stream", stream).Str("group", group).Msg("Failed to read consumer group info")
+		return false
+	}
+	app.Metrics.ConsumerGroupLag.WithLabelValues(stream, group).Set(float64(info.Lag))
+	app.Metrics.ConsumerGroupPending.WithLabelValues(stream, group).Set(float64(info.Pending))
+
+	maxLag := app.Config.Worker.MaxGroupLag
+	if maxLag > 0 && info.Lag > maxLag {
+		logger.Warn().Int64("lag", info.Lag).Int64("max_lag", maxLag).Str("group", group).Msg("Consumer group is lagging")
+		return false
+	}
+	return true
+}
+
+// runDLQMonitor publishes the DLQ depth and arrival count, and raises an alert
+// when either crosses its configured threshold. The worker is flagged degraded
+// until both drop back below their thresholds.
+func runDLQMonitor(
+	ctx context.Context,
+	logger zerolog.Logger,
+	dlq *infraRedis.DLQReader,
+	alerter observability.Alerter,
+	app *bootstrap.App,
+	degraded *atomic.Bool,
+) error {
+	workerCfg := app.Config.Worker
+	interval := workerCfg.DLQCheckInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if reason := checkDLQ(ctx, logger, dlq, app); reason != "" {
+			if !degraded.Swap(true) {
+				err := alerter.Alert(ctx, observability.Alert{
+					Name:     "dlq_threshold_exceeded",
+					Severity: observability.SeverityCritical,
+					Message:  reason,
+					Labels:   map[string]string{"stream": infraRedis.DLQStream},
+				})
 				if err != nil {
-					logger.Error().Str("raw", paymentIDStr).Msg("Invalid payment ID in stream message")
-					consumer.Ack(ctx, msg.ID)
-					continue
+					logger.Error().Err(err).Msg("Failed to send DLQ alert")
 				}
+			}
+		} else if degraded.Swap(false) {
+			logger.Info().Str("stream", infraRedis.DLQStream).Msg("DLQ back below alert thresholds")
+		}
 
-				lock := infraRedis.NewDistributedLock(app.Redis, "payment:"+paymentID.String(), app.Config.Payment.LockTTL)
-				acquired, err := lock.Acquire(ctx)
-				if err != nil || !acquired {
-					logger.Warn().Str("payment_id", paymentID.String()).Msg("Could not acquire lock, skipping")
-					continue
-				}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
 
-				logger.Info().Str("payment_id", paymentID.String()).Msg("Processing payment")
+// checkDLQ updates the DLQ metrics and returns a non-empty reason when a
+// threshold is exceeded. Read errors are logged and do not change the state.
+func checkDLQ(ctx context.Context, logger zerolog.Logger, dlq *infraRedis.DLQReader, app *bootstrap.App) string {
+	workerCfg := app.Config.Worker
 
-				if err := paymentService.ProcessPayment(ctx, paymentID); err != nil {
-					logger.Error().Err(err).Str("payment_id", paymentID.String()).Msg("Failed to process payment")
-					app.Metrics.PaymentErrors.WithLabelValues("external_payment", "processing_error").Inc()
-				} else {
-					app.Metrics.WorkerMessagesProcessed.WithLabelValues(infraRedis.PaymentStream, "success").Inc()
-				}
+	depth, err := dlq.Depth(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("DLQ check failed")
+		return ""
+	}
+	app.Metrics.DLQDepth.Set(float64(depth))
 
-				lock.Release(ctx)
-				consumer.Ack(ctx, msg.ID)
-			}
+	added, err := dlq.NewEntries(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("DLQ check failed")
+	}
+	app.Metrics.DLQEntries.Add(float64(added))
+
+	if workerCfg.DLQDepthThreshold > 0 && depth >= workerCfg.DLQDepthThreshold {
+		return fmt.Sprintf("DLQ depth %d reached threshold %d", depth, workerCfg.DLQDepthThreshold)
+	}
+	if workerCfg.DLQRateThreshold > 0 && added >= workerCfg.DLQRateThreshold {
+		return fmt.Sprintf("%d new DLQ entries in the last interval reached threshold %d", added, workerCfg.DLQRateThreshold)
+	}
+	return ""
+}
+
+// runHealthServer serves liveness, readiness and metrics for the worker.
+// A non-positive port disables the server. A degraded DLQ keeps the worker
+// ready but is reported in the readiness body.
+func runHealthServer(ctx context.Context, logger zerolog.Logger, port int, groupReady, dlqDegraded *atomic.Bool) error {
+	if port <= 0 {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health/live", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, http.StatusOK, map[string]string{"status": "alive"})
+	})
+	mux.HandleFunc("/health/ready", func(w http.ResponseWriter, r *http.Request) {
+		if !groupReady.Load() {
+			writeHealth(w, http.StatusServiceUnavailable, map[string]string{
+				"status": "not ready",
+				"reason": "consumer group unavailable or lagging",
+			})
+			return
+		}
+		if dlqDegraded.Load() {
+			writeHealth(w, http.StatusOK, map[string]string{
+				"status": "degraded",
+				"reason": "dead-letter queue above alert threshold",
+			})
+			return
 		}
+		writeHealth(w, http.StatusOK, map[string]string{"status": "ready"})
+	})
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info().Str("addr", srv.Addr).Msg("Starting worker health server")
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("worker health server: %w", err)
 	}
+	return nil
+}
+
+func writeHealth(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// outboxStore is the part of the outbox the processor uses;
+// *postgres.OutboxRepository implements it.
+type outboxStore interface {
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*outbox.Entry, error)
+	MarkPublished(ctx context.Context, id uuid.UUID) error
+	MarkFailed(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time) (outbox.Status, error)
 }
 
+// outboxPublisher publishes outbox entries; *infraRedis.StreamProducer
+// implements it.
+type outboxPublisher interface {
+	PublishPaymentEvent(ctx context.Context, paymentID string, eventType string, data map[string]any) error
+	PublishOutboxToDLQ(ctx context.Context, entry *outbox.Entry, reason string) error
+}
+
+// outboxRetry is how the outbox processor handles failed publishes: the next
+// attempt waits backoff.Delay(retry), and with deadLetter an entry that runs
+// out of retries is copied to the DLQ.
+type outboxRetry struct {
+	backoff    service.RetryBackoff
+	deadLetter bool
+}
+
+// Outbox entries are claimed outboxBatchSize at a time. A claim lapses after
+// outboxClaimLease, well beyond the time to publish a batch, so entries left
+// claimed by a crashed worker are published by another.
+const (
+	outboxBatchSize  = 10
+	outboxClaimLease = time.Minute
+)
+
 func runOutboxProcessor(
 	ctx context.Context,
 	logger zerolog.Logger,
-	txManager *postgres.TxManager,
-	outboxRepo *postgres.OutboxRepository,
-	streamProducer *infraRedis.StreamProducer,
+	outboxRepo outboxStore,
+	streamProducer outboxPublisher,
 	pollInterval time.Duration,
+	retry outboxRetry,
 ) error {
 	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
@@ -165,25 +724,244 @@ func runOutboxProcessor(
 		case <-ticker.C:
 		}
 
-		err := txManager.WithTransaction(ctx, func(txCtx context.Context) error {
-			entries, err := outboxRepo.GetPending(txCtx, 10)
+		if err := processOutboxBatch(ctx, logger, outboxRepo, streamProducer, retry); err != nil {
+			logger.Error().Err(err).Msg("Outbox processor error")
+		}
+	}
+}
+
+// processOutboxBatch claims a batch of due entries, which commits them as
+// publishing, then publishes each and records the outcome in its own
+// statement. Redis is never written inside a database transaction: a crash
+// between a publish and its mark leaves the entry claimed until the lease
+// lapses, and it is then published again with the same dedup_key, which
+// consumers use to drop the copy.
+func processOutboxBatch(
+	ctx context.Context,
+	logger zerolog.Logger,
+	store outboxStore,
+	publisher outboxPublisher,
+	retry outboxRetry,
+) error {
+	entries, err := store.ClaimPending(ctx, outboxBatchSize, outboxClaimLease)
+	if err != nil {
+		return err
+	}
+	publishOutboxEntries(ctx, logger, store, publisher, retry, entries)
+	return nil
+}
+
+// publishOutboxEntries publishes claimed entries to the payment stream. A
+// failed publish is rescheduled with backoff; once an entry has used its
+// retries it is left failed and, if configured, copied to the DLQ.
+func publishOutboxEntries(
+	ctx context.Context,
+	logger zerolog.Logger,
+	store outboxStore,
+	publisher outboxPublisher,
+	retry outboxRetry,
+	entries []*outbox.Entry,
+) {
+	for _, entry := range entries {
+		err := publisher.PublishPaymentEvent(ctx, entry.AggregateID.String(), entry.EventType, entry.Payload)
+		if err == nil {
+			if err := store.MarkPublished(ctx, entry.ID); err != nil {
+				logger.Error().Err(err).Str("outbox_id", entry.ID.String()).
+					Msg("Failed to mark outbox event published; it will be published again once its claim lapses")
+			}
+			continue
+		}
+
+		attempt := entry.RetryCount + 1
+		nextAttemptAt := time.Now().Add(retry.backoff.Delay(attempt))
+		status, markErr := store.MarkFailed(ctx, entry.ID, nextAttemptAt)
+		if markErr != nil {
+			logger.Error().Err(markErr).Str("outbox_id", entry.ID.String()).Msg("Failed to record outbox publish failure")
+			continue
+		}
+		if status != outbox.StatusFailed {
+			logger.Error().Err(err).Str("outbox_id", entry.ID.String()).Int("attempt", attempt).
+				Time("next_attempt_at", nextAttemptAt).Msg("Failed to publish outbox event")
+			continue
+		}
+
+		logger.Error().Err(err).Str("outbox_id", entry.ID.String()).Int("attempts", attempt).
+			Msg("Outbox event failed permanently")
+		if retry.deadLetter {
+			reason := fmt.Sprintf("outbox publish failed after %d attempts: %v", attempt, err)
+			if dlqErr := publisher.PublishOutboxToDLQ(ctx, entry, reason); dlqErr != nil {
+				logger.Error().Err(dlqErr).Str("outbox_id", entry.ID.String()).Msg("Failed to dead-letter outbox event")
+			}
+		}
+	}
+}
+
+// scheduledBatchSize bounds how many due payments one transaction releases.
+const scheduledBatchSize = 100
+
+// runScheduledPaymentProcessor polls for scheduled payments that have come
+// due and queues them through the outbox onto the normal processing path. A
+// full batch is followed immediately by another so a backlog drains without
+// waiting a whole interval per batch.
+func runScheduledPaymentProcessor(
+	ctx context.Context,
+	logger zerolog.Logger,
+	paymentService *service.PaymentService,
+	interval time.Duration,
+) error {
+	if interval <= 0 {
+		return nil
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		for ctx.Err() == nil {
+			released, err := paymentService.ReleaseDueScheduled(ctx, scheduledBatchSize)
 			if err != nil {
-				return err
+				logger.Error().Err(err).Msg("Failed to release scheduled payments")
+				break
 			}
-			for _, entry := range entries {
-				if err := streamProducer.PublishPaymentEvent(
-					ctx, entry.AggregateID.String(), entry.EventType, entry.Payload,
-				); err != nil {
-					logger.Error().Err(err).Str("outbox_id", entry.ID.String()).Msg("Failed to publish outbox event")
-					outboxRepo.MarkFailed(txCtx, entry.ID)
-					continue
-				}
-				outboxRepo.MarkPublished(txCtx, entry.ID)
+			if released < scheduledBatchSize {
+				break
 			}
+		}
+	}
+}
+
+// runReconciliation periodically reconciles payments stuck in processing
+// with their provider, one batch per tick.
+func runReconciliation(
+	ctx context.Context,
+	logger zerolog.Logger,
+	reconciliation *service.ReconciliationUseCase,
+	interval time.Duration,
+	batchSize int,
+) error {
+	if interval <= 0 {
+		return nil
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
 			return nil
-		})
+		case <-ticker.C:
+		}
+
+		report, err := reconciliation.Run(ctx, batchSize)
 		if err != nil {
-			logger.Error().Err(err).Msg("Outbox processor error")
+			logger.Error().Err(err).Msg("Failed to reconcile payments")
+			continue
+		}
+		if report.Mismatches > 0 || report.Errors > 0 {
+			logger.Warn().
+				Int("checked", report.Checked).
+				Int("mismatches", report.Mismatches).
+				Int("completed", report.Outcomes[service.ReconcileCompleted]).
+				Int("failed", report.Outcomes[service.ReconcileFailed]).
+				Int("flagged", report.Outcomes[service.ReconcileFlagged]).
+				Int("errors", report.Errors).
+				Msg("Reconciliation found mismatches")
+		}
+	}
+}
+
+// idempotencyPurger deletes idempotency keys; *postgres.IdempotencyRepository
+// implements it.
+type idempotencyPurger interface {
+	DeleteExpired(ctx context.Context, olderThan time.Time) (int64, error)
+}
+
+// runIdempotencyCleanup deletes idempotency keys older than ttl every
+// interval. Either being zero disables it.
+func runIdempotencyCleanup(
+	ctx context.Context,
+	logger zerolog.Logger,
+	repo idempotencyPurger,
+	ttl, interval time.Duration,
+) error {
+	if ttl <= 0 || interval <= 0 {
+		logger.Info().Msg("Idempotency cleanup disabled")
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().Add(-ttl)
+		deleted, err := repo.DeleteExpired(ctx, cutoff)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Error().Err(err).Msg("Idempotency cleanup failed")
+			}
+		} else if deleted > 0 {
+			logger.Info().Int64("deleted", deleted).Time("cutoff", cutoff).Msg("Idempotency cleanup completed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOutboxCleanup periodically deletes outbox entries older than the
+// retention window. Each pass deletes in batches so no statement holds locks
+// for long, and stops between batches on shutdown.
+func runOutboxCleanup(
+	ctx context.Context,
+	logger zerolog.Logger,
+	outboxRepo *postgres.OutboxRepository,
+	app *bootstrap.App,
+) error {
+	workerCfg := app.Config.Worker
+	if workerCfg.OutboxRetention <= 0 {
+		logger.Info().Msg("Outbox cleanup disabled")
+		return nil
+	}
+	interval := workerCfg.OutboxCleanupInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	batchSize := workerCfg.OutboxCleanupBatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().Add(-workerCfg.OutboxRetention)
+		var total int64
+		for ctx.Err() == nil {
+			deleted, err := outboxRepo.DeletePublishedBefore(ctx, cutoff, batchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Error().Err(err).Msg("Outbox cleanup failed")
+				}
+				break
+			}
+			total += deleted
+			if deleted < int64(batchSize) {
+				break
+			}
+		}
+		if total > 0 {
+			logger.Info().Int64("deleted", total).Time("cutoff", cutoff).Msg("Outbox cleanup completed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
 		}
 	}
 }
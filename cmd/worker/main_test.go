@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/outbox"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePurger struct {
+	cutoffs []time.Time
+	onCall  func()
+}
+
+func (f *fakePurger) DeleteExpired(ctx context.Context, olderThan time.Time) (int64, error) {
+	f.cutoffs = append(f.cutoffs, olderThan)
+	if f.onCall != nil {
+		f.onCall()
+	}
+	return 3, nil
+}
+
+func TestRunIdempotencyCleanup_PurgesKeysOlderThanTTL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	purger := &fakePurger{}
+	purger.onCall = func() {
+		if len(purger.cutoffs) == 2 {
+			cancel()
+		}
+	}
+
+	start := time.Now()
+	err := runIdempotencyCleanup(ctx, zerolog.Nop(), purger, 24*time.Hour, time.Millisecond)
+	require.NoError(t, err)
+
+	require.Len(t, purger.cutoffs, 2, "purges on start and on every tick until shutdown")
+	assert.WithinDuration(t, start.Add(-24*time.Hour), purger.cutoffs[0], time.Second)
+	assert.False(t, purger.cutoffs[1].Before(purger.cutoffs[0]), "the cutoff moves with the clock")
+}
+
+func TestRunIdempotencyCleanup_Disabled(t *testing.T) {
+	purger := &fakePurger{}
+	require.NoError(t, runIdempotencyCleanup(context.Background(), zerolog.Nop(), purger, 0, time.Hour))
+	require.NoError(t, runIdempotencyCleanup(context.Background(), zerolog.Nop(), purger, time.Hour, 0))
+	assert.Empty(t, purger.cutoffs)
+}
+
+func TestDrainContext_OutlivesParentUntilTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	work, stop := drainContext(ctx, 50*time.Millisecond)
+	defer stop()
+
+	cancel()
+	assert.NoError(t, work.Err(), "in-flight work keeps running after shutdown starts")
+
+	select {
+	case <-work.Done():
+	case <-time.After(time.Second):
+		t.Fatal("drain context outlived its timeout")
+	}
+}
+
+func TestDrainContext_StopEndsIt(t *testing.T) {
+	work, stop := drainContext(context.Background(), time.Hour)
+	stop()
+	assert.Error(t, work.Err())
+}
+
+func TestHandleBatch_LeavesRestPendingOnceDrainEnds(t *testing.T) {
+	work, cancel := context.WithCancel(context.Background())
+	msgs := []redis.XMessage{{ID: "1-0"}, {ID: "2-0"}, {ID: "3-0"}}
+
+	var handled []string
+	done := handleBatch(work, zerolog.Nop(), msgs, func(ctx context.Context, msg redis.XMessage) {
+		handled = append(handled, msg.ID)
+		if msg.ID == "2-0" {
+			cancel() // the drain times out while this message is in flight
+		}
+	})
+
+	assert.False(t, done)
+	assert.Equal(t, []string{"1-0", "2-0"}, handled, "3-0 is not started and stays pending")
+}
+
+// fakeOutbox applies claims and marks to its entries like the postgres
+// repository, on a clock the test controls.
+type fakeOutbox struct {
+	mu        sync.Mutex
+	now       time.Time
+	entries   map[uuid.UUID]*outbox.Entry
+	published []uuid.UUID
+	// markErr, when set, fails MarkPublished as a crash right after the
+	// publish would.
+	markErr error
+}
+
+func newFakeOutbox(entries ...*outbox.Entry) *fakeOutbox {
+	f := &fakeOutbox{now: time.Now(), entries: map[uuid.UUID]*outbox.Entry{}}
+	for _, e := range entries {
+		f.entries[e.ID] = e
+	}
+	return f
+}
+
+func (f *fakeOutbox) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*outbox.Entry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var claimed []*outbox.Entry
+	for _, e := range f.entries {
+		due := (e.Status == outbox.StatusPending || e.Status == outbox.StatusPublishing) && !e.NextAttemptAt.After(f.now)
+		if due && e.RetryCount < e.MaxRetries && len(claimed) < limit {
+			e.Status = outbox.StatusPublishing
+			e.NextAttemptAt = f.now.Add(lease)
+			claimed = append(claimed, e)
+		}
+	}
+	return claimed, nil
+}
+
+func (f *fakeOutbox) MarkPublished(ctx context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.markErr != nil {
+		return f.markErr
+	}
+	f.published = append(f.published, id)
+	f.entries[id].Status = outbox.StatusPublished
+	return nil
+}
+
+func (f *fakeOutbox) MarkFailed(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time) (outbox.Status, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e := f.entries[id]
+	e.RetryCount++
+	e.NextAttemptAt = nextAttemptAt
+	if e.RetryCount >= e.MaxRetries {
+		e.Status = outbox.StatusFailed
+	}
+	return e.Status, nil
+}
+
+type fakeOutboxPublisher struct {
+	mu           sync.Mutex
+	failing      map[uuid.UUID]bool
+	dedupKeys    []any
+	deadLettered []uuid.UUID
+}
+
+func (p *fakeOutboxPublisher) PublishPaymentEvent(ctx context.Context, paymentID string, eventType string, data map[string]any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing[uuid.MustParse(paymentID)] {
+		return errors.New("stream unavailable")
+	}
+	p.dedupKeys = append(p.dedupKeys, data[outbox.DedupKeyKey])
+	return nil
+}
+
+func (p *fakeOutboxPublisher) PublishOutboxToDLQ(ctx context.Context, entry *outbox.Entry, reason string) error {
+	p.deadLettered = append(p.deadLettered, entry.ID)
+	return nil
+}
+
+func TestPublishOutboxEntries_BacksOffFailedPublishes(t *testing.T) {
+	ok := outbox.NewEntry("payment", uuid.New(), "payment.created", nil)
+	broken := outbox.NewEntry("payment", uuid.New(), "payment.created", nil)
+	broken.RetryCount = 2
+	store := newFakeOutbox(ok, broken)
+	publisher := &fakeOutboxPublisher{failing: map[uuid.UUID]bool{broken.AggregateID: true}}
+	retry := outboxRetry{backoff: service.RetryBackoff{Base: time.Second, Max: time.Minute}, deadLetter: true}
+
+	start := time.Now()
+	publishOutboxEntries(context.Background(), zerolog.Nop(), store, publisher, retry, []*outbox.Entry{ok, broken})
+
+	assert.Equal(t, []uuid.UUID{ok.ID}, store.published)
+	assert.Equal(t, outbox.StatusPending, broken.Status)
+	assert.Equal(t, 3, broken.RetryCount)
+	// The third attempt waits between half and all of 4s.
+	assert.WithinRange(t, broken.NextAttemptAt, start.Add(2*time.Second), time.Now().Add(4*time.Second))
+	assert.Empty(t, publisher.deadLettered, "entries with retries left are not dead-lettered")
+}
+
+func TestPublishOutboxEntries_DeadLettersExhaustedEntries(t *testing.T) {
+	for _, deadLetter := range []bool{true, false} {
+		entry := outbox.NewEntry("payment", uuid.New(), "payment.completed", map[string]any{"amount_cents": 100})
+		entry.RetryCount = entry.MaxRetries - 1
+		store := newFakeOutbox(entry)
+		publisher := &fakeOutboxPublisher{failing: map[uuid.UUID]bool{entry.AggregateID: true}}
+		retry := outboxRetry{backoff: service.DefaultRetryBackoff(), deadLetter: deadLetter}
+
+		publishOutboxEntries(context.Background(), zerolog.Nop(), store, publisher, retry, []*outbox.Entry{entry})
+
+		assert.Equal(t, outbox.StatusFailed, entry.Status)
+		if deadLetter {
+			assert.Equal(t, []uuid.UUID{entry.ID}, publisher.deadLettered)
+		} else {
+			assert.Empty(t, publisher.deadLettered)
+		}
+	}
+}
+
+func TestProcessOutboxBatch_CrashBeforeMarkRepublishesWithSameDedupKey(t *testing.T) {
+	entry := outbox.NewEntry("payment", uuid.New(), "payment.created", map[string]any{outbox.DedupKeyKey: "event-1"})
+	store := newFakeOutbox(entry)
+	publisher := &fakeOutboxPublisher{}
+	retry := outboxRetry{backoff: service.DefaultRetryBackoff()}
+
+	// The worker publishes, then dies before the entry is marked published.
+	store.markErr = errors.New("connection lost")
+	require.NoError(t, processOutboxBatch(context.Background(), zerolog.Nop(), store, publisher, retry))
+	assert.Equal(t, outbox.StatusPublishing, entry.Status, "the claim committed before the publish")
+	assert.Len(t, publisher.dedupKeys, 1)
+
+	// While the claim holds, no other worker picks the entry up.
+	store.markErr = nil
+	require.NoError(t, processOutboxBatch(context.Background(), zerolog.Nop(), store, publisher, retry))
+	assert.Len(t, publisher.dedupKeys, 1)
+
+	// Once it lapses the entry is published again, under the same dedup key.
+	store.now = store.now.Add(outboxClaimLease)
+	require.NoError(t, processOutboxBatch(context.Background(), zerolog.Nop(), store, publisher, retry))
+	assert.Equal(t, []any{"event-1", "event-1"}, publisher.dedupKeys)
+	assert.Equal(t, outbox.StatusPublished, entry.Status)
+
+	require.NoError(t, processOutboxBatch(context.Background(), zerolog.Nop(), store, publisher, retry))
+	assert.Len(t, publisher.dedupKeys, 2, "a published entry is not claimed again")
+}
+
+func TestProcessOutboxBatch_ConcurrentWorkersPublishDisjointEntries(t *testing.T) {
+	var entries []*outbox.Entry
+	for i := range 45 {
+		entries = append(entries, outbox.NewEntry("payment", uuid.New(), "payment.created",
+			map[string]any{outbox.DedupKeyKey: fmt.Sprintf("event-%d", i)}))
+	}
+	store := newFakeOutbox(entries...)
+	publisher := &fakeOutboxPublisher{}
+	retry := outboxRetry{backoff: service.DefaultRetryBackoff()}
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range len(entries) {
+				assert.NoError(t, processOutboxBatch(context.Background(), zerolog.Nop(), store, publisher, retry))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, publisher.dedupKeys, len(entries), "every entry is published exactly once")
+	seen := map[any]bool{}
+	for _, key := range publisher.dedupKeys {
+		assert.False(t, seen[key], "%v published twice", key)
+		seen[key] = true
+	}
+}
+
+// fakeWebhookStream is a consumer group's pending list: a message stays
+// pending until acked, and AutoClaim hands back everything still pending.
+type fakeWebhookStream struct {
+	mu      sync.Mutex
+	pending map[string]redis.XMessage
+	onAck   func()
+}
+
+func (f *fakeWebhookStream) Ack(ctx context.Context, messageID string) error {
+	f.mu.Lock()
+	delete(f.pending, messageID)
+	f.mu.Unlock()
+	if f.onAck != nil {
+		f.onAck()
+	}
+	return nil
+}
+
+func (f *fakeWebhookStream) AutoClaim(ctx context.Context, minIdle time.Duration, start string) ([]redis.XMessage, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var msgs []redis.XMessage
+	for _, msg := range f.pending {
+		msgs = append(msgs, msg)
+	}
+	return msgs, "0-0", nil
+}
+
+type fakeWebhookDelivery struct {
+	attempts []string
+	err      error
+}
+
+func (f *fakeWebhookDelivery) Deliver(ctx context.Context, webhookID string, payload []byte) error {
+	f.attempts = append(f.attempts, webhookID)
+	return f.err
+}
+
+type failingWebhookDLQ struct{}
+
+func (failingWebhookDLQ) PublishWebhookToDLQ(ctx context.Context, webhookID string, reason string, payload string) error {
+	return errors.New("redis unavailable")
+}
+
+func TestWebhookMessageHandler_UnackedMessageRedelivered(t *testing.T) {
+	msg := redis.XMessage{ID: "1-0", Values: map[string]any{"webhook_id": "wh-1", "payload": `{"event":"payment.completed"}`}}
+	stream := &fakeWebhookStream{pending: map[string]redis.XMessage{msg.ID: msg}}
+	delivery := &fakeWebhookDelivery{err: errors.New("endpoint returned 503")}
+	handle := webhookMessageHandler(zerolog.Nop(), stream, delivery, failingWebhookDLQ{},
+		observability.NewMetrics("test", prometheus.NewRegistry()))
+
+	// Delivery fails and so does the DLQ write: the message stays pending.
+	handle(context.Background(), msg)
+	require.Contains(t, stream.pending, msg.ID)
+
+	// Once the endpoint recovers, the reclaimer delivers it again.
+	delivery.err = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream.onAck = cancel
+	done := make(chan error, 1)
+	go func() {
+		done <- runPendingReclaimer(ctx, zerolog.Nop(), stream, handle, time.Millisecond, time.Millisecond, time.Second)
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending webhook was not redelivered")
+	}
+
+	assert.Equal(t, []string{"wh-1", "wh-1"}, delivery.attempts)
+	assert.Empty(t, stream.pending, "the redelivered message is acked")
+}
@@ -7,8 +7,10 @@ import (
 
 	"github.com/cassiomorais/payments/internal/infrastructure/config"
 	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/cassiomorais/payments/internal/providers"
 	"github.com/cassiomorais/payments/internal/repository/postgres"
 	infraRedis "github.com/cassiomorais/payments/internal/infrastructure/redis"
+	"github.com/cassiomorais/payments/pkg/currency"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
@@ -31,6 +33,12 @@ func New(ctx context.Context, serviceName string, metricsNamespace string) (*App
 	logger := observability.InitLogger(cfg.Observability.LogLevel, os.Stdout)
 	logger.Info().Str("service", serviceName).Msg("Starting")
 
+	for _, c := range cfg.Payment.CustomCurrencies() {
+		if err := currency.Register(c); err != nil {
+			return nil, fmt.Errorf("register currency: %w", err)
+		}
+	}
+
 	if cfg.Observability.EnableTracing {
 		tp, err := observability.InitTracer(serviceName, cfg.Observability.JaegerEndpoint)
 		if err != nil {
@@ -52,6 +60,11 @@ func New(ctx context.Context, serviceName string, metricsNamespace string) (*App
 		return nil, fmt.Errorf("connect to database: %w", err)
 	}
 	logger.Info().Msg("Connected to PostgreSQL")
+	if cfg.Database.AssertTransactions {
+		postgres.SetTxAssertions(true)
+		logger.Warn().Msg("Transaction assertions enabled for money-moving repository calls")
+	}
+	postgres.SetReadRetry(cfg.Database.ReadRetries, cfg.Database.ReadRetryBackoff)
 
 	redisClient, err := infraRedis.NewClient(ctx, &cfg.Redis)
 	if err != nil {
@@ -69,6 +82,59 @@ func New(ctx context.Context, serviceName string, metricsNamespace string) (*App
 	}, nil
 }
 
+// NewProviderFactory returns the provider factory with circuit breakers
+// configured from payment config, rate limits from the providers section,
+// and both reporting to the app's metrics.
+func (a *App) NewProviderFactory() (*providers.Factory, error) {
+	pc := a.Config.Payment
+	defaults := providers.BreakerSettings{
+		Threshold:         pc.CircuitBreakerThreshold,
+		Timeout:           pc.CircuitBreakerTimeout,
+		HalfOpenProbes:    pc.CircuitBreakerHalfOpenProbes,
+		HalfOpenSuccesses: pc.CircuitBreakerHalfOpenSuccesses,
+	}
+	overrides := make(map[string]providers.BreakerSettings, len(pc.CircuitBreakers))
+	for name, o := range pc.CircuitBreakers {
+		s := defaults
+		if o.Threshold != 0 {
+			s.Threshold = o.Threshold
+		}
+		if o.Timeout != 0 {
+			s.Timeout = o.Timeout
+		}
+		if o.HalfOpenProbes != 0 {
+			s.HalfOpenProbes = o.HalfOpenProbes
+		}
+		if o.HalfOpenSuccesses != 0 {
+			s.HalfOpenSuccesses = o.HalfOpenSuccesses
+		}
+		overrides[name] = s
+	}
+
+	factory := providers.NewFactory()
+	if err := factory.ConfigureBreakers(defaults, overrides, a.Metrics); err != nil {
+		return nil, fmt.Errorf("circuit breaker config: %w", err)
+	}
+	slo := providers.LatencySLO{
+		Percentile: pc.LatencySLO.Percentile,
+		Threshold:  pc.LatencySLO.Threshold,
+		Window:     pc.LatencySLO.Window,
+		MinSamples: pc.LatencySLO.MinSamples,
+		Cooldown:   pc.LatencySLO.Cooldown,
+	}
+	if err := factory.ConfigureLatencySLO(slo, pc.LatencySLO.Fallbacks); err != nil {
+		return nil, fmt.Errorf("latency SLO config: %w", err)
+	}
+	limits := make(map[string]providers.RateLimit, len(a.Config.Providers))
+	for name, p := range a.Config.Providers {
+		limits[name] = providers.RateLimit{RPS: p.RPS, Burst: p.Burst}
+	}
+	if err := factory.ConfigureRateLimits(limits, a.Metrics); err != nil {
+		return nil, fmt.Errorf("provider rate limit config: %w", err)
+	}
+	return factory, nil
+}
+
 func (a *App) Close() {
 	a.Redis.Close()
 	a.Pool.Close()
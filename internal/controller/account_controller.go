@@ -1,14 +1,23 @@
 package controller
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/cassiomorais/payments/internal/domain/account"
 	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/cassiomorais/payments/internal/middleware"
 	"github.com/cassiomorais/payments/internal/service"
+	"github.com/cassiomorais/payments/internal/statement"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
 type AccountController struct {
@@ -39,7 +48,7 @@ func (h *AccountController) Create(w http.ResponseWriter, r *http.Request) {
 	req.UserID = authenticatedUserID
 
 	// Convert with error handling
-	balanceCents, err := floatToCents(req.InitialBalance)
+	balanceCents, err := floatToMinor(req.InitialBalance, req.Currency)
 	if err != nil {
 		writeError(w, err)
 		return
@@ -49,28 +58,68 @@ func (h *AccountController) Create(w http.ResponseWriter, r *http.Request) {
 		UserID:         req.UserID,
 		InitialBalance: balanceCents,
 		Currency:       req.Currency,
+		Label:          req.Label,
 	})
 	if err != nil {
 		writeError(w, err)
 		return
 	}
 
+	setAuditTarget(r.Context(), acct.ID)
 	writeJSON(w, http.StatusCreated, FromAccount(acct))
 }
 
-func (h *AccountController) Get(w http.ResponseWriter, r *http.Request) {
-	id, err := uuid.Parse(chi.URLParam(r, "id"))
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid account id", Code: "invalid_id"})
+// Ensure returns the caller's account in the requested currency, creating it
+// if needed: 201 when created, 200 when it already existed.
+func (h *AccountController) Ensure(w http.ResponseWriter, r *http.Request) {
+	var req EnsureAccountRequest
+	if err := decodeAndValidate(r, &req); err != nil {
+		writeError(w, err)
 		return
 	}
 
-	// Authorization check
-	if err := h.authzService.VerifyAccountOwnership(r.Context(), id); err != nil {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, domainErrors.ErrUnauthorized)
+		return
+	}
+
+	var balanceCents int64
+	if req.InitialBalance > 0 {
+		cents, err := floatToMinor(req.InitialBalance, req.Currency)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		balanceCents = cents
+	}
+
+	acct, created, err := h.accountService.GetOrCreateAccount(r.Context(), service.CreateAccountRequest{
+		UserID:         userID,
+		InitialBalance: balanceCents,
+		Currency:       strings.ToUpper(req.Currency),
+		Label:          req.Label,
+	})
+	if err != nil {
 		writeError(w, err)
 		return
 	}
 
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	setAuditTarget(r.Context(), acct.ID)
+	writeJSON(w, status, EnsureAccountResponse{AccountResponse: FromAccount(acct), Created: created})
+}
+
+func (h *AccountController) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid account id", Code: "invalid_id"})
+		return
+	}
+
 	acct, err := h.accountService.GetAccount(r.Context(), id)
 	if err != nil {
 		writeError(w, err)
@@ -80,19 +129,45 @@ func (h *AccountController) Get(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, FromAccount(acct))
 }
 
-func (h *AccountController) GetBalance(w http.ResponseWriter, r *http.Request) {
+// Suspend, Activate and Deactivate change the account's status and return
+// the updated account. A transition the account's status does not allow is
+// a 409, as is a concurrent update of the account.
+func (h *AccountController) Suspend(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.accountService.SuspendAccount)
+}
+
+func (h *AccountController) Activate(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.accountService.ActivateAccount)
+}
+
+func (h *AccountController) Deactivate(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.accountService.DeactivateAccount)
+}
+
+func (h *AccountController) changeStatus(w http.ResponseWriter, r *http.Request,
+	change func(context.Context, uuid.UUID) (*account.Account, error)) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid account id", Code: "invalid_id"})
 		return
 	}
 
-	// Authorization check
-	if err := h.authzService.VerifyAccountOwnership(r.Context(), id); err != nil {
+	acct, err := change(r.Context(), id)
+	if err != nil {
 		writeError(w, err)
 		return
 	}
 
+	writeJSON(w, http.StatusOK, FromAccount(acct))
+}
+
+func (h *AccountController) GetBalance(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid account id", Code: "invalid_id"})
+		return
+	}
+
 	balanceCents, currency, err := h.accountService.GetBalance(r.Context(), id)
 	if err != nil {
 		writeError(w, err)
@@ -100,7 +175,7 @@ func (h *AccountController) GetBalance(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, http.StatusOK, BalanceResponse{
-		Balance:  centsToFloat(balanceCents),
+		Balance:  minorToFloat(balanceCents, currency),
 		Currency: currency,
 	})
 }
@@ -112,18 +187,17 @@ func (h *AccountController) GetTransactions(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Authorization check
-	if err := h.authzService.VerifyAccountOwnership(r.Context(), id); err != nil {
-		writeError(w, err)
-		return
-	}
-
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
 	if limit <= 0 {
 		limit = 20
 	}
 
+	acct, err := h.accountService.GetAccount(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 	txns, err := h.accountService.GetTransactions(r.Context(), id, limit, offset)
 	if err != nil {
 		writeError(w, err)
@@ -132,7 +206,215 @@ func (h *AccountController) GetTransactions(w http.ResponseWriter, r *http.Reque
 
 	resp := make([]*TransactionResponse, 0, len(txns))
 	for _, tx := range txns {
-		resp = append(resp, FromTransaction(tx))
+		resp = append(resp, FromTransaction(tx, acct.Currency))
 	}
 	writeJSON(w, http.StatusOK, resp)
 }
+
+// StatementSignatureHeader carries a statement's signature, in the form
+// service.StatementSignaturePrefix + hex HMAC.
+const StatementSignatureHeader = "Statement-Signature"
+
+// maxStatementSize bounds statements submitted for verification.
+const maxStatementSize = 10 << 20
+
+// Statement exports the account's transactions in [from, to). format picks
+// json (the default), csv or pdf; from defaults to the start of the history
+// and to to now. With signed=true a CSV statement carries a
+// Statement-Signature header for VerifyStatement, so it is rendered in full
+// first; unsigned CSV statements are streamed as rows are read.
+func (h *AccountController) Statement(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid account id", Code: "invalid_id"})
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = "json"
+	case "json", "csv", "pdf":
+	default:
+		writeError(w, domainErrors.NewValidationError("format", "must be json, csv or pdf"))
+		return
+	}
+	from, to := time.Unix(0, 0).UTC(), time.Now().UTC()
+	if s := r.URL.Query().Get("from"); s != "" {
+		if from, err = time.Parse(time.RFC3339, s); err != nil {
+			writeError(w, domainErrors.NewValidationError("from", "must be an RFC 3339 timestamp"))
+			return
+		}
+	}
+	if s := r.URL.Query().Get("to"); s != "" {
+		if to, err = time.Parse(time.RFC3339, s); err != nil {
+			writeError(w, domainErrors.NewValidationError("to", "must be an RFC 3339 timestamp"))
+			return
+		}
+	}
+	sign, _ := strconv.ParseBool(r.URL.Query().Get("signed"))
+	if sign && format != "csv" {
+		writeError(w, domainErrors.NewValidationError("signed", "is only supported for csv statements"))
+		return
+	}
+
+	setHeaders := func(contentType string) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%s-%s-%s.%s"`,
+			id, from.Format("20060102"), to.Format("20060102"), format))
+	}
+
+	switch {
+	case format == "json" || format == "pdf":
+		st, err := h.accountService.BuildStatement(r.Context(), id, from, to)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if format == "json" {
+			writeJSON(w, http.StatusOK, FromAccountStatement(st))
+			return
+		}
+		var buf bytes.Buffer
+		if err := statement.WritePDF(&buf, st); err != nil {
+			writeError(w, err)
+			return
+		}
+		setHeaders("application/pdf")
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+
+	case !sign:
+		started := false
+		err := h.accountService.StreamStatement(r.Context(), id, from, to, func(*account.Account) io.Writer {
+			started = true
+			setHeaders("text/csv; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			return w
+		})
+		switch {
+		case err != nil && !started:
+			writeError(w, err)
+		case err != nil:
+			// The status is sent; all that is left is to cut the download short.
+			log.Error().Err(err).Str("account_id", id.String()).Msg("statement stream failed")
+		}
+
+	default:
+		st, err := h.accountService.ExportStatement(r.Context(), id, from, to, sign)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		setHeaders("text/csv; charset=utf-8")
+		w.Header().Set(StatementSignatureHeader, st.Signature)
+		w.WriteHeader(http.StatusOK)
+		w.Write(st.Content)
+	}
+}
+
+// VerifyStatement checks a statement exported with signed=true. The body is
+// the statement exactly as downloaded and the Statement-Signature header the
+// signature it came with.
+func (h *AccountController) VerifyStatement(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(io.LimitReader(r.Body, maxStatementSize+1))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "cannot read body", Code: "invalid_body"})
+		return
+	}
+	if len(content) > maxStatementSize {
+		writeJSON(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: "statement too large", Code: "too_large"})
+		return
+	}
+
+	valid, err := h.accountService.VerifyStatement(content, r.Header.Get(StatementSignatureHeader))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, VerifyStatementResponse{Valid: valid})
+}
+
+// List enumerates accounts for admin tooling. Access is restricted by the
+// admin scope on the route.
+func (h *AccountController) List(w http.ResponseWriter, r *http.Request) {
+	accounts, _, err := h.accountService.ListAccounts(r.Context(), accountListFilter(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := make([]*AccountResponse, 0, len(accounts))
+	for _, acct := range accounts {
+		resp = append(resp, FromAccount(acct))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// VerifyLedger recomputes an account's balance from its transaction history
+// and reports whether it matches the stored one. Admin only.
+func (h *AccountController) VerifyLedger(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid account id", Code: "invalid_id"})
+		return
+	}
+
+	report, err := h.accountService.VerifyLedgerIntegrity(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, FromLedgerIntegrityReport(report))
+}
+
+// Search lists the caller's accounts. Admins may pass any user_id; for
+// everyone else user_id is forced to their own.
+func (h *AccountController) Search(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		writeError(w, domainErrors.ErrUnauthorized)
+		return
+	}
+	filter := accountListFilter(r)
+	if filter.UserID == nil || h.authzService.RequireRole(r.Context(), service.RoleAdmin) != nil {
+		filter.UserID = &userID
+	}
+
+	accounts, total, err := h.accountService.ListAccounts(r.Context(), filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := AccountListResponse{
+		Data:   make([]*AccountResponse, 0, len(accounts)),
+		Limit:  filter.PageLimit(),
+		Offset: filter.Offset,
+		Total:  total,
+	}
+	for _, acct := range accounts {
+		resp.Data = append(resp.Data, FromAccount(acct))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// accountListFilter reads the account list query parameters.
+func accountListFilter(r *http.Request) account.ListFilter {
+	q := r.URL.Query()
+	filter := account.ListFilter{SortBy: q.Get("sort_by"), SortOrder: q.Get("sort_order")}
+	if s := q.Get("user_id"); s != "" {
+		filter.UserID = &s
+	}
+	if s := q.Get("currency"); s != "" {
+		currency := strings.ToUpper(s)
+		filter.Currency = &currency
+	}
+	if s := q.Get("status"); s != "" {
+		status := account.AccountStatus(s)
+		filter.Status = &status
+	}
+	filter.Limit, _ = strconv.Atoi(q.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(q.Get("offset"))
+	return filter
+}
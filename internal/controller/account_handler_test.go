@@ -6,12 +6,17 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/cassiomorais/payments/internal/middleware"
 	"github.com/cassiomorais/payments/internal/service"
 	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 func TestAccountController_Create(t *testing.T) {
@@ -52,3 +57,299 @@ func TestAccountController_Create(t *testing.T) {
 		t.Errorf("expected user_id user123, got %s", resp.UserID)
 	}
 }
+
+func TestAccountController_List_PassesFilters(t *testing.T) {
+	mockRepo := &testutil.MockAccountRepository{}
+	handler := NewAccountController(service.NewAccountService(mockRepo), service.NewAuthzService(mockRepo))
+
+	var got account.ListFilter
+	mockRepo.ListFunc = func(ctx context.Context, filter account.ListFilter) ([]*account.Account, error) {
+		got = filter
+		acct, _ := account.NewAccount("user123", 1000, "USD")
+		return []*account.Account{acct}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/accounts?user_id=user123&currency=usd&status=active&limit=5&offset=10", nil)
+	rec := httptest.NewRecorder()
+
+	handler.List(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if got.UserID == nil || *got.UserID != "user123" {
+		t.Errorf("expected user_id filter user123, got %v", got.UserID)
+	}
+	if got.Currency == nil || *got.Currency != "USD" {
+		t.Errorf("expected currency filter USD, got %v", got.Currency)
+	}
+	if got.Status == nil || *got.Status != account.StatusActive {
+		t.Errorf("expected status filter active, got %v", got.Status)
+	}
+	if got.Limit != 5 || got.Offset != 10 {
+		t.Errorf("expected limit 5 offset 10, got %d %d", got.Limit, got.Offset)
+	}
+
+	var resp []AccountResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp) != 1 {
+		t.Errorf("expected 1 account, got %d", len(resp))
+	}
+}
+
+func TestAccountController_Search_ScopedToCaller(t *testing.T) {
+	mockRepo := testutil.NewMockAccountRepository()
+	handler := NewAccountController(service.NewAccountService(mockRepo), service.NewAuthzService(mockRepo))
+	for _, userID := range []string{"user1", "user1", "user2"} {
+		acct, _ := account.NewAccount(userID, 1000, "USD")
+		mockRepo.AddAccount(acct)
+	}
+
+	search := func(query string, scopes ...string) AccountListResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts?"+query, nil)
+		ctx := context.WithValue(req.Context(), middleware.UserIDKey, "user1")
+		ctx = context.WithValue(ctx, middleware.ScopesKey, scopes)
+		rec := httptest.NewRecorder()
+		handler.Search(rec, req.WithContext(ctx))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var resp AccountListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	resp := search("limit=1")
+	if resp.Total != 2 || len(resp.Data) != 1 || resp.Limit != 1 {
+		t.Errorf("expected 1 of the caller's 2 accounts, got %d of %d (limit %d)", len(resp.Data), resp.Total, resp.Limit)
+	}
+	for _, acct := range search("user_id=user2").Data {
+		if acct.UserID != "user1" {
+			t.Errorf("non-admin caller listed %s's account", acct.UserID)
+		}
+	}
+
+	resp = search("user_id=user2", "payments:admin")
+	if resp.Total != 1 || resp.Data[0].UserID != "user2" {
+		t.Errorf("expected admin to list user2's account, got %+v", resp)
+	}
+}
+
+func TestAccountController_Ensure(t *testing.T) {
+	mockRepo := testutil.NewMockAccountRepository()
+	handler := NewAccountController(service.NewAccountService(mockRepo), service.NewAuthzService(mockRepo))
+
+	ensure := func() (*httptest.ResponseRecorder, EnsureAccountResponse) {
+		body, _ := json.Marshal(EnsureAccountRequest{Currency: "usd"})
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "user123"))
+		rec := httptest.NewRecorder()
+		handler.Ensure(rec, req)
+
+		var resp EnsureAccountResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return rec, resp
+	}
+
+	rec, first := ensure()
+	if rec.Code != http.StatusCreated || !first.Created {
+		t.Fatalf("expected 201 with created=true, got %d created=%v", rec.Code, first.Created)
+	}
+	if first.UserID != "user123" || first.Currency != "USD" {
+		t.Errorf("unexpected account %s/%s", first.UserID, first.Currency)
+	}
+
+	rec, second := ensure()
+	if rec.Code != http.StatusOK || second.Created {
+		t.Fatalf("expected 200 with created=false, got %d created=%v", rec.Code, second.Created)
+	}
+	if second.ID != first.ID {
+		t.Errorf("expected existing account %s, got %s", first.ID, second.ID)
+	}
+}
+
+func serveStatusChange(handler http.HandlerFunc, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/"+id+"/status", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestAccountController_StatusTransitions(t *testing.T) {
+	mockRepo := testutil.NewMockAccountRepository()
+	mockRepo.RequireTx = true
+	handler := NewAccountController(
+		service.NewAccountService(mockRepo, service.WithAccountTransactions(testutil.NewMockTransactionManager())),
+		service.NewAuthzService(mockRepo))
+	acct := testutil.NewTestAccount("user123", 1000, "USD")
+	mockRepo.AddAccount(acct)
+
+	steps := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    account.AccountStatus
+	}{
+		{"suspend", handler.Suspend, account.StatusSuspended},
+		{"activate", handler.Activate, account.StatusActive},
+		{"deactivate", handler.Deactivate, account.StatusInactive},
+	}
+	for i, step := range steps {
+		rec := serveStatusChange(step.handler, acct.ID.String())
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", step.name, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var resp AccountResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", step.name, err)
+		}
+		if resp.Status != string(step.want) || resp.Version != i+1 {
+			t.Errorf("%s: expected %s at version %d, got %s at %d", step.name, step.want, i+1, resp.Status, resp.Version)
+		}
+		if stored := mockRepo.GetAccountByID(acct.ID); stored.Status != step.want {
+			t.Errorf("%s: expected stored status %s, got %s", step.name, step.want, stored.Status)
+		}
+	}
+}
+
+func TestAccountController_StatusTransitions_Conflict(t *testing.T) {
+	mockRepo := testutil.NewMockAccountRepository()
+	handler := NewAccountController(service.NewAccountService(mockRepo), service.NewAuthzService(mockRepo))
+	acct := testutil.NewTestAccount("user123", 1000, "USD")
+	mockRepo.AddAccount(acct)
+
+	// Activating an active account is not a transition.
+	rec := serveStatusChange(handler.Activate, acct.ID.String())
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusConflict, rec.Code, rec.Body.String())
+	}
+	var resp ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Code != "invalid_state_transition" {
+		t.Errorf("expected code invalid_state_transition, got %q", resp.Code)
+	}
+
+	// A concurrent update is reported the same way.
+	mockRepo.UpdateFunc = func(ctx context.Context, a *account.Account) error {
+		return domainErrors.ErrOptimisticLockFailed
+	}
+	rec = serveStatusChange(handler.Suspend, acct.ID.String())
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusConflict, rec.Code, rec.Body.String())
+	}
+
+	if rec := serveStatusChange(handler.Suspend, "not-a-uuid"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid id, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func serveStatement(handler *AccountController, id, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/"+id+"/statement"+query, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+	handler.Statement(rec, req)
+	return rec
+}
+
+func TestAccountController_Statement(t *testing.T) {
+	mockRepo := testutil.NewMockAccountRepository()
+	handler := NewAccountController(service.NewAccountService(mockRepo), service.NewAuthzService(mockRepo))
+	acct := testutil.NewTestAccount("user123", 0, "USD")
+	mockRepo.AddAccount(acct)
+	mockRepo.AddTransaction(context.Background(), &account.Transaction{
+		ID: uuid.New(), AccountID: acct.ID, TransactionType: account.TransactionCredit, Amount: 1050, BalanceAfter: 1050,
+		Description: "deposit", CreatedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	})
+
+	rec := serveStatement(handler, acct.ID.String(), "?format=csv&from=2026-03-01T00:00:00Z&to=2026-03-02T00:00:00Z")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("expected text/csv, got %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") {
+		t.Errorf("expected an attachment, got %q", cd)
+	}
+	if lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n"); len(lines) != 2 ||
+		!strings.Contains(lines[1], ",credit,10.50,USD,10.50,,deposit") {
+		t.Errorf("unexpected statement:\n%s", rec.Body.String())
+	}
+
+	rec = serveStatement(handler, acct.ID.String(), "?from=2026-03-01T00:00:00Z&to=2026-03-02T00:00:00Z")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var st AccountStatementResponse
+	if err := json.NewDecoder(rec.Body).Decode(&st); err != nil {
+		t.Fatalf("statements default to JSON: %v", err)
+	}
+	if st.OpeningBalance != 0 || st.TotalCredits != 10.5 || st.ClosingBalance != 10.5 || len(st.Transactions) != 1 {
+		t.Errorf("unexpected statement: %+v", st)
+	}
+
+	rec = serveStatement(handler, acct.ID.String(), "?format=pdf")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" ||
+		!strings.HasPrefix(rec.Body.String(), "%PDF-") {
+		t.Errorf("expected a PDF, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	for query, want := range map[string]int{
+		"?format=xml":              http.StatusBadRequest,
+		"?format=json&signed=true": http.StatusBadRequest,
+		"?from=2026-03-02T00:00:00Z&to=2026-03-01T00:00:00Z": http.StatusBadRequest,
+	} {
+		if rec := serveStatement(handler, acct.ID.String(), query); rec.Code != want {
+			t.Errorf("%s: expected status %d, got %d", query, want, rec.Code)
+		}
+	}
+	rec = serveStatement(handler, uuid.NewString(), "")
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Disposition") != "" {
+		t.Errorf("expected a plain 404 for an unknown account, got %d with headers %v", rec.Code, rec.Header())
+	}
+}
+
+func TestAccountController_VerifyLedger_ReportsDrift(t *testing.T) {
+	mockRepo := testutil.NewMockAccountRepository()
+	handler := NewAccountController(service.NewAccountService(mockRepo), service.NewAuthzService(mockRepo))
+	acct := testutil.NewTestAccount("user123", 1000, "USD")
+	acct.Balance = 1050 // changed without a transaction
+	mockRepo.AddAccount(acct)
+
+	id := acct.ID.String()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/accounts/"+id+"/verify", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+	handler.VerifyLedger(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp LedgerIntegrityResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Consistent {
+		t.Error("expected consistent=false")
+	}
+	if resp.StoredBalance != 10.50 || resp.ComputedBalance != 10.00 || resp.Difference != 0.50 {
+		t.Errorf("expected stored 10.50, computed 10.00, difference 0.50, got %v, %v, %v",
+			resp.StoredBalance, resp.ComputedBalance, resp.Difference)
+	}
+	if resp.FirstDivergence != nil {
+		t.Errorf("expected no divergent transaction, got %+v", resp.FirstDivergence)
+	}
+}
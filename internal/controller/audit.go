@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/cassiomorais/payments/internal/domain/audit"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+type auditTargetKey struct{}
+
+// auditTarget is the resource an audited request acted on. The middleware
+// seeds it from the URL; handlers that create a resource set it once they
+// know its ID.
+type auditTarget struct {
+	id *uuid.UUID
+}
+
+// setAuditTarget records id as the target of the audited request in ctx. It
+// does nothing on routes that are not audited.
+func setAuditTarget(ctx context.Context, id uuid.UUID) {
+	if t, ok := ctx.Value(auditTargetKey{}).(*auditTarget); ok {
+		t.id = &id
+	}
+}
+
+// audited records every request to the route in the audit log once it
+// completes, whatever its outcome, including requests the route's policy
+// rejects. targetParam names the URL parameter holding the target's ID; it
+// may be empty for routes that name none in the URL. A nil logger disables
+// auditing.
+func audited(logger *service.AuditLogger, op service.Operation, targetParam string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if logger == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			target := &auditTarget{}
+			if targetParam != "" {
+				if id, err := uuid.Parse(chi.URLParam(r, targetParam)); err == nil {
+					target.id = &id
+				}
+			}
+			ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), auditTargetKey{}, target)))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			userID, _ := middleware.GetUserID(r.Context())
+			logger.Log(&audit.Entry{
+				UserID:     userID,
+				Operation:  string(op),
+				TargetID:   target.id,
+				SourceIP:   sourceIP(r),
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				StatusCode: status,
+				Result:     audit.ResultOf(status),
+				RequestID:  chimw.GetReqID(r.Context()),
+			})
+		})
+	}
+}
+
+// sourceIP is the client address without its port. RealIP has already
+// replaced RemoteAddr with the forwarded address when there is one.
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
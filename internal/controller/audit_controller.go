@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cassiomorais/payments/internal/domain/audit"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/google/uuid"
+)
+
+type AuditController struct {
+	auditLogger *service.AuditLogger
+}
+
+func NewAuditController(auditLogger *service.AuditLogger) *AuditController {
+	return &AuditController{auditLogger: auditLogger}
+}
+
+// List pages through the audit log, newest first, filtered by user_id,
+// operation, target_id, result and a created_after/created_before range.
+// Access is restricted to admins on the route.
+func (h *AuditController) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := audit.ListFilter{}
+	if s := q.Get("user_id"); s != "" {
+		filter.UserID = &s
+	}
+	if s := q.Get("operation"); s != "" {
+		filter.Operation = &s
+	}
+	if s := q.Get("target_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			writeError(w, domainErrors.NewValidationError("target_id", "must be a valid UUID"))
+			return
+		}
+		filter.TargetID = &id
+	}
+	if s := q.Get("result"); s != "" {
+		result := audit.Result(s)
+		if result != audit.ResultSuccess && result != audit.ResultFailure {
+			writeError(w, domainErrors.NewValidationError("result", "must be success or failure"))
+			return
+		}
+		filter.Result = &result
+	}
+	var err error
+	if filter.CreatedAfter, err = parseTimeParam(r, "created_after"); err != nil {
+		writeError(w, err)
+		return
+	}
+	if filter.CreatedBefore, err = parseTimeParam(r, "created_before"); err != nil {
+		writeError(w, err)
+		return
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && filter.CreatedAfter.After(*filter.CreatedBefore) {
+		writeError(w, domainErrors.NewValidationError("created_after", "must not be after created_before"))
+		return
+	}
+	filter.Limit, _ = strconv.Atoi(q.Get("limit"))
+	filter.Offset, _ = strconv.Atoi(q.Get("offset"))
+
+	entries, total, err := h.auditLogger.List(r.Context(), filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := AuditLogResponse{
+		Data:   make([]*AuditEntryResponse, 0, len(entries)),
+		Limit:  filter.PageLimit(),
+		Offset: filter.Offset,
+		Total:  total,
+	}
+	for _, e := range entries {
+		resp.Data = append(resp.Data, FromAuditEntry(e))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
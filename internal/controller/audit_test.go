@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/audit"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// setupAuditRouter serves the full router with auditing over mocks holding
+// a pending payment out of an account owned by "owner".
+func setupAuditRouter(t *testing.T) (http.Handler, *service.AuditLogger, *testutil.MockAuditRepository, *payment.Payment) {
+	t.Helper()
+	accountRepo := testutil.NewMockAccountRepository()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	source := testutil.NewTestAccount("owner", 10000, "USD")
+	accountRepo.AddAccount(source)
+	p := testutil.NewTestPayment(payment.ExternalPayment, &source.ID, nil, 500, "USD")
+	if err := paymentRepo.Create(t.Context(), p); err != nil {
+		t.Fatalf("create payment: %v", err)
+	}
+
+	auditRepo := &testutil.MockAuditRepository{}
+	auditLogger := service.NewAuditLogger(auditRepo)
+	t.Cleanup(func() { auditLogger.Close(context.Background()) })
+	router := NewRouter(RouterDeps{
+		PaymentService: service.NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+			testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe"))),
+		PaymentRepo:    paymentRepo,
+		AccountService: service.NewAccountService(accountRepo),
+		Metrics:        observability.NewMetrics("test", prometheus.NewRegistry()),
+		JWTSecret:      testJWTSecret,
+		AuthzService: service.NewAuthzService(accountRepo,
+			service.WithPolicies(service.DefaultPolicies("payments:admin", "payments:support"), paymentRepo)),
+		AdminScope:   "payments:admin",
+		SupportScope: "payments:support",
+		AuditLogger:  auditLogger,
+	})
+	return router, auditLogger, auditRepo, p
+}
+
+func TestAudit_RecordsStateChanges(t *testing.T) {
+	router, auditLogger, auditRepo, p := setupAuditRouter(t)
+
+	created := serveAs(t, router, policyRoute{http.MethodPost, "", "/api/v1/accounts",
+		CreateAccountRequest{UserID: "owner", InitialBalance: 10, Currency: "EUR"}}, bearer(t, "owner"))
+	if created.Code != http.StatusCreated {
+		t.Fatalf("create account: expected status %d, got %d: %s", http.StatusCreated, created.Code, created.Body.String())
+	}
+	denied := serveAs(t, router, policyRoute{http.MethodPost, "", "/api/v1/payments/" + p.ID.String() + "/cancel", nil},
+		bearer(t, "intruder"))
+	if denied.Code != http.StatusForbidden {
+		t.Fatalf("cancel: expected status %d, got %d", http.StatusForbidden, denied.Code)
+	}
+	serveAs(t, router, policyRoute{http.MethodGet, "", "/api/v1/payments/" + p.ID.String(), nil}, bearer(t, "owner"))
+
+	if err := auditLogger.Close(context.Background()); err != nil {
+		t.Fatalf("close audit logger: %v", err)
+	}
+	entries := auditRepo.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 audit entries (reads are not audited), got %d", len(entries))
+	}
+
+	create := entries[0]
+	if create.Operation != string(service.OpCreateAccount) || create.UserID != "owner" ||
+		create.Result != audit.ResultSuccess || create.StatusCode != http.StatusCreated {
+		t.Errorf("unexpected create entry: %+v", create)
+	}
+	if create.TargetID == nil {
+		t.Error("expected the created account as the create entry's target")
+	}
+	if create.SourceIP == "" || create.Method != http.MethodPost || create.Path != "/api/v1/accounts" {
+		t.Errorf("expected request details on the create entry, got %+v", create)
+	}
+
+	cancel := entries[1]
+	if cancel.Operation != string(service.OpCancelPayment) || cancel.UserID != "intruder" ||
+		cancel.Result != audit.ResultFailure || cancel.StatusCode != http.StatusForbidden {
+		t.Errorf("unexpected cancel entry: %+v", cancel)
+	}
+	if cancel.TargetID == nil || *cancel.TargetID != p.ID {
+		t.Errorf("expected cancel target %s, got %v", p.ID, cancel.TargetID)
+	}
+}
+
+func TestAuditController_List(t *testing.T) {
+	router, _, auditRepo, p := setupAuditRouter(t)
+	auditRepo.InsertBatch(t.Context(), []*audit.Entry{
+		{UserID: "owner", Operation: string(service.OpRefundPayment), TargetID: &p.ID, Result: audit.ResultSuccess},
+		{UserID: "owner", Operation: string(service.OpCancelPayment), TargetID: &p.ID, Result: audit.ResultFailure},
+	})
+
+	rec := serveAs(t, router, policyRoute{http.MethodGet, "", "/api/v1/admin/audit-log?result=failure&target_id=" + p.ID.String(), nil},
+		bearer(t, "staff", "payments:admin"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp AuditLogResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 1 || len(resp.Data) != 1 || resp.Data[0].Operation != string(service.OpCancelPayment) {
+		t.Errorf("expected only the failed cancel, got %+v", resp)
+	}
+
+	rec = serveAs(t, router, policyRoute{http.MethodGet, "", "/api/v1/admin/audit-log?result=maybe", nil},
+		bearer(t, "staff", "payments:admin"))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid result: expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestAdjustBalance_AdminOnlyAndAudited(t *testing.T) {
+	router, auditLogger, auditRepo, p := setupAuditRouter(t)
+	path := "/api/v1/admin/accounts/" + p.SourceAccountID.String() + "/adjust"
+	admin := bearer(t, "staff", "payments:admin")
+
+	rec := serveAs(t, router, policyRoute{http.MethodPost, "", path,
+		AdjustBalanceRequest{AmountCents: 2500, Type: "debit", Reason: "clawback"}}, admin)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp AdjustBalanceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Account.Balance != 75 || resp.Transaction.TransactionType != "adjustment_debit" ||
+		resp.Transaction.Description != "clawback" || resp.Transaction.PaymentID != nil {
+		t.Errorf("unexpected adjustment: account %+v, transaction %+v", resp.Account, resp.Transaction)
+	}
+
+	rec = serveAs(t, router, policyRoute{http.MethodPost, "", path,
+		AdjustBalanceRequest{AmountCents: 7501, Type: "debit", Reason: "clawback"}}, admin)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("debit beyond the balance: expected status %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+	rec = serveAs(t, router, policyRoute{http.MethodPost, "", path,
+		AdjustBalanceRequest{AmountCents: 100, Type: "credit", Reason: "goodwill"}}, bearer(t, "owner"))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("account owner: expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+
+	if err := auditLogger.Close(context.Background()); err != nil {
+		t.Fatalf("close audit logger: %v", err)
+	}
+	entries := auditRepo.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 audit entries, got %d", len(entries))
+	}
+	adjust := entries[0]
+	if adjust.Operation != string(service.OpAdjustBalance) || adjust.UserID != "staff" || adjust.Result != audit.ResultSuccess {
+		t.Errorf("unexpected adjustment entry: %+v", adjust)
+	}
+	if adjust.TargetID == nil || *adjust.TargetID != *p.SourceAccountID {
+		t.Errorf("expected adjustment target %s, got %v", p.SourceAccountID, adjust.TargetID)
+	}
+}
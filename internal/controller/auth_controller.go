@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/service"
+)
+
+type AuthController struct {
+	tokenService *service.TokenService
+}
+
+func NewAuthController(tokenService *service.TokenService) *AuthController {
+	return &AuthController{tokenService: tokenService}
+}
+
+// Token exchanges client credentials for an access and a refresh token.
+func (h *AuthController) Token(w http.ResponseWriter, r *http.Request) {
+	var req TokenRequest
+	if err := decodeAndValidate(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	pair, err := h.tokenService.IssueToken(r.Context(), req.ClientID, req.ClientSecret)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fromTokenPair(pair))
+}
+
+// Refresh exchanges a refresh token for a new token pair; the presented
+// refresh token is revoked.
+func (h *AuthController) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := decodeAndValidate(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	pair, err := h.tokenService.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fromTokenPair(pair))
+}
+
+// Revoke revokes a refresh token (204 No Content).
+func (h *AuthController) Revoke(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := decodeAndValidate(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := h.tokenService.Revoke(r.Context(), req.RefreshToken); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func fromTokenPair(p *service.TokenPair) TokenResponse {
+	now := time.Now()
+	return TokenResponse{
+		AccessToken:      p.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(p.AccessExpiresAt.Sub(now).Round(time.Second) / time.Second),
+		RefreshToken:     p.RefreshToken,
+		RefreshExpiresIn: int64(p.RefreshExpiresAt.Sub(now).Round(time.Second) / time.Second),
+	}
+}
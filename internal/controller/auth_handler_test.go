@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/auth"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func setupAuthRouter() http.Handler {
+	accountRepo := testutil.NewMockAccountRepository()
+	accountRepo.AddAccount(testutil.NewTestAccount("user1", 10000, "USD"))
+	return NewRouter(RouterDeps{
+		AccountService: service.NewAccountService(accountRepo),
+		AuthzService:   service.NewAuthzService(accountRepo),
+		Metrics:        observability.NewMetrics("test", prometheus.NewRegistry()),
+		JWTSecret:      testJWTSecret,
+		TokenService: service.NewTokenService(testutil.NewMockRefreshTokenRepository(), []byte(testJWTSecret),
+			service.WithClients(map[string]service.Client{
+				"backoffice": {SecretHash: auth.HashToken("s3cret"), UserID: "user1"},
+			})),
+	})
+}
+
+func postJSON(h http.Handler, path string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(body)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, &buf))
+	return rec
+}
+
+func TestAuth_IssueRefreshAndUseToken(t *testing.T) {
+	router := setupAuthRouter()
+
+	rec := postJSON(router, "/api/v1/auth/token", TokenRequest{ClientID: "backoffice", ClientSecret: "s3cret"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var issued TokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&issued); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if issued.TokenType != "Bearer" || issued.AccessToken == "" || issued.RefreshToken == "" || issued.ExpiresIn <= 0 {
+		t.Fatalf("unexpected token response: %+v", issued)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts", nil)
+	req.Header.Set("Authorization", "Bearer "+issued.AccessToken)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("issued token: expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	rec = postJSON(router, "/api/v1/auth/refresh", RefreshTokenRequest{RefreshToken: issued.RefreshToken})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh: expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	rec = postJSON(router, "/api/v1/auth/refresh", RefreshTokenRequest{RefreshToken: issued.RefreshToken})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("reused refresh token: expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	var resp ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Code != "invalid_refresh_token" {
+		t.Errorf("expected code invalid_refresh_token, got %q", resp.Code)
+	}
+}
+
+func TestAuth_InvalidCredentials(t *testing.T) {
+	rec := postJSON(setupAuthRouter(), "/api/v1/auth/token", TokenRequest{ClientID: "backoffice", ClientSecret: "wrong"})
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	var resp ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Code != "invalid_credentials" {
+		t.Errorf("expected code invalid_credentials, got %q", resp.Code)
+	}
+}
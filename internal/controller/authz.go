@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// resourceFunc extracts the resource a policy is checked against. A nil ID
+// means the request names none; ok is false when the request is malformed
+// and a response has already been written.
+type resourceFunc func(w http.ResponseWriter, r *http.Request) (id *uuid.UUID, ok bool)
+
+// urlParamID reads the resource from a UUID URL parameter.
+func urlParamID(param, name string) resourceFunc {
+	return func(w http.ResponseWriter, r *http.Request) (*uuid.UUID, bool) {
+		id, err := uuid.Parse(chi.URLParam(r, param))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid " + name, Code: "invalid_id"})
+			return nil, false
+		}
+		return &id, true
+	}
+}
+
+// queryID reads the resource from an optional UUID query parameter.
+func queryID(param string) resourceFunc {
+	return func(w http.ResponseWriter, r *http.Request) (*uuid.UUID, bool) {
+		s := r.URL.Query().Get(param)
+		if s == "" {
+			return nil, true
+		}
+		id, err := uuid.Parse(s)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid " + param, Code: "invalid_id"})
+			return nil, false
+		}
+		return &id, true
+	}
+}
+
+// requireRole rejects callers without role with 403 before the handler runs,
+// on top of the route's policy.
+func requireRole(authz *service.AuthzService, role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := authz.RequireRole(r.Context(), role); err != nil {
+				writeError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authorize enforces op's policy before the handler runs. resource may be nil
+// for operations that take none. Operations whose resource is in the request
+// body are authorized by their handler instead.
+func authorize(authz *service.AuthzService, op service.Operation, resource resourceFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var id *uuid.UUID
+			if resource != nil {
+				var ok bool
+				if id, ok = resource(w, r); !ok {
+					return
+				}
+			}
+			if err := authz.Authorize(r.Context(), op, id); err != nil {
+				writeError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const testJWTSecret = "test-secret-0123456789abcdef01234"
+
+type policyRoute struct {
+	method, pattern, path string
+	body                  any
+}
+
+// setupPolicyRouter serves the full router over mocks holding an account
+// owned by "owner" and a transfer from it to another user's account.
+func setupPolicyRouter(t *testing.T) (http.Handler, []policyRoute) {
+	t.Helper()
+	accountRepo := testutil.NewMockAccountRepository()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	source := testutil.NewTestAccount("owner", 10000, "USD")
+	dest := testutil.NewTestAccount("payee", 0, "USD")
+	accountRepo.AddAccount(source)
+	accountRepo.AddAccount(dest)
+	p := testutil.NewTestPayment(payment.InternalTransfer, &source.ID, &dest.ID, 500, "USD")
+	if err := paymentRepo.Create(t.Context(), p); err != nil {
+		t.Fatalf("create payment: %v", err)
+	}
+
+	auditLogger := service.NewAuditLogger(&testutil.MockAuditRepository{})
+	t.Cleanup(func() { auditLogger.Close(context.Background()) })
+
+	router := NewRouter(RouterDeps{
+		AuditLogger:    auditLogger,
+		PaymentRepo:    paymentRepo,
+		AccountService: service.NewAccountService(accountRepo),
+		Metrics:        observability.NewMetrics("test", prometheus.NewRegistry()),
+		JWTSecret:      testJWTSecret,
+		AuthzService: service.NewAuthzService(accountRepo,
+			service.WithPolicies(service.DefaultPolicies("payments:admin", "payments:support"), paymentRepo)),
+		AdminScope:   "payments:admin",
+		SupportScope: "payments:support",
+	})
+
+	src, pid := source.ID.String(), p.ID.String()
+	routes := []policyRoute{
+		{http.MethodGet, "/api/v1/accounts/{id}", "/api/v1/accounts/" + src, nil},
+		{http.MethodGet, "/api/v1/accounts/{id}/balance", "/api/v1/accounts/" + src + "/balance", nil},
+		{http.MethodGet, "/api/v1/accounts/{id}/transactions", "/api/v1/accounts/" + src + "/transactions", nil},
+		{http.MethodGet, "/api/v1/accounts/{id}/statement", "/api/v1/accounts/" + src + "/statement", nil},
+		{http.MethodPost, "/api/v1/accounts/{id}/suspend", "/api/v1/accounts/" + src + "/suspend", nil},
+		{http.MethodPost, "/api/v1/accounts/{id}/activate", "/api/v1/accounts/" + src + "/activate", nil},
+		{http.MethodPost, "/api/v1/accounts/{id}/deactivate", "/api/v1/accounts/" + src + "/deactivate", nil},
+		{http.MethodPost, "/api/v1/payments", "/api/v1/payments", CreatePaymentRequest{
+			PaymentType: "internal_transfer", SourceAccountID: &src, Amount: 1, Currency: "USD"}},
+		{http.MethodPost, "/api/v1/payments/batch", "/api/v1/payments/batch", BatchPaymentRequest{
+			Payments: []CreatePaymentRequest{{PaymentType: "internal_transfer", SourceAccountID: &src, Amount: 1, Currency: "USD"}}}},
+		{http.MethodGet, "/api/v1/payments/dlq", "/api/v1/payments/dlq", nil},
+		{http.MethodPost, "/api/v1/payments/dlq/{entryID}/replay", "/api/v1/payments/dlq/1700000000000-0/replay", nil},
+		{http.MethodGet, "/api/v1/payments/{id}", "/api/v1/payments/" + pid, nil},
+		{http.MethodGet, "/api/v1/payments/{id}/events", "/api/v1/payments/" + pid + "/events", nil},
+		{http.MethodGet, "/api/v1/payments/{id}/timeline", "/api/v1/payments/" + pid + "/timeline", nil},
+		{http.MethodGet, "/api/v1/payments", "/api/v1/payments?account_id=" + src, nil},
+		{http.MethodPost, "/api/v1/payments/{id}/refund", "/api/v1/payments/" + pid + "/refund", nil},
+		{http.MethodPost, "/api/v1/payments/{id}/cancel", "/api/v1/payments/" + pid + "/cancel", nil},
+		{http.MethodGet, "/api/v1/payments/{id}/disputes", "/api/v1/payments/" + pid + "/disputes", nil},
+		{http.MethodPost, "/api/v1/transfers", "/api/v1/transfers", TransferRequest{
+			SourceAccountID: src, DestinationAccountID: dest.ID.String(), Amount: 1, Currency: "USD"}},
+		{http.MethodPost, "/api/v1/transfers/to-new-account", "/api/v1/transfers/to-new-account",
+			TransferToNewAccountRequest{SourceAccountID: src, Amount: 1, Currency: "USD"}},
+		{http.MethodGet, "/api/v1/admin/accounts", "/api/v1/admin/accounts", nil},
+		{http.MethodGet, "/api/v1/admin/accounts/{id}/verify", "/api/v1/admin/accounts/" + src + "/verify", nil},
+		{http.MethodPost, "/api/v1/admin/accounts/{id}/adjust", "/api/v1/admin/accounts/" + src + "/adjust",
+			AdjustBalanceRequest{AmountCents: 100, Type: "credit", Reason: "goodwill"}},
+		{http.MethodPost, "/api/v1/admin/payments/{id}/reemit-events", "/api/v1/admin/payments/" + pid + "/reemit-events", nil},
+		{http.MethodPost, "/api/v1/admin/payments/{id}/approve", "/api/v1/admin/payments/" + pid + "/approve", nil},
+		{http.MethodPost, "/api/v1/admin/payments/{id}/reject", "/api/v1/admin/payments/" + pid + "/reject",
+			RejectReviewRequest{Reason: "fraud confirmed"}},
+		{http.MethodGet, "/api/v1/admin/circuit-breakers", "/api/v1/admin/circuit-breakers", nil},
+		{http.MethodPost, "/api/v1/admin/circuit-breakers/{provider}/reset", "/api/v1/admin/circuit-breakers/stripe/reset", nil},
+		{http.MethodGet, "/api/v1/admin/audit-log", "/api/v1/admin/audit-log", nil},
+	}
+	return router, routes
+}
+
+func bearer(t *testing.T, userID string, scopes ...string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{UserID: userID, Scopes: scopes}).
+		SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return "Bearer " + token
+}
+
+func serveAs(t *testing.T, h http.Handler, rt policyRoute, authorization string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	if rt.body != nil {
+		json.NewEncoder(&body).Encode(rt.body)
+	}
+	req := httptest.NewRequest(rt.method, rt.path, &body)
+	req.Header.Set("Authorization", authorization)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouter_ProtectedRoutesEnforcePolicies(t *testing.T) {
+	router, routes := setupPolicyRouter(t)
+	intruder := bearer(t, "intruder")
+
+	for _, rt := range routes {
+		t.Run(rt.method+" "+rt.pattern, func(t *testing.T) {
+			rec := serveAs(t, router, rt, intruder)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
+			}
+			var resp ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if resp.Code != "forbidden" {
+				t.Errorf("expected code forbidden, got %q", resp.Code)
+			}
+		})
+	}
+}
+
+func TestRouter_PoliciesAdmitPermittedCallers(t *testing.T) {
+	router, routes := setupPolicyRouter(t)
+	byPattern := map[string]policyRoute{}
+	for _, rt := range routes {
+		byPattern[rt.pattern] = rt
+	}
+
+	rec := serveAs(t, router, byPattern["/api/v1/accounts/{id}"], bearer(t, "owner"))
+	if rec.Code != http.StatusOK {
+		t.Errorf("owner: expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	rec = serveAs(t, router, byPattern["/api/v1/accounts/{id}"], bearer(t, "staff", "payments:admin"))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("admin on account read: expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+	rec = serveAs(t, router, byPattern["/api/v1/payments/{id}"], bearer(t, "payee"))
+	if rec.Code != http.StatusOK {
+		t.Errorf("payee: expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	rec = serveAs(t, router, byPattern["/api/v1/payments/{id}"], bearer(t, "staff", "payments:support"))
+	if rec.Code != http.StatusOK {
+		t.Errorf("support: expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func bearerWithRoles(t *testing.T, userID string, roles ...string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{UserID: userID, Roles: roles}).
+		SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return "Bearer " + token
+}
+
+func TestRouter_AdminRoutesRequireAdminRole(t *testing.T) {
+	router, routes := setupPolicyRouter(t)
+	byPattern := map[string]policyRoute{}
+	for _, rt := range routes {
+		byPattern[rt.pattern] = rt
+	}
+	adminOnly := []string{
+		"/api/v1/admin/accounts",
+		"/api/v1/admin/accounts/{id}/verify",
+		"/api/v1/admin/accounts/{id}/adjust",
+		"/api/v1/payments/dlq/{entryID}/replay",
+		"/api/v1/admin/circuit-breakers/{provider}/reset",
+		"/api/v1/admin/audit-log",
+	}
+
+	for _, pattern := range adminOnly {
+		rt := byPattern[pattern]
+		for _, tc := range []struct {
+			name  string
+			token string
+			want  int
+		}{
+			{"support role", bearerWithRoles(t, "staff", "support"), http.StatusForbidden},
+			{"no role", bearer(t, "staff"), http.StatusForbidden},
+			{"admin role", bearerWithRoles(t, "staff", "admin"), 0},
+			{"admin scope", bearer(t, "staff", "payments:admin"), 0},
+		} {
+			rec := serveAs(t, router, rt, tc.token)
+			if tc.want == http.StatusForbidden {
+				var resp ErrorResponse
+				json.NewDecoder(rec.Body).Decode(&resp)
+				if rec.Code != http.StatusForbidden || resp.Code != "forbidden" {
+					t.Errorf("%s on %s: expected 403 forbidden, got %d %q", tc.name, pattern, rec.Code, resp.Code)
+				}
+			} else if rec.Code == http.StatusForbidden || rec.Code == http.StatusUnauthorized {
+				t.Errorf("%s on %s: expected access, got %d", tc.name, pattern, rec.Code)
+			}
+		}
+	}
+}
+
+func TestRouter_PolicyResourceErrors(t *testing.T) {
+	router, _ := setupPolicyRouter(t)
+	owner := bearer(t, "owner")
+
+	rec := serveAs(t, router, policyRoute{method: http.MethodGet, path: "/api/v1/accounts/not-a-uuid"}, owner)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid id: expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	rec = serveAs(t, router, policyRoute{method: http.MethodGet, path: "/api/v1/payments/00000000-0000-0000-0000-000000000001"}, owner)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing payment: expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	rec = serveAs(t, router, policyRoute{method: http.MethodGet, path: "/api/v1/accounts"}, "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
+
+// TestRouter_EveryProtectedRouteCovered fails when an /api/v1 route is added
+// without an entry in the policy enforcement table above. Routes any
+// authenticated caller may use are listed here instead.
+func TestRouter_EveryProtectedRouteCovered(t *testing.T) {
+	router, routes := setupPolicyRouter(t)
+	covered := map[string]bool{
+		"POST /api/v1/accounts":          true,
+		"GET /api/v1/accounts":           true,
+		"PUT /api/v1/accounts":           true,
+		"POST /api/v1/statements/verify": true,
+	}
+	for _, rt := range routes {
+		covered[rt.method+" "+rt.pattern] = true
+	}
+
+	err := chi.Walk(router.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.TrimSuffix(route, "/")
+		if strings.HasPrefix(route, "/api/v1/") && !covered[method+" "+route] {
+			t.Errorf("route %s %s has no policy test", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// DisputeSignatureHeader carries "sha256=<hex HMAC of the body>" on provider
+// dispute notifications.
+const DisputeSignatureHeader = "X-Webhook-Signature"
+
+type DisputeController struct {
+	paymentService *service.PaymentService
+	webhookSecret  []byte
+}
+
+func NewDisputeController(paymentService *service.PaymentService, webhookSecret string) *DisputeController {
+	return &DisputeController{
+		paymentService: paymentService,
+		webhookSecret:  []byte(webhookSecret),
+	}
+}
+
+// Notify ingests a provider dispute notification. The body must be signed with
+// the shared webhook secret; without a configured secret the endpoint is off.
+func (h *DisputeController) Notify(w http.ResponseWriter, r *http.Request) {
+	if len(h.webhookSecret) == 0 {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "dispute notifications are disabled", Code: "not_found"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, middleware.MaxBodyBytes(r.Context())))
+	if err != nil {
+		if tooLarge := payloadTooLarge(err); tooLarge != nil {
+			writeError(w, tooLarge)
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "failed to read body", Code: "invalid_request"})
+		return
+	}
+	if !h.validSignature(body, r.Header.Get(DisputeSignatureHeader)) {
+		writeError(w, domainErrors.ErrInvalidSignature)
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	var req DisputeNotificationRequest
+	if err := decodeAndValidate(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	paymentID := uuid.MustParse(req.PaymentID) // validated as uuid
+	var amountCents int64                      // zero disputes the full payment amount
+	if req.Amount > 0 {
+		code, err := h.paymentService.DisputeCurrency(r.Context(), paymentID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if amountCents, err = floatToMinor(req.Amount, code); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	d, err := h.paymentService.HandleDispute(r.Context(), service.DisputeNotification{
+		Provider:          payment.Provider(chi.URLParam(r, "provider")),
+		ProviderDisputeID: req.DisputeID,
+		PaymentID:         paymentID,
+		Event:             service.DisputeEvent(req.Event),
+		Reason:            req.Reason,
+		Amount:            amountCents,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, FromDispute(d))
+}
+
+func (h *DisputeController) List(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid payment id", Code: "invalid_id"})
+		return
+	}
+
+	disputes, err := h.paymentService.ListDisputes(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := make([]*DisputeResponse, 0, len(disputes))
+	for _, d := range disputes {
+		resp = append(resp, FromDispute(d))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *DisputeController) validSignature(body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, h.webhookSecret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/go-chi/chi/v5"
+)
+
+func setupDisputeController(t *testing.T) (*DisputeController, *payment.Payment) {
+	t.Helper()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	paymentService := service.NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(),
+		service.WithDisputes(testutil.NewMockDisputeRepository(), service.DisputeHold))
+
+	p := testutil.NewCompletedPayment(payment.ExternalPayment, nil, nil, 5000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	paymentRepo.Create(context.Background(), p)
+
+	return NewDisputeController(paymentService, "test-secret"), p
+}
+
+func disputeRequest(body []byte, signature string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/providers/stripe/disputes", bytes.NewReader(body))
+	req.Header.Set(DisputeSignatureHeader, signature)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("provider", "stripe")
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestDisputeController_Notify_ValidSignature(t *testing.T) {
+	handler, p := setupDisputeController(t)
+	body, _ := json.Marshal(DisputeNotificationRequest{DisputeID: "dp_1", PaymentID: p.ID.String(), Event: "opened"})
+
+	rec := httptest.NewRecorder()
+	handler.Notify(rec, disputeRequest(body, sign("test-secret", body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp DisputeResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Status != "open" || resp.Amount != 50.0 {
+		t.Errorf("unexpected dispute response: %+v", resp)
+	}
+}
+
+func TestDisputeController_Notify_InvalidSignature(t *testing.T) {
+	handler, p := setupDisputeController(t)
+	body, _ := json.Marshal(DisputeNotificationRequest{DisputeID: "dp_1", PaymentID: p.ID.String(), Event: "opened"})
+
+	rec := httptest.NewRecorder()
+	handler.Notify(rec, disputeRequest(body, sign("wrong-secret", body)))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d: %s", http.StatusUnauthorized, rec.Code, rec.Body.String())
+	}
+}
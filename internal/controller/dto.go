@@ -3,11 +3,19 @@ package controller
 import (
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/account"
+	"github.com/cassiomorais/payments/internal/domain/audit"
 	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/outbox"
 	"github.com/cassiomorais/payments/internal/domain/payment"
+	infraRedis "github.com/cassiomorais/payments/internal/infrastructure/redis"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/cassiomorais/payments/pkg/currency"
 	"github.com/google/uuid"
 )
 
@@ -16,6 +24,15 @@ type CreateAccountRequest struct {
 	UserID         string  `json:"user_id" validate:"required"`
 	InitialBalance float64 `json:"initial_balance" validate:"gte=0,lte=922337203685477.0"`
 	Currency       string  `json:"currency" validate:"required,len=3"`
+	Label          string  `json:"label,omitempty" validate:"max=64"`
+}
+
+// EnsureAccountRequest is the body of PUT /accounts. The account owner is
+// always the authenticated user.
+type EnsureAccountRequest struct {
+	InitialBalance float64 `json:"initial_balance" validate:"gte=0,lte=922337203685477.0"`
+	Currency       string  `json:"currency" validate:"required,len=3"`
+	Label          string  `json:"label,omitempty" validate:"max=64"`
 }
 
 type CreatePaymentRequest struct {
@@ -23,29 +40,171 @@ type CreatePaymentRequest struct {
 	SourceAccountID      *string `json:"source_account_id,omitempty"`
 	DestinationAccountID *string `json:"destination_account_id,omitempty"`
 	Amount               float64 `json:"amount" validate:"required,gt=0,lte=922337203685477.0"`
-	Currency             string  `json:"currency" validate:"required,len=3"`
+	Currency             string  `json:"currency,omitempty" validate:"omitempty,len=3"`
 	Provider             *string `json:"provider,omitempty"`
+	Description          string  `json:"description,omitempty" validate:"max=255"`
+	// ExchangeRate converts an internal transfer into the destination
+	// account's currency; required when the currencies differ.
+	ExchangeRate *float64 `json:"exchange_rate,omitempty" validate:"omitempty,gt=0"`
+	// ScheduledAt (RFC 3339) defers the payment until that time.
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	// ProviderOptions holds provider-specific fields keyed by provider name,
+	// e.g. {"stripe": {"customer_id": "cus_123"}}.
+	ProviderOptions map[string]map[string]string `json:"provider_options,omitempty"`
+	// Fee is a platform fee deducted from an external payment; the
+	// destination account is credited the rest.
+	Fee float64 `json:"fee,omitempty" validate:"omitempty,gte=0,lte=922337203685477.0"`
+}
+
+// BatchPaymentRequest submits several payments at once. The batch size is
+// capped by the service (payment.max_batch_size).
+type BatchPaymentRequest struct {
+	Payments []CreatePaymentRequest `json:"payments" validate:"required,min=1"`
 }
 
 type TransferRequest struct {
-	SourceAccountID      string  `json:"source_account_id" validate:"required,uuid"`
-	DestinationAccountID string  `json:"destination_account_id" validate:"required,uuid"`
-	Amount               float64 `json:"amount" validate:"required,gt=0,lte=922337203685477.0"`
-	Currency             string  `json:"currency" validate:"required,len=3"`
+	SourceAccountID      string   `json:"source_account_id" validate:"required,uuid"`
+	DestinationAccountID string   `json:"destination_account_id" validate:"required,uuid"`
+	Amount               float64  `json:"amount" validate:"required,gt=0,lte=922337203685477.0"`
+	Currency             string   `json:"currency,omitempty" validate:"omitempty,len=3"`
+	Description          string   `json:"description,omitempty" validate:"max=255"`
+	ExchangeRate         *float64 `json:"exchange_rate,omitempty" validate:"omitempty,gt=0"`
+}
+
+// TransferToNewAccountRequest funds DestinationUserID's account in Currency,
+// creating it if needed. DestinationUserID defaults to the caller.
+type TransferToNewAccountRequest struct {
+	SourceAccountID   string  `json:"source_account_id" validate:"required,uuid"`
+	DestinationUserID string  `json:"destination_user_id,omitempty" validate:"max=255"`
+	Amount            float64 `json:"amount" validate:"required,gt=0,lte=922337203685477.0"`
+	Currency          string  `json:"currency,omitempty" validate:"omitempty,len=3"`
+	Description       string  `json:"description,omitempty" validate:"max=255"`
+}
+
+// DisputeNotificationRequest is the body providers POST to report dispute
+// lifecycle changes.
+type DisputeNotificationRequest struct {
+	DisputeID string  `json:"dispute_id" validate:"required"`
+	PaymentID string  `json:"payment_id" validate:"required,uuid"`
+	Event     string  `json:"event" validate:"required,oneof=opened won lost"`
+	Reason    string  `json:"reason,omitempty"`
+	Amount    float64 `json:"amount,omitempty" validate:"gte=0,lte=922337203685477.0"`
+}
+
+// RejectReviewRequest records why a payment held for review was rejected.
+type RejectReviewRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// AdjustBalanceRequest is a manual credit or debit of an account by an admin.
+type AdjustBalanceRequest struct {
+	AmountCents int64  `json:"amount_cents" validate:"required,gt=0"`
+	Type        string `json:"type" validate:"required,oneof=credit debit"`
+	Reason      string `json:"reason" validate:"required,max=500"`
 }
 
+// AdjustBalanceResponse is the adjusted account and the transaction recording
+// the adjustment.
+type AdjustBalanceResponse struct {
+	Account     *AccountResponse     `json:"account"`
+	Transaction *TransactionResponse `json:"transaction"`
+}
 
 type AccountResponse struct {
 	ID        string    `json:"id"`
 	UserID    string    `json:"user_id"`
 	Balance   float64   `json:"balance"`
 	Currency  string    `json:"currency"`
+	Label     string    `json:"label"`
 	Status    string    `json:"status"`
 	Version   int       `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// AccountListResponse is a page of accounts; Total counts every account
+// matching the filters.
+type AccountListResponse struct {
+	Data   []*AccountResponse `json:"data"`
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+	Total  int                `json:"total"`
+}
+
+type TransferToNewAccountResponse struct {
+	Payment            *PaymentResponse `json:"payment"`
+	DestinationAccount *AccountResponse `json:"destination_account"`
+	DestinationCreated bool             `json:"destination_created"`
+}
+
+// EnsureAccountResponse reports whether PUT /accounts created the account.
+type EnsureAccountResponse struct {
+	*AccountResponse
+	Created bool `json:"created"`
+}
+
+// TokenRequest is the body of POST /auth/token.
+type TokenRequest struct {
+	ClientID     string `json:"client_id" validate:"required"`
+	ClientSecret string `json:"client_secret" validate:"required"`
+}
+
+// RefreshTokenRequest is the body of POST /auth/refresh and /auth/revoke.
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// TokenResponse carries a bearer access token and the refresh token that
+// renews it. Lifetimes are in seconds.
+type TokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in"`
+}
+
+type VerifyStatementResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// LedgerIntegrityResponse reports whether an account's stored balance agrees
+// with the balance its transaction history adds up to.
+type LedgerIntegrityResponse struct {
+	AccountID        string                    `json:"account_id"`
+	Currency         string                    `json:"currency"`
+	Consistent       bool                      `json:"consistent"`
+	InitialBalance   float64                   `json:"initial_balance"`
+	StoredBalance    float64                   `json:"stored_balance"`
+	ComputedBalance  float64                   `json:"computed_balance"`
+	Difference       float64                   `json:"difference"` // stored less computed
+	TransactionCount int                       `json:"transaction_count"`
+	FirstDivergence  *LedgerDivergenceResponse `json:"first_divergence,omitempty"`
+}
+
+// LedgerDivergenceResponse is the oldest transaction whose recorded
+// balance_after disagrees with the recomputed running balance.
+type LedgerDivergenceResponse struct {
+	TransactionID        string    `json:"transaction_id"`
+	CreatedAt            time.Time `json:"created_at"`
+	ExpectedBalanceAfter float64   `json:"expected_balance_after"`
+	RecordedBalanceAfter float64   `json:"recorded_balance_after"`
+}
+
+// AccountStatementResponse is the JSON form of a statement: the period's
+// balances and totals followed by its transactions, oldest first.
+type AccountStatementResponse struct {
+	AccountID      string                 `json:"account_id"`
+	Currency       string                 `json:"currency"`
+	From           time.Time              `json:"from"`
+	To             time.Time              `json:"to"`
+	OpeningBalance float64                `json:"opening_balance"`
+	ClosingBalance float64                `json:"closing_balance"`
+	TotalDebits    float64                `json:"total_debits"`
+	TotalCredits   float64                `json:"total_credits"`
+	Transactions   []*TransactionResponse `json:"transactions"`
+}
+
 type BalanceResponse struct {
 	Balance  float64 `json:"balance"`
 	Currency string  `json:"currency"`
@@ -70,6 +229,16 @@ type PaymentResponse struct {
 	DestinationAccountID   *string                `json:"destination_account_id,omitempty"`
 	Amount                 float64                `json:"amount"`
 	Currency               string                 `json:"currency"`
+	Fee                    float64                `json:"fee,omitempty"`
+	// NetAmount is what the destination of an external payment with a
+	// platform fee is credited: Amount less Fee.
+	NetAmount              *float64               `json:"net_amount,omitempty"`
+	// Set for cross-currency transfers: the destination is credited
+	// CreditedAmount in CreditedCurrency.
+	ExchangeRate           *float64               `json:"exchange_rate,omitempty"`
+	CreditedAmount         *float64               `json:"credited_amount,omitempty"`
+	CreditedCurrency       string                 `json:"credited_currency,omitempty"`
+	Description            string                 `json:"description,omitempty"`
 	Status                 string                 `json:"status"`
 	Provider               *string                `json:"provider,omitempty"`
 	ProviderTransactionID  *string                `json:"provider_transaction_id,omitempty"`
@@ -80,11 +249,157 @@ type PaymentResponse struct {
 	CreatedAt              time.Time              `json:"created_at"`
 	UpdatedAt              time.Time              `json:"updated_at"`
 	CompletedAt            *time.Time             `json:"completed_at,omitempty"`
+
+	FailedAt    *time.Time `json:"failed_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	RefundedAt  *time.Time `json:"refunded_at,omitempty"`
+
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	// NextRetryAt is when a failed payment will next be retried.
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	// BatchID is set for payments created through the batch endpoint.
+	BatchID *string `json:"batch_id,omitempty"`
+}
+
+// PaymentListResponse is one page of payments. Total counts every payment
+// matching the filters, so a client knows whether more pages follow.
+type PaymentListResponse struct {
+	Data   []*PaymentResponse `json:"data"`
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+	Total  int                `json:"total"`
+}
+
+// AuditEntryResponse is one audit log entry.
+type AuditEntryResponse struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Operation  string    `json:"operation"`
+	TargetID   *string   `json:"target_id,omitempty"`
+	SourceIP   string    `json:"source_ip"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	StatusCode int       `json:"status_code"`
+	Result     string    `json:"result"`
+	RequestID  string    `json:"request_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AuditLogResponse struct {
+	Data   []*AuditEntryResponse `json:"data"`
+	Limit  int                   `json:"limit"`
+	Offset int                   `json:"offset"`
+	Total  int                   `json:"total"`
+}
+
+// BatchPaymentResponse reports each entry of a batch in request order.
+type BatchPaymentResponse struct {
+	BatchID string              `json:"batch_id"`
+	Results []BatchItemResponse `json:"results"`
+}
+
+// BatchItemResponse is one batch entry's outcome. Status is the HTTP status
+// the entry would have had as a single request; exactly one of Payment and
+// Error is set.
+type BatchItemResponse struct {
+	Index   int              `json:"index"`
+	Status  int              `json:"status"`
+	Payment *PaymentResponse `json:"payment,omitempty"`
+	Error   *ErrorResponse   `json:"error,omitempty"`
+}
+
+// PaymentEventResponse is one entry of a payment's audit trail.
+type PaymentEventResponse struct {
+	EventType string         `json:"event_type"`
+	EventData map[string]any `json:"event_data"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// TimelineEntryResponse is one entry of a payment timeline; Kind says
+// whether Event or Transaction is set.
+type TimelineEntryResponse struct {
+	Kind        string                       `json:"kind"`
+	At          time.Time                    `json:"at"`
+	Event       *PaymentEventResponse        `json:"event,omitempty"`
+	Transaction *TimelineTransactionResponse `json:"transaction,omitempty"`
+}
+
+// TimelineTransactionResponse is an account transaction in a payment
+// timeline. BalanceAfter is omitted for accounts whose balance the caller may
+// not see.
+type TimelineTransactionResponse struct {
+	ID              string   `json:"id"`
+	AccountID       string   `json:"account_id"`
+	TransactionType string   `json:"transaction_type"`
+	Amount          float64  `json:"amount"`
+	Currency        string   `json:"currency"`
+	BalanceAfter    *float64 `json:"balance_after,omitempty"`
+	Description     string   `json:"description"`
+}
+
+type DisputeResponse struct {
+	ID                string     `json:"id"`
+	PaymentID         string     `json:"payment_id"`
+	Provider          string     `json:"provider"`
+	ProviderDisputeID string     `json:"provider_dispute_id"`
+	Reason            string     `json:"reason,omitempty"`
+	Amount            float64    `json:"amount"`
+	Status            string     `json:"status"`
+	FundsReversed     bool       `json:"funds_reversed"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	ResolvedAt        *time.Time `json:"resolved_at,omitempty"`
+}
+
+// ReemitEventsResponse lists the outbox entries queued by a re-emit.
+type ReemitEventsResponse struct {
+	PaymentID string                `json:"payment_id"`
+	Reemitted int                   `json:"reemitted"`
+	Events    []ReemittedEventEntry `json:"events"`
+}
+
+type ReemittedEventEntry struct {
+	OutboxID  string `json:"outbox_id"`
+	EventType string `json:"event_type"`
+	DedupKey  string `json:"dedup_key"`
+}
+
+// DeadLetterResponse is a DLQ entry. Message holds the original payment
+// stream message values, or the undelivered payload of a webhook or outbox
+// entry.
+type DeadLetterResponse struct {
+	ID        string         `json:"id"`
+	PaymentID string         `json:"payment_id,omitempty"`
+	WebhookID string         `json:"webhook_id,omitempty"`
+	OutboxID  string         `json:"outbox_id,omitempty"`
+	Reason    string         `json:"reason"`
+	Message   map[string]any `json:"message"`
+	FailedAt  time.Time      `json:"failed_at"`
+}
+
+// CircuitBreakerResponse is a provider's circuit breaker state and the
+// request counts of its current generation.
+type CircuitBreakerResponse struct {
+	Provider             string `json:"provider"`
+	State                string `json:"state"`
+	Requests             uint32 `json:"requests"`
+	TotalSuccesses       uint32 `json:"total_successes"`
+	TotalFailures        uint32 `json:"total_failures"`
+	ConsecutiveSuccesses uint32 `json:"consecutive_successes"`
+	ConsecutiveFailures  uint32 `json:"consecutive_failures"`
 }
 
 type ErrorResponse struct {
-	Error string `json:"error"`
-	Code  string `json:"code"`
+	Error   string         `json:"error"`
+	Code    string         `json:"code"`
+	Details map[string]any `json:"details,omitempty"`
+	// Errors lists each invalid field when a request fails validation.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 
@@ -92,8 +407,9 @@ func FromAccount(a *account.Account) *AccountResponse {
 	return &AccountResponse{
 		ID:        a.ID.String(),
 		UserID:    a.UserID,
-		Balance:   centsToFloat(a.Balance),
+		Balance:   minorToFloat(a.Balance, a.Currency),
 		Currency:  a.Currency,
+		Label:     a.Label,
 		Status:    string(a.Status),
 		Version:   a.Version,
 		CreatedAt: a.CreatedAt,
@@ -101,13 +417,14 @@ func FromAccount(a *account.Account) *AccountResponse {
 	}
 }
 
-func FromTransaction(t *account.Transaction) *TransactionResponse {
+// FromTransaction renders t, whose amounts are in code.
+func FromTransaction(t *account.Transaction, code string) *TransactionResponse {
 	resp := &TransactionResponse{
 		ID:              t.ID.String(),
 		AccountID:       t.AccountID.String(),
 		TransactionType: string(t.TransactionType),
-		Amount:          centsToFloat(t.Amount),
-		BalanceAfter:    centsToFloat(t.BalanceAfter),
+		Amount:          minorToFloat(t.Amount, code),
+		BalanceAfter:    minorToFloat(t.BalanceAfter, code),
 		Description:     t.Description,
 		CreatedAt:       t.CreatedAt,
 	}
@@ -118,13 +435,168 @@ func FromTransaction(t *account.Transaction) *TransactionResponse {
 	return resp
 }
 
-func FromPayment(p *payment.Payment) *PaymentResponse {
+func FromAccountStatement(st *service.AccountStatement) *AccountStatementResponse {
+	resp := &AccountStatementResponse{
+		AccountID:      st.Account.ID.String(),
+		Currency:       st.Currency.Code,
+		From:           st.From,
+		To:             st.To,
+		OpeningBalance: minorToFloat(st.OpeningBalance, st.Currency.Code),
+		ClosingBalance: minorToFloat(st.ClosingBalance, st.Currency.Code),
+		TotalDebits:    minorToFloat(st.TotalDebits, st.Currency.Code),
+		TotalCredits:   minorToFloat(st.TotalCredits, st.Currency.Code),
+		Transactions:   make([]*TransactionResponse, 0, len(st.Transactions)),
+	}
+	for _, tx := range st.Transactions {
+		resp.Transactions = append(resp.Transactions, FromTransaction(tx, st.Currency.Code))
+	}
+	return resp
+}
+
+func FromLedgerIntegrityReport(r *service.LedgerIntegrityReport) *LedgerIntegrityResponse {
+	code := r.Account.Currency
+	resp := &LedgerIntegrityResponse{
+		AccountID:        r.Account.ID.String(),
+		Currency:         code,
+		Consistent:       r.Consistent,
+		InitialBalance:   minorToFloat(r.InitialBalance, code),
+		StoredBalance:    minorToFloat(r.StoredBalance, code),
+		ComputedBalance:  minorToFloat(r.ComputedBalance, code),
+		Difference:       minorToFloat(r.StoredBalance-r.ComputedBalance, code),
+		TransactionCount: r.TransactionCount,
+	}
+	if d := r.FirstDivergence; d != nil {
+		resp.FirstDivergence = &LedgerDivergenceResponse{
+			TransactionID:        d.TransactionID.String(),
+			CreatedAt:            d.CreatedAt,
+			ExpectedBalanceAfter: minorToFloat(d.Expected, code),
+			RecordedBalanceAfter: minorToFloat(d.Recorded, code),
+		}
+	}
+	return resp
+}
+
+func FromAuditEntry(e *audit.Entry) *AuditEntryResponse {
+	resp := &AuditEntryResponse{
+		ID:         e.ID.String(),
+		UserID:     e.UserID,
+		Operation:  e.Operation,
+		SourceIP:   e.SourceIP,
+		Method:     e.Method,
+		Path:       e.Path,
+		StatusCode: e.StatusCode,
+		Result:     string(e.Result),
+		RequestID:  e.RequestID,
+		CreatedAt:  e.CreatedAt,
+	}
+	if e.TargetID != nil {
+		id := e.TargetID.String()
+		resp.TargetID = &id
+	}
+	return resp
+}
+
+func FromPaymentEvent(e *payment.PaymentEvent) *PaymentEventResponse {
+	return &PaymentEventResponse{
+		EventType: e.EventType,
+		EventData: e.EventData,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+// FromTimelineEntry renders e, with a transaction's balance only when
+// showBalance is set.
+func FromTimelineEntry(e service.TimelineEntry, showBalance bool) *TimelineEntryResponse {
+	resp := &TimelineEntryResponse{Kind: string(e.Kind), At: e.At}
+	if e.Event != nil {
+		resp.Event = FromPaymentEvent(e.Event)
+	}
+	if t := e.Transaction; t != nil {
+		resp.Transaction = &TimelineTransactionResponse{
+			ID:              t.ID.String(),
+			AccountID:       t.AccountID.String(),
+			TransactionType: string(t.TransactionType),
+			Amount:          minorToFloat(t.Amount, e.Currency),
+			Currency:        e.Currency,
+			Description:     t.Description,
+		}
+		if showBalance {
+			balance := minorToFloat(t.BalanceAfter, e.Currency)
+			resp.Transaction.BalanceAfter = &balance
+		}
+	}
+	return resp
+}
+
+func FromDispute(d *payment.Dispute) *DisputeResponse {
+	return &DisputeResponse{
+		ID:                d.ID.String(),
+		PaymentID:         d.PaymentID.String(),
+		Provider:          string(d.Provider),
+		ProviderDisputeID: d.ProviderDisputeID,
+		Reason:            d.Reason,
+		Amount:            minorToFloat(d.AmountCents, d.Currency),
+		Status:            string(d.Status),
+		FundsReversed:     d.FundsReversed,
+		CreatedAt:         d.CreatedAt,
+		UpdatedAt:         d.UpdatedAt,
+		ResolvedAt:        d.ResolvedAt,
+	}
+}
+
+func FromBreakerStatus(s providers.BreakerStatus) *CircuitBreakerResponse {
+	return &CircuitBreakerResponse{
+		Provider:             s.Provider,
+		State:                s.State.String(),
+		Requests:             s.Requests,
+		TotalSuccesses:       s.TotalSuccesses,
+		TotalFailures:        s.TotalFailures,
+		ConsecutiveSuccesses: s.ConsecutiveSuccesses,
+		ConsecutiveFailures:  s.ConsecutiveFailures,
+	}
+}
+
+func FromDLQEntry(e infraRedis.DLQEntry) *DeadLetterResponse {
+	return &DeadLetterResponse{
+		ID:        e.ID,
+		PaymentID: e.PaymentID,
+		WebhookID: e.WebhookID,
+		OutboxID:  e.OutboxID,
+		Reason:    e.Reason,
+		Message:   e.Values,
+		FailedAt:  e.FailedAt,
+	}
+}
+
+// FromReemit summarizes the outbox entries a re-emit created.
+func FromReemit(r *service.ReemitEventsResponse) *ReemitEventsResponse {
+	resp := &ReemitEventsResponse{
+		PaymentID: r.PaymentID.String(),
+		Reemitted: len(r.Entries),
+		Events:    make([]ReemittedEventEntry, 0, len(r.Entries)),
+	}
+	for _, e := range r.Entries {
+		key, _ := e.Payload[outbox.DedupKeyKey].(string)
+		resp.Events = append(resp.Events, ReemittedEventEntry{
+			OutboxID:  e.ID.String(),
+			EventType: e.EventType,
+			DedupKey:  key,
+		})
+	}
+	return resp
+}
+
+// FromPayment renders p for viewer, redacting the fields its masking rules
+// hide.
+func FromPayment(p *payment.Payment, viewer Viewer) *PaymentResponse {
 	resp := &PaymentResponse{
 		ID:             p.ID.String(),
 		IdempotencyKey: p.IdempotencyKey,
 		PaymentType:    string(p.PaymentType),
-		Amount:         centsToFloat(p.Amount.ValueCents),
+		Amount:         minorToFloat(p.Amount.ValueCents, p.Amount.Currency),
 		Currency:       p.Amount.Currency,
+		Fee:            minorToFloat(p.FeeCents, p.Amount.Currency),
+		Description:    p.Description,
 		Status:         string(p.Status),
 		RetryCount:     p.RetryCount,
 		MaxRetries:     p.MaxRetries,
@@ -133,6 +605,15 @@ func FromPayment(p *payment.Payment) *PaymentResponse {
 		CreatedAt:      p.CreatedAt,
 		UpdatedAt:      p.UpdatedAt,
 		CompletedAt:    p.CompletedAt,
+		FailedAt:       p.FailedAt,
+		CancelledAt:    p.CancelledAt,
+		RefundedAt:     p.RefundedAt,
+		ScheduledAt:    p.ScheduledAt,
+		NextRetryAt:    p.NextRetryAt,
+	}
+	if p.BatchID != nil {
+		bid := p.BatchID.String()
+		resp.BatchID = &bid
 	}
 	if p.SourceAccountID != nil {
 		sid := p.SourceAccountID.String()
@@ -146,13 +627,88 @@ func FromPayment(p *payment.Payment) *PaymentResponse {
 		prov := string(*p.Provider)
 		resp.Provider = &prov
 	}
+	if p.PaymentType == payment.ExternalPayment && p.FeeCents > 0 {
+		net := minorToFloat(p.NetCents(), p.Amount.Currency)
+		resp.NetAmount = &net
+	}
+	if c := p.Conversion; c != nil {
+		rate, credited := c.Rate, minorToFloat(c.Credited.ValueCents, c.Credited.Currency)
+		resp.ExchangeRate, resp.CreditedAmount, resp.CreditedCurrency = &rate, &credited, c.Credited.Currency
+	}
 	resp.ProviderTransactionID = p.ProviderTransactionID
+	applyMasking(resp, viewer)
 	return resp
 }
 
-const maxAmountFloat = 922337203685477.0 // Safe max to avoid float64 precision issues (close to (2^63-1)/100)
+// PaymentPreviewResponse is the body of a dry-run POST /payments: what the
+// payment would do. It has no id or status, as nothing was created.
+type PaymentPreviewResponse struct {
+	DryRun               bool       `json:"dry_run"`
+	PaymentType          string     `json:"payment_type"`
+	Processing           string     `json:"processing"` // sync, async or scheduled
+	SourceAccountID      *string    `json:"source_account_id,omitempty"`
+	DestinationAccountID *string    `json:"destination_account_id,omitempty"`
+	Amount               float64    `json:"amount"`
+	Currency             string     `json:"currency"`
+	Fee                  float64    `json:"fee,omitempty"`
+	NetAmount            *float64   `json:"net_amount,omitempty"`
+	ExchangeRate         *float64   `json:"exchange_rate,omitempty"`
+	CreditedAmount       *float64   `json:"credited_amount,omitempty"`
+	CreditedCurrency     string     `json:"credited_currency,omitempty"`
+	ScheduledAt          *time.Time `json:"scheduled_at,omitempty"`
+	// Projected balances once the payment settles.
+	SourceBalanceAfter      *float64 `json:"source_balance_after,omitempty"`
+	DestinationBalanceAfter *float64 `json:"destination_balance_after,omitempty"`
+}
 
-func floatToCents(f float64) (int64, error) {
+// FromPaymentPreview renders the unsaved payment p and its projected outcome.
+func FromPaymentPreview(p *payment.Payment, preview *service.PaymentPreview) *PaymentPreviewResponse {
+	pr := FromPayment(p, Viewer{Role: ViewerOwner})
+	resp := &PaymentPreviewResponse{
+		DryRun:               true,
+		PaymentType:          pr.PaymentType,
+		Processing:           string(preview.Routing),
+		SourceAccountID:      pr.SourceAccountID,
+		DestinationAccountID: pr.DestinationAccountID,
+		Amount:               pr.Amount,
+		Currency:             pr.Currency,
+		Fee:                  pr.Fee,
+		NetAmount:            pr.NetAmount,
+		ExchangeRate:         pr.ExchangeRate,
+		CreditedAmount:       pr.CreditedAmount,
+		CreditedCurrency:     pr.CreditedCurrency,
+		ScheduledAt:          pr.ScheduledAt,
+	}
+	if b := preview.SourceBalanceAfter; b != nil {
+		f := minorToFloat(*b, p.Amount.Currency)
+		resp.SourceBalanceAfter = &f
+	}
+	if b := preview.DestinationBalanceAfter; b != nil {
+		f := minorToFloat(*b, p.CreditedAmount().Currency)
+		resp.DestinationBalanceAfter = &f
+	}
+	return resp
+}
+
+// maxAmountMinor bounds request amounts in minor units, well inside an int64
+// and where float64 still resolves single minor units closely enough.
+const maxAmountMinor = 92233720368547700
+
+// currencyOf returns the registered currency for code. Unknown codes are
+// treated as having two decimal places; the domain rejects them afterwards
+// with a proper validation error.
+func currencyOf(code string) currency.Currency {
+	c, err := currency.Lookup(code)
+	if err != nil {
+		return currency.Currency{Code: code, MinorUnits: 2}
+	}
+	return c
+}
+
+// floatToMinor converts a request amount in major units of code to minor
+// units. Amounts with more decimal places than the currency has (10.999 USD,
+// 1000.5 JPY) are rejected rather than rounded.
+func floatToMinor(f float64, code string) (int64, error) {
 	// Check for special values
 	if math.IsNaN(f) {
 		return 0, domainErrors.NewValidationError("amount", "must be a valid number")
@@ -165,24 +721,48 @@ func floatToCents(f float64) (int64, error) {
 	if f <= 0 {
 		return 0, domainErrors.NewValidationError("amount", "must be greater than 0")
 	}
-	if f > maxAmountFloat {
+	c := currencyOf(code)
+	if f*float64(c.Factor()) > maxAmountMinor {
 		return 0, domainErrors.NewValidationError("amount",
-			fmt.Sprintf("exceeds maximum allowed (%.2f)", maxAmountFloat))
+			fmt.Sprintf("exceeds maximum allowed (%s)", c.Format(maxAmountMinor)))
+	}
+	if decimalPlaces(f) > c.MinorUnits {
+		return 0, domainErrors.NewValidationError("amount",
+			fmt.Sprintf("must have at most %d decimal places for %s", c.MinorUnits, c.Code))
 	}
 
-	// Convert with rounding
-	cents := int64(math.Round(f * 100))
-
-	// Paranoid overflow check
-	if cents < 0 {
+	minor, err := c.ToMinor(f)
+	if err != nil || minor < 0 {
 		return 0, domainErrors.NewValidationError("amount", "overflow detected")
 	}
+	return minor, nil
+}
 
-	return cents, nil
+// decimalPlaces counts the fraction digits in the shortest decimal that
+// parses back to f, so 0.1 has one even though its binary value does not end.
+func decimalPlaces(f float64) int {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// feeToMinor converts an optional fee in code; zero means none.
+func feeToMinor(f float64, code string) (int64, error) {
+	if f == 0 {
+		return 0, nil
+	}
+	minor, err := floatToMinor(f, code)
+	if ve, ok := err.(*domainErrors.ValidationError); ok {
+		return 0, domainErrors.NewValidationError("fee", ve.Message)
+	}
+	return minor, err
 }
 
-func centsToFloat(cents int64) float64 {
-	return float64(cents) / 100.0
+// minorToFloat renders an amount in minor units of code in major units.
+func minorToFloat(minor int64, code string) float64 {
+	return currencyOf(code).ToMajor(minor)
 }
 
 func parseUUID(s string) *uuid.UUID {
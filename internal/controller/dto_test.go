@@ -4,92 +4,128 @@ import (
 	"fmt"
 	"math"
 	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/payment"
 )
 
-func TestFloatToCents(t *testing.T) {
+func TestFloatToMinor(t *testing.T) {
 	tests := []struct {
-		name    string
-		input   float64
-		want    int64
-		wantErr bool
+		name     string
+		input    float64
+		currency string
+		want     int64
+		wantErr  bool
 	}{
-		{"normal", 123.45, 12345, false},
-		{"zero", 0, 0, true},
-		{"negative", -10.00, 0, true},
-		{"max valid", 922337203685477.0, 92233720368547696, false}, // Actual result due to float64 precision
-		{"overflow", 922337203685478.0, 0, true},
-		{"huge overflow", 9999999999999999.99, 0, true},
-		{"NaN", math.NaN(), 0, true},
-		{"positive infinity", math.Inf(1), 0, true},
-		{"negative infinity", math.Inf(-1), 0, true},
-		{"min valid", 0.01, 1, false},
-		{"rounding", 10.999, 1100, false},
-		{"small amount", 1.00, 100, false},
-		{"large amount", 100000.00, 10000000, false},
-		{"exact max", maxAmountFloat, 92233720368547696, false}, // Actual result due to float64 precision
-		{"just over max", maxAmountFloat + 1.0, 0, true},
+		{"normal", 123.45, "USD", 12345, false},
+		{"zero", 0, "USD", 0, true},
+		{"negative", -10.00, "USD", 0, true},
+		{"max valid", 922337203685477.0, "USD", 92233720368547696, false}, // Actual result due to float64 precision
+		{"overflow", 922337203685478.0, "USD", 0, true},
+		{"huge overflow", 9999999999999999.99, "USD", 0, true},
+		{"NaN", math.NaN(), "USD", 0, true},
+		{"positive infinity", math.Inf(1), "USD", 0, true},
+		{"negative infinity", math.Inf(-1), "USD", 0, true},
+		{"min valid", 0.01, "USD", 1, false},
+		{"too precise", 10.999, "USD", 0, true},
+		{"small amount", 1.00, "USD", 100, false},
+		{"large amount", 100000.00, "USD", 10000000, false},
+		{"decimal without exact binary form", 0.1, "USD", 10, false},
+		{"yen", 1000, "JPY", 1000, false},
+		{"fractional yen", 1000.5, "JPY", 0, true},
+		{"dinar", 1.234, "BHD", 1234, false},
+		{"dinar cents", 0.05, "BHD", 50, false},
+		{"too precise dinar", 1.2345, "BHD", 0, true},
+		{"unknown currency", 12.34, "XYZ", 1234, false}, // the domain rejects the currency
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := floatToCents(tt.input)
+			got, err := floatToMinor(tt.input, tt.currency)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("floatToCents() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("floatToMinor() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !tt.wantErr && got != tt.want {
-				t.Errorf("floatToCents() = %v, want %v", got, tt.want)
+				t.Errorf("floatToMinor() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestCentsToFloat(t *testing.T) {
+func TestMinorToFloat(t *testing.T) {
 	tests := []struct {
-		cents int64
-		want  float64
+		minor    int64
+		currency string
+		want     float64
 	}{
-		{12345, 123.45},
-		{1, 0.01},
-		{99, 0.99},
-		{-99, -0.99}, // Negative display correct
-		{92233720368547696, 922337203685476.96}, // Actual max that can be precisely represented
-		{100, 1.00},
-		{10000000, 100000.00},
-		{0, 0.00},
+		{12345, "USD", 123.45},
+		{1, "USD", 0.01},
+		{99, "USD", 0.99},
+		{-99, "USD", -0.99}, // Negative display correct
+		{92233720368547696, "USD", 922337203685476.96}, // Actual max that can be precisely represented
+		{100, "USD", 1.00},
+		{10000000, "USD", 100000.00},
+		{0, "USD", 0.00},
+		{1000, "JPY", 1000},
+		{1234, "BHD", 1.234},
+		{1234, "XYZ", 12.34},
 	}
 
 	for _, tt := range tests {
-		t.Run(fmt.Sprintf("%d", tt.cents), func(t *testing.T) {
-			got := centsToFloat(tt.cents)
+		t.Run(fmt.Sprintf("%d %s", tt.minor, tt.currency), func(t *testing.T) {
+			got := minorToFloat(tt.minor, tt.currency)
 			if got != tt.want {
-				t.Errorf("centsToFloat() = %v, want %v", got, tt.want)
+				t.Errorf("minorToFloat() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestFloatToCentsRoundTrip(t *testing.T) {
+func TestFloatToMinorRoundTrip(t *testing.T) {
 	// Test that converting back and forth preserves values for valid amounts
-	testValues := []float64{
-		1.00,
-		10.50,
-		100.99,
-		1000.01,
-		12345.67,
+	testValues := []struct {
+		amount   float64
+		currency string
+	}{
+		{1.00, "USD"},
+		{10.50, "USD"},
+		{100.99, "USD"},
+		{1000.01, "USD"},
+		{12345.67, "USD"},
+		{1000, "JPY"},
+		{12.345, "BHD"},
 	}
 
-	for _, original := range testValues {
-		t.Run(fmt.Sprintf("%.2f", original), func(t *testing.T) {
-			cents, err := floatToCents(original)
+	for _, v := range testValues {
+		t.Run(fmt.Sprintf("%v %s", v.amount, v.currency), func(t *testing.T) {
+			minor, err := floatToMinor(v.amount, v.currency)
 			if err != nil {
-				t.Fatalf("floatToCents() error = %v", err)
+				t.Fatalf("floatToMinor() error = %v", err)
 			}
-			result := centsToFloat(cents)
-			// Allow small floating point differences
-			if math.Abs(result-original) > 0.01 {
-				t.Errorf("Round trip failed: original=%v, result=%v", original, result)
+			if result := minorToFloat(minor, v.currency); result != v.amount {
+				t.Errorf("Round trip failed: original=%v, result=%v", v.amount, result)
 			}
 		})
 	}
 }
+
+func TestFromPayment_AmountsInCurrencyUnits(t *testing.T) {
+	tests := []struct {
+		currency string
+		minor    int64
+		want     float64
+	}{
+		{"JPY", 1000, 1000},
+		{"USD", 1000, 10.00},
+		{"BHD", 1000, 1.000},
+	}
+	for _, tt := range tests {
+		p, err := payment.NewPayment("key-"+tt.currency, payment.ExternalPayment, nil, nil, payment.NewAmount(tt.minor, tt.currency))
+		if err != nil {
+			t.Fatalf("NewPayment() error = %v", err)
+		}
+		if got := FromPayment(p, Viewer{Role: ViewerOwner}).Amount; got != tt.want {
+			t.Errorf("%d %s serialized as %v, want %v", tt.minor, tt.currency, got, tt.want)
+		}
+	}
+}
@@ -3,48 +3,75 @@ package controller
 import (
 	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
+// readinessTimeout bounds each dependency check so a hung dependency fails
+// the probe instead of stalling it.
+const readinessTimeout = time.Second
+
+// healthCheck pings one dependency the service needs to serve requests.
+type healthCheck struct {
+	name string
+	ping func(ctx context.Context) error
+}
+
 type HealthController struct {
-	pool   *pgxpool.Pool
-	redis  *redis.Client
+	checks []healthCheck
 }
 
 func NewHealthController(pool *pgxpool.Pool, redis *redis.Client) *HealthController {
-	return &HealthController{pool: pool, redis: redis}
+	return &HealthController{checks: []healthCheck{
+		{name: "database", ping: pool.Ping},
+		{name: "redis", ping: func(ctx context.Context) error { return redis.Ping(ctx).Err() }},
+	}}
+}
+
+// ReadinessResponse reports each dependency as "up" or "down".
+type ReadinessResponse struct {
+	Status     string            `json:"status"`
+	Components map[string]string `json:"components"`
 }
 
 func (h *HealthController) Health(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// Liveness reports that the process is up; it checks no dependencies, so a
+// database outage does not get the pod restarted.
 func (h *HealthController) Liveness(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "alive"})
 }
 
+// Readiness pings every dependency in parallel, each within
+// readinessTimeout, and responds 503 if any is down.
 func (h *HealthController) Readiness(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
 	defer cancel()
 
-	if err := h.pool.Ping(ctx); err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-			"status": "not ready",
-			"reason": "database unavailable",
+	errs := make([]error, len(h.checks))
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Go(func() {
+			errs[i] = c.ping(ctx)
 		})
-		return
 	}
+	wg.Wait()
 
-	if err := h.redis.Ping(ctx).Err(); err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-			"status": "not ready",
-			"reason": "redis unavailable",
-		})
-		return
+	resp := ReadinessResponse{Status: "ready", Components: make(map[string]string, len(h.checks))}
+	status := http.StatusOK
+	for i, c := range h.checks {
+		if errs[i] != nil {
+			resp.Components[c.name] = "down"
+			resp.Status = "not ready"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Components[c.name] = "up"
 	}
-
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+	writeJSON(w, status, resp)
 }
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func serveReadiness(t *testing.T, checks ...healthCheck) (int, ReadinessResponse) {
+	t.Helper()
+	h := &HealthController{checks: checks}
+	rec := httptest.NewRecorder()
+	h.Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var resp ReadinessResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode readiness response: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func up(ctx context.Context) error { return nil }
+
+func TestHealthController_Readiness_AllUp(t *testing.T) {
+	code, resp := serveReadiness(t, healthCheck{"database", up}, healthCheck{"redis", up})
+
+	if code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", code)
+	}
+	if resp.Status != "ready" {
+		t.Errorf("expected status ready, got %q", resp.Status)
+	}
+	if resp.Components["database"] != "up" || resp.Components["redis"] != "up" {
+		t.Errorf("expected every component up, got %v", resp.Components)
+	}
+}
+
+func TestHealthController_Readiness_ComponentDown(t *testing.T) {
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+	code, resp := serveReadiness(t, healthCheck{"database", up}, healthCheck{"redis", down})
+
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", code)
+	}
+	if resp.Status != "not ready" {
+		t.Errorf("expected status not ready, got %q", resp.Status)
+	}
+	if resp.Components["database"] != "up" || resp.Components["redis"] != "down" {
+		t.Errorf("expected database up and redis down, got %v", resp.Components)
+	}
+}
+
+func TestHealthController_Readiness_HungDependencyTimesOut(t *testing.T) {
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	start := time.Now()
+	code, resp := serveReadiness(t, healthCheck{"database", hang})
+
+	if elapsed := time.Since(start); elapsed > 2*readinessTimeout {
+		t.Errorf("readiness took %v, want at most about %v", elapsed, readinessTimeout)
+	}
+	if code != http.StatusServiceUnavailable || resp.Components["database"] != "down" {
+		t.Errorf("expected 503 with database down, got %d %v", code, resp.Components)
+	}
+}
+
+func TestRouter_ProbesNeedNoAuth(t *testing.T) {
+	router := NewRouter(RouterDeps{
+		Metrics:   observability.NewMetrics("test", prometheus.NewRegistry()),
+		JWTSecret: testJWTSecret,
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /healthz: expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
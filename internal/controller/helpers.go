@@ -3,9 +3,11 @@ package controller
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 
 	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/middleware"
 	
 	"github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog/log"
@@ -22,14 +24,30 @@ type errorMapping struct {
 var errorMappings = []errorMapping{
 	{domainErrors.ErrAccountNotFound, http.StatusNotFound, "not_found"},
 	{domainErrors.ErrPaymentNotFound, http.StatusNotFound, "not_found"},
+	{domainErrors.ErrDisputeNotFound, http.StatusNotFound, "not_found"},
+	{domainErrors.ErrDeadLetterNotFound, http.StatusNotFound, "not_found"},
+	{domainErrors.ErrProviderNotFound, http.StatusNotFound, "not_found"},
+	{domainErrors.ErrAccountAlreadyExists, http.StatusConflict, "account_exists"},
 	{domainErrors.ErrInsufficientFunds, http.StatusUnprocessableEntity, "insufficient_funds"},
 	{domainErrors.ErrAccountInactive, http.StatusUnprocessableEntity, "account_inactive"},
+	{domainErrors.ErrAccountUnavailable, http.StatusUnprocessableEntity, "account_unavailable"},
 	{domainErrors.ErrInvalidCurrency, http.StatusBadRequest, "invalid_currency"},
+	{domainErrors.ErrUnsupportedCurrencyPair, http.StatusUnprocessableEntity, "unsupported_currency_pair"},
+	{domainErrors.ErrExchangeRateUnavailable, http.StatusServiceUnavailable, "exchange_rate_unavailable"},
 	{domainErrors.ErrDuplicateIdempotencyKey, http.StatusConflict, "duplicate_request"},
+	{domainErrors.ErrIdempotencyKeyReused, http.StatusConflict, "idempotency_key_reused"},
+	{domainErrors.ErrPayloadTooLarge, http.StatusRequestEntityTooLarge, "payload_too_large"},
+	{domainErrors.ErrRefundWindowExpired, http.StatusUnprocessableEntity, "refund_window_expired"},
+	{domainErrors.ErrManualRefundRequired, http.StatusUnprocessableEntity, "manual_refund_required"},
 	{domainErrors.ErrInvalidStateTransition, http.StatusConflict, "invalid_state_transition"},
 	{domainErrors.ErrOptimisticLockFailed, http.StatusConflict, "conflict"},
 	{domainErrors.ErrProviderUnavailable, http.StatusServiceUnavailable, "provider_unavailable"},
 	{domainErrors.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{domainErrors.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{domainErrors.ErrInvalidRefreshToken, http.StatusUnauthorized, "invalid_refresh_token"},
+	{domainErrors.ErrInvalidSignature, http.StatusUnauthorized, "invalid_signature"},
+	{domainErrors.ErrStatementSigningDisabled, http.StatusNotImplemented, "statement_signing_disabled"},
+	{domainErrors.ErrStepUpRequired, http.StatusForbidden, "step_up_required"},
 	{domainErrors.ErrForbidden, http.StatusForbidden, "forbidden"},
 }
 
@@ -40,13 +58,46 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 }
 
 func writeError(w http.ResponseWriter, err error) {
+	status, resp := errorResponse(err)
+	writeJSON(w, status, resp)
+}
+
+// errorResponse maps err to the HTTP status and body writeError sends.
+func errorResponse(err error) (int, ErrorResponse) {
 	resp := ErrorResponse{Error: err.Error()}
 
+	var validationErrs domainErrors.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		resp.Code = "validation_error"
+		resp.Errors = make([]FieldError, len(validationErrs))
+		for i, ve := range validationErrs {
+			resp.Errors[i] = FieldError{Field: ve.Field, Message: ve.Message}
+		}
+		return http.StatusBadRequest, resp
+	}
+
 	var validationErr *domainErrors.ValidationError
 	if errors.As(err, &validationErr) {
 		resp.Code = "validation_error"
-		writeJSON(w, http.StatusBadRequest, resp)
-		return
+		return http.StatusBadRequest, resp
+	}
+
+	var fundsErr *domainErrors.InsufficientFundsError
+	if errors.As(err, &fundsErr) {
+		resp.Details = map[string]any{
+			"available": minorToFloat(fundsErr.Available, fundsErr.Currency),
+			"reserved":  minorToFloat(fundsErr.Reserved, fundsErr.Currency),
+			"requested": minorToFloat(fundsErr.Requested, fundsErr.Currency),
+			"shortfall": minorToFloat(fundsErr.Shortfall(), fundsErr.Currency),
+			// The same amounts in minor units, exact for clients that do
+			// arithmetic on them.
+			"required_cents":  fundsErr.Requested,
+			"available_cents": fundsErr.Available,
+			"shortfall_cents": fundsErr.Shortfall(),
+		}
+		if fundsErr.Currency != "" {
+			resp.Details["currency"] = fundsErr.Currency
+		}
 	}
 
 	for _, m := range errorMappings {
@@ -55,38 +106,57 @@ func writeError(w http.ResponseWriter, err error) {
 			if m.err == domainErrors.ErrOptimisticLockFailed {
 				resp.Error = "concurrent modification, please retry"
 			}
-			writeJSON(w, m.status, resp)
-			return
+			return m.status, resp
 		}
 	}
 
 	var domainErr *domainErrors.DomainError
 	if errors.As(err, &domainErr) {
 		resp.Code = domainErr.Code
-		writeJSON(w, http.StatusUnprocessableEntity, resp)
-		return
+		return http.StatusUnprocessableEntity, resp
 	}
 
 	log.Error().Err(err).Msg("unhandled error in handler")
 	resp.Code = "internal_error"
 	resp.Error = "internal server error"
-	writeJSON(w, http.StatusInternalServerError, resp)
+	return http.StatusInternalServerError, resp
 }
 
-const maxRequestBodySize = 1 << 20 // 1MB
-
+// decodeAndValidate decodes the JSON body into dst and validates it. Bodies
+// over the route's limit (see middleware.MaxBodySize) fail with
+// ErrPayloadTooLarge.
 func decodeAndValidate(r *http.Request, dst any) error {
-	r.Body = http.MaxBytesReader(nil, r.Body, maxRequestBodySize)
+	r.Body = http.MaxBytesReader(nil, r.Body, middleware.MaxBodyBytes(r.Context()))
 
 	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
-		if err.Error() == "http: request body too large" {
-			return domainErrors.NewValidationError("body", "request too large (max 1MB)")
+		if tooLarge := payloadTooLarge(err); tooLarge != nil {
+			return tooLarge
 		}
 		return domainErrors.NewValidationError("body", "invalid JSON: "+err.Error())
 	}
-	if err := validate.Struct(dst); err != nil {
+	return validateStruct(dst)
+}
+
+// payloadTooLarge reports a read that hit http.MaxBytesReader's limit as
+// ErrPayloadTooLarge, and returns nil for any other error.
+func payloadTooLarge(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return nil
+	}
+	return fmt.Errorf("%w (max %d bytes)", domainErrors.ErrPayloadTooLarge, maxBytesErr.Limit)
+}
+
+// validateStruct runs the struct's validate tags, reporting every failing
+// field in a ValidationErrors.
+func validateStruct(v any) error {
+	if err := validate.Struct(v); err != nil {
 		if ve, ok := err.(validator.ValidationErrors); ok && len(ve) > 0 {
-			return domainErrors.NewValidationError(ve[0].Field(), ve[0].Tag()+" validation failed")
+			errs := make(domainErrors.ValidationErrors, len(ve))
+			for i, fe := range ve {
+				errs[i] = domainErrors.NewValidationError(fe.Field(), fe.Tag()+" validation failed")
+			}
+			return errs
 		}
 		return domainErrors.NewValidationError("body", err.Error())
 	}
@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/cassiomorais/payments/internal/domain/account"
 	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -157,6 +160,43 @@ func TestWriteError_OptimisticLockFailed_CustomMessage(t *testing.T) {
 	assert.Equal(t, "conflict", response.Code)
 }
 
+func TestWriteError_InsufficientFunds_IncludesDetails(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeError(w, &domainErrors.InsufficientFundsError{Available: 5000, Reserved: 1000, Requested: 7550})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var response ErrorResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	assert.Equal(t, "insufficient_funds", response.Code)
+	assert.Equal(t, 50.0, response.Details["available"])
+	assert.Equal(t, 10.0, response.Details["reserved"])
+	assert.Equal(t, 75.5, response.Details["requested"])
+	assert.Equal(t, 25.5, response.Details["shortfall"])
+	assert.Equal(t, 7550.0, response.Details["required_cents"])
+	assert.Equal(t, 5000.0, response.Details["available_cents"])
+	assert.Equal(t, 2550.0, response.Details["shortfall_cents"])
+	assert.NotContains(t, response.Details, "currency")
+}
+
+func TestWriteError_InsufficientFunds_FromDebit(t *testing.T) {
+	acct := &account.Account{Status: account.StatusActive, Balance: 1000, HeldBalance: 200, Currency: "JPY"}
+	err := fmt.Errorf("debit source: %w", acct.Debit(1500))
+	assert.ErrorIs(t, err, domainErrors.ErrInsufficientFunds)
+
+	w := httptest.NewRecorder()
+	writeError(w, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var response ErrorResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	assert.Equal(t, 1500.0, response.Details["required_cents"])
+	assert.Equal(t, 800.0, response.Details["available_cents"])
+	assert.Equal(t, 700.0, response.Details["shortfall_cents"])
+	assert.Equal(t, 700.0, response.Details["shortfall"], "yen have no minor unit")
+	assert.Equal(t, "JPY", response.Details["currency"])
+}
+
 func TestWriteError_GenericDomainError(t *testing.T) {
 	w := httptest.NewRecorder()
 	err := domainErrors.NewDomainError("custom_error", "custom error message", nil)
@@ -255,6 +295,51 @@ func TestDecodeAndValidate_ValidationFailure_EmailFormat(t *testing.T) {
 	assert.Contains(t, validationErr.Message, "validation failed")
 }
 
+func TestDecodeAndValidate_ValidationFailure_ReportsEveryField(t *testing.T) {
+	type TestStruct struct {
+		Name  string `json:"name" validate:"required"`
+		Email string `json:"email" validate:"required,email"`
+		Age   int    `json:"age" validate:"gte=18"`
+	}
+
+	body := `{"name":"","email":"not-an-email","age":12}`
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(body))
+
+	var result TestStruct
+	err := decodeAndValidate(req, &result)
+
+	var validationErrs domainErrors.ValidationErrors
+	require.True(t, errors.As(err, &validationErrs))
+	require.Len(t, validationErrs, 3)
+
+	w := httptest.NewRecorder()
+	writeError(w, err)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "validation_error", response.Code)
+	assert.Equal(t, []FieldError{
+		{Field: "Name", Message: "required validation failed"},
+		{Field: "Email", Message: "email validation failed"},
+		{Field: "Age", Message: "gte validation failed"},
+	}, response.Errors)
+}
+
+func TestWriteError_InvalidJSON_HasNoFieldList(t *testing.T) {
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(`{invalid json}`))
+	var result struct{}
+	w := httptest.NewRecorder()
+	writeError(w, decodeAndValidate(req, &result))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response map[string]any
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "validation_error", response["code"])
+	assert.Contains(t, response["error"], "invalid JSON")
+	assert.NotContains(t, response, "errors", "a parse error keeps the single-error shape")
+}
+
 func TestDecodeAndValidate_EmptyBody(t *testing.T) {
 	type TestStruct struct {
 		Name string `json:"name" validate:"required"`
@@ -267,3 +352,44 @@ func TestDecodeAndValidate_EmptyBody(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestDecodeAndValidate_OversizedBody(t *testing.T) {
+	type TestStruct struct {
+		Name string `json:"name"`
+	}
+
+	body := `{"name":"` + strings.Repeat("a", int(middleware.DefaultMaxBodyBytes)) + `"}`
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(body))
+
+	var result TestStruct
+	err := decodeAndValidate(req, &result)
+	require.ErrorIs(t, err, domainErrors.ErrPayloadTooLarge)
+
+	w := httptest.NewRecorder()
+	writeError(w, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	var response ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "payload_too_large", response.Code)
+	assert.Contains(t, response.Error, "max 1048576 bytes")
+}
+
+func TestDecodeAndValidate_RouteBodyLimit(t *testing.T) {
+	type TestStruct struct {
+		Name string `json:"name"`
+	}
+	decodeWithLimit := func(limit int64, body string) error {
+		var err error
+		h := middleware.MaxBodySize(limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var result TestStruct
+			err = decodeAndValidate(r, &result)
+		}))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/test", strings.NewReader(body)))
+		return err
+	}
+
+	assert.ErrorIs(t, decodeWithLimit(16, `{"name":"more than sixteen bytes"}`), domainErrors.ErrPayloadTooLarge)
+
+	large := `{"name":"` + strings.Repeat("a", int(middleware.DefaultMaxBodyBytes)) + `"}`
+	assert.NoError(t, decodeWithLimit(2*middleware.DefaultMaxBodyBytes, large), "a route may allow more than the default")
+}
@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/google/uuid"
+)
+
+// ViewerRole is the caller's relationship to the payment being rendered.
+type ViewerRole string
+
+const (
+	ViewerOwner   ViewerRole = "owner"   // owns the source or destination account
+	ViewerAdmin   ViewerRole = "admin"   // carries the admin scope
+	ViewerSupport ViewerRole = "support" // carries the support scope
+	ViewerOther   ViewerRole = "other"   // any other authenticated caller
+)
+
+// Payment response fields that masking rules may redact.
+const (
+	FieldIdempotencyKey        = "idempotency_key"
+	FieldMetadata              = "metadata"
+	FieldProviderTransactionID = "provider_transaction_id"
+	FieldLastError             = "last_error"
+)
+
+var maskableFields = []string{FieldIdempotencyKey, FieldMetadata, FieldProviderTransactionID, FieldLastError}
+
+// MaskingRules lists the fields redacted for each viewer role. Owners always
+// see everything.
+type MaskingRules map[ViewerRole][]string
+
+// DefaultMaskingRules hides client-chosen identifiers from admins and, for
+// support and other viewers, also the merchant metadata and provider
+// references they do not need to diagnose a payment.
+func DefaultMaskingRules() MaskingRules {
+	return MaskingRules{
+		ViewerAdmin:   {FieldIdempotencyKey},
+		ViewerSupport: {FieldIdempotencyKey, FieldMetadata, FieldProviderTransactionID},
+		ViewerOther:   {FieldIdempotencyKey, FieldMetadata, FieldProviderTransactionID, FieldLastError},
+	}
+}
+
+// ParseMaskingRules builds rules from configuration keyed by role name,
+// rejecting unknown roles and fields.
+func ParseMaskingRules(cfg map[string][]string) (MaskingRules, error) {
+	rules := make(MaskingRules, len(cfg))
+	for role, fields := range cfg {
+		r := ViewerRole(strings.ToLower(role))
+		switch r {
+		case ViewerAdmin, ViewerSupport, ViewerOther:
+		default:
+			return nil, fmt.Errorf("masking: unknown viewer role %q", role)
+		}
+		for _, f := range fields {
+			if !isMaskable(f) {
+				return nil, fmt.Errorf("masking: unknown field %q for role %s", f, role)
+			}
+		}
+		rules[r] = fields
+	}
+	return rules, nil
+}
+
+func isMaskable(field string) bool {
+	for _, f := range maskableFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Viewer describes who a payment response is rendered for. The zero value
+// redacts nothing.
+type Viewer struct {
+	Role   ViewerRole
+	Redact []string
+}
+
+func (v Viewer) redacts(field string) bool {
+	for _, f := range v.Redact {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ResponseMasking resolves the viewer of each payment from the request's
+// token: account owners first, then the admin and support roles or scopes.
+type ResponseMasking struct {
+	rules        MaskingRules
+	adminScope   string
+	supportScope string
+	authz        *service.AuthzService
+}
+
+func NewResponseMasking(rules MaskingRules, adminScope, supportScope string, authz *service.AuthzService) *ResponseMasking {
+	return &ResponseMasking{rules: rules, adminScope: adminScope, supportScope: supportScope, authz: authz}
+}
+
+// viewerResolver returns a function resolving the viewer of payments within
+// one request, caching account ownership lookups. A nil ResponseMasking
+// disables masking.
+func (m *ResponseMasking) viewerResolver(ctx context.Context) func(p *payment.Payment) Viewer {
+	if m == nil {
+		return func(*payment.Payment) Viewer { return Viewer{} }
+	}
+
+	role := ViewerOther
+	switch {
+	case middleware.HasRole(ctx, service.RoleAdmin) || m.adminScope != "" && middleware.HasScope(ctx, m.adminScope):
+		role = ViewerAdmin
+	case middleware.HasRole(ctx, service.RoleSupport) || m.supportScope != "" && middleware.HasScope(ctx, m.supportScope):
+		role = ViewerSupport
+	}
+	nonOwner := Viewer{Role: role, Redact: m.rules[role]}
+
+	owned := make(map[uuid.UUID]bool)
+	owns := func(id *uuid.UUID) bool {
+		if id == nil || m.authz == nil {
+			return false
+		}
+		o, ok := owned[*id]
+		if !ok {
+			o = m.authz.VerifyAccountOwnership(ctx, *id) == nil
+			owned[*id] = o
+		}
+		return o
+	}
+
+	return func(p *payment.Payment) Viewer {
+		if owns(p.SourceAccountID) || owns(p.DestinationAccountID) {
+			return Viewer{Role: ViewerOwner}
+		}
+		return nonOwner
+	}
+}
+
+// maskIdentifier keeps the last four characters so support can still match
+// an identifier the customer quotes.
+func maskIdentifier(s string) string {
+	const visible = 4
+	if len(s) <= visible {
+		return strings.Repeat("*", len(s))
+	}
+	return strings.Repeat("*", len(s)-visible) + s[len(s)-visible:]
+}
+
+func applyMasking(resp *PaymentResponse, v Viewer) {
+	if v.redacts(FieldIdempotencyKey) {
+		resp.IdempotencyKey = maskIdentifier(resp.IdempotencyKey)
+	}
+	if v.redacts(FieldMetadata) {
+		resp.Metadata = nil
+	}
+	if v.redacts(FieldProviderTransactionID) && resp.ProviderTransactionID != nil {
+		masked := maskIdentifier(*resp.ProviderTransactionID)
+		resp.ProviderTransactionID = &masked
+	}
+	if v.redacts(FieldLastError) {
+		resp.LastError = nil
+	}
+}
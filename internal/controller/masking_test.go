@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestPaymentController_GetPayment_MasksByViewer(t *testing.T) {
+	accountRepo := testutil.NewMockAccountRepository()
+	owned, _ := account.NewAccount("owner1", 10000, "USD")
+	accountRepo.AddAccount(owned)
+
+	paymentRepo := testutil.NewMockPaymentRepository()
+	p := testutil.NewCompletedPayment(payment.ExternalPayment, &owned.ID, nil, 5000, "USD")
+	p.IdempotencyKey = "order-12345678"
+	p.Metadata = map[string]any{"customer_email": "a@example.com"}
+	txID := "ch_abcdef123456"
+	p.ProviderTransactionID = &txID
+	paymentRepo.Create(context.Background(), p)
+
+	authz := service.NewAuthzService(accountRepo)
+	handler := NewPaymentController(nil, paymentRepo, authz,
+		NewResponseMasking(DefaultMaskingRules(), "payments:admin", "payments:support", authz))
+
+	tests := []struct {
+		name         string
+		userID       string
+		scopes       []string
+		wantKey      string
+		wantMetadata bool
+		wantTxID     string
+	}{
+		{"owner sees everything", "owner1", []string{"payments:admin"}, "order-12345678", true, txID},
+		{"admin", "admin1", []string{"payments:admin"}, "**********5678", true, txID},
+		{"support", "support1", []string{"payments:support"}, "**********5678", false, "***********3456"},
+		{"other", "someone", nil, "**********5678", false, "***********3456"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/payments/"+p.ID.String(), nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", p.ID.String())
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = context.WithValue(ctx, middleware.UserIDKey, tt.userID)
+			ctx = context.WithValue(ctx, middleware.ScopesKey, tt.scopes)
+			rec := httptest.NewRecorder()
+
+			handler.GetPayment(rec, req.WithContext(ctx))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			var resp PaymentResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.IdempotencyKey != tt.wantKey {
+				t.Errorf("idempotency_key = %q, want %q", resp.IdempotencyKey, tt.wantKey)
+			}
+			if (resp.Metadata != nil) != tt.wantMetadata {
+				t.Errorf("metadata present = %v, want %v", resp.Metadata != nil, tt.wantMetadata)
+			}
+			if resp.ProviderTransactionID == nil || *resp.ProviderTransactionID != tt.wantTxID {
+				t.Errorf("provider_transaction_id = %v, want %q", resp.ProviderTransactionID, tt.wantTxID)
+			}
+		})
+	}
+}
+
+func TestParseMaskingRules(t *testing.T) {
+	rules, err := ParseMaskingRules(map[string][]string{"support": {"metadata"}, "Admin": {}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := rules[ViewerSupport]; len(got) != 1 || got[0] != FieldMetadata {
+		t.Errorf("support rules = %v", got)
+	}
+	if _, ok := rules[ViewerAdmin]; !ok {
+		t.Errorf("expected role names to be case-insensitive")
+	}
+
+	for _, cfg := range []map[string][]string{
+		{"owner": {"metadata"}},
+		{"support": {"amount"}},
+	} {
+		if _, err := ParseMaskingRules(cfg); err == nil {
+			t.Errorf("expected error for %v", cfg)
+		}
+	}
+}
@@ -1,33 +1,54 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
 	"github.com/cassiomorais/payments/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// streamIDPattern matches a Redis stream entry ID.
+var streamIDPattern = regexp.MustCompile(`^\d+-\d+$`)
+
 type PaymentController struct {
 	paymentService *service.PaymentService
 	paymentRepo    payment.Repository
 	authzService   *service.AuthzService
+	masking        *ResponseMasking
 }
 
+// NewPaymentController creates the payment handlers. A nil masking renders
+// every field for every viewer.
 func NewPaymentController(
 	paymentService *service.PaymentService,
 	paymentRepo payment.Repository,
 	authzService *service.AuthzService,
+	masking *ResponseMasking,
 ) *PaymentController {
 	return &PaymentController{
 		paymentService: paymentService,
 		paymentRepo:    paymentRepo,
 		authzService:   authzService,
+		masking:        masking,
 	}
 }
 
+// render converts p for the requesting viewer.
+func (h *PaymentController) render(r *http.Request, p *payment.Payment) *PaymentResponse {
+	return FromPayment(p, h.masking.viewerResolver(r.Context())(p))
+}
+
 func (h *PaymentController) CreatePayment(w http.ResponseWriter, r *http.Request) {
 	var req CreatePaymentRequest
 	if err := decodeAndValidate(r, &req); err != nil {
@@ -40,10 +61,13 @@ func (h *PaymentController) CreatePayment(w http.ResponseWriter, r *http.Request
 		idempotencyKey = uuid.New().String()
 	}
 
-	sourceID := parseUUID(*req.SourceAccountID)
-	if sourceID == nil && req.SourceAccountID != nil {
-		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid source_account_id", Code: "invalid_id"})
-		return
+	var sourceID *uuid.UUID
+	if req.SourceAccountID != nil {
+		sourceID = parseUUID(*req.SourceAccountID)
+		if sourceID == nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid source_account_id", Code: "invalid_id"})
+			return
+		}
 	}
 
 	var destID *uuid.UUID
@@ -56,17 +80,28 @@ func (h *PaymentController) CreatePayment(w http.ResponseWriter, r *http.Request
 	}
 
 	// Authorization check
-	if err := h.authzService.VerifyPaymentAuthorization(r.Context(), sourceID); err != nil {
+	if err := h.authzService.Authorize(r.Context(), service.OpCreatePayment, sourceID); err != nil {
 		writeError(w, err)
 		return
 	}
 
 	// Convert with error handling
-	amountCents, err := floatToCents(req.Amount)
+	code := h.paymentService.RequestCurrency(req.Currency)
+	amountCents, err := floatToMinor(req.Amount, code)
 	if err != nil {
 		writeError(w, err)
 		return
 	}
+	feeCents, err := feeToMinor(req.Fee, code)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := h.authzService.VerifyStepUp(r.Context(), sourceID, amountCents); err != nil {
+		writeError(w, err)
+		return
+	}
 
 	var provider *payment.Provider
 	if req.Provider != nil {
@@ -82,17 +117,168 @@ func (h *PaymentController) CreatePayment(w http.ResponseWriter, r *http.Request
 		Amount:               amountCents,
 		Currency:             req.Currency,
 		Provider:             provider,
+		Preference:           parsePreference(r.Header.Values("Prefer")),
+		Description:          req.Description,
+		ExchangeRate:         req.ExchangeRate,
+		ScheduledAt:          req.ScheduledAt,
+		ProviderOptions:      req.ProviderOptions,
+		FeeCents:             feeCents,
+		DryRun:               isDryRun(r),
 	})
 	if err != nil {
 		writeError(w, err)
 		return
 	}
 
-	status := http.StatusCreated
-	if resp.IsAsync {
-		status = http.StatusAccepted
+	if resp.Preview != nil {
+		// A preview leaves the Idempotency-Key for the real request.
+		middleware.SkipIdempotencyStore(r.Context())
+		// Another user's destination balance is not the caller's to see.
+		if destID != nil && h.authzService.Authorize(r.Context(), service.OpGetBalance, destID) != nil {
+			resp.Preview.DestinationBalanceAfter = nil
+		}
+		writeJSON(w, http.StatusOK, FromPaymentPreview(resp.Payment, resp.Preview))
+		return
+	}
+
+	setAuditTarget(r.Context(), resp.Payment.ID)
+	w.Header().Add("Vary", "Prefer")
+	if resp.PreferenceApplied != service.PreferDefault {
+		w.Header().Set("Preference-Applied", string(resp.PreferenceApplied))
+	}
+	writeJSON(w, createStatus(w, resp.Outcome), h.render(r, resp.Payment))
+}
+
+// CreateBatch creates several payments under one Idempotency-Key. Every
+// entry is validated and authorized before any is created, so a malformed
+// or forbidden entry rejects the whole batch. Entries are then created in
+// order and independently: the response is 200 with each entry's own
+// status, payment or error, and one failing does not undo the others.
+func (h *PaymentController) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var req BatchPaymentRequest
+	if err := decodeAndValidate(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	preference := parsePreference(r.Header.Values("Prefer"))
+	items := make([]service.CreatePaymentRequest, len(req.Payments))
+	for i, entry := range req.Payments {
+		item, err := h.batchEntry(r, entry)
+		if err != nil {
+			writeError(w, batchEntryError(i, err))
+			return
+		}
+		item.Preference = preference
+		items[i] = item
+	}
+
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		writeError(w, domainErrors.NewValidationError("Idempotency-Key", "header required for batch requests"))
+		return
+	}
+
+	resp, err := h.paymentService.CreateBatch(r.Context(), service.CreateBatchRequest{
+		IdempotencyKey: idempotencyKey,
+		Payments:       items,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	setAuditTarget(r.Context(), resp.BatchID)
+	out := BatchPaymentResponse{
+		BatchID: resp.BatchID.String(),
+		Results: make([]BatchItemResponse, len(resp.Results)),
+	}
+	viewerOf := h.masking.viewerResolver(r.Context())
+	for i, res := range resp.Results {
+		item := BatchItemResponse{Index: i}
+		if res.Err != nil {
+			status, errResp := errorResponse(res.Err)
+			item.Status, item.Error = status, &errResp
+		} else {
+			item.Status = outcomeStatus(res.Response.Outcome)
+			item.Payment = FromPayment(res.Response.Payment, viewerOf(res.Response.Payment))
+		}
+		out.Results[i] = item
 	}
-	writeJSON(w, status, FromPayment(resp.Payment))
+	w.Header().Add("Vary", "Prefer")
+	writeJSON(w, http.StatusOK, out)
+}
+
+// batchEntry validates, converts and authorizes one batch entry.
+func (h *PaymentController) batchEntry(r *http.Request, entry CreatePaymentRequest) (service.CreatePaymentRequest, error) {
+	if err := validateStruct(entry); err != nil {
+		return service.CreatePaymentRequest{}, err
+	}
+
+	var sourceID, destID *uuid.UUID
+	if entry.SourceAccountID != nil {
+		if sourceID = parseUUID(*entry.SourceAccountID); sourceID == nil {
+			return service.CreatePaymentRequest{}, domainErrors.NewValidationError("source_account_id", "must be a valid UUID")
+		}
+	}
+	if entry.DestinationAccountID != nil {
+		if destID = parseUUID(*entry.DestinationAccountID); destID == nil {
+			return service.CreatePaymentRequest{}, domainErrors.NewValidationError("destination_account_id", "must be a valid UUID")
+		}
+	}
+
+	if err := h.authzService.Authorize(r.Context(), service.OpCreatePayment, sourceID); err != nil {
+		return service.CreatePaymentRequest{}, err
+	}
+	code := h.paymentService.RequestCurrency(entry.Currency)
+	amountCents, err := floatToMinor(entry.Amount, code)
+	if err != nil {
+		return service.CreatePaymentRequest{}, err
+	}
+	feeCents, err := feeToMinor(entry.Fee, code)
+	if err != nil {
+		return service.CreatePaymentRequest{}, err
+	}
+	if err := h.authzService.VerifyStepUp(r.Context(), sourceID, amountCents); err != nil {
+		return service.CreatePaymentRequest{}, err
+	}
+
+	var provider *payment.Provider
+	if entry.Provider != nil {
+		p := payment.Provider(*entry.Provider)
+		provider = &p
+	}
+	return service.CreatePaymentRequest{
+		PaymentType:          payment.PaymentType(entry.PaymentType),
+		SourceAccountID:      sourceID,
+		DestinationAccountID: destID,
+		Amount:               amountCents,
+		Currency:             entry.Currency,
+		Provider:             provider,
+		Description:          entry.Description,
+		ExchangeRate:         entry.ExchangeRate,
+		ScheduledAt:          entry.ScheduledAt,
+		ProviderOptions:      entry.ProviderOptions,
+		FeeCents:             feeCents,
+	}, nil
+}
+
+// batchEntryError names the failing entry in err, keeping its type so it
+// maps to the same status and code.
+func batchEntryError(i int, err error) error {
+	var validationErrs domainErrors.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		named := make(domainErrors.ValidationErrors, len(validationErrs))
+		for j, ve := range validationErrs {
+			named[j] = domainErrors.NewValidationError(fmt.Sprintf("payments[%d].%s", i, ve.Field), ve.Message)
+		}
+		return named
+	}
+	var validationErr *domainErrors.ValidationError
+	if errors.As(err, &validationErr) {
+		return domainErrors.NewValidationError(fmt.Sprintf("payments[%d].%s", i, validationErr.Field), validationErr.Message)
+	}
+	return fmt.Errorf("payments[%d]: %w", i, err)
 }
 
 func (h *PaymentController) GetPayment(w http.ResponseWriter, r *http.Request) {
@@ -108,7 +294,65 @@ func (h *PaymentController) GetPayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, FromPayment(p))
+	writeJSON(w, http.StatusOK, h.render(r, p))
+}
+
+// GetEvents lists a payment's audit trail, oldest first, paginated with
+// limit (default 20, at most 100) and offset.
+func (h *PaymentController) GetEvents(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid payment id", Code: "invalid_id"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	events, err := h.paymentService.GetPaymentEvents(r.Context(), id, limit, offset)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := make([]*PaymentEventResponse, 0, len(events))
+	for _, e := range events {
+		resp = append(resp, FromPaymentEvent(e))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetTimeline returns a payment's events and the account transactions it
+// generated, merged oldest first. Balances are shown only for accounts whose
+// balance the caller may read.
+func (h *PaymentController) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid payment id", Code: "invalid_id"})
+		return
+	}
+
+	entries, err := h.paymentService.PaymentTimeline(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	visible := make(map[uuid.UUID]bool)
+	balanceVisible := func(accountID uuid.UUID) bool {
+		v, ok := visible[accountID]
+		if !ok {
+			v = h.authzService.Authorize(r.Context(), service.OpGetBalance, &accountID) == nil
+			visible[accountID] = v
+		}
+		return v
+	}
+
+	resp := make([]*TimelineEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		resp = append(resp, FromTimelineEntry(e, e.Transaction != nil && balanceVisible(e.Transaction.AccountID)))
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (h *PaymentController) ListPayments(w http.ResponseWriter, r *http.Request) {
@@ -124,10 +368,47 @@ func (h *PaymentController) ListPayments(w http.ResponseWriter, r *http.Request)
 			filter.AccountID = &id
 		}
 	}
+	if s := r.URL.Query().Get("batch_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			writeError(w, domainErrors.NewValidationError("batch_id", "must be a valid UUID"))
+			return
+		}
+		filter.BatchID = &id
+	}
 	if s := r.URL.Query().Get("provider"); s != "" {
 		prov := payment.Provider(s)
 		filter.Provider = &prov
 	}
+	if s := r.URL.Query().Get("currency"); s != "" {
+		code := strings.ToUpper(s)
+		filter.Currency = &code
+	}
+	var err error
+	if filter.MinAmountCents, err = parseAmountParam(r, "min_amount", filter.Currency); err != nil {
+		writeError(w, err)
+		return
+	}
+	if filter.MaxAmountCents, err = parseAmountParam(r, "max_amount", filter.Currency); err != nil {
+		writeError(w, err)
+		return
+	}
+	if filter.MinAmountCents != nil && filter.MaxAmountCents != nil && *filter.MinAmountCents > *filter.MaxAmountCents {
+		writeError(w, domainErrors.NewValidationError("min_amount", "must not exceed max_amount"))
+		return
+	}
+	if filter.CreatedAfter, err = parseTimeParam(r, "created_after"); err != nil {
+		writeError(w, err)
+		return
+	}
+	if filter.CreatedBefore, err = parseTimeParam(r, "created_before"); err != nil {
+		writeError(w, err)
+		return
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && filter.CreatedAfter.After(*filter.CreatedBefore) {
+		writeError(w, domainErrors.NewValidationError("created_after", "must not be after created_before"))
+		return
+	}
 	filter.Limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
 	filter.Offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
 	filter.SortBy = r.URL.Query().Get("sort_by")
@@ -138,10 +419,21 @@ func (h *PaymentController) ListPayments(w http.ResponseWriter, r *http.Request)
 		writeError(w, err)
 		return
 	}
+	total, err := h.paymentRepo.Count(r.Context(), filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
-	resp := make([]*PaymentResponse, 0, len(payments))
+	viewerOf := h.masking.viewerResolver(r.Context())
+	resp := PaymentListResponse{
+		Data:   make([]*PaymentResponse, 0, len(payments)),
+		Limit:  filter.PageLimit(),
+		Offset: filter.Offset,
+		Total:  total,
+	}
 	for _, p := range payments {
-		resp = append(resp, FromPayment(p))
+		resp.Data = append(resp.Data, FromPayment(p, viewerOf(p)))
 	}
 	writeJSON(w, http.StatusOK, resp)
 }
@@ -159,7 +451,7 @@ func (h *PaymentController) RefundPayment(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	writeJSON(w, http.StatusOK, FromPayment(p))
+	writeJSON(w, http.StatusOK, h.render(r, p))
 }
 
 func (h *PaymentController) CancelPayment(w http.ResponseWriter, r *http.Request) {
@@ -169,24 +461,172 @@ func (h *PaymentController) CancelPayment(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	p, err := h.paymentRepo.GetByID(r.Context(), id)
+	resp, err := h.paymentService.CancelPayment(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if resp.InFlight {
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, h.render(r, resp.Payment))
+}
+
+// ReemitEvents re-queues a payment's stored events through the outbox so a
+// downstream consumer that missed them can recover. Admin only.
+func (h *PaymentController) ReemitEvents(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid payment id", Code: "invalid_id"})
+		return
+	}
+
+	resp, err := h.paymentService.ReemitPaymentEvents(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, FromReemit(resp))
+}
+
+// ListDeadLetters lists dead-lettered stream messages, oldest first.
+func (h *PaymentController) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	entries, err := h.paymentService.ListDeadLetters(r.Context(), limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := make([]*DeadLetterResponse, 0, len(entries))
+	for _, e := range entries {
+		resp = append(resp, FromDLQEntry(e))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ReplayDeadLetter re-enqueues a dead-lettered payment message.
+func (h *PaymentController) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "entryID")
+	if !streamIDPattern.MatchString(id) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid dead letter id", Code: "invalid_id"})
+		return
+	}
+
+	entry, err := h.paymentService.ReplayFromDLQ(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, FromDLQEntry(*entry))
+}
+
+// ListCircuitBreakers reports every provider's circuit breaker in this API
+// instance.
+func (h *PaymentController) ListCircuitBreakers(w http.ResponseWriter, r *http.Request) {
+	statuses := h.paymentService.CircuitBreakers()
+	resp := make([]*CircuitBreakerResponse, 0, len(statuses))
+	for _, s := range statuses {
+		resp = append(resp, FromBreakerStatus(s))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ResetCircuitBreaker forces a provider's circuit breaker closed here and in
+// the workers.
+func (h *PaymentController) ResetCircuitBreaker(w http.ResponseWriter, r *http.Request) {
+	status, err := h.paymentService.ResetCircuitBreaker(r.Context(), payment.Provider(chi.URLParam(r, "provider")))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, FromBreakerStatus(*status))
+}
+
+// ApproveReview completes a payment held for review.
+func (h *PaymentController) ApproveReview(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid payment id", Code: "invalid_id"})
+		return
+	}
+
+	p, err := h.paymentService.ApproveReview(r.Context(), id)
 	if err != nil {
 		writeError(w, err)
 		return
 	}
 
-	if err := p.MarkCancelled(); err != nil {
+	writeJSON(w, http.StatusOK, h.render(r, p))
+}
+
+// RejectReview fails a payment held for review and releases its funds.
+func (h *PaymentController) RejectReview(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid payment id", Code: "invalid_id"})
+		return
+	}
+
+	var req RejectReviewRequest
+	if err := decodeAndValidate(r, &req); err != nil {
 		writeError(w, err)
 		return
 	}
-	if err := h.paymentRepo.Update(r.Context(), p); err != nil {
+
+	p, err := h.paymentService.RejectReview(r.Context(), id, req.Reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.render(r, p))
+}
+
+// AdjustBalance credits or debits an account by hand, recording the reason
+// with the transaction. Admin only.
+func (h *PaymentController) AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid account id", Code: "invalid_id"})
+		return
+	}
+
+	var req AdjustBalanceRequest
+	if err := decodeAndValidate(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	txType := account.TransactionAdjustmentCredit
+	if req.Type == "debit" {
+		txType = account.TransactionAdjustmentDebit
+	}
+
+	acct, tx, err := h.paymentService.AdjustBalance(r.Context(), service.BalanceAdjustment{
+		AccountID:   id,
+		Type:        txType,
+		AmountCents: req.AmountCents,
+		Reason:      req.Reason,
+	})
+	if err != nil {
 		writeError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, FromPayment(p))
+	writeJSON(w, http.StatusOK, AdjustBalanceResponse{
+		Account:     FromAccount(acct),
+		Transaction: FromTransaction(tx, acct.Currency),
+	})
 }
 
+// Transfer moves funds between two accounts. The caller must own the source
+// account; the destination may belong to anyone and is only required to
+// exist and be active, which the payment service checks.
 func (h *PaymentController) Transfer(w http.ResponseWriter, r *http.Request) {
 	var req TransferRequest
 	if err := decodeAndValidate(r, &req); err != nil {
@@ -210,30 +650,190 @@ func (h *PaymentController) Transfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Authorization check
-	if err := h.authzService.VerifyAccountOwnership(r.Context(), sourceID); err != nil {
+	// Only the source is authorized: sending to another user's account is
+	// the normal case.
+	if err := h.authzService.Authorize(r.Context(), service.OpTransfer, &sourceID); err != nil {
 		writeError(w, err)
 		return
 	}
 
 	// Convert with error handling
-	amountCents, err := floatToCents(req.Amount)
+	amountCents, err := floatToMinor(req.Amount, h.paymentService.RequestCurrency(req.Currency))
 	if err != nil {
 		writeError(w, err)
 		return
 	}
 
+	if err := h.authzService.VerifyStepUp(r.Context(), &sourceID, amountCents); err != nil {
+		writeError(w, err)
+		return
+	}
+
 	resp, err := h.paymentService.Transfer(r.Context(), service.TransferRequest{
 		IdempotencyKey:       idempotencyKey,
 		SourceAccountID:      sourceID,
 		DestinationAccountID: destID,
 		Amount:               amountCents,
 		Currency:             req.Currency,
+		Description:          req.Description,
+		ExchangeRate:         req.ExchangeRate,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	setAuditTarget(r.Context(), resp.Payment.ID)
+	writeJSON(w, createStatus(w, resp.Outcome), h.render(r, resp.Payment))
+}
+
+// TransferToNewAccount transfers into the destination user's account,
+// creating it in the same transaction if it does not exist.
+func (h *PaymentController) TransferToNewAccount(w http.ResponseWriter, r *http.Request) {
+	var req TransferToNewAccountRequest
+	if err := decodeAndValidate(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = uuid.New().String()
+	}
+
+	sourceID, err := uuid.Parse(req.SourceAccountID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid source_account_id", Code: "invalid_id"})
+		return
+	}
+
+	if err := h.authzService.Authorize(r.Context(), service.OpTransferToNewAccount, &sourceID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	amountCents, err := floatToMinor(req.Amount, h.paymentService.RequestCurrency(req.Currency))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := h.authzService.VerifyStepUp(r.Context(), &sourceID, amountCents); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	destUserID := req.DestinationUserID
+	if destUserID == "" {
+		destUserID, _ = middleware.GetUserID(r.Context())
+	}
+
+	resp, err := h.paymentService.TransferToNewAccount(r.Context(), service.TransferToNewAccountRequest{
+		IdempotencyKey:    idempotencyKey,
+		SourceAccountID:   sourceID,
+		DestinationUserID: destUserID,
+		Amount:            amountCents,
+		Currency:          req.Currency,
+		Description:       req.Description,
 	})
 	if err != nil {
 		writeError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, FromPayment(resp.Payment))
+	setAuditTarget(r.Context(), resp.Payment.ID)
+	writeJSON(w, createStatus(w, resp.Outcome), TransferToNewAccountResponse{
+		Payment:            h.render(r, resp.Payment),
+		DestinationAccount: FromAccount(resp.Destination),
+		DestinationCreated: resp.DestinationCreated,
+	})
+}
+
+// DryRunHeader asks, like the dry_run query parameter, for a preview of a
+// payment instead of creating it.
+const DryRunHeader = "Dry-Run"
+
+// isDryRun reports whether r asks for a dry run, through ?dry_run=true or
+// the Dry-Run header.
+func isDryRun(r *http.Request) bool {
+	for _, v := range []string{r.URL.Query().Get("dry_run"), r.Header.Get(DryRunHeader)} {
+		if ok, _ := strconv.ParseBool(v); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePreference extracts the processing preference from Prefer header values.
+// Other preferences and parameters are ignored; the first of respond-async or
+// respond-sync wins.
+func parsePreference(values []string) service.ProcessingPreference {
+	for _, v := range values {
+		for _, pref := range strings.Split(v, ",") {
+			token, _, _ := strings.Cut(pref, ";")
+			switch service.ProcessingPreference(strings.ToLower(strings.TrimSpace(token))) {
+			case service.PreferAsync:
+				return service.PreferAsync
+			case service.PreferSync:
+				return service.PreferSync
+			}
+		}
+	}
+	return service.PreferDefault
+}
+
+// createStatus maps a create outcome to its HTTP status, flagging replays
+// with the X-Idempotency-Replayed header.
+func createStatus(w http.ResponseWriter, outcome service.CreateOutcome) int {
+	if outcome == service.OutcomeAlreadyExists {
+		w.Header().Set("X-Idempotency-Replayed", "true")
+	}
+	return outcomeStatus(outcome)
+}
+
+// outcomeStatus maps a create outcome to its HTTP status.
+func outcomeStatus(outcome service.CreateOutcome) int {
+	switch outcome {
+	case service.OutcomeAccepted:
+		return http.StatusAccepted
+	case service.OutcomeAlreadyExists:
+		return http.StatusOK
+	default:
+		return http.StatusCreated
+	}
+}
+
+// parseTimeParam parses an optional RFC 3339 query parameter.
+func parseTimeParam(r *http.Request, name string) (*time.Time, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, domainErrors.NewValidationError(name, "must be an RFC 3339 timestamp")
+	}
+	return &t, nil
+}
+
+// parseAmountParam converts an optional decimal amount query parameter to
+// minor units of code, returning nil when it is absent. Minor units differ
+// between currencies, so a bound without a currency filter is rejected.
+func parseAmountParam(r *http.Request, name string, code *string) (*int64, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	if code == nil {
+		return nil, domainErrors.NewValidationError(name, "requires a currency filter")
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, domainErrors.NewValidationError(name, "must be a number")
+	}
+	cents, err := floatToMinor(f, *code)
+	if err != nil {
+		return nil, domainErrors.NewValidationError(name, "must be a positive amount")
+	}
+	return &cents, nil
 }
@@ -7,15 +7,20 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/account"
 	"github.com/cassiomorais/payments/internal/domain/outbox"
 	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
 	"github.com/cassiomorais/payments/internal/middleware"
 	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/repository/postgres"
 	"github.com/cassiomorais/payments/internal/service"
 	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestPaymentController_CreatePayment(t *testing.T) {
@@ -27,7 +32,7 @@ func TestPaymentController_CreatePayment(t *testing.T) {
 
 	paymentService := service.NewPaymentService(paymentRepo, accountRepo, outboxRepo, txManager, providerFactory)
 	authzService := service.NewAuthzService(accountRepo)
-	handler := NewPaymentController(paymentService, paymentRepo, authzService)
+	handler := NewPaymentController(paymentService, paymentRepo, authzService, nil)
 
 	// Create a test source account
 	sourceAcct, _ := account.NewAccount("user1", 10000, "USD")
@@ -40,9 +45,6 @@ func TestPaymentController_CreatePayment(t *testing.T) {
 	paymentRepo.CreateFunc = func(ctx context.Context, p *payment.Payment) error {
 		return nil
 	}
-	paymentRepo.GetByIdempotencyKeyFunc = func(ctx context.Context, key string) (*payment.Payment, error) {
-		return nil, nil // Not found, will create new
-	}
 	outboxRepo.InsertFunc = func(ctx context.Context, entry *outbox.Entry) error {
 		return nil
 	}
@@ -57,6 +59,7 @@ func TestPaymentController_CreatePayment(t *testing.T) {
 		Amount:          50.0,
 		Currency:        "USD",
 		Provider:        stringPtr("stripe"),
+		ProviderOptions: map[string]map[string]string{"stripe": {"customer_id": "cus_123"}},
 	}
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/payments", bytes.NewReader(body))
@@ -75,6 +78,702 @@ func TestPaymentController_CreatePayment(t *testing.T) {
 	}
 }
 
+func TestPaymentController_CreatePayment_ExternalWithoutSource(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		required bool
+		want     int
+	}{
+		{"source required", true, http.StatusBadRequest},
+		{"source not required", false, http.StatusAccepted},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			paymentRepo := testutil.NewMockPaymentRepository()
+			accountRepo := testutil.NewMockAccountRepository()
+			paymentService := service.NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+				testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe")),
+				service.WithExternalSourceRequired(tc.required))
+			handler := NewPaymentController(paymentService, paymentRepo, service.NewAuthzService(accountRepo), nil)
+
+			body, _ := json.Marshal(CreatePaymentRequest{
+				PaymentType: "external_payment",
+				Amount:      50.0,
+				Currency:    "USD",
+				Provider:    stringPtr("stripe"),
+			})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/payments", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "user1"))
+			rec := httptest.NewRecorder()
+
+			handler.CreatePayment(rec, req)
+
+			if rec.Code != tc.want {
+				t.Fatalf("expected status %d, got %d: %s", tc.want, rec.Code, rec.Body.String())
+			}
+			if tc.required && !bytes.Contains(rec.Body.Bytes(), []byte("source_account_id")) {
+				t.Errorf("expected a source_account_id validation error, got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPaymentController_CreatePayment_TransferWithoutSource(t *testing.T) {
+	for _, prefer := range []string{"", "respond-async"} {
+		paymentRepo := testutil.NewMockPaymentRepository()
+		accountRepo := testutil.NewMockAccountRepository()
+		paymentService := service.NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+			testutil.NewMockTransactionManager(), providers.NewFactory())
+		handler := NewPaymentController(paymentService, paymentRepo, service.NewAuthzService(accountRepo), nil)
+
+		destAcct, _ := account.NewAccount("user2", 0, "USD")
+		accountRepo.AddAccount(destAcct)
+		destID := destAcct.ID.String()
+		body, _ := json.Marshal(CreatePaymentRequest{
+			PaymentType:          "internal_transfer",
+			DestinationAccountID: &destID,
+			Amount:               50.0,
+			Currency:             "USD",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/payments", bytes.NewReader(body))
+		req.Header.Set("Prefer", prefer)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "user1"))
+		rec := httptest.NewRecorder()
+
+		handler.CreatePayment(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("prefer %q: expected status %d, got %d: %s", prefer, http.StatusBadRequest, rec.Code, rec.Body.String())
+		}
+		if !bytes.Contains(rec.Body.Bytes(), []byte("source_account_id")) {
+			t.Errorf("prefer %q: expected a source_account_id validation error, got %s", prefer, rec.Body.String())
+		}
+	}
+}
+
+func TestPaymentController_CreatePayment_IdempotentReplay(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	paymentService := service.NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{}, testutil.NewMockTransactionManager(), providers.NewFactory())
+	authzService := service.NewAuthzService(accountRepo)
+	handler := NewPaymentController(paymentService, paymentRepo, authzService, nil)
+
+	sourceAcct, _ := account.NewAccount("user1", 10000, "USD")
+	accountRepo.AddAccount(sourceAcct)
+
+	existing := testutil.NewTestPayment(payment.ExternalPayment, &sourceAcct.ID, nil, 5000, "USD")
+	existing.IdempotencyKey = "replayed-key"
+	existing.UserID = "user1"
+	existing.SetProvider(payment.ProviderStripe)
+	paymentRepo.Create(context.Background(), existing)
+
+	sourceIDStr := sourceAcct.ID.String()
+	body, _ := json.Marshal(CreatePaymentRequest{
+		PaymentType:     "external_payment",
+		SourceAccountID: &sourceIDStr,
+		Amount:          50.0,
+		Currency:        "USD",
+		Provider:        stringPtr("stripe"),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/payments", bytes.NewReader(body))
+	req.Header.Set("Idempotency-Key", "replayed-key")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "user1"))
+
+	rec := httptest.NewRecorder()
+	handler.CreatePayment(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Idempotency-Replayed"); got != "true" {
+		t.Errorf("expected X-Idempotency-Replayed header true, got %q", got)
+	}
+}
+
+func TestPaymentController_CreatePayment_PreferRespondAsync(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	paymentService := service.NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{}, testutil.NewMockTransactionManager(), providers.NewFactory())
+	authzService := service.NewAuthzService(accountRepo)
+	handler := NewPaymentController(paymentService, paymentRepo, authzService, nil)
+
+	sourceAcct, _ := account.NewAccount("user1", 10000, "USD")
+	destAcct, _ := account.NewAccount("user2", 0, "USD")
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	sourceIDStr, destIDStr := sourceAcct.ID.String(), destAcct.ID.String()
+	body, _ := json.Marshal(CreatePaymentRequest{
+		PaymentType:          "internal_transfer",
+		SourceAccountID:      &sourceIDStr,
+		DestinationAccountID: &destIDStr,
+		Amount:               25.0,
+		Currency:             "USD",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/payments", bytes.NewReader(body))
+	req.Header.Set("Prefer", "wait=10, respond-async")
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "user1"))
+
+	rec := httptest.NewRecorder()
+	handler.CreatePayment(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("expected status %d, got %d: %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Preference-Applied"); got != "respond-async" {
+		t.Errorf("expected Preference-Applied respond-async, got %q", got)
+	}
+}
+
+func TestPaymentController_CreatePayment_DryRun(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	paymentService := service.NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{}, testutil.NewMockTransactionManager(), providers.NewFactory())
+	handler := NewPaymentController(paymentService, paymentRepo, service.NewAuthzService(accountRepo), nil)
+
+	sourceAcct, _ := account.NewAccount("user1", 10000, "USD")
+	ownDest, _ := account.NewAccount("user1", 500, "USD")
+	otherDest, _ := account.NewAccount("user2", 700, "USD")
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(ownDest)
+	accountRepo.AddAccount(otherDest)
+
+	preview := func(dest *account.Account, target string, header bool) *PaymentPreviewResponse {
+		t.Helper()
+		sourceIDStr, destIDStr := sourceAcct.ID.String(), dest.ID.String()
+		body, _ := json.Marshal(CreatePaymentRequest{
+			PaymentType:          "internal_transfer",
+			SourceAccountID:      &sourceIDStr,
+			DestinationAccountID: &destIDStr,
+			Amount:               25.0,
+			Currency:             "USD",
+		})
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+		if header {
+			req.Header.Set(DryRunHeader, "true")
+		}
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "user1"))
+		rec := httptest.NewRecorder()
+		handler.CreatePayment(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var resp PaymentPreviewResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return &resp
+	}
+
+	resp := preview(ownDest, "/api/v1/payments?dry_run=true", false)
+	if !resp.DryRun || resp.Processing != "sync" {
+		t.Errorf("expected a sync dry run, got %+v", resp)
+	}
+	if resp.SourceBalanceAfter == nil || *resp.SourceBalanceAfter != 75.0 {
+		t.Errorf("expected source balance after 75.00, got %v", resp.SourceBalanceAfter)
+	}
+	if resp.DestinationBalanceAfter == nil || *resp.DestinationBalanceAfter != 30.0 {
+		t.Errorf("expected destination balance after 30.00, got %v", resp.DestinationBalanceAfter)
+	}
+
+	resp = preview(otherDest, "/api/v1/payments", true)
+	if resp.DestinationBalanceAfter != nil {
+		t.Errorf("another user's balance must not be shown, got %v", *resp.DestinationBalanceAfter)
+	}
+	if got := accountRepo.GetAccountByID(sourceAcct.ID).Balance; got != 10000 {
+		t.Errorf("dry runs must not move money, source balance %d", got)
+	}
+}
+
+// memIdempotencyStore keeps idempotency entries in memory, ignoring their
+// expiry.
+type memIdempotencyStore struct {
+	entries map[string]*postgres.IdempotencyEntry
+}
+
+func (s *memIdempotencyStore) Get(ctx context.Context, key string) (*postgres.IdempotencyEntry, error) {
+	return s.entries[key], nil
+}
+
+func (s *memIdempotencyStore) Set(ctx context.Context, entry *postgres.IdempotencyEntry) error {
+	s.entries[entry.Key] = entry
+	return nil
+}
+
+func TestRouter_DryRunLeavesIdempotencyKeyUnused(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	source := testutil.NewTestAccount("owner", 10000, "USD")
+	dest := testutil.NewTestAccount("payee", 0, "USD")
+	accountRepo.AddAccount(source)
+	accountRepo.AddAccount(dest)
+	router := NewRouter(RouterDeps{
+		PaymentService: service.NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+			testutil.NewMockTransactionManager(), providers.NewFactory()),
+		PaymentRepo:     paymentRepo,
+		AccountService:  service.NewAccountService(accountRepo),
+		IdempotencyRepo: &memIdempotencyStore{entries: map[string]*postgres.IdempotencyEntry{}},
+		Metrics:         observability.NewMetrics("test", prometheus.NewRegistry()),
+		JWTSecret:       testJWTSecret,
+		AuthzService:    service.NewAuthzService(accountRepo),
+	})
+
+	sourceID, destID := source.ID.String(), dest.ID.String()
+	create := func(dryRun bool) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(CreatePaymentRequest{
+			PaymentType:          "internal_transfer",
+			SourceAccountID:      &sourceID,
+			DestinationAccountID: &destID,
+			Amount:               25.0,
+			Currency:             "USD",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/payments", bytes.NewReader(body))
+		req.Header.Set("Authorization", bearer(t, "owner"))
+		req.Header.Set("Idempotency-Key", "order-42")
+		if dryRun {
+			req.Header.Set(DryRunHeader, "true")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	preview := create(true)
+	if preview.Code != http.StatusOK {
+		t.Fatalf("dry run: expected status %d, got %d: %s", http.StatusOK, preview.Code, preview.Body.String())
+	}
+	created := create(false)
+	if created.Code != http.StatusCreated && created.Code != http.StatusAccepted {
+		t.Fatalf("create: expected the payment to be created, got %d: %s", created.Code, created.Body.String())
+	}
+	if created.Header().Get("X-Idempotency-Replayed") != "" {
+		t.Errorf("create: the preview must not be replayed, got %s", created.Body.String())
+	}
+	if payments, _ := paymentRepo.List(context.Background(), payment.ListFilter{}); len(payments) != 1 {
+		t.Errorf("expected 1 payment, got %d", len(payments))
+	}
+
+	replay := create(false)
+	if replay.Header().Get("X-Idempotency-Replayed") != "true" || replay.Body.String() != created.Body.String() {
+		t.Errorf("replay: expected the stored create response, got %d %s", replay.Code, replay.Body.String())
+	}
+}
+
+func serveTransfer(handler *PaymentController, userID string, source, dest *account.Account) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(TransferRequest{
+		SourceAccountID:      source.ID.String(),
+		DestinationAccountID: dest.ID.String(),
+		Amount:               25.0,
+		Currency:             "USD",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+	rec := httptest.NewRecorder()
+	handler.Transfer(rec, req)
+	return rec
+}
+
+func TestPaymentController_Transfer_Authorization(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	paymentService := service.NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{}, testutil.NewMockTransactionManager(), providers.NewFactory())
+	handler := NewPaymentController(paymentService, paymentRepo, service.NewAuthzService(accountRepo), nil)
+
+	user1Acct, _ := account.NewAccount("user1", 10000, "USD")
+	user2Acct, _ := account.NewAccount("user2", 500, "USD")
+	closedAcct, _ := account.NewAccount("user2", 0, "USD")
+	closedAcct.Status = account.StatusInactive
+	accountRepo.AddAccount(user1Acct)
+	accountRepo.AddAccount(user2Acct)
+	accountRepo.AddAccount(closedAcct)
+
+	if rec := serveTransfer(handler, "user2", user1Acct, user2Acct); rec.Code != http.StatusForbidden {
+		t.Errorf("transfer out of another user's account: expected status %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
+	}
+	if got := accountRepo.GetAccountByID(user1Acct.ID).Balance; got != 10000 {
+		t.Errorf("a forbidden transfer must not move money, source balance %d", got)
+	}
+
+	if rec := serveTransfer(handler, "user1", user1Acct, user2Acct); rec.Code != http.StatusCreated {
+		t.Fatalf("transfer to another user's account: expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if got := accountRepo.GetAccountByID(user2Acct.ID).Balance; got != 3000 {
+		t.Errorf("expected destination balance 3000, got %d", got)
+	}
+
+	if rec := serveTransfer(handler, "user1", user1Acct, closedAcct); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("transfer to an inactive account: expected status %d, got %d: %s", http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	}
+}
+
+func serveBatch(handler *PaymentController, key string, body BatchPaymentRequest) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/payments/batch", bytes.NewReader(b))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "user1"))
+	rec := httptest.NewRecorder()
+	handler.CreateBatch(rec, req)
+	return rec
+}
+
+func TestPaymentController_CreateBatch(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	paymentService := service.NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{}, testutil.NewMockTransactionManager(), providers.NewFactory())
+	handler := NewPaymentController(paymentService, paymentRepo, service.NewAuthzService(accountRepo), nil)
+
+	source, _ := account.NewAccount("user1", 10000, "USD")
+	dest, _ := account.NewAccount("user2", 0, "USD")
+	accountRepo.AddAccount(source)
+	accountRepo.AddAccount(dest)
+	sourceID, destID := source.ID.String(), dest.ID.String()
+	transfer := func(amount float64) CreatePaymentRequest {
+		return CreatePaymentRequest{PaymentType: "internal_transfer", SourceAccountID: &sourceID,
+			DestinationAccountID: &destID, Amount: amount, Currency: "USD"}
+	}
+
+	rec := serveBatch(handler, "batch-key", BatchPaymentRequest{Payments: []CreatePaymentRequest{transfer(10), transfer(500)}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp BatchPaymentResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(resp.Results))
+	}
+	if ok := resp.Results[0]; ok.Status != http.StatusCreated || ok.Payment == nil || ok.Payment.BatchID == nil || *ok.Payment.BatchID != resp.BatchID {
+		t.Errorf("expected entry 0 created in batch %s, got %+v", resp.BatchID, ok)
+	}
+	if failed := resp.Results[1]; failed.Status != http.StatusUnprocessableEntity || failed.Error == nil || failed.Error.Code != "insufficient_funds" {
+		t.Errorf("expected entry 1 to fail with insufficient_funds, got %+v", failed)
+	}
+}
+
+func TestPaymentController_CreateBatch_RejectedUpFront(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	paymentService := service.NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{}, testutil.NewMockTransactionManager(), providers.NewFactory())
+	handler := NewPaymentController(paymentService, paymentRepo, service.NewAuthzService(accountRepo), nil)
+
+	source, _ := account.NewAccount("user1", 10000, "USD")
+	accountRepo.AddAccount(source)
+	sourceID, badID := source.ID.String(), "not-a-uuid"
+	valid := CreatePaymentRequest{PaymentType: "external_payment", SourceAccountID: &sourceID, Amount: 10, Currency: "USD", Provider: stringPtr("stripe")}
+	invalid := valid
+	invalid.DestinationAccountID = &badID
+
+	rec := serveBatch(handler, "batch-key", BatchPaymentRequest{Payments: []CreatePaymentRequest{valid, invalid}})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+	if !bytes.Contains(rec.Body.Bytes(), []byte("payments[1].destination_account_id")) {
+		t.Errorf("expected the failing entry to be named, got %s", rec.Body.String())
+	}
+	if payments, _ := paymentRepo.List(context.Background(), payment.ListFilter{}); len(payments) != 0 {
+		t.Errorf("expected no payments created, got %d", len(payments))
+	}
+
+	rec = serveBatch(handler, "", BatchPaymentRequest{Payments: []CreatePaymentRequest{valid}})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without Idempotency-Key, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestParsePreference(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   service.ProcessingPreference
+	}{
+		{nil, service.PreferDefault},
+		{[]string{"return=minimal"}, service.PreferDefault},
+		{[]string{"respond-async"}, service.PreferAsync},
+		{[]string{"Respond-Sync; foo=bar"}, service.PreferSync},
+		{[]string{"return=minimal", "respond-async, respond-sync"}, service.PreferAsync},
+	}
+	for _, tt := range tests {
+		if got := parsePreference(tt.values); got != tt.want {
+			t.Errorf("parsePreference(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestPaymentController_ListPayments_AmountRange(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	handler := NewPaymentController(nil, paymentRepo, nil, nil)
+
+	var got payment.ListFilter
+	paymentRepo.ListFunc = func(ctx context.Context, filter payment.ListFilter) ([]*payment.Payment, error) {
+		got = filter
+		return nil, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/payments?currency=usd&min_amount=10000&max_amount=25000.50&status=completed&sort_by=amount", nil)
+	rec := httptest.NewRecorder()
+	handler.ListPayments(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got.Currency == nil || *got.Currency != "USD" {
+		t.Errorf("expected currency USD, got %v", got.Currency)
+	}
+	if got.MinAmountCents == nil || *got.MinAmountCents != 1000000 {
+		t.Errorf("expected min 1000000 cents, got %v", got.MinAmountCents)
+	}
+	if got.MaxAmountCents == nil || *got.MaxAmountCents != 2500050 {
+		t.Errorf("expected max 2500050 cents, got %v", got.MaxAmountCents)
+	}
+	if got.Status == nil || got.SortBy != "amount" {
+		t.Errorf("expected amount range to compose with status and sort, got %+v", got)
+	}
+}
+
+func TestPaymentController_ListPayments_AmountRangeZeroDecimalCurrency(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	handler := NewPaymentController(nil, paymentRepo, nil, nil)
+
+	var got payment.ListFilter
+	paymentRepo.ListFunc = func(ctx context.Context, filter payment.ListFilter) ([]*payment.Payment, error) {
+		got = filter
+		return nil, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/payments?currency=JPY&min_amount=1000&max_amount=5000", nil)
+	rec := httptest.NewRecorder()
+	handler.ListPayments(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	// JPY has no minor unit, so 1000 JPY is 1000 in minor units, not 100000.
+	if got.MinAmountCents == nil || *got.MinAmountCents != 1000 {
+		t.Errorf("expected min 1000, got %v", got.MinAmountCents)
+	}
+	if got.MaxAmountCents == nil || *got.MaxAmountCents != 5000 {
+		t.Errorf("expected max 5000, got %v", got.MaxAmountCents)
+	}
+}
+
+func TestPaymentController_ListPayments_Pagination(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	handler := NewPaymentController(nil, paymentRepo, nil, nil)
+
+	var listed, counted payment.ListFilter
+	paymentRepo.ListFunc = func(ctx context.Context, filter payment.ListFilter) ([]*payment.Payment, error) {
+		listed = filter
+		return []*payment.Payment{
+			testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 1000, "USD"),
+			testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 2000, "USD"),
+		}, nil
+	}
+	paymentRepo.CountFunc = func(ctx context.Context, filter payment.ListFilter) (int, error) {
+		counted = filter
+		return 7, nil
+	}
+
+	tests := []struct {
+		query                 string
+		wantLimit, wantOffset int
+	}{
+		{"?status=completed&limit=2&offset=4", 2, 4},
+		{"?status=completed", payment.DefaultListLimit, 0},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/payments"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		handler.ListPayments(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.query, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var resp PaymentListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: decode response: %v", tt.query, err)
+		}
+		if len(resp.Data) != 2 || resp.Total != 7 {
+			t.Errorf("%s: expected 2 of 7 payments, got %d of %d", tt.query, len(resp.Data), resp.Total)
+		}
+		if resp.Limit != tt.wantLimit || resp.Offset != tt.wantOffset {
+			t.Errorf("%s: expected limit %d offset %d, got %d and %d", tt.query, tt.wantLimit, tt.wantOffset, resp.Limit, resp.Offset)
+		}
+		if counted.Status == nil || *counted.Status != *listed.Status {
+			t.Errorf("%s: expected the count to use the list filters, got %+v", tt.query, counted)
+		}
+	}
+}
+
+func TestPaymentController_ListPayments_DateRange(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	handler := NewPaymentController(nil, paymentRepo, nil, nil)
+
+	var got payment.ListFilter
+	paymentRepo.ListFunc = func(ctx context.Context, filter payment.ListFilter) ([]*payment.Payment, error) {
+		got = filter
+		return nil, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/payments?created_after=2026-03-01T00:00:00Z&created_before=2026-03-08T00:00:00%2B02:00", nil)
+	rec := httptest.NewRecorder()
+	handler.ListPayments(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got.CreatedAfter == nil || !got.CreatedAfter.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected created_after 2026-03-01, got %v", got.CreatedAfter)
+	}
+	if got.CreatedBefore == nil || !got.CreatedBefore.Equal(time.Date(2026, 3, 7, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected created_before 2026-03-07T22:00Z, got %v", got.CreatedBefore)
+	}
+
+	for _, query := range []string{
+		"created_after=yesterday",
+		"created_before=2026-03-08",
+		"created_after=2026-03-08T00:00:00Z&created_before=2026-03-01T00:00:00Z",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/payments?"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ListPayments(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestPaymentController_ListPayments_InvalidAmountRange(t *testing.T) {
+	handler := NewPaymentController(nil, testutil.NewMockPaymentRepository(), nil, nil)
+
+	for _, query := range []string{
+		"currency=USD&min_amount=abc",
+		"currency=USD&max_amount=-5",
+		"currency=USD&min_amount=100&max_amount=50",
+		"currency=JPY&min_amount=1000.5",
+		"min_amount=100",
+		"max_amount=100",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/payments?"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ListPayments(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func serveGetEvents(handler *PaymentController, id, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/payments/"+id+"/events"+query, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+	handler.GetEvents(rec, req)
+	return rec
+}
+
+func TestPaymentController_GetEvents(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	paymentService := service.NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(),
+		&testutil.MockOutboxRepository{}, testutil.NewMockTransactionManager(), providers.NewFactory())
+	handler := NewPaymentController(paymentService, paymentRepo, nil, nil)
+
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 5000, "USD")
+	paymentRepo.Create(context.Background(), p)
+	for _, eventType := range []payment.EventType{payment.EventPaymentCreated, payment.EventPaymentCompleted, payment.EventPaymentRefunded} {
+		paymentRepo.AddEvent(context.Background(), &payment.PaymentEvent{
+			ID: uuid.New(), PaymentID: p.ID, EventType: string(eventType),
+			EventData: map[string]any{"amount_cents": 5000},
+		})
+	}
+
+	rec := serveGetEvents(handler, p.ID.String(), "?limit=2&offset=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var events []PaymentEventResponse
+	if err := json.NewDecoder(rec.Body).Decode(&events); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(events) != 2 || events[0].EventType != string(payment.EventPaymentCompleted) || events[1].EventType != string(payment.EventPaymentRefunded) {
+		t.Errorf("expected completed and refunded events, got %+v", events)
+	}
+	if events[0].EventData["amount_cents"] != float64(5000) {
+		t.Errorf("expected event data to be returned, got %v", events[0].EventData)
+	}
+
+	if rec := serveGetEvents(handler, uuid.New().String(), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown payment: expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	if rec := serveGetEvents(handler, "not-a-uuid", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid id: expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestPaymentController_GetTimeline_HidesCounterpartyBalance(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	paymentService := service.NewPaymentService(paymentRepo, accountRepo,
+		&testutil.MockOutboxRepository{}, testutil.NewMockTransactionManager(), providers.NewFactory())
+	handler := NewPaymentController(paymentService, paymentRepo, service.NewAuthzService(accountRepo), nil)
+
+	source := testutil.NewTestAccount("payer", 10000, "USD")
+	dest := testutil.NewTestAccount("payee", 0, "USD")
+	accountRepo.AddAccount(source)
+	accountRepo.AddAccount(dest)
+	p := testutil.NewTestPayment(payment.InternalTransfer, &source.ID, &dest.ID, 2500, "USD")
+	paymentRepo.Create(context.Background(), p)
+	at := time.Now().UTC()
+	paymentRepo.AddEvent(context.Background(), &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCompleted), CreatedAt: at,
+	})
+	for _, tx := range []*account.Transaction{
+		{ID: uuid.New(), AccountID: source.ID, PaymentID: &p.ID, TransactionType: account.TransactionDebit, Amount: 2500, BalanceAfter: 7500, CreatedAt: at},
+		{ID: uuid.New(), AccountID: dest.ID, PaymentID: &p.ID, TransactionType: account.TransactionCredit, Amount: 2500, BalanceAfter: 2500, CreatedAt: at},
+	} {
+		accountRepo.AddTransaction(context.Background(), tx)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/payments/"+p.ID.String()+"/timeline", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", p.ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	req = req.WithContext(context.WithValue(ctx, middleware.UserIDKey, "payee"))
+	rec := httptest.NewRecorder()
+	handler.GetTimeline(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var entries []TimelineEntryResponse
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(entries) != 3 || entries[0].Kind != "event" || entries[0].Event == nil {
+		t.Fatalf("expected the event followed by two transactions, got %+v", entries)
+	}
+	for _, e := range entries[1:] {
+		if e.Kind != "transaction" || e.Transaction == nil {
+			t.Fatalf("expected a transaction entry, got %+v", e)
+		}
+		switch e.Transaction.AccountID {
+		case source.ID.String():
+			if e.Transaction.BalanceAfter != nil {
+				t.Errorf("the payer's balance must not be shown to the payee, got %v", *e.Transaction.BalanceAfter)
+			}
+		case dest.ID.String():
+			if e.Transaction.BalanceAfter == nil || *e.Transaction.BalanceAfter != 25.0 {
+				t.Errorf("expected the payee's own balance 25.00, got %v", e.Transaction.BalanceAfter)
+			}
+		}
+		if e.Transaction.Amount != 25.0 || e.Transaction.Currency != "USD" {
+			t.Errorf("expected 25.00 USD, got %v %s", e.Transaction.Amount, e.Transaction.Currency)
+		}
+	}
+}
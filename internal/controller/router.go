@@ -1,13 +1,13 @@
 package controller
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/payment"
 	"github.com/cassiomorais/payments/internal/infrastructure/config"
 	"github.com/cassiomorais/payments/internal/infrastructure/observability"
 	customMW "github.com/cassiomorais/payments/internal/middleware"
-	"github.com/cassiomorais/payments/internal/repository/postgres"
 	"github.com/cassiomorais/payments/internal/service"
 	"github.com/go-chi/chi/v5"
 	chimw "github.com/go-chi/chi/v5/middleware"
@@ -23,11 +23,40 @@ type RouterDeps struct {
 	PaymentRepo     payment.Repository
 	AccountService  *service.AccountService
 	PaymentService  *service.PaymentService
-	IdempotencyRepo *postgres.IdempotencyRepository
+	IdempotencyRepo customMW.IdempotencyStore
 	Metrics         *observability.Metrics
 	CORSConfig      config.CORSConfig
 	JWTSecret       string
 	AuthzService    *service.AuthzService
+	// AdminScope and SupportScope are granted by the default authorization
+	// policies; AuthzService may be configured with others.
+	AdminScope string
+	// SupportScope and MaskingRules also control which payment fields
+	// non-owner viewers see.
+	SupportScope string
+	MaskingRules MaskingRules
+	// DisputeWebhookSecret signs provider dispute notifications; empty
+	// disables the endpoint.
+	DisputeWebhookSecret string
+	// StrictQueryParams rejects unknown query parameters on list endpoints
+	// for every request, not only those passing ?strict=true.
+	StrictQueryParams bool
+	// ResponseEnvelope is the JSON body shape for requests that do not
+	// choose one; empty means raw.
+	ResponseEnvelope customMW.EnvelopeMode
+	// MaxBodyBytes caps API request bodies and MaxBatchBodyBytes those of
+	// the batch endpoint; zero uses middleware.DefaultMaxBodyBytes.
+	MaxBodyBytes      int64
+	MaxBatchBodyBytes int64
+	// IdempotencyTTL is how long IdempotencyRepo keeps replayable
+	// responses; zero keeps them until they are deleted.
+	IdempotencyTTL time.Duration
+	// TokenService issues and refreshes access tokens; nil disables the
+	// /api/v1/auth endpoints.
+	TokenService *service.TokenService
+	// AuditLogger records state-changing requests; nil disables auditing
+	// and the audit log endpoint.
+	AuditLogger *service.AuditLogger
 }
 
 func NewRouter(deps RouterDeps) *chi.Mux {
@@ -41,11 +70,12 @@ func NewRouter(deps RouterDeps) *chi.Mux {
 	r.Use(chimw.Logger)
 	r.Use(chimw.Recoverer)
 	r.Use(chimw.Timeout(60 * time.Second))
+	r.Use(customMW.ResponseEnvelope(deps.ResponseEnvelope))
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   deps.CORSConfig.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key", "Prefer"},
+		ExposedHeaders:   []string{"Link", "X-Idempotency-Replayed", "Preference-Applied"},
 		AllowCredentials: deps.CORSConfig.AllowCredentials,
 		MaxAge:           300,
 	}))
@@ -53,12 +83,32 @@ func NewRouter(deps RouterDeps) *chi.Mux {
 
 	healthH := NewHealthController(deps.Pool, deps.RedisClient)
 	accountH := NewAccountController(deps.AccountService, deps.AuthzService)
-	paymentH := NewPaymentController(deps.PaymentService, deps.PaymentRepo, deps.AuthzService)
+	paymentH := NewPaymentController(deps.PaymentService, deps.PaymentRepo, deps.AuthzService,
+		NewResponseMasking(deps.MaskingRules, deps.AdminScope, deps.SupportScope, deps.AuthzService))
+	disputeH := NewDisputeController(deps.PaymentService, deps.DisputeWebhookSecret)
 
-	// Public routes (no auth)
+	// Public routes (no auth); /healthz and /readyz are the Kubernetes
+	// probe paths for liveness and readiness.
 	r.Get("/health", healthH.Health)
 	r.Get("/health/live", healthH.Liveness)
 	r.Get("/health/ready", healthH.Readiness)
+	r.Get("/healthz", healthH.Liveness)
+	r.Get("/readyz", healthH.Readiness)
+
+	// Provider webhooks (authenticated by HMAC signature, not JWT)
+	r.Post("/webhooks/providers/{provider}/disputes", disputeH.Notify)
+
+	// Token issuance (authenticated by client credentials or a refresh
+	// token, not JWT)
+	if deps.TokenService != nil {
+		authH := NewAuthController(deps.TokenService)
+		r.Route("/api/v1/auth", func(r chi.Router) {
+			r.Use(customMW.RateLimit(10))
+			r.Post("/token", authH.Token)
+			r.Post("/refresh", authH.Refresh)
+			r.Post("/revoke", authH.Revoke)
+		})
+	}
 
 	// Metrics endpoint (protected with auth)
 	r.Route("/internal", func(r chi.Router) {
@@ -70,25 +120,94 @@ func NewRouter(deps RouterDeps) *chi.Mux {
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Use(customMW.RequireAuth(deps.JWTSecret)) // Require authentication
 		r.Use(customMW.RateLimit(100))              // Global rate limit: 100 req/min
+		r.Use(customMW.MaxBodySize(deps.MaxBodyBytes))
 
 		// Idempotency middleware for mutating endpoints
-		idempotencyMW := customMW.Idempotency(deps.IdempotencyRepo)
+		idempotencyMW := customMW.Idempotency(deps.IdempotencyRepo, deps.IdempotencyTTL)
+		knownQuery := func(params ...string) func(http.Handler) http.Handler {
+			return customMW.KnownQueryParams(deps.StrictQueryParams, params...)
+		}
+
+		// Per-operation authorization policies (see service.DefaultPolicies)
+		authz := func(op service.Operation, resource resourceFunc) func(http.Handler) http.Handler {
+			return authorize(deps.AuthzService, op, resource)
+		}
+		adminOnly := requireRole(deps.AuthzService, service.RoleAdmin)
+		// Audit trail of state-changing requests; the target ID is read from
+		// the named URL parameter or set by the handler
+		audit := func(op service.Operation, targetParam string) func(http.Handler) http.Handler {
+			return audited(deps.AuditLogger, op, targetParam)
+		}
+		accountID := urlParamID("id", "account id")
+		paymentID := urlParamID("id", "payment id")
 
 		// Accounts
-		r.Post("/accounts", accountH.Create)
-		r.Get("/accounts/{id}", accountH.Get)
-		r.Get("/accounts/{id}/balance", accountH.GetBalance)
-		r.Get("/accounts/{id}/transactions", accountH.GetTransactions)
-
-		// Payments - stricter rate limits (10/min)
-		r.With(idempotencyMW, customMW.RateLimit(10)).Post("/payments", paymentH.CreatePayment)
-		r.Get("/payments/{id}", paymentH.GetPayment)
-		r.Get("/payments", paymentH.ListPayments)
-		r.Post("/payments/{id}/refund", paymentH.RefundPayment)
-		r.Post("/payments/{id}/cancel", paymentH.CancelPayment)
-
-		// Transfers - stricter rate limits (10/min)
-		r.With(idempotencyMW, customMW.RateLimit(10)).Post("/transfers", paymentH.Transfer)
+		r.With(audit(service.OpCreateAccount, ""), authz(service.OpCreateAccount, nil)).Post("/accounts", accountH.Create)
+		r.With(audit(service.OpEnsureAccount, ""), authz(service.OpEnsureAccount, nil)).Put("/accounts", accountH.Ensure)
+		r.With(knownQuery("user_id", "currency", "status", "limit", "offset", "sort_by", "sort_order"), authz(service.OpSearchAccounts, nil)).
+			Get("/accounts", accountH.Search)
+		r.With(authz(service.OpGetAccount, accountID)).Get("/accounts/{id}", accountH.Get)
+		r.With(authz(service.OpGetBalance, accountID)).Get("/accounts/{id}/balance", accountH.GetBalance)
+		r.With(audit(service.OpSuspendAccount, "id"), authz(service.OpSuspendAccount, accountID)).
+			Post("/accounts/{id}/suspend", accountH.Suspend)
+		r.With(audit(service.OpActivateAccount, "id"), authz(service.OpActivateAccount, accountID)).
+			Post("/accounts/{id}/activate", accountH.Activate)
+		r.With(audit(service.OpDeactivateAccount, "id"), authz(service.OpDeactivateAccount, accountID)).
+			Post("/accounts/{id}/deactivate", accountH.Deactivate)
+		r.With(knownQuery("limit", "offset"), authz(service.OpListTransactions, accountID)).
+			Get("/accounts/{id}/transactions", accountH.GetTransactions)
+		r.With(knownQuery("format", "from", "to", "signed"), authz(service.OpExportStatement, accountID)).
+			Get("/accounts/{id}/statement", accountH.Statement)
+		r.With(authz(service.OpVerifyStatement, nil)).Post("/statements/verify", accountH.VerifyStatement)
+
+		// Payments - stricter rate limits (10/min). Creation is authorized by
+		// the handler, as its source account is in the body.
+		r.With(audit(service.OpCreatePayment, ""), idempotencyMW, customMW.RateLimit(10)).Post("/payments", paymentH.CreatePayment)
+		r.With(audit(service.OpCreatePayment, ""), idempotencyMW, customMW.RateLimit(10), customMW.MaxBodySize(deps.MaxBatchBodyBytes)).
+			Post("/payments/batch", paymentH.CreateBatch)
+		r.With(knownQuery("limit"), authz(service.OpListDeadLetters, nil)).Get("/payments/dlq", paymentH.ListDeadLetters)
+		r.With(audit(service.OpReplayDeadLetter, ""), adminOnly, authz(service.OpReplayDeadLetter, nil)).
+			Post("/payments/dlq/{entryID}/replay", paymentH.ReplayDeadLetter)
+		r.With(authz(service.OpGetPayment, paymentID)).Get("/payments/{id}", paymentH.GetPayment)
+		r.With(knownQuery("limit", "offset"), authz(service.OpGetPaymentEvents, paymentID)).
+			Get("/payments/{id}/events", paymentH.GetEvents)
+		r.With(authz(service.OpGetPaymentTimeline, paymentID)).Get("/payments/{id}/timeline", paymentH.GetTimeline)
+		r.With(knownQuery("status", "account_id", "batch_id", "provider", "currency", "min_amount", "max_amount",
+			"created_after", "created_before", "limit", "offset", "sort_by", "sort_order"), authz(service.OpListPayments, queryID("account_id"))).
+			Get("/payments", paymentH.ListPayments)
+		r.With(audit(service.OpRefundPayment, "id"), authz(service.OpRefundPayment, paymentID)).
+			Post("/payments/{id}/refund", paymentH.RefundPayment)
+		r.With(audit(service.OpCancelPayment, "id"), authz(service.OpCancelPayment, paymentID)).
+			Post("/payments/{id}/cancel", paymentH.CancelPayment)
+		r.With(authz(service.OpListDisputes, paymentID)).Get("/payments/{id}/disputes", disputeH.List)
+
+		// Transfers - stricter rate limits (10/min), authorized by the handler
+		r.With(audit(service.OpTransfer, ""), idempotencyMW, customMW.RateLimit(10)).Post("/transfers", paymentH.Transfer)
+		r.With(audit(service.OpTransferToNewAccount, ""), idempotencyMW, customMW.RateLimit(10)).
+			Post("/transfers/to-new-account", paymentH.TransferToNewAccount)
+
+		// Admin
+		r.Route("/admin", func(r chi.Router) {
+			r.With(knownQuery("user_id", "currency", "status", "limit", "offset", "sort_by", "sort_order"), adminOnly, authz(service.OpListAccounts, nil)).
+				Get("/accounts", accountH.List)
+			r.With(adminOnly, authz(service.OpVerifyLedger, accountID)).Get("/accounts/{id}/verify", accountH.VerifyLedger)
+			r.With(audit(service.OpAdjustBalance, "id"), idempotencyMW, adminOnly, authz(service.OpAdjustBalance, accountID)).
+				Post("/accounts/{id}/adjust", paymentH.AdjustBalance)
+			r.With(audit(service.OpReemitEvents, "id"), authz(service.OpReemitEvents, paymentID)).
+				Post("/payments/{id}/reemit-events", paymentH.ReemitEvents)
+			r.With(audit(service.OpApproveReview, "id"), authz(service.OpApproveReview, paymentID)).
+				Post("/payments/{id}/approve", paymentH.ApproveReview)
+			r.With(audit(service.OpRejectReview, "id"), authz(service.OpRejectReview, paymentID)).
+				Post("/payments/{id}/reject", paymentH.RejectReview)
+			r.With(authz(service.OpListCircuitBreakers, nil)).Get("/circuit-breakers", paymentH.ListCircuitBreakers)
+			r.With(audit(service.OpResetCircuitBreaker, ""), adminOnly, authz(service.OpResetCircuitBreaker, nil)).
+				Post("/circuit-breakers/{provider}/reset", paymentH.ResetCircuitBreaker)
+			if deps.AuditLogger != nil {
+				auditH := NewAuditController(deps.AuditLogger)
+				r.With(knownQuery("user_id", "operation", "target_id", "result", "created_after", "created_before", "limit", "offset"),
+					adminOnly, authz(service.OpListAuditLog, nil)).Get("/audit-log", auditH.List)
+			}
+		})
 	})
 
 	return r
@@ -1,9 +1,12 @@
 package account
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/pkg/currency"
 	"github.com/google/uuid"
 )
 
@@ -24,32 +27,82 @@ type Account struct {
 	Status    AccountStatus
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// Label tells apart a user's accounts in the same currency. The empty
+	// label is the user's default account.
+	Label string
+
+	// HeldBalance is the part of Balance reserved by open holds, in cents.
+	HeldBalance int64
+
+	// InitialBalance is the balance the account was opened with, in cents.
+	// Every later change is a transaction, so InitialBalance plus their sum
+	// is Balance.
+	InitialBalance int64
 }
 
-func NewAccount(userID string, initialBalance int64, currency string) (*Account, error) {
+// MaxLabelLength is the longest account label, in bytes.
+const MaxLabelLength = 64
+
+func NewAccount(userID string, initialBalance int64, code string) (*Account, error) {
+	return NewLabeledAccount(userID, "", initialBalance, code)
+}
+
+// NewLabeledAccount returns a new account for userID under label, which
+// must be unique among the user's accounts in code.
+func NewLabeledAccount(userID, label string, initialBalance int64, code string) (*Account, error) {
 	if userID == "" {
 		return nil, errors.NewValidationError("user_id", "cannot be empty")
 	}
 	if initialBalance < 0 {
 		return nil, errors.NewValidationError("initial_balance", "cannot be negative")
 	}
-	if currency == "" {
+	if code == "" {
 		return nil, errors.NewValidationError("currency", "cannot be empty")
 	}
+	if !currency.IsKnown(code) {
+		return nil, errors.NewValidationError("currency", fmt.Sprintf("unsupported currency %q", code))
+	}
+	if len(label) > MaxLabelLength {
+		return nil, errors.NewValidationError("label", fmt.Sprintf("cannot exceed %d bytes", MaxLabelLength))
+	}
 
 	now := time.Now()
 	return &Account{
 		ID:        uuid.New(),
 		UserID:    userID,
 		Balance:   initialBalance,
-		Currency:  currency,
+		Currency:  code,
+		Label:     label,
 		Version:   0,
 		Status:    StatusActive,
 		CreatedAt: now,
 		UpdatedAt: now,
+
+		InitialBalance: initialBalance,
 	}, nil
 }
 
+// AvailableBalance is the balance that can be debited or held: Balance less
+// the funds reserved by open holds.
+func (a *Account) AvailableBalance() int64 {
+	return a.Balance - a.HeldBalance
+}
+
+// CheckAvailable returns an *errors.InsufficientFundsError when amount exceeds
+// the available balance.
+func (a *Account) CheckAvailable(amount int64) error {
+	if available := a.AvailableBalance(); available < amount {
+		return &errors.InsufficientFundsError{
+			Available: available,
+			Reserved:  a.Balance - available,
+			Requested: amount,
+			Currency:  a.Currency,
+		}
+	}
+	return nil
+}
+
 func (a *Account) Debit(amount int64) error {
 	if a.Status != StatusActive {
 		return errors.ErrAccountInactive
@@ -57,8 +110,8 @@ func (a *Account) Debit(amount int64) error {
 	if amount <= 0 {
 		return errors.NewValidationError("amount", "must be greater than 0")
 	}
-	if a.Balance < amount {
-		return errors.ErrInsufficientFunds
+	if err := a.CheckAvailable(amount); err != nil {
+		return err
 	}
 
 	a.Balance -= amount
@@ -81,23 +134,93 @@ func (a *Account) Credit(amount int64) error {
 	return nil
 }
 
-func (a *Account) Suspend() error {
-	if a.Status == StatusInactive {
+// Hold reserves amount of the available balance without debiting it. The hold
+// is later captured into a debit or released.
+func (a *Account) Hold(amount int64) error {
+	if a.Status != StatusActive {
 		return errors.ErrAccountInactive
 	}
-	a.Status = StatusSuspended
+	if amount <= 0 {
+		return errors.NewValidationError("amount", "must be greater than 0")
+	}
+	if err := a.CheckAvailable(amount); err != nil {
+		return err
+	}
+
+	a.HeldBalance += amount
+	a.Version++
 	a.UpdatedAt = time.Now()
 	return nil
 }
 
-func (a *Account) Activate() error {
-	a.Status = StatusActive
+// ReleaseHold returns held funds to the available balance. It does not
+// require an active account, so a hold can always be released.
+func (a *Account) ReleaseHold(amount int64) error {
+	if err := a.checkHeld(amount); err != nil {
+		return err
+	}
+
+	a.HeldBalance -= amount
+	a.Version++
 	a.UpdatedAt = time.Now()
 	return nil
 }
 
-func (a *Account) Deactivate() error {
-	a.Status = StatusInactive
+// CaptureHold debits held funds. Like ReleaseHold it does not require an
+// active account: the funds were committed when the hold was placed.
+func (a *Account) CaptureHold(amount int64) error {
+	if err := a.checkHeld(amount); err != nil {
+		return err
+	}
+
+	a.HeldBalance -= amount
+	a.Balance -= amount
+	a.Version++
+	a.UpdatedAt = time.Now()
+	return nil
+}
+
+func (a *Account) checkHeld(amount int64) error {
+	if amount <= 0 {
+		return errors.NewValidationError("amount", "must be greater than 0")
+	}
+	if amount > a.HeldBalance {
+		return errors.NewValidationError("amount", "exceeds the held balance")
+	}
+	return nil
+}
+
+// statusTransitions lists the statuses each status may move to. Inactive
+// is a closed account and final.
+var statusTransitions = map[AccountStatus][]AccountStatus{
+	StatusActive:    {StatusSuspended, StatusInactive},
+	StatusSuspended: {StatusActive, StatusInactive},
+}
+
+// transitionTo moves the account to status, bumping its version so the
+// change is saved with optimistic locking.
+func (a *Account) transitionTo(status AccountStatus) error {
+	if !slices.Contains(statusTransitions[a.Status], status) {
+		return errors.NewDomainError(
+			"invalid_transition",
+			"cannot transition account from "+string(a.Status)+" to "+string(status),
+			errors.ErrInvalidStateTransition,
+		)
+	}
+	a.Status = status
+	a.Version++
 	a.UpdatedAt = time.Now()
 	return nil
 }
+
+func (a *Account) Suspend() error {
+	return a.transitionTo(StatusSuspended)
+}
+
+func (a *Account) Activate() error {
+	return a.transitionTo(StatusActive)
+}
+
+func (a *Account) Deactivate() error {
+	return a.transitionTo(StatusInactive)
+}
@@ -1,9 +1,11 @@
 package account
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -39,6 +41,20 @@ func TestNewAccount_EmptyCurrency(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestNewAccount_UnknownCurrency(t *testing.T) {
+	_, err := NewAccount("user1", 10000, "XYZ")
+	assert.Error(t, err)
+}
+
+func TestNewLabeledAccount_LabelTooLong(t *testing.T) {
+	acct, err := NewLabeledAccount("user1", "savings", 0, "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "savings", acct.Label)
+
+	_, err = NewLabeledAccount("user1", strings.Repeat("x", MaxLabelLength+1), 0, "USD")
+	assert.Error(t, err)
+}
+
 // --- Debit ---
 
 func TestDebit_Success(t *testing.T) {
@@ -56,6 +72,12 @@ func TestDebit_InsufficientFunds(t *testing.T) {
 	err := acct.Debit(10000)
 	assert.ErrorIs(t, err, errors.ErrInsufficientFunds)
 	assert.Equal(t, int64(5000), acct.Balance) // balance unchanged
+
+	var fundsErr *errors.InsufficientFundsError
+	require.ErrorAs(t, err, &fundsErr)
+	assert.Equal(t, int64(5000), fundsErr.Available)
+	assert.Equal(t, int64(10000), fundsErr.Requested)
+	assert.Equal(t, int64(5000), fundsErr.Shortfall())
 }
 
 func TestDebit_ExactBalance(t *testing.T) {
@@ -109,6 +131,54 @@ func TestCredit_InactiveAccount(t *testing.T) {
 	assert.ErrorIs(t, err, errors.ErrAccountInactive)
 }
 
+// --- Holds ---
+
+func TestHold_ReducesAvailableBalance(t *testing.T) {
+	acct, _ := NewAccount("user1", 10000, "USD")
+	require.NoError(t, acct.Hold(4000))
+	assert.Equal(t, int64(10000), acct.Balance)
+	assert.Equal(t, int64(4000), acct.HeldBalance)
+	assert.Equal(t, int64(6000), acct.AvailableBalance())
+
+	err := acct.Debit(7000)
+	var fundsErr *errors.InsufficientFundsError
+	require.ErrorAs(t, err, &fundsErr)
+	assert.Equal(t, int64(6000), fundsErr.Available)
+	assert.Equal(t, int64(4000), fundsErr.Reserved)
+	assert.ErrorIs(t, acct.Hold(7000), errors.ErrInsufficientFunds)
+}
+
+func TestCaptureHold_DebitsHeldFunds(t *testing.T) {
+	acct, _ := NewAccount("user1", 10000, "USD")
+	require.NoError(t, acct.Hold(4000))
+	require.NoError(t, acct.CaptureHold(4000))
+	assert.Equal(t, int64(6000), acct.Balance)
+	assert.Zero(t, acct.HeldBalance)
+	assert.Error(t, acct.CaptureHold(1), "nothing left to capture")
+}
+
+func TestReleaseHold_RestoresAvailableBalance(t *testing.T) {
+	acct, _ := NewAccount("user1", 10000, "USD")
+	require.NoError(t, acct.Hold(4000))
+	require.NoError(t, acct.Suspend())
+	require.NoError(t, acct.ReleaseHold(4000), "a hold is released even on a suspended account")
+	assert.Equal(t, int64(10000), acct.AvailableBalance())
+	assert.Error(t, acct.ReleaseHold(4000))
+}
+
+func TestHold_InactiveAccount(t *testing.T) {
+	acct, _ := NewAccount("user1", 10000, "USD")
+	acct.Suspend()
+	assert.ErrorIs(t, acct.Hold(1000), errors.ErrAccountInactive)
+}
+
+func TestFundsHold_SettlesOnce(t *testing.T) {
+	h := NewFundsHold(uuid.New(), uuid.New(), 1000)
+	require.NoError(t, h.Capture())
+	assert.Equal(t, HoldCaptured, h.Status)
+	assert.ErrorIs(t, h.Release(), errors.ErrInvalidStateTransition)
+}
+
 // --- Status ---
 
 func TestSuspend(t *testing.T) {
@@ -130,6 +200,24 @@ func TestDeactivate(t *testing.T) {
 	assert.Equal(t, StatusInactive, acct.Status)
 }
 
+func TestStatusTransitions_Invalid(t *testing.T) {
+	active, _ := NewAccount("user1", 10000, "USD")
+	assert.ErrorIs(t, active.Activate(), errors.ErrInvalidStateTransition, "already active")
+
+	suspended, _ := NewAccount("user1", 10000, "USD")
+	require.NoError(t, suspended.Suspend())
+	assert.ErrorIs(t, suspended.Suspend(), errors.ErrInvalidStateTransition, "already suspended")
+
+	closed, _ := NewAccount("user1", 10000, "USD")
+	require.NoError(t, closed.Deactivate())
+	version := closed.Version
+	assert.ErrorIs(t, closed.Activate(), errors.ErrInvalidStateTransition)
+	assert.ErrorIs(t, closed.Suspend(), errors.ErrInvalidStateTransition)
+	assert.ErrorIs(t, closed.Deactivate(), errors.ErrInvalidStateTransition)
+	assert.Equal(t, StatusInactive, closed.Status)
+	assert.Equal(t, version, closed.Version, "a rejected transition changes nothing")
+}
+
 // --- Version increments ---
 
 func TestVersionIncrementsOnDebitAndCredit(t *testing.T) {
@@ -145,3 +233,14 @@ func TestVersionIncrementsOnDebitAndCredit(t *testing.T) {
 	acct.Debit(20000)
 	assert.Equal(t, 3, acct.Version)
 }
+
+func TestVersionIncrementsOnStatusChange(t *testing.T) {
+	acct, _ := NewAccount("user1", 100000, "USD")
+
+	require.NoError(t, acct.Suspend())
+	assert.Equal(t, 1, acct.Version)
+	require.NoError(t, acct.Activate())
+	assert.Equal(t, 2, acct.Version)
+	require.NoError(t, acct.Deactivate())
+	assert.Equal(t, 3, acct.Version)
+}
@@ -0,0 +1,64 @@
+package account
+
+import (
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/google/uuid"
+)
+
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "held"
+	HoldCaptured HoldStatus = "captured"
+	HoldReleased HoldStatus = "released"
+)
+
+// FundsHold records funds a payment reserved on an account. The account's
+// HeldBalance carries the amount until the hold is captured or released.
+type FundsHold struct {
+	ID        uuid.UUID
+	AccountID uuid.UUID
+	PaymentID uuid.UUID
+	Amount    int64 // in cents
+	Status    HoldStatus
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func NewFundsHold(accountID, paymentID uuid.UUID, amount int64) *FundsHold {
+	now := time.Now()
+	return &FundsHold{
+		ID:        uuid.New(),
+		AccountID: accountID,
+		PaymentID: paymentID,
+		Amount:    amount,
+		Status:    HoldActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Capture marks an active hold as debited.
+func (h *FundsHold) Capture() error {
+	return h.settle(HoldCaptured)
+}
+
+// Release marks an active hold as returned to the account.
+func (h *FundsHold) Release() error {
+	return h.settle(HoldReleased)
+}
+
+func (h *FundsHold) settle(status HoldStatus) error {
+	if h.Status != HoldActive {
+		return errors.NewDomainError(
+			"invalid_transition",
+			"hold is already "+string(h.Status),
+			errors.ErrInvalidStateTransition,
+		)
+	}
+	h.Status = status
+	h.UpdatedAt = time.Now()
+	return nil
+}
@@ -14,8 +14,9 @@ type Repository interface {
 	// GetByID retrieves an account by ID
 	GetByID(ctx context.Context, id uuid.UUID) (*Account, error)
 
-	// GetByUserID retrieves an account by user ID and currency
-	GetByUserID(ctx context.Context, userID string, currency string) (*Account, error)
+	// GetByUserID retrieves a user's account in currency with the given
+	// label ("" for the default account)
+	GetByUserID(ctx context.Context, userID, currency, label string) (*Account, error)
 
 	// Update updates an existing account with optimistic locking
 	Update(ctx context.Context, account *Account) error
@@ -26,8 +27,59 @@ type Repository interface {
 	// GetTransactions retrieves transactions for an account
 	GetTransactions(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*Transaction, error)
 
+	// EachTransactionBetween calls fn with every transaction created in
+	// [from, to), oldest first, without loading them all at once. An error
+	// from fn stops the iteration and is returned
+	EachTransactionBetween(ctx context.Context, accountID uuid.UUID, from, to time.Time, fn func(*Transaction) error) error
+
+	// GetAllTransactions retrieves every transaction of an account, oldest
+	// first
+	GetAllTransactions(ctx context.Context, accountID uuid.UUID) ([]*Transaction, error)
+
+	// GetTransactionsByPaymentID retrieves the transactions a payment
+	// generated on any account, oldest first
+	GetTransactionsByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*Transaction, error)
+
 	// Lock locks an account for update (SELECT FOR UPDATE)
 	Lock(ctx context.Context, id uuid.UUID) (*Account, error)
+
+	// List retrieves a page of accounts matching the filter, newest first
+	// unless the filter sorts otherwise
+	List(ctx context.Context, filter ListFilter) ([]*Account, error)
+
+	// Count counts the accounts matching a filter's conditions, ignoring its
+	// paging and sort
+	Count(ctx context.Context, filter ListFilter) (int, error)
+
+	// CreateHold records a hold placed on an account
+	CreateHold(ctx context.Context, hold *FundsHold) error
+
+	// GetActiveHold retrieves a payment's active hold, or nil if it has none
+	GetActiveHold(ctx context.Context, paymentID uuid.UUID) (*FundsHold, error)
+
+	// UpdateHold records a hold's capture or release
+	UpdateHold(ctx context.Context, hold *FundsHold) error
+}
+
+// DefaultListLimit is the page size of a ListFilter without a Limit.
+const DefaultListLimit = 20
+
+type ListFilter struct {
+	UserID    *string
+	Currency  *string
+	Status    *AccountStatus
+	Limit     int
+	Offset    int
+	SortBy    string // created_at (default), updated_at, balance or currency
+	SortOrder string // asc or desc (default)
+}
+
+// PageLimit returns the filter's page size, DefaultListLimit if unset.
+func (f ListFilter) PageLimit() int {
+	if f.Limit <= 0 {
+		return DefaultListLimit
+	}
+	return f.Limit
 }
 
 type Transaction struct {
@@ -46,4 +98,13 @@ type TransactionType string
 const (
 	TransactionDebit  TransactionType = "debit"
 	TransactionCredit TransactionType = "credit"
+
+	// Manual corrections of a balance by an operator, not tied to a payment
+	TransactionAdjustmentDebit  TransactionType = "adjustment_debit"
+	TransactionAdjustmentCredit TransactionType = "adjustment_credit"
 )
+
+// IsCredit reports whether a transaction of type t added to the balance.
+func (t TransactionType) IsCredit() bool {
+	return t == TransactionCredit || t == TransactionAdjustmentCredit
+}
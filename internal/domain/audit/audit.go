@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Result is whether an audited operation succeeded.
+type Result string
+
+const (
+	ResultSuccess Result = "success"
+	ResultFailure Result = "failure"
+)
+
+// ResultOf classifies an HTTP response status: anything below 400 succeeded.
+func ResultOf(status int) Result {
+	if status < 400 {
+		return ResultSuccess
+	}
+	return ResultFailure
+}
+
+// Entry records one state-changing API call: who made it, what it did and to
+// what, where it came from and how it ended.
+type Entry struct {
+	ID         uuid.UUID
+	UserID     string
+	Operation  string
+	TargetID   *uuid.UUID // nil when the call names no single resource
+	SourceIP   string
+	Method     string
+	Path       string
+	StatusCode int
+	Result     Result
+	RequestID  string
+	CreatedAt  time.Time
+}
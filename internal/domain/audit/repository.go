@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	// InsertBatch records entries in one round trip
+	InsertBatch(ctx context.Context, entries []*Entry) error
+
+	// List retrieves a page of entries matching the filter, newest first
+	List(ctx context.Context, filter ListFilter) ([]*Entry, error)
+
+	// Count counts the entries matching a filter's conditions, ignoring its
+	// paging
+	Count(ctx context.Context, filter ListFilter) (int, error)
+}
+
+// DefaultListLimit is the page size of a ListFilter without a Limit.
+const DefaultListLimit = 50
+
+type ListFilter struct {
+	UserID        *string
+	Operation     *string
+	TargetID      *uuid.UUID
+	Result        *Result
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Limit         int
+	Offset        int
+}
+
+// PageLimit returns the filter's page size, DefaultListLimit if unset.
+func (f ListFilter) PageLimit() int {
+	if f.Limit <= 0 {
+		return DefaultListLimit
+	}
+	return f.Limit
+}
@@ -0,0 +1,49 @@
+// Package auth holds the refresh tokens the API issues alongside access
+// tokens.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is an issued refresh token. Only the SHA-256 of the token is
+// stored, so a leaked table cannot be replayed.
+type RefreshToken struct {
+	ID        uuid.UUID
+	TokenHash string
+	UserID    string
+	Scopes    []string
+	Roles     []string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+// HashToken returns the stored form of a refresh token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Usable reports whether the token may still be exchanged at now.
+func (t *RefreshToken) Usable(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+type RefreshTokenRepository interface {
+	// Create stores a newly issued refresh token
+	Create(ctx context.Context, token *RefreshToken) error
+
+	// GetByHash retrieves a refresh token by its hash, revoked or not
+	GetByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+
+	// Revoke marks a refresh token revoked at the given time. It returns
+	// ErrInvalidRefreshToken if the token is unknown or already revoked, so
+	// of two concurrent exchanges of one token only one succeeds
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+}
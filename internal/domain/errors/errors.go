@@ -3,15 +3,24 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 var (
 	// Account errors
 	ErrAccountNotFound      = errors.New("account not found")
+	ErrAccountAlreadyExists = errors.New("account already exists")
 	ErrInsufficientFunds    = errors.New("insufficient funds")
 	ErrInvalidCurrency      = errors.New("invalid currency")
 	ErrAccountInactive      = errors.New("account is inactive")
 	ErrOptimisticLockFailed = errors.New("optimistic lock conflict")
+	ErrAccountUnavailable   = errors.New("account unavailable")
+
+	ErrStatementSigningDisabled = errors.New("statement signing is not configured")
+
+	// FX errors
+	ErrUnsupportedCurrencyPair = errors.New("unsupported currency pair")
+	ErrExchangeRateUnavailable = errors.New("exchange rate unavailable")
 
 	// Payment errors
 	ErrPaymentNotFound        = errors.New("payment not found")
@@ -20,17 +29,27 @@ var (
 	ErrInvalidStateTransition = errors.New("invalid state transition")
 	ErrPaymentAlreadyProcessed = errors.New("payment already processed")
 	ErrMaxRetriesExceeded     = errors.New("max retries exceeded")
+	ErrRetryNotDue            = errors.New("payment retry is not due yet")
 	ErrPaymentCancelled       = errors.New("payment is cancelled")
 	ErrPaymentExpired         = errors.New("payment has expired")
+	ErrRefundWindowExpired    = errors.New("refund window has expired")
+	ErrManualRefundRequired   = errors.New("provider requires manual refund processing")
+	ErrDisputeNotFound        = errors.New("dispute not found")
+	ErrPaymentFailed          = errors.New("payment failed")
+	ErrDeadLetterNotFound     = errors.New("dead letter not found")
 
 	// Provider errors
 	ErrProviderNotFound       = errors.New("payment provider not found")
 	ErrProviderUnavailable    = errors.New("payment provider unavailable")
 	ErrProviderRejected       = errors.New("payment rejected by provider")
 	ErrProviderTimeout        = errors.New("provider request timeout")
+	ErrInvalidSignature       = errors.New("invalid webhook signature")
+	ErrReviewRequired         = errors.New("provider outcome requires manual review")
+	ErrProviderTransactionNotFound = errors.New("provider transaction not found")
 
 	// Idempotency errors
 	ErrDuplicateIdempotencyKey = errors.New("duplicate idempotency key")
+	ErrIdempotencyKeyReused    = errors.New("idempotency key reused with a different request")
 
 	// Lock errors
 	ErrLockAcquisitionFailed = errors.New("failed to acquire lock")
@@ -39,10 +58,14 @@ var (
 	// Validation errors
 	ErrValidationFailed = errors.New("validation failed")
 	ErrInvalidInput     = errors.New("invalid input")
+	ErrPayloadTooLarge  = errors.New("request body too large")
 
 	// Authentication/Authorization errors
-	ErrUnauthorized = errors.New("unauthorized")
-	ErrForbidden    = errors.New("forbidden")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrStepUpRequired      = errors.New("step-up authorization required")
+	ErrInvalidCredentials  = errors.New("invalid client credentials")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
 )
 
 // DomainError wraps errors with additional context
@@ -71,6 +94,28 @@ func NewDomainError(code, message string, err error) *DomainError {
 	}
 }
 
+// InsufficientFundsError reports how far a debit falls short of the available
+// balance. It matches ErrInsufficientFunds with errors.Is. Amounts are in
+// minor units of Currency.
+type InsufficientFundsError struct {
+	Available int64
+	Reserved  int64
+	Requested int64
+	Currency  string
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient funds: short by %d minor units", e.Shortfall())
+}
+
+func (e *InsufficientFundsError) Unwrap() error {
+	return ErrInsufficientFunds
+}
+
+func (e *InsufficientFundsError) Shortfall() int64 {
+	return e.Requested - e.Available
+}
+
 type ValidationError struct {
 	Field   string
 	Message string
@@ -86,3 +131,23 @@ func NewValidationError(field, message string) *ValidationError {
 		Message: message,
 	}
 }
+
+// ValidationErrors reports every invalid field of a request at once.
+// errors.As finds each of them as a *ValidationError.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, ve := range e {
+		msgs[i] = ve.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, ve := range e {
+		errs[i] = ve
+	}
+	return errs
+}
@@ -6,6 +6,22 @@ import (
 	"github.com/google/uuid"
 )
 
+// SchemaVersion is stamped as "schema_version" on every event payload. Bump it
+// only for breaking changes (removed or renamed fields, changed types or
+// meaning); adding fields is backwards compatible and keeps the version.
+const SchemaVersion = 1
+
+// SchemaVersionKey is the payload field carrying SchemaVersion.
+const SchemaVersionKey = "schema_version"
+
+// DedupKeyKey is the payload field carrying the ID of the payment event an
+// entry was produced for. It is the same on the original entry and on any
+// re-emitted copy, so consumers that already handled it can skip it.
+const DedupKeyKey = "dedup_key"
+
+// ReplayedKey is set to true on payloads re-emitted by an operator.
+const ReplayedKey = "replayed"
+
 type Entry struct {
 	ID            uuid.UUID
 	AggregateType string
@@ -15,6 +31,10 @@ type Entry struct {
 	Status        Status
 	RetryCount    int
 	MaxRetries    int
+	// NextAttemptAt is the earliest time the entry may be published; failed
+	// publishes push it back with exponential backoff. While the entry is
+	// StatusPublishing it is when the claim lapses.
+	NextAttemptAt time.Time
 	CreatedAt     time.Time
 	PublishedAt   *time.Time
 }
@@ -22,12 +42,23 @@ type Entry struct {
 type Status string
 
 const (
-	StatusPending   Status = "pending"
-	StatusPublished Status = "published"
-	StatusFailed    Status = "failed"
+	StatusPending Status = "pending"
+	// StatusPublishing marks an entry claimed by a worker that is publishing
+	// it outside any database transaction.
+	StatusPublishing Status = "publishing"
+	StatusPublished  Status = "published"
+	StatusFailed     Status = "failed"
 )
 
+// NewEntry stamps payload with the current SchemaVersion unless it already
+// carries one. A nil payload is left nil.
 func NewEntry(aggregateType string, aggregateID uuid.UUID, eventType string, payload map[string]any) *Entry {
+	if payload != nil {
+		if _, ok := payload[SchemaVersionKey]; !ok {
+			payload[SchemaVersionKey] = SchemaVersion
+		}
+	}
+	now := time.Now()
 	return &Entry{
 		ID:            uuid.New(),
 		AggregateType: aggregateType,
@@ -37,6 +68,7 @@ func NewEntry(aggregateType string, aggregateID uuid.UUID, eventType string, pay
 		Status:        StatusPending,
 		RetryCount:    0,
 		MaxRetries:    5,
-		CreatedAt:     time.Now(),
+		NextAttemptAt: now,
+		CreatedAt:     now,
 	}
 }
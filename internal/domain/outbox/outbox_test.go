@@ -28,9 +28,19 @@ func TestNewEntry(t *testing.T) {
 	assert.Equal(t, 0, entry.RetryCount)
 	assert.Equal(t, 5, entry.MaxRetries)
 	assert.False(t, entry.CreatedAt.IsZero())
+	assert.Equal(t, entry.CreatedAt, entry.NextAttemptAt, "a new entry is due right away")
 	assert.Nil(t, entry.PublishedAt)
 }
 
+func TestNewEntry_StampsSchemaVersion(t *testing.T) {
+	entry := NewEntry("payment", uuid.New(), "payment.created", map[string]any{"amount_cents": 100})
+	assert.Equal(t, SchemaVersion, entry.Payload[SchemaVersionKey])
+
+	// An explicit version (e.g. a re-emitted historical event) is kept.
+	entry = NewEntry("payment", uuid.New(), "payment.created", map[string]any{SchemaVersionKey: 0})
+	assert.Equal(t, 0, entry.Payload[SchemaVersionKey])
+}
+
 func TestNewEntry_EmptyPayload(t *testing.T) {
 	aggregateID := uuid.New()
 	entry := NewEntry("account", aggregateID, "account.created", nil)
@@ -66,6 +76,7 @@ func TestNewEntry_DifferentEventTypes(t *testing.T) {
 
 func TestStatus_Constants(t *testing.T) {
 	assert.Equal(t, Status("pending"), StatusPending)
+	assert.Equal(t, Status("publishing"), StatusPublishing)
 	assert.Equal(t, Status("published"), StatusPublished)
 	assert.Equal(t, Status("failed"), StatusFailed)
 }
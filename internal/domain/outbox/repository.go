@@ -2,6 +2,7 @@ package outbox
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -10,12 +11,26 @@ type Repository interface {
 	// Insert creates a new outbox entry (typically inside a transaction)
 	Insert(ctx context.Context, entry *Entry) error
 
-	// GetPending returns pending outbox entries up to the given limit
-	GetPending(ctx context.Context, limit int) ([]*Entry, error)
+	// ClaimPending atomically moves up to limit due entries with retries left
+	// to StatusPublishing and returns them, oldest first. The claim lasts
+	// lease: an entry still publishing after that (its worker died before
+	// marking it) is due again, so every entry is published at least once
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*Entry, error)
 
 	// MarkPublished marks an outbox entry as published
 	MarkPublished(ctx context.Context, id uuid.UUID) error
 
-	// MarkFailed marks an outbox entry as failed and increments retry count
-	MarkFailed(ctx context.Context, id uuid.UUID) error
+	// MarkFailed records a failed publish: it increments the retry count and
+	// schedules the next attempt at nextAttemptAt, or moves the entry to
+	// StatusFailed once it has used MaxRetries. It returns the new status
+	MarkFailed(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time) (Status, error)
+
+	// DeletePublishedBefore deletes up to batchSize published or failed
+	// entries that reached that state before t, returning how many it removed
+	DeletePublishedBefore(ctx context.Context, t time.Time, batchSize int) (int64, error)
+
+	// DeletePending deletes the entries for aggregateID that are still
+	// pending, returning how many it removed. Entries being published are
+	// left alone
+	DeletePending(ctx context.Context, aggregateID uuid.UUID) (int64, error)
 }
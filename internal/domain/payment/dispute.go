@@ -0,0 +1,89 @@
+package payment
+
+import (
+	"context"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/google/uuid"
+)
+
+type DisputeStatus string
+
+const (
+	DisputeOpen DisputeStatus = "open"
+	DisputeWon  DisputeStatus = "won"
+	DisputeLost DisputeStatus = "lost"
+)
+
+// Dispute is a provider-initiated challenge (chargeback) against a completed
+// payment.
+type Dispute struct {
+	ID                uuid.UUID
+	PaymentID         uuid.UUID
+	Provider          Provider
+	ProviderDisputeID string
+	Reason            string
+	AmountCents       int64
+	Currency          string // the disputed payment's currency
+	Status            DisputeStatus
+	// FundsReversed is set while the disputed amount has been moved back from
+	// the destination to the source account.
+	FundsReversed bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	ResolvedAt    *time.Time
+}
+
+func NewDispute(p *Payment, provider Provider, providerDisputeID, reason string, amountCents int64) (*Dispute, error) {
+	if providerDisputeID == "" {
+		return nil, errors.NewValidationError("provider_dispute_id", "cannot be empty")
+	}
+	if amountCents <= 0 || amountCents > p.Amount.ValueCents {
+		return nil, errors.NewValidationError("amount", "must be positive and not exceed the payment amount")
+	}
+
+	now := time.Now()
+	return &Dispute{
+		ID:                uuid.New(),
+		PaymentID:         p.ID,
+		Provider:          provider,
+		ProviderDisputeID: providerDisputeID,
+		Reason:            reason,
+		AmountCents:       amountCents,
+		Currency:          p.Amount.Currency,
+		Status:            DisputeOpen,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}, nil
+}
+
+// Resolve closes an open dispute as won or lost.
+func (d *Dispute) Resolve(status DisputeStatus) error {
+	if d.Status != DisputeOpen || (status != DisputeWon && status != DisputeLost) {
+		return errors.NewDomainError(
+			"invalid_transition",
+			"cannot resolve dispute from "+string(d.Status)+" to "+string(status),
+			errors.ErrInvalidStateTransition,
+		)
+	}
+	now := time.Now()
+	d.Status = status
+	d.UpdatedAt = now
+	d.ResolvedAt = &now
+	return nil
+}
+
+type DisputeRepository interface {
+	// Create records a new dispute
+	Create(ctx context.Context, dispute *Dispute) error
+
+	// Update persists a dispute's status and funds state
+	Update(ctx context.Context, dispute *Dispute) error
+
+	// GetByProviderID retrieves a dispute by the provider's dispute identifier
+	GetByProviderID(ctx context.Context, provider Provider, providerDisputeID string) (*Dispute, error)
+
+	// ListByPayment lists a payment's disputes, oldest first
+	ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]*Dispute, error)
+}
@@ -0,0 +1,27 @@
+package payment
+
+// IdempotencyScope names an operation within a payment's idempotency
+// namespace.
+//
+// A logical payment is identified by the client's idempotency key, which is
+// stored as Payment.IdempotencyKey and deduplicates creation. Every other
+// code path that acts on the payment externally derives its key from that
+// one with IdempotencyKeyFor, so API retries, worker redelivery and retries,
+// provider fallback and any re-creation of the payment under the same client
+// key all map to exactly one provider charge.
+type IdempotencyScope string
+
+const (
+	ScopeCharge IdempotencyScope = "charge"
+	ScopeRefund IdempotencyScope = "refund"
+)
+
+// IdempotencyKey derives the key for scope from a client idempotency key.
+func IdempotencyKey(scope IdempotencyScope, clientKey string) string {
+	return "payments:" + string(scope) + ":" + clientKey
+}
+
+// IdempotencyKeyFor derives the payment's key for scope.
+func (p *Payment) IdempotencyKeyFor(scope IdempotencyScope) string {
+	return IdempotencyKey(scope, p.IdempotencyKey)
+}
@@ -0,0 +1,51 @@
+package payment
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ManualRefundStatus string
+
+const (
+	ManualRefundPending   ManualRefundStatus = "pending"
+	ManualRefundCompleted ManualRefundStatus = "completed"
+)
+
+// ManualRefund is an operations task to refund a payment out of band, for
+// providers that cannot refund through their API.
+type ManualRefund struct {
+	ID                    uuid.UUID
+	PaymentID             uuid.UUID
+	Provider              Provider
+	ProviderTransactionID *string
+	AmountCents           int64
+	Currency              string
+	Status                ManualRefundStatus
+	CreatedAt             time.Time
+	CompletedAt           *time.Time
+}
+
+func NewManualRefund(p *Payment) *ManualRefund {
+	r := &ManualRefund{
+		ID:                    uuid.New(),
+		PaymentID:             p.ID,
+		ProviderTransactionID: p.ProviderTransactionID,
+		AmountCents:           p.Amount.ValueCents,
+		Currency:              p.Amount.Currency,
+		Status:                ManualRefundPending,
+		CreatedAt:             time.Now(),
+	}
+	if p.Provider != nil {
+		r.Provider = *p.Provider
+	}
+	return r
+}
+
+type ManualRefundRepository interface {
+	// Create records a manual refund task. There is at most one task per
+	// payment; creating another for the same payment is a no-op.
+	Create(ctx context.Context, r *ManualRefund) error
+}
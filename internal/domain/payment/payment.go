@@ -2,9 +2,13 @@ package payment
 
 import (
 	"fmt"
+	"math"
 	"time"
+	"unicode/utf8"
 
 	"github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/pkg/currency"
+	"github.com/cassiomorais/payments/pkg/money"
 	"github.com/google/uuid"
 )
 
@@ -18,12 +22,18 @@ const (
 type PaymentStatus string
 
 const (
-	StatusPending    PaymentStatus = "pending"
-	StatusProcessing PaymentStatus = "processing"
-	StatusCompleted  PaymentStatus = "completed"
-	StatusFailed     PaymentStatus = "failed"
-	StatusCancelled  PaymentStatus = "cancelled"
-	StatusRefunded   PaymentStatus = "refunded"
+	StatusPending     PaymentStatus = "pending"
+	StatusProcessing  PaymentStatus = "processing"
+	StatusCompleted   PaymentStatus = "completed"
+	StatusFailed      PaymentStatus = "failed"
+	StatusCancelled   PaymentStatus = "cancelled"
+	StatusRefunded    PaymentStatus = "refunded"
+	StatusDisputed    PaymentStatus = "disputed"
+	StatusChargedBack PaymentStatus = "charged_back"
+	StatusNeedsReview PaymentStatus = "needs_review"
+	StatusAbandoned   PaymentStatus = "abandoned"
+	StatusRejected    PaymentStatus = "rejected"
+	StatusScheduled   PaymentStatus = "scheduled"
 )
 
 type Provider string
@@ -36,10 +46,19 @@ const (
 type EventType string
 
 const (
-	EventPaymentCreated   EventType = "payment.created"
-	EventPaymentCompleted EventType = "payment.completed"
-	EventPaymentFailed    EventType = "payment.failed"
-	EventPaymentRefunded  EventType = "payment.refunded"
+	EventPaymentCreated     EventType = "payment.created"
+	EventPaymentCompleted   EventType = "payment.completed"
+	EventPaymentFailed      EventType = "payment.failed"
+	EventPaymentRefunded    EventType = "payment.refunded"
+	EventPaymentCancelled   EventType = "payment.cancelled"
+	EventPaymentDisputed    EventType = "payment.disputed"
+	EventDisputeWon         EventType = "payment.dispute_won"
+	EventPaymentChargedBack EventType = "payment.charged_back"
+	EventPaymentNeedsReview EventType = "payment.needs_review"
+	EventPaymentAbandoned   EventType = "payment.abandoned"
+	EventPaymentDue         EventType = "payment.due"
+	EventDLQReplayed        EventType = "payment.dlq_replayed"
+	EventPaymentReconciled  EventType = "payment.reconciled"
 )
 
 type Payment struct {
@@ -49,38 +68,83 @@ type Payment struct {
 	SourceAccountID        *uuid.UUID
 	DestinationAccountID   *uuid.UUID
 	Amount                 Amount
+	FeeCents               int64      // transfer fee charged on top of Amount, or platform fee deducted from an external payment's Amount
+	FeeAccountID           *uuid.UUID // account credited with FeeCents
+	Conversion             *Conversion // set when the destination is credited in another currency
+	Description            string
 	Status                 PaymentStatus
 	Provider               *Provider
 	ProviderTransactionID  *string
 	RetryCount             int
 	MaxRetries             int
 	LastError              *string
-	SagaID                 *uuid.UUID
+	SagaID                 *uuid.UUID // reserved for persisted saga state; not written yet
 	SagaStep               int
 	Metadata               map[string]any
 	CreatedAt              time.Time
 	UpdatedAt              time.Time
 	CompletedAt            *time.Time
+
+	// Terminal timestamps other than CompletedAt, which is only set on
+	// successful completion. FailedAt is cleared when a failed payment is
+	// retried.
+	FailedAt    *time.Time
+	CancelledAt *time.Time
+	RefundedAt  *time.Time
+
+	// ScheduledAt is when a scheduled payment becomes due for processing.
+	ScheduledAt *time.Time
+	// NextRetryAt is the earliest time a failed payment may be retried; nil
+	// retries it as soon as it is picked up.
+	NextRetryAt *time.Time
+	// BatchID groups payments submitted together in one batch request.
+	BatchID *uuid.UUID
+	// ProviderOptions carries provider-specific fields for external payments.
+	ProviderOptions ProviderOptions
+	// UserID is the authenticated user who created the payment. Idempotency
+	// keys are unique per user; it is empty when there was no caller.
+	UserID string
+
+	// Version guards against lost updates: PaymentRepository.Update only
+	// writes a payment whose stored version still matches, then bumps it.
+	Version int
+}
+
+// ProviderOptions holds provider-specific request fields, such as a Stripe
+// customer ID, keyed by provider name and then by option name. Each provider
+// reads only its own namespace, so options for a fallback provider can be
+// supplied alongside those for the primary one.
+type ProviderOptions map[string]map[string]string
+
+// For returns the options for provider, or nil.
+func (o ProviderOptions) For(provider Provider) map[string]string {
+	return o[string(provider)]
 }
 
+// Conversion records how a cross-currency internal transfer credits its
+// destination: the source is debited Payment.Amount, the destination Credited.
+type Conversion struct {
+	Rate     float64 // destination currency units per source currency unit
+	Credited Amount
+}
+
+// Amount is what a payment moves: money.Money, with Validate for the rules
+// a payment amount must meet.
 type Amount struct {
-	ValueCents int64
-	Currency   string
+	money.Money
 }
 
-func (a Amount) String() string {
-	whole := a.ValueCents / 100
-	frac := a.ValueCents % 100
-	if frac < 0 {
-		frac = -frac
-	}
-	return fmt.Sprintf("%d.%02d %s", whole, frac, a.Currency)
+func NewAmount(valueCents int64, currency string) Amount {
+	return Amount{Money: money.New(valueCents, currency)}
 }
 
 func (a Amount) Validate() error {
 	return validateAmount(a)
 }
 
+// DefaultMaxRetries is the retry budget NewPayment gives a payment.
+const DefaultMaxRetries = 3
+
 func NewPayment(
 	idempotencyKey string,
 	paymentType PaymentType,
@@ -107,7 +171,7 @@ func NewPayment(
 		Amount:               amount,
 		Status:               StatusPending,
 		RetryCount:           0,
-		MaxRetries:           3,
+		MaxRetries:           DefaultMaxRetries,
 		Metadata:             make(map[string]any),
 		CreatedAt:            now,
 		UpdatedAt:            now,
@@ -124,15 +188,36 @@ func (p *Payment) CanTransitionTo(newStatus PaymentStatus) bool {
 		StatusProcessing: {
 			StatusCompleted,
 			StatusFailed,
+			StatusCancelled,   // Aborted mid provider call
+			StatusNeedsReview, // Ambiguous outcome, held for a human decision
+		},
+		StatusNeedsReview: {
+			StatusCompleted, // Review approved
+			StatusRejected,  // Review rejected
 		},
 		StatusCompleted: {
 			StatusRefunded,
+			StatusDisputed,
+		},
+		StatusDisputed: {
+			StatusCompleted,   // Dispute won
+			StatusChargedBack, // Dispute lost
 		},
 		StatusFailed: {
 			StatusProcessing, // Retry
+			StatusAbandoned,  // Retries exhausted
+		},
+		StatusCancelled:   {}, // Terminal state
+		StatusRefunded:    {}, // Terminal state
+		StatusChargedBack: {}, // Terminal state
+		StatusRejected:    {}, // Terminal state: never retried
+		StatusAbandoned: {
+			StatusFailed, // Reopened by an operator replay
+		},
+		StatusScheduled: {
+			StatusPending,   // Due for processing
+			StatusCancelled, // Cancelled before it fired
 		},
-		StatusCancelled: {},  // Terminal state
-		StatusRefunded:  {},  // Terminal state
 	}
 
 	allowedTransitions, exists := transitions[p.Status]
@@ -157,12 +242,25 @@ func (p *Payment) TransitionTo(newStatus PaymentStatus) error {
 		)
 	}
 
+	now := time.Now()
 	p.Status = newStatus
-	p.UpdatedAt = time.Now()
+	p.UpdatedAt = now
 
-	if newStatus == StatusCompleted || newStatus == StatusFailed || newStatus == StatusCancelled {
-		now := time.Now()
-		p.CompletedAt = &now
+	switch newStatus {
+	case StatusCompleted:
+		// A won dispute returns to completed and keeps the original time.
+		if p.CompletedAt == nil {
+			p.CompletedAt = &now
+		}
+	case StatusFailed:
+		p.FailedAt = &now
+	case StatusCancelled:
+		p.CancelledAt = &now
+	case StatusRefunded:
+		p.RefundedAt = &now
+	case StatusProcessing:
+		p.FailedAt = nil
+		p.NextRetryAt = nil
 	}
 
 	return nil
@@ -190,6 +288,78 @@ func (p *Payment) MarkFailed(errorMsg string) error {
 	return nil
 }
 
+// MarkRejected ends a payment a reviewer rejected, recording why. Unlike a
+// failed payment it is never retried.
+func (p *Payment) MarkRejected(reason string) error {
+	if err := p.TransitionTo(StatusRejected); err != nil {
+		return err
+	}
+	p.LastError = &reason
+	return nil
+}
+
+// MarkNeedsReview holds a processing payment for a human decision, recording
+// why and the provider transaction ID when the provider returned one.
+func (p *Payment) MarkNeedsReview(reason string, providerTxID *string) error {
+	if err := p.TransitionTo(StatusNeedsReview); err != nil {
+		return err
+	}
+	p.LastError = &reason
+	if providerTxID != nil {
+		p.ProviderTransactionID = providerTxID
+	}
+	return nil
+}
+
+// Schedule defers a new payment until at, which must be after now. A
+// scheduled payment is not processed until MarkDue.
+func (p *Payment) Schedule(at, now time.Time) error {
+	if p.Status != StatusPending {
+		return errors.NewDomainError(
+			"invalid_transition",
+			"only a new payment can be scheduled",
+			errors.ErrInvalidStateTransition,
+		)
+	}
+	if !at.After(now) {
+		return errors.NewValidationError("scheduled_at", "must be in the future")
+	}
+	// Stored at the database's microsecond precision, so a replayed request
+	// compares equal to the persisted schedule.
+	at = at.UTC().Truncate(time.Microsecond)
+	p.Status = StatusScheduled
+	p.ScheduledAt = &at
+	return nil
+}
+
+// MarkDue returns a scheduled payment to pending once its time has come.
+func (p *Payment) MarkDue() error {
+	return p.TransitionTo(StatusPending)
+}
+
+// MarkAbandoned gives up on a failed payment that has no retries left.
+func (p *Payment) MarkAbandoned() error {
+	if p.Status == StatusFailed && p.CanRetry() {
+		return errors.NewDomainError(
+			"invalid_transition",
+			"cannot abandon a payment with retries left",
+			errors.ErrInvalidStateTransition,
+		)
+	}
+	return p.TransitionTo(StatusAbandoned)
+}
+
+// Reopen returns an abandoned payment to failed with one more attempt, for
+// an operator replaying it.
+func (p *Payment) Reopen() error {
+	if err := p.TransitionTo(StatusFailed); err != nil {
+		return err
+	}
+	p.MaxRetries = p.RetryCount + 1
+	p.NextRetryAt = nil
+	return nil
+}
+
 func (p *Payment) MarkCancelled() error {
 	return p.TransitionTo(StatusCancelled)
 }
@@ -198,6 +368,20 @@ func (p *Payment) MarkRefunded() error {
 	return p.TransitionTo(StatusRefunded)
 }
 
+func (p *Payment) MarkDisputed() error {
+	return p.TransitionTo(StatusDisputed)
+}
+
+// MarkDisputeWon returns a disputed payment to completed, keeping its
+// original completion time.
+func (p *Payment) MarkDisputeWon() error {
+	return p.TransitionTo(StatusCompleted)
+}
+
+func (p *Payment) MarkChargedBack() error {
+	return p.TransitionTo(StatusChargedBack)
+}
+
 func (p *Payment) IncrementRetry() error {
 	if p.RetryCount >= p.MaxRetries {
 		return errors.ErrMaxRetriesExceeded
@@ -207,6 +391,18 @@ func (p *Payment) IncrementRetry() error {
 	return nil
 }
 
+// ScheduleRetry defers the next retry of a failed payment until at.
+func (p *Payment) ScheduleRetry(at time.Time) {
+	// Stored at the database's microsecond precision.
+	at = at.UTC().Truncate(time.Microsecond)
+	p.NextRetryAt = &at
+}
+
+// RetryDue reports whether a failed payment's backoff has elapsed at now.
+func (p *Payment) RetryDue(now time.Time) bool {
+	return p.NextRetryAt == nil || !now.Before(*p.NextRetryAt)
+}
+
 func (p *Payment) CanRetry() bool {
 	return p.Status == StatusFailed && p.RetryCount < p.MaxRetries
 }
@@ -214,13 +410,112 @@ func (p *Payment) CanRetry() bool {
 func (p *Payment) IsTerminal() bool {
 	return p.Status == StatusCompleted ||
 		p.Status == StatusCancelled ||
-		p.Status == StatusRefunded
+		p.Status == StatusRefunded ||
+		p.Status == StatusChargedBack ||
+		p.Status == StatusAbandoned ||
+		p.Status == StatusRejected
+}
+
+// SetMaxRetries sets how many times a failed payment may be retried.
+func (p *Payment) SetMaxRetries(n int) error {
+	if n < 0 {
+		return errors.NewValidationError("max_retries", "must not be negative")
+	}
+	p.MaxRetries = n
+	return nil
 }
 
 func (p *Payment) SetProvider(provider Provider) {
 	p.Provider = &provider
 }
 
+// SetFee records a fee charged to the source account and credited to
+// feeAccountID when the payment settles.
+func (p *Payment) SetFee(feeCents int64, feeAccountID uuid.UUID) {
+	p.FeeCents = feeCents
+	p.FeeAccountID = &feeAccountID
+}
+
+// SetPlatformFee deducts feeCents from an external payment's Amount for
+// feeAccountID; the destination is credited the rest (NetCents).
+func (p *Payment) SetPlatformFee(feeCents int64, feeAccountID uuid.UUID) error {
+	if p.PaymentType != ExternalPayment {
+		return errors.NewValidationError("fee", "only applies to external payments")
+	}
+	if feeCents < 0 {
+		return errors.NewValidationError("fee", "must not be negative")
+	}
+	if feeCents > p.Amount.ValueCents {
+		return errors.NewValidationError("fee", "must not exceed the amount")
+	}
+	p.SetFee(feeCents, feeAccountID)
+	return nil
+}
+
+// NetCents is what the destination receives: an external payment's Amount
+// less its platform fee. Transfer fees are charged on top, so a transfer's
+// NetCents is its Amount.
+func (p *Payment) NetCents() int64 {
+	if p.PaymentType == ExternalPayment {
+		return p.Amount.ValueCents - p.FeeCents
+	}
+	return p.Amount.ValueCents
+}
+
+// SetConversion credits the destination in code, converting Amount at rate
+// (major units per major unit) and rounding to the destination's nearest
+// minor unit.
+func (p *Payment) SetConversion(rate float64, code string) error {
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		return errors.NewValidationError("exchange_rate", "must be greater than 0")
+	}
+	if code == p.Amount.Currency {
+		return errors.NewValidationError("exchange_rate", "only applies between different currencies")
+	}
+	from, err := lookupCurrency(p.Amount.Currency)
+	if err != nil {
+		return err
+	}
+	to, err := lookupCurrency(code)
+	if err != nil {
+		return err
+	}
+	credited := math.Round(float64(p.Amount.ValueCents) * rate * float64(to.Factor()) / float64(from.Factor()))
+	if credited >= math.MaxInt64 {
+		return errors.NewValidationError("exchange_rate", "converted amount is too large")
+	}
+	if credited < 1 {
+		return errors.NewValidationError("exchange_rate", "converted amount rounds to zero")
+	}
+	converted := NewAmount(int64(credited), code)
+	if err := validateAmount(converted); err != nil {
+		return err
+	}
+	p.Conversion = &Conversion{Rate: rate, Credited: converted}
+	return nil
+}
+
+// CreditedAmount is what the destination receives: Amount, or the converted
+// amount for a cross-currency transfer.
+func (p *Payment) CreditedAmount() Amount {
+	if p.Conversion != nil {
+		return p.Conversion.Credited
+	}
+	return p.Amount
+}
+
+// MaxDescriptionLength bounds Payment.Description, in characters.
+const MaxDescriptionLength = 255
+
+// SetDescription records what the payment is for.
+func (p *Payment) SetDescription(description string) error {
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return errors.NewValidationError("description", fmt.Sprintf("must be at most %d characters", MaxDescriptionLength))
+	}
+	p.Description = description
+	return nil
+}
+
 func validateAmount(amount Amount) error {
 	if amount.ValueCents <= 0 {
 		return errors.NewValidationError("amount", "must be greater than 0")
@@ -228,9 +523,19 @@ func validateAmount(amount Amount) error {
 	if amount.Currency == "" {
 		return errors.NewValidationError("currency", "cannot be empty")
 	}
-	// Simple currency validation (3-letter code)
-	if len(amount.Currency) != 3 {
-		return errors.NewValidationError("currency", "must be a 3-letter ISO code")
+	_, err := lookupCurrency(amount.Currency)
+	return err
+}
+
+// lookupCurrency returns the registered currency for code, or a validation
+// error naming the currency field.
+func lookupCurrency(code string) (currency.Currency, error) {
+	if len(code) != 3 {
+		return currency.Currency{}, errors.NewValidationError("currency", "must be a 3-letter ISO code")
+	}
+	c, err := currency.Lookup(code)
+	if err != nil {
+		return currency.Currency{}, errors.NewValidationError("currency", fmt.Sprintf("unsupported currency %q", code))
 	}
-	return nil
+	return c, nil
 }
@@ -2,6 +2,7 @@ package payment
 
 import (
 	"testing"
+	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/google/uuid"
@@ -20,54 +21,137 @@ func validDestID() *uuid.UUID {
 }
 
 func TestNewPayment_Valid(t *testing.T) {
-	p, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), Amount{ValueCents: 10000, Currency: "USD"})
+	p, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), NewAmount(10000, "USD"))
 	require.NoError(t, err)
 	assert.Equal(t, StatusPending, p.Status)
 	assert.Equal(t, "key-1", p.IdempotencyKey)
 	assert.Equal(t, int64(10000), p.Amount.ValueCents)
 	assert.Equal(t, "USD", p.Amount.Currency)
 	assert.Equal(t, 0, p.RetryCount)
-	assert.Equal(t, 3, p.MaxRetries)
+	assert.Equal(t, DefaultMaxRetries, p.MaxRetries)
+}
+
+func TestPayment_SetMaxRetries(t *testing.T) {
+	p, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), NewAmount(10000, "USD"))
+	require.NoError(t, err)
+
+	require.NoError(t, p.SetMaxRetries(5))
+	assert.Equal(t, 5, p.MaxRetries)
+	assert.Error(t, p.SetMaxRetries(-1))
+	assert.Equal(t, 5, p.MaxRetries)
+}
+
+func TestPayment_SetPlatformFee(t *testing.T) {
+	p, err := NewPayment("key-1", ExternalPayment, validSourceID(), validDestID(), NewAmount(10000, "USD"))
+	require.NoError(t, err)
+	feeAccount := uuid.New()
+
+	require.NoError(t, p.SetPlatformFee(300, feeAccount))
+	assert.Equal(t, int64(300), p.FeeCents)
+	assert.Equal(t, &feeAccount, p.FeeAccountID)
+	assert.Equal(t, int64(9700), p.NetCents())
+
+	assert.Error(t, p.SetPlatformFee(10001, feeAccount))
+	assert.Error(t, p.SetPlatformFee(-1, feeAccount))
+	assert.Equal(t, int64(300), p.FeeCents, "a rejected fee leaves the previous one")
+
+	transfer, err := NewPayment("key-2", InternalTransfer, validSourceID(), validDestID(), NewAmount(10000, "USD"))
+	require.NoError(t, err)
+	assert.Error(t, transfer.SetPlatformFee(300, feeAccount))
+	transfer.SetFee(300, feeAccount)
+	assert.Equal(t, int64(10000), transfer.NetCents(), "transfer fees are charged on top")
+}
+
+func TestPayment_SetConversion(t *testing.T) {
+	p, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), NewAmount(10001, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, p.Amount, p.CreditedAmount())
+
+	require.NoError(t, p.SetConversion(0.92, "EUR"))
+	assert.Equal(t, NewAmount(9201, "EUR"), p.CreditedAmount()) // 9200.92 rounds to nearest cent
+	assert.Equal(t, NewAmount(10001, "USD"), p.Amount)
+
+	assert.Error(t, p.SetConversion(0, "EUR"))
+	assert.Error(t, p.SetConversion(-1, "EUR"))
+	assert.Error(t, p.SetConversion(1.1, "USD"))
+	assert.Error(t, p.SetConversion(0.00001, "JPY"), "converted amount rounds to zero")
+	assert.Equal(t, 0.92, p.Conversion.Rate, "a rejected conversion leaves the previous one")
+}
+
+func TestPayment_SetConversion_AcrossMinorUnits(t *testing.T) {
+	p, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), NewAmount(1000, "USD"))
+	require.NoError(t, err)
+	require.NoError(t, p.SetConversion(150.5, "JPY"))
+	assert.Equal(t, NewAmount(1505, "JPY"), p.CreditedAmount(), "10.00 USD is 1505 yen")
+
+	p, err = NewPayment("key-2", InternalTransfer, validSourceID(), validDestID(), NewAmount(1505, "JPY"))
+	require.NoError(t, err)
+	require.NoError(t, p.SetConversion(0.0066445, "USD"))
+	assert.Equal(t, NewAmount(1000, "USD"), p.CreditedAmount())
+
+	assert.Error(t, p.SetConversion(1, "XYZ"), "unregistered currency")
+}
+
+func TestPayment_IdempotencyKeyFor(t *testing.T) {
+	a, err := NewPayment("client-key", ExternalPayment, validSourceID(), nil, NewAmount(100, "USD"))
+	require.NoError(t, err)
+	b, err := NewPayment("client-key", ExternalPayment, validSourceID(), nil, NewAmount(100, "USD"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "payments:charge:client-key", a.IdempotencyKeyFor(ScopeCharge))
+	assert.Equal(t, a.IdempotencyKeyFor(ScopeCharge), b.IdempotencyKeyFor(ScopeCharge),
+		"payments created under one client key share their charge key")
+	assert.NotEqual(t, a.IdempotencyKeyFor(ScopeCharge), a.IdempotencyKeyFor(ScopeRefund))
 }
 
 func TestNewPayment_InvalidAmount(t *testing.T) {
-	_, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), Amount{ValueCents: -1000, Currency: "USD"})
+	_, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), NewAmount(-1000, "USD"))
 	assert.Error(t, err)
 }
 
 func TestNewPayment_ZeroAmount(t *testing.T) {
-	_, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), Amount{ValueCents: 0, Currency: "USD"})
+	_, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), NewAmount(0, "USD"))
 	assert.Error(t, err)
 }
 
 func TestNewPayment_EmptyCurrency(t *testing.T) {
-	_, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), Amount{ValueCents: 1000, Currency: ""})
+	_, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), NewAmount(1000, ""))
 	assert.Error(t, err)
 }
 
 func TestNewPayment_InvalidCurrencyLength(t *testing.T) {
-	_, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), Amount{ValueCents: 1000, Currency: "US"})
+	_, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), NewAmount(1000, "US"))
 	assert.Error(t, err)
 }
 
+func TestNewPayment_UnknownCurrency(t *testing.T) {
+	_, err := NewPayment("key-1", InternalTransfer, validSourceID(), validDestID(), NewAmount(1000, "XYZ"))
+	var validationErr *errors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "currency", validationErr.Field)
+}
+
 func TestNewPayment_EmptyIdempotencyKey(t *testing.T) {
-	_, err := NewPayment("", InternalTransfer, validSourceID(), validDestID(), Amount{ValueCents: 1000, Currency: "USD"})
+	_, err := NewPayment("", InternalTransfer, validSourceID(), validDestID(), NewAmount(1000, "USD"))
 	assert.ErrorIs(t, err, errors.ErrInvalidInput)
 }
 
 func TestAmount_String(t *testing.T) {
-	a := Amount{ValueCents: 10050, Currency: "USD"}
+	a := NewAmount(10050, "USD")
 	assert.Equal(t, "100.50 USD", a.String())
 
-	a2 := Amount{ValueCents: 5000, Currency: "EUR"}
+	a2 := NewAmount(5000, "EUR")
 	assert.Equal(t, "50.00 EUR", a2.String())
+
+	assert.Equal(t, "1500 JPY", NewAmount(1500, "JPY").String())
+	assert.Equal(t, "1.500 KWD", NewAmount(1500, "KWD").String())
 }
 
 func TestAmount_Validate(t *testing.T) {
-	valid := Amount{ValueCents: 100, Currency: "USD"}
+	valid := NewAmount(100, "USD")
 	assert.NoError(t, valid.Validate())
 
-	invalid := Amount{ValueCents: 0, Currency: "USD"}
+	invalid := NewAmount(0, "USD")
 	assert.Error(t, invalid.Validate())
 }
 
@@ -75,7 +159,7 @@ func TestAmount_Validate(t *testing.T) {
 
 func newPendingPayment(t *testing.T) *Payment {
 	t.Helper()
-	p, err := NewPayment("key-"+uuid.New().String(), ExternalPayment, validSourceID(), nil, Amount{ValueCents: 5000, Currency: "USD"})
+	p, err := NewPayment("key-"+uuid.New().String(), ExternalPayment, validSourceID(), nil, NewAmount(5000, "USD"))
 	require.NoError(t, err)
 	return p
 }
@@ -114,6 +198,8 @@ func TestStateMachine_ProcessingToFailed(t *testing.T) {
 	assert.NoError(t, p.MarkFailed("provider timeout"))
 	assert.Equal(t, StatusFailed, p.Status)
 	assert.Equal(t, "provider timeout", *p.LastError)
+	assert.NotNil(t, p.FailedAt)
+	assert.Nil(t, p.CompletedAt)
 }
 
 func TestStateMachine_FailedToProcessing_Retry(t *testing.T) {
@@ -126,6 +212,7 @@ func TestStateMachine_FailedToProcessing_Retry(t *testing.T) {
 	assert.NoError(t, p.MarkProcessing())
 	assert.Equal(t, StatusProcessing, p.Status)
 	assert.Equal(t, 1, p.RetryCount)
+	assert.Nil(t, p.FailedAt, "retry clears the failure time")
 }
 
 func TestStateMachine_CompletedToRefunded(t *testing.T) {
@@ -133,6 +220,121 @@ func TestStateMachine_CompletedToRefunded(t *testing.T) {
 	require.NoError(t, p.MarkCompleted(nil))
 	assert.NoError(t, p.MarkRefunded())
 	assert.Equal(t, StatusRefunded, p.Status)
+	assert.NotNil(t, p.RefundedAt)
+	assert.NotNil(t, p.CompletedAt)
+}
+
+func TestStateMachine_ProcessingToCancelled(t *testing.T) {
+	p := newPendingPayment(t)
+	require.NoError(t, p.MarkProcessing())
+	assert.NoError(t, p.MarkCancelled())
+	assert.Equal(t, StatusCancelled, p.Status)
+	assert.NotNil(t, p.CancelledAt)
+	assert.Nil(t, p.CompletedAt)
+}
+
+func TestStateMachine_CompletedToDisputedToChargedBack(t *testing.T) {
+	p := newPendingPayment(t)
+	require.NoError(t, p.MarkCompleted(nil))
+	require.NoError(t, p.MarkDisputed())
+	assert.Equal(t, StatusDisputed, p.Status)
+	require.NoError(t, p.MarkChargedBack())
+	assert.Equal(t, StatusChargedBack, p.Status)
+	assert.True(t, p.IsTerminal())
+}
+
+func TestStateMachine_DisputeWon_KeepsCompletedAt(t *testing.T) {
+	p := newPendingPayment(t)
+	require.NoError(t, p.MarkCompleted(nil))
+	completedAt := p.CompletedAt
+	require.NoError(t, p.MarkDisputed())
+	require.NoError(t, p.MarkDisputeWon())
+	assert.Equal(t, StatusCompleted, p.Status)
+	assert.Equal(t, completedAt, p.CompletedAt)
+}
+
+func TestStateMachine_NeedsReview(t *testing.T) {
+	p := newPendingPayment(t)
+	require.NoError(t, p.MarkProcessing())
+	txID := "txn_1"
+	require.NoError(t, p.MarkNeedsReview("provider returned a pending result", &txID))
+	assert.Equal(t, StatusNeedsReview, p.Status)
+	assert.Equal(t, "provider returned a pending result", *p.LastError)
+	assert.Equal(t, &txID, p.ProviderTransactionID)
+	assert.False(t, p.IsTerminal())
+	assert.Error(t, p.MarkProcessing(), "a held payment is not retried")
+	assert.Error(t, p.MarkCancelled())
+
+	require.NoError(t, p.MarkCompleted(nil))
+	assert.Equal(t, &txID, p.ProviderTransactionID)
+
+	p = newPendingPayment(t)
+	assert.Error(t, p.MarkNeedsReview("x", nil), "only processing payments are held")
+	require.NoError(t, p.MarkProcessing())
+	require.NoError(t, p.MarkNeedsReview("x", nil))
+	assert.Error(t, p.MarkFailed("rejected"), "a rejected review is not a retryable failure")
+	require.NoError(t, p.MarkRejected("fraud confirmed"))
+	assert.Equal(t, StatusRejected, p.Status)
+	assert.Equal(t, "fraud confirmed", *p.LastError)
+	assert.True(t, p.IsTerminal())
+	assert.False(t, p.CanRetry())
+	assert.Error(t, p.MarkProcessing())
+}
+
+func TestStateMachine_Abandoned(t *testing.T) {
+	p := newPendingPayment(t)
+	require.NoError(t, p.SetMaxRetries(1))
+	require.NoError(t, p.MarkProcessing())
+	require.NoError(t, p.MarkFailed("declined"))
+	assert.Error(t, p.MarkAbandoned(), "a payment with retries left is not abandoned")
+
+	require.NoError(t, p.IncrementRetry())
+	require.NoError(t, p.MarkProcessing())
+	require.NoError(t, p.MarkFailed("declined"))
+	require.NoError(t, p.MarkAbandoned())
+	assert.Equal(t, StatusAbandoned, p.Status)
+	assert.True(t, p.IsTerminal())
+	assert.False(t, p.CanRetry())
+	assert.Error(t, p.MarkProcessing())
+
+	require.NoError(t, p.Reopen())
+	assert.Equal(t, StatusFailed, p.Status)
+	assert.True(t, p.CanRetry())
+	require.NoError(t, p.IncrementRetry())
+	assert.False(t, p.CanRetry(), "reopening grants exactly one attempt")
+}
+
+func TestStateMachine_Scheduled(t *testing.T) {
+	now := time.Now()
+	p := newPendingPayment(t)
+	assert.Error(t, p.Schedule(now.Add(-time.Minute), now), "a schedule in the past is rejected")
+	require.NoError(t, p.Schedule(now.Add(time.Hour), now))
+	assert.Equal(t, StatusScheduled, p.Status)
+	require.NotNil(t, p.ScheduledAt)
+	assert.Error(t, p.MarkProcessing(), "a scheduled payment is not processed before it is due")
+	assert.Error(t, p.Schedule(now.Add(2*time.Hour), now), "only a new payment can be scheduled")
+
+	require.NoError(t, p.MarkDue())
+	assert.Equal(t, StatusPending, p.Status)
+	require.NoError(t, p.MarkProcessing())
+
+	p = newPendingPayment(t)
+	require.NoError(t, p.Schedule(now.Add(time.Hour), now))
+	require.NoError(t, p.MarkCancelled())
+}
+
+func TestDispute_ResolveTwice_Fails(t *testing.T) {
+	p := newPendingPayment(t)
+	d, err := NewDispute(p, ProviderStripe, "dp_1", "fraudulent", p.Amount.ValueCents)
+	require.NoError(t, err)
+	require.NoError(t, d.Resolve(DisputeLost))
+	assert.ErrorIs(t, d.Resolve(DisputeWon), errors.ErrInvalidStateTransition)
+}
+
+func TestNewDispute_AmountExceedsPayment(t *testing.T) {
+	p := newPendingPayment(t)
+	_, err := NewDispute(p, ProviderStripe, "dp_1", "", p.Amount.ValueCents+1)
+	assert.Error(t, err)
 }
 
 func TestStateMachine_InvalidTransition_CancelledToProcessing(t *testing.T) {
@@ -14,8 +14,13 @@ type Repository interface {
 	// GetByID retrieves a payment by ID
 	GetByID(ctx context.Context, id uuid.UUID) (*Payment, error)
 
-	// GetByIdempotencyKey retrieves a payment by idempotency key
-	GetByIdempotencyKey(ctx context.Context, key string) (*Payment, error)
+	// GetByIdempotencyKey retrieves the payment userID created with the
+	// idempotency key; keys are scoped per user
+	GetByIdempotencyKey(ctx context.Context, userID, key string) (*Payment, error)
+
+	// GetByProviderTransactionID retrieves a payment by the transaction ID
+	// its provider assigned, returning ErrPaymentNotFound on a miss
+	GetByProviderTransactionID(ctx context.Context, txID string) (*Payment, error)
 
 	// Update updates an existing payment
 	Update(ctx context.Context, payment *Payment) error
@@ -23,21 +28,58 @@ type Repository interface {
 	// List lists payments with filters
 	List(ctx context.Context, filter ListFilter) ([]*Payment, error)
 
+	// Count counts the payments matching a filter's conditions, ignoring its
+	// paging and sort
+	Count(ctx context.Context, filter ListFilter) (int, error)
+
 	// AddEvent adds a payment event for audit trail
 	AddEvent(ctx context.Context, event *PaymentEvent) error
 
 	// GetEvents retrieves events for a payment
 	GetEvents(ctx context.Context, paymentID uuid.UUID) ([]*PaymentEvent, error)
+
+	// ListEvents retrieves a page of events for a payment, oldest first
+	ListEvents(ctx context.Context, paymentID uuid.UUID, limit, offset int) ([]*PaymentEvent, error)
+
+	// CountBySourceAccountSince counts payments debiting an account created at or after since
+	CountBySourceAccountSince(ctx context.Context, accountID uuid.UUID, since time.Time) (int, error)
+
+	// ListDueScheduled locks scheduled payments due at or before now, earliest first
+	ListDueScheduled(ctx context.Context, now time.Time, limit int) ([]*Payment, error)
 }
 
+// DefaultListLimit is the page size of a ListFilter without a Limit.
+const DefaultListLimit = 20
+
 type ListFilter struct {
 	AccountID *uuid.UUID
+	BatchID   *uuid.UUID
 	Status    *PaymentStatus
 	Provider  *Provider
+	Currency  *string
 	Limit     int
 	Offset    int
 	SortBy    string
 	SortOrder string
+
+	// Inclusive amount bounds, in minor units of Currency
+	MinAmountCents *int64
+	MaxAmountCents *int64
+
+	// Inclusive creation time bounds
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// UpdatedBefore keeps payments last updated strictly before this time.
+	UpdatedBefore *time.Time
+}
+
+// PageLimit returns the filter's page size, DefaultListLimit if unset.
+func (f ListFilter) PageLimit() int {
+	if f.Limit <= 0 {
+		return DefaultListLimit
+	}
+	return f.Limit
 }
 
 type PaymentEvent struct {
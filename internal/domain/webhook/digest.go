@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Batch is a set of events to deliver to one subscription in a single POST.
+type Batch struct {
+	Subscription *Subscription
+	Events       []Event
+}
+
+type pendingDigest struct {
+	sub     *Subscription
+	events  []Event
+	started time.Time
+}
+
+// DigestBuffer accumulates events for digest-mode subscriptions. A delivery
+// worker calls Add for each event and Due on a timer, delivering every
+// returned batch. Per-event subscriptions pass straight through Add.
+type DigestBuffer struct {
+	mu      sync.Mutex
+	pending map[uuid.UUID]*pendingDigest
+}
+
+func NewDigestBuffer() *DigestBuffer {
+	return &DigestBuffer{pending: make(map[uuid.UUID]*pendingDigest)}
+}
+
+// Add buffers ev for sub. It returns a batch when the event must be delivered
+// now: always for per-event subscriptions, and for digests once they reach
+// DigestMaxSize.
+func (b *DigestBuffer) Add(sub *Subscription, ev Event, now time.Time) *Batch {
+	if sub.DeliveryMode != DeliveryDigest {
+		return &Batch{Subscription: sub, Events: []Event{ev}}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	d, ok := b.pending[sub.ID]
+	if !ok {
+		d = &pendingDigest{sub: sub, started: now}
+		b.pending[sub.ID] = d
+	}
+	d.events = append(d.events, ev)
+
+	if len(d.events) >= sub.DigestMaxSize {
+		delete(b.pending, sub.ID)
+		return &Batch{Subscription: sub, Events: d.events}
+	}
+	return nil
+}
+
+// Due removes and returns the digests whose window has elapsed by now.
+func (b *DigestBuffer) Due(now time.Time) []*Batch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var due []*Batch
+	for id, d := range b.pending {
+		if now.Sub(d.started) >= d.sub.DigestWindow {
+			due = append(due, &Batch{Subscription: d.sub, Events: d.events})
+			delete(b.pending, id)
+		}
+	}
+	return due
+}
+
+// Flush removes and returns every buffered digest, e.g. on shutdown.
+func (b *DigestBuffer) Flush() []*Batch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batches := make([]*Batch, 0, len(b.pending))
+	for id, d := range b.pending {
+		batches = append(batches, &Batch{Subscription: d.sub, Events: d.events})
+		delete(b.pending, id)
+	}
+	return batches
+}
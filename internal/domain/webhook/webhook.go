@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/outbox"
+	"github.com/google/uuid"
+)
+
+type DeliveryMode string
+
+const (
+	// DeliveryPerEvent POSTs each event on its own.
+	DeliveryPerEvent DeliveryMode = "per_event"
+	// DeliveryDigest batches events over DigestWindow (or until DigestMaxSize
+	// events are buffered) and POSTs them as a single array.
+	DeliveryDigest DeliveryMode = "digest"
+)
+
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusInactive Status = "inactive"
+)
+
+type Subscription struct {
+	ID            uuid.UUID
+	URL           string
+	Events        []string
+	Secret        string
+	Status        Status
+	DeliveryMode  DeliveryMode
+	DigestWindow  time.Duration
+	DigestMaxSize int
+	CreatedAt     time.Time
+}
+
+// Event is a single payment event destined for a subscription.
+type Event struct {
+	SchemaVersion int            `json:"schema_version"`
+	ID            uuid.UUID      `json:"id"`
+	PaymentID     uuid.UUID      `json:"payment_id"`
+	EventType     string         `json:"event_type"`
+	Data          map[string]any `json:"data"`
+	OccurredAt    time.Time      `json:"occurred_at"`
+}
+
+// NewEvent builds an event stamped with the current outbox.SchemaVersion.
+func NewEvent(paymentID uuid.UUID, eventType string, data map[string]any, occurredAt time.Time) Event {
+	return Event{
+		SchemaVersion: outbox.SchemaVersion,
+		ID:            uuid.New(),
+		PaymentID:     paymentID,
+		EventType:     eventType,
+		Data:          data,
+		OccurredAt:    occurredAt,
+	}
+}
+
+func NewSubscription(url string, events []string, mode DeliveryMode, digestWindow time.Duration, digestMaxSize int) (*Subscription, error) {
+	if url == "" {
+		return nil, errors.NewValidationError("url", "cannot be empty")
+	}
+	if len(events) == 0 {
+		return nil, errors.NewValidationError("events", "at least one event type is required")
+	}
+
+	switch mode {
+	case "", DeliveryPerEvent:
+		mode = DeliveryPerEvent
+	case DeliveryDigest:
+		if digestWindow <= 0 {
+			return nil, errors.NewValidationError("digest_window", "must be positive in digest mode")
+		}
+		if digestMaxSize <= 0 {
+			return nil, errors.NewValidationError("digest_max_size", "must be positive in digest mode")
+		}
+	default:
+		return nil, errors.NewValidationError("delivery_mode", "must be per_event or digest")
+	}
+
+	return &Subscription{
+		ID:            uuid.New(),
+		URL:           url,
+		Events:        events,
+		Status:        StatusActive,
+		DeliveryMode:  mode,
+		DigestWindow:  digestWindow,
+		DigestMaxSize: digestMaxSize,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+// Wants reports whether the subscription is active and subscribed to eventType.
+func (s *Subscription) Wants(eventType string) bool {
+	if s.Status != StatusActive {
+		return false
+	}
+	for _, e := range s.Events {
+		if e == eventType || e == "*" {
+			return true
+		}
+	}
+	return false
+}
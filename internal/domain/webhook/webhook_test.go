@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/outbox"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEvent() Event {
+	return NewEvent(uuid.New(), "payment.completed", nil, time.Now())
+}
+
+func TestNewEvent_StampsSchemaVersion(t *testing.T) {
+	ev := newEvent()
+	assert.Equal(t, outbox.SchemaVersion, ev.SchemaVersion)
+	assert.NotEqual(t, uuid.Nil, ev.ID)
+}
+
+func TestNewSubscription_DefaultsToPerEvent(t *testing.T) {
+	sub, err := NewSubscription("https://example.com/hook", []string{"payment.completed"}, "", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryPerEvent, sub.DeliveryMode)
+	assert.True(t, sub.Wants("payment.completed"))
+	assert.False(t, sub.Wants("payment.failed"))
+}
+
+func TestNewSubscription_DigestRequiresWindowAndSize(t *testing.T) {
+	_, err := NewSubscription("https://example.com/hook", []string{"*"}, DeliveryDigest, 0, 10)
+	assert.Error(t, err)
+
+	_, err = NewSubscription("https://example.com/hook", []string{"*"}, DeliveryDigest, time.Minute, 0)
+	assert.Error(t, err)
+}
+
+func TestDigestBuffer_PerEventPassesThrough(t *testing.T) {
+	sub, _ := NewSubscription("https://example.com/hook", []string{"*"}, DeliveryPerEvent, 0, 0)
+	buf := NewDigestBuffer()
+
+	batch := buf.Add(sub, newEvent(), time.Now())
+	require.NotNil(t, batch)
+	assert.Len(t, batch.Events, 1)
+}
+
+func TestDigestBuffer_FlushesOnSize(t *testing.T) {
+	sub, _ := NewSubscription("https://example.com/hook", []string{"*"}, DeliveryDigest, time.Hour, 3)
+	buf := NewDigestBuffer()
+	now := time.Now()
+
+	assert.Nil(t, buf.Add(sub, newEvent(), now))
+	assert.Nil(t, buf.Add(sub, newEvent(), now))
+	batch := buf.Add(sub, newEvent(), now)
+	require.NotNil(t, batch)
+	assert.Len(t, batch.Events, 3)
+	assert.Empty(t, buf.Flush())
+}
+
+func TestDigestBuffer_FlushesOnWindow(t *testing.T) {
+	sub, _ := NewSubscription("https://example.com/hook", []string{"*"}, DeliveryDigest, time.Minute, 100)
+	buf := NewDigestBuffer()
+	start := time.Now()
+
+	buf.Add(sub, newEvent(), start)
+	buf.Add(sub, newEvent(), start.Add(10*time.Second))
+
+	assert.Empty(t, buf.Due(start.Add(30*time.Second)))
+
+	due := buf.Due(start.Add(time.Minute))
+	require.Len(t, due, 1)
+	assert.Equal(t, sub.ID, due[0].Subscription.ID)
+	assert.Len(t, due[0].Events, 2)
+}
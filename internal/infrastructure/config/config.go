@@ -1,11 +1,17 @@
 package config
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/cassiomorais/payments/pkg/currency"
+	"github.com/google/uuid"
 	"github.com/spf13/viper"
 )
 
@@ -17,7 +23,10 @@ type Config struct {
 	Worker        WorkerConfig        `mapstructure:"worker"`
 	Observability ObservabilityConfig `mapstructure:"observability"`
 	Auth          AuthConfig          `mapstructure:"auth"`
+	Webhook       WebhookConfig       `mapstructure:"webhook"`
 	InstanceID    string              `mapstructure:"instance_id"`
+	// Providers holds per-provider settings, keyed by provider name.
+	Providers map[string]ProviderConfig `mapstructure:"providers"`
 }
 
 type ServerConfig struct {
@@ -27,6 +36,21 @@ type ServerConfig struct {
 	IdleTimeout     time.Duration `mapstructure:"idle_timeout"`
 	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
 	CORS            CORSConfig    `mapstructure:"cors"`
+	// StrictQueryParams makes list endpoints reject unknown query parameters
+	// with 400. Clients can opt in per request with ?strict=true.
+	StrictQueryParams bool `mapstructure:"strict_query_params"`
+	// ResponseEnvelope is the default JSON body shape, "raw" or "wrapped".
+	// Clients can choose per request with an Accept envelope parameter.
+	ResponseEnvelope string `mapstructure:"response_envelope"`
+	// MaxBodyBytes caps JSON request bodies; larger ones get 413.
+	// MaxBatchBodyBytes replaces it on the batch payment endpoint. Zero uses
+	// the built-in 1MB limit.
+	MaxBodyBytes      int64 `mapstructure:"max_body_bytes"`
+	MaxBatchBodyBytes int64 `mapstructure:"max_batch_body_bytes"`
+	// AuditBufferSize is how many audit log entries may wait to be written.
+	// Entries arriving while it is full are dropped and counted. Zero uses
+	// the built-in 1024.
+	AuditBufferSize int `mapstructure:"audit_buffer_size"`
 }
 
 type CORSConfig struct {
@@ -37,6 +61,54 @@ type CORSConfig struct {
 type AuthConfig struct {
 	JWTSecret string        `mapstructure:"jwt_secret"`
 	JWTExpiry time.Duration `mapstructure:"jwt_expiry"`
+	StepUp    StepUpConfig  `mapstructure:"step_up"`
+	// AdminScope is the token scope the default authorization policies
+	// grant staff access to, including the /api/v1/admin routes.
+	AdminScope string `mapstructure:"admin_scope"`
+
+	// SupportScope marks support staff for response masking. ResponseMasking
+	// lists the payment fields redacted per non-owner role (admin, support,
+	// other); owners always see every field. Empty uses the built-in defaults.
+	SupportScope    string              `mapstructure:"support_scope"`
+	ResponseMasking map[string][]string `mapstructure:"response_masking"`
+
+	// Policies overrides the authorization policy of individual operations,
+	// keyed by operation name (e.g. "refund_payment").
+	Policies map[string]PolicyConfig `mapstructure:"policies"`
+
+	// RefreshTokenExpiry is how long refresh tokens issued by
+	// POST /api/v1/auth/token stay valid. Clients lists who may obtain
+	// tokens there, keyed by client ID (lower case, as viper folds keys).
+	RefreshTokenExpiry time.Duration           `mapstructure:"refresh_token_expiry"`
+	Clients            map[string]ClientConfig `mapstructure:"clients"`
+}
+
+// ClientConfig is an API client allowed to exchange its secret for tokens
+// issued to UserID with Scopes and Roles. SecretSHA256 is the hex SHA-256
+// of the secret.
+type ClientConfig struct {
+	SecretSHA256 string   `mapstructure:"secret_sha256"`
+	UserID       string   `mapstructure:"user_id"`
+	Scopes       []string `mapstructure:"scopes"`
+	Roles        []string `mapstructure:"roles"`
+}
+
+// PolicyConfig admits callers holding any of Scopes or Roles, and otherwise
+// those passing Check (authenticated, account_owner, source_owner,
+// payment_source, payment_party or scope).
+type PolicyConfig struct {
+	Check  string   `mapstructure:"check"`
+	Scopes []string `mapstructure:"scopes"`
+	Roles  []string `mapstructure:"roles"`
+}
+
+// StepUpConfig controls when money-moving requests require an elevated token.
+// A zero threshold disables that check.
+type StepUpConfig struct {
+	AmountThreshold int64         `mapstructure:"amount_threshold"` // in cents
+	CountThreshold  int           `mapstructure:"count_threshold"`
+	CountWindow     time.Duration `mapstructure:"count_window"`
+	RequiredScope   string        `mapstructure:"required_scope"`
 }
 
 type DatabaseConfig struct {
@@ -49,6 +121,13 @@ type DatabaseConfig struct {
 	MinConnections  int           `mapstructure:"min_connections"`
 	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
 	SSLMode         string        `mapstructure:"ssl_mode"`
+	// AssertTransactions fails money-moving repository calls made outside a
+	// transaction. Meant for development; leave off in production.
+	AssertTransactions bool `mapstructure:"assert_transactions"`
+	// ReadRetries is how many times idempotent reads are retried after a
+	// transient connection error. Writes are never retried here.
+	ReadRetries      int           `mapstructure:"read_retries"`
+	ReadRetryBackoff time.Duration `mapstructure:"read_retry_backoff"`
 }
 
 type RedisConfig struct {
@@ -63,10 +142,134 @@ type RedisConfig struct {
 type PaymentConfig struct {
 	MaxRetries              int           `mapstructure:"max_retries"`
 	RetryDelay              time.Duration `mapstructure:"retry_delay"`
+	// MaxRetryDelay caps the backoff between retries of a failed payment,
+	// which starts at RetryDelay and doubles per attempt.
+	MaxRetryDelay           time.Duration `mapstructure:"max_retry_delay"`
 	LockTTL                 time.Duration `mapstructure:"lock_ttl"`
 	ProcessingTimeout       time.Duration `mapstructure:"processing_timeout"`
 	CircuitBreakerThreshold int           `mapstructure:"circuit_breaker_threshold"`
 	CircuitBreakerTimeout   time.Duration `mapstructure:"circuit_breaker_timeout"`
+	// Half-open breakers let CircuitBreakerHalfOpenProbes requests through at
+	// a time and close after CircuitBreakerHalfOpenSuccesses consecutive
+	// successes. CircuitBreakers overrides any of these per provider.
+	CircuitBreakerHalfOpenProbes    int                             `mapstructure:"circuit_breaker_half_open_probes"`
+	CircuitBreakerHalfOpenSuccesses int                             `mapstructure:"circuit_breaker_half_open_successes"`
+	CircuitBreakers                 map[string]CircuitBreakerConfig `mapstructure:"circuit_breakers"`
+	// DefaultCurrency is applied to payment and transfer requests that omit a
+	// currency. It is never inferred from the account; leave it empty to
+	// require an explicit currency on every request.
+	DefaultCurrency     string   `mapstructure:"default_currency"`
+	SupportedCurrencies []string `mapstructure:"supported_currencies"`
+	// Currencies adds currencies to the built-in ISO 4217 registry, or
+	// overrides their metadata, keyed by code.
+	Currencies  map[string]CurrencyConfig `mapstructure:"currencies"`
+	TransferFee TransferFeeConfig         `mapstructure:"transfer_fee"`
+	// PlatformFeeAccountID is credited the fees deducted from external
+	// payments. Empty rejects payments that carry a fee.
+	PlatformFeeAccountID string `mapstructure:"platform_fee_account_id"`
+	// RefundWindow rejects refunds of payments completed longer ago than this
+	// (0 disables). Tokens with RefundOverrideScope may refund past the window.
+	RefundWindow        time.Duration `mapstructure:"refund_window"`
+	RefundOverrideScope string        `mapstructure:"refund_override_scope"`
+	Disputes            DisputeConfig `mapstructure:"disputes"`
+	FX                  FXConfig      `mapstructure:"fx"`
+
+	ManualRefunds ManualRefundConfig `mapstructure:"manual_refunds"`
+	// RequireExternalSource rejects external payments without a source
+	// account, which would leave the charge unaccounted for internally.
+	RequireExternalSource bool `mapstructure:"require_external_source"`
+
+	LatencySLO LatencySLOConfig `mapstructure:"latency_slo"`
+
+	// ReconcileMinAge keeps reconciliation away from payments that entered
+	// processing less than this long ago. The worker never reconciles
+	// sooner than ProcessingTimeout.
+	ReconcileMinAge time.Duration `mapstructure:"reconcile_min_age"`
+
+	// StatementSigningKey is the HMAC key for signed account statements.
+	// Empty disables signing and verification.
+	StatementSigningKey string `mapstructure:"statement_signing_key"`
+
+	// MaxBatchSize caps the number of payments in one batch request (0 uses
+	// the service default).
+	MaxBatchSize int `mapstructure:"max_batch_size"`
+}
+
+// LatencySLOConfig puts a provider into slow mode for Cooldown when the
+// Percentile of its last Window call durations exceeds Threshold (0
+// disables). Fallbacks maps a provider to the one its payments are routed to
+// while it is slow.
+type LatencySLOConfig struct {
+	Percentile float64           `mapstructure:"percentile"`
+	Threshold  time.Duration     `mapstructure:"threshold"`
+	Window     int               `mapstructure:"window"`
+	MinSamples int               `mapstructure:"min_samples"`
+	Cooldown   time.Duration     `mapstructure:"cooldown"`
+	Fallbacks  map[string]string `mapstructure:"fallbacks"`
+}
+
+// ManualRefundConfig lists providers that cannot refund through their API.
+// Mode "task" records a manual refund task for operations; "reject" refuses
+// such refunds.
+type ManualRefundConfig struct {
+	Providers []string `mapstructure:"providers"`
+	Mode      string   `mapstructure:"mode"`
+}
+
+// CircuitBreakerConfig overrides breaker settings for one provider. Zero
+// fields inherit the payment.circuit_breaker_* values.
+type CircuitBreakerConfig struct {
+	Threshold         int           `mapstructure:"threshold"`
+	Timeout           time.Duration `mapstructure:"timeout"`
+	HalfOpenProbes    int           `mapstructure:"half_open_probes"`
+	HalfOpenSuccesses int           `mapstructure:"half_open_successes"`
+}
+
+// ProviderConfig rate-limits the requests sent to one provider to RPS per
+// second, in bursts of up to Burst (default: one second's worth). Zero RPS
+// is unlimited.
+type ProviderConfig struct {
+	RPS   float64 `mapstructure:"rps"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// CurrencyConfig describes a currency to register: MinorUnits is its number
+// of decimal places.
+type CurrencyConfig struct {
+	Name       string `mapstructure:"name"`
+	MinorUnits int    `mapstructure:"minor_units"`
+}
+
+// CustomCurrencies returns the configured currencies. Codes are upper-cased,
+// as map keys arrive lower-cased from the config loader.
+func (c PaymentConfig) CustomCurrencies() []currency.Currency {
+	out := make([]currency.Currency, 0, len(c.Currencies))
+	for code, cc := range c.Currencies {
+		out = append(out, currency.Currency{Code: strings.ToUpper(code), Name: cc.Name, MinorUnits: cc.MinorUnits})
+	}
+	return out
+}
+
+// FXConfig lists the directed currency corridors ("USD->EUR") conversions
+// may use. An empty list disables FX.
+type FXConfig struct {
+	AllowedPairs []string `mapstructure:"allowed_pairs"`
+}
+
+// DisputeConfig controls provider dispute ingestion. Policy is "reverse"
+// (move funds back when a dispute opens) or "hold" (only when it is lost).
+type DisputeConfig struct {
+	Policy        string `mapstructure:"policy"`
+	WebhookSecret string `mapstructure:"webhook_secret"`
+}
+
+// TransferFeeConfig charges a fee on internal transfers of FeeAccountID's
+// currency: FlatCents plus BasisPoints (1/100 of a percent) of the amount.
+// An empty FeeAccountID disables fees.
+type TransferFeeConfig struct {
+	FeeAccountID string `mapstructure:"fee_account_id"`
+	FlatCents    int64  `mapstructure:"flat_cents"`
+	BasisPoints  int64  `mapstructure:"basis_points"`
 }
 
 type WorkerConfig struct {
@@ -75,6 +278,54 @@ type WorkerConfig struct {
 	OutboxPollInterval time.Duration `mapstructure:"outbox_poll_interval"`
 	ConsumerGroup    string        `mapstructure:"consumer_group"`
 	IdempotencyTTL   time.Duration `mapstructure:"idempotency_ttl"`
+	GroupCheckInterval time.Duration `mapstructure:"group_check_interval"`
+	MaxGroupLag        int64         `mapstructure:"max_group_lag"`
+	HealthPort         int           `mapstructure:"health_port"`
+	// DLQ alerting: a zero threshold disables that check. The rate threshold
+	// counts new DLQ entries per check interval.
+	DLQCheckInterval  time.Duration `mapstructure:"dlq_check_interval"`
+	DLQDepthThreshold int64         `mapstructure:"dlq_depth_threshold"`
+	DLQRateThreshold  int64         `mapstructure:"dlq_rate_threshold"`
+	// Outbox cleanup deletes published and failed entries older than
+	// OutboxRetention (0 disables), OutboxCleanupBatchSize rows at a time.
+	OutboxRetention        time.Duration `mapstructure:"outbox_retention"`
+	OutboxCleanupInterval  time.Duration `mapstructure:"outbox_cleanup_interval"`
+	OutboxCleanupBatchSize int           `mapstructure:"outbox_cleanup_batch_size"`
+	// A failed outbox publish is retried after OutboxRetryDelay, doubled on
+	// each attempt up to OutboxMaxRetryDelay. Entries out of retries are
+	// marked failed and, with OutboxDeadLetter, copied to the DLQ.
+	OutboxRetryDelay    time.Duration `mapstructure:"outbox_retry_delay"`
+	OutboxMaxRetryDelay time.Duration `mapstructure:"outbox_max_retry_delay"`
+	OutboxDeadLetter    bool          `mapstructure:"outbox_dead_letter"`
+	// Every ReclaimInterval (0 disables) the worker claims payment and webhook
+	// messages pending longer than ReclaimMinIdle, e.g. from a crashed worker.
+	ReclaimInterval time.Duration `mapstructure:"reclaim_interval"`
+	ReclaimMinIdle  time.Duration `mapstructure:"reclaim_min_idle"`
+	// Every SchedulePollInterval (0 disables) the worker queues scheduled
+	// payments that have come due.
+	SchedulePollInterval time.Duration `mapstructure:"schedule_poll_interval"`
+	// Every ReconcileInterval (0 disables) the worker reconciles up to
+	// ReconcileBatchSize payments stuck in processing with their provider.
+	ReconcileInterval  time.Duration `mapstructure:"reconcile_interval"`
+	ReconcileBatchSize int           `mapstructure:"reconcile_batch_size"`
+	// Idempotency keys expire IdempotencyTTL after they were stored (0 keeps
+	// them forever); every IdempotencyCleanupInterval (0 disables) the worker
+	// purges the expired ones.
+	IdempotencyCleanupInterval time.Duration `mapstructure:"idempotency_cleanup_interval"`
+	// On shutdown the worker stops reading messages and gives those already
+	// read up to ShutdownTimeout to finish; the rest stay pending for reclaim.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+}
+
+// WebhookConfig controls delivery of the webhook stream. An empty URL
+// disables the consumer. Each delivery is signed with Secret and retried
+// MaxRetries times with backoff from RetryDelay before it goes to the DLQ.
+type WebhookConfig struct {
+	URL        string        `mapstructure:"url"`
+	Secret     string        `mapstructure:"secret"`
+	MaxRetries int           `mapstructure:"max_retries"`
+	RetryDelay time.Duration `mapstructure:"retry_delay"`
+	Timeout    time.Duration `mapstructure:"timeout"`
 }
 
 type ObservabilityConfig struct {
@@ -133,21 +384,125 @@ func (c *Config) Validate() error {
 	if c.Server.WriteTimeout <= 0 {
 		errs = append(errs, fmt.Errorf("server.write_timeout must be positive"))
 	}
+	if e := c.Server.ResponseEnvelope; e != "" && e != "raw" && e != "wrapped" {
+		errs = append(errs, fmt.Errorf("server.response_envelope must be raw or wrapped, got %q", e))
+	}
+	if c.Server.MaxBodyBytes < 0 || c.Server.MaxBatchBodyBytes < 0 {
+		errs = append(errs, fmt.Errorf("server.max_body_bytes and server.max_batch_body_bytes must not be negative"))
+	}
+	if c.Server.AuditBufferSize < 0 {
+		errs = append(errs, fmt.Errorf("server.audit_buffer_size must not be negative"))
+	}
+	for name, p := range c.Providers {
+		if p.RPS < 0 || p.Burst < 0 {
+			errs = append(errs, fmt.Errorf("providers.%s: rps and burst must not be negative", name))
+		}
+	}
 	if c.Database.Host == "" {
 		errs = append(errs, fmt.Errorf("database.host is required"))
 	}
 	if c.Database.Port <= 0 {
 		errs = append(errs, fmt.Errorf("database.port must be positive"))
 	}
+	if c.Database.ReadRetries < 0 {
+		errs = append(errs, fmt.Errorf("database.read_retries must not be negative"))
+	}
 	if c.Redis.Port <= 0 {
 		errs = append(errs, fmt.Errorf("redis.port must be positive"))
 	}
+	if c.Payment.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("payment.max_retries must not be negative"))
+	}
+	if c.Payment.RetryDelay < 0 || c.Payment.MaxRetryDelay < 0 {
+		errs = append(errs, fmt.Errorf("payment.retry_delay and payment.max_retry_delay must not be negative"))
+	}
 	if c.Payment.LockTTL <= 0 {
 		errs = append(errs, fmt.Errorf("payment.lock_ttl must be positive"))
 	}
+	if c.Payment.MaxBatchSize < 0 {
+		errs = append(errs, fmt.Errorf("payment.max_batch_size must not be negative"))
+	}
+	if c.Payment.ReconcileMinAge < 0 {
+		errs = append(errs, fmt.Errorf("payment.reconcile_min_age must not be negative"))
+	}
 	if c.Worker.BatchSize <= 0 {
 		errs = append(errs, fmt.Errorf("worker.batch_size must be positive"))
 	}
+	if c.Worker.ShutdownTimeout < 0 {
+		errs = append(errs, fmt.Errorf("worker.shutdown_timeout must not be negative"))
+	}
+	if c.Worker.IdempotencyTTL < 0 {
+		errs = append(errs, fmt.Errorf("worker.idempotency_ttl must not be negative"))
+	}
+	if c.Worker.ReclaimInterval > 0 && c.Worker.ReclaimMinIdle <= 0 {
+		errs = append(errs, fmt.Errorf("worker.reclaim_min_idle must be positive when reclaim is enabled"))
+	}
+	if c.Worker.ReconcileInterval > 0 && c.Worker.ReconcileBatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("worker.reconcile_batch_size must be positive when reconciliation is enabled"))
+	}
+	if c.Worker.OutboxRetryDelay < 0 || c.Worker.OutboxMaxRetryDelay < 0 {
+		errs = append(errs, fmt.Errorf("worker.outbox_retry_delay and worker.outbox_max_retry_delay must not be negative"))
+	}
+
+	custom := make(map[string]bool, len(c.Payment.Currencies))
+	for _, cur := range c.Payment.CustomCurrencies() {
+		if err := cur.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("payment.currencies: %w", err))
+		}
+		custom[cur.Code] = true
+	}
+	for _, code := range c.Payment.SupportedCurrencies {
+		if !custom[code] && !currency.IsKnown(code) {
+			errs = append(errs, fmt.Errorf("payment.supported_currencies: %q is not a known currency; declare it under payment.currencies", code))
+		}
+	}
+
+	if c.Payment.DefaultCurrency != "" && !slices.Contains(c.Payment.SupportedCurrencies, c.Payment.DefaultCurrency) {
+		errs = append(errs, fmt.Errorf("payment.default_currency %q is not in payment.supported_currencies", c.Payment.DefaultCurrency))
+	}
+
+	if fee := c.Payment.TransferFee; fee.FeeAccountID != "" {
+		if _, err := uuid.Parse(fee.FeeAccountID); err != nil {
+			errs = append(errs, fmt.Errorf("payment.transfer_fee.fee_account_id must be a UUID"))
+		}
+		if fee.FlatCents < 0 || fee.BasisPoints < 0 {
+			errs = append(errs, fmt.Errorf("payment.transfer_fee amounts must not be negative"))
+		}
+	}
+	if id := c.Payment.PlatformFeeAccountID; id != "" {
+		if _, err := uuid.Parse(id); err != nil {
+			errs = append(errs, fmt.Errorf("payment.platform_fee_account_id must be a UUID"))
+		}
+	}
+
+	if p := c.Payment.Disputes.Policy; p != "" && p != "reverse" && p != "hold" {
+		errs = append(errs, fmt.Errorf("payment.disputes.policy must be reverse or hold, got %q", p))
+	}
+
+	if m := c.Payment.ManualRefunds.Mode; m != "" && m != "task" && m != "reject" {
+		errs = append(errs, fmt.Errorf("payment.manual_refunds.mode must be task or reject, got %q", m))
+	}
+
+	if slo := c.Payment.LatencySLO; slo.Threshold < 0 {
+		errs = append(errs, fmt.Errorf("payment.latency_slo.threshold must not be negative"))
+	} else if slo.Threshold > 0 {
+		if slo.Percentile <= 0 || slo.Percentile >= 1 {
+			errs = append(errs, fmt.Errorf("payment.latency_slo.percentile must be between 0 and 1, got %v", slo.Percentile))
+		}
+		if slo.MinSamples < 1 || slo.Window < slo.MinSamples {
+			errs = append(errs, fmt.Errorf("payment.latency_slo needs 1 <= min_samples <= window"))
+		}
+		if slo.Cooldown <= 0 {
+			errs = append(errs, fmt.Errorf("payment.latency_slo.cooldown must be positive"))
+		}
+	}
+
+	for _, pair := range c.Payment.FX.AllowedPairs {
+		from, to, ok := strings.Cut(pair, "->")
+		if !ok || from == to || !slices.Contains(c.Payment.SupportedCurrencies, from) || !slices.Contains(c.Payment.SupportedCurrencies, to) {
+			errs = append(errs, fmt.Errorf("payment.fx.allowed_pairs: %q must be FROM->TO of two different supported currencies", pair))
+		}
+	}
 
 	// Production environment checks
 	env := os.Getenv("ENV")
@@ -162,10 +517,40 @@ func (c *Config) Validate() error {
 		// Enable app-level TLS only if required by your architecture
 	}
 
+	if k := c.Payment.StatementSigningKey; k != "" && len(k) < 32 {
+		errs = append(errs, fmt.Errorf("payment.statement_signing_key must be at least 32 characters"))
+	}
+
+	if w := c.Webhook; w.URL != "" {
+		if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("webhook.url must be an http(s) URL"))
+		}
+		if len(w.Secret) < 32 {
+			errs = append(errs, fmt.Errorf("webhook.secret must be at least 32 characters"))
+		}
+		if w.MaxRetries < 0 {
+			errs = append(errs, fmt.Errorf("webhook.max_retries must not be negative"))
+		}
+		if w.RetryDelay <= 0 || w.Timeout <= 0 {
+			errs = append(errs, fmt.Errorf("webhook.retry_delay and webhook.timeout must be positive"))
+		}
+	}
+
 	// JWT secret length validation
 	if c.Auth.JWTSecret != "" && len(c.Auth.JWTSecret) < 32 {
 		errs = append(errs, fmt.Errorf("auth.jwt_secret must be at least 32 characters"))
 	}
+	if c.Auth.RefreshTokenExpiry < 0 {
+		errs = append(errs, fmt.Errorf("auth.refresh_token_expiry must not be negative"))
+	}
+	for id, client := range c.Auth.Clients {
+		if _, err := hex.DecodeString(client.SecretSHA256); err != nil || len(client.SecretSHA256) != 64 {
+			errs = append(errs, fmt.Errorf("auth.clients.%s.secret_sha256 must be a hex SHA-256 digest", id))
+		}
+		if client.UserID == "" {
+			errs = append(errs, fmt.Errorf("auth.clients.%s.user_id is required", id))
+		}
+	}
 
 	return errors.Join(errs...)
 }
@@ -179,6 +564,11 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.shutdown_timeout", "30s")
 	v.SetDefault("server.cors.allowed_origins", []string{"*"})
 	v.SetDefault("server.cors.allow_credentials", false)
+	v.SetDefault("server.strict_query_params", false)
+	v.SetDefault("server.response_envelope", "raw")
+	v.SetDefault("server.max_body_bytes", 1<<20)
+	v.SetDefault("server.max_batch_body_bytes", 10<<20)
+	v.SetDefault("server.audit_buffer_size", 1024)
 
 	// Database defaults
 	v.SetDefault("database.host", "localhost")
@@ -189,6 +579,9 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("database.min_connections", 5)
 	v.SetDefault("database.conn_max_lifetime", "1h")
 	v.SetDefault("database.ssl_mode", "disable")
+	v.SetDefault("database.assert_transactions", false)
+	v.SetDefault("database.read_retries", 2)
+	v.SetDefault("database.read_retry_backoff", "50ms")
 
 	// Redis defaults
 	v.SetDefault("redis.host", "localhost")
@@ -203,14 +596,65 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("worker.outbox_poll_interval", "2s")
 	v.SetDefault("worker.consumer_group", "payment-processors")
 	v.SetDefault("worker.idempotency_ttl", "24h")
+	v.SetDefault("worker.group_check_interval", "30s")
+	v.SetDefault("worker.max_group_lag", 1000)
+	v.SetDefault("worker.health_port", 9091)
+	v.SetDefault("worker.dlq_check_interval", "30s")
+	v.SetDefault("worker.dlq_depth_threshold", 100)
+	v.SetDefault("worker.dlq_rate_threshold", 20)
+	v.SetDefault("worker.outbox_retention", "168h")
+	v.SetDefault("worker.outbox_cleanup_interval", "1h")
+	v.SetDefault("worker.outbox_cleanup_batch_size", 1000)
+	v.SetDefault("worker.outbox_retry_delay", "1s")
+	v.SetDefault("worker.outbox_max_retry_delay", "5m")
+	v.SetDefault("worker.outbox_dead_letter", true)
+	v.SetDefault("worker.reclaim_interval", "30s")
+	v.SetDefault("worker.reclaim_min_idle", "2m")
+	v.SetDefault("worker.schedule_poll_interval", "10s")
+	v.SetDefault("worker.reconcile_interval", "1m")
+	v.SetDefault("worker.reconcile_batch_size", 100)
+	v.SetDefault("worker.idempotency_cleanup_interval", "1h")
+	v.SetDefault("worker.shutdown_timeout", "30s")
+
+	// Webhook defaults
+	v.SetDefault("webhook.url", "")
+	v.SetDefault("webhook.secret", "")
+	v.SetDefault("webhook.max_retries", 5)
+	v.SetDefault("webhook.retry_delay", "1s")
+	v.SetDefault("webhook.timeout", "10s")
 
 	// Payment defaults
 	v.SetDefault("payment.max_retries", 3)
 	v.SetDefault("payment.retry_delay", "1s")
+	v.SetDefault("payment.max_retry_delay", "5m")
 	v.SetDefault("payment.lock_ttl", "30s")
 	v.SetDefault("payment.processing_timeout", "60s")
+	v.SetDefault("payment.reconcile_min_age", "5m")
 	v.SetDefault("payment.circuit_breaker_threshold", 10)
 	v.SetDefault("payment.circuit_breaker_timeout", "30s")
+	v.SetDefault("payment.circuit_breaker_half_open_probes", 10)
+	v.SetDefault("payment.circuit_breaker_half_open_successes", 10)
+	v.SetDefault("payment.default_currency", "")
+	v.SetDefault("payment.supported_currencies", []string{"USD", "EUR", "GBP", "BRL"})
+	v.SetDefault("payment.refund_window", 0)
+	v.SetDefault("payment.refund_override_scope", "payments:admin")
+	v.SetDefault("payment.disputes.policy", "reverse")
+	v.SetDefault("payment.disputes.webhook_secret", "")
+	v.SetDefault("payment.fx.allowed_pairs", []string{})
+	v.SetDefault("payment.manual_refunds.providers", []string{})
+	v.SetDefault("payment.manual_refunds.mode", "task")
+	v.SetDefault("payment.require_external_source", true)
+	v.SetDefault("payment.latency_slo.percentile", 0.95)
+	v.SetDefault("payment.latency_slo.threshold", 0)
+	v.SetDefault("payment.latency_slo.window", 100)
+	v.SetDefault("payment.latency_slo.min_samples", 20)
+	v.SetDefault("payment.latency_slo.cooldown", "5m")
+	v.SetDefault("payment.statement_signing_key", "")
+	v.SetDefault("payment.max_batch_size", 100)
+	v.SetDefault("payment.transfer_fee.fee_account_id", "")
+	v.SetDefault("payment.transfer_fee.flat_cents", 0)
+	v.SetDefault("payment.transfer_fee.basis_points", 0)
+	v.SetDefault("payment.platform_fee_account_id", "")
 
 	// Observability defaults
 	v.SetDefault("observability.log_level", "info")
@@ -220,6 +664,13 @@ func setDefaults(v *viper.Viper) {
 
 	// Auth defaults
 	v.SetDefault("auth.jwt_expiry", "24h")
+	v.SetDefault("auth.refresh_token_expiry", "720h")
+	v.SetDefault("auth.step_up.amount_threshold", 0)
+	v.SetDefault("auth.step_up.count_threshold", 0)
+	v.SetDefault("auth.step_up.count_window", "24h")
+	v.SetDefault("auth.step_up.required_scope", "mfa")
+	v.SetDefault("auth.admin_scope", "payments:admin")
+	v.SetDefault("auth.support_scope", "payments:support")
 
 	// Instance ID
 	v.SetDefault("instance_id", "payments-1")
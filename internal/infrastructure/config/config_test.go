@@ -208,6 +208,99 @@ func TestConfig_Validate_InvalidWorkerBatchSize(t *testing.T) {
 	assert.Contains(t, err.Error(), "worker.batch_size")
 }
 
+func TestConfig_Validate_NegativeOutboxRetryDelay(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{
+			Port:         8080,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		},
+		Database: DatabaseConfig{Host: "localhost", Port: 5432},
+		Redis:    RedisConfig{Port: 6379},
+		Payment:  PaymentConfig{LockTTL: 30 * time.Second},
+		Worker: WorkerConfig{
+			BatchSize:        10,
+			OutboxRetryDelay: -time.Second,
+		},
+	}
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "worker.outbox_retry_delay")
+}
+
+func TestConfig_Validate_UnsupportedDefaultCurrency(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{
+			Port:         8080,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		},
+		Database: DatabaseConfig{Host: "localhost", Port: 5432},
+		Redis:    RedisConfig{Port: 6379},
+		Payment: PaymentConfig{
+			LockTTL:             30 * time.Second,
+			DefaultCurrency:     "XYZ", // Invalid
+			SupportedCurrencies: []string{"USD", "EUR"},
+		},
+		Worker: WorkerConfig{BatchSize: 10},
+	}
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "payment.default_currency")
+}
+
+func TestConfig_Validate_UnknownSupportedCurrency(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{
+			Port:         8080,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		},
+		Database: DatabaseConfig{Host: "localhost", Port: 5432},
+		Redis:    RedisConfig{Port: 6379},
+		Payment: PaymentConfig{
+			LockTTL:             30 * time.Second,
+			SupportedCurrencies: []string{"USD", "XTS"},
+		},
+		Worker: WorkerConfig{BatchSize: 10},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "payment.supported_currencies")
+
+	cfg.Payment.Currencies = map[string]CurrencyConfig{"xts": {Name: "Testing", MinorUnits: 3}}
+	assert.NoError(t, cfg.Validate(), "declared currencies are supported")
+
+	cfg.Payment.Currencies["xts"] = CurrencyConfig{MinorUnits: -1}
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "payment.currencies")
+}
+
+func TestConfig_Validate_InvalidTransferFeeAccount(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{
+			Port:         8080,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		},
+		Database: DatabaseConfig{Host: "localhost", Port: 5432},
+		Redis:    RedisConfig{Port: 6379},
+		Payment: PaymentConfig{
+			LockTTL:     30 * time.Second,
+			TransferFee: TransferFeeConfig{FeeAccountID: "not-a-uuid", FlatCents: 50}, // Invalid
+		},
+		Worker: WorkerConfig{BatchSize: 10},
+	}
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "payment.transfer_fee.fee_account_id")
+}
+
 func TestConfig_Validate_MultipleErrors(t *testing.T) {
 	cfg := &Config{
 		Server: ServerConfig{
@@ -353,3 +446,79 @@ func TestCORSConfig_Fields(t *testing.T) {
 	assert.Equal(t, []string{"https://example.com", "https://app.example.com"}, cfg.AllowedOrigins)
 	assert.True(t, cfg.AllowCredentials)
 }
+
+func TestConfig_Validate_UnsupportedFXPair(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{
+			Port:         8080,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		},
+		Database: DatabaseConfig{Host: "localhost", Port: 5432},
+		Redis:    RedisConfig{Port: 6379},
+		Payment: PaymentConfig{
+			LockTTL:             30 * time.Second,
+			SupportedCurrencies: []string{"USD", "EUR"},
+			FX:                  FXConfig{AllowedPairs: []string{"USD->EUR", "USD->JPY"}},
+		},
+		Worker: WorkerConfig{BatchSize: 10},
+	}
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "USD->JPY")
+	assert.NotContains(t, err.Error(), "USD->EUR")
+}
+
+func TestConfig_Validate_Webhook(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{
+			Port:         8080,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		},
+		Database: DatabaseConfig{Host: "localhost", Port: 5432},
+		Redis:    RedisConfig{Port: 6379},
+		Payment:  PaymentConfig{LockTTL: 30 * time.Second},
+		Worker:   WorkerConfig{BatchSize: 10},
+		Webhook: WebhookConfig{
+			URL:        "https://hooks.example.com/payments",
+			Secret:     "webhook-secret-0123456789abcdef0",
+			MaxRetries: 5,
+			RetryDelay: time.Second,
+			Timeout:    10 * time.Second,
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Webhook.URL = "hooks.example.com"
+	cfg.Webhook.Secret = "short"
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "webhook.url")
+	assert.Contains(t, err.Error(), "webhook.secret")
+
+	cfg.Webhook = WebhookConfig{Secret: "short"}
+	assert.NoError(t, cfg.Validate(), "an empty URL disables delivery")
+}
+
+func TestConfig_Validate_ReclaimNeedsMinIdle(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{
+			Port:         8080,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		},
+		Database: DatabaseConfig{Host: "localhost", Port: 5432},
+		Redis:    RedisConfig{Port: 6379},
+		Payment:  PaymentConfig{LockTTL: 30 * time.Second},
+		Worker:   WorkerConfig{BatchSize: 10, ReclaimInterval: 30 * time.Second},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "worker.reclaim_min_idle")
+
+	cfg.Worker.ReclaimInterval = 0
+	assert.NoError(t, cfg.Validate(), "disabled reclaim needs no min idle")
+}
@@ -0,0 +1,47 @@
+package observability
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+type Alert struct {
+	Name     string
+	Severity Severity
+	Message  string
+	Labels   map[string]string
+}
+
+// Alerter delivers operational alerts. Implementations can page, post to chat,
+// etc.; LogAlerter is the default.
+type Alerter interface {
+	Alert(ctx context.Context, alert Alert) error
+}
+
+// LogAlerter writes alerts as structured error-level log lines.
+type LogAlerter struct {
+	logger zerolog.Logger
+}
+
+func NewLogAlerter(logger zerolog.Logger) *LogAlerter {
+	return &LogAlerter{logger: logger}
+}
+
+func (a *LogAlerter) Alert(ctx context.Context, alert Alert) error {
+	ev := a.logger.Error().
+		Str("alert", alert.Name).
+		Str("severity", string(alert.Severity))
+	for k, v := range alert.Labels {
+		ev = ev.Str(k, v)
+	}
+	ev.Msg(alert.Message)
+	return nil
+}
@@ -0,0 +1,45 @@
+package observability
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// InFlight counts work items currently being processed and mirrors the count
+// into a gauge. It is safe for concurrent use.
+type InFlight struct {
+	count atomic.Int64
+	gauge prometheus.Gauge
+}
+
+func NewInFlight(gauge prometheus.Gauge) *InFlight {
+	return &InFlight{gauge: gauge}
+}
+
+// Start records one item entering processing. The returned func records it
+// leaving; calling it more than once has no further effect.
+func (f *InFlight) Start() func() {
+	f.count.Add(1)
+	f.gauge.Inc()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.count.Add(-1)
+			f.gauge.Dec()
+		})
+	}
+}
+
+// Count returns the number of items currently in flight.
+func (f *InFlight) Count() int64 {
+	return f.count.Load()
+}
+
+// Reset zeroes the count and gauge so no stale in-flight value outlives the
+// processor. Call it only once processing has stopped.
+func (f *InFlight) Reset() {
+	f.count.Store(0)
+	f.gauge.Set(0)
+}
@@ -0,0 +1,64 @@
+package observability
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gaugeValue(t *testing.T, g prometheus.Gauge) float64 {
+	t.Helper()
+	var m dto.Metric
+	require.NoError(t, g.Write(&m))
+	return m.GetGauge().GetValue()
+}
+
+func TestInFlight_ConcurrentStartAndDone(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_active"})
+	inflight := NewInFlight(gauge)
+
+	const workers = 50
+	dones := make(chan func(), workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dones <- inflight.Start()
+		}()
+	}
+	wg.Wait()
+	close(dones)
+
+	assert.Equal(t, int64(workers), inflight.Count())
+	assert.Equal(t, float64(workers), gaugeValue(t, gauge))
+
+	for done := range dones {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done()
+			done() // repeated calls are no-ops
+		}()
+	}
+	wg.Wait()
+
+	assert.Zero(t, inflight.Count())
+	assert.Zero(t, gaugeValue(t, gauge))
+}
+
+func TestInFlight_Reset(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_active"})
+	inflight := NewInFlight(gauge)
+	inflight.Start()
+	inflight.Start()
+
+	inflight.Reset()
+
+	assert.Zero(t, inflight.Count())
+	assert.Zero(t, gaugeValue(t, gauge))
+}
@@ -24,9 +24,34 @@ type Metrics struct {
 	CircuitBreakerState    *prometheus.GaugeVec
 	CircuitBreakerRequests *prometheus.CounterVec
 
+	// Provider latency metrics
+	ProviderLatency     *prometheus.HistogramVec
+	ProviderSlowMode    *prometheus.GaugeVec
+	ProviderSLOBreaches *prometheus.CounterVec
+	ProviderThrottled   *prometheus.CounterVec
+
 	// Worker metrics
 	WorkerMessagesProcessed  *prometheus.CounterVec
 	WorkerProcessingDuration *prometheus.HistogramVec
+
+	// Idempotency metrics
+	IdempotencyReplays        *prometheus.CounterVec
+	IdempotencyReuseConflicts *prometheus.CounterVec
+
+	// Dead-letter queue metrics
+	DLQDepth   prometheus.Gauge
+	DLQEntries prometheus.Counter
+
+	// Consumer group metrics
+	ConsumerGroupLag       *prometheus.GaugeVec
+	ConsumerGroupPending   *prometheus.GaugeVec
+	ConsumerGroupRecreated *prometheus.CounterVec
+
+	// Reconciliation metrics
+	ReconciliationMismatches *prometheus.CounterVec
+
+	// Audit metrics
+	AuditEntriesDropped prometheus.Counter
 }
 
 // If reg is nil, prometheus.DefaultRegisterer is used.
@@ -126,6 +151,39 @@ func NewMetrics(namespace string, reg prometheus.Registerer) *Metrics {
 			},
 			[]string{"name", "result"},
 		),
+		ProviderLatency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "provider_request_duration_seconds",
+				Help:      "Provider call duration in seconds",
+				Buckets:   []float64{.05, .1, .25, .5, 1, 2, 5, 10, 30},
+			},
+			[]string{"provider"},
+		),
+		ProviderSlowMode: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "provider_slow_mode",
+				Help:      "1 while a provider is in slow mode after breaching its latency SLO",
+			},
+			[]string{"provider"},
+		),
+		ProviderSLOBreaches: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "provider_slo_breaches_total",
+				Help:      "Total number of times a provider entered slow mode",
+			},
+			[]string{"provider"},
+		),
+		ProviderThrottled: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "provider_throttled_total",
+				Help:      "Total number of provider requests delayed by the provider's rate limit",
+			},
+			[]string{"provider"},
+		),
 		WorkerMessagesProcessed: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: namespace,
@@ -143,6 +201,75 @@ func NewMetrics(namespace string, reg prometheus.Registerer) *Metrics {
 			},
 			[]string{"stream"},
 		),
+		IdempotencyReplays: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "idempotency_replays_total",
+				Help:      "Total number of create requests answered by replaying an existing payment",
+			},
+			[]string{"type"},
+		),
+		IdempotencyReuseConflicts: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "idempotency_reuse_conflicts_total",
+				Help:      "Total number of idempotency keys reused with a different request",
+			},
+			[]string{"type"},
+		),
+		DLQDepth: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "dlq_depth",
+				Help:      "Number of entries currently in the dead-letter queue",
+			},
+		),
+		DLQEntries: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "dlq_entries_total",
+				Help:      "Total number of entries observed arriving in the dead-letter queue",
+			},
+		),
+		ConsumerGroupLag: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "consumer_group_lag",
+				Help:      "Number of stream entries not yet delivered to the consumer group",
+			},
+			[]string{"stream", "group"},
+		),
+		ConsumerGroupPending: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "consumer_group_pending",
+				Help:      "Number of delivered but unacknowledged entries in the consumer group",
+			},
+			[]string{"stream", "group"},
+		),
+		ConsumerGroupRecreated: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "consumer_group_recreated_total",
+				Help:      "Total number of times a missing consumer group was recreated",
+			},
+			[]string{"stream", "group"},
+		),
+		ReconciliationMismatches: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "reconciliation_mismatches_total",
+				Help:      "Total number of processing payments whose provider status disagreed, by resolution",
+			},
+			[]string{"outcome"},
+		),
+		AuditEntriesDropped: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "audit_entries_dropped_total",
+				Help:      "Total number of audit log entries dropped because the write buffer was full",
+			},
+		),
 	}
 
 	// Register all collectors
@@ -158,8 +285,21 @@ func NewMetrics(namespace string, reg prometheus.Registerer) *Metrics {
 		m.HTTPRequestDuration,
 		m.CircuitBreakerState,
 		m.CircuitBreakerRequests,
+		m.ProviderLatency,
+		m.ProviderSlowMode,
+		m.ProviderSLOBreaches,
+		m.ProviderThrottled,
 		m.WorkerMessagesProcessed,
 		m.WorkerProcessingDuration,
+		m.IdempotencyReplays,
+		m.IdempotencyReuseConflicts,
+		m.DLQDepth,
+		m.DLQEntries,
+		m.ConsumerGroupLag,
+		m.ConsumerGroupPending,
+		m.ConsumerGroupRecreated,
+		m.ReconciliationMismatches,
+		m.AuditEntriesDropped,
 	)
 
 	return m
@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BreakerResetChannel carries "reset provider X's circuit breaker" signals
+// from the API to workers, each of which holds its own breakers.
+const BreakerResetChannel = "providers:breaker-reset"
+
+type BreakerResetPublisher struct {
+	client *redis.Client
+}
+
+func NewBreakerResetPublisher(client *redis.Client) *BreakerResetPublisher {
+	return &BreakerResetPublisher{client: client}
+}
+
+// NotifyReset broadcasts a breaker reset for provider to every subscribed
+// process. Delivery is fire-and-forget.
+func (p *BreakerResetPublisher) NotifyReset(ctx context.Context, provider string) error {
+	if err := p.client.Publish(ctx, BreakerResetChannel, provider).Err(); err != nil {
+		return fmt.Errorf("failed to publish breaker reset: %w", err)
+	}
+	return nil
+}
+
+// ListenBreakerResets subscribes to BreakerResetChannel and calls reset with
+// each provider named until ctx is done.
+func ListenBreakerResets(ctx context.Context, client *redis.Client, reset func(provider string)) error {
+	sub := client.Subscribe(ctx, BreakerResetChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			reset(msg.Payload)
+		}
+	}
+}
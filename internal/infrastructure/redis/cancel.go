@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// PaymentCancelChannel carries "cancel payment X" signals from the API to workers.
+const PaymentCancelChannel = "payments:cancel"
+
+type CancelPublisher struct {
+	client *redis.Client
+}
+
+func NewCancelPublisher(client *redis.Client) *CancelPublisher {
+	return &CancelPublisher{client: client}
+}
+
+// NotifyCancel broadcasts a cancellation request for a payment. Delivery is
+// fire-and-forget: if no worker is processing the payment the message is dropped.
+func (p *CancelPublisher) NotifyCancel(ctx context.Context, paymentID uuid.UUID) error {
+	if err := p.client.Publish(ctx, PaymentCancelChannel, paymentID.String()).Err(); err != nil {
+		return fmt.Errorf("failed to publish cancel signal: %w", err)
+	}
+	return nil
+}
+
+// CancelRegistry tracks the processing contexts of payments owned by this
+// worker so a cancel signal can abort them.
+type CancelRegistry struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelCauseFunc
+}
+
+func NewCancelRegistry() *CancelRegistry {
+	return &CancelRegistry{cancels: make(map[string]context.CancelCauseFunc)}
+}
+
+// Track returns a context that is cancelled with ErrPaymentCancelled when a
+// cancel signal arrives for paymentID. The returned func must be called once
+// processing finishes.
+func (r *CancelRegistry) Track(ctx context.Context, paymentID string) (context.Context, func()) {
+	procCtx, cancel := context.WithCancelCause(ctx)
+
+	r.mu.Lock()
+	r.cancels[paymentID] = cancel
+	r.mu.Unlock()
+
+	return procCtx, func() {
+		r.mu.Lock()
+		delete(r.cancels, paymentID)
+		r.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Cancel aborts the tracked processing of paymentID, reporting whether it was
+// in flight on this worker.
+func (r *CancelRegistry) Cancel(paymentID string) bool {
+	r.mu.Lock()
+	cancel, ok := r.cancels[paymentID]
+	r.mu.Unlock()
+
+	if ok {
+		cancel(domainErrors.ErrPaymentCancelled)
+	}
+	return ok
+}
+
+// Listen subscribes to PaymentCancelChannel and cancels matching tracked
+// payments until ctx is done.
+func (r *CancelRegistry) Listen(ctx context.Context, client *redis.Client) error {
+	sub := client.Subscribe(ctx, PaymentCancelChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			r.Cancel(msg.Payload)
+		}
+	}
+}
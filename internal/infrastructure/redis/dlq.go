@@ -0,0 +1,147 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// dlqScanBatch bounds each XRANGE call when counting new DLQ entries.
+const dlqScanBatch = 500
+
+// DLQReader inspects the dead-letter stream without consuming it.
+type DLQReader struct {
+	client *redis.Client
+	stream string
+	lastID string
+}
+
+func NewDLQReader(client *redis.Client) *DLQReader {
+	return &DLQReader{client: client, stream: DLQStream}
+}
+
+// Depth returns the number of entries currently in the DLQ.
+func (r *DLQReader) Depth(ctx context.Context) (int64, error) {
+	n, err := r.client.XLen(ctx, r.stream).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read DLQ depth: %w", err)
+	}
+	return n, nil
+}
+
+// NewEntries returns how many entries were added since the previous call.
+// The first call only records the current tail so existing entries are not
+// counted as new.
+func (r *DLQReader) NewEntries(ctx context.Context) (int64, error) {
+	if r.lastID == "" {
+		last, err := r.client.XRevRangeN(ctx, r.stream, "+", "-", 1).Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read DLQ tail: %w", err)
+		}
+		r.lastID = "0"
+		if len(last) > 0 {
+			r.lastID = last[0].ID
+		}
+		return 0, nil
+	}
+
+	var count int64
+	for {
+		msgs, err := r.client.XRangeN(ctx, r.stream, "("+r.lastID, "+", dlqScanBatch).Result()
+		if err != nil {
+			return count, fmt.Errorf("failed to scan DLQ: %w", err)
+		}
+		count += int64(len(msgs))
+		if len(msgs) > 0 {
+			r.lastID = msgs[len(msgs)-1].ID
+		}
+		if len(msgs) < dlqScanBatch {
+			return count, nil
+		}
+	}
+}
+
+// DLQEntry is a message moved to the dead-letter stream. Payment entries
+// carry PaymentID and the values of the original PaymentStream message;
+// webhook entries carry WebhookID and the undelivered payload; outbox entries
+// that could not be published carry OutboxID and the event payload.
+type DLQEntry struct {
+	ID        string
+	PaymentID string
+	WebhookID string
+	OutboxID  string
+	Reason    string
+	Values    map[string]any
+	FailedAt  time.Time
+}
+
+// DeadLetters lists DLQ entries and moves payment entries back onto the
+// payment stream.
+type DeadLetters struct {
+	client *redis.Client
+}
+
+func NewDeadLetters(client *redis.Client) *DeadLetters {
+	return &DeadLetters{client: client}
+}
+
+// List returns up to count entries, oldest first.
+func (d *DeadLetters) List(ctx context.Context, count int64) ([]DLQEntry, error) {
+	msgs, err := d.client.XRangeN(ctx, DLQStream, "-", "+", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DLQ: %w", err)
+	}
+	entries := make([]DLQEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		entries = append(entries, dlqEntry(msg))
+	}
+	return entries, nil
+}
+
+// Get returns the entry with id, or nil if there is none.
+func (d *DeadLetters) Get(ctx context.Context, id string) (*DLQEntry, error) {
+	msgs, err := d.client.XRange(ctx, DLQStream, id, id).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DLQ entry: %w", err)
+	}
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+	entry := dlqEntry(msgs[0])
+	return &entry, nil
+}
+
+// Requeue re-publishes a payment entry's original message on PaymentStream
+// and removes it from the DLQ in one transaction.
+func (d *DeadLetters) Requeue(ctx context.Context, entry DLQEntry) error {
+	_, err := d.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: PaymentStream, Values: entry.Values})
+		pipe.XDel(ctx, DLQStream, entry.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to requeue DLQ entry: %w", err)
+	}
+	return nil
+}
+
+func dlqEntry(msg redis.XMessage) DLQEntry {
+	e := DLQEntry{ID: msg.ID}
+	e.PaymentID, _ = msg.Values["payment_id"].(string)
+	e.WebhookID, _ = msg.Values["webhook_id"].(string)
+	e.OutboxID, _ = msg.Values["outbox_id"].(string)
+	e.Reason, _ = msg.Values["reason"].(string)
+	if payload, ok := msg.Values["payload"].(string); ok {
+		json.Unmarshal([]byte(payload), &e.Values)
+	}
+	if ts, ok := msg.Values["timestamp"].(string); ok {
+		if unix, err := strconv.ParseInt(ts, 10, 64); err == nil {
+			e.FailedAt = time.Unix(unix, 0).UTC()
+		}
+	}
+	return e
+}
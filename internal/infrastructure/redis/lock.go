@@ -6,11 +6,25 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
+// fenceKey is the counter fencing tokens are drawn from. It is shared by all
+// locks, so tokens only ever grow, across keys and across expiries.
+const fenceKey = "lock:fence"
+
 var (
+	// Lua script for lock acquisition: takes the lock if it is free and
+	// stores a new fencing token as its value.
+	acquireLockScript = redis.NewScript(`
+		if redis.call("exists", KEYS[1]) == 1 then
+			return 0
+		end
+		local token = redis.call("incr", KEYS[2])
+		redis.call("set", KEYS[1], token, "PX", ARGV[1])
+		return token
+	`)
+
 	// Lua script for safe lock release (only owner can release)
 	releaseLockScript = redis.NewScript(`
 		if redis.call("get", KEYS[1]) == ARGV[1] then
@@ -30,61 +44,63 @@ var (
 	`)
 )
 
+// DistributedLock is a Redis lock whose every acquisition gets a fencing
+// token: a number greater than that of any earlier acquisition. The token is
+// the lock's value, so only the holder of the current token can extend or
+// release it, and writes made under the lock can carry it for the store to
+// reject those from a holder whose lock has since expired.
 type DistributedLock struct {
-	client  *redis.Client
-	key     string
-	value   string
-	ttl     time.Duration
-	acquired bool
+	client *redis.Client
+	key    string
+	ttl    time.Duration
+	token  int64 // 0 while not held
 }
 
 func NewDistributedLock(client *redis.Client, key string, ttl time.Duration) *DistributedLock {
 	return &DistributedLock{
-		client:   client,
-		key:      fmt.Sprintf("lock:%s", key),
-		value:    uuid.New().String(),
-		ttl:      ttl,
-		acquired: false,
+		client: client,
+		key:    lockKey(key),
+		ttl:    ttl,
 	}
 }
 
-func (l *DistributedLock) Acquire(ctx context.Context) (bool, error) {
-	// Use SET NX EX to atomically set the lock if it doesn't exist
-	success, err := l.client.SetNX(ctx, l.key, l.value, l.ttl).Result()
+// Acquire takes the lock if it is free and returns its fencing token.
+func (l *DistributedLock) Acquire(ctx context.Context) (token int64, acquired bool, err error) {
+	token, err = acquireLockScript.Run(ctx, l.client, []string{l.key, fenceKey}, l.ttl.Milliseconds()).Int64()
 	if err != nil {
-		return false, fmt.Errorf("failed to acquire lock: %w", err)
+		return 0, false, fmt.Errorf("failed to acquire lock: %w", err)
 	}
 
-	l.acquired = success
-	return success, nil
+	l.token = token
+	return token, token != 0, nil
 }
 
 // AcquireWithRetry attempts to acquire the lock with retries
-func (l *DistributedLock) AcquireWithRetry(ctx context.Context, maxRetries int, retryDelay time.Duration) error {
+func (l *DistributedLock) AcquireWithRetry(ctx context.Context, maxRetries int, retryDelay time.Duration) (int64, error) {
 	for i := 0; i < maxRetries; i++ {
-		acquired, err := l.Acquire(ctx)
+		token, acquired, err := l.Acquire(ctx)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		if acquired {
-			return nil
+			return token, nil
 		}
 
 		// Wait before retrying
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return 0, ctx.Err()
 		case <-time.After(retryDelay):
 			continue
 		}
 	}
 
-	return errors.New("failed to acquire lock after retries")
+	return 0, errors.New("failed to acquire lock after retries")
 }
 
 // Extend extends the lock TTL
 func (l *DistributedLock) Extend(ctx context.Context, additionalTTL time.Duration) error {
-	if !l.acquired {
+	if l.token == 0 {
 		return errors.New("lock not acquired")
 	}
 
@@ -92,7 +108,7 @@ func (l *DistributedLock) Extend(ctx context.Context, additionalTTL time.Duratio
 		ctx,
 		l.client,
 		[]string{l.key},
-		l.value,
+		l.token,
 		additionalTTL.Milliseconds(),
 	).Result()
 	if err != nil {
@@ -107,9 +123,39 @@ func (l *DistributedLock) Extend(ctx context.Context, additionalTTL time.Duratio
 	return nil
 }
 
-// Release releases the lock
-func (l *DistributedLock) Release(ctx context.Context) error {
-	if !l.acquired {
+// KeepAlive renews the lock every third of its TTL until stop is called, so
+// it outlives work that runs longer than the TTL. stop returns the error that
+// ended renewal early, e.g. when the lock expired before it could be renewed.
+func (l *DistributedLock) KeepAlive(ctx context.Context) (stop func() error) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				done <- nil
+				return
+			case <-ticker.C:
+				if err := l.Extend(ctx, l.ttl); err != nil && ctx.Err() == nil {
+					done <- err
+					return
+				}
+			}
+		}
+	}()
+	return func() error {
+		cancel()
+		return <-done
+	}
+}
+
+// Release releases the lock if it is still held under token. A holder whose
+// lock expired and was taken by someone else gets an error and leaves the
+// new holder's lock in place.
+func (l *DistributedLock) Release(ctx context.Context, token int64) error {
+	if token == 0 {
 		return nil
 	}
 
@@ -117,7 +163,7 @@ func (l *DistributedLock) Release(ctx context.Context) error {
 		ctx,
 		l.client,
 		[]string{l.key},
-		l.value,
+		token,
 	).Result()
 	if err != nil {
 		return fmt.Errorf("failed to release lock: %w", err)
@@ -128,10 +174,65 @@ func (l *DistributedLock) Release(ctx context.Context) error {
 		return errors.New("lock not held or already released")
 	}
 
-	l.acquired = false
+	if token == l.token {
+		l.token = 0
+	}
 	return nil
 }
 
 func (l *DistributedLock) IsAcquired() bool {
-	return l.acquired
+	return l.token != 0
+}
+
+// Token returns the fencing token of the current acquisition, or 0 if the
+// lock is not held.
+func (l *DistributedLock) Token() int64 {
+	return l.token
+}
+
+func lockKey(key string) string {
+	return fmt.Sprintf("lock:%s", key)
+}
+
+// Locker takes DistributedLocks for callers that hold one only briefly, such
+// as the API cancelling a payment a worker may be about to process.
+type Locker struct {
+	client *redis.Client
+}
+
+func NewLocker(client *redis.Client) *Locker {
+	return &Locker{client: client}
+}
+
+// TryLock takes the lock for key once, without retrying. unlock releases it
+// even after ctx is done.
+func (l *Locker) TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), acquired bool, err error) {
+	lock := NewDistributedLock(l.client, key, ttl)
+	token, acquired, err := lock.Acquire(ctx)
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+	return func() {
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+		lock.Release(releaseCtx, token)
+	}, true, nil
+}
+
+// LockInspector reports whether a DistributedLock is held without taking it.
+type LockInspector struct {
+	client *redis.Client
+}
+
+func NewLockInspector(client *redis.Client) *LockInspector {
+	return &LockInspector{client: client}
+}
+
+// IsLocked reports whether any owner currently holds the lock for key.
+func (i *LockInspector) IsLocked(ctx context.Context, key string) (bool, error) {
+	n, err := i.client.Exists(ctx, lockKey(key)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check lock: %w", err)
+	}
+	return n > 0, nil
 }
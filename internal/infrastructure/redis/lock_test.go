@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClient connects to the Redis at PAYMENTS_TEST_REDIS_ADDR, skipping the
+// test when it is not set.
+func testClient(t *testing.T) *redis.Client {
+	t.Helper()
+	addr := os.Getenv("PAYMENTS_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("PAYMENTS_TEST_REDIS_ADDR not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+	require.NoError(t, client.Ping(context.Background()).Err())
+	return client
+}
+
+func TestDistributedLock_TokensIncrease(t *testing.T) {
+	client := testClient(t)
+	ctx := context.Background()
+	key := "test:" + uuid.NewString()
+
+	first := NewDistributedLock(client, key, time.Minute)
+	token1, acquired, err := first.Acquire(ctx)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	_, acquired, err = NewDistributedLock(client, key, time.Minute).Acquire(ctx)
+	require.NoError(t, err)
+	assert.False(t, acquired, "a held lock is not granted twice")
+
+	require.NoError(t, first.Release(ctx, token1))
+	token2, acquired, err := first.Acquire(ctx)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	assert.Greater(t, token2, token1)
+	assert.Equal(t, token2, first.Token())
+	require.NoError(t, first.Release(ctx, token2))
+}
+
+func TestDistributedLock_StaleHolderCannotRelease(t *testing.T) {
+	client := testClient(t)
+	ctx := context.Background()
+	key := "test:" + uuid.NewString()
+
+	stale := NewDistributedLock(client, key, 50*time.Millisecond)
+	staleToken, acquired, err := stale.Acquire(ctx)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	time.Sleep(100 * time.Millisecond) // the lock expires under its holder
+
+	current := NewDistributedLock(client, key, time.Minute)
+	token, acquired, err := current.Acquire(ctx)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	assert.Greater(t, token, staleToken)
+
+	assert.Error(t, stale.Release(ctx, staleToken), "the stale holder no longer owns the lock")
+	assert.Error(t, stale.Extend(ctx, time.Minute))
+	held, err := NewLockInspector(client).IsLocked(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, held, "the current holder keeps the lock")
+
+	require.NoError(t, current.Release(ctx, token))
+	held, err = NewLockInspector(client).IsLocked(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, held)
+}
+
+func TestLocker_TryLockExcludesWorker(t *testing.T) {
+	client := testClient(t)
+	ctx := context.Background()
+	key := "test:" + uuid.NewString()
+
+	unlock, acquired, err := NewLocker(client).TryLock(ctx, key, time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	_, acquired, err = NewDistributedLock(client, key, time.Minute).Acquire(ctx)
+	require.NoError(t, err)
+	assert.False(t, acquired, "a worker cannot take a lock the locker holds")
+	_, acquired, err = NewLocker(client).TryLock(ctx, key, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	unlock()
+	held, err := NewLockInspector(client).IsLocked(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, held)
+}
@@ -3,10 +3,12 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/cassiomorais/payments/internal/domain/outbox"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -16,6 +18,9 @@ const (
 	DLQStream     = "payments:dlq"
 )
 
+// ErrGroupNotFound is returned when the consumer group (or its stream) does not exist.
+var ErrGroupNotFound = errors.New("consumer group not found")
+
 type StreamProducer struct {
 	client *redis.Client
 }
@@ -24,20 +29,35 @@ func NewStreamProducer(client *redis.Client) *StreamProducer {
 	return &StreamProducer{client: client}
 }
 
+// schemaVersion returns the version stamped on data, defaulting to the
+// current outbox.SchemaVersion for payloads that predate stamping. It is
+// copied onto the stream message so consumers can route before decoding.
+func schemaVersion(data map[string]any) any {
+	if v, ok := data[outbox.SchemaVersionKey]; ok {
+		return v
+	}
+	return outbox.SchemaVersion
+}
+
 func (p *StreamProducer) PublishPaymentEvent(ctx context.Context, paymentID string, eventType string, data map[string]any) error {
 	payload, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event data: %w", err)
 	}
 
+	values := map[string]any{
+		"payment_id":     paymentID,
+		"event_type":     eventType,
+		"schema_version": schemaVersion(data),
+		"payload":        string(payload),
+		"timestamp":      time.Now().Unix(),
+	}
+	if key, ok := data[outbox.DedupKeyKey]; ok {
+		values[outbox.DedupKeyKey] = key
+	}
 	args := &redis.XAddArgs{
 		Stream: PaymentStream,
-		Values: map[string]any{
-			"payment_id": paymentID,
-			"event_type": eventType,
-			"payload":    string(payload),
-			"timestamp":  time.Now().Unix(),
-		},
+		Values: values,
 	}
 
 	_, err = p.client.XAdd(ctx, args).Result()
@@ -57,9 +77,10 @@ func (p *StreamProducer) PublishWebhookEvent(ctx context.Context, webhookID stri
 	args := &redis.XAddArgs{
 		Stream: WebhookStream,
 		Values: map[string]any{
-			"webhook_id": webhookID,
-			"payload":    string(payload),
-			"timestamp":  time.Now().Unix(),
+			"webhook_id":     webhookID,
+			"schema_version": schemaVersion(data),
+			"payload":        string(payload),
+			"timestamp":      time.Now().Unix(),
 		},
 	}
 
@@ -95,6 +116,54 @@ func (p *StreamProducer) PublishToDLQ(ctx context.Context, paymentID string, rea
 	return nil
 }
 
+// PublishOutboxToDLQ copies an outbox entry that ran out of publish attempts
+// to the DLQ, so operators see it next to dead-lettered messages.
+func (p *StreamProducer) PublishOutboxToDLQ(ctx context.Context, entry *outbox.Entry, reason string) error {
+	payload, err := json.Marshal(entry.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal DLQ data: %w", err)
+	}
+
+	args := &redis.XAddArgs{
+		Stream: DLQStream,
+		Values: map[string]any{
+			"outbox_id":    entry.ID.String(),
+			"aggregate_id": entry.AggregateID.String(),
+			"event_type":   entry.EventType,
+			"reason":       reason,
+			"payload":      string(payload),
+			"timestamp":    time.Now().Unix(),
+		},
+	}
+
+	_, err = p.client.XAdd(ctx, args).Result()
+	if err != nil {
+		return fmt.Errorf("failed to publish to DLQ: %w", err)
+	}
+
+	return nil
+}
+
+// PublishWebhookToDLQ moves an undeliverable webhook stream message to the DLQ.
+func (p *StreamProducer) PublishWebhookToDLQ(ctx context.Context, webhookID string, reason string, payload string) error {
+	args := &redis.XAddArgs{
+		Stream: DLQStream,
+		Values: map[string]any{
+			"webhook_id": webhookID,
+			"reason":     reason,
+			"payload":    payload,
+			"timestamp":  time.Now().Unix(),
+		},
+	}
+
+	_, err := p.client.XAdd(ctx, args).Result()
+	if err != nil {
+		return fmt.Errorf("failed to publish to DLQ: %w", err)
+	}
+
+	return nil
+}
+
 type StreamConsumer struct {
 	client        *redis.Client
 	stream        string
@@ -102,6 +171,10 @@ type StreamConsumer struct {
 	consumer      string
 	batchSize     int64
 	blockDuration time.Duration
+
+	// lastDeliveredID is the group position seen on the last successful
+	// GroupInfo call, used to resume the group if it has to be recreated.
+	lastDeliveredID string
 }
 
 func NewStreamConsumer(
@@ -122,16 +195,124 @@ func NewStreamConsumer(
 	}
 }
 
-func (c *StreamConsumer) CreateGroup(ctx context.Context) error {
-	// Create stream if it doesn't exist
-	const busyGroupMsg = "BUSYGROUP"
-	err := c.client.XGroupCreateMkStream(ctx, c.stream, c.group, "0").Err()
-	if err != nil && !strings.Contains(err.Error(), busyGroupMsg) {
-		return fmt.Errorf("failed to create consumer group: %w", err)
+// Spec returns the stream and group this consumer reads, for EnsureStreams.
+func (c *StreamConsumer) Spec() StreamSpec {
+	return StreamSpec{Stream: c.stream, Group: c.group}
+}
+
+// StreamSpec names a stream that must exist and, if it is read through a
+// consumer group, that group. An empty Group asks for the stream alone.
+type StreamSpec struct {
+	Stream string
+	Group  string
+}
+
+// EnsureStreams creates every stream and consumer group in specs that does
+// not exist yet, then checks that each is in place. It is safe to call on
+// every startup: existing streams and groups are left untouched. Any error
+// other than an already-existing group is returned, so a caller can refuse
+// to start rather than read from a stream that is not there.
+func EnsureStreams(ctx context.Context, client *redis.Client, specs ...StreamSpec) error {
+	for _, spec := range specs {
+		if err := ensureStream(ctx, client, spec); err != nil {
+			if spec.Group == "" {
+				return fmt.Errorf("ensure stream %s: %w", spec.Stream, err)
+			}
+			return fmt.Errorf("ensure stream %s group %s: %w", spec.Stream, spec.Group, err)
+		}
 	}
 	return nil
 }
 
+func ensureStream(ctx context.Context, client *redis.Client, spec StreamSpec) error {
+	if spec.Group != "" {
+		if _, err := createGroup(ctx, client, spec.Stream, spec.Group, "0"); err != nil {
+			return err
+		}
+		groups, err := client.XInfoGroups(ctx, spec.Stream).Result()
+		if err != nil {
+			return err
+		}
+		for _, g := range groups {
+			if g.Name == spec.Group {
+				return nil
+			}
+		}
+		return ErrGroupNotFound
+	}
+
+	n, err := client.Exists(ctx, spec.Stream).Result()
+	if err != nil || n > 0 {
+		return err
+	}
+	// Redis has no command to create an empty stream on its own; creating
+	// and dropping a throwaway group leaves one behind.
+	const tmpGroup = "ensure-stream"
+	if _, err := createGroup(ctx, client, spec.Stream, tmpGroup, "$"); err != nil {
+		return err
+	}
+	return client.XGroupDestroy(ctx, spec.Stream, tmpGroup).Err()
+}
+
+// createGroup creates group on stream, creating the stream too if needed.
+// It reports false without an error when the group already exists.
+func createGroup(ctx context.Context, client *redis.Client, stream, group, start string) (bool, error) {
+	err := client.XGroupCreateMkStream(ctx, stream, group, start).Err()
+	if isBusyGroup(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// isBusyGroup reports whether err is Redis refusing to create a consumer
+// group that already exists.
+func isBusyGroup(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP")
+}
+
+// GroupInfo returns the XINFO GROUPS entry for the consumer group, or
+// ErrGroupNotFound if the group or the stream is missing.
+func (c *StreamConsumer) GroupInfo(ctx context.Context) (*redis.XInfoGroup, error) {
+	groups, err := c.client.XInfoGroups(ctx, c.stream).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return nil, ErrGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to get consumer group info: %w", err)
+	}
+
+	for i := range groups {
+		if groups[i].Name == c.group {
+			c.lastDeliveredID = groups[i].LastDeliveredID
+			return &groups[i], nil
+		}
+	}
+	return nil, ErrGroupNotFound
+}
+
+// EnsureGroup recreates the consumer group if it no longer exists. The group
+// resumes from the last delivered ID seen by GroupInfo when known, otherwise
+// from the start of the stream so no messages are skipped.
+func (c *StreamConsumer) EnsureGroup(ctx context.Context) (recreated bool, err error) {
+	_, err = c.GroupInfo(ctx)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, ErrGroupNotFound) {
+		return false, err
+	}
+
+	start := c.lastDeliveredID
+	if start == "" {
+		start = "0"
+	}
+	created, err := createGroup(ctx, c.client, c.stream, c.group, start)
+	if err != nil {
+		return false, fmt.Errorf("failed to recreate consumer group: %w", err)
+	}
+	return created, nil
+}
+
 func (c *StreamConsumer) Read(ctx context.Context) ([]redis.XStream, error) {
 	streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
 		Group:    c.group,
@@ -160,18 +341,24 @@ func (c *StreamConsumer) Ack(ctx context.Context, messageID string) error {
 	return nil
 }
 
-func (c *StreamConsumer) Claim(ctx context.Context, minIdleTime time.Duration, messageIDs []string) ([]redis.XMessage, error) {
-	messages, err := c.client.XClaim(ctx, &redis.XClaimArgs{
+// AutoClaim claims up to the consumer's batch size of messages that have
+// been pending longer than minIdle, scanning the group's pending list from
+// start ("0-0" for the beginning). XAUTOCLAIM scans and claims atomically,
+// so concurrent reclaimers never receive the same message. It returns the
+// cursor to continue from, which is "0-0" once the scan is complete.
+func (c *StreamConsumer) AutoClaim(ctx context.Context, minIdle time.Duration, start string) ([]redis.XMessage, string, error) {
+	messages, next, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
 		Stream:   c.stream,
 		Group:    c.group,
 		Consumer: c.consumer,
-		MinIdle:  minIdleTime,
-		Messages: messageIDs,
+		MinIdle:  minIdle,
+		Start:    start,
+		Count:    c.batchSize,
 	}).Result()
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to claim messages: %w", err)
+		return nil, "", fmt.Errorf("failed to autoclaim messages: %w", err)
 	}
 
-	return messages, nil
+	return messages, next, nil
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,10 +13,16 @@ import (
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey contextKey = "user_id"
+	ScopesKey contextKey = "scopes"
+	RolesKey  contextKey = "roles"
+)
 
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID string   `json:"user_id"`
+	Scopes []string `json:"scopes,omitempty"`
+	Roles  []string `json:"roles,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -49,6 +56,8 @@ func RequireAuth(jwtSecret string) func(http.Handler) http.Handler {
 			}
 
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, ScopesKey, claims.Scopes)
+			ctx = context.WithValue(ctx, RolesKey, claims.Roles)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -59,6 +68,51 @@ func GetUserID(ctx context.Context) (string, bool) {
 	return userID, ok
 }
 
+func GetScopes(ctx context.Context) []string {
+	scopes, _ := ctx.Value(ScopesKey).([]string)
+	return scopes
+}
+
+// HasScope reports whether the authenticated token carries the given scope.
+func HasScope(ctx context.Context, scope string) bool {
+	for _, s := range GetScopes(ctx) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func GetRoles(ctx context.Context) []string {
+	roles, _ := ctx.Value(RolesKey).([]string)
+	return roles
+}
+
+// HasRole reports whether the authenticated token's roles claim includes
+// role.
+func HasRole(ctx context.Context, role string) bool {
+	return slices.Contains(GetRoles(ctx), role)
+}
+
+// RequireScope rejects requests whose token lacks scope with 403. It must run
+// after RequireAuth.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r.Context(), scope) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error": "insufficient scope",
+					"code":  "forbidden",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func writeAuthError(w http.ResponseWriter, msg, code string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusUnauthorized)
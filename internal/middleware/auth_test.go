@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireScope(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := RequireScope("payments:admin")(next)
+
+	tests := []struct {
+		name   string
+		scopes []string
+		want   int
+	}{
+		{"with scope", []string{"payments:read", "payments:admin"}, http.StatusOK},
+		{"without scope", []string{"payments:read"}, http.StatusForbidden},
+		{"no scopes", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/accounts", nil)
+			req = req.WithContext(context.WithValue(req.Context(), ScopesKey, tt.scopes))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestRequireAuth_StoresRoles(t *testing.T) {
+	const secret = "test-secret-0123456789abcdef01234"
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: "user1", Roles: []string{"admin"}}).
+		SignedString([]byte(secret))
+	assert.NoError(t, err)
+
+	var isAdmin, isSupport bool
+	handler := RequireAuth(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, isSupport = HasRole(r.Context(), "admin"), HasRole(r.Context(), "support")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.True(t, isAdmin)
+	assert.False(t, isSupport)
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// DefaultMaxBodyBytes caps request bodies on routes without MaxBodySize.
+const DefaultMaxBodyBytes int64 = 1 << 20 // 1MB
+
+const MaxBodyBytesKey contextKey = "max_body_bytes"
+
+// MaxBodySize sets the largest request body the handlers it wraps accept.
+// Applied again on a narrower set of routes, the later limit wins. A
+// non-positive n leaves the current limit in place.
+func MaxBodySize(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if n <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), MaxBodyBytesKey, n)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// MaxBodyBytes returns the request body limit MaxBodySize set, or
+// DefaultMaxBodyBytes.
+func MaxBodyBytes(ctx context.Context) int64 {
+	if n, ok := ctx.Value(MaxBodyBytesKey).(int64); ok {
+		return n
+	}
+	return DefaultMaxBodyBytes
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// EnvelopeMode is the shape of JSON response bodies.
+type EnvelopeMode string
+
+const (
+	// EnvelopeRaw sends the resource or error object as the whole body.
+	EnvelopeRaw EnvelopeMode = "raw"
+	// EnvelopeWrapped sends {"data": ..., "meta": ...} on success and
+	// {"data": null, "errors": [...], "meta": ...} on errors.
+	EnvelopeWrapped EnvelopeMode = "wrapped"
+)
+
+// EnvelopeParam is the Accept media type parameter a client uses to choose
+// the envelope, e.g. "Accept: application/json; envelope=wrapped".
+const EnvelopeParam = "envelope"
+
+type envelope struct {
+	Data   json.RawMessage   `json:"data"`
+	Errors []json.RawMessage `json:"errors,omitempty"`
+	Meta   envelopeMeta      `json:"meta"`
+}
+
+type envelopeMeta struct {
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ResponseEnvelope wraps JSON response bodies in an envelope when the
+// request asks for one through its Accept header, or when defaultMode is
+// EnvelopeWrapped and the request does not ask for raw bodies. Handlers keep
+// writing bare objects; non-JSON bodies such as CSV statements pass through
+// untouched.
+//
+// It must run outside middleware that records responses, such as
+// Idempotency, so a replayed response is shaped for the replaying request.
+func ResponseEnvelope(defaultMode EnvelopeMode) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			if negotiateEnvelope(r.Header.Values("Accept"), defaultMode) != EnvelopeWrapped {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ew := &envelopeWriter{ResponseWriter: w}
+			next.ServeHTTP(ew, r)
+			ew.finish(envelopeMeta{RequestID: chimw.GetReqID(r.Context())})
+		})
+	}
+}
+
+// negotiateEnvelope returns the mode named by the first envelope parameter
+// in the Accept header values, or defaultMode if none names a known mode.
+func negotiateEnvelope(accept []string, defaultMode EnvelopeMode) EnvelopeMode {
+	for _, v := range accept {
+		for _, mediaRange := range strings.Split(v, ",") {
+			_, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil {
+				continue
+			}
+			switch mode := EnvelopeMode(strings.ToLower(params[EnvelopeParam])); mode {
+			case EnvelopeRaw, EnvelopeWrapped:
+				return mode
+			}
+		}
+	}
+	return defaultMode
+}
+
+// envelopeWriter buffers a JSON response so finish can wrap it. Other
+// content types are written through as soon as the status is known.
+type envelopeWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	wrap        bool
+	body        bytes.Buffer
+}
+
+func (w *envelopeWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	w.wrap = isJSON(w.Header().Get("Content-Type")) && code != http.StatusNoContent && code != http.StatusNotModified
+	if !w.wrap {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *envelopeWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.wrap {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+// finish writes the buffered body inside the envelope. A body that is not
+// valid JSON is sent as it was written.
+func (w *envelopeWriter) finish(meta envelopeMeta) {
+	if !w.wrap {
+		return
+	}
+	raw := json.RawMessage(bytes.TrimSpace(w.body.Bytes()))
+	if len(raw) == 0 {
+		raw = json.RawMessage("null")
+	}
+	env := envelope{Data: raw, Meta: meta}
+	if w.status >= http.StatusBadRequest {
+		env.Data = json.RawMessage("null")
+		env.Errors = []json.RawMessage{raw}
+	}
+
+	out, err := json.Marshal(env)
+	if err != nil {
+		out = w.body.Bytes()
+	} else {
+		out = append(out, '\n')
+	}
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(out)
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
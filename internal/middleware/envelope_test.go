@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveEnvelope(defaultMode EnvelopeMode, accept string, h http.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/payments", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	ResponseEnvelope(defaultMode)(h).ServeHTTP(rec, req)
+	return rec
+}
+
+func jsonHandler(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body + "\n"))
+	}
+}
+
+func TestResponseEnvelope_RawByDefault(t *testing.T) {
+	rec := serveEnvelope(EnvelopeRaw, "application/json", jsonHandler(http.StatusOK, `[{"id":"1"}]`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id":"1"}]`, rec.Body.String())
+	assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+}
+
+func TestResponseEnvelope_WrappedOnRequest(t *testing.T) {
+	rec := serveEnvelope(EnvelopeRaw, "application/json; envelope=wrapped", jsonHandler(http.StatusCreated, `{"id":"1"}`))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"data":{"id":"1"},"meta":{}}`, rec.Body.String())
+
+	rec = serveEnvelope(EnvelopeRaw, "application/json; envelope=wrapped", jsonHandler(http.StatusNotFound, `{"error":"payment not found","code":"not_found"}`))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	var body struct {
+		Data   any              `json:"data"`
+		Errors []map[string]any `json:"errors"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Nil(t, body.Data)
+	require.Len(t, body.Errors, 1)
+	assert.Equal(t, "not_found", body.Errors[0]["code"])
+}
+
+func TestResponseEnvelope_WrappedByDefault(t *testing.T) {
+	rec := serveEnvelope(EnvelopeWrapped, "", jsonHandler(http.StatusOK, `{"id":"1"}`))
+	assert.JSONEq(t, `{"data":{"id":"1"},"meta":{}}`, rec.Body.String())
+
+	rec = serveEnvelope(EnvelopeWrapped, "application/json;envelope=raw", jsonHandler(http.StatusOK, `{"id":"1"}`))
+	assert.JSONEq(t, `{"id":"1"}`, rec.Body.String(), "a client can opt out per request")
+}
+
+func TestResponseEnvelope_NonJSONPassesThrough(t *testing.T) {
+	rec := serveEnvelope(EnvelopeWrapped, "", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("date,amount\n"))
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "date,amount\n", rec.Body.String())
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"time"
 
@@ -10,7 +11,29 @@ import (
 
 const maxIdempotencyBodySize = 1 << 20
 
-func Idempotency(idempotencyRepo *postgres.IdempotencyRepository) func(http.Handler) http.Handler {
+// IdempotencyStore keeps the responses Idempotency replays; it is
+// implemented by *postgres.IdempotencyRepository.
+type IdempotencyStore interface {
+	Get(ctx context.Context, key string) (*postgres.IdempotencyEntry, error)
+	Set(ctx context.Context, entry *postgres.IdempotencyEntry) error
+}
+
+type skipIdempotencyKey struct{}
+
+// SkipIdempotencyStore keeps the response to the request of ctx from being
+// stored, so it does not use up the request's Idempotency-Key: a handler
+// calls it for responses such as previews that a later request with the
+// same key must not replay.
+func SkipIdempotencyStore(ctx context.Context) {
+	if skip, ok := ctx.Value(skipIdempotencyKey{}).(*bool); ok {
+		*skip = true
+	}
+}
+
+// Idempotency replays the stored response of an earlier request with the
+// same Idempotency-Key from the same authenticated user. Responses are kept
+// for ttl; zero keeps them until they are deleted.
+func Idempotency(idempotencyRepo IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			key := r.Header.Get("Idempotency-Key")
@@ -18,6 +41,7 @@ func Idempotency(idempotencyRepo *postgres.IdempotencyRepository) func(http.Hand
 				next.ServeHTTP(w, r)
 				return
 			}
+			key = scopedKey(r, key)
 
 			entry, err := idempotencyRepo.Get(r.Context(), key)
 			if err == nil && entry != nil {
@@ -28,23 +52,35 @@ func Idempotency(idempotencyRepo *postgres.IdempotencyRepository) func(http.Hand
 				return
 			}
 
+			skip := false
 			rec := &responseRecorder{ResponseWriter: w, body: &bytes.Buffer{}, statusCode: http.StatusOK}
-			next.ServeHTTP(rec, r)
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), skipIdempotencyKey{}, &skip)))
 
-			if rec.statusCode >= 200 && rec.statusCode < 500 && rec.body.Len() <= maxIdempotencyBodySize {
+			if !skip && rec.statusCode >= 200 && rec.statusCode < 500 && rec.body.Len() <= maxIdempotencyBodySize {
 				now := time.Now()
+				expiresAt := now.Add(ttl)
+				if ttl <= 0 {
+					expiresAt = now.AddDate(100, 0, 0)
+				}
 				idempotencyRepo.Set(r.Context(), &postgres.IdempotencyEntry{
 					Key:            key,
 					ResponseBody:   rec.body.String(),
 					ResponseStatus: rec.statusCode,
 					CreatedAt:      now,
-					ExpiresAt:      now.Add(24 * time.Hour),
+					ExpiresAt:      expiresAt,
 				})
 			}
 		})
 	}
 }
 
+// scopedKey prefixes key with the caller's user ID, so two users sending the
+// same key do not see each other's responses.
+func scopedKey(r *http.Request, key string) string {
+	userID, _ := GetUserID(r.Context())
+	return "user:" + userID + ":" + key
+}
+
 type responseRecorder struct {
 	http.ResponseWriter
 	statusCode    int
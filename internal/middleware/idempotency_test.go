@@ -2,15 +2,72 @@ package middleware_test
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/repository/postgres"
 )
 
-// Since the idempotency middleware depends on *postgres.IdempotencyRepository (concrete type),
-// we cannot easily mock it without a database. These tests cover the responseRecorder
-// behavior and the no-key pass-through path.
+// fakeIdempotencyStore keeps entries in memory, ignoring their expiry.
+type fakeIdempotencyStore struct {
+	entries map[string]*postgres.IdempotencyEntry
+}
+
+func (s *fakeIdempotencyStore) Get(ctx context.Context, key string) (*postgres.IdempotencyEntry, error) {
+	return s.entries[key], nil
+}
+
+func (s *fakeIdempotencyStore) Set(ctx context.Context, entry *postgres.IdempotencyEntry) error {
+	s.entries[entry.Key] = entry
+	return nil
+}
+
+func TestIdempotency_StoresResponseForTTL(t *testing.T) {
+	for name, tc := range map[string]struct {
+		ttl     time.Duration
+		minLife time.Duration
+		maxLife time.Duration
+	}{
+		"configured ttl": {ttl: time.Hour, minLife: time.Hour, maxLife: time.Hour + time.Minute},
+		"zero keeps":     {ttl: 0, minLife: 50 * 365 * 24 * time.Hour, maxLife: 200 * 365 * 24 * time.Hour},
+	} {
+		store := &fakeIdempotencyStore{entries: map[string]*postgres.IdempotencyEntry{}}
+		calls := 0
+		handler := middleware.Idempotency(store, tc.ttl)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"123"}`))
+		}))
+
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/test", nil)
+			req.Header.Set("Idempotency-Key", "order-42")
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "user1"))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusCreated || w.Body.String() != `{"id":"123"}` {
+				t.Errorf("%s: request %d: expected the stored 201, got %d %s", name, i, w.Code, w.Body.String())
+			}
+		}
+		if calls != 1 {
+			t.Errorf("%s: expected the handler to run once, ran %d times", name, calls)
+		}
+
+		entry := store.entries["user:user1:order-42"]
+		if entry == nil {
+			t.Fatalf("%s: expected the response to be stored under the user's key", name)
+		}
+		if life := entry.ExpiresAt.Sub(entry.CreatedAt); life < tc.minLife || life > tc.maxLife {
+			t.Errorf("%s: expected the entry to live between %v and %v, got %v", name, tc.minLife, tc.maxLife, life)
+		}
+	}
+}
 
 func TestIdempotency_NoKey_PassThrough(t *testing.T) {
 	// Without an Idempotency-Key header, the middleware should pass through.
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// StrictQueryParam opts a single request into strict query parameter checks.
+const StrictQueryParam = "strict"
+
+// KnownQueryParams rejects requests carrying query parameters outside known
+// with 400, so a misspelled filter fails instead of silently matching
+// everything. It only checks when strict is set or the request passes
+// ?strict=true; otherwise unknown parameters are ignored as before.
+func KnownQueryParams(strict bool, known ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			enforce := strict
+			if s := query.Get(StrictQueryParam); s != "" {
+				v, err := strconv.ParseBool(s)
+				if err != nil {
+					writeQueryError(w, "invalid strict parameter", "invalid_query", nil)
+					return
+				}
+				enforce = enforce || v
+			}
+			if !enforce {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var unknown []string
+			for name := range query {
+				if name != StrictQueryParam && !slices.Contains(known, name) {
+					unknown = append(unknown, name)
+				}
+			}
+			if len(unknown) > 0 {
+				slices.Sort(unknown)
+				writeQueryError(w, "unknown query parameters", "unknown_query_params", map[string]any{
+					"params":  unknown,
+					"allowed": known,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeQueryError(w http.ResponseWriter, msg, code string, details map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	body := map[string]any{"error": msg, "code": code}
+	if details != nil {
+		body["details"] = details
+	}
+	json.NewEncoder(w).Encode(body)
+}
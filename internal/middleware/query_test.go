@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveKnownQuery(strict bool, target string) *httptest.ResponseRecorder {
+	handler := KnownQueryParams(strict, "status", "limit")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestKnownQueryParams_LenientByDefault(t *testing.T) {
+	rec := serveKnownQuery(false, "/payments?statsu=pending")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestKnownQueryParams_StrictPerRequest(t *testing.T) {
+	rec := serveKnownQuery(false, "/payments?strict=true&statsu=pending&limit=5&zz=1")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var body struct {
+		Code    string `json:"code"`
+		Details struct {
+			Params []string `json:"params"`
+		} `json:"details"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "unknown_query_params", body.Code)
+	assert.Equal(t, []string{"statsu", "zz"}, body.Details.Params)
+
+	rec = serveKnownQuery(false, "/payments?strict=true&status=pending&limit=5")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestKnownQueryParams_StrictGlobally(t *testing.T) {
+	assert.Equal(t, http.StatusBadRequest, serveKnownQuery(true, "/payments?statsu=pending").Code)
+	assert.Equal(t, http.StatusBadRequest, serveKnownQuery(true, "/payments?strict=false&statsu=pending").Code,
+		"the global setting cannot be switched off per request")
+	assert.Equal(t, http.StatusOK, serveKnownQuery(true, "/payments?status=pending").Code)
+}
+
+func TestKnownQueryParams_InvalidStrictValue(t *testing.T) {
+	assert.Equal(t, http.StatusBadRequest, serveKnownQuery(false, "/payments?strict=maybe").Code)
+}
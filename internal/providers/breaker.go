@@ -0,0 +1,152 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/sony/gobreaker/v2"
+)
+
+// BreakerSettings configures a provider's circuit breaker.
+type BreakerSettings struct {
+	// Threshold is the minimum number of requests in a 60s window before a
+	// failure ratio of 60% opens the breaker.
+	Threshold int
+	// Timeout is how long the breaker stays open before going half-open.
+	Timeout time.Duration
+	// HalfOpenProbes caps the requests in flight while half-open.
+	HalfOpenProbes int
+	// HalfOpenSuccesses is the number of consecutive successful probes needed
+	// to close the breaker; any failed probe reopens it.
+	HalfOpenSuccesses int
+}
+
+// DefaultBreakerSettings returns the settings breakers use unless configured.
+func DefaultBreakerSettings() BreakerSettings {
+	return BreakerSettings{Threshold: 10, Timeout: 30 * time.Second, HalfOpenProbes: 10, HalfOpenSuccesses: 10}
+}
+
+func (s BreakerSettings) Validate() error {
+	if s.Threshold < 1 || s.Timeout <= 0 || s.HalfOpenProbes < 1 || s.HalfOpenSuccesses < 1 {
+		return fmt.Errorf("breaker settings must be positive, got %+v", s)
+	}
+	return nil
+}
+
+// Breaker is a provider circuit breaker. gobreaker admits MaxRequests probes
+// per half-open period and closes after as many successes; Breaker uses that
+// as the success count and separately limits concurrent probes, so a breaker
+// can require several successes while probing one request at a time.
+type Breaker struct {
+	name     string
+	settings BreakerSettings
+	// cb is swapped for a fresh breaker by Reset.
+	cb      atomic.Pointer[gobreaker.CircuitBreaker[*ProviderResult]]
+	probes  chan struct{}
+	metrics *observability.Metrics
+	latency *latencyTracker // nil when latency is not tracked
+}
+
+// BreakerStatus is a snapshot of a breaker's state and of the counts in its
+// current generation, which restarts on every state change and, while
+// closed, every 60s.
+type BreakerStatus struct {
+	Provider             string
+	State                gobreaker.State
+	Requests             uint32
+	TotalSuccesses       uint32
+	TotalFailures        uint32
+	ConsecutiveSuccesses uint32
+	ConsecutiveFailures  uint32
+}
+
+func newBreaker(name string, s BreakerSettings, metrics *observability.Metrics, latency *latencyTracker) *Breaker {
+	b := &Breaker{name: name, settings: s, probes: make(chan struct{}, s.HalfOpenProbes), metrics: metrics, latency: latency}
+	b.Reset()
+	return b
+}
+
+// Reset forces the breaker closed with zeroed counts, as after an operator
+// confirms the provider has recovered, and reports the state. Requests
+// already in flight report to the replaced breaker, not the new one.
+func (b *Breaker) Reset() {
+	metrics := b.metrics
+	threshold := uint32(b.settings.Threshold)
+	b.cb.Store(gobreaker.NewCircuitBreaker[*ProviderResult](gobreaker.Settings{
+		Name:        b.name,
+		MaxRequests: uint32(b.settings.HalfOpenSuccesses),
+		Interval:    60 * time.Second,
+		Timeout:     b.settings.Timeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
+			return counts.Requests >= threshold && failureRatio >= 0.6
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			if metrics != nil {
+				metrics.CircuitBreakerState.WithLabelValues(name).Set(float64(to))
+			}
+		},
+	}))
+	if metrics != nil {
+		metrics.CircuitBreakerState.WithLabelValues(b.name).Set(float64(gobreaker.StateClosed))
+	}
+}
+
+func (b *Breaker) Name() string { return b.name }
+
+func (b *Breaker) State() gobreaker.State { return b.cb.Load().State() }
+
+// Status returns the breaker's state and counts.
+func (b *Breaker) Status() BreakerStatus {
+	cb := b.cb.Load()
+	state := cb.State() // first, as it may advance an expired open period
+	counts := cb.Counts()
+	return BreakerStatus{
+		Provider:             b.name,
+		State:                state,
+		Requests:             counts.Requests,
+		TotalSuccesses:       counts.TotalSuccesses,
+		TotalFailures:        counts.TotalFailures,
+		ConsecutiveSuccesses: counts.ConsecutiveSuccesses,
+		ConsecutiveFailures:  counts.ConsecutiveFailures,
+	}
+}
+
+// Execute runs fn through the breaker. Requests beyond the half-open probe
+// limit fail with gobreaker.ErrTooManyRequests.
+func (b *Breaker) Execute(fn func() (*ProviderResult, error)) (*ProviderResult, error) {
+	cb := b.cb.Load()
+	if cb.State() == gobreaker.StateHalfOpen {
+		select {
+		case b.probes <- struct{}{}:
+			defer func() { <-b.probes }()
+		default:
+			b.record(gobreaker.ErrTooManyRequests)
+			return nil, gobreaker.ErrTooManyRequests
+		}
+	}
+	start := time.Now()
+	result, err := cb.Execute(fn)
+	b.record(err)
+	if b.latency != nil && !errors.Is(err, gobreaker.ErrOpenState) && !errors.Is(err, gobreaker.ErrTooManyRequests) {
+		b.latency.observe(time.Since(start))
+	}
+	return result, err
+}
+
+func (b *Breaker) record(err error) {
+	if b.metrics == nil {
+		return
+	}
+	result := "success"
+	switch {
+	case errors.Is(err, gobreaker.ErrOpenState), errors.Is(err, gobreaker.ErrTooManyRequests):
+		result = "rejected"
+	case err != nil:
+		result = "failure"
+	}
+	b.metrics.CircuitBreakerRequests.WithLabelValues(b.name, result).Inc()
+}
@@ -0,0 +1,176 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sony/gobreaker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errProvider = errors.New("provider down")
+
+func succeed() (*ProviderResult, error) { return &ProviderResult{Status: ResultSuccess}, nil }
+func fail() (*ProviderResult, error)    { return nil, errProvider }
+
+// openBreaker trips b and waits for it to go half-open.
+func openBreaker(t *testing.T, b *Breaker, s BreakerSettings) {
+	t.Helper()
+	for i := 0; i < s.Threshold; i++ {
+		b.Execute(fail)
+	}
+	require.Equal(t, gobreaker.StateOpen, b.State())
+	time.Sleep(s.Timeout + 5*time.Millisecond)
+	require.Equal(t, gobreaker.StateHalfOpen, b.State())
+}
+
+func TestBreaker_HalfOpen_LimitsConcurrentProbes(t *testing.T) {
+	s := BreakerSettings{Threshold: 2, Timeout: 10 * time.Millisecond, HalfOpenProbes: 1, HalfOpenSuccesses: 3}
+	b := newBreaker("test", s, nil, nil)
+	openBreaker(t, b, s)
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		b.Execute(func() (*ProviderResult, error) { <-release; return succeed() })
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return len(b.probes) == 1 }, time.Second, time.Millisecond)
+
+	_, err := b.Execute(succeed)
+	assert.ErrorIs(t, err, gobreaker.ErrTooManyRequests)
+
+	close(release)
+	<-done
+}
+
+func TestBreaker_HalfOpen_ClosesAfterRequiredSuccesses(t *testing.T) {
+	s := BreakerSettings{Threshold: 2, Timeout: 10 * time.Millisecond, HalfOpenProbes: 1, HalfOpenSuccesses: 3}
+	b := newBreaker("test", s, nil, nil)
+	openBreaker(t, b, s)
+
+	for i := 0; i < 2; i++ {
+		_, err := b.Execute(succeed)
+		require.NoError(t, err)
+		assert.Equal(t, gobreaker.StateHalfOpen, b.State(), "one lucky success must not close the breaker")
+	}
+	_, err := b.Execute(succeed)
+	require.NoError(t, err)
+	assert.Equal(t, gobreaker.StateClosed, b.State())
+}
+
+func TestBreaker_HalfOpen_FailedProbeReopens(t *testing.T) {
+	s := BreakerSettings{Threshold: 2, Timeout: 10 * time.Millisecond, HalfOpenProbes: 2, HalfOpenSuccesses: 3}
+	b := newBreaker("test", s, nil, nil)
+	openBreaker(t, b, s)
+
+	b.Execute(succeed)
+	b.Execute(fail)
+
+	assert.Equal(t, gobreaker.StateOpen, b.State())
+}
+
+func TestBreaker_ReportsStateAndRequests(t *testing.T) {
+	metrics := observability.NewMetrics("test", prometheus.NewRegistry())
+	s := BreakerSettings{Threshold: 2, Timeout: time.Minute, HalfOpenProbes: 1, HalfOpenSuccesses: 1}
+	b := newBreaker("stripe", s, metrics, nil)
+
+	b.Execute(fail)
+	b.Execute(fail)
+	b.Execute(succeed)
+
+	var m dto.Metric
+	require.NoError(t, metrics.CircuitBreakerState.WithLabelValues("stripe").Write(&m))
+	assert.Equal(t, float64(gobreaker.StateOpen), m.GetGauge().GetValue())
+
+	require.NoError(t, metrics.CircuitBreakerRequests.WithLabelValues("stripe", "failure").Write(&m))
+	assert.Equal(t, 2.0, m.GetCounter().GetValue())
+	require.NoError(t, metrics.CircuitBreakerRequests.WithLabelValues("stripe", "rejected").Write(&m))
+	assert.Equal(t, 1.0, m.GetCounter().GetValue())
+}
+
+func TestFactory_ConfigureBreakers(t *testing.T) {
+	factory := NewFactory(NewMockProvider("stripe"), NewMockProvider("paypal"))
+	defaults := DefaultBreakerSettings()
+	override := BreakerSettings{Threshold: 3, Timeout: time.Second, HalfOpenProbes: 1, HalfOpenSuccesses: 5}
+
+	require.NoError(t, factory.ConfigureBreakers(defaults, map[string]BreakerSettings{"paypal": override}, nil))
+	assert.Equal(t, 1, cap(factory.circuitBreakers["paypal"].probes))
+	assert.Equal(t, defaults.HalfOpenProbes, cap(factory.circuitBreakers["stripe"].probes))
+
+	factory.Register(NewMockProvider("custom"))
+	assert.Equal(t, defaults.HalfOpenProbes, cap(factory.circuitBreakers["custom"].probes))
+
+	bad := override
+	bad.HalfOpenProbes = 0
+	assert.Error(t, factory.ConfigureBreakers(defaults, map[string]BreakerSettings{"paypal": bad}, nil))
+}
+
+func TestBreaker_Reset(t *testing.T) {
+	metrics := observability.NewMetrics("test", prometheus.NewRegistry())
+	s := BreakerSettings{Threshold: 2, Timeout: time.Minute, HalfOpenProbes: 1, HalfOpenSuccesses: 1}
+	b := newBreaker("stripe", s, metrics, nil)
+
+	b.Execute(fail)
+	b.Execute(fail)
+	status := b.Status()
+	assert.Equal(t, gobreaker.StateOpen, status.State)
+
+	b.Reset()
+	assert.Equal(t, BreakerStatus{Provider: "stripe", State: gobreaker.StateClosed}, b.Status())
+	var m dto.Metric
+	require.NoError(t, metrics.CircuitBreakerState.WithLabelValues("stripe").Write(&m))
+	assert.Equal(t, float64(gobreaker.StateClosed), m.GetGauge().GetValue())
+
+	_, err := b.Execute(succeed)
+	require.NoError(t, err)
+	status = b.Status()
+	assert.Equal(t, uint32(1), status.Requests)
+	assert.Equal(t, uint32(1), status.ConsecutiveSuccesses)
+}
+
+func TestFactory_ResetBreaker(t *testing.T) {
+	factory := NewFactory(NewMockProvider("stripe"), NewMockProvider("paypal"))
+
+	statuses := factory.BreakerStatuses()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "paypal", statuses[0].Provider, "statuses are sorted by provider")
+
+	status, err := factory.ResetBreaker("stripe")
+	require.NoError(t, err)
+	assert.Equal(t, gobreaker.StateClosed, status.State)
+
+	_, err = factory.ResetBreaker("unknown")
+	assert.ErrorIs(t, err, domainErrors.ErrProviderNotFound)
+}
+
+func TestBreaker_TripsOnScriptedFailures(t *testing.T) {
+	factory := NewFactory(NewMockProvider("stripe", WithScript(
+		CallOutcome{Err: domainErrors.ErrProviderTimeout},
+		CallOutcome{Err: domainErrors.ErrProviderTimeout},
+		CallOutcome{},
+	)))
+	s := BreakerSettings{Threshold: 2, Timeout: time.Minute, HalfOpenProbes: 1, HalfOpenSuccesses: 1}
+	require.NoError(t, factory.ConfigureBreakers(s, nil, nil))
+	provider, breaker, _, err := factory.Get("stripe")
+	require.NoError(t, err)
+
+	charge := func() (*ProviderResult, error) {
+		return provider.ProcessPayment(context.Background(), ProcessRequest{PaymentID: "pay_1", AmountCents: 100, Currency: "USD"})
+	}
+	_, err = breaker.Execute(charge)
+	assert.ErrorIs(t, err, domainErrors.ErrProviderTimeout)
+	_, err = breaker.Execute(charge)
+	assert.ErrorIs(t, err, domainErrors.ErrProviderTimeout)
+	assert.Equal(t, gobreaker.StateOpen, breaker.State())
+
+	_, err = breaker.Execute(charge)
+	assert.ErrorIs(t, err, gobreaker.ErrOpenState, "an open breaker never reaches the scripted success")
+}
@@ -2,31 +2,52 @@ package providers
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"time"
 
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
 	"github.com/sony/gobreaker/v2"
 )
 
 type Factory struct {
 	providers       map[string]Provider
-	circuitBreakers map[string]*gobreaker.CircuitBreaker[*ProviderResult]
+	circuitBreakers map[string]*Breaker
+	manualRefunds   map[string]bool
+
+	breakerDefaults  BreakerSettings
+	breakerOverrides map[string]BreakerSettings
+	metrics          *observability.Metrics
+
+	slo       LatencySLO
+	latency   map[string]*latencyTracker
+	fallbacks map[string]string
+
+	limiters map[string]*Limiter
 }
 
 func NewFactory(providersList ...Provider) *Factory {
 	f := &Factory{
 		providers:       make(map[string]Provider),
-		circuitBreakers: make(map[string]*gobreaker.CircuitBreaker[*ProviderResult]),
+		circuitBreakers: make(map[string]*Breaker),
+		manualRefunds:   make(map[string]bool),
+		breakerDefaults: DefaultBreakerSettings(),
+		latency:         make(map[string]*latencyTracker),
+		limiters:        make(map[string]*Limiter),
 	}
 
 	if len(providersList) == 0 {
 		f.Register(NewMockProvider("stripe",
 			WithLatency(200*time.Millisecond),
 			WithFailureRate(0.05),
+			WithRequiredOptions("customer_id"),
 		))
 		f.Register(NewMockProvider("paypal",
 			WithLatency(300*time.Millisecond),
 			WithFailureRate(0.08),
+			WithRequiredOptions("payer_email"),
 		))
 	} else {
 		for _, p := range providersList {
@@ -39,23 +60,154 @@ func NewFactory(providersList ...Provider) *Factory {
 
 func (f *Factory) Register(p Provider) {
 	f.providers[p.Name()] = p
-	f.circuitBreakers[p.Name()] = gobreaker.NewCircuitBreaker[*ProviderResult](gobreaker.Settings{
-		Name:        p.Name(),
-		MaxRequests: 10,
-		Interval:    60 * time.Second,
-		Timeout:     30 * time.Second,
-		ReadyToTrip: func(counts gobreaker.Counts) bool {
-			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-			return counts.Requests >= 10 && failureRatio >= 0.6
-		},
-	})
-}
-
-func (f *Factory) Get(name payment.Provider) (Provider, *gobreaker.CircuitBreaker[*ProviderResult], error) {
+	f.latency[p.Name()] = newLatencyTracker(p.Name(), f.slo, f.metrics)
+	f.circuitBreakers[p.Name()] = newBreaker(p.Name(), f.breakerSettings(p.Name()), f.metrics, f.latency[p.Name()])
+}
+
+// ConfigureBreakers replaces every provider's circuit breaker, using
+// overrides[name] where present and defaults otherwise, and reports breaker
+// state and requests to metrics when it is non-nil. Breaker state is reset.
+func (f *Factory) ConfigureBreakers(defaults BreakerSettings, overrides map[string]BreakerSettings, metrics *observability.Metrics) error {
+	if err := defaults.Validate(); err != nil {
+		return err
+	}
+	for name, s := range overrides {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("provider %s: %w", name, err)
+		}
+	}
+	f.breakerDefaults = defaults
+	f.breakerOverrides = overrides
+	f.metrics = metrics
+	for name := range f.providers {
+		f.latency[name] = newLatencyTracker(name, f.slo, metrics)
+		f.circuitBreakers[name] = newBreaker(name, f.breakerSettings(name), metrics, f.latency[name])
+	}
+	return nil
+}
+
+// ConfigureLatencySLO checks provider call latency against slo. While a
+// provider is in slow mode, Route sends its payments to fallbacks[name] if
+// one is configured and healthy.
+func (f *Factory) ConfigureLatencySLO(slo LatencySLO, fallbacks map[string]string) error {
+	if err := slo.Validate(); err != nil {
+		return err
+	}
+	for from, to := range fallbacks {
+		if _, ok := f.providers[to]; !ok || from == to {
+			return fmt.Errorf("latency SLO fallback %s -> %s: unknown or same provider", from, to)
+		}
+	}
+	f.slo = slo
+	f.fallbacks = fallbacks
+	for name, b := range f.circuitBreakers {
+		f.latency[name] = newLatencyTracker(name, slo, f.metrics)
+		b.latency = f.latency[name]
+	}
+	return nil
+}
+
+// Route returns the provider a payment for name should use: name itself, or
+// its fallback while name is in slow mode and the fallback is neither slow
+// nor behind an open breaker.
+func (f *Factory) Route(name payment.Provider) payment.Provider {
+	t, ok := f.latency[string(name)]
+	if !ok || !t.slow() {
+		return name
+	}
+	alt, ok := f.fallbacks[string(name)]
+	if !ok {
+		return name
+	}
+	if at, ok := f.latency[alt]; ok && at.slow() {
+		return name
+	}
+	if b, ok := f.circuitBreakers[alt]; ok && b.State() == gobreaker.StateOpen {
+		return name
+	}
+	return payment.Provider(alt)
+}
+
+// ConfigureRateLimits limits the requests sent to each provider in limits;
+// other providers are unlimited. Throttled requests are counted in metrics
+// when it is non-nil.
+func (f *Factory) ConfigureRateLimits(limits map[string]RateLimit, metrics *observability.Metrics) error {
+	for name, l := range limits {
+		if _, ok := f.providers[name]; !ok {
+			return fmt.Errorf("rate limit for unknown provider %s", name)
+		}
+		if err := l.Validate(); err != nil {
+			return fmt.Errorf("provider %s: %w", name, err)
+		}
+	}
+	f.limiters = make(map[string]*Limiter, len(limits))
+	for name, l := range limits {
+		if limiter := newLimiter(name, l, metrics); limiter != nil {
+			f.limiters[name] = limiter
+		}
+	}
+	return nil
+}
+
+func (f *Factory) breakerSettings(name string) BreakerSettings {
+	if s, ok := f.breakerOverrides[name]; ok {
+		return s
+	}
+	return f.breakerDefaults
+}
+
+// Get returns the named provider with its circuit breaker and rate limiter.
+// Callers Wait on the limiter before each request; it is nil, and never
+// waits, for a provider without a rate limit.
+func (f *Factory) Get(name payment.Provider) (Provider, *Breaker, *Limiter, error) {
 	p, ok := f.providers[string(name)]
 	if !ok {
-		return nil, nil, fmt.Errorf("unknown provider %q: %w", name, fmt.Errorf("provider not found"))
+		return nil, nil, nil, fmt.Errorf("unknown provider %q: %w", name, fmt.Errorf("provider not found"))
 	}
 	breaker := f.circuitBreakers[string(name)]
-	return p, breaker, nil
+	return p, breaker, f.limiters[string(name)], nil
+}
+
+// BreakerStatuses returns every provider's breaker status, ordered by
+// provider name.
+func (f *Factory) BreakerStatuses() []BreakerStatus {
+	names := slices.Sorted(maps.Keys(f.circuitBreakers))
+	statuses := make([]BreakerStatus, 0, len(names))
+	for _, name := range names {
+		statuses = append(statuses, f.circuitBreakers[name].Status())
+	}
+	return statuses
+}
+
+// ResetBreaker forces the named provider's breaker closed and returns its
+// new status.
+func (f *Factory) ResetBreaker(name payment.Provider) (BreakerStatus, error) {
+	b, ok := f.circuitBreakers[string(name)]
+	if !ok {
+		return BreakerStatus{}, fmt.Errorf("%w: %q", domainErrors.ErrProviderNotFound, name)
+	}
+	b.Reset()
+	return b.Status(), nil
+}
+
+// RequireManualRefunds marks providers whose refunds must be processed
+// manually, overriding what the providers declare.
+func (f *Factory) RequireManualRefunds(names ...string) {
+	for _, name := range names {
+		f.manualRefunds[name] = true
+	}
+}
+
+// Capabilities returns the named provider's capabilities, including
+// configured overrides.
+func (f *Factory) Capabilities(name payment.Provider) (Capabilities, error) {
+	p, ok := f.providers[string(name)]
+	if !ok {
+		return Capabilities{}, fmt.Errorf("unknown provider %q: %w", name, fmt.Errorf("provider not found"))
+	}
+	caps := CapabilitiesOf(p)
+	if f.manualRefunds[string(name)] {
+		caps.APIRefunds = false
+	}
+	return caps, nil
 }
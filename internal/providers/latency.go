@@ -0,0 +1,119 @@
+package providers
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/rs/zerolog/log"
+)
+
+// LatencySLO puts a provider into slow mode when the Percentile of its last
+// Window call durations exceeds Threshold. Slow mode lasts Cooldown, after
+// which the provider is measured afresh. A zero Threshold disables it.
+type LatencySLO struct {
+	Percentile float64 // e.g. 0.95
+	Threshold  time.Duration
+	Window     int
+	MinSamples int
+	Cooldown   time.Duration
+}
+
+func (s LatencySLO) Enabled() bool { return s.Threshold > 0 }
+
+func (s LatencySLO) Validate() error {
+	if !s.Enabled() {
+		return nil
+	}
+	if s.Percentile <= 0 || s.Percentile >= 1 {
+		return fmt.Errorf("latency SLO percentile must be between 0 and 1, got %v", s.Percentile)
+	}
+	if s.MinSamples < 1 || s.Window < s.MinSamples {
+		return fmt.Errorf("latency SLO needs 1 <= min_samples <= window, got %d and %d", s.MinSamples, s.Window)
+	}
+	if s.Cooldown <= 0 {
+		return fmt.Errorf("latency SLO cooldown must be positive")
+	}
+	return nil
+}
+
+// latencyTracker keeps a ring of recent call durations for one provider.
+type latencyTracker struct {
+	name    string
+	slo     LatencySLO
+	metrics *observability.Metrics
+
+	mu        sync.Mutex
+	samples   []time.Duration
+	next      int
+	slowUntil time.Time
+}
+
+func newLatencyTracker(name string, slo LatencySLO, metrics *observability.Metrics) *latencyTracker {
+	return &latencyTracker{name: name, slo: slo, metrics: metrics, samples: make([]time.Duration, 0, slo.Window)}
+}
+
+func (t *latencyTracker) observe(d time.Duration) {
+	if t.metrics != nil {
+		t.metrics.ProviderLatency.WithLabelValues(t.name).Observe(d.Seconds())
+	}
+	if !t.slo.Enabled() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if now.Before(t.slowUntil) {
+		return
+	}
+	if !t.slowUntil.IsZero() {
+		// Cooldown over: measure the provider afresh.
+		t.slowUntil = time.Time{}
+		t.samples, t.next = t.samples[:0], 0
+		if t.metrics != nil {
+			t.metrics.ProviderSlowMode.WithLabelValues(t.name).Set(0)
+		}
+		log.Info().Str("provider", t.name).Msg("provider slow mode ended")
+	}
+
+	if len(t.samples) < t.slo.Window {
+		t.samples = append(t.samples, d)
+	} else {
+		t.samples[t.next] = d
+		t.next = (t.next + 1) % t.slo.Window
+	}
+	if len(t.samples) < t.slo.MinSamples {
+		return
+	}
+
+	if p := t.percentile(); p > t.slo.Threshold {
+		t.slowUntil = now.Add(t.slo.Cooldown)
+		if t.metrics != nil {
+			t.metrics.ProviderSlowMode.WithLabelValues(t.name).Set(1)
+			t.metrics.ProviderSLOBreaches.WithLabelValues(t.name).Inc()
+		}
+		log.Error().Str("provider", t.name).
+			Float64("percentile", t.slo.Percentile).Dur("latency", p).Dur("threshold", t.slo.Threshold).
+			Dur("cooldown", t.slo.Cooldown).
+			Msg("provider latency SLO breached; entering slow mode")
+	}
+}
+
+// percentile returns the nearest-rank percentile of the samples. Callers
+// hold t.mu.
+func (t *latencyTracker) percentile() time.Duration {
+	sorted := slices.Clone(t.samples)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(float64(len(sorted))*t.slo.Percentile)) - 1
+	return sorted[max(rank, 0)]
+}
+
+func (t *latencyTracker) slow() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Now().Before(t.slowUntil)
+}
@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSLO() LatencySLO {
+	return LatencySLO{Percentile: 0.9, Threshold: 100 * time.Millisecond, Window: 10, MinSamples: 5, Cooldown: time.Minute}
+}
+
+func TestLatencyTracker_BreachEntersSlowMode(t *testing.T) {
+	metrics := observability.NewMetrics("test", prometheus.NewRegistry())
+	tr := newLatencyTracker("stripe", testSLO(), metrics)
+
+	for i := 0; i < 4; i++ {
+		tr.observe(time.Second)
+	}
+	assert.False(t, tr.slow(), "no verdict before min_samples")
+
+	tr.observe(time.Second)
+	assert.True(t, tr.slow())
+
+	var m dto.Metric
+	require.NoError(t, metrics.ProviderSlowMode.WithLabelValues("stripe").Write(&m))
+	assert.Equal(t, 1.0, m.GetGauge().GetValue())
+	require.NoError(t, metrics.ProviderSLOBreaches.WithLabelValues("stripe").Write(&m))
+	assert.Equal(t, 1.0, m.GetCounter().GetValue())
+}
+
+func TestLatencyTracker_PercentileIgnoresOutliers(t *testing.T) {
+	tr := newLatencyTracker("stripe", testSLO(), nil)
+
+	for i := 0; i < 9; i++ {
+		tr.observe(10 * time.Millisecond)
+	}
+	tr.observe(5 * time.Second)
+
+	assert.False(t, tr.slow(), "one slow call in ten is within p90")
+}
+
+func TestLatencyTracker_CooldownMeasuresAfresh(t *testing.T) {
+	slo := testSLO()
+	slo.Cooldown = 10 * time.Millisecond
+	tr := newLatencyTracker("stripe", slo, nil)
+
+	for i := 0; i < slo.MinSamples; i++ {
+		tr.observe(time.Second)
+	}
+	require.True(t, tr.slow())
+
+	time.Sleep(slo.Cooldown + 5*time.Millisecond)
+	assert.False(t, tr.slow())
+	tr.observe(time.Second)
+	assert.False(t, tr.slow(), "old samples must not count after the cooldown")
+}
+
+func TestFactory_Route(t *testing.T) {
+	factory := NewFactory(NewMockProvider("stripe"), NewMockProvider("paypal"))
+	require.NoError(t, factory.ConfigureLatencySLO(testSLO(), map[string]string{"stripe": "paypal"}))
+
+	assert.Equal(t, payment.Provider("stripe"), factory.Route("stripe"))
+
+	for i := 0; i < 5; i++ {
+		factory.latency["stripe"].observe(time.Second)
+	}
+	assert.Equal(t, payment.Provider("paypal"), factory.Route("stripe"))
+	assert.Equal(t, payment.Provider("paypal"), factory.Route("paypal"))
+
+	for i := 0; i < 5; i++ {
+		factory.latency["paypal"].observe(time.Second)
+	}
+	assert.Equal(t, payment.Provider("stripe"), factory.Route("stripe"), "a slow fallback is no better")
+}
+
+func TestFactory_ConfigureLatencySLO_Validates(t *testing.T) {
+	factory := NewFactory(NewMockProvider("stripe"), NewMockProvider("paypal"))
+
+	bad := testSLO()
+	bad.Percentile = 1
+	assert.Error(t, factory.ConfigureLatencySLO(bad, nil))
+	assert.Error(t, factory.ConfigureLatencySLO(testSLO(), map[string]string{"stripe": "unknown"}))
+	assert.Error(t, factory.ConfigureLatencySLO(testSLO(), map[string]string{"stripe": "stripe"}))
+	assert.NoError(t, factory.ConfigureLatencySLO(LatencySLO{}, nil), "zero threshold disables the SLO")
+}
@@ -2,11 +2,14 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 
 	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
 	"github.com/google/uuid"
 )
 
@@ -15,10 +18,42 @@ type MockProvider struct {
 	failureRate float64 // 0.0 to 1.0
 	latency     time.Duration
 	timeoutRate float64 // 0.0 to 1.0
+
+	refundStatus   string // forced refund result status, returned without an error
+	statusOverride string // forced GetPaymentStatus result status
+	manualRefunds  bool
+	minorUnits     map[string]int
+	required       []string
+
+	// Successful results by idempotency key, replayed for repeated requests
+	// as a real provider would, and the latest charge result by transaction
+	// ID and by payment ID for GetPaymentStatus.
+	mu        sync.Mutex
+	completed map[string]*ProviderResult
+	charges   map[string]*ProviderResult
+	// Outcomes left for ProcessPayment; see WithScript.
+	script []CallOutcome
+}
+
+// CallOutcome scripts one ProcessPayment call: it takes Latency, then
+// succeeds if Err is nil. ErrProviderTimeout is returned without a result,
+// as a provider that never answered; any other error comes with a failed
+// result.
+type CallOutcome struct {
+	Err     error
+	Latency time.Duration
 }
 
 type MockProviderOption func(*MockProvider)
 
+// WithScript makes ProcessPayment calls follow outcomes in order, one per
+// call, so tests can assert exact sequences such as "fail, then succeed".
+// Once the script is used up the failure and timeout rates apply again.
+// Charges replayed by idempotency key do not consume an outcome.
+func WithScript(outcomes ...CallOutcome) MockProviderOption {
+	return func(p *MockProvider) { p.script = append([]CallOutcome(nil), outcomes...) }
+}
+
 func WithFailureRate(rate float64) MockProviderOption {
 	return func(p *MockProvider) { p.failureRate = rate }
 }
@@ -31,6 +66,40 @@ func WithTimeoutRate(rate float64) MockProviderOption {
 	return func(p *MockProvider) { p.timeoutRate = rate }
 }
 
+// WithRefundStatus makes RefundPayment return a result with the given status
+// and a nil error, as a provider reporting a non-success outcome in-band would.
+func WithRefundStatus(status string) MockProviderOption {
+	return func(p *MockProvider) { p.refundStatus = status }
+}
+
+// WithStatusOverride makes GetPaymentStatus report status for every charge,
+// known or not, so tests can drive reconciliation to a chosen outcome.
+func WithStatusOverride(status string) MockProviderOption {
+	return func(p *MockProvider) { p.statusOverride = status }
+}
+
+// WithManualRefunds declares the provider as lacking API refund support.
+func WithManualRefunds() MockProviderOption {
+	return func(p *MockProvider) { p.manualRefunds = true }
+}
+
+// WithMinorUnits makes the provider expect code in units with the given
+// number of decimal places instead of the currency's registered minor units.
+func WithMinorUnits(code string, units int) MockProviderOption {
+	return func(p *MockProvider) {
+		if p.minorUnits == nil {
+			p.minorUnits = make(map[string]int)
+		}
+		p.minorUnits[code] = units
+	}
+}
+
+// WithRequiredOptions makes the provider require the named options in its
+// ProviderOptions namespace, as a real provider requires e.g. a customer ID.
+func WithRequiredOptions(names ...string) MockProviderOption {
+	return func(p *MockProvider) { p.required = names }
+}
+
 func NewMockProvider(name string, opts ...MockProviderOption) *MockProvider {
 	p := &MockProvider{
 		name:        name,
@@ -46,14 +115,48 @@ func NewMockProvider(name string, opts ...MockProviderOption) *MockProvider {
 
 func (p *MockProvider) Name() string { return p.name }
 
+func (p *MockProvider) Capabilities() Capabilities {
+	return Capabilities{APIRefunds: !p.manualRefunds, MinorUnits: p.minorUnits, RequiredOptions: p.required}
+}
+
 func (p *MockProvider) ProcessPayment(ctx context.Context, req ProcessRequest) (*ProviderResult, error) {
+	if res, ok := p.replay("charge", req.IdempotencyKey); ok {
+		return res, nil
+	}
+
+	outcome, scripted := p.nextOutcome()
+	latency := p.latency
+	if scripted {
+		latency = outcome.Latency
+	}
+
 	// Simulate latency
 	select {
-	case <-time.After(p.latency):
+	case <-time.After(latency):
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 
+	if missing := p.Capabilities().MissingOptions(req.ProviderOptions.For(payment.Provider(p.name))); len(missing) > 0 {
+		return &ProviderResult{
+			Status:       "failed",
+			ErrorMessage: fmt.Sprintf("%s: missing required options %v", p.name, missing),
+		}, domainErrors.ErrProviderRejected
+	}
+
+	if scripted {
+		switch {
+		case outcome.Err == nil:
+			return p.charge(req), nil
+		case errors.Is(outcome.Err, domainErrors.ErrProviderTimeout):
+			return nil, outcome.Err
+		}
+		return p.remember(req.PaymentID, &ProviderResult{
+			Status:       "failed",
+			ErrorMessage: fmt.Sprintf("%s: scripted failure for payment %s: %v", p.name, req.PaymentID, outcome.Err),
+		}), outcome.Err
+	}
+
 	// Simulate timeout
 	if rand.Float64() < p.timeoutRate {
 		return nil, domainErrors.ErrProviderTimeout
@@ -61,25 +164,53 @@ func (p *MockProvider) ProcessPayment(ctx context.Context, req ProcessRequest) (
 
 	// Simulate failure
 	if rand.Float64() < p.failureRate {
-		return &ProviderResult{
+		return p.remember(req.PaymentID, &ProviderResult{
 			Status:       "failed",
 			ErrorMessage: fmt.Sprintf("%s: simulated processing failure for payment %s", p.name, req.PaymentID),
-		}, domainErrors.ErrProviderRejected
+		}), domainErrors.ErrProviderRejected
 	}
 
-	return &ProviderResult{
+	return p.charge(req), nil
+}
+
+// charge records a successful charge for req.
+func (p *MockProvider) charge(req ProcessRequest) *ProviderResult {
+	return p.remember(req.PaymentID, p.record("charge", req.IdempotencyKey, &ProviderResult{
 		TransactionID: fmt.Sprintf("%s_txn_%s", p.name, uuid.New().String()[:8]),
 		Status:        "success",
-	}, nil
+	}))
+}
+
+// nextOutcome consumes the next scripted outcome, if any are left.
+func (p *MockProvider) nextOutcome() (CallOutcome, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.script) == 0 {
+		return CallOutcome{}, false
+	}
+	outcome := p.script[0]
+	p.script = p.script[1:]
+	return outcome, true
 }
 
 func (p *MockProvider) RefundPayment(ctx context.Context, req RefundRequest) (*ProviderResult, error) {
+	if res, ok := p.replay("refund", req.IdempotencyKey); ok {
+		return res, nil
+	}
+
 	select {
 	case <-time.After(p.latency):
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 
+	if p.refundStatus != "" {
+		return &ProviderResult{
+			Status:       p.refundStatus,
+			ErrorMessage: fmt.Sprintf("%s: simulated refund status %s", p.name, p.refundStatus),
+		}, nil
+	}
+
 	if rand.Float64() < p.failureRate {
 		return &ProviderResult{
 			Status:       "failed",
@@ -87,8 +218,82 @@ func (p *MockProvider) RefundPayment(ctx context.Context, req RefundRequest) (*P
 		}, domainErrors.ErrProviderRejected
 	}
 
-	return &ProviderResult{
+	return p.record("refund", req.IdempotencyKey, &ProviderResult{
 		TransactionID: fmt.Sprintf("%s_refund_%s", p.name, uuid.New().String()[:8]),
 		Status:        "success",
-	}, nil
+	}), nil
+}
+
+// GetPaymentStatus returns the latest result of the charge made through
+// this provider for req's transaction or, without one, its payment. The
+// result is the same until the payment is charged again, unless
+// WithStatusOverride forces it.
+func (p *MockProvider) GetPaymentStatus(ctx context.Context, req StatusRequest) (*ProviderResult, error) {
+	select {
+	case <-time.After(p.latency):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if p.statusOverride != "" {
+		return &ProviderResult{TransactionID: req.TransactionID, Status: p.statusOverride}, nil
+	}
+
+	key := "payment/" + req.PaymentID
+	if req.TransactionID != "" {
+		key = "tx/" + req.TransactionID
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res, ok := p.charges[key]
+	if !ok {
+		return nil, domainErrors.ErrProviderTransactionNotFound
+	}
+	found := *res
+	return &found, nil
+}
+
+// remember stores the result of a charge for GetPaymentStatus.
+func (p *MockProvider) remember(paymentID string, res *ProviderResult) *ProviderResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.charges == nil {
+		p.charges = make(map[string]*ProviderResult)
+	}
+	stored := *res
+	if paymentID != "" {
+		p.charges["payment/"+paymentID] = &stored
+	}
+	if res.TransactionID != "" {
+		p.charges["tx/"+res.TransactionID] = &stored
+	}
+	return res
+}
+
+// replay returns the result of an earlier successful request with key.
+func (p *MockProvider) replay(kind, key string) (*ProviderResult, bool) {
+	if key == "" {
+		return nil, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	res, ok := p.completed[kind+"/"+key]
+	if !ok {
+		return nil, false
+	}
+	replayed := *res
+	return &replayed, true
+}
+
+func (p *MockProvider) record(kind, key string, res *ProviderResult) *ProviderResult {
+	if key == "" {
+		return res
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.completed == nil {
+		p.completed = make(map[string]*ProviderResult)
+	}
+	stored := *res
+	p.completed[kind+"/"+key] = &stored
+	return res
 }
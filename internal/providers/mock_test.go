@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -51,6 +52,27 @@ func TestMockProvider_ProcessPayment_Success(t *testing.T) {
 	assert.Contains(t, result.TransactionID, "test_")
 }
 
+func TestMockProvider_RepeatedIdempotencyKey_ReplaysCharge(t *testing.T) {
+	provider := NewMockProvider("test", WithLatency(0))
+	ctx := context.Background()
+	req := ProcessRequest{PaymentID: "pay_123", IdempotencyKey: "payments:charge:k1", AmountCents: 10000, Currency: "USD"}
+
+	first, err := provider.ProcessPayment(ctx, req)
+	require.NoError(t, err)
+	again, err := provider.ProcessPayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, first.TransactionID, again.TransactionID)
+
+	req.IdempotencyKey = "payments:charge:k2"
+	other, err := provider.ProcessPayment(ctx, req)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.TransactionID, other.TransactionID)
+
+	refund, err := provider.RefundPayment(ctx, RefundRequest{PaymentID: "pay_123", IdempotencyKey: "payments:charge:k1"})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.TransactionID, refund.TransactionID, "refunds do not replay charges")
+}
+
 func TestMockProvider_ProcessPayment_Failure(t *testing.T) {
 	// Create a provider with 100% failure rate
 	provider := NewMockProvider("test", WithFailureRate(1.0))
@@ -125,3 +147,82 @@ func TestMockProvider_Latency(t *testing.T) {
 	// Should take at least the specified latency
 	assert.GreaterOrEqual(t, duration, latency)
 }
+
+func TestMockProvider_GetPaymentStatus(t *testing.T) {
+	provider := NewMockProvider("test", WithLatency(0))
+	ctx := context.Background()
+
+	res, err := provider.ProcessPayment(ctx, ProcessRequest{PaymentID: "pay_123", AmountCents: 1000, Currency: "USD"})
+	require.NoError(t, err)
+
+	for _, req := range []StatusRequest{{TransactionID: res.TransactionID}, {PaymentID: "pay_123"}} {
+		status, err := provider.GetPaymentStatus(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, ResultSuccess, status.Status)
+		assert.Equal(t, res.TransactionID, status.TransactionID)
+	}
+
+	_, err = provider.GetPaymentStatus(ctx, StatusRequest{PaymentID: "pay_unknown"})
+	assert.ErrorIs(t, err, domainErrors.ErrProviderTransactionNotFound)
+}
+
+func TestMockProvider_GetPaymentStatus_RemembersFailures(t *testing.T) {
+	provider := NewMockProvider("test", WithLatency(0), WithFailureRate(1))
+	ctx := context.Background()
+
+	_, err := provider.ProcessPayment(ctx, ProcessRequest{PaymentID: "pay_123", AmountCents: 1000, Currency: "USD"})
+	require.ErrorIs(t, err, domainErrors.ErrProviderRejected)
+
+	status, err := provider.GetPaymentStatus(ctx, StatusRequest{PaymentID: "pay_123"})
+	require.NoError(t, err)
+	assert.Equal(t, ResultFailed, status.Status)
+}
+
+func TestMockProvider_WithStatusOverride(t *testing.T) {
+	provider := NewMockProvider("test", WithLatency(0), WithStatusOverride(ResultPending))
+
+	status, err := provider.GetPaymentStatus(context.Background(), StatusRequest{TransactionID: "txn_1"})
+	require.NoError(t, err)
+	assert.Equal(t, ResultPending, status.Status)
+	assert.Equal(t, "txn_1", status.TransactionID)
+}
+
+func TestMockProvider_WithScript(t *testing.T) {
+	provider := NewMockProvider("test", WithLatency(0), WithFailureRate(1), WithScript(
+		CallOutcome{Err: domainErrors.ErrProviderRejected},
+		CallOutcome{Err: domainErrors.ErrProviderTimeout},
+		CallOutcome{},
+	))
+	ctx := context.Background()
+	charge := func(key string) (*ProviderResult, error) {
+		return provider.ProcessPayment(ctx, ProcessRequest{PaymentID: "pay_123", IdempotencyKey: key, AmountCents: 1000, Currency: "USD"})
+	}
+
+	res, err := charge("k1")
+	assert.ErrorIs(t, err, domainErrors.ErrProviderRejected)
+	assert.Equal(t, ResultFailed, res.Status)
+
+	res, err = charge("k1")
+	assert.ErrorIs(t, err, domainErrors.ErrProviderTimeout)
+	assert.Nil(t, res)
+
+	res, err = charge("k1")
+	require.NoError(t, err)
+	assert.Equal(t, ResultSuccess, res.Status)
+
+	replayed, err := charge("k1")
+	require.NoError(t, err, "a replayed charge does not consume the script")
+	assert.Equal(t, res.TransactionID, replayed.TransactionID)
+
+	_, err = charge("k2")
+	assert.ErrorIs(t, err, domainErrors.ErrProviderRejected, "the failure rate applies once the script is used up")
+}
+
+func TestMockProvider_WithScript_Latency(t *testing.T) {
+	provider := NewMockProvider("test", WithScript(CallOutcome{Latency: time.Second}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := provider.ProcessPayment(ctx, ProcessRequest{PaymentID: "pay_123", AmountCents: 1000, Currency: "USD"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
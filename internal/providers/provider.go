@@ -2,6 +2,11 @@ package providers
 
 import (
 	"context"
+	"fmt"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/pkg/currency"
 )
 
 type ProviderResult struct {
@@ -10,6 +15,27 @@ type ProviderResult struct {
 	ErrorMessage  string
 }
 
+const (
+	ResultSuccess = "success"
+	ResultFailed  = "failed"
+	ResultPending = "pending"
+)
+
+// PaymentStatus maps the result's status to the payment status it implies:
+// completed for success, failed for failed and processing for pending. It
+// reports false for any other status, which needs a human to interpret.
+func (r *ProviderResult) PaymentStatus() (payment.PaymentStatus, bool) {
+	switch r.Status {
+	case ResultSuccess:
+		return payment.StatusCompleted, true
+	case ResultFailed:
+		return payment.StatusFailed, true
+	case ResultPending:
+		return payment.StatusProcessing, true
+	}
+	return "", false
+}
+
 type Provider interface {
 	// Name returns the provider name.
 	Name() string
@@ -17,18 +43,97 @@ type Provider interface {
 	ProcessPayment(ctx context.Context, req ProcessRequest) (*ProviderResult, error)
 	// RefundPayment refunds a payment through the provider.
 	RefundPayment(ctx context.Context, req RefundRequest) (*ProviderResult, error)
+	// GetPaymentStatus reports the provider's current view of an earlier
+	// charge, or ErrProviderTransactionNotFound if it has no such charge.
+	GetPaymentStatus(ctx context.Context, req StatusRequest) (*ProviderResult, error)
+}
+
+// Capabilities describes optional provider features.
+type Capabilities struct {
+	// APIRefunds is false for providers whose refunds must be processed
+	// manually outside the API.
+	APIRefunds bool
+	// MinorUnits overrides, by currency code, the decimal places the provider
+	// counts amounts in where they differ from the currency's registered
+	// minor units (e.g. a provider taking HUF in whole forints).
+	MinorUnits map[string]int
+	// RequiredOptions lists the options a payment must carry in the
+	// provider's ProviderOptions namespace, e.g. "customer_id".
+	RequiredOptions []string
+}
+
+// MissingOptions returns the required options absent or empty in opts.
+func (c Capabilities) MissingOptions(opts map[string]string) []string {
+	var missing []string
+	for _, name := range c.RequiredOptions {
+		if opts[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
 }
 
+// RequestAmount converts minor, in code's registered minor units, to the
+// units the provider expects for code. It fails for unregistered currencies
+// and for amounts the provider's unit cannot represent exactly.
+func (c Capabilities) RequestAmount(minor int64, code string) (int64, error) {
+	cur, err := currency.Lookup(code)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", domainErrors.ErrInvalidCurrency, err)
+	}
+	units, ok := c.MinorUnits[code]
+	if !ok {
+		return minor, nil
+	}
+	amount, err := currency.Rescale(minor, cur.MinorUnits, units)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %v", domainErrors.ErrInvalidAmount, code, err)
+	}
+	return amount, nil
+}
+
+// CapabilityReporter is implemented by providers that lack some optional
+// feature. Providers that do not implement it support everything.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns p's declared capabilities.
+func CapabilitiesOf(p Provider) Capabilities {
+	if r, ok := p.(CapabilityReporter); ok {
+		return r.Capabilities()
+	}
+	return Capabilities{APIRefunds: true}
+}
+
+// ProcessRequest charges a payment. Providers must treat a repeated
+// IdempotencyKey as the same charge and return its original result.
 type ProcessRequest struct {
-	PaymentID   string
-	AmountCents int64 // in cents
-	Currency    string
-	Metadata    map[string]any
+	PaymentID      string
+	IdempotencyKey string
+	AmountCents    int64 // in the provider's minor units; see Capabilities.RequestAmount
+	Currency       string
+	Metadata       map[string]any
+	// ProviderOptions holds every provider's options for the payment; a
+	// provider reads its own namespace with ProviderOptions.For(name).
+	ProviderOptions payment.ProviderOptions
 }
 
+// RefundRequest refunds a charge. Providers must treat a repeated
+// IdempotencyKey as the same refund.
 type RefundRequest struct {
-	PaymentID     string
-	TransactionID string
-	AmountCents   int64 // in cents
-	Currency      string
+	PaymentID      string
+	IdempotencyKey string
+	TransactionID  string
+	AmountCents    int64 // in the provider's minor units; see Capabilities.RequestAmount
+	Currency       string
+}
+
+// StatusRequest identifies an earlier charge. Providers look it up by
+// TransactionID when set, and otherwise by PaymentID or the charge's
+// IdempotencyKey, so a charge whose result was lost can still be found.
+type StatusRequest struct {
+	PaymentID      string
+	IdempotencyKey string
+	TransactionID  string
 }
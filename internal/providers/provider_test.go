@@ -3,6 +3,7 @@ package providers
 import (
 	"testing"
 
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/cassiomorais/payments/internal/domain/payment"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -28,7 +29,7 @@ func TestNewFactory_WithCustomProviders(t *testing.T) {
 func TestFactory_Get_StripeProvider(t *testing.T) {
 	factory := NewFactory()
 
-	provider, breaker, err := factory.Get(payment.ProviderStripe)
+	provider, breaker, _, err := factory.Get(payment.ProviderStripe)
 	require.NoError(t, err)
 	assert.NotNil(t, provider)
 	assert.NotNil(t, breaker)
@@ -38,7 +39,7 @@ func TestFactory_Get_StripeProvider(t *testing.T) {
 func TestFactory_Get_PayPalProvider(t *testing.T) {
 	factory := NewFactory()
 
-	provider, breaker, err := factory.Get(payment.ProviderPayPal)
+	provider, breaker, _, err := factory.Get(payment.ProviderPayPal)
 	require.NoError(t, err)
 	assert.NotNil(t, provider)
 	assert.NotNil(t, breaker)
@@ -48,7 +49,7 @@ func TestFactory_Get_PayPalProvider(t *testing.T) {
 func TestFactory_Get_UnknownProvider_Error(t *testing.T) {
 	factory := NewFactory()
 
-	provider, breaker, err := factory.Get(payment.Provider("unknown"))
+	provider, breaker, _, err := factory.Get(payment.Provider("unknown"))
 	assert.Error(t, err)
 	assert.Nil(t, provider)
 	assert.Nil(t, breaker)
@@ -64,8 +65,65 @@ func TestFactory_Register(t *testing.T) {
 	assert.Contains(t, factory.providers, "custom")
 	assert.Contains(t, factory.circuitBreakers, "custom")
 
-	provider, breaker, err := factory.Get(payment.Provider("custom"))
+	provider, breaker, _, err := factory.Get(payment.Provider("custom"))
 	require.NoError(t, err)
 	assert.Equal(t, "custom", provider.Name())
 	assert.NotNil(t, breaker)
 }
+
+func TestFactory_Capabilities(t *testing.T) {
+	factory := NewFactory(NewMockProvider("stripe"), NewMockProvider("paypal", WithManualRefunds()), NewMockProvider("bank"))
+	factory.RequireManualRefunds("bank")
+
+	caps, err := factory.Capabilities(payment.ProviderStripe)
+	require.NoError(t, err)
+	assert.True(t, caps.APIRefunds)
+
+	caps, err = factory.Capabilities(payment.ProviderPayPal)
+	require.NoError(t, err)
+	assert.False(t, caps.APIRefunds, "provider-reported capability")
+
+	caps, err = factory.Capabilities(payment.Provider("bank"))
+	require.NoError(t, err)
+	assert.False(t, caps.APIRefunds, "configured override")
+
+	_, err = factory.Capabilities(payment.Provider("unknown"))
+	assert.Error(t, err)
+}
+
+func TestCapabilities_RequestAmount(t *testing.T) {
+	caps := CapabilitiesOf(NewMockProvider("bank", WithMinorUnits("HUF", 0), WithMinorUnits("JPY", 2)))
+
+	amount, err := caps.RequestAmount(1234, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), amount, "registered minor units pass through")
+
+	amount, err = caps.RequestAmount(1500, "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, int64(150000), amount)
+
+	amount, err = caps.RequestAmount(150000, "HUF")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), amount)
+
+	_, err = caps.RequestAmount(150050, "HUF")
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidAmount, "fractions the provider cannot take are rejected")
+
+	_, err = caps.RequestAmount(100, "XYZ")
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidCurrency)
+}
+
+func TestProviderResult_PaymentStatus(t *testing.T) {
+	for status, want := range map[string]payment.PaymentStatus{
+		ResultSuccess: payment.StatusCompleted,
+		ResultFailed:  payment.StatusFailed,
+		ResultPending: payment.StatusProcessing,
+	} {
+		got, ok := (&ProviderResult{Status: status}).PaymentStatus()
+		assert.True(t, ok, status)
+		assert.Equal(t, want, got, status)
+	}
+
+	_, ok := (&ProviderResult{Status: "chargeback_pending"}).PaymentStatus()
+	assert.False(t, ok, "unknown statuses do not map")
+}
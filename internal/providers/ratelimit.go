@@ -0,0 +1,103 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+)
+
+// RateLimit caps the requests sent to a provider: RPS per second on
+// average, with bursts of up to Burst. A zero RPS is unlimited; a zero
+// Burst allows one second's worth of requests at once.
+type RateLimit struct {
+	RPS   float64
+	Burst int
+}
+
+// Validate reports whether l is usable.
+func (l RateLimit) Validate() error {
+	if l.RPS < 0 || l.Burst < 0 || math.IsInf(l.RPS, 0) || math.IsNaN(l.RPS) {
+		return fmt.Errorf("rate limit must not be negative, got %+v", l)
+	}
+	return nil
+}
+
+// Limiter is a token bucket holding up to burst tokens and refilled at rate
+// per second. Each request takes a token, waiting for one if the bucket is
+// empty. A nil Limiter never waits.
+type Limiter struct {
+	name    string
+	rate    float64
+	burst   float64
+	metrics *observability.Metrics
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newLimiter returns the limiter for limit, or nil if it is unlimited.
+func newLimiter(name string, limit RateLimit, metrics *observability.Metrics) *Limiter {
+	if limit.RPS == 0 {
+		return nil
+	}
+	burst := float64(limit.Burst)
+	if burst == 0 {
+		burst = math.Max(1, math.Ceil(limit.RPS))
+	}
+	return &Limiter{name: name, rate: limit.RPS, burst: burst, metrics: metrics, tokens: burst, last: time.Now()}
+}
+
+// Wait takes a token, blocking until one is available. It gives up early
+// with ctx's error if ctx is done first, or would be before the token is.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	delay := l.reserve(time.Now())
+	if delay == 0 {
+		return nil
+	}
+
+	if l.metrics != nil {
+		l.metrics.ProviderThrottled.WithLabelValues(l.name).Inc()
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		l.cancel()
+		return fmt.Errorf("provider %s rate limited: %w", l.name, context.DeadlineExceeded)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token, possibly one not yet refilled, and returns how long
+// until it is.
+func (l *Limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token that was not used.
+func (l *Limiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
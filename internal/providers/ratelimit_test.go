@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_AllowsBurstThenWaits(t *testing.T) {
+	metrics := observability.NewMetrics("test", prometheus.NewRegistry())
+	l := newLimiter("stripe", RateLimit{RPS: 20, Burst: 2}, metrics)
+	ctx := context.Background()
+
+	start := time.Now()
+	require.NoError(t, l.Wait(ctx))
+	require.NoError(t, l.Wait(ctx))
+	assert.Less(t, time.Since(start), 20*time.Millisecond, "the burst is not throttled")
+
+	require.NoError(t, l.Wait(ctx))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond, "the third request waits for a refill")
+
+	var m dto.Metric
+	require.NoError(t, metrics.ProviderThrottled.WithLabelValues("stripe").Write(&m))
+	assert.Equal(t, 1.0, m.GetCounter().GetValue())
+}
+
+func TestLimiter_GivesUpBeforeDeadline(t *testing.T) {
+	l := newLimiter("stripe", RateLimit{RPS: 1, Burst: 1}, nil)
+	require.NoError(t, l.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Millisecond, "fails without waiting out the deadline")
+
+	// The token reserved by the failed wait was returned.
+	assert.InDelta(t, 0, l.tokens, 0.1)
+}
+
+func TestLimiter_CancelledWhileWaiting(t *testing.T) {
+	l := newLimiter("stripe", RateLimit{RPS: 1, Burst: 1}, nil)
+	require.NoError(t, l.Wait(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	assert.ErrorIs(t, l.Wait(ctx), context.Canceled)
+	assert.InDelta(t, 0, l.tokens, 0.1)
+}
+
+func TestLimiter_NilNeverWaits(t *testing.T) {
+	var l *Limiter
+	assert.NoError(t, l.Wait(context.Background()))
+	assert.Nil(t, newLimiter("stripe", RateLimit{}, nil))
+}
+
+func TestFactory_ConfigureRateLimits(t *testing.T) {
+	factory := NewFactory(NewMockProvider("stripe"), NewMockProvider("paypal"))
+	require.NoError(t, factory.ConfigureRateLimits(map[string]RateLimit{"stripe": {RPS: 5}}, nil))
+
+	_, _, limiter, err := factory.Get("stripe")
+	require.NoError(t, err)
+	require.NotNil(t, limiter)
+	assert.Equal(t, 5.0, limiter.burst, "burst defaults to one second's worth")
+
+	_, _, limiter, err = factory.Get("paypal")
+	require.NoError(t, err)
+	assert.Nil(t, limiter)
+
+	assert.Error(t, factory.ConfigureRateLimits(map[string]RateLimit{"adyen": {RPS: 5}}, nil))
+	assert.Error(t, factory.ConfigureRateLimits(map[string]RateLimit{"stripe": {RPS: -1}}, nil))
+}
@@ -2,15 +2,26 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/account"
 	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var allowedAccountSortColumns = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"balance":    "balance",
+	"currency":   "currency",
+}
+
 type AccountRepository struct {
 	pool *pgxpool.Pool
 }
@@ -28,8 +39,10 @@ func (r *AccountRepository) scanAccount(s scanner) (*account.Account, error) {
 	var (
 		status     string
 		balanceStr string
+		heldStr    string
+		initialStr string
 	)
-	err := s.Scan(&a.ID, &a.UserID, &balanceStr, &a.Currency, &a.Version, &status, &a.CreatedAt, &a.UpdatedAt)
+	err := s.Scan(&a.ID, &a.UserID, &balanceStr, &heldStr, &a.Currency, &a.Version, &status, &a.CreatedAt, &a.UpdatedAt, &a.Label, &initialStr)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, domainErrors.ErrAccountNotFound
@@ -42,41 +55,65 @@ func (r *AccountRepository) scanAccount(s scanner) (*account.Account, error) {
 		return nil, fmt.Errorf("parse balance: %w", err)
 	}
 	a.Balance = cents
+	if a.HeldBalance, err = numericStringToCents(heldStr); err != nil {
+		return nil, fmt.Errorf("parse held balance: %w", err)
+	}
+	if a.InitialBalance, err = numericStringToCents(initialStr); err != nil {
+		return nil, fmt.Errorf("parse initial balance: %w", err)
+	}
 	a.Status = account.AccountStatus(status)
 	return a, nil
 }
 
+// Create inserts a, returning ErrAccountAlreadyExists if the user already has
+// an account in its currency under its label. The conflict is resolved with
+// ON CONFLICT rather than a unique violation so it does not abort an
+// enclosing transaction, which may then re-read the existing account.
 func (r *AccountRepository) Create(ctx context.Context, a *account.Account) error {
 	balanceStr := centsToNumericString(a.Balance)
-	_, err := r.db(ctx).Exec(ctx,
-		`INSERT INTO accounts (id, user_id, balance, currency, version, status, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
-		a.ID, a.UserID, balanceStr, a.Currency, a.Version, string(a.Status), a.CreatedAt, a.UpdatedAt,
+	tag, err := r.db(ctx).Exec(ctx,
+		`INSERT INTO accounts (id, user_id, balance, currency, version, status, created_at, updated_at, label, initial_balance)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 ON CONFLICT ON CONSTRAINT unique_user_currency_label DO NOTHING`,
+		a.ID, a.UserID, balanceStr, a.Currency, a.Version, string(a.Status), a.CreatedAt, a.UpdatedAt, a.Label,
+		centsToNumericString(a.InitialBalance),
 	)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return domainErrors.ErrAccountAlreadyExists
+		}
 		return fmt.Errorf("insert account: %w", err)
 	}
+	if tag.RowsAffected() == 0 {
+		return domainErrors.ErrAccountAlreadyExists
+	}
 	return nil
 }
 
 func (r *AccountRepository) GetByID(ctx context.Context, id uuid.UUID) (*account.Account, error) {
-	return r.scanAccount(r.db(ctx).QueryRow(ctx,
-		`SELECT id, user_id, balance, currency, version, status, created_at, updated_at
-		 FROM accounts WHERE id = $1`, id))
+	return withReadRetry(ctx, "get account", func() (*account.Account, error) {
+		return r.scanAccount(r.db(ctx).QueryRow(ctx,
+			`SELECT id, user_id, balance, held_balance, currency, version, status, created_at, updated_at, label, initial_balance
+			 FROM accounts WHERE id = $1`, id))
+	})
 }
 
-func (r *AccountRepository) GetByUserID(ctx context.Context, userID string, currency string) (*account.Account, error) {
+func (r *AccountRepository) GetByUserID(ctx context.Context, userID, currency, label string) (*account.Account, error) {
 	return r.scanAccount(r.db(ctx).QueryRow(ctx,
-		`SELECT id, user_id, balance, currency, version, status, created_at, updated_at
-		 FROM accounts WHERE user_id = $1 AND currency = $2`, userID, currency))
+		`SELECT id, user_id, balance, held_balance, currency, version, status, created_at, updated_at, label, initial_balance
+		 FROM accounts WHERE user_id = $1 AND currency = $2 AND label = $3`, userID, currency, label))
 }
 
 func (r *AccountRepository) Update(ctx context.Context, a *account.Account) error {
+	if err := requireTx(ctx, "update account"); err != nil {
+		return err
+	}
 	balanceStr := centsToNumericString(a.Balance)
 	tag, err := r.db(ctx).Exec(ctx,
-		`UPDATE accounts SET balance = $1, currency = $2, version = $3, status = $4, updated_at = $5
-		 WHERE id = $6 AND version = $7`,
-		balanceStr, a.Currency, a.Version, string(a.Status), a.UpdatedAt, a.ID, a.Version-1,
+		`UPDATE accounts SET balance = $1, held_balance = $2, currency = $3, version = $4, status = $5, updated_at = $6
+		 WHERE id = $7 AND version = $8`,
+		balanceStr, centsToNumericString(a.HeldBalance), a.Currency, a.Version, string(a.Status), a.UpdatedAt, a.ID, a.Version-1,
 	)
 	if err != nil {
 		return fmt.Errorf("update account: %w", err)
@@ -88,6 +125,9 @@ func (r *AccountRepository) Update(ctx context.Context, a *account.Account) erro
 }
 
 func (r *AccountRepository) AddTransaction(ctx context.Context, tx *account.Transaction) error {
+	if err := requireTx(ctx, "insert account transaction"); err != nil {
+		return err
+	}
 	amountStr := centsToNumericString(tx.Amount)
 	balanceAfterStr := centsToNumericString(tx.BalanceAfter)
 	_, err := r.db(ctx).Exec(ctx,
@@ -113,37 +153,228 @@ func (r *AccountRepository) GetTransactions(ctx context.Context, accountID uuid.
 	if err != nil {
 		return nil, fmt.Errorf("list transactions: %w", err)
 	}
+	return scanTransactions(rows)
+}
+
+func (r *AccountRepository) GetAllTransactions(ctx context.Context, accountID uuid.UUID) ([]*account.Transaction, error) {
+	rows, err := r.db(ctx).Query(ctx,
+		`SELECT id, account_id, payment_id, transaction_type, amount, balance_after, description, created_at
+		 FROM account_transactions WHERE account_id = $1 ORDER BY created_at ASC, id ASC`,
+		accountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list all transactions: %w", err)
+	}
+	return scanTransactions(rows)
+}
+
+func (r *AccountRepository) GetTransactionsByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*account.Transaction, error) {
+	rows, err := r.db(ctx).Query(ctx,
+		`SELECT id, account_id, payment_id, transaction_type, amount, balance_after, description, created_at
+		 FROM account_transactions WHERE payment_id = $1 ORDER BY created_at ASC, id ASC`,
+		paymentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list payment transactions: %w", err)
+	}
+	return scanTransactions(rows)
+}
+
+func (r *AccountRepository) EachTransactionBetween(ctx context.Context, accountID uuid.UUID, from, to time.Time, fn func(*account.Transaction) error) error {
+	rows, err := r.db(ctx).Query(ctx,
+		`SELECT id, account_id, payment_id, transaction_type, amount, balance_after, description, created_at
+		 FROM account_transactions WHERE account_id = $1 AND created_at >= $2 AND created_at < $3
+		 ORDER BY created_at ASC, id ASC`,
+		accountID, from, to,
+	)
+	if err != nil {
+		return fmt.Errorf("list transactions: %w", err)
+	}
 	defer rows.Close()
 
-	var txns []*account.Transaction
 	for rows.Next() {
-		tx := &account.Transaction{}
-		var (
-			txType          string
-			amountStr       string
-			balanceAfterStr string
-		)
-		if err := rows.Scan(&tx.ID, &tx.AccountID, &tx.PaymentID, &txType, &amountStr, &balanceAfterStr, &tx.Description, &tx.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan transaction: %w", err)
-		}
-		tx.TransactionType = account.TransactionType(txType)
-		cents, err := numericStringToCents(amountStr)
+		tx, err := scanTransaction(rows)
 		if err != nil {
-			return nil, fmt.Errorf("parse transaction amount: %w", err)
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
 		}
-		tx.Amount = cents
-		balCents, err := numericStringToCents(balanceAfterStr)
+	}
+	return rows.Err()
+}
+
+func scanTransactions(rows pgx.Rows) ([]*account.Transaction, error) {
+	defer rows.Close()
+
+	var txns []*account.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
 		if err != nil {
-			return nil, fmt.Errorf("parse balance_after: %w", err)
+			return nil, err
 		}
-		tx.BalanceAfter = balCents
 		txns = append(txns, tx)
 	}
 	return txns, rows.Err()
 }
 
+func scanTransaction(rows pgx.Rows) (*account.Transaction, error) {
+	tx := &account.Transaction{}
+	var (
+		txType          string
+		amountStr       string
+		balanceAfterStr string
+	)
+	if err := rows.Scan(&tx.ID, &tx.AccountID, &tx.PaymentID, &txType, &amountStr, &balanceAfterStr, &tx.Description, &tx.CreatedAt); err != nil {
+		return nil, fmt.Errorf("scan transaction: %w", err)
+	}
+	tx.TransactionType = account.TransactionType(txType)
+	cents, err := numericStringToCents(amountStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse transaction amount: %w", err)
+	}
+	tx.Amount = cents
+	balCents, err := numericStringToCents(balanceAfterStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse balance_after: %w", err)
+	}
+	tx.BalanceAfter = balCents
+	return tx, nil
+}
+
 func (r *AccountRepository) Lock(ctx context.Context, id uuid.UUID) (*account.Account, error) {
+	if err := requireTx(ctx, "lock account"); err != nil {
+		return nil, err
+	}
 	return r.scanAccount(r.db(ctx).QueryRow(ctx,
-		`SELECT id, user_id, balance, currency, version, status, created_at, updated_at
+		`SELECT id, user_id, balance, held_balance, currency, version, status, created_at, updated_at, label, initial_balance
 		 FROM accounts WHERE id = $1 FOR UPDATE`, id))
 }
+
+func (r *AccountRepository) List(ctx context.Context, f account.ListFilter) ([]*account.Account, error) {
+	where, args := accountFilterWhere(f)
+	query := `SELECT id, user_id, balance, held_balance, currency, version, status, created_at, updated_at, label, initial_balance
+		 FROM accounts WHERE 1=1` + where
+
+	// Strict whitelist for sort column
+	sortBy := "created_at"
+	if col, ok := allowedAccountSortColumns[f.SortBy]; ok {
+		sortBy = col
+	}
+	sortOrder := "DESC"
+	if strings.EqualFold(f.SortOrder, "asc") {
+		sortOrder = "ASC"
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id LIMIT $%d OFFSET $%d", sortBy, sortOrder, len(args)+1, len(args)+2)
+	args = append(args, f.PageLimit(), f.Offset)
+
+	return withReadRetry(ctx, "list accounts", func() ([]*account.Account, error) {
+		rows, err := r.db(ctx).Query(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("list accounts: %w", err)
+		}
+		defer rows.Close()
+
+		var accounts []*account.Account
+		for rows.Next() {
+			a, err := r.scanAccount(rows)
+			if err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, a)
+		}
+		return accounts, rows.Err()
+	})
+}
+
+// Count counts the accounts List would return across all pages.
+func (r *AccountRepository) Count(ctx context.Context, f account.ListFilter) (int, error) {
+	where, args := accountFilterWhere(f)
+	return withReadRetry(ctx, "count accounts", func() (int, error) {
+		var count int
+		if err := r.db(ctx).QueryRow(ctx, `SELECT COUNT(*) FROM accounts WHERE 1=1`+where, args...).Scan(&count); err != nil {
+			return 0, fmt.Errorf("count accounts: %w", err)
+		}
+		return count, nil
+	})
+}
+
+// accountFilterWhere builds the conditions of f, to append to "WHERE 1=1",
+// and their arguments.
+func accountFilterWhere(f account.ListFilter) (string, []any) {
+	var where strings.Builder
+	var args []any
+	if f.UserID != nil {
+		args = append(args, *f.UserID)
+		fmt.Fprintf(&where, " AND user_id = $%d", len(args))
+	}
+	if f.Currency != nil {
+		args = append(args, *f.Currency)
+		fmt.Fprintf(&where, " AND currency = $%d", len(args))
+	}
+	if f.Status != nil {
+		args = append(args, string(*f.Status))
+		fmt.Fprintf(&where, " AND status = $%d", len(args))
+	}
+	return where.String(), args
+}
+
+// CreateHold inserts h. It must run in the transaction that adds the hold to
+// the account's held balance.
+func (r *AccountRepository) CreateHold(ctx context.Context, h *account.FundsHold) error {
+	if err := requireTx(ctx, "insert account hold"); err != nil {
+		return err
+	}
+	_, err := r.db(ctx).Exec(ctx,
+		`INSERT INTO account_holds (id, account_id, payment_id, amount, status, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		h.ID, h.AccountID, h.PaymentID, centsToNumericString(h.Amount), string(h.Status), h.CreatedAt, h.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert account hold: %w", err)
+	}
+	return nil
+}
+
+func (r *AccountRepository) GetActiveHold(ctx context.Context, paymentID uuid.UUID) (*account.FundsHold, error) {
+	h := &account.FundsHold{}
+	var (
+		status    string
+		amountStr string
+	)
+	err := r.db(ctx).QueryRow(ctx,
+		`SELECT id, account_id, payment_id, amount, status, created_at, updated_at
+		 FROM account_holds WHERE payment_id = $1 AND status = $2`,
+		paymentID, string(account.HoldActive),
+	).Scan(&h.ID, &h.AccountID, &h.PaymentID, &amountStr, &status, &h.CreatedAt, &h.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get account hold: %w", err)
+	}
+	if h.Amount, err = numericStringToCents(amountStr); err != nil {
+		return nil, fmt.Errorf("parse hold amount: %w", err)
+	}
+	h.Status = account.HoldStatus(status)
+	return h, nil
+}
+
+// UpdateHold settles an active hold, returning ErrInvalidStateTransition if
+// it was settled concurrently.
+func (r *AccountRepository) UpdateHold(ctx context.Context, h *account.FundsHold) error {
+	if err := requireTx(ctx, "update account hold"); err != nil {
+		return err
+	}
+	tag, err := r.db(ctx).Exec(ctx,
+		`UPDATE account_holds SET status = $1, updated_at = $2 WHERE id = $3 AND status = $4`,
+		string(h.Status), h.UpdatedAt, h.ID, string(account.HoldActive),
+	)
+	if err != nil {
+		return fmt.Errorf("update account hold: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domainErrors.ErrInvalidStateTransition
+	}
+	return nil
+}
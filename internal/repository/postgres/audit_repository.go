@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cassiomorais/payments/internal/domain/audit"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// auditColumns is the column list of audit_log, in scan and insert order.
+const auditColumns = `id, user_id, operation, target_id, source_ip, method, path, status_code, result, request_id, created_at`
+
+type AuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditRepository(pool *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{pool: pool}
+}
+
+func (r *AuditRepository) db(ctx context.Context) DBTX {
+	return ConnFromCtx(ctx, r.pool)
+}
+
+// InsertBatch writes entries with a single multi-row INSERT.
+func (r *AuditRepository) InsertBatch(ctx context.Context, entries []*audit.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var query strings.Builder
+	query.WriteString(`INSERT INTO audit_log (` + auditColumns + `) VALUES `)
+	args := make([]any, 0, len(entries)*11)
+	for i, e := range entries {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		n := len(args)
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11)
+		args = append(args, e.ID, e.UserID, e.Operation, e.TargetID, e.SourceIP, e.Method, e.Path,
+			e.StatusCode, string(e.Result), e.RequestID, e.CreatedAt)
+	}
+	if _, err := r.db(ctx).Exec(ctx, query.String(), args...); err != nil {
+		return fmt.Errorf("insert audit entries: %w", err)
+	}
+	return nil
+}
+
+func (r *AuditRepository) List(ctx context.Context, f audit.ListFilter) ([]*audit.Entry, error) {
+	where, args := auditFilterWhere(f)
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE 1=1` + where +
+		fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, f.PageLimit(), f.Offset)
+
+	return withReadRetry(ctx, "list audit entries", func() ([]*audit.Entry, error) {
+		rows, err := r.db(ctx).Query(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("list audit entries: %w", err)
+		}
+		defer rows.Close()
+
+		var entries []*audit.Entry
+		for rows.Next() {
+			e := &audit.Entry{}
+			var result string
+			if err := rows.Scan(&e.ID, &e.UserID, &e.Operation, &e.TargetID, &e.SourceIP, &e.Method, &e.Path,
+				&e.StatusCode, &result, &e.RequestID, &e.CreatedAt); err != nil {
+				return nil, fmt.Errorf("scan audit entry: %w", err)
+			}
+			e.Result = audit.Result(result)
+			entries = append(entries, e)
+		}
+		return entries, rows.Err()
+	})
+}
+
+// Count counts the entries List would return across all pages.
+func (r *AuditRepository) Count(ctx context.Context, f audit.ListFilter) (int, error) {
+	where, args := auditFilterWhere(f)
+	return withReadRetry(ctx, "count audit entries", func() (int, error) {
+		var count int
+		if err := r.db(ctx).QueryRow(ctx, `SELECT COUNT(*) FROM audit_log WHERE 1=1`+where, args...).Scan(&count); err != nil {
+			return 0, fmt.Errorf("count audit entries: %w", err)
+		}
+		return count, nil
+	})
+}
+
+// auditFilterWhere builds the conditions of f, to append to "WHERE 1=1", and
+// their arguments.
+func auditFilterWhere(f audit.ListFilter) (string, []any) {
+	var where strings.Builder
+	args := []any{}
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		fmt.Fprintf(&where, " AND "+cond, len(args))
+	}
+
+	if f.UserID != nil {
+		add("user_id = $%d", *f.UserID)
+	}
+	if f.Operation != nil {
+		add("operation = $%d", *f.Operation)
+	}
+	if f.TargetID != nil {
+		add("target_id = $%d", *f.TargetID)
+	}
+	if f.Result != nil {
+		add("result = $%d", string(*f.Result))
+	}
+	if f.CreatedAfter != nil {
+		add("created_at >= $%d", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		add("created_at <= $%d", *f.CreatedBefore)
+	}
+	return where.String(), args
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DisputeRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewDisputeRepository(pool *pgxpool.Pool) *DisputeRepository {
+	return &DisputeRepository{pool: pool}
+}
+
+func (r *DisputeRepository) db(ctx context.Context) DBTX {
+	return ConnFromCtx(ctx, r.pool)
+}
+
+const disputeColumns = `id, payment_id, provider, provider_dispute_id, reason, amount, currency, status,
+		        funds_reversed, created_at, updated_at, resolved_at`
+
+func (r *DisputeRepository) Create(ctx context.Context, d *payment.Dispute) error {
+	_, err := r.db(ctx).Exec(ctx,
+		`INSERT INTO disputes (`+disputeColumns+`)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`,
+		d.ID, d.PaymentID, string(d.Provider), d.ProviderDisputeID, d.Reason,
+		centsToNumericString(d.AmountCents), d.Currency, string(d.Status),
+		d.FundsReversed, d.CreatedAt, d.UpdatedAt, d.ResolvedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert dispute: %w", err)
+	}
+	return nil
+}
+
+func (r *DisputeRepository) Update(ctx context.Context, d *payment.Dispute) error {
+	tag, err := r.db(ctx).Exec(ctx,
+		`UPDATE disputes SET status=$1, funds_reversed=$2, updated_at=$3, resolved_at=$4 WHERE id=$5`,
+		string(d.Status), d.FundsReversed, d.UpdatedAt, d.ResolvedAt, d.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update dispute: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domainErrors.ErrDisputeNotFound
+	}
+	return nil
+}
+
+func (r *DisputeRepository) GetByProviderID(ctx context.Context, provider payment.Provider, providerDisputeID string) (*payment.Dispute, error) {
+	return r.scanDispute(r.db(ctx).QueryRow(ctx,
+		`SELECT `+disputeColumns+` FROM disputes WHERE provider = $1 AND provider_dispute_id = $2`,
+		string(provider), providerDisputeID))
+}
+
+func (r *DisputeRepository) ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]*payment.Dispute, error) {
+	rows, err := r.db(ctx).Query(ctx,
+		`SELECT `+disputeColumns+` FROM disputes WHERE payment_id = $1 ORDER BY created_at ASC`, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("list disputes: %w", err)
+	}
+	defer rows.Close()
+
+	var disputes []*payment.Dispute
+	for rows.Next() {
+		d, err := r.scanDispute(rows)
+		if err != nil {
+			return nil, err
+		}
+		disputes = append(disputes, d)
+	}
+	return disputes, rows.Err()
+}
+
+func (r *DisputeRepository) scanDispute(s scanner) (*payment.Dispute, error) {
+	d := &payment.Dispute{}
+	var (
+		provider  string
+		reason    *string
+		amountStr string
+		status    string
+	)
+	err := s.Scan(
+		&d.ID, &d.PaymentID, &provider, &d.ProviderDisputeID, &reason, &amountStr, &d.Currency, &status,
+		&d.FundsReversed, &d.CreatedAt, &d.UpdatedAt, &d.ResolvedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, domainErrors.ErrDisputeNotFound
+		}
+		return nil, fmt.Errorf("scan dispute: %w", err)
+	}
+
+	cents, err := numericStringToCents(amountStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse dispute amount: %w", err)
+	}
+	d.AmountCents = cents
+	d.Provider = payment.Provider(provider)
+	d.Status = payment.DisputeStatus(status)
+	if reason != nil {
+		d.Reason = *reason
+	}
+	return d, nil
+}
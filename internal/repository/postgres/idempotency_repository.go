@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -48,7 +49,8 @@ func (r *IdempotencyRepository) Set(ctx context.Context, entry *IdempotencyEntry
 	_, err := r.db(ctx).Exec(ctx,
 		`INSERT INTO idempotency_keys (key, response_body, response_status, created_at, expires_at)
 		 VALUES ($1, $2, $3, $4, $5)
-		 ON CONFLICT (key) DO UPDATE SET response_body = EXCLUDED.response_body, response_status = EXCLUDED.response_status`,
+		 ON CONFLICT (key) DO UPDATE SET response_body = EXCLUDED.response_body, response_status = EXCLUDED.response_status,
+		 created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at`,
 		entry.Key, entry.ResponseBody, entry.ResponseStatus, entry.CreatedAt, entry.ExpiresAt,
 	)
 	if err != nil {
@@ -57,10 +59,52 @@ func (r *IdempotencyRepository) Set(ctx context.Context, entry *IdempotencyEntry
 	return nil
 }
 
-func (r *IdempotencyRepository) Cleanup(ctx context.Context) (int64, error) {
-	tag, err := r.db(ctx).Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+// DeleteExpired deletes entries created before olderThan, along with any
+// whose own expiry has passed, and returns how many it deleted.
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, olderThan time.Time) (int64, error) {
+	tag, err := r.db(ctx).Exec(ctx,
+		`DELETE FROM idempotency_keys WHERE created_at < $1 OR expires_at < NOW()`, olderThan)
 	if err != nil {
-		return 0, fmt.Errorf("cleanup idempotency keys: %w", err)
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", err)
 	}
 	return tag.RowsAffected(), nil
 }
+
+// ProcessingStore keeps worker processing keys in idempotency_keys. A key is
+// stored with status 202 when processing starts and 200, with the provider
+// transaction ID as the body, once the provider has accepted the charge.
+type ProcessingStore struct {
+	repo *IdempotencyRepository
+	ttl  time.Duration
+}
+
+func NewProcessingStore(repo *IdempotencyRepository, ttl time.Duration) *ProcessingStore {
+	return &ProcessingStore{repo: repo, ttl: ttl}
+}
+
+func (s *ProcessingStore) Start(ctx context.Context, key string) error {
+	return s.set(ctx, key, http.StatusAccepted, "")
+}
+
+func (s *ProcessingStore) Complete(ctx context.Context, key, providerTxID string) error {
+	return s.set(ctx, key, http.StatusOK, providerTxID)
+}
+
+func (s *ProcessingStore) Completed(ctx context.Context, key string) (string, bool, error) {
+	e, err := s.repo.Get(ctx, key)
+	if err != nil || e == nil || e.ResponseStatus != http.StatusOK {
+		return "", false, err
+	}
+	return e.ResponseBody, true, nil
+}
+
+func (s *ProcessingStore) set(ctx context.Context, key string, status int, body string) error {
+	now := time.Now()
+	return s.repo.Set(ctx, &IdempotencyEntry{
+		Key:            key,
+		ResponseBody:   body,
+		ResponseStatus: status,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(s.ttl),
+	})
+}
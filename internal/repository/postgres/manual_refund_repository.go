@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ManualRefundRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewManualRefundRepository(pool *pgxpool.Pool) *ManualRefundRepository {
+	return &ManualRefundRepository{pool: pool}
+}
+
+func (r *ManualRefundRepository) db(ctx context.Context) DBTX {
+	return ConnFromCtx(ctx, r.pool)
+}
+
+func (r *ManualRefundRepository) Create(ctx context.Context, m *payment.ManualRefund) error {
+	_, err := r.db(ctx).Exec(ctx,
+		`INSERT INTO manual_refunds
+		 (id, payment_id, provider, provider_transaction_id, amount, currency, status, created_at, completed_at)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		 ON CONFLICT (payment_id) DO NOTHING`,
+		m.ID, m.PaymentID, string(m.Provider), m.ProviderTransactionID,
+		centsToNumericString(m.AmountCents), m.Currency, string(m.Status), m.CreatedAt, m.CompletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert manual refund: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS fee_account_id,
+    DROP COLUMN IF EXISTS fee;
@@ -0,0 +1,4 @@
+-- Transfer fees: charged to the source on top of the amount and credited to fee_account_id
+ALTER TABLE payments
+    ADD COLUMN fee NUMERIC(19, 4) NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    ADD COLUMN fee_account_id UUID REFERENCES accounts(id);
@@ -0,0 +1,8 @@
+ALTER TABLE webhook_deliveries
+    DROP COLUMN IF EXISTS event_count;
+
+ALTER TABLE webhooks
+    DROP CONSTRAINT IF EXISTS check_webhook_delivery_mode,
+    DROP COLUMN IF EXISTS digest_max_size,
+    DROP COLUMN IF EXISTS digest_window_seconds,
+    DROP COLUMN IF EXISTS delivery_mode;
@@ -0,0 +1,11 @@
+-- Webhook delivery modes: per_event (one POST per event) or digest (batched per window)
+ALTER TABLE webhooks
+    ADD COLUMN delivery_mode VARCHAR(20) NOT NULL DEFAULT 'per_event',
+    ADD COLUMN digest_window_seconds INT,
+    ADD COLUMN digest_max_size INT,
+    ADD CONSTRAINT check_webhook_delivery_mode CHECK (delivery_mode IN ('per_event', 'digest'));
+
+-- A digest delivery covers several payments, so payment_id stays NULL and the
+-- delivered event count is recorded instead.
+ALTER TABLE webhook_deliveries
+    ADD COLUMN event_count INT NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS disputes;
+
+ALTER TABLE payments DROP CONSTRAINT check_status;
+ALTER TABLE payments ADD CONSTRAINT check_status CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'cancelled', 'refunded'));
@@ -0,0 +1,22 @@
+-- Provider-initiated disputes (chargebacks)
+ALTER TABLE payments DROP CONSTRAINT check_status;
+ALTER TABLE payments ADD CONSTRAINT check_status CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'cancelled', 'refunded', 'disputed', 'charged_back'));
+
+CREATE TABLE disputes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_id UUID NOT NULL REFERENCES payments(id),
+    provider VARCHAR(50) NOT NULL,
+    provider_dispute_id VARCHAR(255) NOT NULL,
+    reason TEXT,
+    amount NUMERIC(19, 4) NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    funds_reversed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP,
+
+    CONSTRAINT unique_provider_dispute UNIQUE (provider, provider_dispute_id),
+    CONSTRAINT check_dispute_status CHECK (status IN ('open', 'won', 'lost'))
+);
+
+CREATE INDEX idx_disputes_payment_id ON disputes(payment_id);
@@ -0,0 +1,2 @@
+-- Supports retention cleanup of published outbox entries
+CREATE INDEX idx_outbox_published_at ON outbox(published_at) WHERE status = 'published';
@@ -0,0 +1,2 @@
+-- Human-readable purpose of a payment, also used for its transaction descriptions
+ALTER TABLE payments ADD COLUMN description VARCHAR(255) NOT NULL DEFAULT '';
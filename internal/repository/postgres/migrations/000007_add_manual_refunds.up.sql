@@ -0,0 +1,17 @@
+-- Refunds ops must process by hand for providers without API refund support
+CREATE TABLE manual_refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_id UUID NOT NULL REFERENCES payments(id),
+    provider VARCHAR(50) NOT NULL,
+    provider_transaction_id VARCHAR(255),
+    amount NUMERIC(19, 4) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP,
+
+    CONSTRAINT unique_manual_refund_payment UNIQUE (payment_id),
+    CONSTRAINT check_manual_refund_status CHECK (status IN ('pending', 'completed'))
+);
+
+CREATE INDEX idx_manual_refunds_status ON manual_refunds(status);
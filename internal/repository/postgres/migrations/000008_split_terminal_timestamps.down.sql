@@ -0,0 +1,7 @@
+UPDATE payments SET completed_at = COALESCE(completed_at, failed_at, cancelled_at)
+ WHERE status IN ('failed', 'cancelled');
+
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS refunded_at,
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS failed_at;
@@ -0,0 +1,11 @@
+-- completed_at used to be set on failure and cancellation too; give each
+-- terminal outcome its own timestamp and keep completed_at for success only.
+ALTER TABLE payments
+    ADD COLUMN failed_at TIMESTAMP,
+    ADD COLUMN cancelled_at TIMESTAMP,
+    ADD COLUMN refunded_at TIMESTAMP;
+
+UPDATE payments SET failed_at = completed_at, completed_at = NULL WHERE status = 'failed';
+UPDATE payments SET cancelled_at = completed_at, completed_at = NULL WHERE status = 'cancelled';
+-- The refund time was not recorded; the last update is the best estimate.
+UPDATE payments SET refunded_at = updated_at WHERE status = 'refunded';
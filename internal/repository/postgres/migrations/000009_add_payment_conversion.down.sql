@@ -0,0 +1,4 @@
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS credited_currency,
+    DROP COLUMN IF EXISTS credited_amount,
+    DROP COLUMN IF EXISTS exchange_rate;
@@ -0,0 +1,6 @@
+-- Cross-currency internal transfers: the destination is credited
+-- credited_amount in credited_currency, converted at exchange_rate
+ALTER TABLE payments
+    ADD COLUMN exchange_rate NUMERIC CHECK (exchange_rate > 0),
+    ADD COLUMN credited_amount NUMERIC(19, 4) CHECK (credited_amount > 0),
+    ADD COLUMN credited_currency VARCHAR(3);
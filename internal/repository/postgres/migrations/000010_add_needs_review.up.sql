@@ -0,0 +1,3 @@
+-- Payments held for a human decision on an ambiguous provider outcome
+ALTER TABLE payments DROP CONSTRAINT check_status;
+ALTER TABLE payments ADD CONSTRAINT check_status CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'cancelled', 'refunded', 'disputed', 'charged_back', 'needs_review'));
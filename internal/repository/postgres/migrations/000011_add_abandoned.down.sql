@@ -0,0 +1,3 @@
+UPDATE payments SET status = 'failed' WHERE status = 'abandoned';
+ALTER TABLE payments DROP CONSTRAINT check_status;
+ALTER TABLE payments ADD CONSTRAINT check_status CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'cancelled', 'refunded', 'disputed', 'charged_back', 'needs_review'));
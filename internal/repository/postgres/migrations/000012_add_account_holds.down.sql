@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS account_holds;
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS check_held_within_balance;
+ALTER TABLE accounts DROP COLUMN IF EXISTS held_balance;
@@ -0,0 +1,20 @@
+-- Two-phase reservation of funds: a hold reduces the available balance
+-- (balance - held_balance) until it is captured as a debit or released
+ALTER TABLE accounts ADD COLUMN held_balance NUMERIC(19, 4) NOT NULL DEFAULT 0 CHECK (held_balance >= 0);
+ALTER TABLE accounts ADD CONSTRAINT check_held_within_balance CHECK (held_balance <= balance);
+
+CREATE TABLE account_holds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_id UUID NOT NULL REFERENCES accounts(id),
+    payment_id UUID NOT NULL REFERENCES payments(id),
+    amount NUMERIC(19, 4) NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'held',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_hold_status CHECK (status IN ('held', 'captured', 'released'))
+);
+
+-- A payment holds funds at most once at a time
+CREATE UNIQUE INDEX idx_account_holds_active_payment ON account_holds(payment_id) WHERE status = 'held';
+CREATE INDEX idx_account_holds_account_id ON account_holds(account_id);
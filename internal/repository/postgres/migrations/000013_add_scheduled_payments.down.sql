@@ -0,0 +1,5 @@
+UPDATE payments SET status = 'cancelled', cancelled_at = NOW() WHERE status = 'scheduled';
+DROP INDEX IF EXISTS idx_payments_scheduled_due;
+ALTER TABLE payments DROP CONSTRAINT check_status;
+ALTER TABLE payments ADD CONSTRAINT check_status CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'cancelled', 'refunded', 'disputed', 'charged_back', 'needs_review', 'abandoned'));
+ALTER TABLE payments DROP COLUMN IF EXISTS scheduled_at;
@@ -0,0 +1,6 @@
+-- Future-dated payments wait in 'scheduled' until scheduled_at
+ALTER TABLE payments ADD COLUMN scheduled_at TIMESTAMP;
+ALTER TABLE payments DROP CONSTRAINT check_status;
+ALTER TABLE payments ADD CONSTRAINT check_status CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'cancelled', 'refunded', 'disputed', 'charged_back', 'needs_review', 'abandoned', 'scheduled'));
+
+CREATE INDEX idx_payments_scheduled_due ON payments(scheduled_at) WHERE status = 'scheduled';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_payments_batch_id;
+ALTER TABLE payments DROP COLUMN IF EXISTS batch_id;
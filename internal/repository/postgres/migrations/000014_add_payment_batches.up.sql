@@ -0,0 +1,4 @@
+-- Payments submitted together through the batch endpoint share a batch_id
+ALTER TABLE payments ADD COLUMN batch_id UUID;
+
+CREATE INDEX idx_payments_batch_id ON payments(batch_id) WHERE batch_id IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Provider-specific request fields (e.g. a Stripe customer ID), keyed by provider name
+ALTER TABLE payments ADD COLUMN provider_options JSONB;
@@ -0,0 +1,2 @@
+-- Earliest time a failed payment may be retried (exponential backoff)
+ALTER TABLE payments ADD COLUMN next_retry_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+-- Supports looking payments up by the transaction ID their provider assigned
+CREATE INDEX idx_payments_provider_transaction_id ON payments(provider_transaction_id) WHERE provider_transaction_id IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_idempotency_keys_created_at;
+DROP INDEX IF EXISTS idx_payments_idempotency_key_live;
+-- Fails if an expired key has since been reused by a newer payment.
+ALTER TABLE payments ADD CONSTRAINT payments_idempotency_key_key UNIQUE (idempotency_key);
+ALTER TABLE payments DROP COLUMN IF EXISTS idempotency_key_expired;
@@ -0,0 +1,8 @@
+-- A payment's idempotency key expires after worker.idempotency_ttl; the key
+-- is then only unique among payments that still hold it.
+ALTER TABLE payments ADD COLUMN idempotency_key_expired BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE payments DROP CONSTRAINT payments_idempotency_key_key;
+CREATE UNIQUE INDEX idx_payments_idempotency_key_live ON payments(idempotency_key) WHERE NOT idempotency_key_expired;
+
+-- Supports purging idempotency entries by age
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys(created_at);
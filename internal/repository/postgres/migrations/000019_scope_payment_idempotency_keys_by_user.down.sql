@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_payments_user_idempotency_key_live;
+-- Fails if two users hold the same live key.
+CREATE UNIQUE INDEX idx_payments_idempotency_key_live ON payments(idempotency_key) WHERE NOT idempotency_key_expired;
+ALTER TABLE payments DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,8 @@
+-- Idempotency keys are unique per authenticated user rather than globally.
+ALTER TABLE payments ADD COLUMN user_id VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Attribute existing payments to their source account's owner
+UPDATE payments p SET user_id = a.user_id FROM accounts a WHERE a.id = p.source_account_id;
+
+DROP INDEX IF EXISTS idx_payments_idempotency_key_live;
+CREATE UNIQUE INDEX idx_payments_user_idempotency_key_live ON payments(user_id, idempotency_key) WHERE NOT idempotency_key_expired;
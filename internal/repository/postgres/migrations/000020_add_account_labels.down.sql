@@ -0,0 +1,4 @@
+-- Fails if a user holds several accounts in one currency.
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS unique_user_currency_label;
+ALTER TABLE accounts ADD CONSTRAINT unique_user_currency UNIQUE (user_id, currency);
+ALTER TABLE accounts DROP COLUMN IF EXISTS label;
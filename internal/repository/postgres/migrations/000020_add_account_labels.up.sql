@@ -0,0 +1,6 @@
+-- A user may hold several accounts in one currency, told apart by label.
+-- The empty label is the user's default account in that currency.
+ALTER TABLE accounts ADD COLUMN label VARCHAR(64) NOT NULL DEFAULT '';
+
+ALTER TABLE accounts DROP CONSTRAINT unique_user_currency;
+ALTER TABLE accounts ADD CONSTRAINT unique_user_currency_label UNIQUE (user_id, currency, label);
@@ -0,0 +1,2 @@
+-- Roles claim carried over when a refresh token is exchanged.
+ALTER TABLE refresh_tokens ADD COLUMN roles TEXT[] NOT NULL DEFAULT '{}';
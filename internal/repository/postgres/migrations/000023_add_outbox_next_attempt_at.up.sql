@@ -0,0 +1,4 @@
+-- Earliest time a pending outbox entry may be published (exponential backoff)
+ALTER TABLE outbox ADD COLUMN next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE INDEX idx_outbox_next_attempt_at ON outbox(next_attempt_at) WHERE status = 'pending';
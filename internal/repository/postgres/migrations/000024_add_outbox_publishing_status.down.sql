@@ -0,0 +1,7 @@
+UPDATE outbox SET status = 'pending' WHERE status = 'publishing';
+
+DROP INDEX IF EXISTS idx_outbox_next_attempt_at;
+CREATE INDEX idx_outbox_next_attempt_at ON outbox(next_attempt_at) WHERE status = 'pending';
+
+ALTER TABLE outbox DROP CONSTRAINT check_outbox_status;
+ALTER TABLE outbox ADD CONSTRAINT check_outbox_status CHECK (status IN ('pending', 'published', 'failed'));
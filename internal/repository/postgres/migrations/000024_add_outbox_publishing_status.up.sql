@@ -0,0 +1,7 @@
+-- Entries claimed by a worker are 'publishing' until marked; next_attempt_at
+-- then holds the time the claim lapses
+ALTER TABLE outbox DROP CONSTRAINT check_outbox_status;
+ALTER TABLE outbox ADD CONSTRAINT check_outbox_status CHECK (status IN ('pending', 'publishing', 'published', 'failed'));
+
+DROP INDEX IF EXISTS idx_outbox_next_attempt_at;
+CREATE INDEX idx_outbox_next_attempt_at ON outbox(next_attempt_at) WHERE status IN ('pending', 'publishing');
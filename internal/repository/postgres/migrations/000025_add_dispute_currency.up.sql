@@ -0,0 +1,4 @@
+-- Disputes carry their payment's currency so amounts can be shown in it
+ALTER TABLE disputes ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT '';
+UPDATE disputes d SET currency = p.currency FROM payments p WHERE p.id = d.payment_id;
+ALTER TABLE disputes ALTER COLUMN currency DROP DEFAULT;
@@ -0,0 +1,2 @@
+-- Optimistic locking: updates only apply to the version they were read at
+ALTER TABLE payments ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+-- The balance an account was opened with, the start of its ledger. Existing
+-- accounts take it from their first transaction, or their current balance if
+-- they have none.
+ALTER TABLE accounts ADD COLUMN initial_balance NUMERIC(19, 4) NOT NULL DEFAULT 0;
+UPDATE accounts a SET initial_balance = COALESCE((
+    SELECT CASE t.transaction_type WHEN 'credit' THEN t.balance_after - t.amount ELSE t.balance_after + t.amount END
+    FROM account_transactions t
+    WHERE t.account_id = a.id
+    ORDER BY t.created_at ASC, t.id ASC
+    LIMIT 1
+), a.balance);
+ALTER TABLE accounts ALTER COLUMN initial_balance DROP DEFAULT;
@@ -0,0 +1,20 @@
+-- Who did what through the API: one row per state-changing request
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    operation VARCHAR(64) NOT NULL,
+    target_id UUID,
+    source_ip VARCHAR(64) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    status_code INT NOT NULL,
+    result VARCHAR(10) NOT NULL,
+    request_id VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+
+    CONSTRAINT check_audit_result CHECK (result IN ('success', 'failure'))
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_user_id ON audit_log(user_id, created_at);
+CREATE INDEX idx_audit_log_target_id ON audit_log(target_id, created_at) WHERE target_id IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE account_transactions DROP CONSTRAINT check_transaction_type;
+ALTER TABLE account_transactions ADD CONSTRAINT check_transaction_type
+    CHECK (transaction_type IN ('debit', 'credit'));
@@ -0,0 +1,4 @@
+-- Manual balance adjustments by an operator, recorded without a payment
+ALTER TABLE account_transactions DROP CONSTRAINT check_transaction_type;
+ALTER TABLE account_transactions ADD CONSTRAINT check_transaction_type
+    CHECK (transaction_type IN ('debit', 'credit', 'adjustment_debit', 'adjustment_credit'));
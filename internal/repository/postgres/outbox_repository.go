@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/outbox"
@@ -29,10 +30,10 @@ func (r *OutboxRepository) Insert(ctx context.Context, entry *outbox.Entry) erro
 		return fmt.Errorf("marshal outbox payload: %w", err)
 	}
 	_, err = r.db(ctx).Exec(ctx,
-		`INSERT INTO outbox (id, aggregate_type, aggregate_id, event_type, payload, status, retry_count, max_retries, created_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		`INSERT INTO outbox (id, aggregate_type, aggregate_id, event_type, payload, status, retry_count, max_retries, next_attempt_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
 		entry.ID, entry.AggregateType, entry.AggregateID, entry.EventType, payload,
-		string(entry.Status), entry.RetryCount, entry.MaxRetries, entry.CreatedAt,
+		string(entry.Status), entry.RetryCount, entry.MaxRetries, nextAttemptAt(entry), entry.CreatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("insert outbox entry: %w", err)
@@ -40,19 +41,38 @@ func (r *OutboxRepository) Insert(ctx context.Context, entry *outbox.Entry) erro
 	return nil
 }
 
-func (r *OutboxRepository) GetPending(ctx context.Context, limit int) ([]*outbox.Entry, error) {
+// nextAttemptAt is when a new entry first becomes due: right away unless the
+// caller scheduled it.
+func nextAttemptAt(entry *outbox.Entry) time.Time {
+	if entry.NextAttemptAt.IsZero() {
+		return entry.CreatedAt
+	}
+	return entry.NextAttemptAt
+}
+
+// ClaimPending claims due entries in a single statement, so the claim commits
+// before anything is published. Workers polling concurrently get disjoint
+// batches: FOR UPDATE SKIP LOCKED passes over rows another claim is taking,
+// and once that claim commits the rows are publishing and not yet due, so no
+// transaction needs to stay open while they are published.
+func (r *OutboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*outbox.Entry, error) {
 	if limit <= 0 {
 		limit = 10
 	}
+	now := time.Now()
 	rows, err := r.db(ctx).Query(ctx,
-		`SELECT id, aggregate_type, aggregate_id, event_type, payload, status, retry_count, max_retries, created_at, published_at
-		 FROM outbox WHERE status = 'pending'
-		 ORDER BY created_at ASC
-		 LIMIT $1
-		 FOR UPDATE SKIP LOCKED`, limit,
+		`UPDATE outbox SET status = 'publishing', next_attempt_at = $2
+		 WHERE id IN (
+		     SELECT id FROM outbox
+		     WHERE status IN ('pending', 'publishing') AND next_attempt_at <= $1 AND retry_count < max_retries
+		     ORDER BY created_at ASC
+		     LIMIT $3
+		     FOR UPDATE SKIP LOCKED)
+		 RETURNING id, aggregate_type, aggregate_id, event_type, payload, status, retry_count, max_retries, next_attempt_at, created_at, published_at`,
+		now, now.Add(lease), limit,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("get pending outbox entries: %w", err)
+		return nil, fmt.Errorf("claim pending outbox entries: %w", err)
 	}
 	defer rows.Close()
 
@@ -61,7 +81,7 @@ func (r *OutboxRepository) GetPending(ctx context.Context, limit int) ([]*outbox
 		e := &outbox.Entry{}
 		var payload []byte
 		var status string
-		if err := rows.Scan(&e.ID, &e.AggregateType, &e.AggregateID, &e.EventType, &payload, &status, &e.RetryCount, &e.MaxRetries, &e.CreatedAt, &e.PublishedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.AggregateType, &e.AggregateID, &e.EventType, &payload, &status, &e.RetryCount, &e.MaxRetries, &e.NextAttemptAt, &e.CreatedAt, &e.PublishedAt); err != nil {
 			return nil, fmt.Errorf("scan outbox entry: %w", err)
 		}
 		e.Status = outbox.Status(status)
@@ -73,7 +93,12 @@ func (r *OutboxRepository) GetPending(ctx context.Context, limit int) ([]*outbox
 		}
 		entries = append(entries, e)
 	}
-	return entries, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING does not keep the subquery's order.
+	slices.SortFunc(entries, func(a, b *outbox.Entry) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return entries, nil
 }
 
 func (r *OutboxRepository) MarkPublished(ctx context.Context, id uuid.UUID) error {
@@ -87,14 +112,53 @@ func (r *OutboxRepository) MarkPublished(ctx context.Context, id uuid.UUID) erro
 	return nil
 }
 
-func (r *OutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db(ctx).Exec(ctx,
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time) (outbox.Status, error) {
+	var status string
+	err := r.db(ctx).QueryRow(ctx,
 		`UPDATE outbox SET retry_count = retry_count + 1,
-		        status = CASE WHEN retry_count + 1 >= max_retries THEN 'failed' ELSE 'pending' END
-		 WHERE id = $1`, id,
+		        status = CASE WHEN retry_count + 1 >= max_retries THEN 'failed' ELSE 'pending' END,
+		        next_attempt_at = $2
+		 WHERE id = $1
+		 RETURNING status`, id, nextAttemptAt,
+	).Scan(&status)
+	if err != nil {
+		return "", fmt.Errorf("mark outbox failed: %w", err)
+	}
+	return outbox.Status(status), nil
+}
+
+// DeletePublishedBefore removes one batch of terminal entries. Published
+// entries age from published_at; failed ones, which never publish, from
+// created_at. Rows locked by the outbox processor are skipped.
+func (r *OutboxRepository) DeletePublishedBefore(ctx context.Context, t time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	tag, err := r.db(ctx).Exec(ctx,
+		`DELETE FROM outbox WHERE id IN (
+		     SELECT id FROM outbox
+		     WHERE (status = 'published' AND published_at < $1)
+		        OR (status = 'failed' AND created_at < $1)
+		     LIMIT $2
+		     FOR UPDATE SKIP LOCKED)`, t, batchSize,
 	)
 	if err != nil {
-		return fmt.Errorf("mark outbox failed: %w", err)
+		return 0, fmt.Errorf("delete published outbox entries: %w", err)
 	}
-	return nil
+	return tag.RowsAffected(), nil
+}
+
+// DeletePending removes the entries for aggregateID that no publisher has
+// claimed. Rows locked by the outbox processor are skipped.
+func (r *OutboxRepository) DeletePending(ctx context.Context, aggregateID uuid.UUID) (int64, error) {
+	tag, err := r.db(ctx).Exec(ctx,
+		`DELETE FROM outbox WHERE id IN (
+		     SELECT id FROM outbox
+		     WHERE aggregate_id = $1 AND status = 'pending'
+		     FOR UPDATE SKIP LOCKED)`, aggregateID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("delete pending outbox entries: %w", err)
+	}
+	return tag.RowsAffected(), nil
 }
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/cassiomorais/payments/internal/domain/payment"
@@ -23,11 +25,27 @@ var allowedSortColumns = map[string]string{
 }
 
 type PaymentRepository struct {
-	pool *pgxpool.Pool
+	pool           *pgxpool.Pool
+	idempotencyTTL time.Duration
 }
 
-func NewPaymentRepository(pool *pgxpool.Pool) *PaymentRepository {
-	return &PaymentRepository{pool: pool}
+type PaymentRepositoryOption func(*PaymentRepository)
+
+// WithIdempotencyTTL makes a payment's idempotency key expire ttl after the
+// payment was created: GetByIdempotencyKey no longer finds it, and Create may
+// reuse the key for a new payment. Zero, the default, keeps keys forever.
+func WithIdempotencyTTL(ttl time.Duration) PaymentRepositoryOption {
+	return func(r *PaymentRepository) {
+		r.idempotencyTTL = ttl
+	}
+}
+
+func NewPaymentRepository(pool *pgxpool.Pool, opts ...PaymentRepositoryOption) *PaymentRepository {
+	r := &PaymentRepository{pool: pool}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
 }
 
 func (r *PaymentRepository) db(ctx context.Context) DBTX {
@@ -44,6 +62,13 @@ func (r *PaymentRepository) Create(ctx context.Context, p *payment.Payment) erro
 		return fmt.Errorf("marshal metadata: %w", err)
 	}
 
+	var providerOptions []byte
+	if len(p.ProviderOptions) > 0 {
+		if providerOptions, err = json.Marshal(p.ProviderOptions); err != nil {
+			return fmt.Errorf("marshal provider options: %w", err)
+		}
+	}
+
 	var providerStr *string
 	if p.Provider != nil {
 		s := string(*p.Provider)
@@ -51,16 +76,29 @@ func (r *PaymentRepository) Create(ctx context.Context, p *payment.Payment) erro
 	}
 
 	amountStr := centsToNumericString(p.Amount.ValueCents)
+	feeStr := centsToNumericString(p.FeeCents)
+	var rateStr, creditedStr, creditedCurrency *string
+	if c := p.Conversion; c != nil {
+		rate := strconv.FormatFloat(c.Rate, 'f', -1, 64)
+		credited := centsToNumericString(c.Credited.ValueCents)
+		rateStr, creditedStr, creditedCurrency = &rate, &credited, &c.Credited.Currency
+	}
+
+	if err := r.expireIdempotencyKey(ctx, p.UserID, p.IdempotencyKey); err != nil {
+		return err
+	}
 
 	_, err = r.db(ctx).Exec(ctx,
 		`INSERT INTO payments
 		 (id, idempotency_key, payment_type, source_account_id, destination_account_id,
-		  amount, currency, status, provider, provider_transaction_id,
-		  retry_count, max_retries, last_error, saga_id, saga_step, metadata, created_at, updated_at, completed_at)
-		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19)`,
+		  amount, currency, fee, fee_account_id, description, status, provider, provider_transaction_id,
+		  retry_count, max_retries, last_error, saga_id, saga_step, metadata, created_at, updated_at, completed_at,
+		  failed_at, cancelled_at, refunded_at, exchange_rate, credited_amount, credited_currency, scheduled_at, batch_id, provider_options, next_retry_at, user_id, version)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27,$28,$29,$30,$31,$32,$33,$34)`,
 		p.ID, p.IdempotencyKey, string(p.PaymentType), p.SourceAccountID, p.DestinationAccountID,
-		amountStr, p.Amount.Currency, string(p.Status), providerStr, p.ProviderTransactionID,
+		amountStr, p.Amount.Currency, feeStr, p.FeeAccountID, p.Description, string(p.Status), providerStr, p.ProviderTransactionID,
 		p.RetryCount, p.MaxRetries, p.LastError, p.SagaID, p.SagaStep, metadata, p.CreatedAt, p.UpdatedAt, p.CompletedAt,
+		p.FailedAt, p.CancelledAt, p.RefundedAt, rateStr, creditedStr, creditedCurrency, p.ScheduledAt, p.BatchID, providerOptions, p.NextRetryAt, p.UserID, p.Version,
 	)
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -73,19 +111,62 @@ func (r *PaymentRepository) Create(ctx context.Context, p *payment.Payment) erro
 }
 
 func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*payment.Payment, error) {
-	return r.scanPayment(r.db(ctx).QueryRow(ctx,
-		`SELECT id, idempotency_key, payment_type, source_account_id, destination_account_id,
-		        amount, currency, status, provider, provider_transaction_id,
-		        retry_count, max_retries, last_error, saga_id, saga_step, metadata, created_at, updated_at, completed_at
-		 FROM payments WHERE id = $1`, id))
+	return withReadRetry(ctx, "get payment", func() (*payment.Payment, error) {
+		return r.scanPayment(r.db(ctx).QueryRow(ctx,
+			`SELECT id, idempotency_key, payment_type, source_account_id, destination_account_id,
+			        amount, currency, fee, fee_account_id, description, status, provider, provider_transaction_id,
+			        retry_count, max_retries, last_error, saga_id, saga_step, metadata, created_at, updated_at, completed_at,
+			        failed_at, cancelled_at, refunded_at, exchange_rate, credited_amount, credited_currency, scheduled_at, batch_id, provider_options, next_retry_at, user_id, version
+			 FROM payments WHERE id = $1`, id))
+	})
 }
 
-func (r *PaymentRepository) GetByIdempotencyKey(ctx context.Context, key string) (*payment.Payment, error) {
-	return r.scanPayment(r.db(ctx).QueryRow(ctx,
-		`SELECT id, idempotency_key, payment_type, source_account_id, destination_account_id,
-		        amount, currency, status, provider, provider_transaction_id,
-		        retry_count, max_retries, last_error, saga_id, saga_step, metadata, created_at, updated_at, completed_at
-		 FROM payments WHERE idempotency_key = $1`, key))
+func (r *PaymentRepository) GetByIdempotencyKey(ctx context.Context, userID, key string) (*payment.Payment, error) {
+	return withReadRetry(ctx, "get payment by idempotency key", func() (*payment.Payment, error) {
+		return r.scanPayment(r.db(ctx).QueryRow(ctx,
+			`SELECT id, idempotency_key, payment_type, source_account_id, destination_account_id,
+			        amount, currency, fee, fee_account_id, description, status, provider, provider_transaction_id,
+			        retry_count, max_retries, last_error, saga_id, saga_step, metadata, created_at, updated_at, completed_at,
+			        failed_at, cancelled_at, refunded_at, exchange_rate, credited_amount, credited_currency, scheduled_at, batch_id, provider_options, next_retry_at, user_id, version
+			 FROM payments WHERE user_id = $1 AND idempotency_key = $2 AND NOT idempotency_key_expired AND created_at > $3`,
+			userID, key, r.idempotencyCutoff()))
+	})
+}
+
+// idempotencyCutoff returns the creation time before which a payment's
+// idempotency key has expired.
+func (r *PaymentRepository) idempotencyCutoff() time.Time {
+	if r.idempotencyTTL <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-r.idempotencyTTL)
+}
+
+// expireIdempotencyKey releases userID's key from a payment whose key has
+// expired, so the unique index on live keys lets a new payment take it.
+func (r *PaymentRepository) expireIdempotencyKey(ctx context.Context, userID, key string) error {
+	if r.idempotencyTTL <= 0 {
+		return nil
+	}
+	_, err := r.db(ctx).Exec(ctx,
+		`UPDATE payments SET idempotency_key_expired = TRUE
+		 WHERE user_id = $1 AND idempotency_key = $2 AND NOT idempotency_key_expired AND created_at <= $3`,
+		userID, key, r.idempotencyCutoff())
+	if err != nil {
+		return fmt.Errorf("expire idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *PaymentRepository) GetByProviderTransactionID(ctx context.Context, txID string) (*payment.Payment, error) {
+	return withReadRetry(ctx, "get payment by provider transaction id", func() (*payment.Payment, error) {
+		return r.scanPayment(r.db(ctx).QueryRow(ctx,
+			`SELECT id, idempotency_key, payment_type, source_account_id, destination_account_id,
+			        amount, currency, fee, fee_account_id, description, status, provider, provider_transaction_id,
+			        retry_count, max_retries, last_error, saga_id, saga_step, metadata, created_at, updated_at, completed_at,
+			        failed_at, cancelled_at, refunded_at, exchange_rate, credited_amount, credited_currency, scheduled_at, batch_id, provider_options, next_retry_at, user_id, version
+			 FROM payments WHERE provider_transaction_id = $1`, txID))
+	})
 }
 
 func (r *PaymentRepository) Update(ctx context.Context, p *payment.Payment) error {
@@ -99,48 +180,46 @@ func (r *PaymentRepository) Update(ctx context.Context, p *payment.Payment) erro
 		providerStr = &s
 	}
 
+	// The row is only written if nobody updated it since p was loaded; the
+	// version then moves on so the next writer holding the old one fails.
 	tag, err := r.db(ctx).Exec(ctx,
 		`UPDATE payments SET
 		  status=$1, provider=$2, provider_transaction_id=$3,
 		  retry_count=$4, last_error=$5, saga_id=$6, saga_step=$7,
-		  metadata=$8, updated_at=$9, completed_at=$10
-		 WHERE id=$11`,
+		  metadata=$8, updated_at=$9, completed_at=$10,
+		  failed_at=$11, cancelled_at=$12, refunded_at=$13, scheduled_at=$14, next_retry_at=$15,
+		  version=$16
+		 WHERE id=$17 AND version=$18`,
 		string(p.Status), providerStr, p.ProviderTransactionID,
 		p.RetryCount, p.LastError, p.SagaID, p.SagaStep,
-		metadata, p.UpdatedAt, p.CompletedAt, p.ID,
+		metadata, p.UpdatedAt, p.CompletedAt,
+		p.FailedAt, p.CancelledAt, p.RefundedAt, p.ScheduledAt, p.NextRetryAt,
+		p.Version+1, p.ID, p.Version,
 	)
 	if err != nil {
 		return fmt.Errorf("update payment: %w", err)
 	}
 	if tag.RowsAffected() == 0 {
-		return domainErrors.ErrPaymentNotFound
+		var exists bool
+		if err := r.db(ctx).QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM payments WHERE id = $1)`, p.ID).Scan(&exists); err != nil {
+			return fmt.Errorf("check payment: %w", err)
+		}
+		if !exists {
+			return domainErrors.ErrPaymentNotFound
+		}
+		return domainErrors.ErrOptimisticLockFailed
 	}
+	p.Version++
 	return nil
 }
 
 func (r *PaymentRepository) List(ctx context.Context, f payment.ListFilter) ([]*payment.Payment, error) {
+	where, args := listFilterWhere(f)
 	query := `SELECT id, idempotency_key, payment_type, source_account_id, destination_account_id,
-		        amount, currency, status, provider, provider_transaction_id,
-		        retry_count, max_retries, last_error, saga_id, saga_step, metadata, created_at, updated_at, completed_at
-		 FROM payments WHERE 1=1`
-	args := []any{}
-	argIdx := 1
-
-	if f.AccountID != nil {
-		query += fmt.Sprintf(" AND (source_account_id = $%d OR destination_account_id = $%d)", argIdx, argIdx)
-		args = append(args, *f.AccountID)
-		argIdx++
-	}
-	if f.Status != nil {
-		query += fmt.Sprintf(" AND status = $%d", argIdx)
-		args = append(args, string(*f.Status))
-		argIdx++
-	}
-	if f.Provider != nil {
-		query += fmt.Sprintf(" AND provider = $%d", argIdx)
-		args = append(args, string(*f.Provider))
-		argIdx++
-	}
+		        amount, currency, fee, fee_account_id, description, status, provider, provider_transaction_id,
+		        retry_count, max_retries, last_error, saga_id, saga_step, metadata, created_at, updated_at, completed_at,
+		        failed_at, cancelled_at, refunded_at, exchange_rate, credited_amount, credited_currency, scheduled_at, batch_id, provider_options, next_retry_at, user_id, version
+		 FROM payments WHERE 1=1` + where
 
 	// Strict whitelist for sort column
 	sortBy := "created_at"
@@ -153,16 +232,105 @@ func (r *PaymentRepository) List(ctx context.Context, f payment.ListFilter) ([]*
 	}
 	query += fmt.Sprintf(" ORDER BY %s %s", sortBy, sortOrder)
 
-	limit := f.Limit
-	if limit <= 0 {
-		limit = 20
+	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, f.PageLimit(), f.Offset)
+
+	return withReadRetry(ctx, "list payments", func() ([]*payment.Payment, error) {
+		rows, err := r.db(ctx).Query(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("list payments: %w", err)
+		}
+		defer rows.Close()
+
+		var payments []*payment.Payment
+		for rows.Next() {
+			p, err := r.scanPayment(rows)
+			if err != nil {
+				return nil, err
+			}
+			payments = append(payments, p)
+		}
+		return payments, rows.Err()
+	})
+}
+
+// Count counts the payments List would return across all pages.
+func (r *PaymentRepository) Count(ctx context.Context, f payment.ListFilter) (int, error) {
+	where, args := listFilterWhere(f)
+	return withReadRetry(ctx, "count payments", func() (int, error) {
+		var count int
+		if err := r.db(ctx).QueryRow(ctx, `SELECT COUNT(*) FROM payments WHERE 1=1`+where, args...).Scan(&count); err != nil {
+			return 0, fmt.Errorf("count payments: %w", err)
+		}
+		return count, nil
+	})
+}
+
+// listFilterWhere builds the conditions of f, to append to "WHERE 1=1", and
+// their arguments. List and Count share it so a page and its total agree.
+func listFilterWhere(f payment.ListFilter) (string, []any) {
+	var where strings.Builder
+	args := []any{}
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		fmt.Fprintf(&where, " AND "+cond, len(args))
 	}
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
-	args = append(args, limit, f.Offset)
 
-	rows, err := r.db(ctx).Query(ctx, query, args...)
+	if f.AccountID != nil {
+		args = append(args, *f.AccountID)
+		fmt.Fprintf(&where, " AND (source_account_id = $%d OR destination_account_id = $%d)", len(args), len(args))
+	}
+	if f.BatchID != nil {
+		add("batch_id = $%d", *f.BatchID)
+	}
+	if f.Status != nil {
+		add("status = $%d", string(*f.Status))
+	}
+	if f.Provider != nil {
+		add("provider = $%d", string(*f.Provider))
+	}
+	if f.Currency != nil {
+		add("currency = $%d", *f.Currency)
+	}
+	// Bounds are sent as exact decimal strings and cast, so the column is
+	// compared as NUMERIC rather than as text or a float.
+	if f.MinAmountCents != nil {
+		add("amount >= $%d::numeric", centsToNumericString(*f.MinAmountCents))
+	}
+	if f.MaxAmountCents != nil {
+		add("amount <= $%d::numeric", centsToNumericString(*f.MaxAmountCents))
+	}
+	if f.CreatedAfter != nil {
+		add("created_at >= $%d", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		add("created_at <= $%d", *f.CreatedBefore)
+	}
+	if f.UpdatedBefore != nil {
+		add("updated_at < $%d", *f.UpdatedBefore)
+	}
+	return where.String(), args
+}
+
+// ListDueScheduled locks up to limit scheduled payments due at or before now,
+// earliest first. Rows locked by another transaction are skipped, so
+// concurrent workers release disjoint batches.
+func (r *PaymentRepository) ListDueScheduled(ctx context.Context, now time.Time, limit int) ([]*payment.Payment, error) {
+	if err := requireTx(ctx, "list due scheduled payments"); err != nil {
+		return nil, err
+	}
+	rows, err := r.db(ctx).Query(ctx,
+		`SELECT id, idempotency_key, payment_type, source_account_id, destination_account_id,
+		        amount, currency, fee, fee_account_id, description, status, provider, provider_transaction_id,
+		        retry_count, max_retries, last_error, saga_id, saga_step, metadata, created_at, updated_at, completed_at,
+		        failed_at, cancelled_at, refunded_at, exchange_rate, credited_amount, credited_currency, scheduled_at, batch_id, provider_options, next_retry_at, user_id, version
+		 FROM payments WHERE status = $1 AND scheduled_at <= $2
+		 ORDER BY scheduled_at LIMIT $3
+		 FOR UPDATE SKIP LOCKED`,
+		string(payment.StatusScheduled), now, limit,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("list payments: %w", err)
+		return nil, fmt.Errorf("list due scheduled payments: %w", err)
 	}
 	defer rows.Close()
 
@@ -196,11 +364,30 @@ func (r *PaymentRepository) AddEvent(ctx context.Context, event *payment.Payment
 func (r *PaymentRepository) GetEvents(ctx context.Context, paymentID uuid.UUID) ([]*payment.PaymentEvent, error) {
 	rows, err := r.db(ctx).Query(ctx,
 		`SELECT id, payment_id, event_type, event_data, created_at
-		 FROM payment_events WHERE payment_id = $1 ORDER BY created_at ASC`, paymentID,
+		 FROM payment_events WHERE payment_id = $1 ORDER BY created_at ASC, id ASC`, paymentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list payment events: %w", err)
+	}
+	return scanEvents(rows)
+}
+
+func (r *PaymentRepository) ListEvents(ctx context.Context, paymentID uuid.UUID, limit, offset int) ([]*payment.PaymentEvent, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := r.db(ctx).Query(ctx,
+		`SELECT id, payment_id, event_type, event_data, created_at
+		 FROM payment_events WHERE payment_id = $1 ORDER BY created_at ASC, id ASC LIMIT $2 OFFSET $3`,
+		paymentID, limit, offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("list payment events: %w", err)
 	}
+	return scanEvents(rows)
+}
+
+func scanEvents(rows pgx.Rows) ([]*payment.PaymentEvent, error) {
 	defer rows.Close()
 
 	var events []*payment.PaymentEvent
@@ -218,20 +405,36 @@ func (r *PaymentRepository) GetEvents(ctx context.Context, paymentID uuid.UUID)
 	return events, rows.Err()
 }
 
+func (r *PaymentRepository) CountBySourceAccountSince(ctx context.Context, accountID uuid.UUID, since time.Time) (int, error) {
+	var count int
+	err := r.db(ctx).QueryRow(ctx,
+		`SELECT COUNT(*) FROM payments WHERE source_account_id = $1 AND created_at >= $2`,
+		accountID, since,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count payments: %w", err)
+	}
+	return count, nil
+}
 
 func (r *PaymentRepository) scanPayment(s scanner) (*payment.Payment, error) {
 	p := &payment.Payment{Metadata: make(map[string]any)}
 	var (
 		paymentType string
 		amountStr   string
+		feeStr      string
 		status      string
 		provider    *string
 		metadata    []byte
+		options     []byte
+
+		rateStr, creditedStr, creditedCurrency *string
 	)
 	err := s.Scan(
 		&p.ID, &p.IdempotencyKey, &paymentType, &p.SourceAccountID, &p.DestinationAccountID,
-		&amountStr, &p.Amount.Currency, &status, &provider, &p.ProviderTransactionID,
+		&amountStr, &p.Amount.Currency, &feeStr, &p.FeeAccountID, &p.Description, &status, &provider, &p.ProviderTransactionID,
 		&p.RetryCount, &p.MaxRetries, &p.LastError, &p.SagaID, &p.SagaStep, &metadata, &p.CreatedAt, &p.UpdatedAt, &p.CompletedAt,
+		&p.FailedAt, &p.CancelledAt, &p.RefundedAt, &rateStr, &creditedStr, &creditedCurrency, &p.ScheduledAt, &p.BatchID, &options, &p.NextRetryAt, &p.UserID, &p.Version,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -246,6 +449,20 @@ func (r *PaymentRepository) scanPayment(s scanner) (*payment.Payment, error) {
 	}
 	p.Amount.ValueCents = cents
 
+	if p.FeeCents, err = numericStringToCents(feeStr); err != nil {
+		return nil, fmt.Errorf("parse fee: %w", err)
+	}
+	if rateStr != nil && creditedStr != nil && creditedCurrency != nil {
+		c := &payment.Conversion{Credited: payment.NewAmount(0, *creditedCurrency)}
+		if c.Rate, err = strconv.ParseFloat(*rateStr, 64); err != nil {
+			return nil, fmt.Errorf("parse exchange rate: %w", err)
+		}
+		if c.Credited.ValueCents, err = numericStringToCents(*creditedStr); err != nil {
+			return nil, fmt.Errorf("parse credited amount: %w", err)
+		}
+		p.Conversion = c
+	}
+
 	p.PaymentType = payment.PaymentType(paymentType)
 	p.Status = payment.PaymentStatus(status)
 	if provider != nil {
@@ -257,5 +474,10 @@ func (r *PaymentRepository) scanPayment(s scanner) (*payment.Payment, error) {
 			return nil, fmt.Errorf("unmarshal payment metadata: %w", err)
 		}
 	}
+	if len(options) > 0 {
+		if err := json.Unmarshal(options, &p.ProviderOptions); err != nil {
+			return nil, fmt.Errorf("unmarshal provider options: %w", err)
+		}
+	}
 	return p, nil
 }
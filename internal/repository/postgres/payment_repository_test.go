@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListFilterWhere(t *testing.T) {
+	accountID := uuid.New()
+	status := payment.StatusCompleted
+	currency := "USD"
+	minAmount := int64(1000)
+
+	where, args := listFilterWhere(payment.ListFilter{
+		AccountID:      &accountID,
+		Status:         &status,
+		Currency:       &currency,
+		MinAmountCents: &minAmount,
+		Limit:          5,
+		Offset:         10,
+	})
+	assert.Equal(t, " AND (source_account_id = $1 OR destination_account_id = $1) AND status = $2 AND currency = $3 AND amount >= $4::numeric", where)
+	assert.Equal(t, []any{accountID, "completed", "USD", "10.00"}, args, "paging is not part of the filter")
+
+	after := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	before := after.AddDate(0, 0, 7)
+	where, args = listFilterWhere(payment.ListFilter{Status: &status, CreatedAfter: &after, CreatedBefore: &before})
+	assert.Equal(t, " AND status = $1 AND created_at >= $2 AND created_at <= $3", where)
+	assert.Equal(t, []any{"completed", after, before}, args)
+
+	where, args = listFilterWhere(payment.ListFilter{})
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+}
+
+func TestListFilterWhere_AmountBoundsAreInclusive(t *testing.T) {
+	minAmount, maxAmount := int64(10000), int64(2500050)
+
+	where, args := listFilterWhere(payment.ListFilter{MinAmountCents: &minAmount, MaxAmountCents: &maxAmount})
+	assert.Equal(t, " AND amount >= $1::numeric AND amount <= $2::numeric", where)
+	assert.Equal(t, []any{"100.00", "25000.50"}, args)
+
+	where, args = listFilterWhere(payment.ListFilter{MinAmountCents: &minAmount, MaxAmountCents: &minAmount})
+	assert.Equal(t, " AND amount >= $1::numeric AND amount <= $2::numeric", where, "equal bounds match exactly that amount")
+	assert.Equal(t, []any{"100.00", "100.00"}, args)
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/auth"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RefreshTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRefreshTokenRepository(pool *pgxpool.Pool) *RefreshTokenRepository {
+	return &RefreshTokenRepository{pool: pool}
+}
+
+func (r *RefreshTokenRepository) db(ctx context.Context) DBTX {
+	return ConnFromCtx(ctx, r.pool)
+}
+
+func (r *RefreshTokenRepository) Create(ctx context.Context, t *auth.RefreshToken) error {
+	// The array columns are NOT NULL
+	scopes, roles := t.Scopes, t.Roles
+	if scopes == nil {
+		scopes = []string{}
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	_, err := r.db(ctx).Exec(ctx,
+		`INSERT INTO refresh_tokens (id, token_hash, user_id, scopes, roles, created_at, expires_at, revoked_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		t.ID, t.TokenHash, t.UserID, scopes, roles, t.CreatedAt, t.ExpiresAt, t.RevokedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetByHash returns ErrInvalidRefreshToken for an unknown token.
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*auth.RefreshToken, error) {
+	return withReadRetry(ctx, "get refresh token", func() (*auth.RefreshToken, error) {
+		t := &auth.RefreshToken{}
+		err := r.db(ctx).QueryRow(ctx,
+			`SELECT id, token_hash, user_id, scopes, roles, created_at, expires_at, revoked_at
+			 FROM refresh_tokens WHERE token_hash = $1`, tokenHash,
+		).Scan(&t.ID, &t.TokenHash, &t.UserID, &t.Scopes, &t.Roles, &t.CreatedAt, &t.ExpiresAt, &t.RevokedAt)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, domainErrors.ErrInvalidRefreshToken
+			}
+			return nil, fmt.Errorf("get refresh token: %w", err)
+		}
+		return t, nil
+	})
+}
+
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	tag, err := r.db(ctx).Exec(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`, at, id)
+	if err != nil {
+		return fmt.Errorf("revoke refresh token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domainErrors.ErrInvalidRefreshToken
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	readRetryMu      sync.RWMutex
+	readRetries      int
+	readRetryBackoff time.Duration
+)
+
+// SetReadRetry configures how many extra attempts idempotent reads make after
+// a transient connection error, and the base backoff between them (doubled on
+// each attempt). Zero retries disables the behaviour.
+func SetReadRetry(retries int, backoff time.Duration) {
+	readRetryMu.Lock()
+	defer readRetryMu.Unlock()
+	readRetries = max(retries, 0)
+	readRetryBackoff = backoff
+}
+
+func readRetrySettings() (int, time.Duration) {
+	readRetryMu.RLock()
+	defer readRetryMu.RUnlock()
+	return readRetries, readRetryBackoff
+}
+
+// isTransientConnError reports whether err looks like a dropped or refused
+// connection rather than a query or data error.
+func isTransientConnError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08: connection exception; 57P01-57P03: server shutting down or
+		// not yet accepting connections.
+		return len(pgErr.Code) == 5 && (pgErr.Code[:2] == "08" ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03")
+	}
+	var connErr *pgconn.ConnectError
+	if errors.As(err, &connErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// withReadRetry runs an idempotent read, retrying it on transient connection
+// errors. Reads inside a transaction are not retried: a broken connection
+// aborts the transaction, so the caller has to start over.
+func withReadRetry[T any](ctx context.Context, op string, fn func() (T, error)) (T, error) {
+	retries, backoff := readRetrySettings()
+	if InTransaction(ctx) {
+		retries = 0
+	}
+
+	result, err := fn()
+	for attempt := 1; attempt <= retries && isTransientConnError(err); attempt++ {
+		log.Warn().Err(err).Str("op", op).Int("attempt", attempt).Msg("transient database error, retrying read")
+
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(backoff << (attempt - 1)):
+		}
+		result, err = fn()
+	}
+	return result, err
+}
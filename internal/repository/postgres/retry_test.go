@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransientConnError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"unexpected EOF", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"not found", domainErrors.ErrPaymentNotFound, false},
+		{"context canceled", context.Canceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isTransientConnError(tt.err))
+		})
+	}
+}
+
+func TestWithReadRetry_RetriesTransientErrors(t *testing.T) {
+	SetReadRetry(2, 0)
+	defer SetReadRetry(0, 0)
+
+	calls := 0
+	got, err := withReadRetry(context.Background(), "get payment", func() (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 42, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 42, got)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWithReadRetry_GivesUpAfterConfiguredRetries(t *testing.T) {
+	SetReadRetry(1, 0)
+	defer SetReadRetry(0, 0)
+
+	calls := 0
+	_, err := withReadRetry(context.Background(), "get payment", func() (int, error) {
+		calls++
+		return 0, io.ErrUnexpectedEOF
+	})
+
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWithReadRetry_DoesNotRetryOtherErrors(t *testing.T) {
+	SetReadRetry(3, 0)
+	defer SetReadRetry(0, 0)
+
+	calls := 0
+	_, err := withReadRetry(context.Background(), "get payment", func() (int, error) {
+		calls++
+		return 0, domainErrors.ErrPaymentNotFound
+	})
+
+	assert.True(t, errors.Is(err, domainErrors.ErrPaymentNotFound))
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithReadRetry_NotRetriedInsideTransaction(t *testing.T) {
+	SetReadRetry(3, 0)
+	defer SetReadRetry(0, 0)
+
+	ctx := context.WithValue(context.Background(), txKey, fakeTx{})
+	calls := 0
+	_, err := withReadRetry(ctx, "get payment", func() (int, error) {
+		calls++
+		return 0, io.ErrUnexpectedEOF
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
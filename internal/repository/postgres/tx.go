@@ -2,13 +2,44 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
 )
 
+// ErrNoTransaction is returned by money-moving repository methods called
+// outside WithTransaction while transaction assertions are enabled.
+var ErrNoTransaction = errors.New("money-moving operation outside a transaction")
+
+var assertTx atomic.Bool
+
+// SetTxAssertions makes money-moving repository methods fail with
+// ErrNoTransaction when the context carries no transaction. Intended for
+// development and tests; it catches a txCtx that was not threaded through.
+func SetTxAssertions(enabled bool) {
+	assertTx.Store(enabled)
+}
+
+// InTransaction reports whether ctx carries a transaction from WithTransaction.
+func InTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey).(pgx.Tx)
+	return ok
+}
+
+// requireTx enforces SetTxAssertions for the named operation.
+func requireTx(ctx context.Context, op string) error {
+	if !assertTx.Load() || InTransaction(ctx) {
+		return nil
+	}
+	log.Error().Str("op", op).Msg("money-moving repository call outside a transaction")
+	return fmt.Errorf("%s: %w", op, ErrNoTransaction)
+}
+
 // ctxKey is an unexported type for context keys in this package.
 type ctxKey int
 
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTx satisfies pgx.Tx for context propagation checks; it is never used to
+// run queries.
+type fakeTx struct{ pgx.Tx }
+
+func TestRequireTx_AssertionsDisabled(t *testing.T) {
+	SetTxAssertions(false)
+
+	assert.NoError(t, requireTx(context.Background(), "update account"))
+}
+
+func TestRequireTx_OutsideTransaction_Fails(t *testing.T) {
+	SetTxAssertions(true)
+	defer SetTxAssertions(false)
+
+	err := requireTx(context.Background(), "update account")
+	assert.ErrorIs(t, err, ErrNoTransaction)
+}
+
+func TestRequireTx_InsideTransaction_Allowed(t *testing.T) {
+	SetTxAssertions(true)
+	defer SetTxAssertions(false)
+
+	ctx := context.WithValue(context.Background(), txKey, pgx.Tx(fakeTx{}))
+	assert.True(t, InTransaction(ctx))
+	assert.NoError(t, requireTx(ctx, "update account"))
+}
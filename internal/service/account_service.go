@@ -2,23 +2,48 @@ package service
 
 import (
 	"context"
+	"errors"
 
 	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/middleware"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
 type AccountService struct {
 	accountRepo account.Repository
+	txManager   TransactionManager
+
+	statementKey []byte
+}
+
+type AccountServiceOption func(*AccountService)
+
+// WithStatementSigningKey enables signed statement exports. Without it,
+// ExportStatement refuses to sign and VerifyStatement is unavailable.
+func WithStatementSigningKey(key []byte) AccountServiceOption {
+	return func(s *AccountService) { s.statementKey = key }
+}
+
+// WithAccountTransactions runs account status changes in transactions, which
+// the Postgres repository requires for updates.
+func WithAccountTransactions(tm TransactionManager) AccountServiceOption {
+	return func(s *AccountService) { s.txManager = tm }
 }
 
-func NewAccountService(accountRepo account.Repository) *AccountService {
-	return &AccountService{
+func NewAccountService(accountRepo account.Repository, opts ...AccountServiceOption) *AccountService {
+	s := &AccountService{
 		accountRepo: accountRepo,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *AccountService) CreateAccount(ctx context.Context, req CreateAccountRequest) (*account.Account, error) {
-	acct, err := account.NewAccount(req.UserID, req.InitialBalance, req.Currency)
+	acct, err := account.NewLabeledAccount(req.UserID, req.Label, req.InitialBalance, req.Currency)
 	if err != nil {
 		return nil, err
 	}
@@ -28,6 +53,40 @@ func (s *AccountService) CreateAccount(ctx context.Context, req CreateAccountReq
 	return acct, nil
 }
 
+// GetOrCreateAccount returns the user's account in req.Currency under
+// req.Label, creating it with req.InitialBalance if none exists. created
+// reports which happened. A concurrent create losing the unique (user_id,
+// currency, label) race re-reads the winner's account instead of failing.
+func (s *AccountService) GetOrCreateAccount(ctx context.Context, req CreateAccountRequest) (acct *account.Account, created bool, err error) {
+	existing, err := s.findByUser(ctx, req.UserID, req.Currency, req.Label)
+	if err != nil || existing != nil {
+		return existing, false, err
+	}
+
+	acct, err = s.CreateAccount(ctx, req)
+	if errors.Is(err, domainErrors.ErrAccountAlreadyExists) {
+		existing, err = s.findByUser(ctx, req.UserID, req.Currency, req.Label)
+		if err == nil && existing == nil {
+			err = domainErrors.ErrAccountNotFound
+		}
+		return existing, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return acct, true, nil
+}
+
+// findByUser returns nil without error when the user has no account in
+// currency under label.
+func (s *AccountService) findByUser(ctx context.Context, userID, currency, label string) (*account.Account, error) {
+	acct, err := s.accountRepo.GetByUserID(ctx, userID, currency, label)
+	if errors.Is(err, domainErrors.ErrAccountNotFound) {
+		return nil, nil
+	}
+	return acct, err
+}
+
 func (s *AccountService) GetAccount(ctx context.Context, id uuid.UUID) (*account.Account, error) {
 	return s.accountRepo.GetByID(ctx, id)
 }
@@ -43,3 +102,70 @@ func (s *AccountService) GetBalance(ctx context.Context, id uuid.UUID) (int64, s
 func (s *AccountService) GetTransactions(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*account.Transaction, error) {
 	return s.accountRepo.GetTransactions(ctx, accountID, limit, offset)
 }
+
+// ListAccounts returns a page of accounts matching the filter and how many
+// match in total. It performs no ownership check; callers must restrict the
+// filter to the caller's user unless they hold the admin scope.
+func (s *AccountService) ListAccounts(ctx context.Context, filter account.ListFilter) ([]*account.Account, int, error) {
+	accounts, err := s.accountRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.accountRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return accounts, total, nil
+}
+
+// SuspendAccount blocks debits, credits and new holds on an account until it
+// is activated again.
+func (s *AccountService) SuspendAccount(ctx context.Context, id uuid.UUID) (*account.Account, error) {
+	return s.changeStatus(ctx, id, (*account.Account).Suspend)
+}
+
+// ActivateAccount returns a suspended account to service.
+func (s *AccountService) ActivateAccount(ctx context.Context, id uuid.UUID) (*account.Account, error) {
+	return s.changeStatus(ctx, id, (*account.Account).Activate)
+}
+
+// DeactivateAccount closes an account for good.
+func (s *AccountService) DeactivateAccount(ctx context.Context, id uuid.UUID) (*account.Account, error) {
+	return s.changeStatus(ctx, id, (*account.Account).Deactivate)
+}
+
+// changeStatus applies transition to the account and saves it with
+// optimistic locking: a change racing a balance update fails with
+// ErrOptimisticLockFailed instead of overwriting it.
+func (s *AccountService) changeStatus(ctx context.Context, id uuid.UUID, transition func(*account.Account) error) (*account.Account, error) {
+	var acct *account.Account
+	err := s.withTransaction(ctx, func(txCtx context.Context) error {
+		a, err := s.accountRepo.GetByID(txCtx, id)
+		if err != nil {
+			return err
+		}
+		from := a.Status
+		if err := transition(a); err != nil {
+			return err
+		}
+		if err := s.accountRepo.Update(txCtx, a); err != nil {
+			return err
+		}
+		operator, _ := middleware.GetUserID(ctx)
+		log.Info().Str("account_id", a.ID.String()).Str("from", string(from)).Str("to", string(a.Status)).
+			Str("operator", operator).Msg("account status changed")
+		acct = a
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return acct, nil
+}
+
+func (s *AccountService) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.txManager == nil {
+		return fn(ctx)
+	}
+	return s.txManager.WithTransaction(ctx, fn)
+}
@@ -128,6 +128,27 @@ func TestCreateAccount_RepositoryError(t *testing.T) {
 	assert.Contains(t, err.Error(), "database error")
 }
 
+func TestCreateAccount_LabelsSeparateAccountsInOneCurrency(t *testing.T) {
+	svc, accountRepo := setupAccountService()
+	ctx := context.Background()
+
+	spending, err := svc.CreateAccount(ctx, CreateAccountRequest{UserID: "user123", Currency: "USD"})
+	require.NoError(t, err)
+	savings, err := svc.CreateAccount(ctx, CreateAccountRequest{UserID: "user123", Currency: "USD", Label: "savings"})
+	require.NoError(t, err)
+	assert.NotEqual(t, spending.ID, savings.ID)
+
+	_, err = svc.CreateAccount(ctx, CreateAccountRequest{UserID: "user123", Currency: "USD", Label: "savings"})
+	assert.ErrorIs(t, err, domainErrors.ErrAccountAlreadyExists)
+
+	found, err := accountRepo.GetByUserID(ctx, "user123", "USD", "savings")
+	require.NoError(t, err)
+	assert.Equal(t, savings.ID, found.ID)
+	found, err = accountRepo.GetByUserID(ctx, "user123", "USD", "")
+	require.NoError(t, err)
+	assert.Equal(t, spending.ID, found.ID)
+}
+
 // --- GetAccount Tests ---
 
 func TestGetAccount_Success(t *testing.T) {
@@ -146,16 +167,10 @@ func TestGetAccount_Success(t *testing.T) {
 }
 
 func TestGetAccount_NotFound(t *testing.T) {
-	svc, accountRepo := setupAccountService()
+	svc, _ := setupAccountService()
 	ctx := context.Background()
 
 	nonExistentID := uuid.New()
-	accountRepo.GetByIDFunc = func(ctx context.Context, id uuid.UUID) (*account.Account, error) {
-		if id == nonExistentID {
-			return nil, domainErrors.ErrAccountNotFound
-		}
-		return nil, domainErrors.ErrAccountNotFound
-	}
 
 	acct, err := svc.GetAccount(ctx, nonExistentID)
 	assert.ErrorIs(t, err, domainErrors.ErrAccountNotFound)
@@ -178,13 +193,10 @@ func TestGetBalance_Success(t *testing.T) {
 }
 
 func TestGetBalance_AccountNotFound(t *testing.T) {
-	svc, accountRepo := setupAccountService()
+	svc, _ := setupAccountService()
 	ctx := context.Background()
 
 	nonExistentID := uuid.New()
-	accountRepo.GetByIDFunc = func(ctx context.Context, id uuid.UUID) (*account.Account, error) {
-		return nil, domainErrors.ErrAccountNotFound
-	}
 
 	balance, currency, err := svc.GetBalance(ctx, nonExistentID)
 	assert.ErrorIs(t, err, domainErrors.ErrAccountNotFound)
@@ -296,3 +308,50 @@ func TestGetTransactions_WithPagination(t *testing.T) {
 	require.NoError(t, err)
 	assert.Nil(t, page3)
 }
+
+// --- GetOrCreateAccount Tests ---
+
+func TestGetOrCreateAccount_CreatesWhenMissing(t *testing.T) {
+	svc, accountRepo := setupAccountService()
+
+	acct, created, err := svc.GetOrCreateAccount(context.Background(), CreateAccountRequest{UserID: "user123", Currency: "USD"})
+
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.NotNil(t, accountRepo.GetAccountByID(acct.ID))
+}
+
+func TestGetOrCreateAccount_ReturnsExisting(t *testing.T) {
+	svc, accountRepo := setupAccountService()
+	existing, _ := account.NewAccount("user123", 5000, "USD")
+	accountRepo.AddAccount(existing)
+
+	acct, created, err := svc.GetOrCreateAccount(context.Background(), CreateAccountRequest{UserID: "user123", Currency: "USD"})
+
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, existing.ID, acct.ID)
+}
+
+func TestGetOrCreateAccount_LostCreateRace_RequeriesWinner(t *testing.T) {
+	svc, accountRepo := setupAccountService()
+	winner, _ := account.NewAccount("user123", 0, "USD")
+	lookups := 0
+	accountRepo.GetByUserIDFunc = func(ctx context.Context, userID, currency, label string) (*account.Account, error) {
+		lookups++
+		if lookups == 1 {
+			return nil, domainErrors.ErrAccountNotFound // not there yet
+		}
+		return winner, nil
+	}
+	accountRepo.CreateFunc = func(ctx context.Context, acct *account.Account) error {
+		return domainErrors.ErrAccountAlreadyExists // a concurrent call created it first
+	}
+
+	acct, created, err := svc.GetOrCreateAccount(context.Background(), CreateAccountRequest{UserID: "user123", Currency: "USD"})
+
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, winner.ID, acct.ID)
+	assert.Equal(t, 2, lookups)
+}
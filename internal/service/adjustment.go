@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// BalanceAdjustment is a manual correction of an account's balance, such as
+// a goodwill credit or a clawback. Type is TransactionAdjustmentCredit or
+// TransactionAdjustmentDebit.
+type BalanceAdjustment struct {
+	AccountID   uuid.UUID
+	Type        account.TransactionType
+	AmountCents int64
+	Reason      string
+}
+
+// AdjustBalance applies adj to the locked account and records it as an
+// adjustment transaction with the reason as its description. Like any other
+// debit, a debit adjustment may not exceed the available balance, so it
+// never drives the balance negative.
+func (s *PaymentService) AdjustBalance(ctx context.Context, adj BalanceAdjustment) (*account.Account, *account.Transaction, error) {
+	if adj.Reason == "" {
+		return nil, nil, domainErrors.NewValidationError("reason", "is required")
+	}
+
+	var post func(context.Context, uuid.UUID, *uuid.UUID, account.TransactionType, int64, string) (*account.Account, *account.Transaction, error)
+	switch adj.Type {
+	case account.TransactionAdjustmentCredit:
+		post = s.postCredit
+	case account.TransactionAdjustmentDebit:
+		post = s.postDebit
+	default:
+		return nil, nil, domainErrors.NewValidationError("type", "must be an adjustment credit or debit")
+	}
+
+	var acct *account.Account
+	var tx *account.Transaction
+	err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		acct, tx, err = post(txCtx, adj.AccountID, nil, adj.Type, adj.AmountCents, adj.Reason)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	operator, _ := middleware.GetUserID(ctx)
+	log.Info().Str("account_id", acct.ID.String()).Str("type", string(adj.Type)).
+		Int64("amount_cents", adj.AmountCents).Int64("balance_after", tx.BalanceAfter).
+		Str("reason", adj.Reason).Str("operator", operator).Msg("account balance adjusted")
+	return acct, tx, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAdjustment holds a 100.00 USD account with 30.00 of it on hold.
+func setupAdjustment(t *testing.T) (*PaymentService, *testutil.MockAccountRepository, *account.Account) {
+	t.Helper()
+	accountRepo := testutil.NewMockAccountRepository()
+	svc := NewPaymentService(testutil.NewMockPaymentRepository(), accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe")))
+	acct := createTestAccount(t, "user1", 10000, account.StatusActive)
+	acct.HeldBalance = 3000
+	accountRepo.AddAccount(acct)
+	return svc, accountRepo, acct
+}
+
+func TestAdjustBalance_Credit(t *testing.T) {
+	svc, accountRepo, acct := setupAdjustment(t)
+	ctx := context.Background()
+
+	adjusted, tx, err := svc.AdjustBalance(ctx, BalanceAdjustment{
+		AccountID: acct.ID, Type: account.TransactionAdjustmentCredit, AmountCents: 2500, Reason: "goodwill credit",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(12500), adjusted.Balance)
+	assert.Equal(t, account.TransactionAdjustmentCredit, tx.TransactionType)
+	assert.Equal(t, int64(2500), tx.Amount)
+	assert.Equal(t, int64(12500), tx.BalanceAfter)
+	assert.Equal(t, "goodwill credit", tx.Description)
+	assert.Nil(t, tx.PaymentID)
+
+	assert.Equal(t, int64(12500), accountRepo.GetAccountByID(acct.ID).Balance)
+	txns, err := accountRepo.GetAllTransactions(ctx, acct.ID)
+	require.NoError(t, err)
+	assert.Len(t, txns, 1)
+}
+
+func TestAdjustBalance_Debit(t *testing.T) {
+	svc, accountRepo, acct := setupAdjustment(t)
+	ctx := context.Background()
+
+	adjusted, tx, err := svc.AdjustBalance(ctx, BalanceAdjustment{
+		AccountID: acct.ID, Type: account.TransactionAdjustmentDebit, AmountCents: 7000, Reason: "clawback",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3000), adjusted.Balance)
+	assert.Equal(t, account.TransactionAdjustmentDebit, tx.TransactionType)
+	assert.Equal(t, int64(3000), tx.BalanceAfter)
+	assert.Equal(t, "clawback", tx.Description)
+
+	report, err := NewAccountService(accountRepo).VerifyLedgerIntegrity(ctx, acct.ID)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent, "adjustments count in the ledger's running total")
+}
+
+func TestAdjustBalance_DebitBeyondAvailable_Rejected(t *testing.T) {
+	svc, accountRepo, acct := setupAdjustment(t)
+	ctx := context.Background()
+
+	// 100.00 balance with 30.00 held leaves 70.00 to debit.
+	_, _, err := svc.AdjustBalance(ctx, BalanceAdjustment{
+		AccountID: acct.ID, Type: account.TransactionAdjustmentDebit, AmountCents: 7001, Reason: "clawback",
+	})
+	assert.ErrorIs(t, err, domainErrors.ErrInsufficientFunds)
+
+	assert.Equal(t, int64(10000), accountRepo.GetAccountByID(acct.ID).Balance)
+	txns, err := accountRepo.GetAllTransactions(ctx, acct.ID)
+	require.NoError(t, err)
+	assert.Empty(t, txns)
+}
+
+func TestAdjustBalance_Validation(t *testing.T) {
+	svc, _, acct := setupAdjustment(t)
+	ctx := context.Background()
+
+	for name, adj := range map[string]BalanceAdjustment{
+		"no reason":        {AccountID: acct.ID, Type: account.TransactionAdjustmentCredit, AmountCents: 100},
+		"payment type":     {AccountID: acct.ID, Type: account.TransactionCredit, AmountCents: 100, Reason: "x"},
+		"non-positive amt": {AccountID: acct.ID, Type: account.TransactionAdjustmentCredit, AmountCents: 0, Reason: "x"},
+	} {
+		_, _, err := svc.AdjustBalance(ctx, adj)
+		var validationErr *domainErrors.ValidationError
+		assert.ErrorAs(t, err, &validationErr, name)
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/audit"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultAuditBufferSize    = 1024
+	defaultAuditBatchSize     = 100
+	defaultAuditFlushInterval = time.Second
+	// auditWriteTimeout bounds each batch insert.
+	auditWriteTimeout = 5 * time.Second
+)
+
+type AuditLoggerOption func(*AuditLogger)
+
+// WithAuditBuffer sets how many entries may wait to be written. Entries
+// logged while the buffer is full are dropped rather than delaying the
+// request.
+func WithAuditBuffer(size int) AuditLoggerOption {
+	return func(l *AuditLogger) {
+		if size > 0 {
+			l.bufferSize = size
+		}
+	}
+}
+
+// WithAuditFlush sets the largest batch written at once and how long an
+// entry may wait for a batch to fill.
+func WithAuditFlush(batchSize int, interval time.Duration) AuditLoggerOption {
+	return func(l *AuditLogger) {
+		if batchSize > 0 {
+			l.batchSize = batchSize
+		}
+		if interval > 0 {
+			l.flushInterval = interval
+		}
+	}
+}
+
+// WithAuditMetrics counts dropped entries.
+func WithAuditMetrics(m *observability.Metrics) AuditLoggerOption {
+	return func(l *AuditLogger) { l.metrics = m }
+}
+
+// AuditLogger writes the application audit trail. Log only queues an entry;
+// a background goroutine writes queued entries in batches, so auditing adds
+// no database round trip to the request. Close flushes what is queued.
+type AuditLogger struct {
+	repo          audit.Repository
+	metrics       *observability.Metrics
+	bufferSize    int
+	batchSize     int
+	flushInterval time.Duration
+
+	mu      sync.RWMutex // guards closed against sends on a closed entries
+	closed  bool
+	entries chan *audit.Entry
+	done    chan struct{}
+}
+
+// NewAuditLogger starts an AuditLogger writing to repo. Call Close on
+// shutdown to write the entries still queued.
+func NewAuditLogger(repo audit.Repository, opts ...AuditLoggerOption) *AuditLogger {
+	l := &AuditLogger{
+		repo:          repo,
+		bufferSize:    defaultAuditBufferSize,
+		batchSize:     defaultAuditBatchSize,
+		flushInterval: defaultAuditFlushInterval,
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.entries = make(chan *audit.Entry, l.bufferSize)
+	go l.run()
+	return l
+}
+
+// Log queues e without blocking, filling in its ID and time if unset. It is
+// dropped if the buffer is full or the logger is closed.
+func (l *AuditLogger) Log(e *audit.Entry) {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if !l.closed {
+		select {
+		case l.entries <- e:
+			return
+		default:
+		}
+	}
+	if l.metrics != nil {
+		l.metrics.AuditEntriesDropped.Inc()
+	}
+	log.Error().Str("operation", e.Operation).Str("user_id", e.UserID).Msg("Audit entry dropped")
+}
+
+// Close stops accepting entries and waits until the queued ones are written
+// or ctx is done.
+func (l *AuditLogger) Close(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.entries)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// List returns a page of audit entries matching filter, newest first, and
+// how many match in total.
+func (l *AuditLogger) List(ctx context.Context, filter audit.ListFilter) ([]*audit.Entry, int, error) {
+	entries, err := l.repo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := l.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+func (l *AuditLogger) run() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*audit.Entry, 0, l.batchSize)
+	for {
+		select {
+		case e, ok := <-l.entries:
+			if !ok {
+				l.write(batch)
+				return
+			}
+			batch = append(batch, e)
+			if len(batch) >= l.batchSize {
+				l.write(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			l.write(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// write inserts batch. A failed batch is logged and lost: retrying would
+// hold back every entry queued behind it.
+func (l *AuditLogger) write(batch []*audit.Entry) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+	defer cancel()
+	if err := l.repo.InsertBatch(ctx, batch); err != nil {
+		log.Error().Err(err).Int("entries", len(batch)).Msg("Failed to write audit entries")
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/audit"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLogger_CloseFlushesQueuedEntries(t *testing.T) {
+	repo := &testutil.MockAuditRepository{}
+	logger := NewAuditLogger(repo, WithAuditFlush(100, time.Hour))
+
+	for i := 0; i < 3; i++ {
+		logger.Log(&audit.Entry{UserID: "user1", Operation: string(OpRefundPayment), Result: audit.ResultSuccess})
+	}
+	require.NoError(t, logger.Close(context.Background()))
+
+	entries := repo.Entries()
+	require.Len(t, entries, 3)
+	for _, e := range entries {
+		assert.NotEqual(t, uuid.Nil, e.ID)
+		assert.False(t, e.CreatedAt.IsZero())
+	}
+}
+
+func TestAuditLogger_WritesFullBatchesWithoutWaiting(t *testing.T) {
+	batches := make(chan int, 10)
+	repo := &testutil.MockAuditRepository{}
+	repo.InsertBatchFunc = func(ctx context.Context, entries []*audit.Entry) error {
+		batches <- len(entries)
+		return nil
+	}
+	logger := NewAuditLogger(repo, WithAuditFlush(2, time.Hour))
+	defer logger.Close(context.Background())
+
+	logger.Log(&audit.Entry{Operation: string(OpTransfer)})
+	logger.Log(&audit.Entry{Operation: string(OpTransfer)})
+
+	select {
+	case n := <-batches:
+		assert.Equal(t, 2, n)
+	case <-time.After(5 * time.Second):
+		t.Fatal("a full batch was not written before the flush interval")
+	}
+}
+
+func TestAuditLogger_DropsWhenBufferFull(t *testing.T) {
+	release := make(chan struct{})
+	writing := make(chan struct{}, 1)
+	repo := &testutil.MockAuditRepository{}
+	var written int
+	repo.InsertBatchFunc = func(ctx context.Context, entries []*audit.Entry) error {
+		writing <- struct{}{}
+		<-release
+		written += len(entries)
+		return nil
+	}
+	logger := NewAuditLogger(repo, WithAuditBuffer(1), WithAuditFlush(1, time.Hour))
+
+	logger.Log(&audit.Entry{Operation: "first"})
+	<-writing // the writer holds the first entry
+	logger.Log(&audit.Entry{Operation: "queued"})
+
+	done := make(chan struct{})
+	go func() {
+		logger.Log(&audit.Entry{Operation: "dropped"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Log blocked on a full buffer")
+	}
+
+	close(release)
+	require.NoError(t, logger.Close(context.Background()))
+	assert.Equal(t, 2, written)
+}
+
+func TestAuditLogger_LogAfterCloseIsDropped(t *testing.T) {
+	repo := &testutil.MockAuditRepository{}
+	logger := NewAuditLogger(repo)
+	require.NoError(t, logger.Close(context.Background()))
+
+	assert.NotPanics(t, func() { logger.Log(&audit.Entry{Operation: string(OpCancelPayment)}) })
+	assert.NoError(t, logger.Close(context.Background()), "closing twice is harmless")
+	assert.Empty(t, repo.Entries())
+}
+
+func TestAuditLogger_ListFiltersAndCounts(t *testing.T) {
+	repo := &testutil.MockAuditRepository{}
+	target := uuid.New()
+	base := time.Now()
+	require.NoError(t, repo.InsertBatch(context.Background(), []*audit.Entry{
+		{ID: uuid.New(), UserID: "user1", Operation: string(OpRefundPayment), TargetID: &target, Result: audit.ResultSuccess, CreatedAt: base},
+		{ID: uuid.New(), UserID: "user1", Operation: string(OpCancelPayment), TargetID: &target, Result: audit.ResultFailure, CreatedAt: base.Add(time.Second)},
+		{ID: uuid.New(), UserID: "user2", Operation: string(OpTransfer), Result: audit.ResultSuccess, CreatedAt: base.Add(2 * time.Second)},
+	}))
+	logger := NewAuditLogger(repo)
+	defer logger.Close(context.Background())
+
+	entries, total, err := logger.List(context.Background(), audit.ListFilter{TargetID: &target, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, entries, 1)
+	assert.Equal(t, string(OpCancelPayment), entries[0].Operation, "newest first")
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/google/uuid"
+)
+
+// Operation names an API operation subject to an authorization policy.
+type Operation string
+
+const (
+	OpCreateAccount        Operation = "create_account"
+	OpEnsureAccount        Operation = "ensure_account"
+	OpGetAccount           Operation = "get_account"
+	OpGetBalance           Operation = "get_balance"
+	OpListTransactions     Operation = "list_transactions"
+	OpExportStatement      Operation = "export_statement"
+	OpVerifyStatement      Operation = "verify_statement"
+	OpCreatePayment        Operation = "create_payment"
+	OpGetPayment           Operation = "get_payment"
+	OpGetPaymentEvents     Operation = "get_payment_events"
+	OpGetPaymentTimeline   Operation = "get_payment_timeline"
+	OpListPayments         Operation = "list_payments"
+	OpRefundPayment        Operation = "refund_payment"
+	OpCancelPayment        Operation = "cancel_payment"
+	OpListDisputes         Operation = "list_disputes"
+	OpTransfer             Operation = "transfer"
+	OpTransferToNewAccount Operation = "transfer_to_new_account"
+	OpListAccounts         Operation = "list_accounts"
+	OpVerifyLedger         Operation = "verify_ledger"
+	OpAdjustBalance        Operation = "adjust_balance"
+	OpSearchAccounts       Operation = "search_accounts"
+	OpSuspendAccount       Operation = "suspend_account"
+	OpActivateAccount      Operation = "activate_account"
+	OpDeactivateAccount    Operation = "deactivate_account"
+	OpReemitEvents         Operation = "reemit_events"
+	OpApproveReview        Operation = "approve_review"
+	OpRejectReview         Operation = "reject_review"
+	OpListDeadLetters      Operation = "list_dead_letters"
+	OpReplayDeadLetter     Operation = "replay_dead_letter"
+	OpListCircuitBreakers  Operation = "list_circuit_breakers"
+	OpResetCircuitBreaker  Operation = "reset_circuit_breaker"
+	OpListAuditLog         Operation = "list_audit_log"
+)
+
+// Check is the resource check a policy applies to callers without one of its
+// scopes. Account checks take an account ID as the resource, payment checks a
+// payment ID.
+type Check string
+
+const (
+	// CheckAuthenticated admits any authenticated caller.
+	CheckAuthenticated Check = "authenticated"
+	// CheckAccountOwner admits the owner of the account.
+	CheckAccountOwner Check = "account_owner"
+	// CheckSourceOwner admits the owner of the account, or anyone when there
+	// is no account (external payments without a source).
+	CheckSourceOwner Check = "source_owner"
+	// CheckPaymentSource admits the owner of the payment's source account.
+	CheckPaymentSource Check = "payment_source"
+	// CheckPaymentParty admits the owner of the payment's source or
+	// destination account.
+	CheckPaymentParty Check = "payment_party"
+	// CheckScope admits no one; only the policy's scopes grant access.
+	CheckScope Check = "scope"
+)
+
+var validChecks = []Check{
+	CheckAuthenticated, CheckAccountOwner, CheckSourceOwner,
+	CheckPaymentSource, CheckPaymentParty, CheckScope,
+}
+
+// Policy admits callers holding any of Scopes or Roles, and otherwise those
+// passing Check.
+type Policy struct {
+	Check  Check
+	Scopes []string
+	Roles  []string
+}
+
+// Policies maps each operation to its policy. Operations without a policy
+// are denied.
+type Policies map[Operation]Policy
+
+// DefaultPolicies returns the built-in policy for every operation.
+func DefaultPolicies(adminScope, supportScope string) Policies {
+	staff := []string{adminScope, supportScope}
+	admin := []string{adminScope}
+	staffRoles := []string{RoleAdmin, RoleSupport}
+	adminRoles := []string{RoleAdmin}
+	return Policies{
+		OpCreateAccount:        {Check: CheckAuthenticated},
+		OpEnsureAccount:        {Check: CheckAuthenticated},
+		OpGetAccount:           {Check: CheckAccountOwner},
+		OpGetBalance:           {Check: CheckAccountOwner},
+		OpListTransactions:     {Check: CheckAccountOwner},
+		OpExportStatement:      {Check: CheckAccountOwner},
+		OpVerifyStatement:      {Check: CheckAuthenticated},
+		OpCreatePayment:        {Check: CheckSourceOwner},
+		OpGetPayment:           {Check: CheckPaymentParty, Scopes: staff, Roles: staffRoles},
+		OpGetPaymentEvents:     {Check: CheckPaymentParty, Scopes: staff, Roles: staffRoles},
+		OpGetPaymentTimeline:   {Check: CheckPaymentParty, Scopes: staff, Roles: staffRoles},
+		OpListPayments:         {Check: CheckAccountOwner, Scopes: staff, Roles: staffRoles},
+		OpRefundPayment:        {Check: CheckPaymentSource, Scopes: admin, Roles: adminRoles},
+		OpCancelPayment:        {Check: CheckPaymentSource, Scopes: admin, Roles: adminRoles},
+		OpListDisputes:         {Check: CheckPaymentParty, Scopes: staff, Roles: staffRoles},
+		OpTransfer:             {Check: CheckAccountOwner},
+		OpTransferToNewAccount: {Check: CheckAccountOwner},
+		OpListAccounts:         {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpVerifyLedger:         {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpAdjustBalance:        {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpSearchAccounts:       {Check: CheckAuthenticated},
+		OpSuspendAccount:       {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpActivateAccount:      {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpDeactivateAccount:    {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpReemitEvents:         {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpApproveReview:        {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpRejectReview:         {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpListDeadLetters:      {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpReplayDeadLetter:     {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpListCircuitBreakers:  {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpResetCircuitBreaker:  {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+		OpListAuditLog:         {Check: CheckScope, Scopes: admin, Roles: adminRoles},
+	}
+}
+
+// Operations lists every operation with a default policy.
+func Operations() []Operation {
+	ops := make([]Operation, 0, len(DefaultPolicies("", "")))
+	for op := range DefaultPolicies("", "") {
+		ops = append(ops, op)
+	}
+	slices.Sort(ops)
+	return ops
+}
+
+// WithOverrides returns a copy of p with overrides applied, rejecting
+// unknown operations and checks.
+func (p Policies) WithOverrides(overrides map[string]Policy) (Policies, error) {
+	out := make(Policies, len(p))
+	for op, policy := range p {
+		out[op] = policy
+	}
+	for name, policy := range overrides {
+		op := Operation(name)
+		if _, ok := p[op]; !ok {
+			return nil, fmt.Errorf("authz policy: unknown operation %q", name)
+		}
+		if !slices.Contains(validChecks, policy.Check) {
+			return nil, fmt.Errorf("authz policy %s: unknown check %q", name, policy.Check)
+		}
+		out[op] = policy
+	}
+	return out, nil
+}
+
+// Authorize enforces op's policy for the caller on resource, an account or
+// payment ID depending on the policy's check (nil when the request names
+// none). It returns ErrUnauthorized without an authenticated caller and
+// ErrForbidden when the policy denies access.
+func (s *AuthzService) Authorize(ctx context.Context, op Operation, resource *uuid.UUID) error {
+	userID, ok := middleware.GetUserID(ctx)
+	if !ok || userID == "" {
+		return errors.ErrUnauthorized
+	}
+	policy, ok := s.policies[op]
+	if !ok {
+		return errors.ErrForbidden
+	}
+	for _, scope := range policy.Scopes {
+		if scope != "" && middleware.HasScope(ctx, scope) {
+			return nil
+		}
+	}
+	for _, role := range policy.Roles {
+		if s.hasRole(ctx, role) {
+			return nil
+		}
+	}
+
+	switch policy.Check {
+	case CheckAuthenticated:
+		return nil
+	case CheckSourceOwner:
+		if resource == nil {
+			return nil
+		}
+		return s.VerifyAccountOwnership(ctx, *resource)
+	case CheckAccountOwner:
+		if resource == nil {
+			return errors.ErrForbidden
+		}
+		return s.VerifyAccountOwnership(ctx, *resource)
+	case CheckPaymentSource, CheckPaymentParty:
+		if resource == nil || s.paymentRepo == nil {
+			return errors.ErrForbidden
+		}
+		p, err := s.paymentRepo.GetByID(ctx, *resource)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return errors.ErrPaymentNotFound
+		}
+		accounts := []*uuid.UUID{p.SourceAccountID}
+		if policy.Check == CheckPaymentParty {
+			accounts = append(accounts, p.DestinationAccountID)
+		}
+		for _, id := range accounts {
+			if id != nil && s.VerifyAccountOwnership(ctx, *id) == nil {
+				return nil
+			}
+		}
+		return errors.ErrForbidden
+	default:
+		return errors.ErrForbidden
+	}
+}
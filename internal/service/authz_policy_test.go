@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type policyFixture struct {
+	authz     *AuthzService
+	source    *account.Account
+	dest      *account.Account
+	paymentID uuid.UUID
+}
+
+// setupPolicies creates a transfer from user1's account to user2's.
+func setupPolicies(t *testing.T, policies Policies) policyFixture {
+	t.Helper()
+	accountRepo := testutil.NewMockAccountRepository()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	source := testutil.NewTestAccount("user1", 10000, "USD")
+	dest := testutil.NewTestAccount("user2", 0, "USD")
+	accountRepo.AddAccount(source)
+	accountRepo.AddAccount(dest)
+	p := testutil.NewTestPayment(payment.InternalTransfer, &source.ID, &dest.ID, 500, "USD")
+	require.NoError(t, paymentRepo.Create(context.Background(), p))
+	return policyFixture{
+		authz:     NewAuthzService(accountRepo, WithPolicies(policies, paymentRepo)),
+		source:    source,
+		dest:      dest,
+		paymentID: p.ID,
+	}
+}
+
+func ctxAs(userID string, scopes ...string) context.Context {
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, userID)
+	return context.WithValue(ctx, middleware.ScopesKey, scopes)
+}
+
+func TestAuthorize_Unauthenticated(t *testing.T) {
+	f := setupPolicies(t, DefaultPolicies("admin", "support"))
+	err := f.authz.Authorize(context.Background(), OpCreateAccount, nil)
+	assert.ErrorIs(t, err, domainErrors.ErrUnauthorized)
+}
+
+func TestAuthorize_AccountOwner(t *testing.T) {
+	f := setupPolicies(t, DefaultPolicies("admin", "support"))
+
+	assert.NoError(t, f.authz.Authorize(ctxAs("user1"), OpGetBalance, &f.source.ID))
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user2"), OpGetBalance, &f.source.ID), domainErrors.ErrForbidden)
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user1", "admin"), OpGetBalance, &f.dest.ID), domainErrors.ErrForbidden,
+		"account reads grant no scope by default")
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user1"), OpListPayments, nil), domainErrors.ErrForbidden,
+		"listing every payment needs a scope")
+
+	missing := uuid.New()
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user1"), OpGetBalance, &missing), domainErrors.ErrAccountNotFound)
+}
+
+func TestAuthorize_SourceOwner(t *testing.T) {
+	f := setupPolicies(t, DefaultPolicies("admin", "support"))
+
+	assert.NoError(t, f.authz.Authorize(ctxAs("user2"), OpCreatePayment, nil), "external payments have no source")
+	assert.NoError(t, f.authz.Authorize(ctxAs("user1"), OpCreatePayment, &f.source.ID))
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user2"), OpCreatePayment, &f.source.ID), domainErrors.ErrForbidden)
+}
+
+func TestAuthorize_PaymentChecks(t *testing.T) {
+	f := setupPolicies(t, DefaultPolicies("admin", "support"))
+
+	assert.NoError(t, f.authz.Authorize(ctxAs("user2"), OpGetPayment, &f.paymentID), "destination owner is a party")
+	assert.NoError(t, f.authz.Authorize(ctxAs("user3", "support"), OpGetPayment, &f.paymentID))
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user3"), OpGetPayment, &f.paymentID), domainErrors.ErrForbidden)
+
+	assert.NoError(t, f.authz.Authorize(ctxAs("user1"), OpRefundPayment, &f.paymentID))
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user2"), OpRefundPayment, &f.paymentID), domainErrors.ErrForbidden,
+		"only the source owner may refund")
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user3", "support"), OpRefundPayment, &f.paymentID), domainErrors.ErrForbidden)
+	assert.NoError(t, f.authz.Authorize(ctxAs("user3", "admin"), OpRefundPayment, &f.paymentID))
+
+	missing := uuid.New()
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user1"), OpGetPayment, &missing), domainErrors.ErrPaymentNotFound)
+}
+
+func TestAuthorize_ScopeOnly(t *testing.T) {
+	f := setupPolicies(t, DefaultPolicies("admin", "support"))
+
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user1"), OpListAccounts, nil), domainErrors.ErrForbidden)
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user1", "support"), OpListAccounts, nil), domainErrors.ErrForbidden)
+	assert.NoError(t, f.authz.Authorize(ctxAs("user1", "admin"), OpListAccounts, nil))
+}
+
+func ctxWithRoles(userID string, roles ...string) context.Context {
+	return context.WithValue(ctxAs(userID), middleware.RolesKey, roles)
+}
+
+func TestAuthorize_RolesGrantLikeScopes(t *testing.T) {
+	f := setupPolicies(t, DefaultPolicies("admin", "support"))
+
+	assert.NoError(t, f.authz.Authorize(ctxWithRoles("user3", RoleAdmin), OpResetCircuitBreaker, nil))
+	assert.NoError(t, f.authz.Authorize(ctxWithRoles("user3", RoleSupport), OpGetPayment, &f.paymentID))
+	assert.ErrorIs(t, f.authz.Authorize(ctxWithRoles("user3", RoleSupport), OpResetCircuitBreaker, nil), domainErrors.ErrForbidden)
+	assert.ErrorIs(t, f.authz.Authorize(ctxWithRoles("user3", "auditor"), OpListAccounts, nil), domainErrors.ErrForbidden)
+}
+
+func TestRequireRole(t *testing.T) {
+	f := setupPolicies(t, DefaultPolicies("admin", "support"))
+
+	assert.NoError(t, f.authz.RequireRole(ctxWithRoles("user1", RoleAdmin), RoleAdmin))
+	assert.NoError(t, f.authz.RequireRole(ctxAs("user1", "payments:admin"), RoleAdmin), "the admin scope implies the role")
+	assert.ErrorIs(t, f.authz.RequireRole(ctxWithRoles("user1", RoleSupport), RoleAdmin), domainErrors.ErrForbidden)
+	assert.ErrorIs(t, f.authz.RequireRole(ctxAs("user1"), RoleAdmin), domainErrors.ErrForbidden)
+	assert.ErrorIs(t, f.authz.RequireRole(context.Background(), RoleAdmin), domainErrors.ErrUnauthorized)
+
+	custom := NewAuthzService(testutil.NewMockAccountRepository(), WithRoleScopes(map[string]string{RoleAdmin: "ops"}))
+	assert.NoError(t, custom.RequireRole(ctxAs("user1", "ops"), RoleAdmin))
+	assert.ErrorIs(t, custom.RequireRole(ctxAs("user1", "payments:admin"), RoleAdmin), domainErrors.ErrForbidden)
+}
+
+func TestAuthorize_UnknownOperationDenied(t *testing.T) {
+	f := setupPolicies(t, Policies{})
+	err := f.authz.Authorize(ctxAs("user1", "admin"), OpGetAccount, &f.source.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrForbidden)
+}
+
+func TestAuthorize_WithOverrides(t *testing.T) {
+	policies, err := DefaultPolicies("admin", "support").WithOverrides(map[string]Policy{
+		"refund_payment": {Check: CheckScope, Scopes: []string{"refunds"}},
+	})
+	require.NoError(t, err)
+	f := setupPolicies(t, policies)
+
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user1"), OpRefundPayment, &f.paymentID), domainErrors.ErrForbidden)
+	assert.ErrorIs(t, f.authz.Authorize(ctxAs("user3", "admin"), OpRefundPayment, &f.paymentID), domainErrors.ErrForbidden)
+	assert.NoError(t, f.authz.Authorize(ctxAs("user3", "refunds"), OpRefundPayment, &f.paymentID))
+	assert.NoError(t, f.authz.Authorize(ctxAs("user1"), OpCancelPayment, &f.paymentID), "other policies are kept")
+}
+
+func TestPolicies_WithOverridesValidates(t *testing.T) {
+	defaults := DefaultPolicies("admin", "support")
+
+	_, err := defaults.WithOverrides(map[string]Policy{"refund": {Check: CheckScope}})
+	assert.ErrorContains(t, err, `unknown operation "refund"`)
+
+	_, err = defaults.WithOverrides(map[string]Policy{"refund_payment": {Check: "owner"}})
+	assert.ErrorContains(t, err, `unknown check "owner"`)
+
+	_, err = defaults.WithOverrides(map[string]Policy{"refund_payment": {Check: CheckScope}})
+	require.NoError(t, err)
+	assert.Equal(t, CheckPaymentSource, defaults[OpRefundPayment].Check, "defaults are not modified")
+}
+
+func TestOperations_AllHaveDefaultPolicies(t *testing.T) {
+	defaults := DefaultPolicies("admin", "support")
+	for _, op := range Operations() {
+		assert.Contains(t, defaults, op)
+	}
+	assert.Len(t, Operations(), len(defaults))
+}
@@ -2,19 +2,78 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/account"
 	"github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
 	"github.com/cassiomorais/payments/internal/middleware"
 	"github.com/google/uuid"
 )
 
+// Roles a token may carry in its roles claim. Staff roles see other users'
+// data under the default policies.
+const (
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+)
+
+// StepUpPolicy describes when a money-moving request needs a token carrying
+// RequiredScope. A zero AmountThreshold or CountThreshold disables that check.
+type StepUpPolicy struct {
+	AmountThreshold int64 // in cents
+	CountThreshold  int
+	CountWindow     time.Duration
+	RequiredScope   string
+}
+
+type AuthzOption func(*AuthzService)
+
+// WithStepUpPolicy enables step-up checks. paymentRepo is used to count recent
+// payments for the count threshold.
+func WithStepUpPolicy(policy StepUpPolicy, paymentRepo payment.Repository) AuthzOption {
+	return func(s *AuthzService) {
+		s.stepUp = policy
+		s.paymentRepo = paymentRepo
+	}
+}
+
+// WithPolicies replaces the default per-operation policies. paymentRepo
+// resolves payments for the payment checks.
+func WithPolicies(policies Policies, paymentRepo payment.Repository) AuthzOption {
+	return func(s *AuthzService) {
+		s.policies = policies
+		s.paymentRepo = paymentRepo
+	}
+}
+
+// WithRoleScopes maps roles to the token scope that grants the same role,
+// so tokens issued before the roles claim keep their access. The default
+// maps admin and support to the default admin and support scopes.
+func WithRoleScopes(roleScopes map[string]string) AuthzOption {
+	return func(s *AuthzService) { s.roleScopes = roleScopes }
+}
+
 type AuthzService struct {
 	accountRepo account.Repository
+	paymentRepo payment.Repository
+	stepUp      StepUpPolicy
+	policies    Policies
+	roleScopes  map[string]string
 }
 
-func NewAuthzService(accountRepo account.Repository) *AuthzService {
-	return &AuthzService{accountRepo: accountRepo}
+// NewAuthzService enforces DefaultPolicies with the default admin and
+// support scopes unless WithPolicies is given.
+func NewAuthzService(accountRepo account.Repository, opts ...AuthzOption) *AuthzService {
+	s := &AuthzService{
+		accountRepo: accountRepo,
+		policies:    DefaultPolicies("payments:admin", "payments:support"),
+		roleScopes:  map[string]string{RoleAdmin: "payments:admin", RoleSupport: "payments:support"},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
 }
 
 func (s *AuthzService) VerifyAccountOwnership(ctx context.Context, accountID uuid.UUID) error {
@@ -27,6 +86,9 @@ func (s *AuthzService) VerifyAccountOwnership(ctx context.Context, accountID uui
 	if err != nil {
 		return err
 	}
+	if acct == nil {
+		return errors.ErrAccountNotFound
+	}
 
 	if acct.UserID != userID {
 		return errors.ErrForbidden
@@ -35,9 +97,52 @@ func (s *AuthzService) VerifyAccountOwnership(ctx context.Context, accountID uui
 	return nil
 }
 
-func (s *AuthzService) VerifyPaymentAuthorization(ctx context.Context, sourceAccountID *uuid.UUID) error {
-	if sourceAccountID == nil {
-		return nil // External payments without source account allowed
+// RequireRole returns ErrUnauthorized without an authenticated caller and
+// ErrForbidden unless the caller's token carries role, in its roles claim or
+// as the scope mapped to it by WithRoleScopes.
+func (s *AuthzService) RequireRole(ctx context.Context, role string) error {
+	if userID, ok := middleware.GetUserID(ctx); !ok || userID == "" {
+		return errors.ErrUnauthorized
 	}
-	return s.VerifyAccountOwnership(ctx, *sourceAccountID)
+	if !s.hasRole(ctx, role) {
+		return errors.ErrForbidden
+	}
+	return nil
+}
+
+func (s *AuthzService) hasRole(ctx context.Context, role string) bool {
+	if role == "" {
+		return false
+	}
+	if middleware.HasRole(ctx, role) {
+		return true
+	}
+	scope := s.roleScopes[role]
+	return scope != "" && middleware.HasScope(ctx, scope)
+}
+
+// VerifyStepUp returns ErrStepUpRequired when the payment exceeds the amount
+// threshold, or the source account has reached the count threshold within the
+// window, and the caller's token lacks the required scope.
+func (s *AuthzService) VerifyStepUp(ctx context.Context, sourceAccountID *uuid.UUID, amountCents int64) error {
+	if s.stepUp.RequiredScope == "" || middleware.HasScope(ctx, s.stepUp.RequiredScope) {
+		return nil
+	}
+
+	if s.stepUp.AmountThreshold > 0 && amountCents > s.stepUp.AmountThreshold {
+		return errors.ErrStepUpRequired
+	}
+
+	if s.stepUp.CountThreshold > 0 && sourceAccountID != nil && s.paymentRepo != nil {
+		since := time.Now().Add(-s.stepUp.CountWindow)
+		count, err := s.paymentRepo.CountBySourceAccountSince(ctx, *sourceAccountID, since)
+		if err != nil {
+			return err
+		}
+		if count >= s.stepUp.CountThreshold {
+			return errors.ErrStepUpRequired
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Test Helpers ---
+
+func setupStepUpAuthz(policy StepUpPolicy) (*AuthzService, *testutil.MockPaymentRepository) {
+	accountRepo := testutil.NewMockAccountRepository()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	return NewAuthzService(accountRepo, WithStepUpPolicy(policy, paymentRepo)), paymentRepo
+}
+
+func ctxWithScopes(scopes ...string) context.Context {
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user1")
+	return context.WithValue(ctx, middleware.ScopesKey, scopes)
+}
+
+// --- VerifyStepUp Tests ---
+
+func TestVerifyStepUp_BelowAmountThreshold_Allowed(t *testing.T) {
+	svc, _ := setupStepUpAuthz(StepUpPolicy{AmountThreshold: 100000, RequiredScope: "mfa"})
+
+	err := svc.VerifyStepUp(ctxWithScopes(), nil, 50000)
+	assert.NoError(t, err)
+}
+
+func TestVerifyStepUp_AboveAmountThresholdWithoutScope_Denied(t *testing.T) {
+	svc, _ := setupStepUpAuthz(StepUpPolicy{AmountThreshold: 100000, RequiredScope: "mfa"})
+
+	err := svc.VerifyStepUp(ctxWithScopes("payments:write"), nil, 100001)
+	assert.ErrorIs(t, err, domainErrors.ErrStepUpRequired)
+}
+
+func TestVerifyStepUp_AboveAmountThresholdWithScope_Allowed(t *testing.T) {
+	svc, _ := setupStepUpAuthz(StepUpPolicy{AmountThreshold: 100000, RequiredScope: "mfa"})
+
+	err := svc.VerifyStepUp(ctxWithScopes("mfa"), nil, 500000)
+	assert.NoError(t, err)
+}
+
+func TestVerifyStepUp_CountThresholdReached_Denied(t *testing.T) {
+	svc, paymentRepo := setupStepUpAuthz(StepUpPolicy{CountThreshold: 3, CountWindow: time.Hour, RequiredScope: "mfa"})
+	sourceID := uuid.New()
+	paymentRepo.CountBySourceAccountSinceFunc = func(ctx context.Context, accountID uuid.UUID, since time.Time) (int, error) {
+		assert.Equal(t, sourceID, accountID)
+		return 3, nil
+	}
+
+	err := svc.VerifyStepUp(ctxWithScopes(), &sourceID, 100)
+	assert.ErrorIs(t, err, domainErrors.ErrStepUpRequired)
+}
+
+func TestVerifyStepUp_CountBelowThreshold_Allowed(t *testing.T) {
+	svc, paymentRepo := setupStepUpAuthz(StepUpPolicy{CountThreshold: 3, CountWindow: time.Hour, RequiredScope: "mfa"})
+	sourceID := uuid.New()
+	paymentRepo.Create(context.Background(), testutil.NewTestPayment(payment.InternalTransfer, &sourceID, nil, 100, "USD"))
+
+	err := svc.VerifyStepUp(ctxWithScopes(), &sourceID, 100)
+	assert.NoError(t, err)
+}
+
+func TestVerifyStepUp_NoPolicy_Allowed(t *testing.T) {
+	svc := NewAuthzService(testutil.NewMockAccountRepository())
+
+	err := svc.VerifyStepUp(ctxWithScopes(), nil, 1<<40)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/google/uuid"
+)
+
+// DefaultMaxBatchSize caps the entries of one batch request unless
+// WithMaxBatchSize sets another limit.
+const DefaultMaxBatchSize = 100
+
+// WithMaxBatchSize caps the number of payments one CreateBatch call accepts.
+// n <= 0 keeps DefaultMaxBatchSize.
+func WithMaxBatchSize(n int) PaymentServiceOption {
+	return func(s *PaymentService) {
+		if n > 0 {
+			s.maxBatchSize = n
+		}
+	}
+}
+
+// BatchID is the batch identifier derived from a batch idempotency key, so
+// a replayed batch reports the same ID.
+func BatchID(idempotencyKey string) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("payments:batch:"+idempotencyKey))
+}
+
+// batchItemKey is the idempotency key of entry i of a batch.
+func batchItemKey(idempotencyKey string, i int) string {
+	return fmt.Sprintf("%s#%d", idempotencyKey, i)
+}
+
+// CreateBatch creates each payment of req in order through CreatePayment.
+// Entries are independent: one failing does not stop or undo the others, and
+// each result carries its own outcome or error. Entry i is created under a
+// key derived from the batch key and i, so replaying the batch replays every
+// entry that was created and retries the rest.
+func (s *PaymentService) CreateBatch(ctx context.Context, req CreateBatchRequest) (*CreateBatchResponse, error) {
+	if req.IdempotencyKey == "" {
+		return nil, domainErrors.NewValidationError("idempotency_key", "required for batch requests")
+	}
+	if len(req.Payments) == 0 {
+		return nil, domainErrors.NewValidationError("payments", "must not be empty")
+	}
+	if len(req.Payments) > s.maxBatchSize {
+		return nil, domainErrors.NewValidationError("payments", fmt.Sprintf("must have at most %d entries", s.maxBatchSize))
+	}
+
+	batchID := BatchID(req.IdempotencyKey)
+	resp := &CreateBatchResponse{BatchID: batchID, Results: make([]BatchItemResult, len(req.Payments))}
+	for i, item := range req.Payments {
+		item.IdempotencyKey = batchItemKey(req.IdempotencyKey, i)
+		item.BatchID = &batchID
+		created, err := s.CreatePayment(ctx, item)
+		resp.Results[i] = BatchItemResult{Response: created, Err: err}
+	}
+	return resp, nil
+}
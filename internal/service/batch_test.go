@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchTransfers(t *testing.T, accountRepo *testutil.MockAccountRepository, amounts ...int64) []CreatePaymentRequest {
+	t.Helper()
+	source := createTestAccount(t, "user1", 10000, account.StatusActive)
+	dest := createTestAccount(t, "user2", 0, account.StatusActive)
+	accountRepo.AddAccount(source)
+	accountRepo.AddAccount(dest)
+
+	reqs := make([]CreatePaymentRequest, len(amounts))
+	for i, amount := range amounts {
+		reqs[i] = CreatePaymentRequest{
+			PaymentType:          payment.InternalTransfer,
+			SourceAccountID:      &source.ID,
+			DestinationAccountID: &dest.ID,
+			Amount:               amount,
+			Currency:             "USD",
+		}
+	}
+	return reqs
+}
+
+func TestCreateBatch_PartialFailure(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	items := batchTransfers(t, accountRepo, 3000, 50000, 2000)
+	resp, err := svc.CreateBatch(ctx, CreateBatchRequest{IdempotencyKey: "batch-1", Payments: items})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, BatchID("batch-1"), resp.BatchID)
+
+	require.NoError(t, resp.Results[0].Err)
+	assert.ErrorIs(t, resp.Results[1].Err, domainErrors.ErrInsufficientFunds)
+	require.NoError(t, resp.Results[2].Err, "a failed entry does not stop the rest")
+
+	for _, i := range []int{0, 2} {
+		p := resp.Results[i].Response.Payment
+		require.NotNil(t, p.BatchID)
+		assert.Equal(t, resp.BatchID, *p.BatchID)
+	}
+	assert.Equal(t, int64(5000), accountRepo.GetAccountByID(*items[0].SourceAccountID).Balance)
+
+	grouped, err := paymentRepo.List(ctx, payment.ListFilter{BatchID: &resp.BatchID})
+	require.NoError(t, err)
+	ids := make([]uuid.UUID, 0, len(grouped))
+	for _, p := range grouped {
+		ids = append(ids, p.ID)
+	}
+	assert.Contains(t, ids, resp.Results[0].Response.Payment.ID)
+	assert.Contains(t, ids, resp.Results[2].Response.Payment.ID)
+}
+
+func TestCreateBatch_Replay(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	req := CreateBatchRequest{IdempotencyKey: "batch-2", Payments: batchTransfers(t, accountRepo, 1000, 2000)}
+	first, err := svc.CreateBatch(ctx, req)
+	require.NoError(t, err)
+
+	replay, err := svc.CreateBatch(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, first.BatchID, replay.BatchID)
+	for i, res := range replay.Results {
+		require.NoError(t, res.Err)
+		assert.Equal(t, OutcomeAlreadyExists, res.Response.Outcome)
+		assert.Equal(t, first.Results[i].Response.Payment.ID, res.Response.Payment.ID)
+	}
+	assert.Equal(t, int64(7000), accountRepo.GetAccountByID(*req.Payments[0].SourceAccountID).Balance,
+		"a replayed batch moves no money")
+}
+
+func TestCreateBatch_Rejected(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	svc.maxBatchSize = 2
+	ctx := context.Background()
+	items := batchTransfers(t, accountRepo, 100, 100, 100)
+
+	tests := []struct {
+		name  string
+		req   CreateBatchRequest
+		field string
+	}{
+		{"missing key", CreateBatchRequest{Payments: items[:1]}, "idempotency_key"},
+		{"empty", CreateBatchRequest{IdempotencyKey: "k"}, "payments"},
+		{"too large", CreateBatchRequest{IdempotencyKey: "k", Payments: items}, "payments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateBatch(ctx, tt.req)
+			var validationErr *domainErrors.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.field, validationErr.Field)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/rs/zerolog/log"
+)
+
+// BreakerResetNotifier tells other processes, such as workers, to reset a
+// provider's circuit breaker.
+type BreakerResetNotifier interface {
+	NotifyReset(ctx context.Context, provider string) error
+}
+
+func WithBreakerResetNotifier(n BreakerResetNotifier) PaymentServiceOption {
+	return func(s *PaymentService) { s.breakerResets = n }
+}
+
+// CircuitBreakers returns the state and counts of every provider's circuit
+// breaker in this process.
+func (s *PaymentService) CircuitBreakers() []providers.BreakerStatus {
+	return s.providerFactory.BreakerStatuses()
+}
+
+// ResetCircuitBreaker forces provider's breaker closed here and signals
+// other processes to do the same. It returns the breaker's new status.
+func (s *PaymentService) ResetCircuitBreaker(ctx context.Context, provider payment.Provider) (*providers.BreakerStatus, error) {
+	status, err := s.providerFactory.ResetBreaker(provider)
+	if err != nil {
+		return nil, err
+	}
+	operator, _ := middleware.GetUserID(ctx)
+	log.Info().Str("provider", string(provider)).Str("operator", operator).Msg("circuit breaker reset")
+
+	if s.breakerResets != nil {
+		if err := s.breakerResets.NotifyReset(ctx, string(provider)); err != nil {
+			return nil, fmt.Errorf("notify breaker reset: %w", err)
+		}
+	}
+	return &status, nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/sony/gobreaker/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingBreakerResets struct {
+	notified []string
+}
+
+func (n *recordingBreakerResets) NotifyReset(ctx context.Context, provider string) error {
+	n.notified = append(n.notified, provider)
+	return nil
+}
+
+func TestResetCircuitBreaker_NotifiesWorkers(t *testing.T) {
+	notifier := &recordingBreakerResets{}
+	svc := NewPaymentService(testutil.NewMockPaymentRepository(), testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe")),
+		WithBreakerResetNotifier(notifier))
+	ctx := context.Background()
+
+	status, err := svc.ResetCircuitBreaker(ctx, "stripe")
+	require.NoError(t, err)
+	assert.Equal(t, gobreaker.StateClosed, status.State)
+	assert.Equal(t, []string{"stripe"}, notifier.notified)
+
+	_, err = svc.ResetCircuitBreaker(ctx, "unknown")
+	assert.ErrorIs(t, err, domainErrors.ErrProviderNotFound)
+	assert.Len(t, notifier.notified, 1, "an unknown provider is not broadcast")
+}
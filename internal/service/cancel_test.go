@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memLocker is an in-process PaymentLocker. onLock, when set, runs after a
+// lock is granted and before TryLock returns.
+type memLocker struct {
+	mu       sync.Mutex
+	held     map[string]bool
+	onLock   func()
+	releases int
+}
+
+func newMemLocker() *memLocker {
+	return &memLocker{held: make(map[string]bool)}
+}
+
+func (l *memLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	l.mu.Lock()
+	if l.held[key] {
+		l.mu.Unlock()
+		return nil, false, nil
+	}
+	l.held[key] = true
+	l.mu.Unlock()
+	if l.onLock != nil {
+		l.onLock()
+	}
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, key)
+		l.releases++
+	}, true, nil
+}
+
+func setupGuardedCancel(t *testing.T) (*PaymentService, *testutil.MockPaymentRepository, *testutil.MockOutboxRepository, *memLocker) {
+	t.Helper()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	outboxRepo := &testutil.MockOutboxRepository{}
+	locker := newMemLocker()
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), outboxRepo,
+		testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe")),
+		WithCancelLock(locker, time.Minute))
+	return svc, paymentRepo, outboxRepo, locker
+}
+
+func TestCancelPayment_Pending_RemovesUnpublishedEntries(t *testing.T) {
+	svc, paymentRepo, outboxRepo, locker := setupGuardedCancel(t)
+	ctx := context.Background()
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	var deletedFor []uuid.UUID
+	outboxRepo.DeletePendingFunc = func(ctx context.Context, aggregateID uuid.UUID) (int64, error) {
+		deletedFor = append(deletedFor, aggregateID)
+		return 1, nil
+	}
+
+	resp, err := svc.CancelPayment(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusCancelled, resp.Payment.Status)
+	assert.Equal(t, []uuid.UUID{p.ID}, deletedFor)
+	assert.Equal(t, 1, locker.releases, "the lock is released")
+}
+
+func TestCancelPayment_WorkerHoldsLock_TooLate(t *testing.T) {
+	svc, paymentRepo, outboxRepo, locker := setupGuardedCancel(t)
+	ctx := context.Background()
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	require.NoError(t, paymentRepo.Create(ctx, p))
+	locker.held[PaymentLockKey(p.ID)] = true
+	outboxRepo.DeletePendingFunc = func(ctx context.Context, aggregateID uuid.UUID) (int64, error) {
+		t.Error("entries of a payment the worker holds are not removed")
+		return 0, nil
+	}
+
+	_, err := svc.CancelPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidStateTransition)
+	assert.Contains(t, err.Error(), "already being processed")
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusPending, stored.Status)
+}
+
+func TestCancelPayment_ClaimedBeforeLock_TooLate(t *testing.T) {
+	svc, paymentRepo, _, locker := setupGuardedCancel(t)
+	ctx := context.Background()
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	// A worker claims the payment after the cancel loaded it and lets go of
+	// the lock just before the cancel takes it.
+	locker.onLock = func() {
+		claimed, _ := paymentRepo.GetByID(ctx, p.ID)
+		require.NoError(t, claimed.MarkProcessing())
+		require.NoError(t, paymentRepo.Update(ctx, claimed))
+	}
+
+	_, err := svc.CancelPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidStateTransition)
+	assert.Contains(t, err.Error(), "already being processed")
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusProcessing, stored.Status, "the claim is not overwritten")
+	assert.Equal(t, 1, locker.releases)
+}
+
+func TestCancelPayment_Processing_NoNotifier_TooLate(t *testing.T) {
+	svc, paymentRepo, _, _ := setupGuardedCancel(t)
+	ctx := context.Background()
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	p.Status = payment.StatusProcessing
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	_, err := svc.CancelPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidStateTransition)
+	assert.Contains(t, err.Error(), "already being processed")
+}
+
+func TestCancelPayment_BeforeWorker_NotCharged(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	locker := newMemLocker()
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe")),
+		WithCancelLock(locker, time.Minute))
+	ctx := context.Background()
+
+	source := createTestAccount(t, "user1", 50000, account.StatusActive)
+	accountRepo.AddAccount(source)
+	p, err := payment.NewPayment("cancel-first", payment.ExternalPayment, &source.ID, nil, payment.NewAmount(10000, "USD"))
+	require.NoError(t, err)
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	_, err = svc.CancelPayment(ctx, p.ID)
+	require.NoError(t, err)
+
+	// The stream message was published before the cancel; the worker gets
+	// the lock once the cancel lets go of it.
+	unlock, acquired, err := locker.TryLock(ctx, PaymentLockKey(p.ID), time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+	unlock()
+
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusCancelled, stored.Status)
+	acct := accountRepo.GetAccountByID(source.ID)
+	assert.Equal(t, int64(50000), acct.Balance, "a cancelled payment is not charged")
+	assert.Zero(t, acct.HeldBalance)
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/google/uuid"
+)
+
+var errDisputesDisabled = errors.New("dispute handling is not configured")
+
+// HandleDispute applies a provider dispute notification. Notifications are
+// idempotent: a repeated "opened" returns the existing dispute, and a repeated
+// resolution with the same outcome is a no-op.
+func (s *PaymentService) HandleDispute(ctx context.Context, n DisputeNotification) (*payment.Dispute, error) {
+	if s.disputeRepo == nil {
+		return nil, errDisputesDisabled
+	}
+
+	existing, err := s.disputeRepo.GetByProviderID(ctx, n.Provider, n.ProviderDisputeID)
+	if err != nil && !errors.Is(err, domainErrors.ErrDisputeNotFound) {
+		return nil, err
+	}
+
+	switch n.Event {
+	case DisputeOpened:
+		if existing != nil {
+			return existing, nil
+		}
+		return s.openDispute(ctx, n)
+	case DisputeWon, DisputeLost:
+		if existing == nil {
+			return nil, domainErrors.ErrDisputeNotFound
+		}
+		if existing.Status == payment.DisputeStatus(n.Event) {
+			return existing, nil
+		}
+		return s.resolveDispute(ctx, existing, payment.DisputeStatus(n.Event))
+	default:
+		return nil, domainErrors.NewValidationError("event", "must be opened, won or lost")
+	}
+}
+
+func (s *PaymentService) ListDisputes(ctx context.Context, paymentID uuid.UUID) ([]*payment.Dispute, error) {
+	if s.disputeRepo == nil {
+		return nil, errDisputesDisabled
+	}
+	if _, err := s.paymentRepo.GetByID(ctx, paymentID); err != nil {
+		return nil, err
+	}
+	return s.disputeRepo.ListByPayment(ctx, paymentID)
+}
+
+// DisputeCurrency is the currency a dispute on paymentID is raised in: the
+// payment's own.
+func (s *PaymentService) DisputeCurrency(ctx context.Context, paymentID uuid.UUID) (string, error) {
+	p, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		return "", domainErrors.ErrPaymentNotFound
+	}
+	return p.Amount.Currency, nil
+}
+
+func (s *PaymentService) openDispute(ctx context.Context, n DisputeNotification) (*payment.Dispute, error) {
+	var d *payment.Dispute
+	err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		p, err := s.paymentRepo.GetByID(txCtx, n.PaymentID)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return domainErrors.ErrPaymentNotFound
+		}
+		if p.Provider == nil || *p.Provider != n.Provider {
+			return domainErrors.NewValidationError("payment_id", "payment was not processed by this provider")
+		}
+
+		amount := n.Amount
+		if amount == 0 {
+			amount = p.Amount.ValueCents
+		}
+		if d, err = payment.NewDispute(p, n.Provider, n.ProviderDisputeID, n.Reason, amount); err != nil {
+			return err
+		}
+		if err := p.MarkDisputed(); err != nil {
+			return err
+		}
+
+		if s.disputePolicy == DisputeReverse {
+			if err := s.reverseDisputedFunds(txCtx, p, d.AmountCents); err != nil {
+				return err
+			}
+			d.FundsReversed = true
+		}
+
+		if err := s.disputeRepo.Create(txCtx, d); err != nil {
+			return err
+		}
+		if err := s.paymentRepo.Update(txCtx, p); err != nil {
+			return err
+		}
+		return s.paymentRepo.AddEvent(txCtx, &payment.PaymentEvent{
+			ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentDisputed),
+			EventData: map[string]any{
+				"dispute_id":     d.ID.String(),
+				"amount_cents":   d.AmountCents,
+				"reason":         d.Reason,
+				"funds_reversed": d.FundsReversed,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// resolveDispute settles a dispute. Won disputes restore reversed funds and
+// return the payment to completed; lost disputes reverse any held funds and
+// mark the payment charged back.
+func (s *PaymentService) resolveDispute(ctx context.Context, d *payment.Dispute, outcome payment.DisputeStatus) (*payment.Dispute, error) {
+	err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		p, err := s.paymentRepo.GetByID(txCtx, d.PaymentID)
+		if err != nil {
+			return err
+		}
+		if err := d.Resolve(outcome); err != nil {
+			return err
+		}
+
+		eventType := payment.EventDisputeWon
+		if outcome == payment.DisputeWon {
+			if d.FundsReversed {
+				if err := s.restoreDisputedFunds(txCtx, p, d.AmountCents); err != nil {
+					return err
+				}
+				d.FundsReversed = false
+			}
+			err = p.MarkDisputeWon()
+		} else {
+			if !d.FundsReversed {
+				if err := s.reverseDisputedFunds(txCtx, p, d.AmountCents); err != nil {
+					return err
+				}
+				d.FundsReversed = true
+			}
+			eventType = payment.EventPaymentChargedBack
+			err = p.MarkChargedBack()
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.disputeRepo.Update(txCtx, d); err != nil {
+			return err
+		}
+		if err := s.paymentRepo.Update(txCtx, p); err != nil {
+			return err
+		}
+		return s.paymentRepo.AddEvent(txCtx, &payment.PaymentEvent{
+			ID: uuid.New(), PaymentID: p.ID, EventType: string(eventType),
+			EventData: map[string]any{
+				"dispute_id":   d.ID.String(),
+				"amount_cents": d.AmountCents,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// reverseDisputedFunds debits the merchant (destination) side and credits the
+// payer (source) side of a disputed payment, where those accounts exist.
+func (s *PaymentService) reverseDisputedFunds(ctx context.Context, p *payment.Payment, amount int64) error {
+	if p.DestinationAccountID != nil {
+		if _, err := s.debitAccount(ctx, *p.DestinationAccountID, p.ID, amount, "dispute reversal"); err != nil {
+			return fmt.Errorf("reverse disputed funds: %w", err)
+		}
+	}
+	if p.SourceAccountID != nil {
+		if _, err := s.creditAccount(ctx, *p.SourceAccountID, p.ID, amount, "dispute reversal"); err != nil {
+			return fmt.Errorf("reverse disputed funds: %w", err)
+		}
+	}
+	return nil
+}
+
+// restoreDisputedFunds undoes reverseDisputedFunds after a won dispute.
+func (s *PaymentService) restoreDisputedFunds(ctx context.Context, p *payment.Payment, amount int64) error {
+	if p.SourceAccountID != nil {
+		if _, err := s.debitAccount(ctx, *p.SourceAccountID, p.ID, amount, "dispute won"); err != nil {
+			return fmt.Errorf("restore disputed funds: %w", err)
+		}
+	}
+	if p.DestinationAccountID != nil {
+		if _, err := s.creditAccount(ctx, *p.DestinationAccountID, p.ID, amount, "dispute won"); err != nil {
+			return fmt.Errorf("restore disputed funds: %w", err)
+		}
+	}
+	return nil
+}
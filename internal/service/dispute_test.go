@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- Test Helpers ---
+
+func setupDisputeService(t *testing.T, policy DisputePolicy) (*PaymentService, *testutil.MockPaymentRepository, *testutil.MockAccountRepository, *payment.Payment) {
+	t.Helper()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(),
+		WithDisputes(testutil.NewMockDisputeRepository(), policy))
+
+	source := createTestAccount(t, "payer", 0, account.StatusActive)
+	accountRepo.AddAccount(source)
+
+	p := testutil.NewCompletedPayment(payment.ExternalPayment, &source.ID, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	paymentRepo.Create(context.Background(), p)
+	return svc, paymentRepo, accountRepo, p
+}
+
+func disputeNotification(p *payment.Payment, event DisputeEvent) DisputeNotification {
+	return DisputeNotification{
+		Provider:          payment.ProviderStripe,
+		ProviderDisputeID: "dp_123",
+		PaymentID:         p.ID,
+		Event:             event,
+		Reason:            "fraudulent",
+	}
+}
+
+// --- HandleDispute Tests ---
+
+func TestHandleDispute_OpenedWithReversePolicy_ReversesFunds(t *testing.T) {
+	svc, paymentRepo, accountRepo, p := setupDisputeService(t, DisputeReverse)
+	ctx := context.Background()
+
+	d, err := svc.HandleDispute(ctx, disputeNotification(p, DisputeOpened))
+	require.NoError(t, err)
+	assert.Equal(t, payment.DisputeOpen, d.Status)
+	assert.True(t, d.FundsReversed)
+	assert.Equal(t, int64(10000), d.AmountCents)
+
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusDisputed, stored.Status)
+	assert.Equal(t, int64(10000), accountRepo.GetAccountByID(*p.SourceAccountID).Balance)
+}
+
+func TestHandleDispute_OpenedTwice_ReturnsExisting(t *testing.T) {
+	svc, _, accountRepo, p := setupDisputeService(t, DisputeReverse)
+	ctx := context.Background()
+
+	first, err := svc.HandleDispute(ctx, disputeNotification(p, DisputeOpened))
+	require.NoError(t, err)
+	second, err := svc.HandleDispute(ctx, disputeNotification(p, DisputeOpened))
+	require.NoError(t, err)
+
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, int64(10000), accountRepo.GetAccountByID(*p.SourceAccountID).Balance)
+}
+
+func TestHandleDispute_WonAfterReverse_RestoresFunds(t *testing.T) {
+	svc, paymentRepo, accountRepo, p := setupDisputeService(t, DisputeReverse)
+	ctx := context.Background()
+
+	_, err := svc.HandleDispute(ctx, disputeNotification(p, DisputeOpened))
+	require.NoError(t, err)
+	d, err := svc.HandleDispute(ctx, disputeNotification(p, DisputeWon))
+	require.NoError(t, err)
+
+	assert.Equal(t, payment.DisputeWon, d.Status)
+	assert.False(t, d.FundsReversed)
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusCompleted, stored.Status)
+	assert.Equal(t, int64(0), accountRepo.GetAccountByID(*p.SourceAccountID).Balance)
+}
+
+func TestHandleDispute_LostWithHoldPolicy_ReversesOnLoss(t *testing.T) {
+	svc, paymentRepo, accountRepo, p := setupDisputeService(t, DisputeHold)
+	ctx := context.Background()
+
+	d, err := svc.HandleDispute(ctx, disputeNotification(p, DisputeOpened))
+	require.NoError(t, err)
+	assert.False(t, d.FundsReversed)
+	assert.Equal(t, int64(0), accountRepo.GetAccountByID(*p.SourceAccountID).Balance)
+
+	d, err = svc.HandleDispute(ctx, disputeNotification(p, DisputeLost))
+	require.NoError(t, err)
+	assert.Equal(t, payment.DisputeLost, d.Status)
+	assert.True(t, d.FundsReversed)
+
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusChargedBack, stored.Status)
+	assert.Equal(t, int64(10000), accountRepo.GetAccountByID(*p.SourceAccountID).Balance)
+}
+
+func TestHandleDispute_ResolutionWithoutDispute_NotFound(t *testing.T) {
+	svc, _, _, p := setupDisputeService(t, DisputeReverse)
+
+	_, err := svc.HandleDispute(context.Background(), disputeNotification(p, DisputeWon))
+	assert.ErrorIs(t, err, domainErrors.ErrDisputeNotFound)
+}
+
+func TestHandleDispute_OtherProvider_Rejected(t *testing.T) {
+	svc, _, _, p := setupDisputeService(t, DisputeReverse)
+	n := disputeNotification(p, DisputeOpened)
+	n.Provider = payment.ProviderPayPal
+
+	_, err := svc.HandleDispute(context.Background(), n)
+	var validationErr *domainErrors.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
+
+func TestListDisputes_ReturnsPaymentDisputes(t *testing.T) {
+	svc, _, _, p := setupDisputeService(t, DisputeReverse)
+	ctx := context.Background()
+
+	_, err := svc.HandleDispute(ctx, disputeNotification(p, DisputeOpened))
+	require.NoError(t, err)
+
+	disputes, err := svc.ListDisputes(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Len(t, disputes, 1)
+}
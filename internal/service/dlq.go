@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	infraRedis "github.com/cassiomorais/payments/internal/infrastructure/redis"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultDLQPageSize = 50
+	maxDLQPageSize     = 500
+)
+
+// DeadLetterStore reads the dead-letter stream and moves payment entries
+// back onto the payment stream.
+type DeadLetterStore interface {
+	List(ctx context.Context, count int64) ([]infraRedis.DLQEntry, error)
+	Get(ctx context.Context, id string) (*infraRedis.DLQEntry, error)
+	Requeue(ctx context.Context, entry infraRedis.DLQEntry) error
+}
+
+// ListDeadLetters returns up to limit DLQ entries, oldest first. limit
+// defaults to 50 and is capped at 500.
+func (s *PaymentService) ListDeadLetters(ctx context.Context, limit int) ([]infraRedis.DLQEntry, error) {
+	if s.deadLetters == nil {
+		return nil, fmt.Errorf("dead letters are not configured")
+	}
+	if limit <= 0 {
+		limit = defaultDLQPageSize
+	}
+	return s.deadLetters.List(ctx, int64(min(limit, maxDLQPageSize)))
+}
+
+// ReplayFromDLQ re-enqueues a dead-lettered payment message after operator
+// review. An abandoned payment is reopened with one more attempt, so the
+// replay is processed instead of dead-lettered again.
+func (s *PaymentService) ReplayFromDLQ(ctx context.Context, entryID string) (*infraRedis.DLQEntry, error) {
+	if s.deadLetters == nil {
+		return nil, fmt.Errorf("dead letters are not configured")
+	}
+	entry, err := s.deadLetters.Get(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, domainErrors.ErrDeadLetterNotFound
+	}
+	paymentID, err := uuid.Parse(entry.PaymentID)
+	if err != nil {
+		return nil, domainErrors.NewValidationError("id", "only payment entries can be replayed")
+	}
+
+	p, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, domainErrors.ErrPaymentNotFound
+	}
+	if p.Status == payment.StatusAbandoned {
+		if err := p.Reopen(); err != nil {
+			return nil, err
+		}
+		if err := s.paymentRepo.Update(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.deadLetters.Requeue(ctx, *entry); err != nil {
+		return nil, err
+	}
+
+	operator, _ := middleware.GetUserID(ctx)
+	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventDLQReplayed),
+		EventData: map[string]any{
+			"dlq_entry_id": entry.ID,
+			"reason":       entry.Reason,
+			"replayed_by":  operator,
+			"max_retries":  p.MaxRetries,
+		},
+	})
+	log.Info().Str("payment_id", p.ID.String()).Str("dlq_entry_id", entry.ID).Msg("payment replayed from DLQ")
+	return entry, nil
+}
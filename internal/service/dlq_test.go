@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	infraRedis "github.com/cassiomorais/payments/internal/infrastructure/redis"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDeadLetters struct {
+	entries   map[string]infraRedis.DLQEntry
+	requeued  []string
+	listCount int64
+}
+
+func (f *fakeDeadLetters) List(ctx context.Context, count int64) ([]infraRedis.DLQEntry, error) {
+	f.listCount = count
+	return nil, nil
+}
+
+func (f *fakeDeadLetters) Get(ctx context.Context, id string) (*infraRedis.DLQEntry, error) {
+	e, ok := f.entries[id]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+func (f *fakeDeadLetters) Requeue(ctx context.Context, entry infraRedis.DLQEntry) error {
+	f.requeued = append(f.requeued, entry.ID)
+	return nil
+}
+
+// setupDLQ dead-letters an abandoned external payment.
+func setupDLQ(t *testing.T) (*PaymentService, *testutil.MockPaymentRepository, *fakeDeadLetters, *payment.Payment) {
+	t.Helper()
+	svc, paymentRepo, _, _, _ := setupPaymentService()
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 5000, "USD")
+	require.NoError(t, p.SetMaxRetries(1))
+	require.NoError(t, p.MarkProcessing())
+	require.NoError(t, p.MarkFailed("provider down"))
+	require.NoError(t, p.IncrementRetry())
+	require.NoError(t, p.MarkAbandoned())
+	require.NoError(t, paymentRepo.Create(context.Background(), p))
+
+	store := &fakeDeadLetters{entries: map[string]infraRedis.DLQEntry{
+		"1-0": {ID: "1-0", PaymentID: p.ID.String(), Reason: "max retries exceeded",
+			Values: map[string]any{"payment_id": p.ID.String()}},
+		"2-0": {ID: "2-0", WebhookID: "wh-1", Reason: "endpoint returned 500"},
+	}}
+	WithDeadLetters(store)(svc)
+	return svc, paymentRepo, store, p
+}
+
+func TestReplayFromDLQ_GrantsAnotherAttempt(t *testing.T) {
+	svc, paymentRepo, store, p := setupDLQ(t)
+	ctx := context.Background()
+
+	entry, err := svc.ReplayFromDLQ(ctx, "1-0")
+	require.NoError(t, err)
+	assert.Equal(t, "1-0", entry.ID)
+	assert.Equal(t, []string{"1-0"}, store.requeued)
+
+	updated, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusFailed, updated.Status)
+	assert.Equal(t, 2, updated.MaxRetries)
+	assert.True(t, updated.CanRetry())
+
+	events, _ := paymentRepo.GetEvents(ctx, p.ID)
+	require.NotEmpty(t, events)
+	assert.Equal(t, string(payment.EventDLQReplayed), events[len(events)-1].EventType)
+}
+
+func TestReplayFromDLQ_Errors(t *testing.T) {
+	svc, _, store, _ := setupDLQ(t)
+	ctx := context.Background()
+
+	_, err := svc.ReplayFromDLQ(ctx, "9-0")
+	assert.ErrorIs(t, err, domainErrors.ErrDeadLetterNotFound)
+
+	_, err = svc.ReplayFromDLQ(ctx, "2-0")
+	var ve *domainErrors.ValidationError
+	assert.ErrorAs(t, err, &ve, "webhook entries are not payment messages")
+	assert.Empty(t, store.requeued)
+}
+
+func TestListDeadLetters_CapsLimit(t *testing.T) {
+	svc, _, store, _ := setupDLQ(t)
+
+	_, err := svc.ListDeadLetters(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(defaultDLQPageSize), store.listCount)
+
+	_, err = svc.ListDeadLetters(context.Background(), 10000)
+	require.NoError(t, err)
+	assert.Equal(t, int64(maxDLQPageSize), store.listCount)
+}
@@ -1,6 +1,10 @@
 package service
 
 import (
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	"github.com/cassiomorais/payments/internal/domain/outbox"
 	"github.com/cassiomorais/payments/internal/domain/payment"
 	"github.com/google/uuid"
 )
@@ -11,6 +15,7 @@ type CreateAccountRequest struct {
 	UserID         string
 	InitialBalance int64 // in cents
 	Currency       string
+	Label          string // "" for the user's default account in Currency
 }
 
 
@@ -23,11 +28,101 @@ type CreatePaymentRequest struct {
 	Amount               int64 // in cents
 	Currency             string
 	Provider             *payment.Provider
+	Preference           ProcessingPreference
+	Description          string
+	// ExchangeRate converts an internal transfer into the destination
+	// account's currency (destination units per source unit). Required when
+	// the currencies differ, rejected otherwise.
+	ExchangeRate *float64
+	// ScheduledAt defers the payment until that time. A scheduled payment is
+	// persisted without executing and is processed once the scheduler finds
+	// it due, whatever the processing preference.
+	ScheduledAt *time.Time
+	// BatchID groups the payment with the others of a batch request; set by
+	// CreateBatch.
+	BatchID *uuid.UUID
+	// ProviderOptions carries provider-specific fields of an external
+	// payment, keyed by provider name. The chosen provider's required
+	// options are checked at creation.
+	ProviderOptions payment.ProviderOptions
+	// FeeCents is a platform fee deducted from an external payment: on
+	// completion the destination is credited Amount less the fee and the
+	// platform fee account the fee.
+	FeeCents int64
+	// DryRun validates the request and projects its outcome without
+	// persisting anything or consuming IdempotencyKey; see PaymentPreview.
+	DryRun bool
+}
+
+// CreateBatchRequest submits several payments under one idempotency key.
+// Each entry's IdempotencyKey and BatchID are derived from the batch and
+// overwritten.
+type CreateBatchRequest struct {
+	IdempotencyKey string
+	Payments       []CreatePaymentRequest
+}
+
+// BatchItemResult is the outcome of one batch entry: Response on success,
+// Err otherwise.
+type BatchItemResult struct {
+	Response *CreatePaymentResponse
+	Err      error
+}
+
+// CreateBatchResponse reports every entry of a batch, in request order.
+type CreateBatchResponse struct {
+	BatchID uuid.UUID
+	Results []BatchItemResult
 }
 
+// ProcessingPreference is a client's request to override the default
+// sync/async routing of a payment type (RFC 7240 Prefer header).
+type ProcessingPreference string
+
+const (
+	PreferDefault ProcessingPreference = ""
+	PreferAsync   ProcessingPreference = "respond-async"
+	PreferSync    ProcessingPreference = "respond-sync"
+)
+
+// CreateOutcome tells the caller what a CreatePayment call actually did.
+type CreateOutcome string
+
+const (
+	OutcomeCreated       CreateOutcome = "created"        // completed synchronously
+	OutcomeAccepted      CreateOutcome = "accepted"       // queued for async processing
+	OutcomeAlreadyExists CreateOutcome = "already_exists" // idempotent replay of an earlier request
+	OutcomePreview       CreateOutcome = "preview"        // dry run; nothing was persisted
+)
+
 type CreatePaymentResponse struct {
 	Payment *payment.Payment
 	IsAsync bool
+	Outcome CreateOutcome
+	// PreferenceApplied is the requested preference when it was honored, and
+	// PreferDefault otherwise.
+	PreferenceApplied ProcessingPreference
+	// Preview is set, instead of a persisted Payment, for a dry run.
+	Preview *PaymentPreview
+}
+
+// PaymentRouting is how a payment would be processed once created.
+type PaymentRouting string
+
+const (
+	RoutingSync      PaymentRouting = "sync"      // settled within the request
+	RoutingAsync     PaymentRouting = "async"     // queued for the worker
+	RoutingScheduled PaymentRouting = "scheduled" // held until its scheduled time
+)
+
+// PaymentPreview is the projected outcome of a dry-run CreatePayment. The
+// response's Payment holds the fee and any conversion but was not persisted.
+type PaymentPreview struct {
+	Routing PaymentRouting
+	// SourceBalanceAfter and DestinationBalanceAfter are the accounts'
+	// balances once the payment settles, nil for a side without an account.
+	SourceBalanceAfter      *int64
+	DestinationBalanceAfter *int64
 }
 
 type TransferRequest struct {
@@ -36,4 +131,56 @@ type TransferRequest struct {
 	DestinationAccountID uuid.UUID
 	Amount               int64 // in cents
 	Currency             string
+	Description          string
+	ExchangeRate         *float64 // see CreatePaymentRequest.ExchangeRate
+}
+
+// TransferToNewAccountRequest transfers into DestinationUserID's account in
+// Currency, creating that account first if the user has none.
+type TransferToNewAccountRequest struct {
+	IdempotencyKey    string
+	SourceAccountID   uuid.UUID
+	DestinationUserID string
+	Amount            int64 // in cents
+	Currency          string
+	Description       string
+}
+
+type TransferToNewAccountResponse struct {
+	*CreatePaymentResponse
+	Destination *account.Account
+	// DestinationCreated reports whether this request created Destination.
+	DestinationCreated bool
+}
+
+// ReemitEventsResponse lists the outbox entries re-emitted for a payment.
+type ReemitEventsResponse struct {
+	PaymentID uuid.UUID
+	Entries   []*outbox.Entry
+}
+
+type CancelPaymentResponse struct {
+	Payment *payment.Payment
+	// InFlight is set when the payment was already being processed and a
+	// cancel signal was sent to the worker instead of cancelling it directly.
+	InFlight bool
+}
+
+// DisputeEvent is the lifecycle step a provider dispute notification reports.
+type DisputeEvent string
+
+const (
+	DisputeOpened DisputeEvent = "opened"
+	DisputeWon    DisputeEvent = "won"
+	DisputeLost   DisputeEvent = "lost"
+)
+
+// Controllers convert provider dispute webhooks to this type.
+type DisputeNotification struct {
+	Provider          payment.Provider
+	ProviderDisputeID string
+	PaymentID         uuid.UUID
+	Event             DisputeEvent
+	Reason            string
+	Amount            int64 // in cents; 0 disputes the full payment amount
 }
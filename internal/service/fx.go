@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+)
+
+// CurrencyPair is a directed FX corridor, written "USD->EUR".
+type CurrencyPair struct {
+	From string
+	To   string
+}
+
+func (p CurrencyPair) String() string {
+	return p.From + "->" + p.To
+}
+
+// ParseCurrencyPair parses a "FROM->TO" pair of ISO currency codes.
+func ParseCurrencyPair(s string) (CurrencyPair, error) {
+	from, to, ok := strings.Cut(s, "->")
+	from, to = strings.ToUpper(strings.TrimSpace(from)), strings.ToUpper(strings.TrimSpace(to))
+	if !ok || len(from) != 3 || len(to) != 3 || from == to {
+		return CurrencyPair{}, fmt.Errorf("invalid currency pair %q: want FROM->TO", s)
+	}
+	return CurrencyPair{From: from, To: to}, nil
+}
+
+// ExchangeRateProvider quotes how many units of to one unit of from buys.
+type ExchangeRateProvider interface {
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// FXPolicy restricts currency conversion to an allowlist of corridors. Pairs
+// are directed: allowing USD->EUR does not allow EUR->USD.
+type FXPolicy struct {
+	allowed map[CurrencyPair]struct{}
+	rates   ExchangeRateProvider
+}
+
+func NewFXPolicy(pairs []CurrencyPair, rates ExchangeRateProvider) *FXPolicy {
+	allowed := make(map[CurrencyPair]struct{}, len(pairs))
+	for _, p := range pairs {
+		allowed[p] = struct{}{}
+	}
+	return &FXPolicy{allowed: allowed, rates: rates}
+}
+
+// CheckPair returns ErrUnsupportedCurrencyPair unless from->to is allowed.
+func (f *FXPolicy) CheckPair(from, to string) error {
+	if _, ok := f.allowed[CurrencyPair{From: from, To: to}]; !ok {
+		return fmt.Errorf("%s->%s: %w", from, to, domainErrors.ErrUnsupportedCurrencyPair)
+	}
+	return nil
+}
+
+// Rate returns the exchange rate for an allowed pair. The provider is never
+// consulted for pairs outside the allowlist.
+func (f *FXPolicy) Rate(ctx context.Context, from, to string) (float64, error) {
+	if err := f.CheckPair(from, to); err != nil {
+		return 0, err
+	}
+	if f.rates == nil {
+		return 0, domainErrors.ErrExchangeRateUnavailable
+	}
+	rate, err := f.rates.Rate(ctx, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("%s->%s: %w: %v", from, to, domainErrors.ErrExchangeRateUnavailable, err)
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("%s->%s: %w: non-positive rate", from, to, domainErrors.ErrExchangeRateUnavailable)
+	}
+	return rate, nil
+}
+
+// applyConversion sets an internal transfer to credit its destination in
+// destCurrency at the client's rate. Conversion must be enabled with WithFX
+// and the corridor allowed; without a differing currency no rate may be given.
+func (s *PaymentService) applyConversion(p *payment.Payment, destCurrency string, rate *float64) error {
+	if destCurrency == p.Amount.Currency {
+		if rate != nil {
+			return domainErrors.NewValidationError("exchange_rate", "only applies between different currencies")
+		}
+		return nil
+	}
+	if rate == nil {
+		return domainErrors.NewValidationError("exchange_rate",
+			fmt.Sprintf("required to transfer %s into a %s account", p.Amount.Currency, destCurrency))
+	}
+	if s.fx == nil {
+		return fmt.Errorf("%s->%s: %w", p.Amount.Currency, destCurrency, domainErrors.ErrUnsupportedCurrencyPair)
+	}
+	if err := s.fx.CheckPair(p.Amount.Currency, destCurrency); err != nil {
+		return err
+	}
+	return p.SetConversion(*rate, destCurrency)
+}
+
+// withConversion adds a cross-currency transfer's rate and credited amount
+// to event data.
+func withConversion(p *payment.Payment, data map[string]any) map[string]any {
+	if c := p.Conversion; c != nil {
+		data["exchange_rate"] = c.Rate
+		data["credited_cents"] = c.Credited.ValueCents
+		data["credited_currency"] = c.Credited.Currency
+	}
+	return data
+}
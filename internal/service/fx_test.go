@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRateProvider struct {
+	rate  float64
+	err   error
+	calls int
+}
+
+func (p *stubRateProvider) Rate(ctx context.Context, from, to string) (float64, error) {
+	p.calls++
+	return p.rate, p.err
+}
+
+func TestParseCurrencyPair(t *testing.T) {
+	pair, err := ParseCurrencyPair("usd->EUR")
+	require.NoError(t, err)
+	assert.Equal(t, CurrencyPair{From: "USD", To: "EUR"}, pair)
+	assert.Equal(t, "USD->EUR", pair.String())
+
+	for _, s := range []string{"USDEUR", "USD->USD", "US->EUR", ""} {
+		_, err := ParseCurrencyPair(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFXPolicy_AllowedPair_ConsultsProvider(t *testing.T) {
+	rates := &stubRateProvider{rate: 0.92}
+	fx := NewFXPolicy([]CurrencyPair{{From: "USD", To: "EUR"}}, rates)
+
+	rate, err := fx.Rate(context.Background(), "USD", "EUR")
+
+	require.NoError(t, err)
+	assert.Equal(t, 0.92, rate)
+	assert.Equal(t, 1, rates.calls)
+}
+
+func TestFXPolicy_DisallowedPair_SkipsProvider(t *testing.T) {
+	rates := &stubRateProvider{rate: 1.08}
+	fx := NewFXPolicy([]CurrencyPair{{From: "USD", To: "EUR"}}, rates)
+
+	// Pairs are directed, so the reverse corridor is not allowed.
+	_, err := fx.Rate(context.Background(), "EUR", "USD")
+
+	assert.ErrorIs(t, err, domainErrors.ErrUnsupportedCurrencyPair)
+	assert.Zero(t, rates.calls)
+}
+
+func TestFXPolicy_ProviderFailure(t *testing.T) {
+	fx := NewFXPolicy([]CurrencyPair{{From: "USD", To: "BRL"}}, &stubRateProvider{err: errors.New("feed down")})
+
+	_, err := fx.Rate(context.Background(), "USD", "BRL")
+
+	assert.ErrorIs(t, err, domainErrors.ErrExchangeRateUnavailable)
+}
+
+func TestFXPolicy_NoProvider(t *testing.T) {
+	fx := NewFXPolicy([]CurrencyPair{{From: "USD", To: "BRL"}}, nil)
+
+	assert.NoError(t, fx.CheckPair("USD", "BRL"))
+	_, err := fx.Rate(context.Background(), "USD", "BRL")
+	assert.ErrorIs(t, err, domainErrors.ErrExchangeRateUnavailable)
+}
+
+func setupFXTransfer(t *testing.T, opts ...PaymentServiceOption) (*PaymentService, *testutil.MockPaymentRepository, *testutil.MockAccountRepository, *account.Account, *account.Account) {
+	t.Helper()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(), opts...)
+	usd := testutil.NewTestAccount("user1", 100000, "USD")
+	eur := testutil.NewTestAccount("user2", 5000, "EUR")
+	accountRepo.AddAccount(usd)
+	accountRepo.AddAccount(eur)
+	return svc, paymentRepo, accountRepo, usd, eur
+}
+
+func withUSDToEUR() PaymentServiceOption {
+	return WithFX(NewFXPolicy([]CurrencyPair{{From: "USD", To: "EUR"}}, nil))
+}
+
+func TestTransfer_CrossCurrency_ConvertsCredit(t *testing.T) {
+	svc, _, accountRepo, usd, eur := setupFXTransfer(t, withUSDToEUR())
+	ctx := context.Background()
+	rate := 0.92
+
+	resp, err := svc.Transfer(ctx, TransferRequest{
+		IdempotencyKey: "fx-1", SourceAccountID: usd.ID, DestinationAccountID: eur.ID,
+		Amount: 10000, Currency: "USD", ExchangeRate: &rate,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusCompleted, resp.Payment.Status)
+	require.NotNil(t, resp.Payment.Conversion)
+	assert.Equal(t, payment.NewAmount(9200, "EUR"), resp.Payment.Conversion.Credited)
+
+	src, _ := accountRepo.GetByID(ctx, usd.ID)
+	dst, _ := accountRepo.GetByID(ctx, eur.ID)
+	assert.Equal(t, int64(90000), src.Balance)
+	assert.Equal(t, int64(14200), dst.Balance)
+
+	debits, _ := accountRepo.GetTransactions(ctx, usd.ID, 10, 0)
+	require.Len(t, debits, 1)
+	assert.Equal(t, int64(10000), debits[0].Amount)
+	credits, _ := accountRepo.GetTransactions(ctx, eur.ID, 10, 0)
+	require.Len(t, credits, 1)
+	assert.Equal(t, int64(9200), credits[0].Amount)
+	assert.Equal(t, int64(14200), credits[0].BalanceAfter)
+}
+
+func TestTransfer_CrossCurrency_RequiresRate(t *testing.T) {
+	svc, _, _, usd, eur := setupFXTransfer(t, withUSDToEUR())
+
+	_, err := svc.Transfer(context.Background(), TransferRequest{
+		IdempotencyKey: "fx-2", SourceAccountID: usd.ID, DestinationAccountID: eur.ID, Amount: 10000, Currency: "USD",
+	})
+	var validationErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "exchange_rate", validationErr.Field)
+}
+
+func TestTransfer_CrossCurrency_PairMustBeAllowed(t *testing.T) {
+	rate := 1.08
+	svc, _, _, usd, eur := setupFXTransfer(t, withUSDToEUR())
+	_, err := svc.Transfer(context.Background(), TransferRequest{
+		IdempotencyKey: "fx-3", SourceAccountID: eur.ID, DestinationAccountID: usd.ID,
+		Amount: 1000, Currency: "EUR", ExchangeRate: &rate,
+	})
+	assert.ErrorIs(t, err, domainErrors.ErrUnsupportedCurrencyPair, "pairs are directed")
+
+	svc, _, _, usd, eur = setupFXTransfer(t)
+	rate = 0.92
+	_, err = svc.Transfer(context.Background(), TransferRequest{
+		IdempotencyKey: "fx-4", SourceAccountID: usd.ID, DestinationAccountID: eur.ID,
+		Amount: 1000, Currency: "USD", ExchangeRate: &rate,
+	})
+	assert.ErrorIs(t, err, domainErrors.ErrUnsupportedCurrencyPair, "conversion is disabled without WithFX")
+}
+
+func TestTransfer_SameCurrency_RejectsRate(t *testing.T) {
+	svc, _, accountRepo, usd, _ := setupFXTransfer(t, withUSDToEUR())
+	other := testutil.NewTestAccount("user3", 0, "USD")
+	accountRepo.AddAccount(other)
+	rate := 1.0
+
+	_, err := svc.Transfer(context.Background(), TransferRequest{
+		IdempotencyKey: "fx-5", SourceAccountID: usd.ID, DestinationAccountID: other.ID,
+		Amount: 1000, Currency: "USD", ExchangeRate: &rate,
+	})
+	var validationErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "exchange_rate", validationErr.Field)
+}
+
+func TestRefundPayment_CrossCurrency_ReversesCreditedAmount(t *testing.T) {
+	svc, _, accountRepo, usd, eur := setupFXTransfer(t, withUSDToEUR())
+	ctx := context.Background()
+	rate := 0.92
+
+	resp, err := svc.Transfer(ctx, TransferRequest{
+		IdempotencyKey: "fx-6", SourceAccountID: usd.ID, DestinationAccountID: eur.ID,
+		Amount: 10000, Currency: "USD", ExchangeRate: &rate,
+	})
+	require.NoError(t, err)
+	_, err = svc.RefundPayment(ctx, resp.Payment.ID)
+	require.NoError(t, err)
+
+	src, _ := accountRepo.GetByID(ctx, usd.ID)
+	dst, _ := accountRepo.GetByID(ctx, eur.ID)
+	assert.Equal(t, int64(100000), src.Balance)
+	assert.Equal(t, int64(5000), dst.Balance)
+}
+
+func TestRefundPayment_CrossCurrency_ReversesExactRecordedAmounts(t *testing.T) {
+	svc, paymentRepo, accountRepo, usd, eur := setupFXTransfer(t, withUSDToEUR())
+	ctx := context.Background()
+	rate := 0.9137
+
+	resp, err := svc.Transfer(ctx, TransferRequest{
+		IdempotencyKey: "fx-8", SourceAccountID: usd.ID, DestinationAccountID: eur.ID,
+		Amount: 12345, Currency: "USD", ExchangeRate: &rate,
+	})
+	require.NoError(t, err)
+	// 12345 * 0.9137 = 11279.6265, rounded to 11280.
+	credited := resp.Payment.Conversion.Credited.ValueCents
+	require.Equal(t, int64(11280), credited)
+
+	_, err = svc.RefundPayment(ctx, resp.Payment.ID)
+	require.NoError(t, err)
+
+	src, _ := accountRepo.GetByID(ctx, usd.ID)
+	dst, _ := accountRepo.GetByID(ctx, eur.ID)
+	assert.Equal(t, int64(100000), src.Balance)
+	assert.Equal(t, int64(5000), dst.Balance)
+
+	net := func(id uuid.UUID) int64 {
+		txs, _ := accountRepo.GetTransactions(ctx, id, 10, 0)
+		require.Len(t, txs, 2)
+		var sum int64
+		for _, tx := range txs {
+			if tx.TransactionType == account.TransactionDebit {
+				sum -= tx.Amount
+			} else {
+				sum += tx.Amount
+			}
+		}
+		return sum
+	}
+	assert.Zero(t, net(usd.ID), "source is credited back exactly its original debit")
+	assert.Zero(t, net(eur.ID), "destination is debited exactly its original credit")
+
+	events, _ := paymentRepo.GetEvents(ctx, resp.Payment.ID)
+	last := events[len(events)-1]
+	assert.Equal(t, string(payment.EventPaymentRefunded), last.EventType)
+	assert.EqualValues(t, credited, last.EventData["credited_cents"])
+}
+
+func TestProcessPayment_QueuedCrossCurrencyTransfer_ConvertsCredit(t *testing.T) {
+	svc, _, accountRepo, usd, eur := setupFXTransfer(t, withUSDToEUR())
+	ctx := context.Background()
+	rate := 0.92
+
+	resp, err := svc.CreatePayment(ctx, CreatePaymentRequest{
+		IdempotencyKey: "fx-7", PaymentType: payment.InternalTransfer,
+		SourceAccountID: &usd.ID, DestinationAccountID: &eur.ID,
+		Amount: 10000, Currency: "USD", Preference: PreferAsync, ExchangeRate: &rate,
+	})
+	require.NoError(t, err)
+	require.NoError(t, svc.ProcessPayment(ctx, resp.Payment.ID))
+
+	dst, _ := accountRepo.GetByID(ctx, eur.ID)
+	assert.Equal(t, int64(14200), dst.Balance)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	"github.com/google/uuid"
+)
+
+// LedgerIntegrityReport compares an account's stored balance with the one
+// its transaction history adds up to. All amounts are in cents.
+type LedgerIntegrityReport struct {
+	Account          *account.Account
+	InitialBalance   int64
+	StoredBalance    int64
+	ComputedBalance  int64
+	TransactionCount int
+	Consistent       bool
+
+	// FirstDivergence is the oldest transaction whose recorded balance_after
+	// differs from the running total, nil if there is none. Drift that
+	// happened outside any transaction shows only as a balance mismatch.
+	FirstDivergence *LedgerDivergence
+}
+
+// LedgerDivergence locates where an account's history stops adding up.
+type LedgerDivergence struct {
+	TransactionID uuid.UUID
+	CreatedAt     time.Time
+	Expected      int64 // running total after the transaction
+	Recorded      int64 // balance_after stored with it
+}
+
+// VerifyLedgerIntegrity recomputes an account's balance from its initial
+// balance and every transaction since, oldest first, and compares it with
+// the stored balance. The account is consistent when both the final balance
+// and each transaction's balance_after agree with the running total.
+func (s *AccountService) VerifyLedgerIntegrity(ctx context.Context, accountID uuid.UUID) (*LedgerIntegrityReport, error) {
+	acct, err := s.accountRepo.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	txns, err := s.accountRepo.GetAllTransactions(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LedgerIntegrityReport{
+		Account:          acct,
+		InitialBalance:   acct.InitialBalance,
+		StoredBalance:    acct.Balance,
+		TransactionCount: len(txns),
+	}
+	running := acct.InitialBalance
+	for _, tx := range txns {
+		if tx.TransactionType.IsCredit() {
+			running += tx.Amount
+		} else {
+			running -= tx.Amount
+		}
+		if running != tx.BalanceAfter && report.FirstDivergence == nil {
+			report.FirstDivergence = &LedgerDivergence{
+				TransactionID: tx.ID,
+				CreatedAt:     tx.CreatedAt,
+				Expected:      running,
+				Recorded:      tx.BalanceAfter,
+			}
+		}
+	}
+	report.ComputedBalance = running
+	report.Consistent = running == acct.Balance && report.FirstDivergence == nil
+	return report, nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupLedger opens an account with 100.00 and records a credit of 5.00 and a
+// debit of 2.00, leaving its stored balance at the 103.00 they add up to.
+func setupLedger(t *testing.T) (*AccountService, *testutil.MockAccountRepository, *account.Account, []*account.Transaction) {
+	t.Helper()
+	repo := testutil.NewMockAccountRepository()
+	acct, err := account.NewAccount("user1", 10000, "USD")
+	require.NoError(t, err)
+	acct.Balance = 10300
+	repo.AddAccount(acct)
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	txns := []*account.Transaction{
+		{ID: uuid.New(), AccountID: acct.ID, TransactionType: account.TransactionCredit, Amount: 500, BalanceAfter: 10500, CreatedAt: base},
+		{ID: uuid.New(), AccountID: acct.ID, TransactionType: account.TransactionDebit, Amount: 200, BalanceAfter: 10300, CreatedAt: base.Add(time.Hour)},
+	}
+	// Recorded newest first to check the recomputation orders them.
+	for i := len(txns) - 1; i >= 0; i-- {
+		require.NoError(t, repo.AddTransaction(context.Background(), txns[i]))
+	}
+	return NewAccountService(repo), repo, acct, txns
+}
+
+func TestVerifyLedgerIntegrity_Consistent(t *testing.T) {
+	svc, _, acct, _ := setupLedger(t)
+
+	report, err := svc.VerifyLedgerIntegrity(context.Background(), acct.ID)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent)
+	assert.Equal(t, int64(10000), report.InitialBalance)
+	assert.Equal(t, int64(10300), report.ComputedBalance)
+	assert.Equal(t, int64(10300), report.StoredBalance)
+	assert.Equal(t, 2, report.TransactionCount)
+	assert.Nil(t, report.FirstDivergence)
+}
+
+func TestVerifyLedgerIntegrity_BalanceDriftedOutsideTransactions(t *testing.T) {
+	svc, _, acct, _ := setupLedger(t)
+	acct.Balance = 10400
+
+	report, err := svc.VerifyLedgerIntegrity(context.Background(), acct.ID)
+	require.NoError(t, err)
+	assert.False(t, report.Consistent)
+	assert.Equal(t, int64(10300), report.ComputedBalance)
+	assert.Equal(t, int64(10400), report.StoredBalance)
+	assert.Nil(t, report.FirstDivergence, "every transaction agrees with its running total")
+}
+
+func TestVerifyLedgerIntegrity_LocatesDivergentTransaction(t *testing.T) {
+	svc, _, acct, txns := setupLedger(t)
+	// The balance moved by 1.00 more than the debit recorded.
+	txns[1].BalanceAfter = 10200
+	acct.Balance = 10200
+
+	report, err := svc.VerifyLedgerIntegrity(context.Background(), acct.ID)
+	require.NoError(t, err)
+	assert.False(t, report.Consistent)
+	assert.Equal(t, int64(10300), report.ComputedBalance)
+	require.NotNil(t, report.FirstDivergence)
+	assert.Equal(t, txns[1].ID, report.FirstDivergence.TransactionID)
+	assert.Equal(t, int64(10300), report.FirstDivergence.Expected)
+	assert.Equal(t, int64(10200), report.FirstDivergence.Recorded)
+}
+
+func TestVerifyLedgerIntegrity_NoTransactions(t *testing.T) {
+	repo := testutil.NewMockAccountRepository()
+	acct, err := account.NewAccount("user1", 2500, "USD")
+	require.NoError(t, err)
+	repo.AddAccount(acct)
+
+	report, err := NewAccountService(repo).VerifyLedgerIntegrity(context.Background(), acct.ID)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent)
+	assert.Equal(t, int64(2500), report.ComputedBalance)
+	assert.Zero(t, report.TransactionCount)
+}
+
+func TestVerifyLedgerIntegrity_AccountNotFound(t *testing.T) {
+	svc := NewAccountService(testutil.NewMockAccountRepository())
+
+	_, err := svc.VerifyLedgerIntegrity(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, domainErrors.ErrAccountNotFound)
+}
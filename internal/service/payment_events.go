@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultEventsPageSize = 20
+	maxEventsPageSize     = 100
+)
+
+// GetPaymentEvents returns a page of a payment's audit trail, oldest first.
+// limit defaults to 20 and is capped at 100.
+func (s *PaymentService) GetPaymentEvents(ctx context.Context, paymentID uuid.UUID, limit, offset int) ([]*payment.PaymentEvent, error) {
+	p, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, domainErrors.ErrPaymentNotFound
+	}
+
+	if limit <= 0 {
+		limit = defaultEventsPageSize
+	}
+	limit = min(limit, maxEventsPageSize)
+	offset = max(offset, 0)
+	return s.paymentRepo.ListEvents(ctx, paymentID, limit, offset)
+}
+
+// TimelineKind tells which record a TimelineEntry holds.
+type TimelineKind string
+
+const (
+	TimelineEvent       TimelineKind = "event"
+	TimelineTransaction TimelineKind = "transaction"
+)
+
+// TimelineEntry is one step in a payment's history: an event, or a
+// transaction the payment posted to an account, with that account's
+// currency.
+type TimelineEntry struct {
+	Kind        TimelineKind
+	At          time.Time
+	Event       *payment.PaymentEvent
+	Transaction *account.Transaction
+	Currency    string
+}
+
+// PaymentTimeline returns a payment's events and the account transactions it
+// generated in one list, oldest first. At the same instant events come before
+// transactions, and each keeps the order its repository returned it in, so
+// repeated reads give the same timeline.
+func (s *PaymentService) PaymentTimeline(ctx context.Context, paymentID uuid.UUID) ([]TimelineEntry, error) {
+	p, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, domainErrors.ErrPaymentNotFound
+	}
+
+	events, err := s.paymentRepo.GetEvents(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	txns, err := s.accountRepo.GetTransactionsByPaymentID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]TimelineEntry, 0, len(events)+len(txns))
+	for _, e := range events {
+		entries = append(entries, TimelineEntry{Kind: TimelineEvent, At: e.CreatedAt, Event: e})
+	}
+	currencies := make(map[uuid.UUID]string)
+	for _, tx := range txns {
+		code, ok := currencies[tx.AccountID]
+		if !ok {
+			acct, err := s.accountRepo.GetByID(ctx, tx.AccountID)
+			if err != nil {
+				return nil, fmt.Errorf("load account %s: %w", tx.AccountID, err)
+			}
+			code = acct.Currency
+			currencies[tx.AccountID] = code
+		}
+		entries = append(entries, TimelineEntry{Kind: TimelineTransaction, At: tx.CreatedAt, Transaction: tx, Currency: code})
+	}
+	slices.SortStableFunc(entries, func(a, b TimelineEntry) int { return a.At.Compare(b.At) })
+	return entries, nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPaymentEvents_PagesOldestFirst(t *testing.T) {
+	svc, paymentRepo, _, _, _ := setupPaymentService()
+	ctx := context.Background()
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 5000, "USD")
+	require.NoError(t, paymentRepo.Create(ctx, p))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+			ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCreated),
+			EventData: map[string]any{"seq": i},
+		}))
+	}
+
+	events, err := svc.GetPaymentEvents(ctx, p.ID, 2, 3)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, 3, events[0].EventData["seq"])
+	assert.Equal(t, 4, events[1].EventData["seq"])
+
+	events, err = svc.GetPaymentEvents(ctx, p.ID, 0, -1)
+	require.NoError(t, err)
+	assert.Len(t, events, 5, "defaults apply to a missing limit and negative offset")
+}
+
+func TestGetPaymentEvents_CapsLimit(t *testing.T) {
+	svc, paymentRepo, _, _, _ := setupPaymentService()
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 5000, "USD")
+	require.NoError(t, paymentRepo.Create(context.Background(), p))
+
+	var gotLimit int
+	paymentRepo.ListEventsFunc = func(ctx context.Context, paymentID uuid.UUID, limit, offset int) ([]*payment.PaymentEvent, error) {
+		gotLimit = limit
+		return nil, nil
+	}
+	_, err := svc.GetPaymentEvents(context.Background(), p.ID, 10000, 0)
+	require.NoError(t, err)
+	assert.Equal(t, maxEventsPageSize, gotLimit)
+}
+
+func TestGetPaymentEvents_PaymentNotFound(t *testing.T) {
+	svc, _, _, _, _ := setupPaymentService()
+	_, err := svc.GetPaymentEvents(context.Background(), uuid.New(), 10, 0)
+	assert.ErrorIs(t, err, domainErrors.ErrPaymentNotFound)
+}
+
+func TestPaymentTimeline_MergesEventsAndTransactions(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+	source := testutil.NewTestAccount("user1", 10000, "USD")
+	dest := testutil.NewTestAccount("user2", 0, "EUR")
+	accountRepo.AddAccount(source)
+	accountRepo.AddAccount(dest)
+	p := testutil.NewTestPayment(payment.InternalTransfer, &source.ID, &dest.ID, 5000, "USD")
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	t0 := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	addEvent := func(eventType payment.EventType, at time.Time) {
+		require.NoError(t, paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+			ID: uuid.New(), PaymentID: p.ID, EventType: string(eventType), CreatedAt: at,
+		}))
+	}
+	addTx := func(acct *account.Account, txType account.TransactionType, paymentID uuid.UUID, at time.Time) {
+		require.NoError(t, accountRepo.AddTransaction(ctx, &account.Transaction{
+			ID: uuid.New(), AccountID: acct.ID, PaymentID: &paymentID, TransactionType: txType,
+			Amount: 5000, CreatedAt: at,
+		}))
+	}
+	addEvent(payment.EventPaymentCreated, t0)
+	addTx(source, account.TransactionDebit, p.ID, t0.Add(time.Second))
+	addTx(dest, account.TransactionCredit, p.ID, t0.Add(time.Second))
+	addEvent(payment.EventPaymentCompleted, t0.Add(time.Second))
+	addTx(source, account.TransactionDebit, uuid.New(), t0) // another payment's
+
+	entries, err := svc.PaymentTimeline(ctx, p.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, TimelineEvent, entries[0].Kind)
+	assert.Equal(t, string(payment.EventPaymentCreated), entries[0].Event.EventType)
+	assert.Equal(t, TimelineEvent, entries[1].Kind, "an event sorts before transactions at the same instant")
+	assert.Equal(t, string(payment.EventPaymentCompleted), entries[1].Event.EventType)
+	assert.Equal(t, TimelineTransaction, entries[2].Kind)
+	assert.Equal(t, TimelineTransaction, entries[3].Kind)
+	currencies := map[uuid.UUID]string{entries[2].Transaction.AccountID: entries[2].Currency, entries[3].Transaction.AccountID: entries[3].Currency}
+	assert.Equal(t, map[uuid.UUID]string{source.ID: "USD", dest.ID: "EUR"}, currencies)
+
+	again, err := svc.PaymentTimeline(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entries, again, "the order is stable across reads")
+}
+
+func TestPaymentTimeline_PaymentNotFound(t *testing.T) {
+	svc, _, _, _, _ := setupPaymentService()
+	_, err := svc.PaymentTimeline(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, domainErrors.ErrPaymentNotFound)
+}
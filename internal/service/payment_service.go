@@ -2,23 +2,216 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/account"
 	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/cassiomorais/payments/internal/domain/outbox"
 	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/cassiomorais/payments/internal/middleware"
 	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/pkg/money"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
+// CancelNotifier signals workers to abort in-flight processing of a payment.
+type CancelNotifier interface {
+	NotifyCancel(ctx context.Context, paymentID uuid.UUID) error
+}
+
+// ProcessingStore records worker processing attempts, keyed by payment and
+// retry count, and the provider transaction ID of those that completed.
+type ProcessingStore interface {
+	Start(ctx context.Context, key string) error
+	Complete(ctx context.Context, key, providerTxID string) error
+	// Completed returns the provider transaction ID recorded for key, if any.
+	Completed(ctx context.Context, key string) (providerTxID string, ok bool, err error)
+}
+
+type PaymentServiceOption func(*PaymentService)
+
+func WithCancelNotifier(n CancelNotifier) PaymentServiceOption {
+	return func(s *PaymentService) { s.cancelNotifier = n }
+}
+
+// PaymentLocker takes the distributed lock a worker holds while processing a
+// payment. TryLock does not wait: acquired is false while another holder has
+// the lock. unlock releases a lock that was acquired.
+type PaymentLocker interface {
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), acquired bool, err error)
+}
+
+// WithCancelLock makes cancelling a pending payment take the payment's
+// processing lock for up to ttl, so a cancel and a worker picking the
+// payment up cannot both go ahead.
+func WithCancelLock(locker PaymentLocker, ttl time.Duration) PaymentServiceOption {
+	return func(s *PaymentService) {
+		s.cancelLocker = locker
+		s.cancelLockTTL = ttl
+	}
+}
+
+func WithMetrics(m *observability.Metrics) PaymentServiceOption {
+	return func(s *PaymentService) { s.metrics = m }
+}
+
+// WithDefaultCurrency sets the currency used when a create or transfer
+// request omits one.
+func WithDefaultCurrency(currency string) PaymentServiceOption {
+	return func(s *PaymentService) { s.defaultCurrency = currency }
+}
+
+// WithExternalSourceRequired sets whether external payments must name a
+// source account (the default). Without one the charge has no internal
+// account to reserve from or refund to.
+func WithExternalSourceRequired(required bool) PaymentServiceOption {
+	return func(s *PaymentService) { s.requireExternalSource = required }
+}
+
+// WithMaxRetries sets the retry budget of payments created by the service
+// (payment.DefaultMaxRetries otherwise).
+func WithMaxRetries(n int) PaymentServiceOption {
+	return func(s *PaymentService) { s.maxRetries = n }
+}
+
+// TransferFeePolicy charges FlatCents plus BasisPoints (1/100 of a percent)
+// of the amount on internal transfers, credited to AccountID. Transfers in a
+// currency other than the fee account's are not charged.
+type TransferFeePolicy struct {
+	AccountID   uuid.UUID
+	FlatCents   int64
+	BasisPoints int64
+}
+
+// Fee returns the fee for a transfer of amountCents, rounding half up.
+func (f TransferFeePolicy) Fee(amountCents int64) int64 {
+	return f.FlatCents + (amountCents*f.BasisPoints+5000)/10000
+}
+
+func WithTransferFee(policy TransferFeePolicy) PaymentServiceOption {
+	return func(s *PaymentService) { s.transferFee = &policy }
+}
+
+// WithPlatformFeeAccount credits the fees deducted from external payments to
+// accountID. Without it payments carrying a fee are rejected.
+func WithPlatformFeeAccount(accountID uuid.UUID) PaymentServiceOption {
+	return func(s *PaymentService) { s.platformFeeAccount = &accountID }
+}
+
+// WithRefundWindow rejects refunds of payments completed more than window ago
+// unless the caller's token carries overrideScope.
+func WithRefundWindow(window time.Duration, overrideScope string) PaymentServiceOption {
+	return func(s *PaymentService) {
+		s.refundWindow = window
+		s.refundOverrideScope = overrideScope
+	}
+}
+
+// ManualRefundMode decides how refunds are handled for providers without API
+// refund support.
+type ManualRefundMode string
+
+const (
+	// ManualRefundTask records a manual refund task for operations and
+	// reverses internal balances as for any other refund.
+	ManualRefundTask ManualRefundMode = "task"
+	// ManualRefundReject refuses the refund with ErrManualRefundRequired.
+	ManualRefundReject ManualRefundMode = "reject"
+)
+
+// WithManualRefunds sets how refunds through providers lacking API refunds
+// are handled. Without it such refunds are rejected.
+func WithManualRefunds(repo payment.ManualRefundRepository, mode ManualRefundMode) PaymentServiceOption {
+	return func(s *PaymentService) {
+		s.manualRefundRepo = repo
+		s.manualRefundMode = mode
+	}
+}
+
+// DisputePolicy decides when disputed funds move back to the source account.
+type DisputePolicy string
+
+const (
+	// DisputeReverse reverses funds as soon as a dispute opens and restores
+	// them if it is won.
+	DisputeReverse DisputePolicy = "reverse"
+	// DisputeHold leaves funds in place while the dispute is open and only
+	// reverses them if it is lost.
+	DisputeHold DisputePolicy = "hold"
+)
+
+// WithDisputes enables provider dispute handling.
+func WithDisputes(repo payment.DisputeRepository, policy DisputePolicy) PaymentServiceOption {
+	return func(s *PaymentService) {
+		s.disputeRepo = repo
+		s.disputePolicy = policy
+	}
+}
+
+// WithProcessingStore makes external payment processing idempotent across
+// stream redeliveries: an attempt whose charge was already accepted completes
+// from the recorded result instead of calling the provider again.
+func WithProcessingStore(store ProcessingStore) PaymentServiceOption {
+	return func(s *PaymentService) { s.processing = store }
+}
+
+// WithDeadLetters enables listing and replaying dead-lettered payment
+// messages.
+func WithDeadLetters(store DeadLetterStore) PaymentServiceOption {
+	return func(s *PaymentService) { s.deadLetters = store }
+}
+
+// WithFX enables currency conversion restricted to the policy's allowed pairs.
+func WithFX(policy *FXPolicy) PaymentServiceOption {
+	return func(s *PaymentService) { s.fx = policy }
+}
+
 type PaymentService struct {
 	paymentRepo     payment.Repository
 	accountRepo     account.Repository
 	outboxRepo      outbox.Repository
 	txManager       TransactionManager
 	providerFactory *providers.Factory
+	cancelNotifier  CancelNotifier
+	cancelLocker    PaymentLocker
+	cancelLockTTL   time.Duration
+	breakerResets   BreakerResetNotifier
+	processing      ProcessingStore
+	deadLetters     DeadLetterStore
+	metrics         *observability.Metrics
+	defaultCurrency string
+	transferFee     *TransferFeePolicy
+
+	platformFeeAccount *uuid.UUID
+
+	refundWindow        time.Duration
+	refundOverrideScope string
+
+	disputeRepo   payment.DisputeRepository
+	disputePolicy DisputePolicy
+
+	fx *FXPolicy
+
+	manualRefundRepo payment.ManualRefundRepository
+	manualRefundMode ManualRefundMode
+
+	requireExternalSource bool
+	maxRetries            int
+	maxBatchSize          int
+	retryBackoff          RetryBackoff
+	providerTimeout       time.Duration
+
+	reconcileMinAge time.Duration
+	locks           LockChecker
 }
 
 func NewPaymentService(
@@ -27,25 +220,76 @@ func NewPaymentService(
 	outboxRepo outbox.Repository,
 	txManager TransactionManager,
 	providerFactory *providers.Factory,
+	opts ...PaymentServiceOption,
 ) *PaymentService {
-	return &PaymentService{
+	s := &PaymentService{
 		paymentRepo:     paymentRepo,
 		accountRepo:     accountRepo,
 		outboxRepo:      outboxRepo,
 		txManager:       txManager,
 		providerFactory: providerFactory,
+
+		requireExternalSource: true,
+		maxRetries:            payment.DefaultMaxRetries,
+		maxBatchSize:          DefaultMaxBatchSize,
+		retryBackoff:          DefaultRetryBackoff(),
+	}
+	for _, o := range opts {
+		o(s)
 	}
+	return s
+}
+
+// RequestCurrency is the currency of a request naming code: code itself, or
+// the default currency when the request omits one.
+func (s *PaymentService) RequestCurrency(code string) string {
+	if code == "" {
+		return s.defaultCurrency
+	}
+	return code
 }
 
 func (s *PaymentService) CreatePayment(ctx context.Context, req CreatePaymentRequest) (*CreatePaymentResponse, error) {
-	existing, err := s.paymentRepo.GetByIdempotencyKey(ctx, req.IdempotencyKey)
+	if req.Currency == "" {
+		if s.defaultCurrency == "" {
+			return nil, domainErrors.NewValidationError("currency", "cannot be empty")
+		}
+		req.Currency = s.defaultCurrency
+	}
+
+	userID, _ := middleware.GetUserID(ctx)
+	// A dry run neither replays nor consumes the idempotency key.
+	var existing *payment.Payment
+	var err error
+	if !req.DryRun {
+		existing, err = s.paymentRepo.GetByIdempotencyKey(ctx, userID, req.IdempotencyKey)
+	}
 	if err == nil && existing != nil {
+		if !matchesCreateRequest(existing, req) {
+			if s.metrics != nil {
+				s.metrics.IdempotencyReuseConflicts.WithLabelValues(string(req.PaymentType)).Inc()
+			}
+			log.Warn().
+				Str("idempotency_key_hash", hashKey(req.IdempotencyKey)).
+				Str("payment_id", existing.ID.String()).
+				Msg("idempotency key reused with a different request")
+			return nil, domainErrors.ErrIdempotencyKeyReused
+		}
+		if s.metrics != nil {
+			s.metrics.IdempotencyReplays.WithLabelValues(string(existing.PaymentType)).Inc()
+		}
 		return &CreatePaymentResponse{
 			Payment: existing,
-			IsAsync: existing.PaymentType == payment.ExternalPayment,
+			IsAsync: existing.PaymentType == payment.ExternalPayment || existing.Status == payment.StatusPending ||
+				existing.ScheduledAt != nil,
+			Outcome: OutcomeAlreadyExists,
 		}, nil
 	}
 
+	if req.PaymentType == payment.ExternalPayment && req.SourceAccountID == nil && s.requireExternalSource {
+		return nil, domainErrors.NewValidationError("source_account_id", "required for external payments")
+	}
+
 	if req.SourceAccountID != nil {
 		src, err := s.accountRepo.GetByID(ctx, *req.SourceAccountID)
 		if err != nil {
@@ -59,7 +303,11 @@ func (s *PaymentService) CreatePayment(ctx context.Context, req CreatePaymentReq
 		}
 	}
 
+	var destCurrency string
 	if req.PaymentType == payment.InternalTransfer {
+		if req.SourceAccountID == nil {
+			return nil, domainErrors.NewValidationError("source_account_id", "required for internal transfers")
+		}
 		if req.DestinationAccountID == nil {
 			return nil, domainErrors.NewValidationError("destination_account_id", "required for internal transfers")
 		}
@@ -70,6 +318,24 @@ func (s *PaymentService) CreatePayment(ctx context.Context, req CreatePaymentReq
 		if dst.Status != account.StatusActive {
 			return nil, domainErrors.ErrAccountInactive
 		}
+		destCurrency = dst.Currency
+	} else if req.ExchangeRate != nil {
+		return nil, domainErrors.NewValidationError("exchange_rate", "only applies to internal transfers")
+	} else if req.DestinationAccountID != nil {
+		// The destination of an external payment is credited on completion.
+		dst, err := s.accountRepo.GetByID(ctx, *req.DestinationAccountID)
+		if err != nil {
+			return nil, err
+		}
+		if dst.Status != account.StatusActive {
+			return nil, domainErrors.ErrAccountInactive
+		}
+		if dst.Currency != req.Currency {
+			return nil, domainErrors.ErrInvalidCurrency
+		}
+	}
+	if req.PaymentType != payment.ExternalPayment && len(req.ProviderOptions) > 0 {
+		return nil, domainErrors.NewValidationError("provider_options", "only applies to external payments")
 	}
 
 	p, err := payment.NewPayment(
@@ -77,64 +343,221 @@ func (s *PaymentService) CreatePayment(ctx context.Context, req CreatePaymentReq
 		req.PaymentType,
 		req.SourceAccountID,
 		req.DestinationAccountID,
-		payment.Amount{ValueCents: req.Amount, Currency: req.Currency},
+		payment.NewAmount(req.Amount, req.Currency),
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := p.SetMaxRetries(s.maxRetries); err != nil {
+		return nil, err
+	}
+	p.UserID = userID
+	p.BatchID = req.BatchID
+	p.ProviderOptions = req.ProviderOptions
 	if req.Provider != nil {
 		p.SetProvider(*req.Provider)
+		if err := s.checkProviderAmount(p); err != nil {
+			return nil, err
+		}
+		if err := s.checkProviderOptions(p); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.SetDescription(req.Description); err != nil {
+		return nil, err
+	}
+	if req.PaymentType == payment.InternalTransfer {
+		if err := s.applyConversion(p, destCurrency, req.ExchangeRate); err != nil {
+			return nil, err
+		}
+		if err := s.applyTransferFee(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+	if req.FeeCents != 0 {
+		if err := s.applyPlatformFee(ctx, p, req.FeeCents); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.ScheduledAt != nil {
+		if req.PaymentType != payment.InternalTransfer && req.PaymentType != payment.ExternalPayment {
+			return nil, domainErrors.ErrInvalidPaymentType
+		}
+		if err := p.Schedule(*req.ScheduledAt, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if req.DryRun {
+		return s.previewPayment(ctx, p, req.Preference)
+	}
+	if req.ScheduledAt != nil {
+		return s.createScheduled(ctx, p)
 	}
 
+	// Internal transfers run synchronously unless the client prefers async, in
+	// which case the worker executes them. External payments always go through
+	// the worker so provider calls keep their retries and circuit breaker;
+	// respond-sync is not honored for them.
+	var resp *CreatePaymentResponse
 	switch req.PaymentType {
 	case payment.InternalTransfer:
-		return s.executeSync(ctx, p)
+		if req.Preference == PreferAsync {
+			resp, err = s.enqueueAsync(ctx, p)
+		} else {
+			resp, err = s.executeSync(ctx, p)
+		}
+		if err == nil && req.Preference != PreferDefault {
+			resp.PreferenceApplied = req.Preference
+		}
 	case payment.ExternalPayment:
-		return s.enqueueAsync(ctx, p)
+		resp, err = s.enqueueAsync(ctx, p)
+		if err == nil && req.Preference == PreferAsync {
+			resp.PreferenceApplied = PreferAsync
+		}
 	default:
 		return nil, domainErrors.ErrInvalidPaymentType
 	}
+	return resp, err
 }
 
 func (s *PaymentService) executeSync(ctx context.Context, p *payment.Payment) (*CreatePaymentResponse, error) {
 	err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
-		ids := sortUUIDs(*p.SourceAccountID, *p.DestinationAccountID)
-		if _, err := s.accountRepo.Lock(txCtx, ids[0]); err != nil {
+		return s.settleTransfer(txCtx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatePaymentResponse{Payment: p, IsAsync: false, Outcome: OutcomeCreated}, nil
+}
+
+// settleTransfer records a completed internal transfer and moves its funds.
+// It must run inside a transaction.
+func (s *PaymentService) settleTransfer(ctx context.Context, p *payment.Payment) error {
+	if err := s.lockTransferAccounts(ctx, p); err != nil {
+		return err
+	}
+
+	if err := p.MarkCompleted(nil); err != nil {
+		return err
+	}
+
+	if err := s.paymentRepo.Create(ctx, p); err != nil {
+		return err
+	}
+
+	if err := s.moveFunds(ctx, p); err != nil {
+		return err
+	}
+
+	return s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCompleted),
+		EventData: withConversion(p, map[string]any{
+			"type":         string(p.PaymentType),
+			"amount_cents": p.Amount.ValueCents,
+			"fee_cents":    p.FeeCents,
+			"status":       string(p.Status),
+		}),
+	})
+}
+
+// applyTransferFee sets the configured fee on an internal transfer when the
+// transfer is in the fee account's currency.
+func (s *PaymentService) applyTransferFee(ctx context.Context, p *payment.Payment) error {
+	if s.transferFee == nil || *p.SourceAccountID == s.transferFee.AccountID {
+		return nil
+	}
+	fee := s.transferFee.Fee(p.Amount.ValueCents)
+	if fee <= 0 {
+		return nil
+	}
+
+	feeAcct, err := s.accountRepo.GetByID(ctx, s.transferFee.AccountID)
+	if err != nil {
+		return fmt.Errorf("load fee account: %w", err)
+	}
+	if feeAcct.Currency != p.Amount.Currency {
+		return nil
+	}
+	p.SetFee(fee, feeAcct.ID)
+	return nil
+}
+
+// applyPlatformFee deducts feeCents from an external payment for the platform
+// fee account, which must hold the payment's currency. The rest goes to the
+// destination, so one is required.
+func (s *PaymentService) applyPlatformFee(ctx context.Context, p *payment.Payment, feeCents int64) error {
+	if p.PaymentType != payment.ExternalPayment {
+		return domainErrors.NewValidationError("fee", "only applies to external payments")
+	}
+	if s.platformFeeAccount == nil {
+		return domainErrors.NewValidationError("fee", "platform fees are not enabled")
+	}
+	if p.DestinationAccountID == nil {
+		return domainErrors.NewValidationError("destination_account_id", "required for payments with a fee")
+	}
+	feeAcct, err := s.accountRepo.GetByID(ctx, *s.platformFeeAccount)
+	if err != nil {
+		return fmt.Errorf("load platform fee account: %w", err)
+	}
+	if feeAcct.Currency != p.Amount.Currency {
+		return domainErrors.NewValidationError("fee", fmt.Sprintf("platform fees are only charged in %s", feeAcct.Currency))
+	}
+	return p.SetPlatformFee(feeCents, feeAcct.ID)
+}
+
+// lockTransferAccounts locks every account an internal transfer touches in a
+// stable order to avoid deadlocks between opposing transfers.
+func (s *PaymentService) lockTransferAccounts(ctx context.Context, p *payment.Payment) error {
+	ids := []uuid.UUID{*p.SourceAccountID, *p.DestinationAccountID}
+	if p.FeeCents > 0 && p.FeeAccountID != nil {
+		ids = append(ids, *p.FeeAccountID)
+	}
+	slices.SortFunc(ids, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+	for _, id := range slices.Compact(ids) {
+		if _, err := s.accountRepo.Lock(ctx, id); err != nil {
 			return err
 		}
-		if _, err := s.accountRepo.Lock(txCtx, ids[1]); err != nil {
+	}
+	return nil
+}
+
+// moveFunds debits the source and credits the destination of an internal
+// transfer, each in its own currency, plus any fee. It must run inside a transaction holding the locks
+// from lockTransferAccounts.
+func (s *PaymentService) moveFunds(ctx context.Context, p *payment.Payment) error {
+	if p.FeeCents > 0 {
+		// Check amount and fee together so a transfer never settles without its fee.
+		src, err := s.accountRepo.Lock(ctx, *p.SourceAccountID)
+		if err != nil {
 			return err
 		}
-
-		if err := p.MarkCompleted(nil); err != nil {
+		total, err := p.Amount.Add(money.New(p.FeeCents, p.Amount.Currency))
+		if err != nil {
 			return err
 		}
-
-		if err := s.paymentRepo.Create(txCtx, p); err != nil {
+		if err := src.CheckAvailable(total.ValueCents); err != nil {
 			return err
 		}
+	}
+
+	if _, err := s.debitAccount(ctx, *p.SourceAccountID, p.ID, p.Amount.ValueCents, txDescription(p, "internal transfer debit")); err != nil {
+		return err
+	}
+	if _, err := s.creditAccount(ctx, *p.DestinationAccountID, p.ID, p.CreditedAmount().ValueCents, txDescription(p, "internal transfer credit")); err != nil {
+		return err
+	}
 
-		if _, err := s.debitAccount(txCtx, *p.SourceAccountID, p.ID, p.Amount.ValueCents, "internal transfer debit"); err != nil {
+	if p.FeeCents > 0 {
+		if _, err := s.debitAccount(ctx, *p.SourceAccountID, p.ID, p.FeeCents, "transfer fee"); err != nil {
 			return err
 		}
-		if _, err := s.creditAccount(txCtx, *p.DestinationAccountID, p.ID, p.Amount.ValueCents, "internal transfer credit"); err != nil {
+		if _, err := s.creditAccount(ctx, *p.FeeAccountID, p.ID, p.FeeCents, "transfer fee"); err != nil {
 			return err
 		}
-
-		return s.paymentRepo.AddEvent(txCtx, &payment.PaymentEvent{
-			ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCompleted),
-			EventData: map[string]any{
-				"type":         string(p.PaymentType),
-				"amount_cents": p.Amount.ValueCents,
-				"status":       string(p.Status),
-			},
-		})
-	})
-	if err != nil {
-		return nil, err
 	}
-
-	return &CreatePaymentResponse{Payment: p, IsAsync: false}, nil
+	return nil
 }
 
 func (s *PaymentService) enqueueAsync(ctx context.Context, p *payment.Payment) (*CreatePaymentResponse, error) {
@@ -143,24 +566,13 @@ func (s *PaymentService) enqueueAsync(ctx context.Context, p *payment.Payment) (
 			return err
 		}
 
-		entry := outbox.NewEntry(
-			"payment",
-			p.ID,
-			"payment.created",
-			map[string]any{
-				"payment_id":   p.ID.String(),
-				"type":         string(p.PaymentType),
-				"amount_cents": p.Amount.ValueCents,
-				"currency":     p.Amount.Currency,
-				"provider":     string(*p.Provider),
-			},
-		)
-		if err := s.outboxRepo.Insert(txCtx, entry); err != nil {
+		eventID := uuid.New()
+		if err := s.outboxRepo.Insert(txCtx, processingEntry(p, payment.EventPaymentCreated, eventID)); err != nil {
 			return err
 		}
 
 		return s.paymentRepo.AddEvent(txCtx, &payment.PaymentEvent{
-			ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCreated),
+			ID: eventID, PaymentID: p.ID, EventType: string(payment.EventPaymentCreated),
 			EventData: map[string]any{
 				"type":         string(p.PaymentType),
 				"amount_cents": p.Amount.ValueCents,
@@ -172,7 +584,59 @@ func (s *PaymentService) enqueueAsync(ctx context.Context, p *payment.Payment) (
 		return nil, err
 	}
 
-	return &CreatePaymentResponse{Payment: p, IsAsync: true}, nil
+	return &CreatePaymentResponse{Payment: p, IsAsync: true, Outcome: OutcomeAccepted}, nil
+}
+
+// checkProviderAmount rejects an external payment whose amount its provider
+// cannot be sent exactly, e.g. a fraction of a unit the provider counts
+// whole. Unknown providers are left for processing to report.
+func (s *PaymentService) checkProviderAmount(p *payment.Payment) error {
+	if p.PaymentType != payment.ExternalPayment {
+		return nil
+	}
+	caps, err := s.providerFactory.Capabilities(*p.Provider)
+	if err != nil {
+		return nil
+	}
+	if _, err := caps.RequestAmount(p.Amount.ValueCents, p.Amount.Currency); err != nil {
+		return domainErrors.NewValidationError("amount", fmt.Sprintf("cannot be sent to provider %s: %v", *p.Provider, err))
+	}
+	return nil
+}
+
+// checkProviderOptions rejects an external payment that lacks options its
+// provider requires, so it fails here rather than in the worker. Unknown
+// providers are left for processing to report.
+func (s *PaymentService) checkProviderOptions(p *payment.Payment) error {
+	if p.PaymentType != payment.ExternalPayment {
+		return nil
+	}
+	caps, err := s.providerFactory.Capabilities(*p.Provider)
+	if err != nil {
+		return nil
+	}
+	if missing := caps.MissingOptions(p.ProviderOptions.For(*p.Provider)); len(missing) > 0 {
+		return domainErrors.NewValidationError(
+			fmt.Sprintf("provider_options.%s.%s", *p.Provider, missing[0]),
+			fmt.Sprintf("required by provider %s", *p.Provider))
+	}
+	return nil
+}
+
+// processingEntry is the outbox entry that queues p for the worker, keyed for
+// deduplication by the ID of the payment event recorded with it.
+func processingEntry(p *payment.Payment, eventType payment.EventType, eventID uuid.UUID) *outbox.Entry {
+	data := map[string]any{
+		"payment_id":       p.ID.String(),
+		"type":             string(p.PaymentType),
+		"amount_cents":     p.Amount.ValueCents,
+		"currency":         p.Amount.Currency,
+		outbox.DedupKeyKey: eventID.String(),
+	}
+	if p.Provider != nil {
+		data["provider"] = string(*p.Provider)
+	}
+	return outbox.NewEntry("payment", p.ID, string(eventType), data)
 }
 
 func (s *PaymentService) Transfer(ctx context.Context, req TransferRequest) (*CreatePaymentResponse, error) {
@@ -183,6 +647,8 @@ func (s *PaymentService) Transfer(ctx context.Context, req TransferRequest) (*Cr
 		DestinationAccountID: &req.DestinationAccountID,
 		Amount:               req.Amount,
 		Currency:             req.Currency,
+		Description:          req.Description,
+		ExchangeRate:         req.ExchangeRate,
 	})
 }
 
@@ -192,11 +658,18 @@ func (s *PaymentService) ProcessPayment(ctx context.Context, paymentID uuid.UUID
 		return fmt.Errorf("load payment: %w", err)
 	}
 
+	if p.Status == payment.StatusProcessing {
+		// Redelivered after a crash, or still being processed elsewhere.
+		return s.replayProcessed(ctx, p)
+	}
 	if p.Status != payment.StatusPending && p.Status != payment.StatusFailed {
 		return nil
 	}
 
 	if p.Status == payment.StatusFailed {
+		if !p.RetryDue(time.Now()) {
+			return domainErrors.ErrRetryNotDue
+		}
 		if err := p.IncrementRetry(); err != nil {
 			return err
 		}
@@ -208,66 +681,281 @@ func (s *PaymentService) ProcessPayment(ctx context.Context, paymentID uuid.UUID
 		return err
 	}
 
-	if err := s.processExternalPayment(ctx, p); err != nil {
-		return s.failPayment(ctx, p, err.Error())
+	process := s.processExternalPayment
+	if p.PaymentType == payment.InternalTransfer {
+		process = s.processInternalTransfer
+	}
+	if err := process(ctx, p); err != nil {
+		if errors.Is(err, domainErrors.ErrPaymentCancelled) {
+			return s.cancelInFlight(context.WithoutCancel(ctx), p)
+		}
+		return s.failPayment(ctx, p, failureReason(err))
 	}
 
 	return nil
 }
 
+// failureReason prefixes domain errors with their code so the stored
+// last_error identifies the failure class (e.g. "account_unavailable: ...").
+func failureReason(err error) string {
+	var domainErr *domainErrors.DomainError
+	if errors.As(err, &domainErr) && domainErr.Code != "" {
+		return domainErr.Code + ": " + domainErr.Message
+	}
+	return err.Error()
+}
+
+// processInternalTransfer executes an internal transfer that was queued with
+// Prefer: respond-async. The funds move and the payment completes in one
+// transaction, like settleTransfer, so a transfer is never left processing
+// with its funds moved.
+func (s *PaymentService) processInternalTransfer(ctx context.Context, p *payment.Payment) error {
+	processing := *p
+	err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.lockTransferAccounts(txCtx, p); err != nil {
+			return err
+		}
+		if err := s.moveFunds(txCtx, p); err != nil {
+			return err
+		}
+		if err := p.MarkCompleted(nil); err != nil {
+			return err
+		}
+		if err := s.paymentRepo.Update(txCtx, p); err != nil {
+			return err
+		}
+		return s.paymentRepo.AddEvent(txCtx, &payment.PaymentEvent{
+			ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCompleted),
+			EventData: withConversion(p, map[string]any{
+				"type":         string(p.PaymentType),
+				"amount_cents": p.Amount.ValueCents,
+				"fee_cents":    p.FeeCents,
+			}),
+		})
+	})
+	if err != nil {
+		// The transaction rolled back: the payment is still processing.
+		*p = processing
+		if cancelRequested(ctx) {
+			return domainErrors.ErrPaymentCancelled
+		}
+		return err
+	}
+	return nil
+}
+
+// hasRequiredOptions reports whether p carries the options provider needs,
+// so a payment is not rerouted to a fallback that would reject it.
+func (s *PaymentService) hasRequiredOptions(p *payment.Payment, provider payment.Provider) bool {
+	caps, err := s.providerFactory.Capabilities(provider)
+	return err == nil && len(caps.MissingOptions(p.ProviderOptions.For(provider))) == 0
+}
+
 func (s *PaymentService) processExternalPayment(ctx context.Context, p *payment.Payment) error {
 	if p.Provider == nil {
 		return fmt.Errorf("no provider specified")
 	}
 
-	provider, breaker, err := s.providerFactory.Get(*p.Provider)
+	// A provider in latency slow mode hands its payments to its fallback.
+	// The new provider is saved with the payment when it completes.
+	var reroutedFrom payment.Provider
+	if routed := s.providerFactory.Route(*p.Provider); routed != *p.Provider && s.hasRequiredOptions(p, routed) {
+		log.Warn().Str("payment_id", p.ID.String()).
+			Str("provider", string(*p.Provider)).Str("fallback", string(routed)).
+			Msg("provider in slow mode; routing payment to fallback")
+		reroutedFrom = *p.Provider
+		p.SetProvider(routed)
+	}
+
+	provider, breaker, limiter, err := s.providerFactory.Get(*p.Provider)
 	if err != nil {
 		return err
 	}
+	amount, err := providers.CapabilitiesOf(provider).RequestAmount(p.Amount.ValueCents, p.Amount.Currency)
+	if err != nil {
+		return fmt.Errorf("provider amount: %w", err)
+	}
+
+	key := processingKey(p)
+	if s.processing != nil {
+		txID, ok, err := s.processing.Completed(ctx, key)
+		if err != nil {
+			return fmt.Errorf("check processing key: %w", err)
+		}
+		if ok {
+			return s.completeReplayed(context.WithoutCancel(ctx), p, txID)
+		}
+		if err := s.processing.Start(ctx, key); err != nil {
+			return fmt.Errorf("record processing key: %w", err)
+		}
+	}
+
+	// Wait out the provider's rate limit before holding funds, so a throttled
+	// payment reserves nothing and a burst does not trip the breaker.
+	if err := limiter.Wait(ctx); err != nil {
+		if cancelRequested(ctx) {
+			return domainErrors.ErrPaymentCancelled
+		}
+		return fmt.Errorf("provider rate limit: %w", err)
+	}
 
+	// Funds are held rather than debited, so a failure that skips the release
+	// leaves them reserved on the account instead of gone.
 	if p.SourceAccountID != nil {
 		if err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
-			_, err := s.debitAccount(txCtx, *p.SourceAccountID, p.ID, p.Amount.ValueCents, "external payment reserve")
-			return err
+			return s.holdFunds(txCtx, *p.SourceAccountID, p.ID, p.Amount.ValueCents)
 		}); err != nil {
+			if cancelRequested(ctx) {
+				return domainErrors.ErrPaymentCancelled
+			}
+			if errors.Is(err, domainErrors.ErrAccountNotFound) || errors.Is(err, domainErrors.ErrAccountInactive) {
+				// The account was closed or removed after the payment was created;
+				// the reserve ran in a rolled-back transaction so nothing was held.
+				return domainErrors.NewDomainError(
+					"account_unavailable",
+					fmt.Sprintf("source account %s: %v", p.SourceAccountID, err),
+					domainErrors.ErrAccountUnavailable,
+				)
+			}
 			return fmt.Errorf("reserve funds: %w", err)
 		}
 	}
 
 	result, err := breaker.Execute(func() (*providers.ProviderResult, error) {
-		return provider.ProcessPayment(ctx, providers.ProcessRequest{
-			PaymentID:   p.ID.String(),
-			AmountCents: p.Amount.ValueCents,
-			Currency:    p.Amount.Currency,
-			Metadata:    p.Metadata,
+		return s.callProvider(ctx, func(ctx context.Context) (*providers.ProviderResult, error) {
+			return provider.ProcessPayment(ctx, providers.ProcessRequest{
+				PaymentID:       p.ID.String(),
+				IdempotencyKey:  p.IdempotencyKeyFor(payment.ScopeCharge),
+				AmountCents:     amount,
+				Currency:        p.Amount.Currency,
+				Metadata:        p.Metadata,
+				ProviderOptions: p.ProviderOptions,
+			})
 		})
 	})
+	if reason, ok := reviewReason(result, err); ok {
+		// Neither retryable nor terminal: keep the funds reserved for a human.
+		return s.holdForReview(context.WithoutCancel(ctx), p, result, reason)
+	}
 	if err != nil {
+		// Compensation must run even when ctx was cancelled by a cancel signal.
+		compCtx := context.WithoutCancel(ctx)
 		if p.SourceAccountID != nil {
-			_ = s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
-				_, err := s.creditAccount(txCtx, *p.SourceAccountID, p.ID, p.Amount.ValueCents, "external payment compensation")
+			if err := s.txManager.WithTransaction(compCtx, func(txCtx context.Context) error {
+				_, err := s.releaseActiveHold(txCtx, p.ID)
 				return err
-			})
+			}); err != nil {
+				log.Error().Err(err).Str("payment_id", p.ID.String()).Msg("failed to release funds hold")
+			}
+		}
+		if cancelRequested(ctx) {
+			return domainErrors.ErrPaymentCancelled
 		}
 		return fmt.Errorf("provider call: %w", err)
 	}
 
+	// The provider accepted the charge; record it even if a cancel arrived late.
+	ctx = context.WithoutCancel(ctx)
 	txID := result.TransactionID
-	if err := p.MarkCompleted(&txID); err != nil {
+	if s.processing != nil {
+		if err := s.processing.Complete(ctx, key, txID); err != nil {
+			log.Error().Err(err).Str("payment_id", p.ID.String()).Str("provider_tx_id", txID).
+				Msg("failed to record processing result; a redelivery may not be deduplicated")
+		}
+	}
+	if err := s.completeCharged(ctx, p, txID); err != nil {
 		return err
 	}
-	if err := s.paymentRepo.Update(ctx, p); err != nil {
+
+	eventData := map[string]any{
+		"provider_tx_id": txID,
+		"amount_cents":   p.Amount.ValueCents,
+	}
+	if reroutedFrom != "" {
+		eventData["rerouted_from"] = string(reroutedFrom)
+	}
+	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCompleted),
+		EventData: eventData,
+	})
+
+	return nil
+}
+
+// processingKey identifies one processing attempt of p.
+func processingKey(p *payment.Payment) string {
+	return fmt.Sprintf("process:%s:%d", p.ID, p.RetryCount)
+}
+
+// replayProcessed completes a payment left in processing whose charge the
+// provider already accepted. Without a recorded result it is left alone.
+func (s *PaymentService) replayProcessed(ctx context.Context, p *payment.Payment) error {
+	if s.processing == nil || p.PaymentType == payment.InternalTransfer {
+		return nil
+	}
+	txID, ok, err := s.processing.Completed(ctx, processingKey(p))
+	if err != nil || !ok {
 		return err
 	}
+	return s.completeReplayed(context.WithoutCancel(ctx), p, txID)
+}
 
+// completeReplayed records p as completed with a provider result from an
+// earlier delivery of the same attempt.
+func (s *PaymentService) completeReplayed(ctx context.Context, p *payment.Payment, txID string) error {
+	log.Warn().Str("payment_id", p.ID.String()).Str("provider_tx_id", txID).
+		Msg("payment already charged by an earlier delivery; completing from recorded result")
+	if err := s.completeCharged(ctx, p, txID); err != nil {
+		return err
+	}
 	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
 		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCompleted),
 		EventData: map[string]any{
 			"provider_tx_id": txID,
 			"amount_cents":   p.Amount.ValueCents,
+			"replayed":       true,
 		},
 	})
+	return nil
+}
 
+// completeCharged records p as completed with the provider's transaction ID
+// and captures its funds hold in the same transaction. If that transaction
+// fails p stays processing, and a redelivery completes it from the recorded
+// processing result.
+func (s *PaymentService) completeCharged(ctx context.Context, p *payment.Payment, txID string) error {
+	if err := p.MarkCompleted(&txID); err != nil {
+		return err
+	}
+	return s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.settleCharge(txCtx, p); err != nil {
+			return err
+		}
+		return s.paymentRepo.Update(txCtx, p)
+	})
+}
+
+// settleCharge books a completed external payment: the source's funds hold
+// is captured, the destination is credited the net amount and the platform
+// fee account the fee, each as its own ledger row. It must run inside a
+// transaction.
+func (s *PaymentService) settleCharge(ctx context.Context, p *payment.Payment) error {
+	if err := s.captureActiveHold(ctx, p); err != nil {
+		return err
+	}
+	if p.DestinationAccountID == nil {
+		return nil
+	}
+	if net := p.NetCents(); net > 0 {
+		if _, err := s.creditAccount(ctx, *p.DestinationAccountID, p.ID, net, txDescription(p, "external payment")); err != nil {
+			return err
+		}
+	}
+	if p.FeeCents > 0 && p.FeeAccountID != nil {
+		if _, err := s.creditAccount(ctx, *p.FeeAccountID, p.ID, p.FeeCents, "platform fee"); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -275,6 +963,16 @@ func (s *PaymentService) failPayment(ctx context.Context, p *payment.Payment, re
 	if err := p.MarkFailed(reason); err != nil {
 		return err
 	}
+	// A payment with no retries left is abandoned, so clients can tell a
+	// final failure from one that will be retried.
+	abandoned := !p.CanRetry()
+	if abandoned {
+		if err := p.MarkAbandoned(); err != nil {
+			return err
+		}
+	} else {
+		p.ScheduleRetry(time.Now().Add(s.retryBackoff.Delay(p.RetryCount + 1)))
+	}
 	if err := s.paymentRepo.Update(ctx, p); err != nil {
 		return err
 	}
@@ -282,7 +980,104 @@ func (s *PaymentService) failPayment(ctx context.Context, p *payment.Payment, re
 		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentFailed),
 		EventData: map[string]any{"error": reason},
 	})
-	return domainErrors.NewDomainError("payment_failed", reason, nil)
+	if !abandoned {
+		return domainErrors.NewDomainError("payment_failed", reason, domainErrors.ErrPaymentFailed)
+	}
+	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentAbandoned),
+		EventData: map[string]any{"error": reason, "retry_count": p.RetryCount},
+	})
+	return domainErrors.NewDomainError("payment_failed", reason, domainErrors.ErrMaxRetriesExceeded)
+}
+
+// CancelPayment cancels a pending payment directly. For a payment already being
+// processed it sends a best-effort cancel signal to the worker: the worker
+// aborts its provider call and compensates the reserved funds, but a charge
+// the provider has already accepted cannot be recalled and will complete.
+func (s *PaymentService) CancelPayment(ctx context.Context, paymentID uuid.UUID) (*CancelPaymentResponse, error) {
+	p, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Status == payment.StatusProcessing {
+		if s.cancelNotifier == nil {
+			return nil, errCancelTooLate(p.ID)
+		}
+		if err := s.cancelNotifier.NotifyCancel(ctx, p.ID); err != nil {
+			return nil, err
+		}
+		return &CancelPaymentResponse{Payment: p, InFlight: true}, nil
+	}
+
+	if p.Status == payment.StatusPending && s.cancelLocker != nil {
+		unlock, acquired, err := s.cancelLocker.TryLock(ctx, PaymentLockKey(p.ID), s.cancelLockTTL)
+		if err != nil {
+			return nil, fmt.Errorf("lock payment: %w", err)
+		}
+		if !acquired {
+			// A worker has picked the payment up and is about to charge it.
+			return nil, errCancelTooLate(p.ID)
+		}
+		defer unlock()
+
+		// The worker may have claimed and released the payment between the
+		// read above and taking the lock.
+		if p, err = s.paymentRepo.GetByID(ctx, paymentID); err != nil {
+			return nil, err
+		}
+		if p.Status == payment.StatusProcessing {
+			return nil, errCancelTooLate(p.ID)
+		}
+	}
+
+	// Dropping the payment's unpublished outbox entries keeps it off the
+	// processing stream. One already published is skipped by the worker,
+	// which leaves cancelled payments alone.
+	var removed int64
+	err = s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := p.MarkCancelled(); err != nil {
+			return err
+		}
+		if err := s.paymentRepo.Update(txCtx, p); err != nil {
+			return err
+		}
+		removed, err = s.outboxRepo.DeletePending(txCtx, p.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCancelled),
+		EventData: map[string]any{"status": string(p.Status), "outbox_entries_removed": removed},
+	})
+
+	return &CancelPaymentResponse{Payment: p}, nil
+}
+
+// errCancelTooLate reports a cancel that lost the race with the worker.
+func errCancelTooLate(id uuid.UUID) error {
+	return domainErrors.NewDomainError(
+		"cancel_too_late",
+		fmt.Sprintf("payment %s is already being processed and can no longer be cancelled", id),
+		domainErrors.ErrInvalidStateTransition,
+	)
+}
+
+// cancelInFlight records a payment aborted mid-processing by a cancel signal.
+func (s *PaymentService) cancelInFlight(ctx context.Context, p *payment.Payment) error {
+	if err := p.MarkCancelled(); err != nil {
+		return err
+	}
+	if err := s.paymentRepo.Update(ctx, p); err != nil {
+		return err
+	}
+	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCancelled),
+		EventData: map[string]any{"status": string(p.Status), "in_flight": true},
+	})
+	return nil
 }
 
 func (s *PaymentService) RefundPayment(ctx context.Context, paymentID uuid.UUID) (*payment.Payment, error) {
@@ -299,108 +1094,406 @@ func (s *PaymentService) RefundPayment(ctx context.Context, paymentID uuid.UUID)
 		)
 	}
 
+	windowOverridden, err := s.checkRefundWindow(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var manualRefund *payment.ManualRefund
 	if p.PaymentType == payment.ExternalPayment && p.Provider != nil {
-		provider, breaker, err := s.providerFactory.Get(*p.Provider)
+		caps, err := s.providerFactory.Capabilities(*p.Provider)
 		if err != nil {
 			return nil, err
 		}
+		if !caps.APIRefunds {
+			if manualRefund, err = s.requestManualRefund(ctx, p); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if p.PaymentType == payment.ExternalPayment && p.Provider != nil && manualRefund == nil {
+		provider, breaker, limiter, err := s.providerFactory.Get(*p.Provider)
+		if err != nil {
+			return nil, err
+		}
+		amount, err := providers.CapabilitiesOf(provider).RequestAmount(p.Amount.ValueCents, p.Amount.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("provider amount: %w", err)
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("provider rate limit: %w", err)
+		}
 
 		txID := ""
 		if p.ProviderTransactionID != nil {
 			txID = *p.ProviderTransactionID
 		}
 
-		_, cbErr := breaker.Execute(func() (*providers.ProviderResult, error) {
-			return provider.RefundPayment(ctx, providers.RefundRequest{
-				PaymentID:     p.ID.String(),
-				TransactionID: txID,
-				AmountCents:   p.Amount.ValueCents,
-				Currency:      p.Amount.Currency,
+		result, cbErr := breaker.Execute(func() (*providers.ProviderResult, error) {
+			return s.callProvider(ctx, func(ctx context.Context) (*providers.ProviderResult, error) {
+				return provider.RefundPayment(ctx, providers.RefundRequest{
+					PaymentID:      p.ID.String(),
+					IdempotencyKey: p.IdempotencyKeyFor(payment.ScopeRefund),
+					TransactionID:  txID,
+					AmountCents:    amount,
+					Currency:       p.Amount.Currency,
+				})
 			})
 		})
 		if cbErr != nil {
 			return nil, fmt.Errorf("provider refund: %w", cbErr)
 		}
+		// A provider can report a failed or pending refund without an error.
+		// Balances are only reversed once the provider confirms the refund.
+		if result == nil || result.Status != providers.ResultSuccess {
+			status, msg := "empty result", ""
+			if result != nil {
+				status, msg = result.Status, result.ErrorMessage
+			}
+			log.Warn().Str("payment_id", p.ID.String()).Str("provider_status", status).Str("provider_error", msg).
+				Msg("provider did not confirm refund; balances left unchanged")
+			return nil, domainErrors.NewDomainError(
+				"refund_not_confirmed",
+				fmt.Sprintf("provider refund returned status %q", status),
+				domainErrors.ErrProviderRejected,
+			)
+		}
+	}
+
+	eventData := map[string]any{"amount_cents": p.Amount.ValueCents, "fee_cents": p.FeeCents}
+	if manualRefund != nil {
+		eventData["manual_refund_id"] = manualRefund.ID.String()
+	}
+	if windowOverridden {
+		userID, _ := middleware.GetUserID(ctx)
+		eventData["refund_window_override"] = true
+		eventData["overridden_by"] = userID
+	}
+
+	// Every reversal leg and the refunded status commit together: if one leg
+	// fails (e.g. the destination has spent the funds) nothing is reversed
+	// and the payment stays completed, so a retried refund is not credited
+	// twice.
+	err = s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.reverseLegs(txCtx, p); err != nil {
+			return err
+		}
+		if err := p.MarkRefunded(); err != nil {
+			return err
+		}
+		if err := s.paymentRepo.Update(txCtx, p); err != nil {
+			return err
+		}
+		return s.paymentRepo.AddEvent(txCtx, &payment.PaymentEvent{
+			ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentRefunded),
+			EventData: withConversion(p, eventData),
+		})
+	})
+	if err != nil {
+		if p.PaymentType == payment.ExternalPayment && p.Provider != nil && manualRefund == nil {
+			log.Error().Err(err).Str("payment_id", p.ID.String()).
+				Msg("provider refunded the charge but the balances could not be reversed")
+		}
+		return nil, err
 	}
 
+	return p, nil
+}
+
+// reverseLegs takes back every balance movement of a completed payment. It
+// must run inside a transaction.
+func (s *PaymentService) reverseLegs(ctx context.Context, p *payment.Payment) error {
 	if p.SourceAccountID != nil {
-		if err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
-			_, err := s.creditAccount(txCtx, *p.SourceAccountID, p.ID, p.Amount.ValueCents, "refund")
+		if _, err := s.creditAccount(ctx, *p.SourceAccountID, p.ID, p.Amount.ValueCents, "refund"); err != nil {
 			return err
-		}); err != nil {
-			return nil, err
+		}
+		if p.PaymentType == payment.InternalTransfer && p.FeeCents > 0 && p.FeeAccountID != nil {
+			if _, err := s.debitAccount(ctx, *p.FeeAccountID, p.ID, p.FeeCents, "transfer fee refund"); err != nil {
+				return err
+			}
+			if _, err := s.creditAccount(ctx, *p.SourceAccountID, p.ID, p.FeeCents, "transfer fee refund"); err != nil {
+				return err
+			}
 		}
 	}
 
-	if p.PaymentType == payment.InternalTransfer && p.DestinationAccountID != nil {
-		if err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
-			_, err := s.debitAccount(txCtx, *p.DestinationAccountID, p.ID, p.Amount.ValueCents, "refund reversal")
+	if p.DestinationAccountID == nil {
+		return nil
+	}
+	// A cross-currency transfer is reversed at the amounts it recorded, never
+	// reconverted, so rounding in the original conversion leaves no residual.
+	if p.PaymentType == payment.InternalTransfer {
+		_, err := s.debitAccount(ctx, *p.DestinationAccountID, p.ID, p.CreditedAmount().ValueCents, "refund reversal")
+		return err
+	}
+	// An external payment takes back what its completion credited: the net
+	// amount from the destination and the platform fee.
+	if net := p.NetCents(); net > 0 {
+		if _, err := s.debitAccount(ctx, *p.DestinationAccountID, p.ID, net, "refund reversal"); err != nil {
+			return err
+		}
+	}
+	if p.FeeCents > 0 && p.FeeAccountID != nil {
+		if _, err := s.debitAccount(ctx, *p.FeeAccountID, p.ID, p.FeeCents, "platform fee refund"); err != nil {
 			return err
-		}); err != nil {
-			return nil, err
 		}
 	}
+	return nil
+}
 
-	if err := p.MarkRefunded(); err != nil {
-		return nil, err
+// requestManualRefund records a manual refund task for a provider that cannot
+// refund through its API, or rejects the refund when so configured.
+func (s *PaymentService) requestManualRefund(ctx context.Context, p *payment.Payment) (*payment.ManualRefund, error) {
+	if s.manualRefundRepo == nil || s.manualRefundMode != ManualRefundTask {
+		return nil, domainErrors.NewDomainError(
+			"manual_refund_required",
+			fmt.Sprintf("provider %s does not support API refunds; refund the charge in the provider dashboard and reconcile it manually", *p.Provider),
+			domainErrors.ErrManualRefundRequired,
+		)
 	}
-	if err := s.paymentRepo.Update(ctx, p); err != nil {
+
+	task := payment.NewManualRefund(p)
+	if err := s.manualRefundRepo.Create(ctx, task); err != nil {
 		return nil, err
 	}
+	log.Info().Str("payment_id", p.ID.String()).Str("provider", string(*p.Provider)).
+		Msg("provider lacks API refunds; manual refund task recorded")
+	return task, nil
+}
 
-	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
-		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentRefunded),
-		EventData: map[string]any{"amount_cents": p.Amount.ValueCents},
-	})
+// checkRefundWindow returns ErrRefundWindowExpired when p completed longer ago
+// than the refund window. Callers holding the override scope may proceed; it
+// then reports true so the override is recorded on the refund event.
+func (s *PaymentService) checkRefundWindow(ctx context.Context, p *payment.Payment) (overridden bool, err error) {
+	if s.refundWindow <= 0 || p.CompletedAt == nil || time.Since(*p.CompletedAt) <= s.refundWindow {
+		return false, nil
+	}
 
-	return p, nil
+	if s.refundOverrideScope == "" || !middleware.HasScope(ctx, s.refundOverrideScope) {
+		return false, domainErrors.NewDomainError(
+			"refund_window_expired",
+			fmt.Sprintf("payment completed at %s is outside the %s refund window", p.CompletedAt.Format(time.RFC3339), s.refundWindow),
+			domainErrors.ErrRefundWindowExpired,
+		)
+	}
+
+	userID, _ := middleware.GetUserID(ctx)
+	log.Info().
+		Str("payment_id", p.ID.String()).
+		Str("user_id", userID).
+		Time("completed_at", *p.CompletedAt).
+		Msg("refund window overridden")
+	return true, nil
 }
 
 func (s *PaymentService) debitAccount(ctx context.Context, accountID uuid.UUID, paymentID uuid.UUID, amount int64, description string) (balanceAfter int64, err error) {
-	acct, err := s.accountRepo.Lock(ctx, accountID)
+	acct, _, err := s.postDebit(ctx, accountID, &paymentID, account.TransactionDebit, amount, description)
 	if err != nil {
 		return 0, err
 	}
+	return acct.Balance, nil
+}
+
+// postDebit locks the account, debits amount and records the transaction as
+// txType. paymentID is nil for entries no payment caused.
+func (s *PaymentService) postDebit(ctx context.Context, accountID uuid.UUID, paymentID *uuid.UUID, txType account.TransactionType, amount int64, description string) (*account.Account, *account.Transaction, error) {
+	acct, err := s.accountRepo.Lock(ctx, accountID)
+	if err != nil {
+		return nil, nil, err
+	}
 	if err := acct.Debit(amount); err != nil {
-		return 0, err
+		return nil, nil, err
 	}
 	if err := s.accountRepo.Update(ctx, acct); err != nil {
-		return 0, err
+		return nil, nil, err
 	}
-	if err := s.accountRepo.AddTransaction(ctx, &account.Transaction{
-		ID: uuid.New(), AccountID: acct.ID, PaymentID: &paymentID,
-		TransactionType: account.TransactionDebit, Amount: amount,
+	tx := &account.Transaction{
+		ID: uuid.New(), AccountID: acct.ID, PaymentID: paymentID,
+		TransactionType: txType, Amount: amount,
 		BalanceAfter: acct.Balance, Description: description, CreatedAt: time.Now(),
+	}
+	if err := s.accountRepo.AddTransaction(ctx, tx); err != nil {
+		return nil, nil, err
+	}
+	return acct, tx, nil
+}
+
+// holdFunds reserves amount on an account for a payment without debiting it.
+func (s *PaymentService) holdFunds(ctx context.Context, accountID uuid.UUID, paymentID uuid.UUID, amount int64) error {
+	acct, err := s.accountRepo.Lock(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if err := acct.Hold(amount); err != nil {
+		return err
+	}
+	if err := s.accountRepo.Update(ctx, acct); err != nil {
+		return err
+	}
+	return s.accountRepo.CreateHold(ctx, account.NewFundsHold(accountID, paymentID, amount))
+}
+
+// captureActiveHold debits p's active funds hold. A payment without one has
+// nothing to capture: it has no source account, or nothing was reserved.
+func (s *PaymentService) captureActiveHold(ctx context.Context, p *payment.Payment) error {
+	hold, err := s.accountRepo.GetActiveHold(ctx, p.ID)
+	if err != nil || hold == nil {
+		return err
+	}
+	acct, err := s.accountRepo.Lock(ctx, hold.AccountID)
+	if err != nil {
+		return err
+	}
+	if err := acct.CaptureHold(hold.Amount); err != nil {
+		return err
+	}
+	if err := s.accountRepo.Update(ctx, acct); err != nil {
+		return err
+	}
+	if err := s.accountRepo.AddTransaction(ctx, &account.Transaction{
+		ID: uuid.New(), AccountID: acct.ID, PaymentID: &p.ID,
+		TransactionType: account.TransactionDebit, Amount: hold.Amount,
+		BalanceAfter: acct.Balance, Description: txDescription(p, "external payment"), CreatedAt: time.Now(),
 	}); err != nil {
+		return err
+	}
+	if err := hold.Capture(); err != nil {
+		return err
+	}
+	return s.accountRepo.UpdateHold(ctx, hold)
+}
+
+// releaseActiveHold returns a payment's active funds hold to its account,
+// reporting whether there was one to release.
+func (s *PaymentService) releaseActiveHold(ctx context.Context, paymentID uuid.UUID) (bool, error) {
+	hold, err := s.accountRepo.GetActiveHold(ctx, paymentID)
+	if err != nil || hold == nil {
+		return false, err
+	}
+	acct, err := s.accountRepo.Lock(ctx, hold.AccountID)
+	if err != nil {
+		return false, err
+	}
+	if err := acct.ReleaseHold(hold.Amount); err != nil {
+		return false, err
+	}
+	if err := s.accountRepo.Update(ctx, acct); err != nil {
+		return false, err
+	}
+	if err := hold.Release(); err != nil {
+		return false, err
+	}
+	return true, s.accountRepo.UpdateHold(ctx, hold)
+}
+
+func (s *PaymentService) creditAccount(ctx context.Context, accountID uuid.UUID, paymentID uuid.UUID, amount int64, description string) (balanceAfter int64, err error) {
+	acct, _, err := s.postCredit(ctx, accountID, &paymentID, account.TransactionCredit, amount, description)
+	if err != nil {
 		return 0, err
 	}
 	return acct.Balance, nil
 }
 
-func (s *PaymentService) creditAccount(ctx context.Context, accountID uuid.UUID, paymentID uuid.UUID, amount int64, description string) (balanceAfter int64, err error) {
+// postCredit is the credit counterpart of postDebit.
+func (s *PaymentService) postCredit(ctx context.Context, accountID uuid.UUID, paymentID *uuid.UUID, txType account.TransactionType, amount int64, description string) (*account.Account, *account.Transaction, error) {
 	acct, err := s.accountRepo.Lock(ctx, accountID)
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
 	if err := acct.Credit(amount); err != nil {
-		return 0, err
+		return nil, nil, err
 	}
 	if err := s.accountRepo.Update(ctx, acct); err != nil {
-		return 0, err
+		return nil, nil, err
 	}
-	if err := s.accountRepo.AddTransaction(ctx, &account.Transaction{
-		ID: uuid.New(), AccountID: acct.ID, PaymentID: &paymentID,
-		TransactionType: account.TransactionCredit, Amount: amount,
+	tx := &account.Transaction{
+		ID: uuid.New(), AccountID: acct.ID, PaymentID: paymentID,
+		TransactionType: txType, Amount: amount,
 		BalanceAfter: acct.Balance, Description: description, CreatedAt: time.Now(),
-	}); err != nil {
-		return 0, err
 	}
-	return acct.Balance, nil
+	if err := s.accountRepo.AddTransaction(ctx, tx); err != nil {
+		return nil, nil, err
+	}
+	return acct, tx, nil
 }
 
-func sortUUIDs(a, b uuid.UUID) [2]uuid.UUID {
-	if a.String() < b.String() {
-		return [2]uuid.UUID{a, b}
+// txDescription labels the principal debit/credit of a payment with the
+// payment's own description when it has one. Fees, refunds and other
+// adjustments keep their fixed labels.
+func txDescription(p *payment.Payment, fallback string) string {
+	if p.Description != "" {
+		return p.Description
 	}
-	return [2]uuid.UUID{b, a}
+	return fallback
+}
+
+// matchesCreateRequest reports whether an existing payment was created from an
+// equivalent request, so a repeated idempotency key is a genuine replay.
+func matchesCreateRequest(p *payment.Payment, req CreatePaymentRequest) bool {
+	return p.PaymentType == req.PaymentType &&
+		p.Amount.ValueCents == req.Amount &&
+		p.Amount.Currency == req.Currency &&
+		sameUUID(p.SourceAccountID, req.SourceAccountID) &&
+		sameUUID(p.DestinationAccountID, req.DestinationAccountID) &&
+		sameProvider(p.Provider, req.Provider) &&
+		p.Description == req.Description &&
+		sameRate(p.Conversion, req.ExchangeRate) &&
+		sameSchedule(p.ScheduledAt, req.ScheduledAt) &&
+		sameUUID(p.BatchID, req.BatchID) &&
+		sameProviderOptions(p.ProviderOptions, req.ProviderOptions) &&
+		samePlatformFee(p, req.FeeCents)
+}
+
+// samePlatformFee compares the requested platform fee of an external payment.
+// A transfer's fee comes from the fee policy, not the request.
+func samePlatformFee(p *payment.Payment, feeCents int64) bool {
+	if p.PaymentType != payment.ExternalPayment {
+		return feeCents == 0
+	}
+	return p.FeeCents == feeCents
+}
+
+func sameProviderOptions(a, b payment.ProviderOptions) bool {
+	return maps.EqualFunc(a, b, func(x, y map[string]string) bool { return maps.Equal(x, y) })
+}
+
+func sameRate(c *payment.Conversion, rate *float64) bool {
+	if c == nil || rate == nil {
+		return c == nil && rate == nil
+	}
+	return c.Rate == *rate
+}
+
+// sameSchedule compares at the microsecond precision schedules are stored at.
+func sameSchedule(stored, requested *time.Time) bool {
+	if stored == nil || requested == nil {
+		return stored == nil && requested == nil
+	}
+	return stored.Equal(requested.Truncate(time.Microsecond))
+}
+
+func sameUUID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameProvider(a, b *payment.Provider) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// hashKey returns a short, non-reversible form of an idempotency key for logs.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// cancelRequested reports whether ctx was cancelled by a payment cancel signal.
+func cancelRequested(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), domainErrors.ErrPaymentCancelled)
 }
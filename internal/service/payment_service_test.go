@@ -3,12 +3,16 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/account"
 	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/cassiomorais/payments/internal/domain/outbox"
 	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
 	"github.com/cassiomorais/payments/internal/providers"
 	"github.com/cassiomorais/payments/internal/testutil"
 	"github.com/google/uuid"
@@ -79,6 +83,57 @@ func TestCreatePayment_InternalTransfer_Success(t *testing.T) {
 	assert.Equal(t, int64(60000), destAfter.Balance)   // 50000 + 10000
 }
 
+func TestCreatePayment_InternalTransfer_DescriptionLabelsTransactions(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 50000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	resp, err := svc.Transfer(ctx, TransferRequest{
+		IdempotencyKey:       "test-key-memo",
+		SourceAccountID:      sourceAcct.ID,
+		DestinationAccountID: destAcct.ID,
+		Amount:               10000,
+		Currency:             "USD",
+		Description:          "March rent",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "March rent", resp.Payment.Description)
+
+	stored, _ := paymentRepo.GetByID(ctx, resp.Payment.ID)
+	assert.Equal(t, "March rent", stored.Description)
+
+	for _, id := range []uuid.UUID{sourceAcct.ID, destAcct.ID} {
+		txns, _ := accountRepo.GetTransactions(ctx, id, 10, 0)
+		require.Len(t, txns, 1)
+		assert.Equal(t, "March rent", txns[0].Description)
+	}
+}
+
+func TestCreatePayment_DescriptionTooLong(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 50000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	_, err := svc.Transfer(context.Background(), TransferRequest{
+		IdempotencyKey:       "test-key-long-memo",
+		SourceAccountID:      sourceAcct.ID,
+		DestinationAccountID: destAcct.ID,
+		Amount:               10000,
+		Currency:             "USD",
+		Description:          strings.Repeat("x", payment.MaxDescriptionLength+1),
+	})
+	var validationErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "description", validationErr.Field)
+}
+
 func TestCreatePayment_InternalTransfer_InsufficientFunds(t *testing.T) {
 	svc, _, accountRepo, _, _ := setupPaymentService()
 	ctx := context.Background()
@@ -114,17 +169,7 @@ func TestCreatePayment_InternalTransfer_SourceAccountNotFound(t *testing.T) {
 	destAcct := createTestAccount(t, "user2", 50000, account.StatusActive)
 	accountRepo.AddAccount(destAcct)
 
-	// Mock GetByID to return error for non-existent account
 	nonExistentID := uuid.New()
-	accountRepo.GetByIDFunc = func(ctx context.Context, id uuid.UUID) (*account.Account, error) {
-		if id == nonExistentID {
-			return nil, domainErrors.ErrAccountNotFound
-		}
-		if id == destAcct.ID {
-			return destAcct, nil
-		}
-		return nil, domainErrors.ErrAccountNotFound
-	}
 
 	req := CreatePaymentRequest{
 		IdempotencyKey:       "test-key-3",
@@ -260,6 +305,48 @@ func TestCreatePayment_InternalTransfer_MissingDestinationAccount(t *testing.T)
 	assert.Contains(t, err.Error(), "required for internal transfers")
 }
 
+func TestCreatePayment_MissingCurrency_UsesDefault(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{}, testutil.NewMockTransactionManager(),
+		providers.NewFactory(providers.NewMockProvider("stripe")), WithDefaultCurrency("USD"))
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 50000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	resp, err := svc.Transfer(ctx, TransferRequest{
+		IdempotencyKey:       "test-key-default-currency",
+		SourceAccountID:      sourceAcct.ID,
+		DestinationAccountID: destAcct.ID,
+		Amount:               10000,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "USD", resp.Payment.Amount.Currency)
+}
+
+func TestCreatePayment_MissingCurrency_NoDefault_ValidationError(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 50000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	_, err := svc.Transfer(ctx, TransferRequest{
+		IdempotencyKey:       "test-key-no-currency",
+		SourceAccountID:      sourceAcct.ID,
+		DestinationAccountID: destAcct.ID,
+		Amount:               10000,
+	})
+	var validationErr *domainErrors.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "currency", validationErr.Field)
+}
+
 func TestCreatePayment_Idempotency_ReturnsExisting(t *testing.T) {
 	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
 	ctx := context.Background()
@@ -287,15 +374,127 @@ func TestCreatePayment_Idempotency_ReturnsExisting(t *testing.T) {
 	resp2, err := svc.CreatePayment(ctx, req)
 	require.NoError(t, err)
 	assert.Equal(t, paymentID1, resp2.Payment.ID) // Same payment returned
+	assert.Equal(t, OutcomeCreated, resp1.Outcome)
+	assert.Equal(t, OutcomeAlreadyExists, resp2.Outcome)
 
 	// Verify only one payment was created
-	stored, _ := paymentRepo.GetByIdempotencyKey(ctx, "test-key-idempotent")
+	stored, _ := paymentRepo.GetByIdempotencyKey(ctx, "", "test-key-idempotent")
 	assert.Equal(t, paymentID1, stored.ID)
 }
 
+func TestCreatePayment_Idempotency_ExpiredKeyCreatesNewPayment(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+	paymentRepo.IdempotencyTTL = time.Hour
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 50000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	req := CreatePaymentRequest{
+		IdempotencyKey:       "test-key-expiring",
+		PaymentType:          payment.InternalTransfer,
+		SourceAccountID:      &sourceAcct.ID,
+		DestinationAccountID: &destAcct.ID,
+		Amount:               10000,
+		Currency:             "USD",
+	}
+	resp1, err := svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+
+	resp2, err := svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeAlreadyExists, resp2.Outcome, "a key within the TTL replays")
+
+	// Age the first payment past the TTL
+	resp1.Payment.CreatedAt = time.Now().Add(-2 * time.Hour)
+
+	_, err = paymentRepo.GetByIdempotencyKey(ctx, "", req.IdempotencyKey)
+	assert.ErrorIs(t, err, domainErrors.ErrPaymentNotFound, "an expired key is not found")
+
+	resp3, err := svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeCreated, resp3.Outcome)
+	assert.NotEqual(t, resp1.Payment.ID, resp3.Payment.ID)
+}
+
+func TestCreatePayment_Idempotency_ScopedPerUser(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+
+	alice := createTestAccount(t, "alice", 100000, account.StatusActive)
+	bob := createTestAccount(t, "bob", 100000, account.StatusActive)
+	accountRepo.AddAccount(alice)
+	accountRepo.AddAccount(bob)
+	aliceCtx := context.WithValue(context.Background(), middleware.UserIDKey, "alice")
+	bobCtx := context.WithValue(context.Background(), middleware.UserIDKey, "bob")
+
+	aliceReq := CreatePaymentRequest{
+		IdempotencyKey:       "order-42",
+		PaymentType:          payment.InternalTransfer,
+		SourceAccountID:      &alice.ID,
+		DestinationAccountID: &bob.ID,
+		Amount:               10000,
+		Currency:             "USD",
+	}
+	bobReq := aliceReq
+	bobReq.SourceAccountID, bobReq.DestinationAccountID = &bob.ID, &alice.ID
+	bobReq.Amount = 2500
+
+	aliceResp, err := svc.CreatePayment(aliceCtx, aliceReq)
+	require.NoError(t, err)
+	bobResp, err := svc.CreatePayment(bobCtx, bobReq)
+	require.NoError(t, err, "another user's key does not conflict")
+	assert.Equal(t, OutcomeCreated, bobResp.Outcome)
+	assert.NotEqual(t, aliceResp.Payment.ID, bobResp.Payment.ID)
+	assert.Equal(t, "alice", aliceResp.Payment.UserID)
+	assert.Equal(t, "bob", bobResp.Payment.UserID)
+
+	replay, err := svc.CreatePayment(aliceCtx, aliceReq)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeAlreadyExists, replay.Outcome)
+	assert.Equal(t, aliceResp.Payment.ID, replay.Payment.ID, "each user replays their own payment")
+
+	stored, err := paymentRepo.GetByIdempotencyKey(bobCtx, "bob", "order-42")
+	require.NoError(t, err)
+	assert.Equal(t, bobResp.Payment.ID, stored.ID)
+}
+
+func TestCreatePayment_Idempotency_DifferentRequest_Conflict(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 50000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	req := CreatePaymentRequest{
+		IdempotencyKey:       "test-key-reused",
+		PaymentType:          payment.InternalTransfer,
+		SourceAccountID:      &sourceAcct.ID,
+		DestinationAccountID: &destAcct.ID,
+		Amount:               10000,
+		Currency:             "USD",
+	}
+	_, err := svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+
+	// Same key, different amount
+	req.Amount = 20000
+	_, err = svc.CreatePayment(ctx, req)
+	assert.ErrorIs(t, err, domainErrors.ErrIdempotencyKeyReused)
+
+	// Only the first transfer moved money
+	sourceAfter := accountRepo.GetAccountByID(sourceAcct.ID)
+	assert.Equal(t, int64(90000), sourceAfter.Balance)
+}
+
 func TestCreatePayment_ExternalPayment_Success(t *testing.T) {
-	svc, paymentRepo, _, outboxRepo, _ := setupPaymentService()
+	svc, paymentRepo, accountRepo, outboxRepo, _ := setupPaymentService()
 	ctx := context.Background()
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
 
 	var outboxInserted bool
 	outboxRepo.InsertFunc = func(ctx context.Context, entry *outbox.Entry) error {
@@ -308,7 +507,7 @@ func TestCreatePayment_ExternalPayment_Success(t *testing.T) {
 	req := CreatePaymentRequest{
 		IdempotencyKey:  "test-key-external-1",
 		PaymentType:     payment.ExternalPayment,
-		SourceAccountID: nil,
+		SourceAccountID: &sourceAcct.ID,
 		Amount:          10000,
 		Currency:        "USD",
 		Provider:        &provider,
@@ -318,6 +517,7 @@ func TestCreatePayment_ExternalPayment_Success(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, resp)
 	assert.True(t, resp.IsAsync)
+	assert.Equal(t, OutcomeAccepted, resp.Outcome)
 	assert.Equal(t, payment.StatusPending, resp.Payment.Status)
 
 	// Verify payment was created
@@ -329,6 +529,234 @@ func TestCreatePayment_ExternalPayment_Success(t *testing.T) {
 	assert.True(t, outboxInserted)
 }
 
+func TestCreatePayment_ExternalPayment_AmountInProviderUnits(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	provider := &amountRecordingProvider{MockProvider: providers.NewMockProvider("stripe",
+		providers.WithLatency(0), providers.WithMinorUnits("USD", 0))}
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(provider), WithExternalSourceRequired(false))
+	ctx := context.Background()
+
+	stripe := payment.ProviderStripe
+	_, err := svc.CreatePayment(ctx, CreatePaymentRequest{
+		IdempotencyKey: "fractional-dollars",
+		PaymentType:    payment.ExternalPayment,
+		Amount:         10050,
+		Currency:       "USD",
+		Provider:       &stripe,
+	})
+	var validationErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &validationErr, "the provider only takes whole dollars")
+	assert.Equal(t, "amount", validationErr.Field)
+
+	resp, err := svc.CreatePayment(ctx, CreatePaymentRequest{
+		IdempotencyKey: "whole-dollars",
+		PaymentType:    payment.ExternalPayment,
+		Amount:         10000,
+		Currency:       "USD",
+		Provider:       &stripe,
+	})
+	require.NoError(t, err)
+	require.NoError(t, svc.ProcessPayment(ctx, resp.Payment.ID))
+	assert.Equal(t, []int64{100}, provider.amounts)
+}
+
+func TestCreatePayment_ExternalPayment_RequiredProviderOptions(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(),
+		providers.NewFactory(providers.NewMockProvider("stripe", providers.WithLatency(0), providers.WithRequiredOptions("customer_id"))),
+		WithExternalSourceRequired(false))
+	ctx := context.Background()
+
+	stripe := payment.ProviderStripe
+	req := CreatePaymentRequest{
+		IdempotencyKey:  "stripe-options",
+		PaymentType:     payment.ExternalPayment,
+		Amount:          10000,
+		Currency:        "USD",
+		Provider:        &stripe,
+		ProviderOptions: payment.ProviderOptions{"paypal": {"payer_email": "a@example.com"}},
+	}
+	_, err := svc.CreatePayment(ctx, req)
+	var validationErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &validationErr, "another provider's options do not count")
+	assert.Equal(t, "provider_options.stripe.customer_id", validationErr.Field)
+
+	req.ProviderOptions = payment.ProviderOptions{"stripe": {"customer_id": "cus_123"}}
+	resp, err := svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	require.NoError(t, svc.ProcessPayment(ctx, resp.Payment.ID), "the provider reads its options from the request")
+	assert.Equal(t, "cus_123", resp.Payment.ProviderOptions.For(stripe)["customer_id"])
+
+	req.ProviderOptions = payment.ProviderOptions{"stripe": {"customer_id": "cus_456"}}
+	_, err = svc.CreatePayment(ctx, req)
+	assert.ErrorIs(t, err, domainErrors.ErrIdempotencyKeyReused)
+}
+
+func TestCreatePayment_UsesConfiguredMaxRetries(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe")),
+		WithMaxRetries(5))
+	ctx := context.Background()
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+
+	provider := payment.ProviderStripe
+	resp, err := svc.CreatePayment(ctx, CreatePaymentRequest{
+		IdempotencyKey:  "max-retries-1",
+		PaymentType:     payment.ExternalPayment,
+		SourceAccountID: &sourceAcct.ID,
+		Amount:          10000,
+		Currency:        "USD",
+		Provider:        &provider,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, resp.Payment.MaxRetries)
+
+	stored, _ := paymentRepo.GetByID(ctx, resp.Payment.ID)
+	assert.Equal(t, 5, stored.MaxRetries)
+}
+
+func TestCreatePayment_InternalTransfer_PreferAsync_Queued(t *testing.T) {
+	svc, _, accountRepo, outboxRepo, _ := setupPaymentService()
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 0, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	var queued bool
+	outboxRepo.InsertFunc = func(ctx context.Context, entry *outbox.Entry) error {
+		queued = true
+		assert.NotContains(t, entry.Payload, "provider")
+		return nil
+	}
+
+	resp, err := svc.CreatePayment(ctx, CreatePaymentRequest{
+		IdempotencyKey:       "prefer-async",
+		PaymentType:          payment.InternalTransfer,
+		SourceAccountID:      &sourceAcct.ID,
+		DestinationAccountID: &destAcct.ID,
+		Amount:               10000,
+		Currency:             "USD",
+		Preference:           PreferAsync,
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.IsAsync)
+	assert.Equal(t, OutcomeAccepted, resp.Outcome)
+	assert.Equal(t, PreferAsync, resp.PreferenceApplied)
+	assert.Equal(t, payment.StatusPending, resp.Payment.Status)
+	assert.True(t, queued)
+	assert.Equal(t, int64(100000), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+}
+
+func TestProcessPayment_InternalTransfer_CompletionFails_FundsNotMoved(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, txManager := setupPaymentService()
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 0, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+	resp, err := svc.CreatePayment(ctx, CreatePaymentRequest{
+		IdempotencyKey:       "async-complete-fails",
+		PaymentType:          payment.InternalTransfer,
+		SourceAccountID:      &sourceAcct.ID,
+		DestinationAccountID: &destAcct.ID,
+		Amount:               10000,
+		Currency:             "USD",
+		Preference:           PreferAsync,
+	})
+	require.NoError(t, err)
+
+	// Roll back balances when the transaction fails, as Postgres would.
+	txManager.WithTransactionFunc = func(ctx context.Context, fn func(ctx context.Context) error) error {
+		source, dest := *accountRepo.GetAccountByID(sourceAcct.ID), *accountRepo.GetAccountByID(destAcct.ID)
+		if err := fn(ctx); err != nil {
+			*accountRepo.GetAccountByID(sourceAcct.ID), *accountRepo.GetAccountByID(destAcct.ID) = source, dest
+			return err
+		}
+		return nil
+	}
+	paymentRepo.UpdateFunc = func(ctx context.Context, p *payment.Payment) error {
+		if p.Status == payment.StatusCompleted {
+			assert.True(t, testutil.InMockTransaction(ctx), "completion is saved with the funds movement")
+			return domainErrors.ErrOptimisticLockFailed
+		}
+		return nil
+	}
+
+	err = svc.ProcessPayment(ctx, resp.Payment.ID)
+	require.Error(t, err)
+
+	stored, _ := paymentRepo.GetByID(ctx, resp.Payment.ID)
+	assert.Equal(t, payment.StatusFailed, stored.Status, "a failed completion is retried, not left processing")
+	assert.Nil(t, stored.CompletedAt)
+	assert.Equal(t, int64(100000), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+	assert.Equal(t, int64(0), accountRepo.GetAccountByID(destAcct.ID).Balance)
+}
+
+func TestCreatePayment_ExternalPayment_PreferSync_NotHonored(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	provider := payment.ProviderStripe
+
+	resp, err := svc.CreatePayment(context.Background(), CreatePaymentRequest{
+		IdempotencyKey:  "prefer-sync",
+		PaymentType:     payment.ExternalPayment,
+		SourceAccountID: &sourceAcct.ID,
+		Amount:          5000,
+		Currency:        "USD",
+		Provider:        &provider,
+		Preference:      PreferSync,
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.IsAsync)
+	assert.Equal(t, PreferDefault, resp.PreferenceApplied)
+}
+
+func TestCreatePayment_ExternalPayment_NoSource_Rejected(t *testing.T) {
+	svc, paymentRepo, _, _, _ := setupPaymentService()
+	provider := payment.ProviderStripe
+
+	_, err := svc.CreatePayment(context.Background(), CreatePaymentRequest{
+		IdempotencyKey: "no-source",
+		PaymentType:    payment.ExternalPayment,
+		Amount:         5000,
+		Currency:       "USD",
+		Provider:       &provider,
+	})
+
+	var vErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &vErr)
+	assert.Equal(t, "source_account_id", vErr.Field)
+	stored, _ := paymentRepo.GetByIdempotencyKey(context.Background(), "", "no-source")
+	assert.Nil(t, stored)
+}
+
+func TestCreatePayment_ExternalPayment_NoSource_AllowedWhenDisabled(t *testing.T) {
+	svc := NewPaymentService(testutil.NewMockPaymentRepository(), testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe")),
+		WithExternalSourceRequired(false))
+	provider := payment.ProviderStripe
+
+	resp, err := svc.CreatePayment(context.Background(), CreatePaymentRequest{
+		IdempotencyKey: "no-source",
+		PaymentType:    payment.ExternalPayment,
+		Amount:         5000,
+		Currency:       "USD",
+		Provider:       &provider,
+	})
+
+	require.NoError(t, err)
+	assert.Nil(t, resp.Payment.SourceAccountID)
+}
+
 func TestCreatePayment_TransactionRollback(t *testing.T) {
 	svc, paymentRepo, accountRepo, _, txManager := setupPaymentService()
 	ctx := context.Background()
@@ -356,7 +784,7 @@ func TestCreatePayment_TransactionRollback(t *testing.T) {
 	assert.Error(t, err)
 
 	// Verify payment was not created
-	stored, _ := paymentRepo.GetByIdempotencyKey(ctx, "test-key-rollback")
+	stored, _ := paymentRepo.GetByIdempotencyKey(ctx, "", "test-key-rollback")
 	assert.Nil(t, stored)
 
 	// Verify balances unchanged
@@ -374,7 +802,7 @@ func TestProcessPayment_PendingToCompleted(t *testing.T) {
 
 	// Create a pending external payment
 	provider := payment.ProviderStripe
-	p, err := payment.NewPayment("test-key", payment.ExternalPayment, nil, nil, payment.Amount{ValueCents: 10000, Currency: "USD"})
+	p, err := payment.NewPayment("test-key", payment.ExternalPayment, nil, nil, payment.NewAmount(10000, "USD"))
 	require.NoError(t, err)
 	p.SetProvider(provider)
 	paymentRepo.Create(ctx, p)
@@ -388,12 +816,32 @@ func TestProcessPayment_PendingToCompleted(t *testing.T) {
 	assert.NotNil(t, stored.ProviderTransactionID)
 }
 
+func TestProcessPayment_QueuedInternalTransfer_MovesFunds(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 0, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	p := testutil.NewTestPayment(payment.InternalTransfer, &sourceAcct.ID, &destAcct.ID, 10000, "USD")
+	paymentRepo.Create(ctx, p)
+
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusCompleted, stored.Status)
+	assert.Equal(t, int64(90000), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+	assert.Equal(t, int64(10000), accountRepo.GetAccountByID(destAcct.ID).Balance)
+}
+
 func TestProcessPayment_AlreadyCompleted_NoOp(t *testing.T) {
 	svc, paymentRepo, _, _, _ := setupPaymentService()
 	ctx := context.Background()
 
 	// Create a completed payment
-	p, err := payment.NewPayment("test-key", payment.ExternalPayment, nil, nil, payment.Amount{ValueCents: 10000, Currency: "USD"})
+	p, err := payment.NewPayment("test-key", payment.ExternalPayment, nil, nil, payment.NewAmount(10000, "USD"))
 	require.NoError(t, err)
 	p.MarkCompleted(nil)
 	paymentRepo.Create(ctx, p)
@@ -407,20 +855,13 @@ func TestProcessPayment_AlreadyCompleted_NoOp(t *testing.T) {
 }
 
 func TestProcessPayment_PaymentNotFound(t *testing.T) {
-	svc, paymentRepo, _, _, _ := setupPaymentService()
+	svc, _, _, _, _ := setupPaymentService()
 	ctx := context.Background()
 
-	// Mock GetByID to return error for non-existent payment
 	nonExistentID := uuid.New()
-	paymentRepo.GetByIDFunc = func(ctx context.Context, id uuid.UUID) (*payment.Payment, error) {
-		if id == nonExistentID {
-			return nil, domainErrors.ErrPaymentNotFound
-		}
-		return nil, domainErrors.ErrPaymentNotFound
-	}
 
 	err := svc.ProcessPayment(ctx, nonExistentID)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, domainErrors.ErrPaymentNotFound)
 	assert.Contains(t, err.Error(), "load payment")
 }
 
@@ -439,7 +880,7 @@ func TestProcessPayment_ProviderFailure_MarksAsFailed(t *testing.T) {
 
 	// Create a pending payment
 	provider := payment.Provider("failing")
-	p, err := payment.NewPayment("test-key", payment.ExternalPayment, nil, nil, payment.Amount{ValueCents: 10000, Currency: "USD"})
+	p, err := payment.NewPayment("test-key", payment.ExternalPayment, nil, nil, payment.NewAmount(10000, "USD"))
 	require.NoError(t, err)
 	p.SetProvider(provider)
 	paymentRepo.Create(ctx, p)
@@ -459,7 +900,7 @@ func TestProcessPayment_WithRetry_IncrementsRetryCount(t *testing.T) {
 
 	// Create a failed payment
 	provider := payment.ProviderStripe
-	p, err := payment.NewPayment("test-key", payment.ExternalPayment, nil, nil, payment.Amount{ValueCents: 10000, Currency: "USD"})
+	p, err := payment.NewPayment("test-key", payment.ExternalPayment, nil, nil, payment.NewAmount(10000, "USD"))
 	require.NoError(t, err)
 	p.SetProvider(provider)
 	p.MarkProcessing()
@@ -476,15 +917,160 @@ func TestProcessPayment_WithRetry_IncrementsRetryCount(t *testing.T) {
 	assert.Equal(t, payment.StatusCompleted, stored.Status)
 }
 
-func TestProcessPayment_WithSourceAccount_ReservesFunds(t *testing.T) {
-	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+func TestProcessPayment_FailsThenSucceedsOnRetry(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	provider := providers.NewMockProvider("stripe", providers.WithLatency(0),
+		providers.WithScript(providers.CallOutcome{Err: domainErrors.ErrProviderRejected}, providers.CallOutcome{}))
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(provider), WithRetryBackoff(time.Nanosecond, time.Nanosecond))
 	ctx := context.Background()
 
-	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
-	accountRepo.AddAccount(sourceAcct)
+	p, err := payment.NewPayment("client-key", payment.ExternalPayment, nil, nil, payment.NewAmount(10000, "USD"))
+	require.NoError(t, err)
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	assert.ErrorIs(t, svc.ProcessPayment(ctx, p.ID), domainErrors.ErrPaymentFailed)
+	failed, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusFailed, failed.Status)
+
+	time.Sleep(time.Millisecond)
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+	completed, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusCompleted, completed.Status)
+	assert.Equal(t, 1, completed.RetryCount)
+}
+
+func TestProcessPayment_Retry_ReusesChargeIdempotencyKey(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	provider := &flakyProvider{}
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(provider))
+	ctx := context.Background()
+
+	p, err := payment.NewPayment("client-key", payment.ExternalPayment, nil, nil, payment.NewAmount(10000, "USD"))
+	require.NoError(t, err)
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	assert.Error(t, svc.ProcessPayment(ctx, p.ID))
+	failed, _ := paymentRepo.GetByID(ctx, p.ID)
+	failed.ScheduleRetry(time.Now()) // skip the backoff
+	require.NoError(t, paymentRepo.Update(ctx, failed))
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+
+	require.Len(t, provider.keys, 2)
+	assert.Equal(t, payment.IdempotencyKey(payment.ScopeCharge, "client-key"), provider.keys[0])
+	assert.Equal(t, provider.keys[0], provider.keys[1], "a retry must not look like a new charge")
+}
+
+func TestProcessPayment_FailureReportsWhetherRetriesRemain(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(&flakyProvider{}))
+	ctx := context.Background()
+
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, paymentRepo.Create(ctx, p))
+	err := svc.ProcessPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrPaymentFailed)
+
+	exhausted := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	exhausted.SetProvider(payment.ProviderStripe)
+	require.NoError(t, exhausted.SetMaxRetries(0))
+	require.NoError(t, paymentRepo.Create(ctx, exhausted))
+	svc = NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(&flakyProvider{}))
+	err = svc.ProcessPayment(ctx, exhausted.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrMaxRetriesExceeded)
+
+	retryable, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusFailed, retryable.Status)
+	abandoned, _ := paymentRepo.GetByID(ctx, exhausted.ID)
+	assert.Equal(t, payment.StatusAbandoned, abandoned.Status)
+	events, _ := paymentRepo.GetEvents(ctx, exhausted.ID)
+	require.NotEmpty(t, events)
+	assert.Equal(t, string(payment.EventPaymentAbandoned), events[len(events)-1].EventType)
+}
+
+func TestProcessPayment_RecordsProcessingKey(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	store := newFakeProcessingStore()
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe")),
+		WithProcessingStore(store))
+	ctx := context.Background()
+
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+
+	updated, _ := paymentRepo.GetByID(ctx, p.ID)
+	key := fmt.Sprintf("process:%s:0", p.ID)
+	assert.Equal(t, []string{key}, store.started)
+	require.NotNil(t, updated.ProviderTransactionID)
+	assert.Equal(t, *updated.ProviderTransactionID, store.completed[key])
+}
+
+func TestProcessPayment_RedeliveryReplaysRecordedResult(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	provider := &flakyProvider{}
+	store := newFakeProcessingStore()
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(provider), WithProcessingStore(store))
+	ctx := context.Background()
+
+	// The worker charged the payment and crashed before saving the result.
+	source := createTestAccount(t, "user1", 90000, account.StatusActive)
+	accountRepo.AddAccount(source)
+	p := testutil.NewTestPayment(payment.ExternalPayment, &source.ID, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, p.MarkProcessing())
+	require.NoError(t, paymentRepo.Create(ctx, p))
+	store.completed[fmt.Sprintf("process:%s:0", p.ID)] = "txn_original"
+
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+
+	assert.Empty(t, provider.keys, "the provider must not be charged again")
+	updated, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusCompleted, updated.Status)
+	require.NotNil(t, updated.ProviderTransactionID)
+	assert.Equal(t, "txn_original", *updated.ProviderTransactionID)
+	assert.Equal(t, int64(90000), accountRepo.GetAccountByID(source.ID).Balance, "funds are not reserved twice")
+}
+
+func TestProcessPayment_ProcessingWithoutRecordIsLeftAlone(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	provider := &flakyProvider{}
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(provider), WithProcessingStore(newFakeProcessingStore()))
+	ctx := context.Background()
+
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, p.MarkProcessing())
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+
+	assert.Empty(t, provider.keys)
+	updated, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusProcessing, updated.Status)
+}
+
+func TestProcessPayment_WithSourceAccount_ReservesFunds(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
 
 	provider := payment.ProviderStripe
-	p, err := payment.NewPayment("test-key", payment.ExternalPayment, &sourceAcct.ID, nil, payment.Amount{ValueCents: 10000, Currency: "USD"})
+	p, err := payment.NewPayment("test-key", payment.ExternalPayment, &sourceAcct.ID, nil, payment.NewAmount(10000, "USD"))
 	require.NoError(t, err)
 	p.SetProvider(provider)
 	paymentRepo.Create(ctx, p)
@@ -492,15 +1078,500 @@ func TestProcessPayment_WithSourceAccount_ReservesFunds(t *testing.T) {
 	err = svc.ProcessPayment(ctx, p.ID)
 	require.NoError(t, err)
 
-	// Verify funds were reserved (debited)
+	// Verify the funds hold was captured
 	sourceAfter := accountRepo.GetAccountByID(sourceAcct.ID)
 	assert.Equal(t, int64(90000), sourceAfter.Balance)
+	assert.Zero(t, sourceAfter.HeldBalance)
 
 	// Verify payment completed
 	stored, _ := paymentRepo.GetByID(ctx, p.ID)
 	assert.Equal(t, payment.StatusCompleted, stored.Status)
 }
 
+func TestProcessPayment_SourceAccountClosed_FailsWithAccountUnavailable(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+
+	p, err := payment.NewPayment("test-key", payment.ExternalPayment, &sourceAcct.ID, nil, payment.NewAmount(10000, "USD"))
+	require.NoError(t, err)
+	p.SetProvider(payment.ProviderStripe)
+	paymentRepo.Create(ctx, p)
+
+	// Account is closed after the payment was accepted but before the worker runs
+	require.NoError(t, sourceAcct.Deactivate())
+	closedVersion := sourceAcct.Version
+
+	err = svc.ProcessPayment(ctx, p.ID)
+	require.Error(t, err)
+
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusFailed, stored.Status)
+	require.NotNil(t, stored.LastError)
+	assert.True(t, strings.HasPrefix(*stored.LastError, "account_unavailable"), *stored.LastError)
+
+	// No reservation was taken
+	sourceAfter := accountRepo.GetAccountByID(sourceAcct.ID)
+	assert.Equal(t, int64(100000), sourceAfter.Balance)
+	assert.Equal(t, closedVersion, sourceAfter.Version)
+}
+
+func TestProcessPayment_CancelSignal_CompensatesAndCancels(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+
+	p, err := payment.NewPayment("test-key", payment.ExternalPayment, &sourceAcct.ID, nil, payment.NewAmount(10000, "USD"))
+	require.NoError(t, err)
+	p.SetProvider(payment.ProviderStripe)
+	paymentRepo.Create(context.Background(), p)
+
+	// Cancelled before the (100ms) mock provider call can return
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(domainErrors.ErrPaymentCancelled)
+
+	err = svc.ProcessPayment(ctx, p.ID)
+	require.NoError(t, err)
+
+	stored, _ := paymentRepo.GetByID(context.Background(), p.ID)
+	assert.Equal(t, payment.StatusCancelled, stored.Status)
+
+	// The funds hold was released
+	sourceAfter := accountRepo.GetAccountByID(sourceAcct.ID)
+	assert.Equal(t, int64(100000), sourceAfter.Balance)
+	assert.Zero(t, sourceAfter.HeldBalance)
+}
+
+func TestProcessPayment_ExternalPayment_CapturesHold(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	p := testutil.NewTestPayment(payment.ExternalPayment, &sourceAcct.ID, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+
+	holds := accountRepo.Holds(p.ID)
+	require.Len(t, holds, 1)
+	assert.Equal(t, account.HoldCaptured, holds[0].Status)
+	txns, _ := accountRepo.GetTransactions(ctx, sourceAcct.ID, 10, 0)
+	require.Len(t, txns, 1, "only the capture reaches the ledger")
+	assert.Equal(t, account.TransactionDebit, txns[0].TransactionType)
+	assert.Equal(t, int64(90000), txns[0].BalanceAfter)
+}
+
+func TestProcessPayment_ProviderFailure_ReleasesHold(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(&flakyProvider{}))
+	ctx := context.Background()
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	p := testutil.NewTestPayment(payment.ExternalPayment, &sourceAcct.ID, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	assert.Error(t, svc.ProcessPayment(ctx, p.ID))
+
+	sourceAfter := accountRepo.GetAccountByID(sourceAcct.ID)
+	assert.Equal(t, int64(100000), sourceAfter.Balance)
+	assert.Zero(t, sourceAfter.HeldBalance)
+	holds := accountRepo.Holds(p.ID)
+	require.Len(t, holds, 1)
+	assert.Equal(t, account.HoldReleased, holds[0].Status)
+	txns, _ := accountRepo.GetTransactions(ctx, sourceAcct.ID, 10, 0)
+	assert.Empty(t, txns)
+}
+
+func TestProcessPayment_SlowProvider_RoutesToFallback(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	factory := providers.NewFactory(
+		providers.NewMockProvider("stripe", providers.WithLatency(20*time.Millisecond)),
+		providers.NewMockProvider("paypal", providers.WithLatency(0)),
+	)
+	slo := providers.LatencySLO{Percentile: 0.95, Threshold: 10 * time.Millisecond, Window: 1, MinSamples: 1, Cooldown: time.Minute}
+	require.NoError(t, factory.ConfigureLatencySLO(slo, map[string]string{"stripe": "paypal"}))
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), factory, WithExternalSourceRequired(false))
+	ctx := context.Background()
+
+	process := func(key string) *payment.Payment {
+		p, err := payment.NewPayment(key, payment.ExternalPayment, nil, nil, payment.NewAmount(10000, "USD"))
+		require.NoError(t, err)
+		p.SetProvider(payment.ProviderStripe)
+		paymentRepo.Create(ctx, p)
+		require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+		stored, _ := paymentRepo.GetByID(ctx, p.ID)
+		return stored
+	}
+
+	first := process("slow-1")
+	assert.Equal(t, payment.ProviderStripe, *first.Provider, "the breaching call itself is not rerouted")
+
+	second := process("slow-2")
+	assert.Equal(t, payment.StatusCompleted, second.Status)
+	assert.Equal(t, payment.ProviderPayPal, *second.Provider)
+	events, _ := paymentRepo.GetEvents(ctx, second.ID)
+	require.NotEmpty(t, events)
+	assert.Equal(t, "stripe", events[len(events)-1].EventData["rerouted_from"])
+}
+
+// --- CancelPayment Tests ---
+
+type recordingCancelNotifier struct {
+	notified []uuid.UUID
+}
+
+func (n *recordingCancelNotifier) NotifyCancel(ctx context.Context, paymentID uuid.UUID) error {
+	n.notified = append(n.notified, paymentID)
+	return nil
+}
+
+func TestCancelPayment_Pending_Cancelled(t *testing.T) {
+	svc, paymentRepo, _, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	paymentRepo.Create(ctx, p)
+
+	resp, err := svc.CancelPayment(ctx, p.ID)
+	require.NoError(t, err)
+	assert.False(t, resp.InFlight)
+	assert.Equal(t, payment.StatusCancelled, resp.Payment.Status)
+}
+
+func TestCancelPayment_Processing_SignalsWorker(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	notifier := &recordingCancelNotifier{}
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe")),
+		WithCancelNotifier(notifier))
+	ctx := context.Background()
+
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	p.Status = payment.StatusProcessing
+	paymentRepo.Create(ctx, p)
+
+	resp, err := svc.CancelPayment(ctx, p.ID)
+	require.NoError(t, err)
+	assert.True(t, resp.InFlight)
+	assert.Equal(t, payment.StatusProcessing, resp.Payment.Status)
+	assert.Equal(t, []uuid.UUID{p.ID}, notifier.notified)
+}
+
+func TestCancelPayment_Completed_InvalidTransition(t *testing.T) {
+	svc, paymentRepo, _, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	p := testutil.NewCompletedPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	paymentRepo.Create(ctx, p)
+
+	_, err := svc.CancelPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidStateTransition)
+}
+
+func TestCancelPayment_ClaimedConcurrently_Conflict(t *testing.T) {
+	svc, paymentRepo, _, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	paymentRepo.Create(ctx, p)
+	stale := *p
+
+	// A worker claims the payment after the cancel request loaded it.
+	claimed := *p
+	require.NoError(t, claimed.MarkProcessing())
+	require.NoError(t, paymentRepo.Update(ctx, &claimed))
+	paymentRepo.GetByIDFunc = func(ctx context.Context, id uuid.UUID) (*payment.Payment, error) {
+		cp := stale
+		return &cp, nil
+	}
+
+	_, err := svc.CancelPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrOptimisticLockFailed)
+	paymentRepo.GetByIDFunc = nil
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusProcessing, stored.Status, "the claim is not overwritten")
+	assert.Equal(t, 1, stored.Version)
+}
+
+func TestProcessPayment_ConcurrentClaim_LoserStops(t *testing.T) {
+	svc, paymentRepo, _, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	paymentRepo.Create(ctx, p)
+	stale := *p
+	paymentRepo.GetByIDFunc = func(ctx context.Context, id uuid.UUID) (*payment.Payment, error) {
+		cp := stale
+		return &cp, nil
+	}
+
+	// Both workers loaded the pending payment; the first claim wins.
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+	err := svc.ProcessPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrOptimisticLockFailed)
+
+	paymentRepo.GetByIDFunc = nil
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusCompleted, stored.Status)
+}
+
+// --- Transfer Fee Tests ---
+
+func setupFeeService(t *testing.T, policy TransferFeePolicy) (*PaymentService, *testutil.MockPaymentRepository, *testutil.MockAccountRepository) {
+	t.Helper()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(), WithTransferFee(policy))
+	return svc, paymentRepo, accountRepo
+}
+
+func TestTransferFeePolicy_Fee(t *testing.T) {
+	policy := TransferFeePolicy{FlatCents: 25, BasisPoints: 150} // 0.25 + 1.5%
+	assert.Equal(t, int64(25+150), policy.Fee(10000))
+	assert.Equal(t, int64(25+1), policy.Fee(50)) // 0.75 cents rounds up
+	assert.Equal(t, int64(25+0), policy.Fee(30)) // 0.45 cents rounds down
+}
+
+func TestTransfer_WithFee_ChargesSourceAndCreditsFeeAccount(t *testing.T) {
+	feeAcct := createTestAccount(t, "fees", 0, account.StatusActive)
+	svc, _, accountRepo := setupFeeService(t, TransferFeePolicy{AccountID: feeAcct.ID, FlatCents: 100})
+	sourceAcct := createTestAccount(t, "user1", 10000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 0, account.StatusActive)
+	accountRepo.AddAccount(feeAcct)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	resp, err := svc.Transfer(context.Background(), TransferRequest{
+		IdempotencyKey:       "fee-transfer",
+		SourceAccountID:      sourceAcct.ID,
+		DestinationAccountID: destAcct.ID,
+		Amount:               5000,
+		Currency:             "USD",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), resp.Payment.FeeCents)
+	assert.Equal(t, int64(4900), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+	assert.Equal(t, int64(5000), accountRepo.GetAccountByID(destAcct.ID).Balance)
+	assert.Equal(t, int64(100), accountRepo.GetAccountByID(feeAcct.ID).Balance)
+}
+
+func TestTransfer_WithFee_InsufficientForAmountPlusFee(t *testing.T) {
+	feeAcct := createTestAccount(t, "fees", 0, account.StatusActive)
+	svc, _, accountRepo := setupFeeService(t, TransferFeePolicy{AccountID: feeAcct.ID, FlatCents: 100})
+	sourceAcct := createTestAccount(t, "user1", 5050, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 0, account.StatusActive)
+	accountRepo.AddAccount(feeAcct)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	_, err := svc.Transfer(context.Background(), TransferRequest{
+		IdempotencyKey:       "fee-insufficient",
+		SourceAccountID:      sourceAcct.ID,
+		DestinationAccountID: destAcct.ID,
+		Amount:               5000,
+		Currency:             "USD",
+	})
+	assert.ErrorIs(t, err, domainErrors.ErrInsufficientFunds)
+	assert.Equal(t, int64(5050), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+}
+
+func TestTransfer_WithFee_OtherCurrencyNotCharged(t *testing.T) {
+	feeAcct, err := account.NewAccount("fees", 0, "EUR")
+	require.NoError(t, err)
+	svc, _, accountRepo := setupFeeService(t, TransferFeePolicy{AccountID: feeAcct.ID, FlatCents: 100})
+	sourceAcct := createTestAccount(t, "user1", 10000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 0, account.StatusActive)
+	accountRepo.AddAccount(feeAcct)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	resp, err := svc.Transfer(context.Background(), TransferRequest{
+		IdempotencyKey:       "fee-other-currency",
+		SourceAccountID:      sourceAcct.ID,
+		DestinationAccountID: destAcct.ID,
+		Amount:               5000,
+		Currency:             "USD",
+	})
+	require.NoError(t, err)
+	assert.Zero(t, resp.Payment.FeeCents)
+	assert.Equal(t, int64(5000), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+}
+
+func TestRefundPayment_WithFee_ReversesFee(t *testing.T) {
+	feeAcct := createTestAccount(t, "fees", 0, account.StatusActive)
+	svc, _, accountRepo := setupFeeService(t, TransferFeePolicy{AccountID: feeAcct.ID, FlatCents: 100})
+	sourceAcct := createTestAccount(t, "user1", 10000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 0, account.StatusActive)
+	accountRepo.AddAccount(feeAcct)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+	ctx := context.Background()
+
+	resp, err := svc.Transfer(ctx, TransferRequest{
+		IdempotencyKey:       "fee-refund",
+		SourceAccountID:      sourceAcct.ID,
+		DestinationAccountID: destAcct.ID,
+		Amount:               5000,
+		Currency:             "USD",
+	})
+	require.NoError(t, err)
+
+	_, err = svc.RefundPayment(ctx, resp.Payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10000), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+	assert.Equal(t, int64(0), accountRepo.GetAccountByID(destAcct.ID).Balance)
+	assert.Equal(t, int64(0), accountRepo.GetAccountByID(feeAcct.ID).Balance)
+}
+
+func setupPlatformFeeService(t *testing.T) (*PaymentService, *testutil.MockAccountRepository, *account.Account, *account.Account, *account.Account) {
+	t.Helper()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	feeAcct := createTestAccount(t, "platform", 0, account.StatusActive)
+	buyer := createTestAccount(t, "buyer", 10000, account.StatusActive)
+	seller := createTestAccount(t, "seller", 0, account.StatusActive)
+	accountRepo.AddAccount(feeAcct)
+	accountRepo.AddAccount(buyer)
+	accountRepo.AddAccount(seller)
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe")),
+		WithPlatformFeeAccount(feeAcct.ID))
+	return svc, accountRepo, feeAcct, buyer, seller
+}
+
+func platformFeePayment(buyer, seller *account.Account, feeCents int64) CreatePaymentRequest {
+	provider := payment.ProviderStripe
+	return CreatePaymentRequest{
+		IdempotencyKey:       "marketplace-order",
+		PaymentType:          payment.ExternalPayment,
+		SourceAccountID:      &buyer.ID,
+		DestinationAccountID: &seller.ID,
+		Amount:               5000,
+		Currency:             "USD",
+		Provider:             &provider,
+		FeeCents:             feeCents,
+	}
+}
+
+func TestProcessPayment_PlatformFee_SplitsNetAndFee(t *testing.T) {
+	svc, accountRepo, feeAcct, buyer, seller := setupPlatformFeeService(t)
+	ctx := context.Background()
+
+	resp, err := svc.CreatePayment(ctx, platformFeePayment(buyer, seller, 250))
+	require.NoError(t, err)
+	assert.Equal(t, int64(250), resp.Payment.FeeCents)
+	assert.Equal(t, int64(4750), resp.Payment.NetCents())
+
+	require.NoError(t, svc.ProcessPayment(ctx, resp.Payment.ID))
+	assert.Equal(t, int64(5000), accountRepo.GetAccountByID(buyer.ID).Balance)
+	assert.Equal(t, int64(4750), accountRepo.GetAccountByID(seller.ID).Balance)
+	assert.Equal(t, int64(250), accountRepo.GetAccountByID(feeAcct.ID).Balance)
+
+	// The net amount and the fee are separate ledger rows that add up to the charge.
+	sellerTxns, _ := accountRepo.GetTransactions(ctx, seller.ID, 10, 0)
+	feeTxns, _ := accountRepo.GetTransactions(ctx, feeAcct.ID, 10, 0)
+	require.Len(t, sellerTxns, 1)
+	require.Len(t, feeTxns, 1)
+	assert.Equal(t, "platform fee", feeTxns[0].Description)
+	assert.Equal(t, int64(5000), sellerTxns[0].Amount+feeTxns[0].Amount)
+
+	_, err = svc.RefundPayment(ctx, resp.Payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10000), accountRepo.GetAccountByID(buyer.ID).Balance)
+	assert.Zero(t, accountRepo.GetAccountByID(seller.ID).Balance)
+	assert.Zero(t, accountRepo.GetAccountByID(feeAcct.ID).Balance)
+}
+
+func TestCreatePayment_PlatformFee_Validation(t *testing.T) {
+	svc, _, _, buyer, seller := setupPlatformFeeService(t)
+	ctx := context.Background()
+
+	_, err := svc.CreatePayment(ctx, platformFeePayment(buyer, seller, 5001))
+	assert.ErrorContains(t, err, "must not exceed the amount")
+
+	noDest := platformFeePayment(buyer, seller, 100)
+	noDest.DestinationAccountID = nil
+	_, err = svc.CreatePayment(ctx, noDest)
+	assert.ErrorContains(t, err, "destination_account_id")
+
+	transfer := platformFeePayment(buyer, seller, 100)
+	transfer.PaymentType = payment.InternalTransfer
+	transfer.Provider = nil
+	_, err = svc.CreatePayment(ctx, transfer)
+	assert.ErrorContains(t, err, "only applies to external payments")
+
+	disabled, _, otherAccounts, _, _ := setupPaymentService()
+	otherAccounts.AddAccount(buyer)
+	otherAccounts.AddAccount(seller)
+	_, err = disabled.CreatePayment(ctx, platformFeePayment(buyer, seller, 100))
+	assert.ErrorContains(t, err, "platform fees are not enabled")
+
+	resp, err := svc.CreatePayment(ctx, platformFeePayment(buyer, seller, 5000))
+	require.NoError(t, err, "the whole amount may go to the platform")
+	_, err = svc.CreatePayment(ctx, platformFeePayment(buyer, seller, 100))
+	assert.ErrorIs(t, err, domainErrors.ErrIdempotencyKeyReused, "a replay must carry the same fee")
+	assert.Zero(t, resp.Payment.NetCents())
+}
+
+// --- Transaction Propagation Tests ---
+
+// Every debit and credit must run inside WithTransaction; RequireTx makes the
+// mock repository reject balance writes on a context without the tx.
+func TestMoneyMovement_AlwaysRunsInTransaction(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+	accountRepo.RequireTx = true
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	destAcct := createTestAccount(t, "user2", 0, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+
+	transfer, err := svc.Transfer(ctx, TransferRequest{
+		IdempotencyKey:       "tx-transfer",
+		SourceAccountID:      sourceAcct.ID,
+		DestinationAccountID: destAcct.ID,
+		Amount:               1000,
+		Currency:             "USD",
+	})
+	require.NoError(t, err)
+
+	queued, err := svc.CreatePayment(ctx, CreatePaymentRequest{
+		IdempotencyKey:       "tx-queued-transfer",
+		PaymentType:          payment.InternalTransfer,
+		SourceAccountID:      &sourceAcct.ID,
+		DestinationAccountID: &destAcct.ID,
+		Amount:               1000,
+		Currency:             "USD",
+		Preference:           PreferAsync,
+	})
+	require.NoError(t, err)
+	require.NoError(t, svc.ProcessPayment(ctx, queued.Payment.ID))
+
+	external := testutil.NewTestPayment(payment.ExternalPayment, &sourceAcct.ID, nil, 1000, "USD")
+	external.SetProvider(payment.ProviderStripe)
+	paymentRepo.Create(ctx, external)
+	require.NoError(t, svc.ProcessPayment(ctx, external.ID))
+
+	_, err = svc.RefundPayment(ctx, transfer.Payment.ID)
+	require.NoError(t, err)
+	_, err = svc.RefundPayment(ctx, external.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(99000), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+	assert.Equal(t, int64(1000), accountRepo.GetAccountByID(destAcct.ID).Balance)
+}
+
 // --- RefundPayment Tests ---
 
 func TestRefundPayment_Success(t *testing.T) {
@@ -512,7 +1583,7 @@ func TestRefundPayment_Success(t *testing.T) {
 
 	// Create a completed external payment
 	provider := payment.ProviderStripe
-	p, err := payment.NewPayment("test-key", payment.ExternalPayment, &sourceAcct.ID, nil, payment.Amount{ValueCents: 10000, Currency: "USD"})
+	p, err := payment.NewPayment("test-key", payment.ExternalPayment, &sourceAcct.ID, nil, payment.NewAmount(10000, "USD"))
 	require.NoError(t, err)
 	p.SetProvider(provider)
 	p.MarkCompleted(nil)
@@ -527,12 +1598,174 @@ func TestRefundPayment_Success(t *testing.T) {
 	assert.Equal(t, int64(60000), sourceAfter.Balance) // 50000 + 10000
 }
 
+func TestRefundPayment_ProviderReportsFailure_BalancesUnchanged(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	factory := providers.NewFactory(providers.NewMockProvider("stripe", providers.WithLatency(0), providers.WithRefundStatus(providers.ResultFailed)))
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{}, testutil.NewMockTransactionManager(), factory)
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 50000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	p := testutil.NewCompletedPayment(payment.ExternalPayment, &sourceAcct.ID, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	paymentRepo.Create(ctx, p)
+
+	_, err := svc.RefundPayment(ctx, p.ID)
+
+	assert.ErrorIs(t, err, domainErrors.ErrProviderRejected)
+	assert.Equal(t, int64(50000), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusCompleted, stored.Status)
+}
+
+func TestRefundPayment_DestinationSpentFunds_NothingReversed(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, txManager := setupPaymentService()
+	ctx := context.Background()
+
+	sourceAcct := createTestAccount(t, "user1", 50000, account.StatusActive)
+	destAcct := createTestAccount(t, "merchant", 0, account.StatusActive) // already spent the payment
+	accountRepo.AddAccount(sourceAcct)
+	accountRepo.AddAccount(destAcct)
+	p := testutil.NewCompletedPayment(payment.ExternalPayment, &sourceAcct.ID, &destAcct.ID, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	paymentRepo.Create(ctx, p)
+
+	// Roll back balances when the transaction fails, as Postgres would.
+	txManager.WithTransactionFunc = func(ctx context.Context, fn func(ctx context.Context) error) error {
+		source, dest := *accountRepo.GetAccountByID(sourceAcct.ID), *accountRepo.GetAccountByID(destAcct.ID)
+		if err := fn(ctx); err != nil {
+			*accountRepo.GetAccountByID(sourceAcct.ID), *accountRepo.GetAccountByID(destAcct.ID) = source, dest
+			return err
+		}
+		return nil
+	}
+
+	_, err := svc.RefundPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrInsufficientFunds)
+	assert.Equal(t, int64(50000), accountRepo.GetAccountByID(sourceAcct.ID).Balance, "the source is not credited")
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusCompleted, stored.Status)
+
+	// Once the destination is funded a retried refund credits the source once.
+	accountRepo.GetAccountByID(destAcct.ID).Balance = 10000
+	refunded, err := svc.RefundPayment(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusRefunded, refunded.Status)
+	assert.Equal(t, int64(60000), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+	assert.Zero(t, accountRepo.GetAccountByID(destAcct.ID).Balance)
+}
+
+func setupManualRefundService(t *testing.T, opts ...PaymentServiceOption) (*PaymentService, *testutil.MockAccountRepository, *payment.Payment, *account.Account) {
+	t.Helper()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	// The provider would fail any API refund, proving it is never called.
+	factory := providers.NewFactory(providers.NewMockProvider("stripe", providers.WithLatency(0),
+		providers.WithManualRefunds(), providers.WithRefundStatus(providers.ResultFailed)))
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{}, testutil.NewMockTransactionManager(), factory, opts...)
+
+	sourceAcct := createTestAccount(t, "user1", 50000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+	p := testutil.NewCompletedPayment(payment.ExternalPayment, &sourceAcct.ID, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	paymentRepo.Create(context.Background(), p)
+	return svc, accountRepo, p, sourceAcct
+}
+
+func TestRefundPayment_ManualRefundProvider_RecordsTask(t *testing.T) {
+	manualRepo := testutil.NewMockManualRefundRepository()
+	svc, accountRepo, p, sourceAcct := setupManualRefundService(t, WithManualRefunds(manualRepo, ManualRefundTask))
+
+	refunded, err := svc.RefundPayment(context.Background(), p.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusRefunded, refunded.Status)
+	assert.Equal(t, int64(60000), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+	task := manualRepo.Get(p.ID)
+	require.NotNil(t, task)
+	assert.Equal(t, payment.ManualRefundPending, task.Status)
+	assert.Equal(t, int64(10000), task.AmountCents)
+}
+
+func TestRefundPayment_ManualRefundProvider_RejectMode(t *testing.T) {
+	manualRepo := testutil.NewMockManualRefundRepository()
+	svc, accountRepo, p, sourceAcct := setupManualRefundService(t, WithManualRefunds(manualRepo, ManualRefundReject))
+
+	_, err := svc.RefundPayment(context.Background(), p.ID)
+
+	assert.ErrorIs(t, err, domainErrors.ErrManualRefundRequired)
+	assert.Nil(t, manualRepo.Get(p.ID))
+	assert.Equal(t, int64(50000), accountRepo.GetAccountByID(sourceAcct.ID).Balance)
+}
+
+func TestRefundPayment_ManualRefundProvider_NotConfigured_Rejects(t *testing.T) {
+	svc, _, p, _ := setupManualRefundService(t)
+
+	_, err := svc.RefundPayment(context.Background(), p.ID)
+
+	assert.ErrorIs(t, err, domainErrors.ErrManualRefundRequired)
+}
+
+func setupRefundWindowService(t *testing.T, completedAgo time.Duration) (*PaymentService, *testutil.MockPaymentRepository, *payment.Payment) {
+	t.Helper()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(providers.NewMockProvider("stripe")),
+		WithRefundWindow(30*24*time.Hour, "payments:admin"))
+
+	p := testutil.NewCompletedPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	completedAt := time.Now().Add(-completedAgo)
+	p.CompletedAt = &completedAt
+	paymentRepo.Create(context.Background(), p)
+	return svc, paymentRepo, p
+}
+
+func TestRefundPayment_WithinRefundWindow_Success(t *testing.T) {
+	svc, _, p := setupRefundWindowService(t, 29*24*time.Hour)
+
+	refunded, err := svc.RefundPayment(context.Background(), p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusRefunded, refunded.Status)
+}
+
+func TestRefundPayment_OutsideRefundWindow_Rejected(t *testing.T) {
+	svc, paymentRepo, p := setupRefundWindowService(t, 31*24*time.Hour)
+	ctx := context.WithValue(context.Background(), middleware.ScopesKey, []string{"payments:write"})
+
+	_, err := svc.RefundPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrRefundWindowExpired)
+
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusCompleted, stored.Status)
+}
+
+func TestRefundPayment_OutsideRefundWindow_AdminOverride(t *testing.T) {
+	svc, paymentRepo, p := setupRefundWindowService(t, 90*24*time.Hour)
+	var events []*payment.PaymentEvent
+	paymentRepo.AddEventFunc = func(ctx context.Context, event *payment.PaymentEvent) error {
+		events = append(events, event)
+		return nil
+	}
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "admin1")
+	ctx = context.WithValue(ctx, middleware.ScopesKey, []string{"payments:admin"})
+
+	refunded, err := svc.RefundPayment(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusRefunded, refunded.Status)
+
+	require.Len(t, events, 1)
+	assert.Equal(t, true, events[0].EventData["refund_window_override"])
+	assert.Equal(t, "admin1", events[0].EventData["overridden_by"])
+}
+
 func TestRefundPayment_NonCompletedPayment_Error(t *testing.T) {
 	svc, paymentRepo, _, _, _ := setupPaymentService()
 	ctx := context.Background()
 
 	// Create a pending payment
-	p, err := payment.NewPayment("test-key", payment.ExternalPayment, nil, nil, payment.Amount{ValueCents: 10000, Currency: "USD"})
+	p, err := payment.NewPayment("test-key", payment.ExternalPayment, nil, nil, payment.NewAmount(10000, "USD"))
 	require.NoError(t, err)
 	paymentRepo.Create(ctx, p)
 
@@ -551,7 +1784,7 @@ func TestRefundPayment_InternalTransfer_ReversesAccounts(t *testing.T) {
 	accountRepo.AddAccount(destAcct)
 
 	// Create a completed internal transfer
-	p, err := payment.NewPayment("test-key", payment.InternalTransfer, &sourceAcct.ID, &destAcct.ID, payment.Amount{ValueCents: 10000, Currency: "USD"})
+	p, err := payment.NewPayment("test-key", payment.InternalTransfer, &sourceAcct.ID, &destAcct.ID, payment.NewAmount(10000, "USD"))
 	require.NoError(t, err)
 	p.MarkCompleted(nil)
 	paymentRepo.Create(ctx, p)
@@ -621,3 +1854,65 @@ func (m *mockFailingProvider) RefundPayment(ctx context.Context, req providers.R
 	return nil, errors.New("refund failed")
 }
 
+func (m *mockFailingProvider) GetPaymentStatus(ctx context.Context, req providers.StatusRequest) (*providers.ProviderResult, error) {
+	return nil, errors.New("provider is down")
+}
+
+
+// flakyProvider fails its first charge and records every idempotency key.
+type flakyProvider struct {
+	keys []string
+}
+
+func (f *flakyProvider) Name() string { return string(payment.ProviderStripe) }
+
+func (f *flakyProvider) ProcessPayment(ctx context.Context, req providers.ProcessRequest) (*providers.ProviderResult, error) {
+	f.keys = append(f.keys, req.IdempotencyKey)
+	if len(f.keys) == 1 {
+		return nil, errors.New("connection reset")
+	}
+	return &providers.ProviderResult{TransactionID: "txn_1", Status: providers.ResultSuccess}, nil
+}
+
+func (f *flakyProvider) RefundPayment(ctx context.Context, req providers.RefundRequest) (*providers.ProviderResult, error) {
+	return &providers.ProviderResult{TransactionID: "refund_1", Status: providers.ResultSuccess}, nil
+}
+
+func (f *flakyProvider) GetPaymentStatus(ctx context.Context, req providers.StatusRequest) (*providers.ProviderResult, error) {
+	return &providers.ProviderResult{TransactionID: req.TransactionID, Status: providers.ResultSuccess}, nil
+}
+
+// amountRecordingProvider records the amount of every charge it receives.
+type amountRecordingProvider struct {
+	*providers.MockProvider
+	amounts []int64
+}
+
+func (r *amountRecordingProvider) ProcessPayment(ctx context.Context, req providers.ProcessRequest) (*providers.ProviderResult, error) {
+	r.amounts = append(r.amounts, req.AmountCents)
+	return r.MockProvider.ProcessPayment(ctx, req)
+}
+
+type fakeProcessingStore struct {
+	started   []string
+	completed map[string]string
+}
+
+func newFakeProcessingStore() *fakeProcessingStore {
+	return &fakeProcessingStore{completed: map[string]string{}}
+}
+
+func (f *fakeProcessingStore) Start(ctx context.Context, key string) error {
+	f.started = append(f.started, key)
+	return nil
+}
+
+func (f *fakeProcessingStore) Complete(ctx context.Context, key, providerTxID string) error {
+	f.completed[key] = providerTxID
+	return nil
+}
+
+func (f *fakeProcessingStore) Completed(ctx context.Context, key string) (string, bool, error) {
+	txID, ok := f.completed[key]
+	return txID, ok, nil
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/google/uuid"
+)
+
+// previewPayment projects the outcome of creating p without writing anything:
+// no transaction is opened, and balances are computed from the accounts as
+// they stand. Insufficient funds in the source fail the preview as they would
+// fail the payment.
+func (s *PaymentService) previewPayment(ctx context.Context, p *payment.Payment, pref ProcessingPreference) (*CreatePaymentResponse, error) {
+	preview := &PaymentPreview{Routing: previewRouting(p, pref)}
+
+	if p.SourceAccountID != nil {
+		debit := p.Amount.ValueCents
+		if p.PaymentType == payment.InternalTransfer {
+			debit += p.FeeCents // transfer fees are charged on top
+		}
+		after, err := s.projectBalance(ctx, *p.SourceAccountID, -debit)
+		if err != nil {
+			return nil, err
+		}
+		preview.SourceBalanceAfter = &after
+	}
+	if p.DestinationAccountID != nil {
+		credit := p.CreditedAmount().ValueCents
+		if p.PaymentType == payment.ExternalPayment {
+			credit = p.NetCents()
+		}
+		after, err := s.projectBalance(ctx, *p.DestinationAccountID, credit)
+		if err != nil {
+			return nil, err
+		}
+		preview.DestinationBalanceAfter = &after
+	}
+
+	return &CreatePaymentResponse{
+		Payment: p,
+		IsAsync: preview.Routing != RoutingSync,
+		Outcome: OutcomePreview,
+		Preview: preview,
+	}, nil
+}
+
+// projectBalance returns the balance of id after delta is applied, checking a
+// debit against the available balance.
+func (s *PaymentService) projectBalance(ctx context.Context, id uuid.UUID, delta int64) (int64, error) {
+	acct, err := s.accountRepo.GetByID(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if delta < 0 {
+		if err := acct.CheckAvailable(-delta); err != nil {
+			return 0, err
+		}
+	}
+	return acct.Balance + delta, nil
+}
+
+// previewRouting mirrors the routing in CreatePayment.
+func previewRouting(p *payment.Payment, pref ProcessingPreference) PaymentRouting {
+	switch {
+	case p.ScheduledAt != nil:
+		return RoutingScheduled
+	case p.PaymentType == payment.InternalTransfer && pref != PreferAsync:
+		return RoutingSync
+	default:
+		return RoutingAsync
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/outbox"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePayment_DryRun_ProjectsTransferWithoutPersisting(t *testing.T) {
+	svc, paymentRepo, accountRepo, outboxRepo, _ := setupPaymentService()
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user1")
+
+	var queued bool
+	outboxRepo.InsertFunc = func(ctx context.Context, entry *outbox.Entry) error {
+		queued = true
+		return nil
+	}
+	source := createTestAccount(t, "user1", 100000, account.StatusActive)
+	dest := createTestAccount(t, "user2", 5000, account.StatusActive)
+	accountRepo.AddAccount(source)
+	accountRepo.AddAccount(dest)
+	req := CreatePaymentRequest{
+		IdempotencyKey:       "preview-key",
+		PaymentType:          payment.InternalTransfer,
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               10000,
+		Currency:             "USD",
+		DryRun:               true,
+	}
+
+	resp, err := svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomePreview, resp.Outcome)
+	require.NotNil(t, resp.Preview)
+	assert.Equal(t, RoutingSync, resp.Preview.Routing)
+	assert.Equal(t, int64(90000), *resp.Preview.SourceBalanceAfter)
+	assert.Equal(t, int64(15000), *resp.Preview.DestinationBalanceAfter)
+
+	assert.Equal(t, int64(100000), accountRepo.GetAccountByID(source.ID).Balance)
+	assert.Equal(t, int64(5000), accountRepo.GetAccountByID(dest.ID).Balance)
+	assert.False(t, queued)
+	_, err = paymentRepo.GetByIdempotencyKey(ctx, "user1", "preview-key")
+	assert.ErrorIs(t, err, domainErrors.ErrPaymentNotFound, "a dry run does not consume the idempotency key")
+
+	req.DryRun = false
+	resp, err = svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeCreated, resp.Outcome)
+	assert.Equal(t, int64(90000), accountRepo.GetAccountByID(source.ID).Balance)
+
+	req.DryRun = true
+	resp, err = svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomePreview, resp.Outcome, "a dry run never replays an earlier payment")
+	assert.Equal(t, int64(80000), *resp.Preview.SourceBalanceAfter)
+}
+
+func TestCreatePayment_DryRun_Routing(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	req := scheduledTransfer(t, accountRepo, time.Now().Add(time.Hour))
+	req.DryRun = true
+	resp, err := svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, RoutingScheduled, resp.Preview.Routing)
+	assert.True(t, resp.IsAsync)
+
+	req.ScheduledAt = nil
+	req.Preference = PreferAsync
+	resp, err = svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, RoutingAsync, resp.Preview.Routing)
+}
+
+func TestCreatePayment_DryRun_PlatformFee(t *testing.T) {
+	svc, accountRepo, _, buyer, seller := setupPlatformFeeService(t)
+
+	req := platformFeePayment(buyer, seller, 500)
+	req.DryRun = true
+	resp, err := svc.CreatePayment(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, RoutingAsync, resp.Preview.Routing)
+	assert.Equal(t, int64(500), resp.Payment.FeeCents)
+	assert.Equal(t, int64(5000), *resp.Preview.SourceBalanceAfter)
+	assert.Equal(t, int64(4500), *resp.Preview.DestinationBalanceAfter)
+	assert.Equal(t, int64(10000), accountRepo.GetAccountByID(buyer.ID).Balance)
+}
+
+func TestCreatePayment_DryRun_Validates(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	source := createTestAccount(t, "user1", 1000, account.StatusActive)
+	dest := createTestAccount(t, "user2", 0, account.StatusActive)
+	accountRepo.AddAccount(source)
+	accountRepo.AddAccount(dest)
+
+	_, err := svc.CreatePayment(context.Background(), CreatePaymentRequest{
+		IdempotencyKey:       "too-much",
+		PaymentType:          payment.InternalTransfer,
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               5000,
+		Currency:             "USD",
+		DryRun:               true,
+	})
+	var fundsErr *domainErrors.InsufficientFundsError
+	require.ErrorAs(t, err, &fundsErr)
+	assert.Equal(t, int64(4000), fundsErr.Shortfall())
+
+	_, err = svc.CreatePayment(context.Background(), CreatePaymentRequest{
+		IdempotencyKey:       "bad-currency",
+		PaymentType:          payment.InternalTransfer,
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               500,
+		Currency:             "EUR",
+		DryRun:               true,
+	})
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidCurrency)
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/providers"
+)
+
+// WithProviderTimeout bounds every provider call (charges, refunds and
+// status checks) to d, so a hung provider cannot hold a worker slot. A call
+// that runs out of time fails with ErrProviderTimeout, which the breaker
+// counts and the retry schedule retries like any other provider failure.
+// A non-positive d leaves calls bounded only by the caller's context.
+func WithProviderTimeout(d time.Duration) PaymentServiceOption {
+	return func(s *PaymentService) { s.providerTimeout = d }
+}
+
+// callProvider runs call with ctx bounded by the provider timeout. Running
+// out of that time is reported as ErrProviderTimeout; a deadline or cancel
+// on ctx itself is returned as is.
+func (s *PaymentService) callProvider(ctx context.Context, call func(context.Context) (*providers.ProviderResult, error)) (*providers.ProviderResult, error) {
+	if s.providerTimeout <= 0 {
+		return call(ctx)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, s.providerTimeout)
+	defer cancel()
+	result, err := call(callCtx)
+	if err != nil && ctx.Err() == nil && callCtx.Err() == context.DeadlineExceeded {
+		return result, fmt.Errorf("%w: no answer within %s", domainErrors.ErrProviderTimeout, s.providerTimeout)
+	}
+	return result, err
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSlowProvider(t *testing.T) (*PaymentService, *testutil.MockPaymentRepository, *testutil.MockAccountRepository, *account.Account) {
+	t.Helper()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	provider := providers.NewMockProvider("stripe", providers.WithLatency(time.Minute))
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(provider), WithProviderTimeout(20*time.Millisecond))
+	source := createTestAccount(t, "user1", 50000, account.StatusActive)
+	accountRepo.AddAccount(source)
+	return svc, paymentRepo, accountRepo, source
+}
+
+func TestProcessPayment_ProviderTimeout_AbortsAndFails(t *testing.T) {
+	svc, paymentRepo, accountRepo, source := setupSlowProvider(t)
+	ctx := context.Background()
+	p, err := payment.NewPayment("slow-key", payment.ExternalPayment, &source.ID, nil, payment.NewAmount(10000, "USD"))
+	require.NoError(t, err)
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	start := time.Now()
+	err = svc.ProcessPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrPaymentFailed)
+	assert.Less(t, time.Since(start), 5*time.Second, "the provider call is abandoned at the timeout")
+
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusFailed, stored.Status)
+	require.NotNil(t, stored.LastError)
+	assert.Contains(t, *stored.LastError, domainErrors.ErrProviderTimeout.Error())
+	assert.NotNil(t, stored.NextRetryAt, "a timeout is retried")
+	assert.Zero(t, accountRepo.GetAccountByID(source.ID).HeldBalance, "the hold is released")
+}
+
+func TestRefundPayment_ProviderTimeout(t *testing.T) {
+	svc, paymentRepo, accountRepo, source := setupSlowProvider(t)
+	ctx := context.Background()
+	p := testutil.NewCompletedPayment(payment.ExternalPayment, &source.ID, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	_, err := svc.RefundPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrProviderTimeout)
+	assert.Equal(t, int64(50000), accountRepo.GetAccountByID(source.ID).Balance)
+}
+
+func TestCallProvider_CallerDeadlineIsNotATimeout(t *testing.T) {
+	svc := NewPaymentService(nil, nil, nil, nil, providers.NewFactory(), WithProviderTimeout(time.Minute))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := svc.callProvider(ctx, func(ctx context.Context) (*providers.ProviderResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, domainErrors.ErrProviderTimeout)
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// LockChecker reports whether another process holds a distributed lock.
+type LockChecker interface {
+	IsLocked(ctx context.Context, key string) (bool, error)
+}
+
+// PaymentLockKey is the lock a worker holds while processing a payment.
+func PaymentLockKey(id uuid.UUID) string {
+	return "payment:" + id.String()
+}
+
+// WithReconciliation sets which processing payments reconciliation may
+// check: those that entered processing at least minAge ago and that no
+// worker currently holds locked. locks may be nil to skip the lock check.
+func WithReconciliation(minAge time.Duration, locks LockChecker) PaymentServiceOption {
+	return func(s *PaymentService) {
+		s.reconcileMinAge = minAge
+		s.locks = locks
+	}
+}
+
+// ReconciliationCandidates returns up to limit processing payments that are
+// safe to reconcile against their provider, oldest update first. Payments a
+// worker may still be charging are left alone: they are either younger than
+// the configured minimum age or locked by the worker processing them.
+func (s *PaymentService) ReconciliationCandidates(ctx context.Context, limit int) ([]*payment.Payment, error) {
+	status := payment.StatusProcessing
+	cutoff := time.Now().Add(-s.reconcileMinAge)
+	payments, err := s.paymentRepo.List(ctx, payment.ListFilter{
+		Status:        &status,
+		UpdatedBefore: &cutoff,
+		Limit:         limit,
+		SortBy:        "updated_at",
+		SortOrder:     "asc",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.locks == nil {
+		return payments, nil
+	}
+
+	candidates := make([]*payment.Payment, 0, len(payments))
+	for _, p := range payments {
+		locked, err := s.locks.IsLocked(ctx, PaymentLockKey(p.ID))
+		if err != nil {
+			return nil, err
+		}
+		if !locked {
+			candidates = append(candidates, p)
+		}
+	}
+	return candidates, nil
+}
+
+// ReconcileOutcome is what reconciling one payment did to it.
+type ReconcileOutcome string
+
+const (
+	// ReconcileUnchanged: the provider agrees the charge is still in flight,
+	// or the payment left processing before it was reconciled.
+	ReconcileUnchanged ReconcileOutcome = "unchanged"
+	// ReconcileCompleted: the provider settled the charge; the payment is
+	// completed and its hold captured.
+	ReconcileCompleted ReconcileOutcome = "completed"
+	// ReconcileFailed: the provider failed the charge or never made it; the
+	// payment is failed and its hold released.
+	ReconcileFailed ReconcileOutcome = "failed"
+	// ReconcileFlagged: the provider reported a status with no payment
+	// equivalent; the payment is held for review with its funds reserved.
+	ReconcileFlagged ReconcileOutcome = "flagged"
+)
+
+// ReconciliationReport summarizes one reconciliation pass.
+type ReconciliationReport struct {
+	Checked    int
+	Mismatches int
+	Outcomes   map[ReconcileOutcome]int
+	Errors     int
+}
+
+// ReconciliationUseCase settles payments stuck in processing, e.g. after a
+// missed provider callback or a worker crash, by asking their provider what
+// became of the charge.
+type ReconciliationUseCase struct {
+	payments *PaymentService
+	metrics  *observability.Metrics
+}
+
+// NewReconciliationUseCase reconciles the candidates of payments, which
+// also supplies the repositories and providers. metrics may be nil.
+func NewReconciliationUseCase(payments *PaymentService, metrics *observability.Metrics) *ReconciliationUseCase {
+	return &ReconciliationUseCase{payments: payments, metrics: metrics}
+}
+
+// Run reconciles up to limit ReconciliationCandidates. A payment that cannot
+// be reconciled, e.g. because its provider is unreachable, is logged and
+// counted in Errors and left for the next pass.
+func (u *ReconciliationUseCase) Run(ctx context.Context, limit int) (ReconciliationReport, error) {
+	report := ReconciliationReport{Outcomes: make(map[ReconcileOutcome]int)}
+	candidates, err := u.payments.ReconciliationCandidates(ctx, limit)
+	if err != nil {
+		return report, err
+	}
+	for _, p := range candidates {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		report.Checked++
+		outcome, err := u.Reconcile(ctx, p.ID)
+		if err != nil {
+			report.Errors++
+			log.Error().Err(err).Str("payment_id", p.ID.String()).Msg("failed to reconcile payment")
+			continue
+		}
+		report.Outcomes[outcome]++
+		if outcome != ReconcileUnchanged {
+			report.Mismatches++
+			if u.metrics != nil {
+				u.metrics.ReconciliationMismatches.WithLabelValues(string(outcome)).Inc()
+			}
+		}
+	}
+	return report, nil
+}
+
+// Reconcile brings one processing payment in line with its provider. The
+// charge is looked up by the payment's provider transaction ID, or the one
+// recorded for its current processing attempt, and otherwise by the payment
+// and its charge idempotency key. A provider status that maps to no payment
+// status flags the payment for review. Every change records a
+// payment.reconciled event with the status before and after.
+func (u *ReconciliationUseCase) Reconcile(ctx context.Context, paymentID uuid.UUID) (ReconcileOutcome, error) {
+	s := u.payments
+	p, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		return "", domainErrors.ErrPaymentNotFound
+	}
+	if p.Status != payment.StatusProcessing || p.PaymentType == payment.InternalTransfer || p.Provider == nil {
+		// Internal transfers settle in one database transaction and have no
+		// provider to ask.
+		return ReconcileUnchanged, nil
+	}
+
+	txID, err := u.providerTxID(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	provider, _, limiter, err := s.providerFactory.Get(*p.Provider)
+	if err != nil {
+		return "", err
+	}
+	if err := limiter.Wait(ctx); err != nil {
+		return "", fmt.Errorf("provider rate limit: %w", err)
+	}
+	result, err := s.callProvider(ctx, func(ctx context.Context) (*providers.ProviderResult, error) {
+		return provider.GetPaymentStatus(ctx, providers.StatusRequest{
+			PaymentID:      p.ID.String(),
+			IdempotencyKey: p.IdempotencyKeyFor(payment.ScopeCharge),
+			TransactionID:  txID,
+		})
+	})
+	switch {
+	case errors.Is(err, domainErrors.ErrProviderTransactionNotFound):
+		return u.fail(ctx, p, txID, "not_found", "reconciliation: provider has no record of the charge")
+	case err != nil:
+		return "", fmt.Errorf("provider status: %w", err)
+	case result == nil:
+		return "", fmt.Errorf("provider status: %s returned no result", *p.Provider)
+	}
+	if result.TransactionID != "" {
+		txID = result.TransactionID
+	}
+
+	status, ok := result.PaymentStatus()
+	switch {
+	case !ok:
+		return u.flag(ctx, p, result, fmt.Sprintf("reconciliation: unrecognized provider status %q", result.Status))
+	case status == payment.StatusCompleted:
+		return u.complete(ctx, p, txID)
+	case status == payment.StatusFailed:
+		reason := "reconciliation: provider reports the charge failed"
+		if result.ErrorMessage != "" {
+			reason += ": " + result.ErrorMessage
+		}
+		return u.fail(ctx, p, txID, result.Status, reason)
+	default:
+		log.Info().Str("payment_id", p.ID.String()).Str("provider_tx_id", txID).Str("provider_status", result.Status).
+			Msg("payment still in flight at provider")
+		return ReconcileUnchanged, nil
+	}
+}
+
+// providerTxID returns the provider transaction ID known for p's charge, or
+// "" if none was recorded.
+func (u *ReconciliationUseCase) providerTxID(ctx context.Context, p *payment.Payment) (string, error) {
+	if p.ProviderTransactionID != nil {
+		return *p.ProviderTransactionID, nil
+	}
+	if u.payments.processing == nil {
+		return "", nil
+	}
+	txID, _, err := u.payments.processing.Completed(ctx, processingKey(p))
+	if err != nil {
+		return "", fmt.Errorf("check processing key: %w", err)
+	}
+	return txID, nil
+}
+
+func (u *ReconciliationUseCase) complete(ctx context.Context, p *payment.Payment, txID string) (ReconcileOutcome, error) {
+	s := u.payments
+	if err := s.completeCharged(ctx, p, txID); err != nil {
+		return "", err
+	}
+	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCompleted),
+		EventData: map[string]any{
+			"provider_tx_id": txID,
+			"amount_cents":   p.Amount.ValueCents,
+			"reconciled":     true,
+		},
+	})
+	u.recordReconciled(ctx, p, txID, providers.ResultSuccess)
+	return ReconcileCompleted, nil
+}
+
+// fail fails p and releases its funds hold. A payment the worker left before
+// holding its funds has none, and no balance changes.
+func (u *ReconciliationUseCase) fail(ctx context.Context, p *payment.Payment, txID, providerStatus, reason string) (ReconcileOutcome, error) {
+	s := u.payments
+	err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		if _, err := s.releaseActiveHold(txCtx, p.ID); err != nil {
+			return err
+		}
+		if err := p.MarkFailed(reason); err != nil {
+			return err
+		}
+		return s.paymentRepo.Update(txCtx, p)
+	})
+	if err != nil {
+		return "", err
+	}
+	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentFailed),
+		EventData: map[string]any{"error": reason, "reconciled": true},
+	})
+	u.recordReconciled(ctx, p, txID, providerStatus)
+	return ReconcileFailed, nil
+}
+
+func (u *ReconciliationUseCase) flag(ctx context.Context, p *payment.Payment, result *providers.ProviderResult, reason string) (ReconcileOutcome, error) {
+	if err := u.payments.holdForReview(ctx, p, result, reason); err != nil {
+		return "", err
+	}
+	u.recordReconciled(ctx, p, result.TransactionID, result.Status)
+	return ReconcileFlagged, nil
+}
+
+// recordReconciled adds the payment.reconciled event for p, which has just
+// left processing.
+func (u *ReconciliationUseCase) recordReconciled(ctx context.Context, p *payment.Payment, txID, providerStatus string) {
+	data := map[string]any{
+		"from_status": string(payment.StatusProcessing),
+		"to_status":   string(p.Status),
+	}
+	if txID != "" {
+		data["provider_tx_id"] = txID
+	}
+	if providerStatus != "" {
+		data["provider_status"] = providerStatus
+	}
+	u.payments.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentReconciled),
+		EventData: data,
+	})
+	log.Warn().Str("payment_id", p.ID.String()).Str("status", string(p.Status)).Msg("payment reconciled with provider")
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/infrastructure/observability"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLocks struct {
+	held map[string]bool
+	err  error
+}
+
+func (f *fakeLocks) IsLocked(ctx context.Context, key string) (bool, error) {
+	return f.held[key], f.err
+}
+
+func TestReconciliationCandidates_FiltersByAge(t *testing.T) {
+	svc, paymentRepo, _, _, _ := setupPaymentService()
+	WithReconciliation(5*time.Minute, nil)(svc)
+
+	var got payment.ListFilter
+	paymentRepo.ListFunc = func(ctx context.Context, filter payment.ListFilter) ([]*payment.Payment, error) {
+		got = filter
+		return nil, nil
+	}
+	_, err := svc.ReconciliationCandidates(context.Background(), 50)
+	require.NoError(t, err)
+
+	require.NotNil(t, got.Status)
+	assert.Equal(t, payment.StatusProcessing, *got.Status)
+	require.NotNil(t, got.UpdatedBefore)
+	assert.WithinDuration(t, time.Now().Add(-5*time.Minute), *got.UpdatedBefore, time.Second)
+	assert.Equal(t, 50, got.Limit)
+	assert.Equal(t, "updated_at", got.SortBy)
+}
+
+func TestReconciliationCandidates_SkipsLockedPayments(t *testing.T) {
+	svc, paymentRepo, _, _, _ := setupPaymentService()
+	locked := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 5000, "USD")
+	idle := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 5000, "USD")
+	paymentRepo.ListFunc = func(ctx context.Context, filter payment.ListFilter) ([]*payment.Payment, error) {
+		return []*payment.Payment{locked, idle}, nil
+	}
+	locks := &fakeLocks{held: map[string]bool{PaymentLockKey(locked.ID): true}}
+	WithReconciliation(time.Minute, locks)(svc)
+
+	candidates, err := svc.ReconciliationCandidates(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, idle.ID, candidates[0].ID)
+
+	locks.err = errors.New("redis down")
+	_, err = svc.ReconciliationCandidates(context.Background(), 10)
+	assert.Error(t, err, "an unknown lock state is not treated as unlocked")
+}
+
+// setupReconcile returns a 100.00 USD external payment stuck in processing
+// with its funds held on a 1000.00 account, and a reconciliation use case
+// whose provider reports status for every charge.
+func setupReconcile(t *testing.T, status *reviewProvider, txID string) (*ReconciliationUseCase, *testutil.MockPaymentRepository, *testutil.MockAccountRepository, *payment.Payment) {
+	t.Helper()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(status))
+	ctx := context.Background()
+
+	source := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(source)
+	p := testutil.NewTestPayment(payment.ExternalPayment, &source.ID, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, p.MarkProcessing())
+	if txID != "" {
+		p.ProviderTransactionID = &txID
+	}
+	require.NoError(t, paymentRepo.Create(ctx, p))
+	require.NoError(t, svc.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		return svc.holdFunds(txCtx, source.ID, p.ID, p.Amount.ValueCents)
+	}))
+	return NewReconciliationUseCase(svc, nil), paymentRepo, accountRepo, p
+}
+
+func lastEvent(t *testing.T, repo *testutil.MockPaymentRepository, id uuid.UUID) *payment.PaymentEvent {
+	t.Helper()
+	events, err := repo.GetEvents(context.Background(), id)
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	return events[len(events)-1]
+}
+
+func TestReconcile_CompletesSettledCharge(t *testing.T) {
+	uc, paymentRepo, accountRepo, p := setupReconcile(t, &reviewProvider{
+		result: &providers.ProviderResult{TransactionID: "txn_1", Status: providers.ResultSuccess},
+	}, "txn_1")
+
+	outcome, err := uc.Reconcile(context.Background(), p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ReconcileCompleted, outcome)
+
+	got, _ := paymentRepo.GetByID(context.Background(), p.ID)
+	assert.Equal(t, payment.StatusCompleted, got.Status)
+	source := accountRepo.GetAccountByID(*p.SourceAccountID)
+	assert.Equal(t, int64(90000), source.Balance)
+	assert.Zero(t, source.HeldBalance, "the hold is captured")
+
+	ev := lastEvent(t, paymentRepo, p.ID)
+	assert.Equal(t, string(payment.EventPaymentReconciled), ev.EventType)
+	assert.Equal(t, "processing", ev.EventData["from_status"])
+	assert.Equal(t, "completed", ev.EventData["to_status"])
+	assert.Equal(t, "txn_1", ev.EventData["provider_tx_id"])
+}
+
+func TestReconcile_FailsRejectedOrUnknownCharge(t *testing.T) {
+	for name, provider := range map[string]*reviewProvider{
+		"failed":    {result: &providers.ProviderResult{TransactionID: "txn_1", Status: providers.ResultFailed, ErrorMessage: "card declined"}},
+		"not found": {err: domainErrors.ErrProviderTransactionNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			uc, paymentRepo, accountRepo, p := setupReconcile(t, provider, "txn_1")
+
+			outcome, err := uc.Reconcile(context.Background(), p.ID)
+			require.NoError(t, err)
+			assert.Equal(t, ReconcileFailed, outcome)
+
+			got, _ := paymentRepo.GetByID(context.Background(), p.ID)
+			assert.Equal(t, payment.StatusFailed, got.Status)
+			assert.Contains(t, *got.LastError, "reconciliation")
+			source := accountRepo.GetAccountByID(*p.SourceAccountID)
+			assert.Equal(t, int64(100000), source.Balance)
+			assert.Zero(t, source.HeldBalance, "the hold is released")
+			assert.Equal(t, "failed", lastEvent(t, paymentRepo, p.ID).EventData["to_status"])
+		})
+	}
+}
+
+func TestReconcile_FailsChargeNeverHeld(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(&reviewProvider{err: domainErrors.ErrProviderTransactionNotFound}))
+	ctx := context.Background()
+
+	// The worker crashed after marking the payment processing but before
+	// holding its funds.
+	source := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(source)
+	p := testutil.NewTestPayment(payment.ExternalPayment, &source.ID, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, p.MarkProcessing())
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	outcome, err := NewReconciliationUseCase(svc, nil).Reconcile(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ReconcileFailed, outcome)
+
+	got, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusFailed, got.Status)
+	stored := accountRepo.GetAccountByID(source.ID)
+	assert.Equal(t, int64(100000), stored.Balance, "nothing was reserved, so nothing is credited")
+	assert.Zero(t, stored.HeldBalance)
+	txns, _ := accountRepo.GetTransactions(ctx, source.ID, 10, 0)
+	assert.Empty(t, txns)
+}
+
+func TestReconcile_LeavesInFlightChargeAlone(t *testing.T) {
+	uc, paymentRepo, _, p := setupReconcile(t, &reviewProvider{
+		result: &providers.ProviderResult{TransactionID: "txn_1", Status: providers.ResultPending},
+	}, "txn_1")
+
+	outcome, err := uc.Reconcile(context.Background(), p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ReconcileUnchanged, outcome)
+	got, _ := paymentRepo.GetByID(context.Background(), p.ID)
+	assert.Equal(t, payment.StatusProcessing, got.Status)
+
+	_, err = (&ReconciliationUseCase{payments: uc.payments}).Reconcile(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, domainErrors.ErrPaymentNotFound)
+}
+
+func TestReconcile_FlagsUnrecognizedProviderStatus(t *testing.T) {
+	uc, paymentRepo, accountRepo, p := setupReconcile(t, &reviewProvider{
+		result: &providers.ProviderResult{TransactionID: "txn_9", Status: "under_investigation"},
+	}, "")
+
+	outcome, err := uc.Reconcile(context.Background(), p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ReconcileFlagged, outcome)
+
+	got, _ := paymentRepo.GetByID(context.Background(), p.ID)
+	assert.Equal(t, payment.StatusNeedsReview, got.Status)
+	assert.Equal(t, "txn_9", *got.ProviderTransactionID, "the charge found by payment is recorded")
+	assert.Equal(t, int64(10000), accountRepo.GetAccountByID(*p.SourceAccountID).HeldBalance, "funds stay held for the reviewer")
+	ev := lastEvent(t, paymentRepo, p.ID)
+	assert.Equal(t, "needs_review", ev.EventData["to_status"])
+	assert.Equal(t, "under_investigation", ev.EventData["provider_status"])
+}
+
+func TestReconcile_LooksUpChargeByPayment(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	provider := providers.NewMockProvider("stripe", providers.WithLatency(0))
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(provider))
+	ctx := context.Background()
+
+	charged := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 5000, "USD")
+	lost := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 5000, "USD")
+	for _, p := range []*payment.Payment{charged, lost} {
+		p.SetProvider(payment.ProviderStripe)
+		require.NoError(t, p.MarkProcessing())
+		require.NoError(t, paymentRepo.Create(ctx, p))
+	}
+	res, err := provider.ProcessPayment(ctx, providers.ProcessRequest{PaymentID: charged.ID.String(), AmountCents: 5000, Currency: "USD"})
+	require.NoError(t, err)
+
+	uc := NewReconciliationUseCase(svc, nil)
+	outcome, err := uc.Reconcile(ctx, charged.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ReconcileCompleted, outcome)
+	assert.Equal(t, res.TransactionID, *charged.ProviderTransactionID)
+
+	outcome, err = uc.Reconcile(ctx, lost.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ReconcileFailed, outcome, "a charge the provider never saw fails")
+}
+
+func TestReconciliationRun_CountsMismatches(t *testing.T) {
+	uc, paymentRepo, _, p := setupReconcile(t, &reviewProvider{
+		result: &providers.ProviderResult{TransactionID: "txn_1", Status: providers.ResultSuccess},
+	}, "txn_1")
+	metrics := observability.NewMetrics("test", prometheus.NewRegistry())
+	uc.metrics = metrics
+	paymentRepo.ListFunc = func(ctx context.Context, filter payment.ListFilter) ([]*payment.Payment, error) {
+		return []*payment.Payment{p, {ID: uuid.New()}}, nil
+	}
+
+	report, err := uc.Run(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Checked)
+	assert.Equal(t, 1, report.Mismatches)
+	assert.Equal(t, 1, report.Outcomes[ReconcileCompleted])
+	assert.Equal(t, 1, report.Errors, "an unknown payment is reported, not fatal")
+
+	var m dto.Metric
+	require.NoError(t, metrics.ReconciliationMismatches.WithLabelValues("completed").Write(&m))
+	assert.Equal(t, 1.0, m.GetCounter().GetValue())
+}
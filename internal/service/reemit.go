@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/outbox"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ReemitPaymentEvents re-inserts an outbox entry for every stored event of a
+// payment, for recovering a downstream consumer that missed them. Each entry
+// is marked replayed and keyed by its event ID (outbox.DedupKeyKey), so
+// consumers that already handled the event can ignore the copy. The worker
+// treats re-emitted events like any other: processing a payment that is
+// already settled is a no-op.
+func (s *PaymentService) ReemitPaymentEvents(ctx context.Context, paymentID uuid.UUID) (*ReemitEventsResponse, error) {
+	p, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, domainErrors.ErrPaymentNotFound
+	}
+	events, err := s.paymentRepo.GetEvents(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*outbox.Entry, 0, len(events))
+	err = s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		for _, e := range events {
+			data := maps.Clone(e.EventData)
+			if data == nil {
+				data = map[string]any{}
+			}
+			data["payment_id"] = p.ID.String()
+			data["occurred_at"] = e.CreatedAt.Format(time.RFC3339Nano)
+			data[outbox.DedupKeyKey] = e.ID.String()
+			data[outbox.ReplayedKey] = true
+
+			entry := outbox.NewEntry("payment", p.ID, e.EventType, data)
+			if err := s.outboxRepo.Insert(txCtx, entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("payment_id", p.ID.String()).Int("events", len(entries)).Msg("payment events re-emitted")
+	return &ReemitEventsResponse{PaymentID: p.ID, Entries: entries}, nil
+}
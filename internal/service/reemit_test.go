@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/outbox"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReemitPaymentEvents_ReusesDedupKeys(t *testing.T) {
+	svc, _, accountRepo, outboxRepo, _ := setupPaymentService()
+	ctx := context.Background()
+	sourceAcct := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(sourceAcct)
+
+	var inserted []*outbox.Entry
+	outboxRepo.InsertFunc = func(ctx context.Context, entry *outbox.Entry) error {
+		inserted = append(inserted, entry)
+		return nil
+	}
+
+	provider := payment.ProviderStripe
+	created, err := svc.CreatePayment(ctx, CreatePaymentRequest{
+		IdempotencyKey:  "reemit-1",
+		PaymentType:     payment.ExternalPayment,
+		SourceAccountID: &sourceAcct.ID,
+		Amount:          10000,
+		Currency:        "USD",
+		Provider:        &provider,
+	})
+	require.NoError(t, err)
+	require.Len(t, inserted, 1)
+	original := inserted[0]
+
+	resp, err := svc.ReemitPaymentEvents(ctx, created.Payment.ID)
+	require.NoError(t, err)
+	require.Len(t, resp.Entries, 1)
+
+	replay := resp.Entries[0]
+	assert.NotEqual(t, original.ID, replay.ID)
+	assert.Equal(t, original.EventType, replay.EventType)
+	assert.Equal(t, original.Payload[outbox.DedupKeyKey], replay.Payload[outbox.DedupKeyKey])
+	assert.Equal(t, true, replay.Payload[outbox.ReplayedKey])
+	assert.Equal(t, outbox.SchemaVersion, replay.Payload[outbox.SchemaVersionKey])
+	assert.Equal(t, created.Payment.ID.String(), replay.Payload["payment_id"])
+	assert.Len(t, inserted, 2)
+}
+
+func TestReemitPaymentEvents_PaymentNotFound(t *testing.T) {
+	svc, _, _, _, _ := setupPaymentService()
+
+	_, err := svc.ReemitPaymentEvents(context.Background(), uuid.New())
+
+	assert.ErrorIs(t, err, domainErrors.ErrPaymentNotFound)
+}
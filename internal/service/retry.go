@@ -0,0 +1,50 @@
+package service
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// RetryBackoff spaces out the retries of failed payments so a struggling
+// provider is not hammered. The delay before retry n is Base doubled n-1
+// times and capped at Max; up to half of it is random jitter, so payments
+// that failed together do not retry together.
+type RetryBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// DefaultRetryBackoff returns the backoff used unless WithRetryBackoff sets
+// another.
+func DefaultRetryBackoff() RetryBackoff {
+	return RetryBackoff{Base: time.Second, Max: 5 * time.Minute}
+}
+
+// WithRetryBackoff sets the delay between retries of a failed payment. A
+// non-positive base keeps the default; a limit below base is raised to base.
+func WithRetryBackoff(base, limit time.Duration) PaymentServiceOption {
+	return func(s *PaymentService) {
+		if base > 0 {
+			s.retryBackoff = RetryBackoff{Base: base, Max: max(base, limit)}
+		}
+	}
+}
+
+// Delay returns the wait before retry attempt (1 for the first retry).
+func (b RetryBackoff) Delay(attempt int) time.Duration {
+	ceiling := b.ceiling(attempt)
+	if ceiling <= 0 {
+		return 0
+	}
+	half := ceiling / 2
+	return ceiling - half + rand.N(half+1)
+}
+
+// ceiling is the delay before attempt without jitter.
+func (b RetryBackoff) ceiling(attempt int) time.Duration {
+	d := b.Base
+	for i := 1; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	return min(d, b.Max)
+}
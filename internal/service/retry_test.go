@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryBackoff_DelayGrowsAndIsBounded(t *testing.T) {
+	b := RetryBackoff{Base: time.Second, Max: 10 * time.Second}
+	ceilings := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+
+	for i := 0; i < 100; i++ {
+		prev := time.Duration(0)
+		for n, ceiling := range ceilings {
+			d := b.Delay(n + 1)
+			assert.GreaterOrEqual(t, d, ceiling/2, "attempt %d", n+1)
+			assert.LessOrEqual(t, d, ceiling, "attempt %d", n+1)
+			if ceiling < b.Max {
+				assert.GreaterOrEqual(t, d, prev, "attempt %d waits no less than the one before", n+1)
+			}
+			prev = d
+		}
+	}
+	assert.LessOrEqual(t, b.Delay(1000), b.Max, "doubling stops at the cap")
+}
+
+func TestWithRetryBackoff(t *testing.T) {
+	svc := NewPaymentService(nil, nil, nil, nil, nil, WithRetryBackoff(0, time.Hour))
+	assert.Equal(t, DefaultRetryBackoff(), svc.retryBackoff)
+
+	svc = NewPaymentService(nil, nil, nil, nil, nil, WithRetryBackoff(time.Minute, time.Second))
+	assert.Equal(t, RetryBackoff{Base: time.Minute, Max: time.Minute}, svc.retryBackoff)
+}
+
+func TestProcessPayment_Failure_SchedulesRetry(t *testing.T) {
+	paymentRepo := testutil.NewMockPaymentRepository()
+	provider := &flakyProvider{}
+	svc := NewPaymentService(paymentRepo, testutil.NewMockAccountRepository(), &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(provider),
+		WithRetryBackoff(time.Minute, time.Hour))
+	ctx := context.Background()
+
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	before := time.Now()
+	assert.ErrorIs(t, svc.ProcessPayment(ctx, p.ID), domainErrors.ErrPaymentFailed)
+	failed, _ := paymentRepo.GetByID(ctx, p.ID)
+	require.NotNil(t, failed.NextRetryAt)
+	assert.WithinRange(t, *failed.NextRetryAt, before.Add(30*time.Second-time.Microsecond), time.Now().Add(time.Minute))
+
+	err := svc.ProcessPayment(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrRetryNotDue)
+	assert.Len(t, provider.keys, 1, "the provider is not called before the backoff elapses")
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusFailed, stored.Status)
+	assert.Equal(t, 0, stored.RetryCount)
+
+	stored.ScheduleRetry(time.Now().Add(-time.Second))
+	require.NoError(t, paymentRepo.Update(ctx, stored))
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+	completed, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusCompleted, completed.Status)
+	assert.Nil(t, completed.NextRetryAt)
+}
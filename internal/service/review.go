@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// reviewReason reports whether a provider outcome is ambiguous: the provider
+// flagged the charge for review (e.g. suspected fraud) or left it pending
+// instead of settling it either way.
+func reviewReason(result *providers.ProviderResult, err error) (string, bool) {
+	if errors.Is(err, domainErrors.ErrReviewRequired) {
+		return err.Error(), true
+	}
+	if err == nil && result != nil && result.Status == providers.ResultPending {
+		return "provider returned a pending result", true
+	}
+	return "", false
+}
+
+// holdForReview moves p to needs_review. Its funds hold stays in place until
+// a reviewer approves or rejects the payment.
+func (s *PaymentService) holdForReview(ctx context.Context, p *payment.Payment, result *providers.ProviderResult, reason string) error {
+	var txID *string
+	if result != nil && result.TransactionID != "" {
+		txID = &result.TransactionID
+	}
+	if err := p.MarkNeedsReview(reason, txID); err != nil {
+		return err
+	}
+	if err := s.paymentRepo.Update(ctx, p); err != nil {
+		return err
+	}
+
+	eventData := map[string]any{"reason": reason}
+	if txID != nil {
+		eventData["provider_tx_id"] = *txID
+	}
+	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentNeedsReview),
+		EventData: eventData,
+	})
+	log.Warn().Str("payment_id", p.ID.String()).Str("reason", reason).Msg("payment held for review")
+	return nil
+}
+
+// ApproveReview completes a payment held for review, capturing its funds
+// hold as the charge.
+func (s *PaymentService) ApproveReview(ctx context.Context, paymentID uuid.UUID) (*payment.Payment, error) {
+	p, err := s.reviewedPayment(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.MarkCompleted(nil); err != nil {
+		return nil, err
+	}
+	if err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.settleCharge(txCtx, p); err != nil {
+			return err
+		}
+		return s.paymentRepo.Update(txCtx, p)
+	}); err != nil {
+		return nil, err
+	}
+
+	reviewer, _ := middleware.GetUserID(ctx)
+	eventData := map[string]any{
+		"amount_cents": p.Amount.ValueCents,
+		"review":       "approved",
+		"reviewed_by":  reviewer,
+	}
+	if p.ProviderTransactionID != nil {
+		eventData["provider_tx_id"] = *p.ProviderTransactionID
+	}
+	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCompleted),
+		EventData: eventData,
+	})
+	return p, nil
+}
+
+// RejectReview ends a payment held for review in the terminal rejected
+// status and releases its funds hold, if it has one; no balance is credited.
+// Any charge the provider did accept must be reversed with the provider
+// separately.
+func (s *PaymentService) RejectReview(ctx context.Context, paymentID uuid.UUID, reason string) (*payment.Payment, error) {
+	p, err := s.reviewedPayment(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		// Without an active hold nothing was reserved, or it was already
+		// released: there is nothing to return.
+		if _, err := s.releaseActiveHold(txCtx, p.ID); err != nil {
+			return err
+		}
+		if err := p.MarkRejected(reason); err != nil {
+			return err
+		}
+		return s.paymentRepo.Update(txCtx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reviewer, _ := middleware.GetUserID(ctx)
+	s.paymentRepo.AddEvent(ctx, &payment.PaymentEvent{
+		ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentFailed),
+		EventData: map[string]any{
+			"error":       reason,
+			"review":      "rejected",
+			"reviewed_by": reviewer,
+		},
+	})
+	return p, nil
+}
+
+func (s *PaymentService) reviewedPayment(ctx context.Context, paymentID uuid.UUID) (*payment.Payment, error) {
+	p, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, domainErrors.ErrPaymentNotFound
+	}
+	if p.Status != payment.StatusNeedsReview {
+		return nil, domainErrors.NewDomainError(
+			"invalid_review",
+			fmt.Sprintf("payment in status %s is not awaiting review", p.Status),
+			domainErrors.ErrInvalidStateTransition,
+		)
+	}
+	return p, nil
+}
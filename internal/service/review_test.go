@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/providers"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reviewProvider answers every charge with result and err, counting them.
+type reviewProvider struct {
+	result  *providers.ProviderResult
+	err     error
+	charges int
+}
+
+func (r *reviewProvider) Name() string { return string(payment.ProviderStripe) }
+
+func (r *reviewProvider) ProcessPayment(ctx context.Context, req providers.ProcessRequest) (*providers.ProviderResult, error) {
+	r.charges++
+	return r.result, r.err
+}
+
+func (r *reviewProvider) RefundPayment(ctx context.Context, req providers.RefundRequest) (*providers.ProviderResult, error) {
+	return &providers.ProviderResult{TransactionID: "refund_1", Status: providers.ResultSuccess}, nil
+}
+
+func (r *reviewProvider) GetPaymentStatus(ctx context.Context, req providers.StatusRequest) (*providers.ProviderResult, error) {
+	return r.result, r.err
+}
+
+// setupReview processes a 100.00 USD external payment from a 1000.00 account
+// through provider.
+func setupReview(t *testing.T, provider providers.Provider) (*PaymentService, *testutil.MockPaymentRepository, *testutil.MockAccountRepository, *payment.Payment) {
+	t.Helper()
+	paymentRepo := testutil.NewMockPaymentRepository()
+	accountRepo := testutil.NewMockAccountRepository()
+	svc := NewPaymentService(paymentRepo, accountRepo, &testutil.MockOutboxRepository{},
+		testutil.NewMockTransactionManager(), providers.NewFactory(provider))
+	ctx := context.Background()
+
+	source := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(source)
+	p := testutil.NewTestPayment(payment.ExternalPayment, &source.ID, nil, 10000, "USD")
+	p.SetProvider(payment.ProviderStripe)
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+	return svc, paymentRepo, accountRepo, p
+}
+
+func TestProcessPayment_PendingResultNeedsReview(t *testing.T) {
+	_, paymentRepo, accountRepo, p := setupReview(t, &reviewProvider{
+		result: &providers.ProviderResult{TransactionID: "txn_pending", Status: providers.ResultPending},
+	})
+
+	held, _ := paymentRepo.GetByID(context.Background(), p.ID)
+	assert.Equal(t, payment.StatusNeedsReview, held.Status)
+	require.NotNil(t, held.ProviderTransactionID)
+	assert.Equal(t, "txn_pending", *held.ProviderTransactionID)
+	source := accountRepo.GetAccountByID(*p.SourceAccountID)
+	assert.Equal(t, int64(100000), source.Balance)
+	assert.Equal(t, int64(90000), source.AvailableBalance(), "funds stay held")
+}
+
+func TestProcessPayment_ReviewRequiredErrorNeedsReview(t *testing.T) {
+	_, paymentRepo, accountRepo, p := setupReview(t, &reviewProvider{
+		err: fmt.Errorf("risk score 97: %w", domainErrors.ErrReviewRequired),
+	})
+
+	held, _ := paymentRepo.GetByID(context.Background(), p.ID)
+	assert.Equal(t, payment.StatusNeedsReview, held.Status)
+	assert.Contains(t, *held.LastError, "risk score 97")
+	assert.Equal(t, int64(10000), accountRepo.GetAccountByID(*p.SourceAccountID).HeldBalance)
+}
+
+func TestApproveReview_CompletesWithReservedFunds(t *testing.T) {
+	svc, _, accountRepo, p := setupReview(t, &reviewProvider{
+		result: &providers.ProviderResult{TransactionID: "txn_pending", Status: providers.ResultPending},
+	})
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "reviewer")
+
+	approved, err := svc.ApproveReview(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusCompleted, approved.Status)
+	assert.Equal(t, "txn_pending", *approved.ProviderTransactionID)
+	source := accountRepo.GetAccountByID(*p.SourceAccountID)
+	assert.Equal(t, int64(90000), source.Balance)
+	assert.Zero(t, source.HeldBalance, "the hold is captured")
+
+	_, err = svc.ApproveReview(ctx, p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidStateTransition, "a decided payment cannot be reviewed again")
+}
+
+func TestRejectReview_RejectsAndReleasesFunds(t *testing.T) {
+	svc, paymentRepo, accountRepo, p := setupReview(t, &reviewProvider{
+		result: &providers.ProviderResult{TransactionID: "txn_pending", Status: providers.ResultPending},
+	})
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "reviewer")
+
+	rejected, err := svc.RejectReview(ctx, p.ID, "fraud confirmed")
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusRejected, rejected.Status)
+	assert.Equal(t, "fraud confirmed", *rejected.LastError)
+	source := accountRepo.GetAccountByID(*p.SourceAccountID)
+	assert.Equal(t, int64(100000), source.Balance)
+	assert.Zero(t, source.HeldBalance, "the hold is released")
+	txns, _ := accountRepo.GetTransactions(ctx, source.ID, 10, 0)
+	assert.Empty(t, txns, "a released hold never touches the ledger")
+
+	events, _ := paymentRepo.GetEvents(ctx, p.ID)
+	last := events[len(events)-1]
+	assert.Equal(t, string(payment.EventPaymentFailed), last.EventType)
+	assert.Equal(t, "reviewer", last.EventData["reviewed_by"])
+}
+
+func TestRejectReview_NotProcessedAgain(t *testing.T) {
+	provider := &reviewProvider{
+		result: &providers.ProviderResult{TransactionID: "txn_pending", Status: providers.ResultPending},
+	}
+	svc, paymentRepo, accountRepo, p := setupReview(t, provider)
+	ctx := context.Background()
+	_, err := svc.RejectReview(ctx, p.ID, "fraud confirmed")
+	require.NoError(t, err)
+
+	// A redelivered or re-emitted message for the payment arrives later.
+	require.NoError(t, svc.ProcessPayment(ctx, p.ID))
+
+	assert.Equal(t, 1, provider.charges, "the provider is not called again")
+	stored, _ := paymentRepo.GetByID(ctx, p.ID)
+	assert.Equal(t, payment.StatusRejected, stored.Status)
+	source := accountRepo.GetAccountByID(*p.SourceAccountID)
+	assert.Equal(t, int64(100000), source.Balance)
+	assert.Zero(t, source.HeldBalance, "the funds are not held again")
+}
+
+func TestRejectReview_WithoutHoldReturnsNothing(t *testing.T) {
+	svc, paymentRepo, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+	// Flagged before any funds were reserved: there is no hold to release.
+	source := createTestAccount(t, "user1", 100000, account.StatusActive)
+	accountRepo.AddAccount(source)
+	p := testutil.NewTestPayment(payment.ExternalPayment, &source.ID, nil, 10000, "USD")
+	require.NoError(t, p.MarkProcessing())
+	require.NoError(t, p.MarkNeedsReview("pending", nil))
+	require.NoError(t, paymentRepo.Create(ctx, p))
+
+	rejected, err := svc.RejectReview(ctx, p.ID, "declined")
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusRejected, rejected.Status)
+	assert.Equal(t, int64(100000), accountRepo.GetAccountByID(source.ID).Balance, "nothing is credited")
+	txns, _ := accountRepo.GetTransactions(ctx, source.ID, 10, 0)
+	assert.Empty(t, txns)
+}
+
+func TestApproveReview_NotHeld(t *testing.T) {
+	svc, paymentRepo, _, _, _ := setupPaymentService()
+	p := testutil.NewTestPayment(payment.ExternalPayment, nil, nil, 5000, "USD")
+	require.NoError(t, paymentRepo.Create(context.Background(), p))
+
+	_, err := svc.ApproveReview(context.Background(), p.ID)
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidStateTransition)
+}
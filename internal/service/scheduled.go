@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// createScheduled persists a scheduled payment without queueing it. The
+// scheduler queues it once due; until then it can be cancelled.
+func (s *PaymentService) createScheduled(ctx context.Context, p *payment.Payment) (*CreatePaymentResponse, error) {
+	err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.paymentRepo.Create(txCtx, p); err != nil {
+			return err
+		}
+		return s.paymentRepo.AddEvent(txCtx, &payment.PaymentEvent{
+			ID: uuid.New(), PaymentID: p.ID, EventType: string(payment.EventPaymentCreated),
+			EventData: map[string]any{
+				"type":         string(p.PaymentType),
+				"amount_cents": p.Amount.ValueCents,
+				"status":       string(p.Status),
+				"scheduled_at": p.ScheduledAt.Format(time.RFC3339Nano),
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &CreatePaymentResponse{Payment: p, IsAsync: true, Outcome: OutcomeAccepted}, nil
+}
+
+// ReleaseDueScheduled moves up to limit scheduled payments that are due to
+// pending and queues them for the worker through the outbox, as if they had
+// just been created. It returns how many it released.
+func (s *PaymentService) ReleaseDueScheduled(ctx context.Context, limit int) (int, error) {
+	released := 0
+	err := s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		due, err := s.paymentRepo.ListDueScheduled(txCtx, time.Now(), limit)
+		if err != nil {
+			return err
+		}
+		for _, p := range due {
+			if err := p.MarkDue(); err != nil {
+				return err
+			}
+			if err := s.paymentRepo.Update(txCtx, p); err != nil {
+				return err
+			}
+			eventID := uuid.New()
+			if err := s.outboxRepo.Insert(txCtx, processingEntry(p, payment.EventPaymentDue, eventID)); err != nil {
+				return err
+			}
+			if err := s.paymentRepo.AddEvent(txCtx, &payment.PaymentEvent{
+				ID: eventID, PaymentID: p.ID, EventType: string(payment.EventPaymentDue),
+				EventData: map[string]any{"scheduled_at": p.ScheduledAt.Format(time.RFC3339Nano)},
+			}); err != nil {
+				return err
+			}
+		}
+		released = len(due)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if released > 0 {
+		log.Info().Int("count", released).Msg("scheduled payments released for processing")
+	}
+	return released, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/outbox"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scheduledTransfer(t *testing.T, accountRepo interface{ AddAccount(*account.Account) }, at time.Time) CreatePaymentRequest {
+	t.Helper()
+	source := createTestAccount(t, "user1", 100000, account.StatusActive)
+	dest := createTestAccount(t, "user2", 0, account.StatusActive)
+	accountRepo.AddAccount(source)
+	accountRepo.AddAccount(dest)
+	return CreatePaymentRequest{
+		IdempotencyKey:       "scheduled-" + at.String(),
+		PaymentType:          payment.InternalTransfer,
+		SourceAccountID:      &source.ID,
+		DestinationAccountID: &dest.ID,
+		Amount:               10000,
+		Currency:             "USD",
+		ScheduledAt:          &at,
+	}
+}
+
+func TestCreatePayment_Scheduled_PersistedWithoutExecuting(t *testing.T) {
+	svc, _, accountRepo, outboxRepo, _ := setupPaymentService()
+	ctx := context.Background()
+
+	var queued bool
+	outboxRepo.InsertFunc = func(ctx context.Context, entry *outbox.Entry) error {
+		queued = true
+		return nil
+	}
+
+	req := scheduledTransfer(t, accountRepo, time.Now().Add(time.Hour))
+	resp, err := svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, resp.IsAsync)
+	assert.Equal(t, OutcomeAccepted, resp.Outcome)
+	assert.Equal(t, payment.StatusScheduled, resp.Payment.Status)
+	assert.False(t, queued, "a scheduled payment is not queued until due")
+	assert.Equal(t, int64(100000), accountRepo.GetAccountByID(*req.SourceAccountID).Balance)
+}
+
+func TestCreatePayment_Scheduled_InPast_Rejected(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+
+	req := scheduledTransfer(t, accountRepo, time.Now().Add(-time.Minute))
+	_, err := svc.CreatePayment(context.Background(), req)
+	var validationErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "scheduled_at", validationErr.Field)
+}
+
+func TestCreatePayment_Scheduled_Idempotency(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	req := scheduledTransfer(t, accountRepo, time.Now().Add(time.Hour))
+	first, err := svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+
+	replay, err := svc.CreatePayment(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, first.Payment.ID, replay.Payment.ID)
+	assert.Equal(t, OutcomeAlreadyExists, replay.Outcome)
+	assert.True(t, replay.IsAsync)
+
+	later := req.ScheduledAt.Add(time.Minute)
+	req.ScheduledAt = &later
+	_, err = svc.CreatePayment(ctx, req)
+	assert.ErrorIs(t, err, domainErrors.ErrIdempotencyKeyReused, "a different schedule is a different request")
+}
+
+func TestCancelPayment_Scheduled_NeverReleased(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	ctx := context.Background()
+
+	resp, err := svc.CreatePayment(ctx, scheduledTransfer(t, accountRepo, time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+
+	cancelled, err := svc.CancelPayment(ctx, resp.Payment.ID)
+	require.NoError(t, err)
+	assert.False(t, cancelled.InFlight)
+	assert.Equal(t, payment.StatusCancelled, cancelled.Payment.Status)
+
+	past := time.Now().Add(-time.Second)
+	resp.Payment.ScheduledAt = &past
+	released, err := svc.ReleaseDueScheduled(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, released)
+}
+
+func TestReleaseDueScheduled_QueuesDuePayments(t *testing.T) {
+	svc, paymentRepo, accountRepo, outboxRepo, _ := setupPaymentService()
+	ctx := context.Background()
+
+	var entries []*outbox.Entry
+	outboxRepo.InsertFunc = func(ctx context.Context, entry *outbox.Entry) error {
+		entries = append(entries, entry)
+		return nil
+	}
+
+	due, err := svc.CreatePayment(ctx, scheduledTransfer(t, accountRepo, time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+	notDue, err := svc.CreatePayment(ctx, scheduledTransfer(t, accountRepo, time.Now().Add(2*time.Hour)))
+	require.NoError(t, err)
+	// Bring the first schedule forward instead of waiting for it.
+	past := time.Now().Add(-time.Second)
+	due.Payment.ScheduledAt = &past
+
+	released, err := svc.ReleaseDueScheduled(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, released)
+
+	stored, err := paymentRepo.GetByID(ctx, due.Payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusPending, stored.Status)
+	require.Len(t, entries, 1)
+	assert.Equal(t, due.Payment.ID, entries[0].AggregateID)
+	assert.Equal(t, string(payment.EventPaymentDue), entries[0].EventType)
+
+	stored, err = paymentRepo.GetByID(ctx, notDue.Payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusScheduled, stored.Status)
+
+	require.NoError(t, svc.ProcessPayment(ctx, due.Payment.ID))
+	stored, err = paymentRepo.GetByID(ctx, due.Payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusCompleted, stored.Status)
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/pkg/currency"
+	"github.com/google/uuid"
+)
+
+// StatementSignaturePrefix starts a statement signature: "sha256=" followed
+// by the hex HMAC-SHA256 of the statement bytes under the server key.
+const StatementSignaturePrefix = "sha256="
+
+// statementColumns is the header row of a statement export.
+var statementColumns = []string{
+	"account_id", "transaction_id", "created_at", "type",
+	"amount", "currency", "balance_after", "payment_id", "description",
+}
+
+// statementEnd bounds the search for activity after a statement period.
+var statementEnd = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// errStopIteration ends an EachTransactionBetween walk early.
+var errStopIteration = errors.New("stop iteration")
+
+// Statement is an account's transaction export for [From, To).
+type Statement struct {
+	Account   *account.Account
+	From, To  time.Time
+	Content   []byte
+	Signature string // empty unless signed
+}
+
+// ExportStatement renders the account's transactions in [from, to) as CSV,
+// signing it when sign is set. The content is canonical, so the same ledger
+// rows always produce the same bytes:
+//
+//   - RFC 4180 CSV, UTF-8, "\n" line endings, fields quoted only when they
+//     contain a comma, quote or line break; the header row is statementColumns
+//   - one row per transaction, ordered by created_at then transaction_id
+//   - created_at in UTC as RFC 3339 with nanoseconds, trailing zeros trimmed
+//   - amount and balance_after as decimals with exactly two fraction digits
+//   - payment_id empty when the transaction has none
+//
+// The signature covers exactly these bytes; any edit invalidates it.
+func (s *AccountService) ExportStatement(ctx context.Context, accountID uuid.UUID, from, to time.Time, sign bool) (*Statement, error) {
+	if sign && len(s.statementKey) == 0 {
+		return nil, domainErrors.ErrStatementSigningDisabled
+	}
+	acct, err := s.statementAccount(ctx, accountID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := s.writeStatement(ctx, &buf, acct, from, to); err != nil {
+		return nil, err
+	}
+	st := &Statement{Account: acct, From: from, To: to, Content: buf.Bytes()}
+	if sign {
+		st.Signature = StatementSignaturePrefix + hex.EncodeToString(s.statementMAC(st.Content))
+	}
+	return st, nil
+}
+
+// StreamStatement writes the same CSV as an unsigned ExportStatement, row by
+// row as transactions are read, so a large statement is never held in
+// memory. start is called once the account and range are validated and
+// returns the writer to stream to; errors before that are returned with
+// nothing written, so the caller can still report them.
+func (s *AccountService) StreamStatement(ctx context.Context, accountID uuid.UUID, from, to time.Time, start func(*account.Account) io.Writer) error {
+	acct, err := s.statementAccount(ctx, accountID, from, to)
+	if err != nil {
+		return err
+	}
+	return s.writeStatement(ctx, start(acct), acct, from, to)
+}
+
+// AccountStatement is an account's activity over [From, To) with the totals
+// every statement format reports, so JSON and PDF statements agree on the
+// numbers. Amounts are in the currency's minor units.
+type AccountStatement struct {
+	Account        *account.Account
+	Currency       currency.Currency
+	From, To       time.Time
+	OpeningBalance int64
+	ClosingBalance int64
+	TotalDebits    int64
+	TotalCredits   int64
+	Transactions   []*account.Transaction // oldest first
+}
+
+// BuildStatement collects the account's transactions in [from, to) and
+// totals them. The opening balance is the balance just before the first
+// transaction in the period; a period without activity opens (and closes)
+// at the balance before the next later transaction, or the current balance
+// if there is none.
+func (s *AccountService) BuildStatement(ctx context.Context, accountID uuid.UUID, from, to time.Time) (*AccountStatement, error) {
+	acct, err := s.statementAccount(ctx, accountID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := currency.Lookup(acct.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &AccountStatement{Account: acct, Currency: cur, From: from, To: to}
+	err = s.accountRepo.EachTransactionBetween(ctx, acct.ID, from, to, func(tx *account.Transaction) error {
+		st.Transactions = append(st.Transactions, tx)
+		if tx.TransactionType.IsCredit() {
+			st.TotalCredits += tx.Amount
+		} else {
+			st.TotalDebits += tx.Amount
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("build statement: %w", err)
+	}
+
+	st.OpeningBalance = acct.Balance
+	if len(st.Transactions) > 0 {
+		st.OpeningBalance = balanceBefore(st.Transactions[0])
+	} else {
+		err = s.accountRepo.EachTransactionBetween(ctx, acct.ID, to, statementEnd, func(tx *account.Transaction) error {
+			st.OpeningBalance = balanceBefore(tx)
+			return errStopIteration
+		})
+		if err != nil && !errors.Is(err, errStopIteration) {
+			return nil, fmt.Errorf("build statement: %w", err)
+		}
+	}
+	st.ClosingBalance = st.OpeningBalance + st.TotalCredits - st.TotalDebits
+	return st, nil
+}
+
+// balanceBefore is the account balance just before tx was recorded.
+func balanceBefore(tx *account.Transaction) int64 {
+	if tx.TransactionType.IsCredit() {
+		return tx.BalanceAfter - tx.Amount
+	}
+	return tx.BalanceAfter + tx.Amount
+}
+
+func (s *AccountService) statementAccount(ctx context.Context, accountID uuid.UUID, from, to time.Time) (*account.Account, error) {
+	if !from.Before(to) {
+		return nil, domainErrors.NewValidationError("from", "must be before to")
+	}
+	acct, err := s.accountRepo.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if acct == nil {
+		return nil, domainErrors.ErrAccountNotFound
+	}
+	if _, err := currency.Lookup(acct.Currency); err != nil {
+		return nil, err
+	}
+	return acct, nil
+}
+
+// VerifyStatement reports whether signature was issued by this server for
+// exactly content.
+func (s *AccountService) VerifyStatement(content []byte, signature string) (bool, error) {
+	if len(s.statementKey) == 0 {
+		return false, domainErrors.ErrStatementSigningDisabled
+	}
+	sig, ok := strings.CutPrefix(signature, StatementSignaturePrefix)
+	if !ok {
+		return false, nil
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false, nil
+	}
+	return hmac.Equal(got, s.statementMAC(content)), nil
+}
+
+func (s *AccountService) statementMAC(content []byte) []byte {
+	mac := hmac.New(sha256.New, s.statementKey)
+	mac.Write(content)
+	return mac.Sum(nil)
+}
+
+// writeStatement writes the canonical statement CSV for acct to w. The csv
+// writer's buffer is flushed to w as it fills.
+func (s *AccountService) writeStatement(ctx context.Context, w io.Writer, acct *account.Account, from, to time.Time) error {
+	cur, err := currency.Lookup(acct.Currency)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(statementColumns); err != nil {
+		return err
+	}
+	err = s.accountRepo.EachTransactionBetween(ctx, acct.ID, from, to, func(tx *account.Transaction) error {
+		paymentID := ""
+		if tx.PaymentID != nil {
+			paymentID = tx.PaymentID.String()
+		}
+		return cw.Write([]string{
+			acct.ID.String(),
+			tx.ID.String(),
+			tx.CreatedAt.UTC().Format(time.RFC3339Nano),
+			string(tx.TransactionType),
+			cur.Format(tx.Amount),
+			acct.Currency,
+			cur.Format(tx.BalanceAfter),
+			paymentID,
+			tx.Description,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("render statement: %w", err)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("render statement: %w", err)
+	}
+	return nil
+}
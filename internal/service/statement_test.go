@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var statementKey = []byte("0123456789abcdef0123456789abcdef")
+
+func setupStatement(t *testing.T) (*AccountService, *account.Account, time.Time) {
+	t.Helper()
+	repo := testutil.NewMockAccountRepository()
+	svc := NewAccountService(repo, WithStatementSigningKey(statementKey))
+	acct, err := account.NewAccount("user1", 0, "USD")
+	require.NoError(t, err)
+	repo.AddAccount(acct)
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	paymentID := uuid.New()
+	for _, tx := range []*account.Transaction{
+		{ID: uuid.New(), AccountID: acct.ID, TransactionType: account.TransactionCredit, Amount: 10050, BalanceAfter: 10050,
+			Description: "deposit", CreatedAt: base.Add(time.Hour)},
+		{ID: uuid.New(), AccountID: acct.ID, PaymentID: &paymentID, TransactionType: account.TransactionDebit, Amount: 5,
+			BalanceAfter: 10045, Description: `fee, "monthly"`, CreatedAt: base},
+		{ID: uuid.New(), AccountID: acct.ID, TransactionType: account.TransactionCredit, Amount: 100, BalanceAfter: 10145,
+			Description: "outside period", CreatedAt: base.Add(48 * time.Hour)},
+	} {
+		require.NoError(t, repo.AddTransaction(context.Background(), tx))
+	}
+	return svc, acct, base
+}
+
+func TestExportStatement_CanonicalCSV(t *testing.T) {
+	svc, acct, base := setupStatement(t)
+
+	st, err := svc.ExportStatement(context.Background(), acct.ID, base, base.Add(24*time.Hour), false)
+	require.NoError(t, err)
+	assert.Empty(t, st.Signature)
+
+	lines := strings.Split(strings.TrimSuffix(string(st.Content), "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "account_id,transaction_id,created_at,type,amount,currency,balance_after,payment_id,description", lines[0])
+	assert.Contains(t, lines[1], `,2026-03-01T12:00:00Z,debit,0.05,USD,100.45,`)
+	assert.True(t, strings.HasSuffix(lines[1], `,"fee, ""monthly"""`))
+	assert.Contains(t, lines[2], `,2026-03-01T13:00:00Z,credit,100.50,USD,100.50,,deposit`)
+}
+
+func TestExportStatement_SignAndVerify(t *testing.T) {
+	svc, acct, base := setupStatement(t)
+
+	st, err := svc.ExportStatement(context.Background(), acct.ID, base, base.Add(24*time.Hour), true)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(st.Signature, StatementSignaturePrefix))
+
+	again, err := svc.ExportStatement(context.Background(), acct.ID, base, base.Add(24*time.Hour), true)
+	require.NoError(t, err)
+	assert.Equal(t, st.Signature, again.Signature, "the same rows must sign identically")
+
+	valid, err := svc.VerifyStatement(st.Content, st.Signature)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	tampered := []byte(strings.Replace(string(st.Content), "100.50", "900.50", 1))
+	valid, err = svc.VerifyStatement(tampered, st.Signature)
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	valid, err = svc.VerifyStatement(st.Content, "sha256=zz")
+	require.NoError(t, err)
+	assert.False(t, valid)
+}
+
+func TestExportStatement_SigningDisabled(t *testing.T) {
+	repo := testutil.NewMockAccountRepository()
+	svc := NewAccountService(repo)
+	acct, _ := account.NewAccount("user1", 0, "USD")
+	repo.AddAccount(acct)
+
+	_, err := svc.ExportStatement(context.Background(), acct.ID, time.Time{}, time.Now(), true)
+	assert.ErrorIs(t, err, domainErrors.ErrStatementSigningDisabled)
+
+	_, err = svc.VerifyStatement([]byte("x"), "sha256=00")
+	assert.ErrorIs(t, err, domainErrors.ErrStatementSigningDisabled)
+
+	st, err := svc.ExportStatement(context.Background(), acct.ID, time.Time{}, time.Now(), false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, st.Content)
+}
+
+func TestExportStatement_AmountsInCurrencyMinorUnits(t *testing.T) {
+	repo := testutil.NewMockAccountRepository()
+	svc := NewAccountService(repo)
+	acct, err := account.NewAccount("user1", 0, "JPY")
+	require.NoError(t, err)
+	repo.AddAccount(acct)
+	require.NoError(t, repo.AddTransaction(context.Background(), &account.Transaction{
+		ID: uuid.New(), AccountID: acct.ID, TransactionType: account.TransactionCredit, Amount: 1500, BalanceAfter: 1500,
+		Description: "deposit", CreatedAt: time.Now().Add(-time.Hour),
+	}))
+
+	st, err := svc.ExportStatement(context.Background(), acct.ID, time.Time{}, time.Now(), false)
+	require.NoError(t, err)
+	assert.Contains(t, string(st.Content), ",credit,1500,JPY,1500,,deposit")
+}
+
+func TestStreamStatement_MatchesExport(t *testing.T) {
+	svc, acct, base := setupStatement(t)
+	ctx := context.Background()
+
+	var streamed strings.Builder
+	var started *account.Account
+	err := svc.StreamStatement(ctx, acct.ID, base, base.Add(24*time.Hour), func(a *account.Account) io.Writer {
+		started = a
+		return &streamed
+	})
+	require.NoError(t, err)
+	assert.Equal(t, acct.ID, started.ID)
+
+	st, err := svc.ExportStatement(ctx, acct.ID, base, base.Add(24*time.Hour), false)
+	require.NoError(t, err)
+	assert.Equal(t, string(st.Content), streamed.String())
+}
+
+func TestStreamStatement_ErrorsBeforeStart(t *testing.T) {
+	svc, acct, base := setupStatement(t)
+	start := func(*account.Account) io.Writer {
+		t.Fatal("start must not be called for a rejected statement")
+		return nil
+	}
+
+	err := svc.StreamStatement(context.Background(), uuid.New(), base, base.Add(time.Hour), start)
+	assert.ErrorIs(t, err, domainErrors.ErrAccountNotFound)
+
+	err = svc.StreamStatement(context.Background(), acct.ID, base, base, start)
+	var validationErr *domainErrors.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "from", validationErr.Field)
+}
+
+func TestBuildStatement_Totals(t *testing.T) {
+	svc, acct, base := setupStatement(t)
+
+	st, err := svc.BuildStatement(context.Background(), acct.ID, base, base.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, st.Transactions, 2)
+	assert.Equal(t, "USD", st.Currency.Code)
+	assert.Equal(t, int64(10050), st.OpeningBalance, "balance before the first debit of the period")
+	assert.Equal(t, int64(5), st.TotalDebits)
+	assert.Equal(t, int64(10050), st.TotalCredits)
+	assert.Equal(t, int64(20095), st.ClosingBalance, "opening plus credits minus debits")
+}
+
+func TestBuildStatement_QuietPeriod(t *testing.T) {
+	svc, acct, base := setupStatement(t)
+
+	st, err := svc.BuildStatement(context.Background(), acct.ID, base.Add(24*time.Hour), base.Add(36*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, st.Transactions)
+	assert.Equal(t, int64(10045), st.OpeningBalance, "balance before the next later transaction")
+	assert.Equal(t, st.OpeningBalance, st.ClosingBalance)
+
+	st, err = svc.BuildStatement(context.Background(), acct.ID, base.Add(72*time.Hour), base.Add(96*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, acct.Balance, st.ClosingBalance, "no later activity leaves the current balance")
+}
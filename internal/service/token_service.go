@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/auth"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Client is an API client allowed to obtain tokens with its secret. Tokens
+// issued to it carry UserID, Scopes and Roles. SecretHash is the hex SHA-256
+// of the secret, so the secret itself never sits in configuration.
+type Client struct {
+	SecretHash string
+	UserID     string
+	Scopes     []string
+	Roles      []string
+}
+
+// TokenPair is an access token with the refresh token that renews it.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+type TokenService struct {
+	refreshTokens auth.RefreshTokenRepository
+	secret        []byte
+	clients       map[string]Client
+	accessTTL     time.Duration
+	refreshTTL    time.Duration
+}
+
+type TokenServiceOption func(*TokenService)
+
+// WithClients sets the clients that may exchange credentials for tokens,
+// keyed by client ID. Without it, IssueToken rejects every client.
+func WithClients(clients map[string]Client) TokenServiceOption {
+	return func(s *TokenService) { s.clients = clients }
+}
+
+// WithTokenTTLs sets how long access and refresh tokens are valid; a
+// non-positive duration keeps the default (24h and 30 days).
+func WithTokenTTLs(access, refresh time.Duration) TokenServiceOption {
+	return func(s *TokenService) {
+		if access > 0 {
+			s.accessTTL = access
+		}
+		if refresh > 0 {
+			s.refreshTTL = refresh
+		}
+	}
+}
+
+// NewTokenService signs access tokens with secret, the key RequireAuth
+// verifies them with.
+func NewTokenService(refreshTokens auth.RefreshTokenRepository, secret []byte, opts ...TokenServiceOption) *TokenService {
+	s := &TokenService{
+		refreshTokens: refreshTokens,
+		secret:        secret,
+		accessTTL:     24 * time.Hour,
+		refreshTTL:    30 * 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// IssueToken authenticates a client by its secret and returns a new token
+// pair for the client's user. Unknown clients and wrong secrets both return
+// ErrInvalidCredentials.
+func (s *TokenService) IssueToken(ctx context.Context, clientID, clientSecret string) (*TokenPair, error) {
+	client, ok := s.clients[clientID]
+	// Compare even for unknown clients so timing does not reveal which exist.
+	match := subtle.ConstantTimeCompare([]byte(auth.HashToken(clientSecret)), []byte(client.SecretHash)) == 1
+	if !ok || !match || client.UserID == "" {
+		return nil, domainErrors.ErrInvalidCredentials
+	}
+	return s.issue(ctx, client.UserID, client.Scopes, client.Roles)
+}
+
+// Refresh exchanges an unexpired, unrevoked refresh token for a new token
+// pair. The presented refresh token is revoked, so each can be used once.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	stored, err := s.usableToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refreshTokens.Revoke(ctx, stored.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, stored.UserID, stored.Scopes, stored.Roles)
+}
+
+// Revoke revokes a refresh token so it can no longer be exchanged. Access
+// tokens already issued stay valid until they expire.
+func (s *TokenService) Revoke(ctx context.Context, refreshToken string) error {
+	stored, err := s.usableToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	return s.refreshTokens.Revoke(ctx, stored.ID, time.Now())
+}
+
+// usableToken looks up a refresh token, returning ErrInvalidRefreshToken
+// unless it exists and is neither revoked nor expired.
+func (s *TokenService) usableToken(ctx context.Context, refreshToken string) (*auth.RefreshToken, error) {
+	if refreshToken == "" {
+		return nil, domainErrors.ErrInvalidRefreshToken
+	}
+	stored, err := s.refreshTokens.GetByHash(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || !stored.Usable(time.Now()) {
+		return nil, domainErrors.ErrInvalidRefreshToken
+	}
+	return stored, nil
+}
+
+func (s *TokenService) issue(ctx context.Context, userID string, scopes, roles []string) (*TokenPair, error) {
+	now := time.Now()
+	pair := &TokenPair{
+		AccessExpiresAt:  now.Add(s.accessTTL),
+		RefreshExpiresAt: now.Add(s.refreshTTL),
+	}
+
+	access, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{
+		UserID: userID,
+		Scopes: scopes,
+		Roles:  roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(pair.AccessExpiresAt),
+		},
+	}).SignedString(s.secret)
+	if err != nil {
+		return nil, fmt.Errorf("sign access token: %w", err)
+	}
+	pair.AccessToken = access
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("generate refresh token: %w", err)
+	}
+	pair.RefreshToken = base64.RawURLEncoding.EncodeToString(raw)
+
+	err = s.refreshTokens.Create(ctx, &auth.RefreshToken{
+		ID:        uuid.New(),
+		TokenHash: auth.HashToken(pair.RefreshToken),
+		UserID:    userID,
+		Scopes:    scopes,
+		Roles:     roles,
+		CreatedAt: now,
+		ExpiresAt: pair.RefreshExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/auth"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTokenSecret = "test-secret-0123456789abcdef01234"
+
+func setupTokenService() (*TokenService, *testutil.MockRefreshTokenRepository) {
+	repo := testutil.NewMockRefreshTokenRepository()
+	svc := NewTokenService(repo, []byte(testTokenSecret),
+		WithClients(map[string]Client{
+			"backoffice": {SecretHash: auth.HashToken("s3cret"), UserID: "user1", Scopes: []string{"payments:admin"}},
+		}),
+		WithTokenTTLs(time.Hour, 24*time.Hour))
+	return svc, repo
+}
+
+func parseAccessToken(t *testing.T, token string) *middleware.Claims {
+	t.Helper()
+	claims := &middleware.Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) { return []byte(testTokenSecret), nil })
+	require.NoError(t, err)
+	return claims
+}
+
+func TestIssueToken_ValidCredentials(t *testing.T) {
+	svc, repo := setupTokenService()
+
+	pair, err := svc.IssueToken(context.Background(), "backoffice", "s3cret")
+	require.NoError(t, err)
+
+	claims := parseAccessToken(t, pair.AccessToken)
+	assert.Equal(t, "user1", claims.UserID)
+	assert.Equal(t, []string{"payments:admin"}, claims.Scopes)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt.Time, 5*time.Second)
+
+	stored, err := repo.GetByHash(context.Background(), auth.HashToken(pair.RefreshToken))
+	require.NoError(t, err)
+	assert.Equal(t, "user1", stored.UserID)
+	assert.NotEqual(t, pair.RefreshToken, stored.TokenHash, "only the hash is stored")
+}
+
+func TestIssueToken_InvalidCredentials(t *testing.T) {
+	svc, _ := setupTokenService()
+
+	_, err := svc.IssueToken(context.Background(), "backoffice", "wrong")
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidCredentials)
+	_, err = svc.IssueToken(context.Background(), "unknown", "s3cret")
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidCredentials)
+}
+
+func TestRefresh_RotatesRefreshToken(t *testing.T) {
+	svc, _ := setupTokenService()
+	ctx := context.Background()
+	pair, err := svc.IssueToken(ctx, "backoffice", "s3cret")
+	require.NoError(t, err)
+
+	renewed, err := svc.Refresh(ctx, pair.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user1", parseAccessToken(t, renewed.AccessToken).UserID)
+	assert.NotEqual(t, pair.RefreshToken, renewed.RefreshToken)
+
+	_, err = svc.Refresh(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidRefreshToken, "a refresh token is single-use")
+	_, err = svc.Refresh(ctx, renewed.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestRefresh_RejectsRevokedExpiredAndUnknownTokens(t *testing.T) {
+	svc, repo := setupTokenService()
+	ctx := context.Background()
+
+	revoked, err := svc.IssueToken(ctx, "backoffice", "s3cret")
+	require.NoError(t, err)
+	require.NoError(t, svc.Revoke(ctx, revoked.RefreshToken))
+	_, err = svc.Refresh(ctx, revoked.RefreshToken)
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidRefreshToken)
+
+	expired, err := svc.IssueToken(ctx, "backoffice", "s3cret")
+	require.NoError(t, err)
+	repo.Expire(auth.HashToken(expired.RefreshToken))
+	_, err = svc.Refresh(ctx, expired.RefreshToken)
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidRefreshToken)
+
+	_, err = svc.Refresh(ctx, "not-a-token")
+	assert.ErrorIs(t, err, domainErrors.ErrInvalidRefreshToken)
+}
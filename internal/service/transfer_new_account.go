@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/domain/payment"
+	"github.com/cassiomorais/payments/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+// TransferToNewAccount creates the destination user's account in the
+// transfer currency if it does not exist and transfers into it, in one
+// transaction: if the transfer fails the new account is rolled back too.
+func (s *PaymentService) TransferToNewAccount(ctx context.Context, req TransferToNewAccountRequest) (*TransferToNewAccountResponse, error) {
+	if req.Currency == "" {
+		if s.defaultCurrency == "" {
+			return nil, domainErrors.NewValidationError("currency", "cannot be empty")
+		}
+		req.Currency = s.defaultCurrency
+	}
+	if req.DestinationUserID == "" {
+		return nil, domainErrors.NewValidationError("destination_user_id", "cannot be empty")
+	}
+
+	userID, _ := middleware.GetUserID(ctx)
+	existing, err := s.paymentRepo.GetByIdempotencyKey(ctx, userID, req.IdempotencyKey)
+	if err == nil && existing != nil {
+		return s.replayTransferToNewAccount(ctx, existing, req)
+	}
+
+	src, err := s.accountRepo.GetByID(ctx, req.SourceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if src.Status != account.StatusActive {
+		return nil, domainErrors.ErrAccountInactive
+	}
+	if src.Currency != req.Currency {
+		return nil, domainErrors.ErrInvalidCurrency
+	}
+
+	var (
+		dst     *account.Account
+		created bool
+		p       *payment.Payment
+	)
+	err = s.txManager.WithTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		if dst, created, err = s.ensureAccount(txCtx, req.DestinationUserID, req.Currency); err != nil {
+			return err
+		}
+		if dst.Status != account.StatusActive {
+			return domainErrors.ErrAccountInactive
+		}
+
+		p, err = payment.NewPayment(req.IdempotencyKey, payment.InternalTransfer, &src.ID, &dst.ID,
+			payment.NewAmount(req.Amount, req.Currency))
+		if err != nil {
+			return err
+		}
+		p.UserID = userID
+		if err := p.SetMaxRetries(s.maxRetries); err != nil {
+			return err
+		}
+		if err := p.SetDescription(req.Description); err != nil {
+			return err
+		}
+		if err := s.applyTransferFee(txCtx, p); err != nil {
+			return err
+		}
+		return s.settleTransfer(txCtx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		log.Info().Str("account_id", dst.ID.String()).Str("payment_id", p.ID.String()).
+			Msg("destination account created for transfer")
+	}
+	return &TransferToNewAccountResponse{
+		CreatePaymentResponse: &CreatePaymentResponse{Payment: p, Outcome: OutcomeCreated},
+		Destination:           dst,
+		DestinationCreated:    created,
+	}, nil
+}
+
+// ensureAccount returns the user's default account in currency, creating an
+// empty one if none exists. A concurrent transaction winning the unique
+// (user_id, currency, label) race is resolved by re-reading its account; the
+// repository reports the conflict without aborting the surrounding
+// transaction.
+func (s *PaymentService) ensureAccount(ctx context.Context, userID, currency string) (*account.Account, bool, error) {
+	acct, err := s.accountRepo.GetByUserID(ctx, userID, currency, "")
+	if err != nil && !errors.Is(err, domainErrors.ErrAccountNotFound) {
+		return nil, false, err
+	}
+	if err == nil && acct != nil {
+		return acct, false, nil
+	}
+
+	acct, err = account.NewAccount(userID, 0, currency)
+	if err != nil {
+		return nil, false, err
+	}
+	err = s.accountRepo.Create(ctx, acct)
+	if err == nil {
+		return acct, true, nil
+	}
+	if !errors.Is(err, domainErrors.ErrAccountAlreadyExists) {
+		return nil, false, err
+	}
+
+	acct, err = s.accountRepo.GetByUserID(ctx, userID, currency, "")
+	if err == nil && acct == nil {
+		err = domainErrors.ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return acct, false, nil
+}
+
+// replayTransferToNewAccount answers a repeated request with the payment it
+// created, provided the request is the same one.
+func (s *PaymentService) replayTransferToNewAccount(ctx context.Context, existing *payment.Payment, req TransferToNewAccountRequest) (*TransferToNewAccountResponse, error) {
+	var dst *account.Account
+	if existing.DestinationAccountID != nil {
+		var err error
+		if dst, err = s.accountRepo.GetByID(ctx, *existing.DestinationAccountID); err != nil {
+			return nil, err
+		}
+	}
+	same := dst != nil && dst.UserID == req.DestinationUserID && matchesCreateRequest(existing, CreatePaymentRequest{
+		PaymentType:          payment.InternalTransfer,
+		SourceAccountID:      &req.SourceAccountID,
+		DestinationAccountID: &dst.ID,
+		Amount:               req.Amount,
+		Currency:             req.Currency,
+		Description:          req.Description,
+	})
+	if !same {
+		if s.metrics != nil {
+			s.metrics.IdempotencyReuseConflicts.WithLabelValues(string(payment.InternalTransfer)).Inc()
+		}
+		return nil, domainErrors.ErrIdempotencyKeyReused
+	}
+	if s.metrics != nil {
+		s.metrics.IdempotencyReplays.WithLabelValues(string(existing.PaymentType)).Inc()
+	}
+	return &TransferToNewAccountResponse{
+		CreatePaymentResponse: &CreatePaymentResponse{Payment: existing, Outcome: OutcomeAlreadyExists},
+		Destination:           dst,
+	}, nil
+}
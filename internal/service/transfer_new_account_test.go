@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
+	"github.com/cassiomorais/payments/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransferToNewAccountRequest(src *account.Account, key string) TransferToNewAccountRequest {
+	return TransferToNewAccountRequest{
+		IdempotencyKey:    key,
+		SourceAccountID:   src.ID,
+		DestinationUserID: "child1",
+		Amount:            2500,
+		Currency:          "USD",
+	}
+}
+
+func TestTransferToNewAccount_CreatesAndFundsDestination(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	src := createTestAccount(t, "parent1", 10000, account.StatusActive)
+	accountRepo.AddAccount(src)
+
+	var createdInTx bool
+	accountRepo.CreateFunc = func(ctx context.Context, acct *account.Account) error {
+		createdInTx = testutil.InMockTransaction(ctx)
+		accountRepo.AddAccount(acct)
+		return nil
+	}
+
+	resp, err := svc.TransferToNewAccount(context.Background(), newTransferToNewAccountRequest(src, "onboard-1"))
+
+	require.NoError(t, err)
+	assert.True(t, resp.DestinationCreated)
+	assert.True(t, createdInTx, "account must be created inside the transfer transaction")
+	assert.Equal(t, "child1", resp.Destination.UserID)
+	assert.Equal(t, OutcomeCreated, resp.Outcome)
+	assert.Equal(t, resp.Destination.ID, *resp.Payment.DestinationAccountID)
+	assert.Equal(t, int64(7500), accountRepo.GetAccountByID(src.ID).Balance)
+	assert.Equal(t, int64(2500), accountRepo.GetAccountByID(resp.Destination.ID).Balance)
+}
+
+func TestTransferToNewAccount_ExistingDestinationReused(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	src := createTestAccount(t, "parent1", 10000, account.StatusActive)
+	dst := createTestAccount(t, "child1", 100, account.StatusActive)
+	accountRepo.AddAccount(src)
+	accountRepo.AddAccount(dst)
+
+	resp, err := svc.TransferToNewAccount(context.Background(), newTransferToNewAccountRequest(src, "onboard-2"))
+
+	require.NoError(t, err)
+	assert.False(t, resp.DestinationCreated)
+	assert.Equal(t, dst.ID, resp.Destination.ID)
+	assert.Equal(t, int64(2600), accountRepo.GetAccountByID(dst.ID).Balance)
+}
+
+func TestTransferToNewAccount_LostCreateRace_RequeriesWinner(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	src := createTestAccount(t, "parent1", 10000, account.StatusActive)
+	accountRepo.AddAccount(src)
+	winner := createTestAccount(t, "child1", 0, account.StatusActive)
+
+	// A concurrent request commits the destination between our lookup and insert.
+	accountRepo.CreateFunc = func(ctx context.Context, acct *account.Account) error {
+		accountRepo.AddAccount(winner)
+		return domainErrors.ErrAccountAlreadyExists
+	}
+
+	resp, err := svc.TransferToNewAccount(context.Background(), newTransferToNewAccountRequest(src, "onboard-3"))
+
+	require.NoError(t, err)
+	assert.False(t, resp.DestinationCreated)
+	assert.Equal(t, winner.ID, resp.Destination.ID)
+	assert.Equal(t, int64(2500), accountRepo.GetAccountByID(winner.ID).Balance)
+}
+
+func TestTransferToNewAccount_InsufficientFunds(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	src := createTestAccount(t, "parent1", 1000, account.StatusActive)
+	accountRepo.AddAccount(src)
+
+	_, err := svc.TransferToNewAccount(context.Background(), newTransferToNewAccountRequest(src, "onboard-4"))
+
+	assert.ErrorIs(t, err, domainErrors.ErrInsufficientFunds)
+	assert.Equal(t, int64(1000), accountRepo.GetAccountByID(src.ID).Balance)
+}
+
+func TestTransferToNewAccount_Replay(t *testing.T) {
+	svc, _, accountRepo, _, _ := setupPaymentService()
+	src := createTestAccount(t, "parent1", 10000, account.StatusActive)
+	accountRepo.AddAccount(src)
+	ctx := context.Background()
+	req := newTransferToNewAccountRequest(src, "onboard-5")
+
+	first, err := svc.TransferToNewAccount(ctx, req)
+	require.NoError(t, err)
+
+	replay, err := svc.TransferToNewAccount(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, OutcomeAlreadyExists, replay.Outcome)
+	assert.Equal(t, first.Payment.ID, replay.Payment.ID)
+	assert.Equal(t, first.Destination.ID, replay.Destination.ID)
+	assert.Equal(t, int64(7500), accountRepo.GetAccountByID(src.ID).Balance)
+
+	req.DestinationUserID = "child2"
+	_, err = svc.TransferToNewAccount(ctx, req)
+	assert.ErrorIs(t, err, domainErrors.ErrIdempotencyKeyReused)
+}
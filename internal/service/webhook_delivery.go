@@ -0,0 +1,95 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// WebhookSignatureHeader carries a delivery's signature: "sha256="
+	// followed by the hex HMAC-SHA256 of the request body under the secret.
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookIDHeader identifies a delivery so receivers can deduplicate
+	// retries.
+	WebhookIDHeader = "X-Webhook-ID"
+
+	maxWebhookBackoff = 30 * time.Second
+)
+
+// WebhookDeliveryPolicy configures outbound webhook delivery. A failed
+// attempt is retried MaxRetries times, waiting RetryDelay before the first
+// retry and doubling up to 30s.
+type WebhookDeliveryPolicy struct {
+	URL        string
+	Secret     string
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+// WebhookDeliveryService posts webhook stream messages to the configured
+// endpoint.
+type WebhookDeliveryService struct {
+	policy WebhookDeliveryPolicy
+	client *http.Client
+}
+
+func NewWebhookDeliveryService(policy WebhookDeliveryPolicy, client *http.Client) *WebhookDeliveryService {
+	return &WebhookDeliveryService{policy: policy, client: client}
+}
+
+// Deliver POSTs payload to the endpoint until it answers 2xx, retrying with
+// backoff. It returns the last failure once retries are exhausted, or the
+// context error if ctx ends first.
+func (s *WebhookDeliveryService) Deliver(ctx context.Context, webhookID string, payload []byte) error {
+	delay := s.policy.RetryDelay
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = s.post(ctx, webhookID, payload); err == nil {
+			return nil
+		}
+		if attempt >= s.policy.MaxRetries {
+			return fmt.Errorf("webhook %s not delivered after %d attempts: %w", webhookID, attempt+1, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxWebhookBackoff)
+	}
+}
+
+func (s *WebhookDeliveryService) post(ctx context.Context, webhookID string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.policy.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, webhookID)
+	req.Header.Set(WebhookSignatureHeader, s.Sign(payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook endpoint returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the WebhookSignatureHeader value for payload.
+func (s *WebhookDeliveryService) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(s.policy.Secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebhookSecret = "webhook-secret-0123456789abcdef0"
+
+func newTestDelivery(url string, maxRetries int) *WebhookDeliveryService {
+	return NewWebhookDeliveryService(WebhookDeliveryPolicy{
+		URL:        url,
+		Secret:     testWebhookSecret,
+		MaxRetries: maxRetries,
+		RetryDelay: time.Millisecond,
+	}, http.DefaultClient)
+}
+
+func TestWebhookDelivery_SignsPayload(t *testing.T) {
+	payload := []byte(`{"event":"payment.completed"}`)
+	var gotBody []byte
+	var gotHeaders http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeaders = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	require.NoError(t, newTestDelivery(srv.URL, 0).Deliver(context.Background(), "wh-1", payload))
+
+	assert.Equal(t, payload, gotBody)
+	assert.Equal(t, "wh-1", gotHeaders.Get(WebhookIDHeader))
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write(payload)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), gotHeaders.Get(WebhookSignatureHeader))
+}
+
+func TestWebhookDelivery_RetriesUntilSuccess(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	require.NoError(t, newTestDelivery(srv.URL, 3).Deliver(context.Background(), "wh-1", []byte(`{}`)))
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestWebhookDelivery_GivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	err := newTestDelivery(srv.URL, 2).Deliver(context.Background(), "wh-1", []byte(`{}`))
+	assert.ErrorContains(t, err, "after 3 attempts")
+	assert.Equal(t, int32(3), calls.Load())
+}
@@ -0,0 +1,145 @@
+// Package statement renders account statements as documents. It is kept
+// apart from the service so the totals are computed once, in
+// service.AccountStatement, and every format only lays them out.
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/service"
+)
+
+// Page layout in PDF points: A4 portrait, monospaced text so the
+// transaction columns line up without measuring glyphs.
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	margin       = 50
+	fontSize     = 9
+	leading      = 12
+	linesPerPage = (pageHeight - 2*margin) / leading
+	maxDescLen   = 40
+)
+
+const rowFormat = "%-19s  %-6s  %14s  %14s  %s"
+
+// WritePDF renders st as a PDF document: a summary with the opening and
+// closing balances and the period's totals, followed by one line per
+// transaction, oldest first. Text outside printable ASCII is replaced with
+// "?", as the built-in PDF fonts cannot show it.
+func WritePDF(w io.Writer, st *service.AccountStatement) error {
+	return writePDF(w, paginate(statementLines(st)))
+}
+
+func statementLines(st *service.AccountStatement) []string {
+	cur := st.Currency
+	lines := []string{
+		"Account statement",
+		"",
+		"Account:  " + st.Account.ID.String(),
+		"Currency: " + cur.Code,
+		"Period:   " + st.From.UTC().Format(time.RFC3339) + " to " + st.To.UTC().Format(time.RFC3339),
+		"",
+		fmt.Sprintf("%-16s %14s", "Opening balance", cur.Format(st.OpeningBalance)),
+		fmt.Sprintf("%-16s %14s", "Total credits", cur.Format(st.TotalCredits)),
+		fmt.Sprintf("%-16s %14s", "Total debits", cur.Format(st.TotalDebits)),
+		fmt.Sprintf("%-16s %14s", "Closing balance", cur.Format(st.ClosingBalance)),
+		"",
+		fmt.Sprintf(rowFormat, "Date (UTC)", "Type", "Amount", "Balance", "Description"),
+	}
+	if len(st.Transactions) == 0 {
+		return append(lines, "No transactions in this period.")
+	}
+	for _, tx := range st.Transactions {
+		desc := tx.Description
+		if len(desc) > maxDescLen {
+			desc = desc[:maxDescLen-3] + "..."
+		}
+		lines = append(lines, fmt.Sprintf(rowFormat,
+			tx.CreatedAt.UTC().Format(time.DateTime), tx.TransactionType,
+			cur.Format(tx.Amount), cur.Format(tx.BalanceAfter), desc))
+	}
+	return lines
+}
+
+// paginate splits lines into pages, leaving room on each for a page number.
+func paginate(lines []string) [][]string {
+	var pages [][]string
+	for len(lines) > 0 {
+		n := min(len(lines), linesPerPage-2)
+		pages = append(pages, lines[:n])
+		lines = lines[n:]
+	}
+	for i := range pages {
+		pages[i] = append(pages[i], "", fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+	}
+	return pages
+}
+
+// writePDF writes a minimal PDF 1.4 file: a catalog, a page tree, one
+// Courier font and a page with a text content stream for each page.
+func writePDF(w io.Writer, pages [][]string) error {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	// Objects 1-3 are fixed; page i is object 4+2i and its content 5+2i.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	for i, lines := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*i))
+		content := pageContent(lines)
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func pageContent(lines []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, leading, margin, pageHeight-margin)
+	for _, line := range lines {
+		b.WriteString("(" + escapeText(line) + ") Tj T*\n")
+	}
+	b.WriteString("ET")
+	return b.String()
+}
+
+// escapeText makes s safe inside a PDF literal string.
+func escapeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < ' ' || r > '~':
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cassiomorais/payments/internal/domain/account"
+	"github.com/cassiomorais/payments/internal/service"
+	"github.com/cassiomorais/payments/pkg/currency"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStatement(t *testing.T, n int) *service.AccountStatement {
+	t.Helper()
+	usd, err := currency.Lookup("USD")
+	require.NoError(t, err)
+	acct := &account.Account{ID: uuid.New(), Currency: "USD"}
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	st := &service.AccountStatement{Account: acct, Currency: usd, From: base, To: base.AddDate(0, 1, 0)}
+	for i := range n {
+		st.Transactions = append(st.Transactions, &account.Transaction{
+			ID: uuid.New(), AccountID: acct.ID, TransactionType: account.TransactionCredit,
+			Amount: 100, BalanceAfter: int64(i+1) * 100, Description: fmt.Sprintf("deposit (%d) café", i),
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		})
+		st.TotalCredits += 100
+	}
+	st.ClosingBalance = st.TotalCredits
+	return st
+}
+
+func TestWritePDF_Structure(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WritePDF(&buf, testStatement(t, 3)))
+	out := buf.String()
+
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte("%%EOF\n")))
+	assert.Contains(t, out, `(Closing balance            3.00) Tj`)
+	assert.Contains(t, out, `deposit \(0\) caf?) Tj`, "parentheses are escaped and non-ASCII replaced")
+	assert.Contains(t, out, "(Page 1 of 1) Tj")
+
+	// Every xref entry must point at the object it names.
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(out)
+	require.NotNil(t, m)
+	xref, _ := strconv.Atoi(m[1])
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(out[xref:], -1)
+	require.Len(t, entries, 5)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(e[1])
+		assert.Equal(t, fmt.Sprintf("%d 0 obj", i+1), out[off:off+len(fmt.Sprintf("%d 0 obj", i+1))])
+	}
+}
+
+func TestWritePDF_Paginates(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WritePDF(&buf, testStatement(t, 150)))
+	out := buf.String()
+
+	assert.Contains(t, out, "/Count 3")
+	assert.Contains(t, out, "(Page 3 of 3) Tj")
+	assert.Contains(t, out, "deposit \\(149\\)")
+}
@@ -19,6 +19,8 @@ func NewTestAccount(userID string, balanceCents int64, currency string) *account
 		Status:    account.StatusActive,
 		CreatedAt: now,
 		UpdatedAt: now,
+
+		InitialBalance: balanceCents,
 	}
 }
 
@@ -36,10 +38,10 @@ func NewTestPayment(
 		PaymentType:          paymentType,
 		SourceAccountID:      sourceID,
 		DestinationAccountID: destID,
-		Amount:               payment.Amount{ValueCents: amountCents, Currency: currency},
+		Amount:               payment.NewAmount(amountCents, currency),
 		Status:               payment.StatusPending,
 		RetryCount:           0,
-		MaxRetries:           3,
+		MaxRetries:           payment.DefaultMaxRetries,
 		Metadata:             make(map[string]any),
 		CreatedAt:            now,
 		UpdatedAt:            now,
@@ -2,35 +2,57 @@ package testutil
 
 import (
 	"context"
+	"errors"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/cassiomorais/payments/internal/domain/account"
+	"github.com/cassiomorais/payments/internal/domain/audit"
+	"github.com/cassiomorais/payments/internal/domain/auth"
+	domainErrors "github.com/cassiomorais/payments/internal/domain/errors"
 	"github.com/cassiomorais/payments/internal/domain/outbox"
 	"github.com/cassiomorais/payments/internal/domain/payment"
 	"github.com/google/uuid"
 )
 
-
 type MockPaymentRepository struct {
 	mu       sync.Mutex
 	payments map[uuid.UUID]*payment.Payment
 	events   map[uuid.UUID][]*payment.PaymentEvent
-	byKey    map[string]*payment.Payment
+	byKey    map[idempotencyScope]*payment.Payment
+	// byProviderTxID is kept current by Create and Update.
+	byProviderTxID map[string]*payment.Payment
+
+	// IdempotencyTTL, when positive, makes GetByIdempotencyKey ignore
+	// payments created longer ago, as the postgres repository does.
+	IdempotencyTTL time.Duration
+
+	CreateFunc                     func(ctx context.Context, p *payment.Payment) error
+	GetByIDFunc                    func(ctx context.Context, id uuid.UUID) (*payment.Payment, error)
+	GetByIdempotencyKeyFunc        func(ctx context.Context, userID, key string) (*payment.Payment, error)
+	GetByProviderTransactionIDFunc func(ctx context.Context, txID string) (*payment.Payment, error)
+	UpdateFunc                     func(ctx context.Context, p *payment.Payment) error
+	ListFunc                       func(ctx context.Context, filter payment.ListFilter) ([]*payment.Payment, error)
+	CountFunc                      func(ctx context.Context, filter payment.ListFilter) (int, error)
+	AddEventFunc                   func(ctx context.Context, event *payment.PaymentEvent) error
+	GetEventsFunc                  func(ctx context.Context, paymentID uuid.UUID) ([]*payment.PaymentEvent, error)
+	ListEventsFunc                 func(ctx context.Context, paymentID uuid.UUID, limit, offset int) ([]*payment.PaymentEvent, error)
+	CountBySourceAccountSinceFunc  func(ctx context.Context, accountID uuid.UUID, since time.Time) (int, error)
+	ListDueScheduledFunc           func(ctx context.Context, now time.Time, limit int) ([]*payment.Payment, error)
+}
 
-	CreateFunc              func(ctx context.Context, p *payment.Payment) error
-	GetByIDFunc             func(ctx context.Context, id uuid.UUID) (*payment.Payment, error)
-	GetByIdempotencyKeyFunc func(ctx context.Context, key string) (*payment.Payment, error)
-	UpdateFunc              func(ctx context.Context, p *payment.Payment) error
-	ListFunc                func(ctx context.Context, filter payment.ListFilter) ([]*payment.Payment, error)
-	AddEventFunc            func(ctx context.Context, event *payment.PaymentEvent) error
-	GetEventsFunc           func(ctx context.Context, paymentID uuid.UUID) ([]*payment.PaymentEvent, error)
+// idempotencyScope is a user's idempotency key.
+type idempotencyScope struct {
+	userID, key string
 }
 
 func NewMockPaymentRepository() *MockPaymentRepository {
 	return &MockPaymentRepository{
-		payments: make(map[uuid.UUID]*payment.Payment),
-		events:   make(map[uuid.UUID][]*payment.PaymentEvent),
-		byKey:    make(map[string]*payment.Payment),
+		payments:       make(map[uuid.UUID]*payment.Payment),
+		events:         make(map[uuid.UUID][]*payment.PaymentEvent),
+		byKey:          make(map[idempotencyScope]*payment.Payment),
+		byProviderTxID: make(map[string]*payment.Payment),
 	}
 }
 
@@ -41,7 +63,8 @@ func (m *MockPaymentRepository) Create(ctx context.Context, p *payment.Payment)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.payments[p.ID] = p
-	m.byKey[p.IdempotencyKey] = p
+	m.byKey[idempotencyScope{p.UserID, p.IdempotencyKey}] = p
+	m.indexProviderTxID(p)
 	return nil
 }
 
@@ -53,20 +76,36 @@ func (m *MockPaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*pay
 	defer m.mu.Unlock()
 	p, ok := m.payments[id]
 	if !ok {
-		return nil, nil
+		return nil, domainErrors.ErrPaymentNotFound
 	}
 	return p, nil
 }
 
-func (m *MockPaymentRepository) GetByIdempotencyKey(ctx context.Context, key string) (*payment.Payment, error) {
+func (m *MockPaymentRepository) GetByIdempotencyKey(ctx context.Context, userID, key string) (*payment.Payment, error) {
 	if m.GetByIdempotencyKeyFunc != nil {
-		return m.GetByIdempotencyKeyFunc(ctx, key)
+		return m.GetByIdempotencyKeyFunc(ctx, userID, key)
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	p, ok := m.byKey[key]
+	p, ok := m.byKey[idempotencyScope{userID, key}]
 	if !ok {
-		return nil, nil
+		return nil, domainErrors.ErrPaymentNotFound
+	}
+	if m.IdempotencyTTL > 0 && !p.CreatedAt.After(time.Now().Add(-m.IdempotencyTTL)) {
+		return nil, domainErrors.ErrPaymentNotFound
+	}
+	return p, nil
+}
+
+func (m *MockPaymentRepository) GetByProviderTransactionID(ctx context.Context, txID string) (*payment.Payment, error) {
+	if m.GetByProviderTransactionIDFunc != nil {
+		return m.GetByProviderTransactionIDFunc(ctx, txID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.byProviderTxID[txID]
+	if !ok || p.ProviderTransactionID == nil || *p.ProviderTransactionID != txID {
+		return nil, domainErrors.ErrPaymentNotFound
 	}
 	return p, nil
 }
@@ -77,10 +116,23 @@ func (m *MockPaymentRepository) Update(ctx context.Context, p *payment.Payment)
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	// Payments are shared by pointer, so only a copy loaded before another
+	// update can carry a stale version.
+	if stored, ok := m.payments[p.ID]; ok && stored != p && stored.Version != p.Version {
+		return domainErrors.ErrOptimisticLockFailed
+	}
+	p.Version++
 	m.payments[p.ID] = p
+	m.indexProviderTxID(p)
 	return nil
 }
 
+func (m *MockPaymentRepository) indexProviderTxID(p *payment.Payment) {
+	if p.ProviderTransactionID != nil {
+		m.byProviderTxID[*p.ProviderTransactionID] = p
+	}
+}
+
 func (m *MockPaymentRepository) List(ctx context.Context, filter payment.ListFilter) ([]*payment.Payment, error) {
 	if m.ListFunc != nil {
 		return m.ListFunc(ctx, filter)
@@ -89,11 +141,33 @@ func (m *MockPaymentRepository) List(ctx context.Context, filter payment.ListFil
 	defer m.mu.Unlock()
 	result := make([]*payment.Payment, 0, len(m.payments))
 	for _, p := range m.payments {
-		result = append(result, p)
+		if matchesListFilter(p, filter) {
+			result = append(result, p)
+		}
 	}
 	return result, nil
 }
 
+func (m *MockPaymentRepository) Count(ctx context.Context, filter payment.ListFilter) (int, error) {
+	if m.CountFunc != nil {
+		return m.CountFunc(ctx, filter)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, p := range m.payments {
+		if matchesListFilter(p, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// matchesListFilter applies the filter conditions the mock supports.
+func matchesListFilter(p *payment.Payment, filter payment.ListFilter) bool {
+	return filter.BatchID == nil || (p.BatchID != nil && *p.BatchID == *filter.BatchID)
+}
+
 func (m *MockPaymentRepository) AddEvent(ctx context.Context, event *payment.PaymentEvent) error {
 	if m.AddEventFunc != nil {
 		return m.AddEventFunc(ctx, event)
@@ -113,25 +187,90 @@ func (m *MockPaymentRepository) GetEvents(ctx context.Context, paymentID uuid.UU
 	return m.events[paymentID], nil
 }
 
+func (m *MockPaymentRepository) ListEvents(ctx context.Context, paymentID uuid.UUID, limit, offset int) ([]*payment.PaymentEvent, error) {
+	if m.ListEventsFunc != nil {
+		return m.ListEventsFunc(ctx, paymentID, limit, offset)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	events := m.events[paymentID]
+	if offset >= len(events) {
+		return nil, nil
+	}
+	return events[offset:min(offset+limit, len(events))], nil
+}
+
+func (m *MockPaymentRepository) CountBySourceAccountSince(ctx context.Context, accountID uuid.UUID, since time.Time) (int, error) {
+	if m.CountBySourceAccountSinceFunc != nil {
+		return m.CountBySourceAccountSinceFunc(ctx, accountID, since)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, p := range m.payments {
+		if p.SourceAccountID != nil && *p.SourceAccountID == accountID && !p.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockPaymentRepository) ListDueScheduled(ctx context.Context, now time.Time, limit int) ([]*payment.Payment, error) {
+	if m.ListDueScheduledFunc != nil {
+		return m.ListDueScheduledFunc(ctx, now, limit)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []*payment.Payment
+	for _, p := range m.payments {
+		if p.Status == payment.StatusScheduled && p.ScheduledAt != nil && !p.ScheduledAt.After(now) {
+			due = append(due, p)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ScheduledAt.Before(*due[j].ScheduledAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
 
 type MockAccountRepository struct {
 	mu           sync.Mutex
 	accounts     map[uuid.UUID]*account.Account
 	transactions map[uuid.UUID][]*account.Transaction
+	holds        map[uuid.UUID]*account.FundsHold
 
 	CreateFunc          func(ctx context.Context, acct *account.Account) error
 	GetByIDFunc         func(ctx context.Context, id uuid.UUID) (*account.Account, error)
-	GetByUserIDFunc     func(ctx context.Context, userID string, currency string) (*account.Account, error)
+	GetByUserIDFunc     func(ctx context.Context, userID, currency, label string) (*account.Account, error)
 	UpdateFunc          func(ctx context.Context, acct *account.Account) error
 	AddTransactionFunc  func(ctx context.Context, tx *account.Transaction) error
 	GetTransactionsFunc func(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*account.Transaction, error)
 	LockFunc            func(ctx context.Context, id uuid.UUID) (*account.Account, error)
+	ListFunc            func(ctx context.Context, filter account.ListFilter) ([]*account.Account, error)
+	CountFunc           func(ctx context.Context, filter account.ListFilter) (int, error)
+
+	// RequireTx makes Update, AddTransaction and Lock fail with
+	// ErrNoTransaction unless called inside MockTransactionManager.WithTransaction.
+	RequireTx bool
+}
+
+// ErrNoTransaction is returned by MockAccountRepository when RequireTx is set
+// and a money-moving call happens outside a transaction.
+var ErrNoTransaction = errors.New("mock: money-moving call outside a transaction")
+
+func (m *MockAccountRepository) checkTx(ctx context.Context) error {
+	if m.RequireTx && !InMockTransaction(ctx) {
+		return ErrNoTransaction
+	}
+	return nil
 }
 
 func NewMockAccountRepository() *MockAccountRepository {
 	return &MockAccountRepository{
 		accounts:     make(map[uuid.UUID]*account.Account),
 		transactions: make(map[uuid.UUID][]*account.Transaction),
+		holds:        make(map[uuid.UUID]*account.FundsHold),
 	}
 }
 
@@ -148,6 +287,11 @@ func (m *MockAccountRepository) Create(ctx context.Context, acct *account.Accoun
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	for _, existing := range m.accounts {
+		if existing.UserID == acct.UserID && existing.Currency == acct.Currency && existing.Label == acct.Label {
+			return domainErrors.ErrAccountAlreadyExists
+		}
+	}
 	m.accounts[acct.ID] = acct
 	return nil
 }
@@ -160,26 +304,29 @@ func (m *MockAccountRepository) GetByID(ctx context.Context, id uuid.UUID) (*acc
 	defer m.mu.Unlock()
 	acct, ok := m.accounts[id]
 	if !ok {
-		return nil, nil
+		return nil, domainErrors.ErrAccountNotFound
 	}
 	return acct, nil
 }
 
-func (m *MockAccountRepository) GetByUserID(ctx context.Context, userID string, currency string) (*account.Account, error) {
+func (m *MockAccountRepository) GetByUserID(ctx context.Context, userID, currency, label string) (*account.Account, error) {
 	if m.GetByUserIDFunc != nil {
-		return m.GetByUserIDFunc(ctx, userID, currency)
+		return m.GetByUserIDFunc(ctx, userID, currency, label)
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	for _, acct := range m.accounts {
-		if acct.UserID == userID && acct.Currency == currency {
+		if acct.UserID == userID && acct.Currency == currency && acct.Label == label {
 			return acct, nil
 		}
 	}
-	return nil, nil
+	return nil, domainErrors.ErrAccountNotFound
 }
 
 func (m *MockAccountRepository) Update(ctx context.Context, acct *account.Account) error {
+	if err := m.checkTx(ctx); err != nil {
+		return err
+	}
 	if m.UpdateFunc != nil {
 		return m.UpdateFunc(ctx, acct)
 	}
@@ -190,6 +337,9 @@ func (m *MockAccountRepository) Update(ctx context.Context, acct *account.Accoun
 }
 
 func (m *MockAccountRepository) AddTransaction(ctx context.Context, tx *account.Transaction) error {
+	if err := m.checkTx(ctx); err != nil {
+		return err
+	}
 	if m.AddTransactionFunc != nil {
 		return m.AddTransactionFunc(ctx, tx)
 	}
@@ -216,7 +366,66 @@ func (m *MockAccountRepository) GetTransactions(ctx context.Context, accountID u
 	return txns[offset:end], nil
 }
 
+func (m *MockAccountRepository) EachTransactionBetween(ctx context.Context, accountID uuid.UUID, from, to time.Time, fn func(*account.Transaction) error) error {
+	m.mu.Lock()
+	var txns []*account.Transaction
+	for _, tx := range m.transactions[accountID] {
+		if !tx.CreatedAt.Before(from) && tx.CreatedAt.Before(to) {
+			txns = append(txns, tx)
+		}
+	}
+	m.mu.Unlock()
+	sort.Slice(txns, func(i, j int) bool {
+		if !txns[i].CreatedAt.Equal(txns[j].CreatedAt) {
+			return txns[i].CreatedAt.Before(txns[j].CreatedAt)
+		}
+		return txns[i].ID.String() < txns[j].ID.String()
+	})
+	for _, tx := range txns {
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockAccountRepository) GetAllTransactions(ctx context.Context, accountID uuid.UUID) ([]*account.Transaction, error) {
+	m.mu.Lock()
+	txns := append([]*account.Transaction(nil), m.transactions[accountID]...)
+	m.mu.Unlock()
+	sort.Slice(txns, func(i, j int) bool {
+		if !txns[i].CreatedAt.Equal(txns[j].CreatedAt) {
+			return txns[i].CreatedAt.Before(txns[j].CreatedAt)
+		}
+		return txns[i].ID.String() < txns[j].ID.String()
+	})
+	return txns, nil
+}
+
+func (m *MockAccountRepository) GetTransactionsByPaymentID(ctx context.Context, paymentID uuid.UUID) ([]*account.Transaction, error) {
+	m.mu.Lock()
+	var txns []*account.Transaction
+	for _, accountTxns := range m.transactions {
+		for _, tx := range accountTxns {
+			if tx.PaymentID != nil && *tx.PaymentID == paymentID {
+				txns = append(txns, tx)
+			}
+		}
+	}
+	m.mu.Unlock()
+	sort.Slice(txns, func(i, j int) bool {
+		if !txns[i].CreatedAt.Equal(txns[j].CreatedAt) {
+			return txns[i].CreatedAt.Before(txns[j].CreatedAt)
+		}
+		return txns[i].ID.String() < txns[j].ID.String()
+	})
+	return txns, nil
+}
+
 func (m *MockAccountRepository) Lock(ctx context.Context, id uuid.UUID) (*account.Account, error) {
+	if err := m.checkTx(ctx); err != nil {
+		return nil, err
+	}
 	if m.LockFunc != nil {
 		return m.LockFunc(ctx, id)
 	}
@@ -229,13 +438,110 @@ func (m *MockAccountRepository) Lock(ctx context.Context, id uuid.UUID) (*accoun
 	return acct, nil
 }
 
+func (m *MockAccountRepository) List(ctx context.Context, filter account.ListFilter) ([]*account.Account, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx, filter)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := m.matchingAccounts(filter)
+	// Newest first, like the postgres default; other sort columns are not
+	// supported.
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.After(result[j].CreatedAt)
+		}
+		return result[i].ID.String() < result[j].ID.String()
+	})
+	offset := max(filter.Offset, 0)
+	if offset >= len(result) {
+		return []*account.Account{}, nil
+	}
+	return result[offset:min(offset+filter.PageLimit(), len(result))], nil
+}
+
+func (m *MockAccountRepository) Count(ctx context.Context, filter account.ListFilter) (int, error) {
+	if m.CountFunc != nil {
+		return m.CountFunc(ctx, filter)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.matchingAccounts(filter)), nil
+}
+
+// matchingAccounts returns the accounts matching the filter's conditions.
+// The caller must hold m.mu.
+func (m *MockAccountRepository) matchingAccounts(filter account.ListFilter) []*account.Account {
+	result := make([]*account.Account, 0, len(m.accounts))
+	for _, acct := range m.accounts {
+		if filter.UserID != nil && acct.UserID != *filter.UserID {
+			continue
+		}
+		if filter.Currency != nil && acct.Currency != *filter.Currency {
+			continue
+		}
+		if filter.Status != nil && acct.Status != *filter.Status {
+			continue
+		}
+		result = append(result, acct)
+	}
+	return result
+}
+
+func (m *MockAccountRepository) CreateHold(ctx context.Context, hold *account.FundsHold) error {
+	if err := m.checkTx(ctx); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.holds[hold.ID] = hold
+	return nil
+}
+
+func (m *MockAccountRepository) GetActiveHold(ctx context.Context, paymentID uuid.UUID) (*account.FundsHold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, h := range m.holds {
+		if h.PaymentID == paymentID && h.Status == account.HoldActive {
+			copied := *h
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockAccountRepository) UpdateHold(ctx context.Context, hold *account.FundsHold) error {
+	if err := m.checkTx(ctx); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.holds[hold.ID]; !ok || existing.Status != account.HoldActive {
+		return domainErrors.ErrInvalidStateTransition
+	}
+	m.holds[hold.ID] = hold
+	return nil
+}
+
+// Holds returns every hold placed for paymentID.
+func (m *MockAccountRepository) Holds(paymentID uuid.UUID) []*account.FundsHold {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var holds []*account.FundsHold
+	for _, h := range m.holds {
+		if h.PaymentID == paymentID {
+			holds = append(holds, h)
+		}
+	}
+	return holds
+}
+
 func (m *MockAccountRepository) GetAccountByID(id uuid.UUID) *account.Account {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	return m.accounts[id]
 }
 
-
 type MockTransactionManager struct {
 	WithTransactionFunc func(ctx context.Context, fn func(ctx context.Context) error) error
 }
@@ -244,19 +550,29 @@ func NewMockTransactionManager() *MockTransactionManager {
 	return &MockTransactionManager{}
 }
 
+type mockTxKey struct{}
+
+// InMockTransaction reports whether ctx came from MockTransactionManager.WithTransaction.
+func InMockTransaction(ctx context.Context) bool {
+	return ctx.Value(mockTxKey{}) != nil
+}
+
 func (m *MockTransactionManager) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	txCtx := context.WithValue(ctx, mockTxKey{}, true)
 	if m.WithTransactionFunc != nil {
-		return m.WithTransactionFunc(ctx, fn)
+		return m.WithTransactionFunc(txCtx, fn)
 	}
-	return fn(ctx)
+	return fn(txCtx)
 }
 
-
 type MockOutboxRepository struct {
 	InsertFunc        func(ctx context.Context, entry *outbox.Entry) error
-	GetPendingFunc    func(ctx context.Context, limit int) ([]*outbox.Entry, error)
+	ClaimPendingFunc  func(ctx context.Context, limit int, lease time.Duration) ([]*outbox.Entry, error)
 	MarkPublishedFunc func(ctx context.Context, id uuid.UUID) error
-	MarkFailedFunc    func(ctx context.Context, id uuid.UUID) error
+	MarkFailedFunc    func(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time) (outbox.Status, error)
+
+	DeletePublishedBeforeFunc func(ctx context.Context, t time.Time, batchSize int) (int64, error)
+	DeletePendingFunc         func(ctx context.Context, aggregateID uuid.UUID) (int64, error)
 }
 
 func (m *MockOutboxRepository) Insert(ctx context.Context, entry *outbox.Entry) error {
@@ -266,9 +582,9 @@ func (m *MockOutboxRepository) Insert(ctx context.Context, entry *outbox.Entry)
 	return nil
 }
 
-func (m *MockOutboxRepository) GetPending(ctx context.Context, limit int) ([]*outbox.Entry, error) {
-	if m.GetPendingFunc != nil {
-		return m.GetPendingFunc(ctx, limit)
+func (m *MockOutboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*outbox.Entry, error) {
+	if m.ClaimPendingFunc != nil {
+		return m.ClaimPendingFunc(ctx, limit, lease)
 	}
 	return nil, nil
 }
@@ -280,9 +596,230 @@ func (m *MockOutboxRepository) MarkPublished(ctx context.Context, id uuid.UUID)
 	return nil
 }
 
-func (m *MockOutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID) error {
+func (m *MockOutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time) (outbox.Status, error) {
 	if m.MarkFailedFunc != nil {
-		return m.MarkFailedFunc(ctx, id)
+		return m.MarkFailedFunc(ctx, id, nextAttemptAt)
+	}
+	return outbox.StatusPending, nil
+}
+
+func (m *MockOutboxRepository) DeletePublishedBefore(ctx context.Context, t time.Time, batchSize int) (int64, error) {
+	if m.DeletePublishedBeforeFunc != nil {
+		return m.DeletePublishedBeforeFunc(ctx, t, batchSize)
+	}
+	return 0, nil
+}
+
+func (m *MockOutboxRepository) DeletePending(ctx context.Context, aggregateID uuid.UUID) (int64, error) {
+	if m.DeletePendingFunc != nil {
+		return m.DeletePendingFunc(ctx, aggregateID)
+	}
+	return 0, nil
+}
+
+type MockDisputeRepository struct {
+	mu       sync.Mutex
+	disputes map[uuid.UUID]*payment.Dispute
+
+	CreateFunc          func(ctx context.Context, d *payment.Dispute) error
+	UpdateFunc          func(ctx context.Context, d *payment.Dispute) error
+	GetByProviderIDFunc func(ctx context.Context, provider payment.Provider, providerDisputeID string) (*payment.Dispute, error)
+	ListByPaymentFunc   func(ctx context.Context, paymentID uuid.UUID) ([]*payment.Dispute, error)
+}
+
+func NewMockDisputeRepository() *MockDisputeRepository {
+	return &MockDisputeRepository{disputes: make(map[uuid.UUID]*payment.Dispute)}
+}
+
+func (m *MockDisputeRepository) Create(ctx context.Context, d *payment.Dispute) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, d)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disputes[d.ID] = d
+	return nil
+}
+
+func (m *MockDisputeRepository) Update(ctx context.Context, d *payment.Dispute) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(ctx, d)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.disputes[d.ID]; !ok {
+		return domainErrors.ErrDisputeNotFound
+	}
+	m.disputes[d.ID] = d
+	return nil
+}
+
+func (m *MockDisputeRepository) GetByProviderID(ctx context.Context, provider payment.Provider, providerDisputeID string) (*payment.Dispute, error) {
+	if m.GetByProviderIDFunc != nil {
+		return m.GetByProviderIDFunc(ctx, provider, providerDisputeID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.disputes {
+		if d.Provider == provider && d.ProviderDisputeID == providerDisputeID {
+			return d, nil
+		}
+	}
+	return nil, domainErrors.ErrDisputeNotFound
+}
+
+func (m *MockDisputeRepository) ListByPayment(ctx context.Context, paymentID uuid.UUID) ([]*payment.Dispute, error) {
+	if m.ListByPaymentFunc != nil {
+		return m.ListByPaymentFunc(ctx, paymentID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var disputes []*payment.Dispute
+	for _, d := range m.disputes {
+		if d.PaymentID == paymentID {
+			disputes = append(disputes, d)
+		}
+	}
+	return disputes, nil
+}
+
+// MockManualRefundRepository is a mock implementation of payment.ManualRefundRepository.
+type MockManualRefundRepository struct {
+	mu      sync.Mutex
+	refunds map[uuid.UUID]*payment.ManualRefund // keyed by payment ID
+
+	CreateFunc func(ctx context.Context, r *payment.ManualRefund) error
+}
+
+func NewMockManualRefundRepository() *MockManualRefundRepository {
+	return &MockManualRefundRepository{refunds: make(map[uuid.UUID]*payment.ManualRefund)}
+}
+
+func (m *MockManualRefundRepository) Create(ctx context.Context, r *payment.ManualRefund) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, r)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.refunds[r.PaymentID]; !ok {
+		m.refunds[r.PaymentID] = r
 	}
 	return nil
 }
+
+// Get returns the manual refund task recorded for a payment, if any.
+func (m *MockManualRefundRepository) Get(paymentID uuid.UUID) *payment.ManualRefund {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.refunds[paymentID]
+}
+
+// MockRefreshTokenRepository is a mock implementation of auth.RefreshTokenRepository.
+type MockRefreshTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]*auth.RefreshToken // keyed by token hash
+}
+
+func NewMockRefreshTokenRepository() *MockRefreshTokenRepository {
+	return &MockRefreshTokenRepository{tokens: make(map[string]*auth.RefreshToken)}
+}
+
+func (m *MockRefreshTokenRepository) Create(ctx context.Context, t *auth.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[t.TokenHash] = t
+	return nil
+}
+
+func (m *MockRefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*auth.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, domainErrors.ErrInvalidRefreshToken
+	}
+	copied := *t
+	return &copied, nil
+}
+
+func (m *MockRefreshTokenRepository) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tokens {
+		if t.ID == id && t.RevokedAt == nil {
+			t.RevokedAt = &at
+			return nil
+		}
+	}
+	return domainErrors.ErrInvalidRefreshToken
+}
+
+// Expire moves a stored token's expiry into the past.
+func (m *MockRefreshTokenRepository) Expire(tokenHash string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.tokens[tokenHash]; ok {
+		t.ExpiresAt = time.Now().Add(-time.Second)
+	}
+}
+
+// MockAuditRepository is a mock implementation of audit.Repository.
+type MockAuditRepository struct {
+	mu      sync.Mutex
+	entries []*audit.Entry
+
+	InsertBatchFunc func(ctx context.Context, entries []*audit.Entry) error
+}
+
+func (m *MockAuditRepository) InsertBatch(ctx context.Context, entries []*audit.Entry) error {
+	if m.InsertBatchFunc != nil {
+		return m.InsertBatchFunc(ctx, entries)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, entries...)
+	return nil
+}
+
+func (m *MockAuditRepository) List(ctx context.Context, filter audit.ListFilter) ([]*audit.Entry, error) {
+	matched := m.matching(filter)
+	if filter.Offset >= len(matched) {
+		return nil, nil
+	}
+	end := filter.Offset + filter.PageLimit()
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[filter.Offset:end], nil
+}
+
+func (m *MockAuditRepository) Count(ctx context.Context, filter audit.ListFilter) (int, error) {
+	return len(m.matching(filter)), nil
+}
+
+// matching returns the entries filter selects, newest first.
+func (m *MockAuditRepository) matching(f audit.ListFilter) []*audit.Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var matched []*audit.Entry
+	for _, e := range m.entries {
+		if (f.UserID != nil && e.UserID != *f.UserID) ||
+			(f.Operation != nil && e.Operation != *f.Operation) ||
+			(f.TargetID != nil && (e.TargetID == nil || *e.TargetID != *f.TargetID)) ||
+			(f.Result != nil && e.Result != *f.Result) ||
+			(f.CreatedAfter != nil && e.CreatedAt.Before(*f.CreatedAfter)) ||
+			(f.CreatedBefore != nil && e.CreatedAt.After(*f.CreatedBefore)) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].CreatedAt.After(matched[j].CreatedAt) })
+	return matched
+}
+
+// Entries returns every entry written so far, in write order.
+func (m *MockAuditRepository) Entries() []*audit.Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*audit.Entry(nil), m.entries...)
+}
@@ -0,0 +1,228 @@
+// Package currency is a registry of ISO 4217 currencies and the minor units
+// each is counted in. Amounts throughout the system are integers in a
+// currency's minor units (cents for USD, yen for JPY, fils for KWD); the
+// registry is what says how many of those make up one major unit.
+//
+// The common currencies are registered by default. Others, including
+// non-ISO units a deployment needs, are added with Register at startup.
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownCurrency is returned for codes that are not registered.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// maxMinorUnits bounds the exponent so 10^MinorUnits fits an int64 with room
+// for the amount itself.
+const maxMinorUnits = 8
+
+// Currency describes one currency.
+type Currency struct {
+	Code string // ISO 4217 alphabetic code, e.g. "USD"
+	Name string
+	// MinorUnits is the number of decimal places: 2 for USD, 0 for JPY, 3
+	// for KWD.
+	MinorUnits int
+}
+
+// Validate reports whether c can be registered.
+func (c Currency) Validate() error {
+	if len(c.Code) != 3 || strings.ToUpper(c.Code) != c.Code {
+		return fmt.Errorf("currency code %q must be 3 upper-case letters", c.Code)
+	}
+	for _, r := range c.Code {
+		if r < 'A' || r > 'Z' {
+			return fmt.Errorf("currency code %q must be 3 upper-case letters", c.Code)
+		}
+	}
+	if c.MinorUnits < 0 || c.MinorUnits > maxMinorUnits {
+		return fmt.Errorf("currency %s: minor units must be between 0 and %d", c.Code, maxMinorUnits)
+	}
+	return nil
+}
+
+// Factor is the number of minor units in one major unit.
+func (c Currency) Factor() int64 {
+	return pow10(c.MinorUnits)
+}
+
+// Format renders an amount in minor units as a decimal with exactly
+// MinorUnits fraction digits, e.g. "12.34" USD, "1234" JPY, "1.234" KWD.
+func (c Currency) Format(minor int64) string {
+	sign := ""
+	u := uint64(minor)
+	if minor < 0 {
+		sign = "-"
+		u = -u
+	}
+	if c.MinorUnits == 0 {
+		return fmt.Sprintf("%s%d", sign, u)
+	}
+	f := uint64(c.Factor())
+	return fmt.Sprintf("%s%d.%0*d", sign, u/f, c.MinorUnits, u%f)
+}
+
+// ToMinor converts a major-unit amount to minor units, rounding to the
+// nearest minor unit.
+func (c Currency) ToMinor(major float64) (int64, error) {
+	if math.IsNaN(major) || math.IsInf(major, 0) {
+		return 0, fmt.Errorf("amount must be finite")
+	}
+	minor := math.Round(major * float64(c.Factor()))
+	if minor >= math.MaxInt64 || minor <= math.MinInt64 {
+		return 0, fmt.Errorf("amount is too large for %s", c.Code)
+	}
+	return int64(minor), nil
+}
+
+// ToMajor converts an amount in minor units to major units.
+func (c Currency) ToMajor(minor int64) float64 {
+	return float64(minor) / float64(c.Factor())
+}
+
+// Rescale converts an amount counted with from decimal places to one counted
+// with to decimal places, as when a provider expects a currency in units
+// other than its ISO minor unit. It fails rather than drop a fraction that
+// the target unit cannot represent, or on overflow.
+func Rescale(amount int64, from, to int) (int64, error) {
+	switch {
+	case from == to:
+		return amount, nil
+	case to > from:
+		f := pow10(to - from)
+		if amount > math.MaxInt64/f || amount < math.MinInt64/f {
+			return 0, fmt.Errorf("amount %d overflows at %d decimal places", amount, to)
+		}
+		return amount * f, nil
+	default:
+		f := pow10(from - to)
+		if amount%f != 0 {
+			return 0, fmt.Errorf("amount %d has more precision than %d decimal places", amount, to)
+		}
+		return amount / f, nil
+	}
+}
+
+func pow10(n int) int64 {
+	f := int64(1)
+	for range n {
+		f *= 10
+	}
+	return f
+}
+
+// Registry maps currency codes to their metadata. It is safe for concurrent
+// use.
+type Registry struct {
+	mu     sync.RWMutex
+	byCode map[string]Currency
+}
+
+// NewRegistry returns a registry holding currencies. It panics on an invalid
+// entry, as it is meant for static tables.
+func NewRegistry(currencies ...Currency) *Registry {
+	r := &Registry{byCode: make(map[string]Currency, len(currencies))}
+	for _, c := range currencies {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds c, replacing any currency with the same code.
+func (r *Registry) Register(c Currency) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byCode[c.Code] = c
+	return nil
+}
+
+// Lookup returns the currency registered under code.
+func (r *Registry) Lookup(code string) (Currency, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.byCode[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return c, nil
+}
+
+// Codes returns the registered codes in sorted order.
+func (r *Registry) Codes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	codes := make([]string, 0, len(r.byCode))
+	for code := range r.byCode {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Default is the process-wide registry, preloaded with common currencies.
+var Default = NewRegistry(common...)
+
+// Register adds c to the Default registry.
+func Register(c Currency) error { return Default.Register(c) }
+
+// Lookup returns the currency registered under code in the Default registry.
+func Lookup(code string) (Currency, error) { return Default.Lookup(code) }
+
+// IsKnown reports whether code is registered in the Default registry.
+func IsKnown(code string) bool {
+	_, err := Default.Lookup(code)
+	return err == nil
+}
+
+var common = []Currency{
+	{Code: "AED", Name: "UAE Dirham", MinorUnits: 2},
+	{Code: "ARS", Name: "Argentine Peso", MinorUnits: 2},
+	{Code: "AUD", Name: "Australian Dollar", MinorUnits: 2},
+	{Code: "BHD", Name: "Bahraini Dinar", MinorUnits: 3},
+	{Code: "BRL", Name: "Brazilian Real", MinorUnits: 2},
+	{Code: "CAD", Name: "Canadian Dollar", MinorUnits: 2},
+	{Code: "CHF", Name: "Swiss Franc", MinorUnits: 2},
+	{Code: "CLP", Name: "Chilean Peso", MinorUnits: 0},
+	{Code: "CNY", Name: "Yuan Renminbi", MinorUnits: 2},
+	{Code: "COP", Name: "Colombian Peso", MinorUnits: 2},
+	{Code: "CZK", Name: "Czech Koruna", MinorUnits: 2},
+	{Code: "DKK", Name: "Danish Krone", MinorUnits: 2},
+	{Code: "EUR", Name: "Euro", MinorUnits: 2},
+	{Code: "GBP", Name: "Pound Sterling", MinorUnits: 2},
+	{Code: "HKD", Name: "Hong Kong Dollar", MinorUnits: 2},
+	{Code: "HUF", Name: "Forint", MinorUnits: 2},
+	{Code: "IDR", Name: "Rupiah", MinorUnits: 2},
+	{Code: "ILS", Name: "New Israeli Sheqel", MinorUnits: 2},
+	{Code: "INR", Name: "Indian Rupee", MinorUnits: 2},
+	{Code: "ISK", Name: "Iceland Krona", MinorUnits: 0},
+	{Code: "JOD", Name: "Jordanian Dinar", MinorUnits: 3},
+	{Code: "JPY", Name: "Yen", MinorUnits: 0},
+	{Code: "KRW", Name: "Won", MinorUnits: 0},
+	{Code: "KWD", Name: "Kuwaiti Dinar", MinorUnits: 3},
+	{Code: "MXN", Name: "Mexican Peso", MinorUnits: 2},
+	{Code: "NOK", Name: "Norwegian Krone", MinorUnits: 2},
+	{Code: "NZD", Name: "New Zealand Dollar", MinorUnits: 2},
+	{Code: "OMR", Name: "Rial Omani", MinorUnits: 3},
+	{Code: "PLN", Name: "Zloty", MinorUnits: 2},
+	{Code: "SAR", Name: "Saudi Riyal", MinorUnits: 2},
+	{Code: "SEK", Name: "Swedish Krona", MinorUnits: 2},
+	{Code: "SGD", Name: "Singapore Dollar", MinorUnits: 2},
+	{Code: "THB", Name: "Baht", MinorUnits: 2},
+	{Code: "TND", Name: "Tunisian Dinar", MinorUnits: 3},
+	{Code: "TRY", Name: "Turkish Lira", MinorUnits: 2},
+	{Code: "USD", Name: "US Dollar", MinorUnits: 2},
+	{Code: "VND", Name: "Dong", MinorUnits: 0},
+	{Code: "ZAR", Name: "Rand", MinorUnits: 2},
+}
@@ -0,0 +1,89 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup_Common(t *testing.T) {
+	tests := []struct {
+		code  string
+		minor int
+	}{
+		{"USD", 2},
+		{"JPY", 0},
+		{"KWD", 3},
+	}
+	for _, tt := range tests {
+		c, err := Lookup(tt.code)
+		require.NoError(t, err, tt.code)
+		assert.Equal(t, tt.minor, c.MinorUnits, tt.code)
+	}
+
+	_, err := Lookup("XYZ")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Register(Currency{Code: "XTS", Name: "Testing", MinorUnits: 4}))
+	c, err := r.Lookup("XTS")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10000), c.Factor())
+	assert.Equal(t, []string{"XTS"}, r.Codes())
+
+	assert.Error(t, r.Register(Currency{Code: "xts"}), "codes are upper-case")
+	assert.Error(t, r.Register(Currency{Code: "US1"}), "codes are letters")
+	assert.Error(t, r.Register(Currency{Code: "XTS", MinorUnits: -1}))
+	assert.Error(t, r.Register(Currency{Code: "XTS", MinorUnits: 9}))
+}
+
+func TestCurrency_Format(t *testing.T) {
+	usd, _ := Lookup("USD")
+	jpy, _ := Lookup("JPY")
+	kwd, _ := Lookup("KWD")
+
+	assert.Equal(t, "12.34", usd.Format(1234))
+	assert.Equal(t, "-0.05", usd.Format(-5))
+	assert.Equal(t, "1234", jpy.Format(1234))
+	assert.Equal(t, "1.234", kwd.Format(1234))
+	assert.Equal(t, "0.007", kwd.Format(7))
+}
+
+func TestCurrency_ToMinor(t *testing.T) {
+	usd, _ := Lookup("USD")
+	jpy, _ := Lookup("JPY")
+	kwd, _ := Lookup("KWD")
+
+	minor, err := usd.ToMinor(10.01)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1001), minor)
+	minor, err = jpy.ToMinor(1500)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), minor)
+	minor, err = kwd.ToMinor(1.5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), minor)
+	assert.Equal(t, 1.5, kwd.ToMajor(1500))
+
+	_, err = usd.ToMinor(1e18)
+	assert.Error(t, err)
+}
+
+func TestRescale(t *testing.T) {
+	v, err := Rescale(1500, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(150000), v)
+
+	v, err = Rescale(150000, 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), v)
+
+	_, err = Rescale(150050, 2, 0)
+	assert.Error(t, err, "a fraction the target cannot hold is not dropped")
+
+	_, err = Rescale(1<<62, 0, 2)
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+// Package money pairs an amount in minor units with its currency, so amounts
+// in different currencies cannot be combined by accident: arithmetic on
+// mismatched currencies fails instead of producing a meaningless number.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/cassiomorais/payments/pkg/currency"
+)
+
+var (
+	// ErrCurrencyMismatch is returned when arithmetic mixes currencies.
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrOverflow is returned when a result does not fit an int64.
+	ErrOverflow = errors.New("amount overflows")
+)
+
+// Money is an amount of one currency. ValueCents counts the currency's minor
+// units (cents for USD, yen for JPY, fils for KWD); the name predates
+// currencies without cents and is kept for the many callers that use it.
+type Money struct {
+	ValueCents int64
+	Currency   string
+}
+
+// New returns valueCents minor units of code.
+func New(valueCents int64, code string) Money {
+	return Money{ValueCents: valueCents, Currency: code}
+}
+
+// Add returns m + o. Both must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	if (o.ValueCents > 0 && m.ValueCents > math.MaxInt64-o.ValueCents) ||
+		(o.ValueCents < 0 && m.ValueCents < math.MinInt64-o.ValueCents) {
+		return Money{}, fmt.Errorf("%w: %d + %d", ErrOverflow, m.ValueCents, o.ValueCents)
+	}
+	return New(m.ValueCents+o.ValueCents, m.Currency), nil
+}
+
+// Subtract returns m - o. Both must be in the same currency.
+func (m Money) Subtract(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s - %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	if (o.ValueCents < 0 && m.ValueCents > math.MaxInt64+o.ValueCents) ||
+		(o.ValueCents > 0 && m.ValueCents < math.MinInt64+o.ValueCents) {
+		return Money{}, fmt.Errorf("%w: %d - %d", ErrOverflow, m.ValueCents, o.ValueCents)
+	}
+	return New(m.ValueCents-o.ValueCents, m.Currency), nil
+}
+
+func (m Money) IsNegative() bool {
+	return m.ValueCents < 0
+}
+
+func (m Money) IsZero() bool {
+	return m.ValueCents == 0
+}
+
+// String formats the amount in its currency's decimal places. Currencies
+// missing from the registry are shown with two.
+func (m Money) String() string {
+	c, err := currency.Lookup(m.Currency)
+	if err != nil {
+		c = currency.Currency{Code: m.Currency, MinorUnits: 2}
+	}
+	return c.Format(m.ValueCents) + " " + m.Currency
+}
+
+// jsonMoney is the wire form: the amount stays in minor units, so it
+// round-trips exactly.
+type jsonMoney struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes m as {"amount": <minor units>, "currency": "<code>"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.ValueCents, Currency: m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v jsonMoney
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*m = New(v.Amount, v.Currency)
+	return nil
+}
@@ -0,0 +1,55 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoney_AddSubtract(t *testing.T) {
+	sum, err := New(1050, "USD").Add(New(250, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, New(1300, "USD"), sum)
+
+	diff, err := New(250, "USD").Subtract(New(1050, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, New(-800, "USD"), diff)
+	assert.True(t, diff.IsNegative())
+	assert.False(t, diff.IsZero())
+}
+
+func TestMoney_CurrencyMismatch(t *testing.T) {
+	_, err := New(100, "USD").Add(New(100, "EUR"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = New(100, "USD").Subtract(New(100, "JPY"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestMoney_Overflow(t *testing.T) {
+	_, err := New(math.MaxInt64, "USD").Add(New(1, "USD"))
+	assert.ErrorIs(t, err, ErrOverflow)
+	_, err = New(math.MinInt64, "USD").Subtract(New(1, "USD"))
+	assert.ErrorIs(t, err, ErrOverflow)
+	_, err = New(0, "USD").Subtract(New(math.MinInt64, "USD"))
+	assert.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestMoney_String(t *testing.T) {
+	assert.Equal(t, "100.50 USD", New(10050, "USD").String())
+	assert.Equal(t, "1500 JPY", New(1500, "JPY").String())
+	assert.Equal(t, "-1.500 KWD", New(-1500, "KWD").String())
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(New(1234, "USD"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":1234,"currency":"USD"}`, string(data))
+
+	var m Money
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, New(1234, "USD"), m)
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"12.34"}`), &m))
+}
@@ -0,0 +1,81 @@
+// Package pagination implements opaque keyset cursors shared by list
+// endpoints. A cursor identifies the last row of a page by its
+// (timestamp, id) sort key; the next page continues strictly after it.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned for cursors that cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const cursorVersion = "v1"
+
+// Cursor is the sort key of the last row on a page.
+type Cursor struct {
+	Timestamp time.Time
+	ID        uuid.UUID
+}
+
+// EncodeCursor returns the opaque string form of c.
+func EncodeCursor(c Cursor) string {
+	raw := cursorVersion + "|" + c.Timestamp.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a string produced by EncodeCursor. Any malformed or
+// altered input yields ErrInvalidCursor.
+func DecodeCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, fmt.Errorf("%w: empty", ErrInvalidCursor)
+	}
+	raw, err := base64.RawURLEncoding.Strict().DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: bad encoding", ErrInvalidCursor)
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || parts[0] != cursorVersion {
+		return Cursor{}, fmt.Errorf("%w: bad format", ErrInvalidCursor)
+	}
+	ts, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: bad timestamp", ErrInvalidCursor)
+	}
+	// uuid.Parse also accepts braced and urn forms; require the canonical one
+	// so every cursor has exactly one encoding.
+	id, err := uuid.Parse(parts[2])
+	if err != nil || id == uuid.Nil || id.String() != parts[2] {
+		return Cursor{}, fmt.Errorf("%w: bad id", ErrInvalidCursor)
+	}
+	return Cursor{Timestamp: ts, ID: id}, nil
+}
+
+// KeysetPredicate returns a SQL condition selecting rows after c in a listing
+// ordered by (timeCol, idCol), with its arguments bound from placeholder
+// $argIdx onwards. desc must match the listing's sort direction.
+func KeysetPredicate(c Cursor, timeCol, idCol string, desc bool, argIdx int) (string, []any) {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	clause := fmt.Sprintf("(%s, %s) %s ($%d, $%d)", timeCol, idCol, op, argIdx, argIdx+1)
+	return clause, []any{c.Timestamp, c.ID}
+}
+
+// NextPage trims rows fetched with a limit of limit+1 to at most limit and
+// returns the cursor for the following page, or "" when this is the last.
+func NextPage[T any](rows []T, limit int, key func(T) Cursor) ([]T, string) {
+	if limit <= 0 || len(rows) <= limit {
+		return rows, ""
+	}
+	rows = rows[:limit]
+	return rows, EncodeCursor(key(rows[limit-1]))
+}
@@ -0,0 +1,96 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	c := Cursor{
+		Timestamp: time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.FixedZone("X", 3600)),
+		ID:        uuid.New(),
+	}
+
+	decoded, err := DecodeCursor(EncodeCursor(c))
+
+	require.NoError(t, err)
+	assert.True(t, c.Timestamp.Equal(decoded.Timestamp))
+	assert.Equal(t, c.ID, decoded.ID)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	id := uuid.New().String()
+	valid := EncodeCursor(Cursor{Timestamp: time.Now(), ID: uuid.New()})
+
+	cases := map[string]string{
+		"empty":            "",
+		"not base64":       "!!!not-base64!!!",
+		"padded":           valid + "==",
+		"truncated":        valid[:len(valid)-3],
+		"unknown version":  enc("v2|2024-01-01T00:00:00Z|" + id),
+		"missing id":       enc("v1|2024-01-01T00:00:00Z"),
+		"extra field":      enc("v1|2024-01-01T00:00:00Z|" + id + "|x"),
+		"bad timestamp":    enc("v1|yesterday|" + id),
+		"bad id":           enc("v1|2024-01-01T00:00:00Z|not-a-uuid"),
+		"nil id":           enc("v1|2024-01-01T00:00:00Z|" + uuid.Nil.String()),
+		"non-canonical id": enc("v1|2024-01-01T00:00:00Z|{" + id + "}"),
+		"sql in id":        enc("v1|2024-01-01T00:00:00Z|' OR 1=1 --"),
+	}
+	for name, cursor := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodeCursor(cursor)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
+
+func TestDecodeCursor_FlippedByte(t *testing.T) {
+	raw := []byte(EncodeCursor(Cursor{Timestamp: time.Now(), ID: uuid.New()}))
+	for i := range raw {
+		tampered := append([]byte(nil), raw...)
+		tampered[i] ^= 0x01
+		if c, err := DecodeCursor(string(tampered)); err == nil {
+			// A flipped bit may still decode to a well-formed cursor; it
+			// must then be a valid key, never a partially parsed one.
+			assert.NotEqual(t, uuid.Nil, c.ID)
+			assert.False(t, c.Timestamp.IsZero())
+		}
+	}
+}
+
+func TestKeysetPredicate(t *testing.T) {
+	c := Cursor{Timestamp: time.Now(), ID: uuid.New()}
+
+	clause, args := KeysetPredicate(c, "created_at", "id", true, 3)
+	assert.Equal(t, "(created_at, id) < ($3, $4)", clause)
+	assert.Equal(t, []any{c.Timestamp, c.ID}, args)
+
+	clause, _ = KeysetPredicate(c, "created_at", "id", false, 1)
+	assert.Equal(t, "(created_at, id) > ($1, $2)", clause)
+}
+
+func TestNextPage(t *testing.T) {
+	base := time.Now()
+	rows := make([]Cursor, 3)
+	for i := range rows {
+		rows[i] = Cursor{Timestamp: base.Add(time.Duration(i) * time.Second), ID: uuid.New()}
+	}
+	key := func(c Cursor) Cursor { return c }
+
+	page, next := NextPage(rows, 2, key)
+	require.Len(t, page, 2)
+	require.NotEmpty(t, next)
+	decoded, err := DecodeCursor(next)
+	require.NoError(t, err)
+	assert.Equal(t, rows[1].ID, decoded.ID)
+
+	page, next = NextPage(rows, 3, key)
+	assert.Len(t, page, 3)
+	assert.Empty(t, next, "no extra row means last page")
+}